@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+const defaultClusterK = 8
+
+// Cluster describes one archetype cluster found over stored profiles
+type Cluster struct {
+	ID       int                `json:"id"`
+	Size     int                `json:"size"`
+	Centroid map[string]float64 `json:"centroid"`
+}
+
+// ClustersResponse is the body of GET /api/analytics/clusters
+type ClustersResponse struct {
+	ComputedAt time.Time `json:"computedAt"`
+	K          int       `json:"k"`
+	Profiles   int       `json:"profiles"`
+	Clusters   []Cluster `json:"clusters"`
+}
+
+var (
+	clustersMu       sync.RWMutex
+	clustersAt       time.Time                    // zero until the first run
+	clustersByTenant map[string]*ClustersResponse // by tenant ID
+)
+
+// runClusterAnalysis periodically clusters the stored profiles of each
+// tenant, labels each record with its cluster ID and publishes the centroids
+func runClusterAnalysis(s store.Store) {
+	interval := envDuration("HCS_CLUSTER_INTERVAL", time.Hour)
+	k := defaultClusterK
+	if v := getenv("HCS_CLUSTER_K"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			k = n
+		} else {
+			log.Printf("Warning: invalid HCS_CLUSTER_K %q, using %d", v, k)
+		}
+	}
+
+	log.Printf("Cluster analysis enabled (interval=%s, k=%d)", interval, k)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := clusterStoredProfiles(context.Background(), s, k); err != nil {
+			log.Printf("Warning: cluster analysis failed: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// clusterStoredProfiles clusters each tenant's records on their own, so no
+// tenant's centroids or labels depend on the profiles of another
+func clusterStoredProfiles(ctx context.Context, s store.Store, k int) error {
+	records, err := s.ListAll(ctx)
+	if err != nil {
+		return err
+	}
+	byTenant := make(map[string][]store.Record)
+	for _, rec := range records {
+		byTenant[rec.TenantID] = append(byTenant[rec.TenantID], rec)
+	}
+
+	now := clk.Now().UTC()
+	responses := make(map[string]*ClustersResponse, len(byTenant))
+	for tenant, records := range byTenant {
+		response, err := clusterTenant(ctx, s, tenant, records, k)
+		if err != nil {
+			return err
+		}
+		response.ComputedAt = now
+		responses[tenant] = response
+	}
+
+	clustersMu.Lock()
+	clustersAt = now
+	clustersByTenant = responses
+	clustersMu.Unlock()
+	return nil
+}
+
+// clusterTenant runs k-means over the records of one tenant and writes the
+// cluster IDs that changed
+func clusterTenant(ctx context.Context, s store.Store, tenant string, records []store.Record, k int) (*ClustersResponse, error) {
+	vectors := make([][]float64, len(records))
+	for i := range records {
+		vectors[i] = hcs.ProfileVector(hcs.NormalizeProfile(&records[i].Input))
+	}
+
+	result, err := hcs.KMeans(vectors, k, 100)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := make([]Cluster, len(result.Centroids))
+	for i, centroid := range result.Centroids {
+		named := make(map[string]float64, len(centroid))
+		for d, v := range centroid {
+			named[hcs.ProfileVectorDimensions[d]] = math.Round(v*10000) / 10000
+		}
+		clusters[i] = Cluster{ID: i, Centroid: named}
+	}
+
+	// Only labels that changed are written, in place, so records updated
+	// since ListAll (such as reminders sent) are left alone
+	changed := make(map[string]int)
+	for i, rec := range records {
+		id := result.Assignments[i]
+		clusters[id].Size++
+		if old := rec.ClusterID; old == nil || *old != id {
+			changed[rec.Chip] = id
+		}
+	}
+	if err := store.SetClusterIDs(ctx, s, tenant, changed); err != nil {
+		return nil, err
+	}
+
+	return &ClustersResponse{K: len(clusters), Profiles: len(records), Clusters: clusters}, nil
+}
+
+// handleClusters serves the clusters of the caller's tenant only
+func handleClusters(w http.ResponseWriter, r *http.Request) {
+	tenant, err := callerTenant(r, r.URL.Query().Get("tenantId"))
+	if err != nil {
+		sendCodedError(w, err, errcode.TenantForbidden)
+		return
+	}
+
+	clustersMu.RLock()
+	computedAt, response := clustersAt, clustersByTenant[tenant]
+	clustersMu.RUnlock()
+
+	if computedAt.IsZero() {
+		sendError(w, errcode.NotReady, "cluster analysis has not run yet (requires HCS_STORAGE)")
+		return
+	}
+	if response == nil {
+		response = &ClustersResponse{ComputedAt: computedAt, Clusters: []Cluster{}}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
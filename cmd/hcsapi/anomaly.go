@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/corehuman/hcs-lab-api/internal/anomaly"
+	"github.com/corehuman/hcs-lab-api/internal/features"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/webhook"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+var (
+	anomalies     *anomaly.Detector // nil when HCS_ANOMALY_DETECTION=off
+	alertOutbox   *webhook.Outbox   // delivers anomaly and honeypot alerts; nil unless HCS_WEBHOOK_URL is set
+	anomalyAlerts = expvar.NewMap("hcs_anomalies_by_kind")
+)
+
+// AnomalyAlert is the payload of "anomaly.detected" webhook events
+type AnomalyAlert struct {
+	anomaly.Alert
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// newAnomalyDetector reads the thresholds from the environment. Detection is
+// on unless HCS_ANOMALY_DETECTION=off.
+func newAnomalyDetector() *anomaly.Detector {
+	if getenv("HCS_ANOMALY_DETECTION") == "off" {
+		return nil
+	}
+	d := anomaly.NewDetector(anomaly.Config{
+		Window:       envDuration("HCS_ANOMALY_WINDOW", anomaly.DefaultWindow),
+		SpikeMin:     envInt("HCS_ANOMALY_SPIKE_MIN", anomaly.DefaultSpikeMin),
+		SpikeFactor:  envFloat("HCS_ANOMALY_SPIKE_FACTOR", anomaly.DefaultSpikeFactor),
+		FailureMin:   envInt("HCS_ANOMALY_FAILURE_MIN", anomaly.DefaultFailureMin),
+		FailureRatio: envFloat("HCS_ANOMALY_FAILURE_RATIO", anomaly.DefaultFailureRatio),
+		RepeatMin:    envInt("HCS_ANOMALY_REPEAT_MIN", anomaly.DefaultRepeatMin),
+	})
+	c := d.Config()
+	log.Printf("Anomaly detection enabled (window=%s, spike=%dx%.1f, failures=%d@%.2f, repeats=%d, webhook=%t)",
+		c.Window, c.SpikeMin, c.SpikeFactor, c.FailureMin, c.FailureRatio, c.RepeatMin, alertOutbox != nil)
+	return d
+}
+
+// newAlertOutbox returns the outbox of security alerts when HCS_WEBHOOK_URL
+// is set and webhooks are enabled. Unlike expiry reminders, alerts need no storage.
+func newAlertOutbox() *webhook.Outbox {
+	url := getenv("HCS_WEBHOOK_URL")
+	if url == "" || !cfg().flags.Enabled(features.Webhooks, "") {
+		return nil
+	}
+	notifier := webhook.NewNotifier(url)
+	notifier.Secret = getenv("HCS_WEBHOOK_SECRET")
+	notifier.Clock = clk
+	return webhook.NewOutbox(notifier, webhookBreaker)
+}
+
+// anomalyProfileKey is the context key of the profile fingerprint slot that
+// watchAnomalies hands to generateForRequest
+type anomalyProfileKey struct{}
+
+// watchAnomalies feeds the requests of the routes it wraps to the detector,
+// keyed by a fingerprint of their API key. A 400 response counts as a
+// validation failure.
+func watchAnomalies(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if anomalies == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var profile string
+		r = r.WithContext(context.WithValue(r.Context(), anomalyProfileKey{}, &profile))
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		obs := anomaly.Observation{Failed: ww.Status() == http.StatusBadRequest, Profile: profile}
+		for _, alert := range anomalies.Observe(apiKeyFingerprint(r), clk.Now().UTC(), obs) {
+			reportAnomaly(r.Context(), AnomalyAlert{Alert: alert, Tenant: apiKeyTenant(r)})
+		}
+	})
+}
+
+// noteProfile records the fingerprint of a generated profile for watchAnomalies
+func noteProfile(ctx context.Context, input *hcs.InputProfile) {
+	slot, ok := ctx.Value(anomalyProfileKey{}).(*string)
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(data)
+	*slot = hex.EncodeToString(sum[:8])
+}
+
+// apiKeyFingerprint identifies the request's API key in alerts without
+// revealing it; requests without a key share "anonymous"
+func apiKeyFingerprint(r *http.Request) string {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		return "anonymous"
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "key-" + hex.EncodeToString(sum[:4])
+}
+
+// reportAnomaly logs an alert and posts it to the webhook, if any
+func reportAnomaly(ctx context.Context, alert AnomalyAlert) {
+	anomalyAlerts.Add(string(alert.Kind), 1)
+	log.Printf("Anomaly: %s for %s (tenant %q): %s", alert.Kind, alert.Key, alert.Tenant, alert.Detail)
+	sendAlert(ctx, "anomaly.detected", alert)
+}
+
+// sendAlert posts a security alert to the webhook, if any, without delaying
+// the response
+func sendAlert(ctx context.Context, eventType string, data any) {
+	if alertOutbox == nil {
+		return
+	}
+	// The request context ends with the response; delivery must not
+	go func() {
+		if err := alertOutbox.Send(context.WithoutCancel(ctx), eventType, data); err != nil {
+			log.Printf("Warning: %s alert failed: %v", eventType, err)
+		}
+	}()
+}
+
+// envInt parses a positive integer from the environment, falling back to def
+func envInt(name string, def int) int {
+	v := getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Printf("Warning: invalid %s %q, using %d", name, v, def)
+		return def
+	}
+	return n
+}
+
+// envFloat parses a positive number from the environment, falling back to def
+func envFloat(name string, def float64) float64 {
+	v := getenv(name)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f <= 0 {
+		log.Printf("Warning: invalid %s %q, using %g", name, v, def)
+		return def
+	}
+	return f
+}
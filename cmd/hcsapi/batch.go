@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/corehuman/hcs-lab-api/internal/batch"
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+// defaultGenerateBatchMax is the most items a batch accepts without HCS_GENERATE_BATCH_MAX
+const defaultGenerateBatchMax = 1000
+
+// GenerateBatchRequest is the body of POST /api/generate/batch. Each item is
+// a generate request, flat or nested, with its own tenant and options.
+type GenerateBatchRequest struct {
+	Items []json.RawMessage `json:"items"`
+	// Conflict is upsert or reject, overriding HCS_BATCH_CONFLICT for the
+	// records whose CHIP is already stored
+	Conflict string `json:"conflict,omitempty"`
+}
+
+// handleGenerateBatch generates the codes of many profiles in one request
+// (see package batch). Records are written in chunks of HCS_STORE_CHUNK_SIZE.
+func handleGenerateBatch(w http.ResponseWriter, r *http.Request) {
+	var req GenerateBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+	c := cfg()
+	if len(req.Items) == 0 {
+		sendError(w, errcode.InvalidRequest, "items must not be empty")
+		return
+	}
+	if len(req.Items) > c.generateBatchMax {
+		sendBatchTooLarge(w, len(req.Items), c.generateBatchMax)
+		return
+	}
+	conflict := c.batchConflict
+	if req.Conflict != "" {
+		var err error
+		if conflict, err = store.ParseConflict(req.Conflict); err != nil {
+			sendError(w, errcode.InvalidRequest, err.Error())
+			return
+		}
+	}
+
+	items := make([]GenerateRequest, len(req.Items))
+	response := batch.Run(r.Context(), len(req.Items), func(i int) (*hcs.OutputHCS, *store.Record, error) {
+		return generateBatchItem(r, req.Items[i], &items[i])
+	}, codeStore, batch.Options{
+		Lang:      w.Header().Get("Content-Language"),
+		Conflict:  conflict,
+		ChunkSize: c.storeChunkSize,
+		Timeout:   c.requestTimeout,
+	})
+	for i, result := range response.Results {
+		if result.Error != nil {
+			errorsByCode.Add(string(result.Error.ErrorCode), 1)
+		}
+		if result.StorageError != nil {
+			errorsByCode.Add(string(result.StorageError.ErrorCode), 1)
+			if result.StorageError.ErrorCode == errcode.LookupFailed {
+				storageSkipped.Add(1)
+			}
+		}
+		if result.Output != nil {
+			validateOutput(result.Output)
+			publishGenerated(r.Context(), &items[i], result.Output)
+			deliverReport(r.Context(), &items[i], result.Output)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// generateBatchItem decodes and generates one item of a batch under its own
+// compute budget, without storing it
+func generateBatchItem(r *http.Request, raw json.RawMessage, req *GenerateRequest) (*hcs.OutputHCS, *store.Record, error) {
+	if err := json.Unmarshal(raw, req); err != nil {
+		return nil, nil, errcode.Wrap(errcode.InvalidJSON, err)
+	}
+	ctx, cancel := generationContext(r)
+	defer cancel()
+	return generateCodes(ctx, r, req)
+}
+
+// sendBatchTooLarge rejects a batch of n items over the limit
+func sendBatchTooLarge(w http.ResponseWriter, n, limit int) {
+	response := batch.NewTooLargeResponse(w.Header().Get("Content-Language"), n, limit)
+	errorsByCode.Add(string(response.ErrorCode), 1)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.Code)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/jsonc"
+	"github.com/corehuman/hcs-lab-api/internal/yamljson"
+)
+
+// inputContentTypes are the request body formats the API accepts, advertised
+// in the capabilities
+var inputContentTypes = []string{"application/json", jsonc.ContentType, yamljson.ContentType}
+
+// standardizeBody rewrites bodies sent as application/jsonc, which may hold
+// comments and trailing commas, or as application/yaml into standard JSON for
+// the handlers. Other bodies pass through unchanged and stay strict.
+func standardizeBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		var convert func([]byte) ([]byte, error)
+		switch {
+		case mediaType == jsonc.ContentType:
+			convert = jsonc.Standardize
+		case yamljson.IsContentType(mediaType):
+			convert = yamljson.ToJSON
+		}
+		if convert == nil || r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			sendError(w, errcode.InvalidJSON, err.Error())
+			return
+		}
+		if data, err = convert(data); err != nil {
+			sendError(w, errcode.InvalidJSON, err.Error())
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(data))
+		r.ContentLength = int64(len(data))
+		r.Header.Set("Content-Type", "application/json")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"expvar"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/corehuman/hcs-lab-api/internal/breaker"
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/webhook"
+)
+
+// Circuit breakers around the auxiliary dependencies. Code generation does not
+// need either: while storage is down codes are served without being persisted,
+// and webhook events are queued in reminderOutbox. They are created in main,
+// once the clock is set.
+var (
+	storageBreaker *breaker.Breaker
+	webhookBreaker *breaker.Breaker
+	reminderOutbox *webhook.Outbox // nil unless expiry reminders go to HCS_WEBHOOK_URL
+)
+
+// Breaker metrics, served by GET /api/admin/metrics
+var storageSkipped = expvar.NewInt("hcs_storage_skipped_total")
+
+func init() {
+	expvar.Publish("hcs_breakers", expvar.Func(func() any {
+		stats := map[string]breaker.Stats{}
+		for _, b := range []*breaker.Breaker{storageBreaker, webhookBreaker} {
+			if b != nil {
+				stats[b.Name] = b.Stats()
+			}
+		}
+		return stats
+	}))
+	expvar.Publish("hcs_webhook_queue_length", expvar.Func(func() any {
+		if reminderOutbox == nil {
+			return 0
+		}
+		return reminderOutbox.Len()
+	}))
+}
+
+// newBreaker creates a breaker configured by HCS_BREAKER_THRESHOLD (consecutive
+// failures, default 5) and HCS_BREAKER_COOLDOWN (default 30s)
+func newBreaker(name string) *breaker.Breaker {
+	threshold := breaker.DefaultThreshold
+	if v := getenv("HCS_BREAKER_THRESHOLD"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			threshold = n
+		} else {
+			log.Printf("Warning: invalid HCS_BREAKER_THRESHOLD %q, using %d", v, threshold)
+		}
+	}
+	b := breaker.New(name, threshold, envDuration("HCS_BREAKER_COOLDOWN", breaker.DefaultCooldown))
+	b.Clock = clk
+	return b
+}
+
+// sendLookupError reports a failed storage read: 503 while the storage breaker
+// is open, 500 otherwise
+func sendLookupError(w http.ResponseWriter, err error) {
+	if errors.Is(err, breaker.ErrOpen) {
+		sendError(w, errcode.StorageUnavailable, "storage is failing and temporarily bypassed; retry later")
+		return
+	}
+	sendError(w, errcode.LookupFailed, err.Error())
+}
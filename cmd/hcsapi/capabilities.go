@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/features"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/i18n"
+	"github.com/corehuman/hcs-lab-api/internal/metrics"
+	"github.com/corehuman/hcs-lab-api/internal/scoring"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+// CapabilitiesResponse is the body of GET /api/capabilities
+type CapabilitiesResponse struct {
+	Version string `json:"version"`
+	// CodeLevels are the code levels generated for the tenant, e.g. U3, U4, U5, U6, U7
+	CodeLevels       []string `json:"codeLevels"`
+	U7Versions       []string `json:"u7Versions"`
+	CurrentU7Version string   `json:"currentU7Version"`
+	// EngineVersions are the selectable BaZi engine versions
+	EngineVersions       []string              `json:"engineVersions"`
+	CurrentEngineVersion string                `json:"currentEngineVersion"`
+	Signatures           SignatureCapabilities `json:"signatures"`
+	FusionConfigs        []string              `json:"fusionConfigs"`
+	ItemBanks            []string              `json:"itemBanks"`
+	// MaxCompareItems is the most items a comparison request accepts (HCS_COMPARE_MAX)
+	MaxCompareItems int                       `json:"maxCompareItems"`
+	GenerateBatch   GenerateBatchCapabilities `json:"generateBatch"`
+	// Modules are the optional modules enabled for the tenant
+	Modules map[string]bool `json:"modules"`
+	// Locales are the languages of error titles (Accept-Language) and CLI messages
+	Locales []string `json:"locales"`
+	// Terminology names the terms reports and catalog texts use for the tenant
+	Terminology string `json:"terminology"`
+	// Norms names the norms cognition percentiles are ranked against for the tenant
+	Norms string `json:"norms"`
+	// InputContentTypes are the accepted request body formats
+	InputContentTypes []string         `json:"inputContentTypes"`
+	FIPS              FIPSCapabilities `json:"fips"`
+}
+
+// GenerateBatchCapabilities lets clients size their POST /api/generate/batch requests
+type GenerateBatchCapabilities struct {
+	MaxItems int `json:"maxItems"` // HCS_GENERATE_BATCH_MAX
+	// ItemLatency is the recent generation time of one item on this server
+	ItemLatency metrics.Summary `json:"itemLatency"`
+	// Conflict applies to CHIPs already stored unless a batch overrides it
+	Conflict store.Conflict `json:"conflict"`
+}
+
+// FIPSCapabilities reports whether the server is a FIPS build
+type FIPSCapabilities struct {
+	Enabled bool `json:"enabled"`
+	// Module is the cryptographic module of a FIPS build: boringcrypto or hcs_fips
+	Module string `json:"module,omitempty"`
+	// Hashes are the hash algorithms the server may use
+	Hashes []string `json:"hashes"`
+}
+
+// SignatureCapabilities describes the U7 signatures the server emits
+type SignatureCapabilities struct {
+	// Algorithms are the ALG tokens of generated codes, e.g. QS, QS-HKDF-SHA3, MLDSA65
+	Algorithms      []string             `json:"algorithms"`
+	KeyDerivation   hcs.KeyDerivation    `json:"keyDerivation"`
+	SecondaryDigest hcs.SecondaryDigest  `json:"secondaryDigest"`
+	Lengths         hcs.SignatureLengths `json:"lengths"`
+}
+
+// handleCapabilities reports what this server supports under its active
+// configuration, so clients adapt at runtime. ?tenantId= applies the tenant's
+// feature flags, terminology and norms.
+func handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	c := cfg()
+	tenant := r.URL.Query().Get("tenantId")
+
+	lengths, err := c.signatureLengths.Resolve()
+	if err != nil {
+		sendError(w, errcode.Internal, err.Error())
+		return
+	}
+	// New codes are always signed with HKDF keys
+	qs := "QS-HKDF"
+	if c.secondaryDigest == hcs.DigestSHA3 {
+		qs += "-SHA3"
+	}
+	algorithms := []string{qs}
+	if generator != nil {
+		if key, ok := generator.PQPublicKey(); ok {
+			algorithms = append(algorithms, key.Algorithm)
+		}
+	}
+
+	modules := c.flags.Snapshot()
+	for name := range modules {
+		modules[name] = c.flags.Enabled(name, tenant)
+	}
+	// Flags only enable what is configured
+	modules[features.Storage] = modules[features.Storage] && codeStore != nil
+	modules[features.Webhooks] = modules[features.Webhooks] && store.WebhooksOf(codeStore) != nil
+	modules["trace"] = c.allowTrace
+	modules["generation"] = !readOnly
+	modules["emailDelivery"] = mailer != nil
+
+	levels := []string{"U3", "U4"}
+	if modules[features.U5] {
+		levels = append(levels, "U5")
+	}
+	if modules[features.U6] {
+		levels = append(levels, "U6")
+	}
+	if modules[features.U7] {
+		levels = append(levels, "U7")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CapabilitiesResponse{
+		Version:              version,
+		CodeLevels:           levels,
+		U7Versions:           hcs.SupportedU7Versions(),
+		CurrentU7Version:     hcs.CurrentU7Version,
+		EngineVersions:       hcs.SupportedEngineVersions(),
+		CurrentEngineVersion: hcs.CurrentEngineVersion,
+		Signatures: SignatureCapabilities{
+			Algorithms:      algorithms,
+			KeyDerivation:   c.keyDerivation,
+			SecondaryDigest: c.secondaryDigest,
+			Lengths:         lengths,
+		},
+		FusionConfigs:   hcs.FusionConfigIDs(),
+		ItemBanks:       scoring.BankVersions(),
+		MaxCompareItems: compareMax(),
+		GenerateBatch: GenerateBatchCapabilities{
+			MaxItems:    c.generateBatchMax,
+			ItemLatency: generationLatency.Summary(),
+			Conflict:    c.batchConflict,
+		},
+		Modules:           modules,
+		Locales:           i18n.Locales(),
+		Terminology:       terminologyName(c.selectTerminology(tenant)),
+		Norms:             c.selectNorms(tenant).ID,
+		InputContentTypes: inputContentTypes,
+		FIPS: FIPSCapabilities{
+			Enabled: hcs.FIPSMode(),
+			Module:  hcs.FIPSModule(),
+			Hashes:  hcs.HashNames(),
+		},
+	})
+}
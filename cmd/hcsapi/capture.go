@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	defaultCaptureSize = 100
+	// maxCapturedBody bounds each recorded body; longer bodies are omitted
+	maxCapturedBody = 64 << 10
+)
+
+// redactedKeys are the JSON fields that carry or reveal birth data: the birth
+// info itself, the BaZi pillars and profiles and the natal chart computed from it
+var redactedKeys = map[string]bool{
+	"birthInfo":      true,
+	"birthTime":      true,
+	"chineseProfile": true,
+	"chinese":        true,
+	"pillars":        true,
+	"westernChart":   true,
+}
+
+// debugCapture records recent API exchanges, when enabled by
+// HCS_DEBUG_CAPTURE=on, so that user-reported generation discrepancies can be
+// reproduced from GET /api/admin/recent. It keeps the last
+// HCS_DEBUG_CAPTURE_SIZE exchanges in memory only; birth data is redacted
+// before anything is recorded and API keys are never recorded.
+var debugCapture *captureBuffer
+
+// capturedExchange is one recorded request/response pair
+type capturedExchange struct {
+	Time       time.Time       `json:"time"`
+	RequestID  string          `json:"requestId,omitempty"`
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	Status     int             `json:"status"`
+	DurationMs int64           `json:"durationMs"`
+	Request    json.RawMessage `json:"request,omitempty"`
+	Response   json.RawMessage `json:"response,omitempty"`
+}
+
+// captureBuffer is a fixed-size ring of the most recent exchanges
+type captureBuffer struct {
+	mu        sync.Mutex
+	exchanges []capturedExchange
+	next      int
+	full      bool
+}
+
+// newDebugCapture returns the capture buffer, or nil unless HCS_DEBUG_CAPTURE is on
+func newDebugCapture() *captureBuffer {
+	if getenv("HCS_DEBUG_CAPTURE") != "on" {
+		return nil
+	}
+	size := defaultCaptureSize
+	if v := getenv("HCS_DEBUG_CAPTURE_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			size = n
+		} else {
+			log.Printf("Warning: invalid HCS_DEBUG_CAPTURE_SIZE %q, using %d", v, defaultCaptureSize)
+		}
+	}
+	if getenv("HCS_ADMIN_TOKEN") == "" {
+		log.Printf("Warning: HCS_DEBUG_CAPTURE is on but HCS_ADMIN_TOKEN is not set; captures cannot be viewed")
+	}
+	log.Printf("Debug capture on: the last %d API exchanges are kept in memory, birth data redacted", size)
+	return &captureBuffer{exchanges: make([]capturedExchange, size)}
+}
+
+func (b *captureBuffer) add(e capturedExchange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.exchanges[b.next] = e
+	b.next = (b.next + 1) % len(b.exchanges)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// recent returns the recorded exchanges, newest first
+func (b *captureBuffer) recent() []capturedExchange {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := b.next
+	if b.full {
+		n = len(b.exchanges)
+	}
+	out := make([]capturedExchange, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, b.exchanges[(b.next-i+len(b.exchanges))%len(b.exchanges)])
+	}
+	return out
+}
+
+// middleware records the exchanges of the routes it wraps
+func (b *captureBuffer) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := clk.Now()
+		var reqBody limitedBuffer
+		if r.Body != nil {
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.TeeReader(r.Body, &reqBody), r.Body}
+		}
+		var respBody limitedBuffer
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(&respBody)
+
+		next.ServeHTTP(ww, r)
+
+		path := r.URL.Path
+		if r.URL.RawQuery != "" {
+			path += "?" + r.URL.RawQuery
+		}
+		b.add(capturedExchange{
+			Time:       start,
+			RequestID:  middleware.GetReqID(r.Context()),
+			Method:     r.Method,
+			Path:       path,
+			Status:     ww.Status(),
+			DurationMs: clk.Now().Sub(start).Milliseconds(),
+			Request:    redactBody(&reqBody),
+			Response:   redactBody(&respBody),
+		})
+	})
+}
+
+// handleRecent serves the captured exchanges, newest first
+func handleRecent(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(debugCapture.recent())
+}
+
+// limitedBuffer keeps up to maxCapturedBody bytes and notes whether more were written
+type limitedBuffer struct {
+	bytes.Buffer
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if room := maxCapturedBody - b.Len(); n > room {
+		b.truncated = true
+		p = p[:room]
+	}
+	b.Buffer.Write(p)
+	return n, nil
+}
+
+// redactBody returns the recorded JSON body with birth data replaced by
+// "[redacted]". A body that is truncated or not JSON is omitted altogether,
+// since it cannot be redacted reliably.
+func redactBody(b *limitedBuffer) json.RawMessage {
+	if b.Len() == 0 {
+		return nil
+	}
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(b.Bytes()))
+	dec.UseNumber()
+	if b.truncated || dec.Decode(&v) != nil {
+		return json.RawMessage(`"[omitted: truncated or not JSON]"`)
+	}
+	out, err := json.Marshal(redact(v))
+	if err != nil {
+		return json.RawMessage(`"[omitted: truncated or not JSON]"`)
+	}
+	return out
+}
+
+func redact(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if redactedKeys[k] {
+				v[k] = "[redacted]"
+			} else {
+				v[k] = redact(child)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = redact(child)
+		}
+	}
+	return v
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// CodeResponse describes a stored code and its expiry status
+type CodeResponse struct {
+	Chip       string         `json:"chip"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	ValidUntil string         `json:"validUntil,omitempty"`
+	Stale      bool           `json:"stale"`
+	Output     *hcs.OutputHCS `json:"output"`
+}
+
+// handleGetCode returns a stored code of the caller's tenant
+func handleGetCode(w http.ResponseWriter, r *http.Request) {
+	if codeStore == nil {
+		sendError(w, errcode.StorageDisabled, "set HCS_STORAGE to enable code lookup")
+		return
+	}
+	tenant, err := callerTenant(r, r.URL.Query().Get("tenantId"))
+	if err != nil {
+		sendCodedError(w, err, errcode.TenantForbidden)
+		return
+	}
+
+	rec, err := codeStore.Get(r.Context(), tenant, chi.URLParam(r, "chip"))
+	if errors.Is(err, store.ErrNotFound) {
+		sendError(w, errcode.NotFound, "no code stored for this CHIP")
+		return
+	}
+	if err != nil {
+		sendLookupError(w, err)
+		return
+	}
+
+	response := CodeResponse{
+		Chip:      rec.Chip,
+		CreatedAt: rec.CreatedAt,
+		Stale:     hcs.IsStale(rec.Output.Metadata, clk.Now()),
+		Output:    rec.Output,
+	}
+	if !rec.ValidUntil.IsZero() {
+		response.ValidUntil = rec.ValidUntil.Format(time.RFC3339)
+	}
+
+	validateOutput(rec.Output)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
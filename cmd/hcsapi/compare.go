@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+const defaultCompareMax = 50
+
+// CompareItem is either an HCS code (U3/U4/U6/U7) or an input profile
+type CompareItem struct {
+	Code    string            `json:"code,omitempty"`
+	Profile *hcs.InputProfile `json:"profile,omitempty"`
+}
+
+// CompareRequest is the body of POST /api/compare
+type CompareRequest struct {
+	A CompareItem `json:"a"`
+	B CompareItem `json:"b"`
+	// Weights adds a score weighted by them to the report
+	Weights *hcs.CompatibilityWeights `json:"weights,omitempty"`
+}
+
+// CompareResponse is the compatibility report of two profiles
+type CompareResponse struct {
+	hcs.Compatibility
+	Weights *hcs.CompatibilityWeights `json:"weights,omitempty"`
+	Score   *float64                  `json:"score,omitempty"` // only with weights
+}
+
+// MatrixRequest is the body of POST /api/compare/matrix
+type MatrixRequest struct {
+	Items []CompareItem `json:"items"`
+}
+
+// MatrixResponse holds the pairwise resonance scores, indexed like the request items
+type MatrixResponse struct {
+	Count  int         `json:"count"`
+	Scores [][]float64 `json:"scores"`
+}
+
+// compareMax returns the maximum number of items accepted by comparison endpoints
+func compareMax() int {
+	if v := getenv("HCS_COMPARE_MAX"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 1 {
+			return n
+		}
+		log.Printf("Warning: invalid HCS_COMPARE_MAX %q, using %d", v, defaultCompareMax)
+	}
+	return defaultCompareMax
+}
+
+func handleCompare(w http.ResponseWriter, r *http.Request) {
+	var req CompareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+	if req.Weights != nil {
+		if err := req.Weights.Validate(); err != nil {
+			sendError(w, errcode.InvalidRequest, err.Error())
+			return
+		}
+	}
+
+	var profiles [2]*hcs.NormalizedProfile
+	for i, item := range []CompareItem{req.A, req.B} {
+		profile, err := resolveCompareItem(item)
+		if err != nil {
+			sendError(w, errcode.Of(err, errcode.InvalidRequest), fmt.Sprintf("%s: %v", []string{"a", "b"}[i], err))
+			return
+		}
+		profiles[i] = profile
+	}
+
+	resp := CompareResponse{Compatibility: hcs.ComputeCompatibility(profiles[0], profiles[1])}
+	if req.Weights != nil {
+		score := resp.Weighted(*req.Weights)
+		resp.Weights, resp.Score = req.Weights, &score
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func handleCompareMatrix(w http.ResponseWriter, r *http.Request) {
+	var req MatrixRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+
+	max := compareMax()
+	if len(req.Items) < 2 || len(req.Items) > max {
+		sendError(w, errcode.InvalidRequest,
+			fmt.Sprintf("items must contain between 2 and %d entries, got %d", max, len(req.Items)))
+		return
+	}
+
+	// Decode every item once; the matrix then works on normalized profiles only
+	profiles := make([]*hcs.NormalizedProfile, len(req.Items))
+	for i, item := range req.Items {
+		profile, err := resolveCompareItem(item)
+		if err != nil {
+			sendError(w, errcode.Of(err, errcode.InvalidRequest), fmt.Sprintf("items[%d]: %v", i, err))
+			return
+		}
+		profiles[i] = profile
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MatrixResponse{
+		Count:  len(profiles),
+		Scores: hcs.CompatibilityMatrix(profiles),
+	})
+}
+
+// resolveCompareItem converts a code or profile into its normalized form
+func resolveCompareItem(item CompareItem) (*hcs.NormalizedProfile, error) {
+	switch {
+	case item.Code != "" && item.Profile != nil:
+		return nil, fmt.Errorf("provide either code or profile, not both")
+	case item.Code != "":
+		return hcs.NormalizedFromCode(item.Code)
+	case item.Profile != nil:
+		if err := hcs.ValidateInput(item.Profile); err != nil {
+			return nil, fmt.Errorf("invalid input profile: %w", err)
+		}
+		return hcs.NormalizeProfile(item.Profile), nil
+	default:
+		return nil, fmt.Errorf("code or profile is required")
+	}
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/features"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/honeypot"
+	"github.com/corehuman/hcs-lab-api/internal/report"
+	"github.com/corehuman/hcs-lab-api/internal/scoring"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+	"github.com/corehuman/hcs-lab-api/internal/webhook"
+)
+
+// defaultCORSOrigins are allowed when HCS_CORS_ORIGINS is not set
+var defaultCORSOrigins = []string{
+	"http://localhost:*",
+	"https://localhost:*",
+	"http://127.0.0.1:*",
+	"https://127.0.0.1:*",
+	"https://*.vercel.app",
+	"https://vercel.app",
+}
+
+// config holds the settings that can be reloaded without restarting the server.
+// A loaded config is never modified; reloads swap in a new one.
+type config struct {
+	// env holds the HCS_CONFIG_FILE settings the configuration was loaded with
+	env   settings
+	flags *features.Flags
+
+	// defaultValidityMonths applies to requests that don't specify validityMonths
+	defaultValidityMonths int
+	// driftConfig is the rate-of-change guard applied to stored subject histories
+	driftConfig hcs.DriftConfig
+	// requestTimeout bounds the compute and storage work of a single generation
+	requestTimeout time.Duration
+	// storeChunkSize is the number of records written per storage transaction
+	storeChunkSize int
+	// generateBatchMax is the most items POST /api/generate/batch accepts
+	generateBatchMax int
+	// batchConflict decides what a batch does with CHIPs already stored
+	batchConflict store.Conflict
+	// webhookRetry is the backoff of deliveries to tenant webhooks
+	webhookRetry webhook.Retry
+	// fusionConfigs are the HCS_FUSION_CONFIGS entries, registered when the
+	// configuration is swapped in
+	fusionConfigs []hcs.FusionConfig
+	// tenantFusionConfigs maps tenant IDs to the fusion config they are enrolled in
+	tenantFusionConfigs map[string]string
+	transition          codecTransition
+	// itemBanks are the HCS_ITEM_BANK_DIR banks, registered like fusionConfigs
+	itemBanks []*scoring.Bank
+	// tenantItemBanks freezes tenants on a questionnaire item bank version
+	tenantItemBanks map[string]string
+	// signatureLengths are the inline U7 signature lengths (HCS_U7_QSIG_LENGTH, HCS_U7_B3_LENGTH)
+	signatureLengths hcs.SignatureLengths
+	// keyDerivation selects how signing keys are derived (HCS_KEY_DERIVATION)
+	keyDerivation hcs.KeyDerivation
+	// secondaryDigest selects the algorithm of the B3 signature (HCS_SECONDARY_DIGEST)
+	secondaryDigest hcs.SecondaryDigest
+	// modalValidation checks modal sums (HCS_MODAL_CHECK, HCS_MODAL_TOLERANCE, HCS_MODAL_AUTO_NORMALIZE)
+	modalValidation hcs.ModalValidation
+	// allowTrace lets requests ask for the intermediate generation artifacts
+	allowTrace bool
+	// postQuantumDefault adds post-quantum signatures to every generation
+	postQuantumDefault bool
+	// qualityDefault adds the input quality score to every generation
+	qualityDefault bool
+	// percentilesDefault adds cognition percentiles to every generation
+	percentilesDefault bool
+	// validateResponses checks every outgoing OutputHCS against its JSON Schema (dev/staging)
+	validateResponses bool
+
+	// honeypot lists the canary CHIPs whose use raises an alert (HCS_HONEYPOT_FILE)
+	honeypot *honeypot.File
+	// reportTemplates render the emailed reports (HCS_REPORT_TEMPLATES overrides the built-in ones)
+	reportTemplates *report.Templates
+	// terminology rewords reports and error catalog texts per tenant (HCS_TERMINOLOGY, HCS_TENANT_TERMINOLOGY)
+	terminology *tenantTerminology
+	// norms rank cognition values as percentiles (HCS_NORMS, HCS_TENANT_NORMS, HCS_NORMS_FILE)
+	norms *tenantNorms
+
+	cors *corsPolicy
+	// apiKeys maps the accepted X-API-Key values to their tenant ("" for none);
+	// empty disables the check
+	apiKeys map[string]string
+}
+
+var (
+	currentConfig atomic.Pointer[config]
+	reloadMu      sync.Mutex
+)
+
+// cfg returns the active configuration
+func cfg() *config {
+	return currentConfig.Load()
+}
+
+// loadConfig builds a configuration from the environment and the optional
+// HCS_CONFIG_FILE on top of it. It has no side effects: the process
+// environment is left alone and fusion configs and item banks are only
+// registered by install.
+func loadConfig() (*config, error) {
+	env, err := readConfigFile(os.Getenv("HCS_CONFIG_FILE"))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &config{
+		env:            env,
+		driftConfig:    hcs.DefaultDriftConfig(),
+		requestTimeout: 10 * time.Second,
+		apiKeys:        map[string]string{},
+	}
+
+	if v := env.get("HCS_REQUEST_TIMEOUT"); v != "" {
+		if c.requestTimeout, err = time.ParseDuration(v); err != nil || c.requestTimeout <= 0 {
+			return nil, fmt.Errorf("invalid HCS_REQUEST_TIMEOUT: %q", v)
+		}
+	}
+
+	c.flags, err = features.LoadEnv(env.get("HCS_FEATURES_FILE"), env.get)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load feature flags: %w", err)
+	}
+
+	if v := env.get("HCS_VALIDITY_MONTHS"); v != "" {
+		c.defaultValidityMonths, err = strconv.Atoi(v)
+		if err != nil || c.defaultValidityMonths < 0 {
+			return nil, fmt.Errorf("invalid HCS_VALIDITY_MONTHS: %q", v)
+		}
+	}
+
+	if v := env.get("HCS_DRIFT_COGNITION_THRESHOLD"); v != "" {
+		c.driftConfig.CognitionThreshold, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HCS_DRIFT_COGNITION_THRESHOLD: %q", v)
+		}
+	}
+	if env.get("HCS_DRIFT_ELEMENT_CHANGE") == "off" {
+		c.driftConfig.FlagElementChange = false
+	}
+
+	c.storeChunkSize = store.DefaultChunkSize
+	if v := env.get("HCS_STORE_CHUNK_SIZE"); v != "" {
+		c.storeChunkSize, err = strconv.Atoi(v)
+		if err != nil || c.storeChunkSize < 1 {
+			return nil, fmt.Errorf("invalid HCS_STORE_CHUNK_SIZE: %q", v)
+		}
+	}
+
+	c.generateBatchMax = defaultGenerateBatchMax
+	if v := env.get("HCS_GENERATE_BATCH_MAX"); v != "" {
+		c.generateBatchMax, err = strconv.Atoi(v)
+		if err != nil || c.generateBatchMax < 1 {
+			return nil, fmt.Errorf("invalid HCS_GENERATE_BATCH_MAX: %q", v)
+		}
+	}
+	if c.batchConflict, err = store.ParseConflict(env.get("HCS_BATCH_CONFLICT")); err != nil {
+		return nil, fmt.Errorf("invalid HCS_BATCH_CONFLICT: %w", err)
+	}
+
+	c.webhookRetry = webhook.DefaultRetry
+	if v := env.get("HCS_WEBHOOK_RETRY_ATTEMPTS"); v != "" {
+		c.webhookRetry.Attempts, err = strconv.Atoi(v)
+		if err != nil || c.webhookRetry.Attempts < 1 {
+			return nil, fmt.Errorf("invalid HCS_WEBHOOK_RETRY_ATTEMPTS: %q", v)
+		}
+	}
+	for name, d := range map[string]*time.Duration{
+		"HCS_WEBHOOK_RETRY_BASE": &c.webhookRetry.Base,
+		"HCS_WEBHOOK_RETRY_MAX":  &c.webhookRetry.Max,
+	} {
+		if v := env.get(name); v != "" {
+			if *d, err = time.ParseDuration(v); err != nil || *d <= 0 {
+				return nil, fmt.Errorf("invalid %s: %q", name, v)
+			}
+		}
+	}
+
+	c.allowTrace = env.get("HCS_ALLOW_TRACE") == "on"
+	c.postQuantumDefault = env.get("HCS_PQ_DEFAULT") == "on"
+	c.qualityDefault = env.get("HCS_QUALITY_SCORE") == "on"
+	c.percentilesDefault = env.get("HCS_PERCENTILES") == "on"
+	c.validateResponses = env.get("HCS_VALIDATE_RESPONSES") == "on"
+
+	for name, length := range map[string]*int{
+		"HCS_U7_QSIG_LENGTH": &c.signatureLengths.QSig,
+		"HCS_U7_B3_LENGTH":   &c.signatureLengths.B3,
+	} {
+		if v := env.get(name); v != "" {
+			if *length, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("invalid %s: %q", name, v)
+			}
+		}
+	}
+	if _, err := c.signatureLengths.Resolve(); err != nil {
+		return nil, fmt.Errorf("invalid U7 signature lengths: %w", err)
+	}
+
+	if c.keyDerivation, err = hcs.ResolveKeyDerivation(hcs.KeyDerivation(env.get("HCS_KEY_DERIVATION"))); err != nil {
+		return nil, fmt.Errorf("invalid HCS_KEY_DERIVATION: %w", err)
+	}
+	if c.secondaryDigest, err = hcs.ResolveSecondaryDigest(hcs.SecondaryDigest(env.get("HCS_SECONDARY_DIGEST"))); err != nil {
+		return nil, fmt.Errorf("invalid HCS_SECONDARY_DIGEST: %w", err)
+	}
+
+	c.modalValidation.Check = hcs.ModalCheck(env.get("HCS_MODAL_CHECK"))
+	if v := env.get("HCS_MODAL_TOLERANCE"); v != "" {
+		if c.modalValidation.Tolerance, err = strconv.ParseFloat(v, 64); err != nil {
+			return nil, fmt.Errorf("invalid HCS_MODAL_TOLERANCE: %q", v)
+		}
+	}
+	c.modalValidation.Normalize = env.get("HCS_MODAL_AUTO_NORMALIZE") == "on"
+	if c.modalValidation, err = c.modalValidation.Resolve(); err != nil {
+		return nil, fmt.Errorf("invalid modal validation: %w", err)
+	}
+
+	if c.fusionConfigs, c.tenantFusionConfigs, err = loadExperiments(env); err != nil {
+		return nil, fmt.Errorf("failed to load fusion experiments: %w", err)
+	}
+	if c.itemBanks, c.tenantItemBanks, err = loadItemBanks(env); err != nil {
+		return nil, fmt.Errorf("failed to load item banks: %w", err)
+	}
+	if c.transition, err = loadCodecTransition(env); err != nil {
+		return nil, fmt.Errorf("failed to configure codec transition: %w", err)
+	}
+
+	if c.cors, err = loadCORS(env); err != nil {
+		return nil, err
+	}
+	if c.honeypot, err = honeypot.Load(env.get("HCS_HONEYPOT_FILE")); err != nil {
+		return nil, err
+	}
+	if c.reportTemplates, err = report.Load(env.get("HCS_REPORT_TEMPLATES")); err != nil {
+		return nil, fmt.Errorf("invalid HCS_REPORT_TEMPLATES: %w", err)
+	}
+	if c.terminology, err = loadTerminology(env); err != nil {
+		return nil, fmt.Errorf("failed to load terminology: %w", err)
+	}
+	if c.norms, err = loadNorms(env); err != nil {
+		return nil, fmt.Errorf("failed to load norms: %w", err)
+	}
+
+	// HCS_API_KEYS entries are key or key:tenant
+	for _, entry := range splitList(env.get("HCS_API_KEYS")) {
+		key, tenant, _ := strings.Cut(entry, ":")
+		c.apiKeys[key] = tenant
+	}
+
+	return c, nil
+}
+
+// settings are the KEY=VALUE entries of HCS_CONFIG_FILE, layered over the
+// process environment
+type settings map[string]string
+
+// get returns the file's value for key, falling back to the environment
+func (s settings) get(key string) string {
+	if v, ok := s[key]; ok {
+		return v
+	}
+	return os.Getenv(key)
+}
+
+// getenv returns a setting of the active configuration, so HCS_CONFIG_FILE
+// also applies to the settings read once at startup
+func getenv(key string) string {
+	if c := cfg(); c != nil {
+		return c.env.get(key)
+	}
+	return os.Getenv(key)
+}
+
+// readConfigFile reads a file of KEY=VALUE lines. Blank lines and lines
+// starting with # are ignored.
+func readConfigFile(path string) (settings, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HCS_CONFIG_FILE: %w", err)
+	}
+	defer f.Close()
+
+	env := settings{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("HCS_CONFIG_FILE line %d: expected KEY=VALUE", line)
+		}
+		env[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read HCS_CONFIG_FILE: %w", err)
+	}
+	return env, nil
+}
+
+// install registers the configuration's fusion configs and item banks and
+// makes it the active configuration
+func (c *config) install() error {
+	for _, fc := range c.fusionConfigs {
+		if err := hcs.RegisterFusionConfig(fc); err != nil {
+			return err
+		}
+	}
+	for _, b := range c.itemBanks {
+		if err := scoring.RegisterBank(b); err != nil {
+			return err
+		}
+	}
+	currentConfig.Store(c)
+	return nil
+}
+
+// reloadConfig reloads the configuration and the secret key. Nothing is
+// replaced unless everything loads successfully.
+func reloadConfig() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	c, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if holdsSecretKey() {
+		if _, err := secrets.Reload(); err != nil {
+			return err
+		}
+	}
+	return c.install()
+}
+
+// watchReloadSignal reloads the configuration whenever the process receives SIGHUP
+func watchReloadSignal() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	for range sig {
+		if err := reloadConfig(); err != nil {
+			log.Printf("Warning: configuration reload failed, keeping previous configuration: %v", err)
+			continue
+		}
+		log.Printf("Configuration reloaded")
+	}
+}
+
+// handleAdminReload reloads the configuration on demand. It is only routed
+// when HCS_ADMIN_TOKEN is set, and requires that token as a bearer token.
+func handleAdminReload(w http.ResponseWriter, r *http.Request) {
+	if err := reloadConfig(); err != nil {
+		sendError(w, errcode.ReloadFailed, err.Error())
+		return
+	}
+	log.Printf("Configuration reloaded via admin endpoint")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"reloaded"}` + "\n"))
+}
+
+// corsMiddleware applies the CORS policy of the active configuration
+func corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg().cors.handler(next).ServeHTTP(w, r)
+	})
+}
+
+// requireAPIKey rejects requests without a configured X-API-Key once
+// HCS_API_KEYS is set
+func requireAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys := cfg().apiKeys
+		if _, ok := keys[r.Header.Get("X-API-Key")]; len(keys) > 0 && !ok {
+			sendError(w, errcode.Unauthorized, "a valid X-API-Key header is required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiKeyTenant returns the tenant owning the request's X-API-Key, if any
+func apiKeyTenant(r *http.Request) string {
+	return cfg().apiKeys[r.Header.Get("X-API-Key")]
+}
+
+// callerTenant returns the tenant that scopes the records a request stores
+// and reads: that of its API key, which a tenantId in the request must not
+// contradict. Servers without HCS_API_KEYS authenticate no one and take the
+// requested tenant as given.
+func callerTenant(r *http.Request, requested string) (string, error) {
+	keys := cfg().apiKeys
+	if len(keys) == 0 {
+		return requested, nil
+	}
+	tenant := keys[r.Header.Get("X-API-Key")]
+	if requested != "" && requested != tenant {
+		return "", errcode.Errorf(errcode.TenantForbidden, "tenant %q does not belong to this API key", requested)
+	}
+	return tenant, nil
+}
+
+// requireAdminToken guards admin endpoints with the HCS_ADMIN_TOKEN bearer token
+func requireAdminToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got := r.Header.Get("Authorization")
+			if subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+token)) != 1 {
+				sendError(w, errcode.Unauthorized, "admin token required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/go-chi/cors"
+)
+
+// defaultCORSMaxAge is how long browsers may cache a preflight response, in
+// seconds; the maximum not ignored by any of the major browsers
+const defaultCORSMaxAge = 300
+
+// corsFile is the declarative CORS policy named by HCS_CORS_FILE
+type corsFile struct {
+	// MaxAge overrides the preflight cache duration of every route (HCS_CORS_MAX_AGE)
+	MaxAge *int `json:"maxAge,omitempty"`
+	// Routes are tried in order; requests matching none use the default origins
+	Routes []corsRoute `json:"routes"`
+	// Tenants adds allowed origins for requests authenticated with a tenant's API key
+	Tenants map[string][]string `json:"tenants"`
+}
+
+// corsRoute is the CORS policy of one path, or of a path prefix ending in /*
+type corsRoute struct {
+	Path        string   `json:"path"`
+	Origins     []string `json:"origins"`
+	Credentials *bool    `json:"credentials,omitempty"` // default true
+	MaxAge      *int     `json:"maxAge,omitempty"`
+}
+
+// corsPolicy applies the CORS rules of the active configuration
+type corsPolicy struct {
+	routes  []routeCORS
+	def     routeCORS
+	tenants map[string]originList
+}
+
+// routeCORS is a compiled corsRoute
+type routeCORS struct {
+	path    string
+	origins originList
+	cors    *cors.Cors
+}
+
+// loadCORS builds the CORS policy from HCS_CORS_ORIGINS (the default origins),
+// HCS_CORS_MAX_AGE and the optional HCS_CORS_FILE
+func loadCORS(env settings) (*corsPolicy, error) {
+	var file corsFile
+	if path := env.get("HCS_CORS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read HCS_CORS_FILE: %w", err)
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse HCS_CORS_FILE %s: %w", path, err)
+		}
+	}
+
+	maxAge := defaultCORSMaxAge
+	if file.MaxAge != nil {
+		maxAge = *file.MaxAge
+	}
+	if v := env.get("HCS_CORS_MAX_AGE"); v != "" {
+		var err error
+		if maxAge, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid HCS_CORS_MAX_AGE: %q", v)
+		}
+	}
+	if maxAge < 0 {
+		return nil, fmt.Errorf("CORS max age must not be negative, got %d", maxAge)
+	}
+
+	p := &corsPolicy{tenants: make(map[string]originList)}
+	for tenant, origins := range file.Tenants {
+		if tenant == "" {
+			return nil, fmt.Errorf("HCS_CORS_FILE: tenant without a name")
+		}
+		p.tenants[tenant] = newOriginList(origins)
+	}
+
+	origins := defaultCORSOrigins
+	if v := env.get("HCS_CORS_ORIGINS"); v != "" {
+		origins = splitList(v)
+	}
+	p.def = p.compile(corsRoute{Origins: origins}, maxAge)
+	for i, route := range file.Routes {
+		if !strings.HasPrefix(route.Path, "/") {
+			return nil, fmt.Errorf("HCS_CORS_FILE route %d: path must start with /, got %q", i, route.Path)
+		}
+		if route.MaxAge != nil && *route.MaxAge < 0 {
+			return nil, fmt.Errorf("HCS_CORS_FILE route %s: max age must not be negative", route.Path)
+		}
+		p.routes = append(p.routes, p.compile(route, maxAge))
+	}
+	return p, nil
+}
+
+// compile builds the handler of a route. Preflight requests carry no API key,
+// so they are allowed from any tenant's origins too; the actual request is
+// then checked against the tenant of its key.
+func (p *corsPolicy) compile(route corsRoute, maxAge int) routeCORS {
+	rc := routeCORS{path: route.Path, origins: newOriginList(route.Origins)}
+	if route.MaxAge != nil {
+		maxAge = *route.MaxAge
+	}
+	credentials := route.Credentials == nil || *route.Credentials
+	rc.cors = cors.New(cors.Options{
+		AllowOriginFunc: func(r *http.Request, origin string) bool {
+			return p.allowed(rc, r, origin)
+		},
+		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-API-Key", "X-Request-ID"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowCredentials: credentials,
+		MaxAge:           maxAge,
+	})
+	return rc
+}
+
+// route returns the policy of the first route matching path
+func (p *corsPolicy) route(path string) routeCORS {
+	for _, rc := range p.routes {
+		if prefix, ok := strings.CutSuffix(rc.path, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return rc
+			}
+		} else if path == rc.path {
+			return rc
+		}
+	}
+	return p.def
+}
+
+// allowed reports whether origin may call the route: through the route's own
+// origins, or the origins of the tenant owning the request's API key
+func (p *corsPolicy) allowed(rc routeCORS, r *http.Request, origin string) bool {
+	if rc.origins.match(origin) {
+		return true
+	}
+	if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		for _, origins := range p.tenants {
+			if origins.match(origin) {
+				return true
+			}
+		}
+		return false
+	}
+	return p.tenants[apiKeyTenant(r)].match(origin)
+}
+
+// handler applies the CORS policy of the request's route. A cross-origin
+// request with a tenant's key from an origin allowed neither for the route nor
+// for that tenant is refused before it reaches the handler, since only the
+// browser would otherwise enforce the missing CORS headers.
+func (p *corsPolicy) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := p.route(r.URL.Path)
+		origin := r.Header.Get("Origin")
+		if origin != "" && r.Method != http.MethodOptions && p.tenants[apiKeyTenant(r)] != nil && !p.allowed(rc, r, origin) {
+			sendError(w, errcode.OriginNotAllowed, fmt.Sprintf("origin %s is not allowed for this API key", origin))
+			return
+		}
+		rc.cors.Handler(next).ServeHTTP(w, r)
+	})
+}
+
+// originList matches origins, each of which may contain one * wildcard
+// (e.g. https://*.vercel.app); a lone * matches every origin
+type originList []string
+
+func newOriginList(origins []string) originList {
+	out := make(originList, len(origins))
+	for i, o := range origins {
+		out[i] = strings.ToLower(strings.TrimSpace(o))
+	}
+	return out
+}
+
+func (l originList) match(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, o := range l {
+		if o == "*" || o == origin {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(o, "*"); ok &&
+			len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/metrics"
+)
+
+// Dashboard metrics, also served by GET /api/admin/metrics
+var (
+	generationLatency = metrics.NewWindow(metrics.DefaultWindowSize)
+	generationsByDay  = metrics.NewDays()
+	codesByVersion    = expvar.NewMap("hcs_codes_by_version")
+	errorsByCode      = expvar.NewMap("hcs_errors_by_code")
+)
+
+func init() {
+	expvar.Publish("hcs_generation_latency", expvar.Func(func() any { return generationLatency.Summary() }))
+}
+
+// Dashboard limits
+const (
+	defaultDashboardDays = 30
+	maxDashboardDays     = 366
+	topErrorCount        = 10
+)
+
+// DashboardResponse aggregates the operator metrics
+type DashboardResponse struct {
+	GeneratedAt time.Time `json:"generatedAt"`
+	Uptime      string    `json:"uptime"`
+	// Source is "storage" when the generation counts come from stored
+	// records, or "process" when they were counted since the server started
+	Source            string             `json:"source"`
+	GenerationsPerDay []metrics.DayCount `json:"generationsPerDay"`
+	// CodeVersions counts issued codes by level and format version, e.g. "U7/7.0"
+	CodeVersions map[string]int64 `json:"codeVersions"`
+	// TopValidationErrors are the most frequent invalid-input (HCS-1xxx)
+	// responses since the server started
+	TopValidationErrors []ErrorCount    `json:"topValidationErrors"`
+	Latency             metrics.Summary `json:"latency"` // of the most recent generations
+}
+
+// ErrorCount is the number of responses with one error code
+type ErrorCount struct {
+	Code        errcode.Code `json:"code"`
+	Description string       `json:"description"`
+	Count       int64        `json:"count"`
+}
+
+// recordGeneration counts a successful generation and its latency
+func recordGeneration(output *hcs.OutputHCS, elapsed time.Duration) {
+	generationLatency.Observe(elapsed)
+	generationsByDay.Add(clk.Now(), 1)
+	for _, version := range codeVersions(output) {
+		codesByVersion.Add(version, 1)
+	}
+}
+
+// codeVersions names the codes of an output by level, and format version for U7
+func codeVersions(output *hcs.OutputHCS) []string {
+	var out []string
+	for _, c := range []struct{ level, code string }{{"U3", output.CodeU3}, {"U4", output.CodeU4}, {"U5", output.CodeU5}, {"U6", output.CodeU6}} {
+		if c.code != "" {
+			out = append(out, c.level)
+		}
+	}
+	if output.CodeU7 != "" {
+		out = append(out, "U7/"+hcs.U7Version(output.CodeU7))
+	}
+	for _, c := range output.LegacyCodes {
+		out = append(out, c.Level+"/"+c.Version)
+	}
+	return out
+}
+
+// handleDashboard serves the operator metrics of the last ?days=N days (default 30)
+func handleDashboard(w http.ResponseWriter, r *http.Request) {
+	days := defaultDashboardDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDashboardDays {
+			sendError(w, errcode.InvalidRequest, "days must be between 1 and 366")
+			return
+		}
+		days = n
+	}
+	response, err := dashboard(r, days)
+	if err != nil {
+		sendLookupError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// dashboard aggregates the metrics, counting generations from storage when it is enabled
+func dashboard(r *http.Request, days int) (*DashboardResponse, error) {
+	now := clk.Now().UTC()
+	response := &DashboardResponse{
+		GeneratedAt:         now,
+		Uptime:              formatDuration(clk.Now().Sub(startTime)),
+		Source:              "process",
+		TopValidationErrors: topValidationErrors(topErrorCount),
+		Latency:             generationLatency.Summary(),
+	}
+
+	if codeStore == nil {
+		response.GenerationsPerDay = generationsByDay.Last(now, days)
+		response.CodeVersions = make(map[string]int64)
+		codesByVersion.Do(func(kv expvar.KeyValue) {
+			response.CodeVersions[kv.Key] = kv.Value.(*expvar.Int).Value()
+		})
+		return response, nil
+	}
+
+	records, err := codeStore.ListAll(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	response.Source = "storage"
+	perDay := make(map[string]int64)
+	response.CodeVersions = make(map[string]int64)
+	for _, rec := range records {
+		perDay[rec.CreatedAt.UTC().Format(time.DateOnly)]++
+		if rec.Output != nil {
+			for _, version := range codeVersions(rec.Output) {
+				response.CodeVersions[version]++
+			}
+		}
+	}
+	response.GenerationsPerDay = metrics.LastDays(perDay, now, days)
+	return response, nil
+}
+
+// topValidationErrors returns the n most frequent invalid-input codes sent
+// since the server started
+func topValidationErrors(n int) []ErrorCount {
+	out := []ErrorCount{}
+	errorsByCode.Do(func(kv expvar.KeyValue) {
+		if !strings.HasPrefix(kv.Key, "HCS-1") {
+			return
+		}
+		entry := errcode.Lookup(errcode.Code(kv.Key))
+		out = append(out, ErrorCount{Code: entry.Code, Description: entry.Description, Count: kv.Value.(*expvar.Int).Value()})
+	})
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Count > out[j].Count })
+	return out[:min(n, len(out))]
+}
+
+// Grafana JSON datasource (simpod-json-datasource) protocol, so the dashboard
+// metrics can be charted without an exporter. Point the datasource at
+// /api/admin/grafana with the admin token as a bearer header.
+
+// grafanaMetrics are the targets offered by /search
+var grafanaMetrics = []string{"generations_per_day", "code_versions", "top_validation_errors", "latency_ms"}
+
+// GrafanaQuery is the part of a /query request the server reads
+type GrafanaQuery struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	Targets []struct {
+		Target string `json:"target"`
+	} `json:"targets"`
+}
+
+// grafanaSeries is a time series result: datapoints are [value, unix ms]
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// grafanaTable is a table result
+type grafanaTable struct {
+	Type    string          `json:"type"` // always "table"
+	Columns []grafanaColumn `json:"columns"`
+	Rows    [][]any         `json:"rows"`
+}
+
+type grafanaColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"` // "string" or "number"
+}
+
+// handleGrafanaTest answers the datasource connection test
+func handleGrafanaTest(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleGrafanaSearch lists the queryable metrics
+func handleGrafanaSearch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(grafanaMetrics)
+}
+
+// handleGrafanaQuery answers each target: generations per day as a time
+// series over the requested range, the others as tables
+func handleGrafanaQuery(w http.ResponseWriter, r *http.Request) {
+	var q GrafanaQuery
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		sendError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+	days := defaultDashboardDays
+	if !q.Range.From.IsZero() && q.Range.To.After(q.Range.From) {
+		days = min(int(q.Range.To.Sub(q.Range.From).Hours()/24)+1, maxDashboardDays)
+	}
+	d, err := dashboard(r, days)
+	if err != nil {
+		sendLookupError(w, err)
+		return
+	}
+
+	results := []any{}
+	for _, t := range q.Targets {
+		switch t.Target {
+		case "generations_per_day":
+			series := grafanaSeries{Target: t.Target, Datapoints: [][2]float64{}}
+			for _, day := range d.GenerationsPerDay {
+				date, _ := time.Parse(time.DateOnly, day.Date)
+				series.Datapoints = append(series.Datapoints, [2]float64{float64(day.Count), float64(date.UnixMilli())})
+			}
+			results = append(results, series)
+		case "code_versions":
+			table := grafanaTable{Type: "table", Columns: []grafanaColumn{{"version", "string"}, {"count", "number"}}, Rows: [][]any{}}
+			versions := make([]string, 0, len(d.CodeVersions))
+			for v := range d.CodeVersions {
+				versions = append(versions, v)
+			}
+			sort.Strings(versions)
+			for _, v := range versions {
+				table.Rows = append(table.Rows, []any{v, d.CodeVersions[v]})
+			}
+			results = append(results, table)
+		case "top_validation_errors":
+			table := grafanaTable{Type: "table", Columns: []grafanaColumn{{"code", "string"}, {"description", "string"}, {"count", "number"}}, Rows: [][]any{}}
+			for _, e := range d.TopValidationErrors {
+				table.Rows = append(table.Rows, []any{e.Code, e.Description, e.Count})
+			}
+			results = append(results, table)
+		case "latency_ms":
+			l := d.Latency
+			table := grafanaTable{Type: "table", Columns: []grafanaColumn{{"percentile", "string"}, {"ms", "number"}},
+				Rows: [][]any{{"p50", l.P50}, {"p90", l.P90}, {"p99", l.P99}, {"max", l.Max}}}
+			results = append(results, table)
+		default:
+			sendError(w, errcode.InvalidRequest, "unknown target "+strconv.Quote(t.Target))
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
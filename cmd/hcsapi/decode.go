@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// DecodeRequest is the body of POST /api/decode
+type DecodeRequest struct {
+	Code string `json:"code"`
+}
+
+// handleDecode returns the components of an HCS code of any level. It only
+// parses the code: use /api/verify to check its CHIP and revocation status.
+func handleDecode(w http.ResponseWriter, r *http.Request) {
+	var req DecodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+	if _, ok := codeLevel(req.Code); !ok {
+		sendError(w, errcode.InvalidCode, "code must be an HCS-U3, U4, U5, U6 or U7 code")
+		return
+	}
+	decoded, err := hcs.Decode(req.Code)
+	if err != nil {
+		sendError(w, errcode.InvalidCode, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(decoded)
+}
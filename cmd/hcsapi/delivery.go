@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"log"
+	netmail "net/mail"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/i18n"
+	"github.com/corehuman/hcs-lab-api/internal/mail"
+	"github.com/corehuman/hcs-lab-api/internal/report"
+	"github.com/corehuman/hcs-lab-api/internal/terminology"
+)
+
+// maxPendingEmails bounds the reports waiting to be sent; reports beyond it
+// are dropped
+const maxPendingEmails = 100
+
+var (
+	// mailer emails reports to the deliverTo address of generate requests;
+	// nil when HCS_SMTP_ADDR is not set
+	mailer *mail.Sender
+	// verifyURL is the link of reports, with {chip} replaced by the CHIP (HCS_VERIFY_URL)
+	verifyURL string
+
+	emailDeliveries = expvar.NewMap("hcs_email_deliveries")
+	pendingEmails   = make(chan struct{}, maxPendingEmails)
+)
+
+// newMailer returns the sender of report emails when HCS_SMTP_ADDR is set,
+// relaying as HCS_SMTP_FROM and authenticating with HCS_SMTP_USERNAME and
+// HCS_SMTP_PASSWORD when set
+func newMailer() (*mail.Sender, error) {
+	addr := getenv("HCS_SMTP_ADDR")
+	if addr == "" {
+		return nil, nil
+	}
+	s, err := mail.NewSender(addr, getenv("HCS_SMTP_FROM"))
+	if err != nil {
+		return nil, err
+	}
+	s.Username, s.Password = getenv("HCS_SMTP_USERNAME"), getenv("HCS_SMTP_PASSWORD")
+	s.Clock = clk
+	return s, nil
+}
+
+// checkDeliverTo rejects a deliverTo address before anything is generated
+func checkDeliverTo(to string) error {
+	if to == "" {
+		return nil
+	}
+	if mailer == nil {
+		return errcode.Errorf(errcode.DeliveryDisabled, "set HCS_SMTP_ADDR to enable email delivery")
+	}
+	if addr, err := netmail.ParseAddress(to); err != nil || addr.Name != "" {
+		return errcode.Errorf(errcode.InvalidRequest, "deliverTo must be a single email address")
+	}
+	return nil
+}
+
+// deliverReport emails the report of output to the deliverTo address of req,
+// without delaying the response
+func deliverReport(ctx context.Context, req *GenerateRequest, output *hcs.OutputHCS) {
+	if req.DeliverTo == "" || mailer == nil {
+		return
+	}
+	select {
+	case pendingEmails <- struct{}{}:
+	default:
+		emailDeliveries.Add("dropped", 1)
+		log.Printf("Warning: dropped the report of %s: too many pending emails", output.Chip)
+		return
+	}
+	// The request context ends with the response; delivery must not
+	go func() {
+		defer func() { <-pendingEmails }()
+		terms := cfg().selectTerminology(req.TenantID).Dictionary(i18n.Default)
+		if err := sendReport(context.WithoutCancel(ctx), req.DeliverTo, output, terms); err != nil {
+			emailDeliveries.Add("failed", 1)
+			log.Printf("Warning: failed to email the report of %s: %v", output.Chip, err)
+			return
+		}
+		emailDeliveries.Add("sent", 1)
+	}()
+}
+
+// sendReport renders the report of output with the configured templates and
+// terms, and emails it to one address
+func sendReport(ctx context.Context, to string, output *hcs.OutputHCS, terms *terminology.Dictionary) error {
+	rep, templates := report.New(output, verifyURL), cfg().reportTemplates
+	rep.Terms = terms
+	html, err := templates.HTML(rep)
+	if err != nil {
+		return err
+	}
+	text, err := templates.Text(rep)
+	if err != nil {
+		return err
+	}
+	return mailer.Send(ctx, to, rep.Subject(), text, html)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/lockout"
+)
+
+// displayAttempts counts failed display code verifications per CHIP and per
+// API key (per client address without one), so codes cannot be brute-forced
+var displayAttempts *lockout.Limiter
+
+// newDisplayAttempts reads the lockout of display code verifications from
+// HCS_DISPLAY_CODE_MAX_FAILURES, HCS_DISPLAY_CODE_WINDOW and HCS_DISPLAY_CODE_LOCKOUT
+func newDisplayAttempts() *lockout.Limiter {
+	return lockout.New(lockout.Config{
+		MaxFailures: envInt("HCS_DISPLAY_CODE_MAX_FAILURES", lockout.DefaultMaxFailures),
+		Window:      envDuration("HCS_DISPLAY_CODE_WINDOW", lockout.DefaultWindow),
+		Lockout:     envDuration("HCS_DISPLAY_CODE_LOCKOUT", lockout.DefaultLockout),
+	})
+}
+
+// displayAttemptKeys are the counters a display code verification counts against
+func displayAttemptKeys(r *http.Request, chip string) (chipKey, callerKey string) {
+	callerKey = "key:" + apiKeyFingerprint(r)
+	if r.Header.Get("X-API-Key") == "" {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		callerKey = "addr:" + host
+	}
+	return "chip:" + chip, callerKey
+}
+
+// DisplayCodeRequest is the body of POST /api/display-codes
+type DisplayCodeRequest struct {
+	Code   string `json:"code"`             // full HCS-U3, HCS-U4 or HCS-U6 code held by the person
+	Digits int    `json:"digits,omitempty"` // 6 to 8, default 6
+}
+
+// VerifyDisplayCodeRequest is the body of POST /api/display-codes/verify
+type VerifyDisplayCodeRequest struct {
+	Chip        string `json:"chip"`
+	DisplayCode string `json:"displayCode"`
+}
+
+// VerifyDisplayCodeResponse reports whether a display code is currently valid
+type VerifyDisplayCodeResponse struct {
+	Chip  string `json:"chip"`
+	Valid bool   `json:"valid"`
+}
+
+// handleDisplayCode issues a short-lived display code for a genuine code, so
+// its holder can prove live possession without revealing the code itself
+func handleDisplayCode(w http.ResponseWriter, r *http.Request) {
+	var req DisplayCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+
+	chip, err := hcs.ChipFromCode(req.Code)
+	if err != nil {
+		sendError(w, errcode.InvalidCode, err.Error())
+		return
+	}
+	rec, err := storedCode(r.Context(), apiKeyTenant(r), chip)
+	if err != nil {
+		sendLookupError(w, err)
+		return
+	}
+	if err := generator.VerifyCHIP(req.Code, recordedHardening(rec)); err != nil {
+		sendError(w, errcode.InvalidCode, err.Error())
+		return
+	}
+
+	code, err := generator.DisplayCode(chip, req.Digits)
+	if err != nil {
+		sendError(w, errcode.InvalidRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(code)
+}
+
+func handleVerifyDisplayCode(w http.ResponseWriter, r *http.Request) {
+	var req VerifyDisplayCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+	if req.Chip == "" || req.DisplayCode == "" {
+		sendError(w, errcode.InvalidRequest, "chip and displayCode are required")
+		return
+	}
+
+	chipKey, callerKey := displayAttemptKeys(r, req.Chip)
+	if locked, until := displayAttempts.Locked(clk.Now(), chipKey, callerKey); locked {
+		retry := int(math.Ceil(until.Sub(clk.Now()).Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+		sendError(w, errcode.TooManyAttempts, fmt.Sprintf("too many failed display code verifications; retry in %ds", retry))
+		return
+	}
+
+	valid, err := generator.VerifyDisplayCode(req.Chip, req.DisplayCode)
+	if err != nil {
+		sendError(w, errcode.VerificationFailed, err.Error())
+		return
+	}
+	// A success clears the CHIP's count only: a caller could otherwise reset
+	// its own by verifying a code it holds between guesses
+	if valid {
+		displayAttempts.Reset(chipKey)
+	} else {
+		displayAttempts.Fail(clk.Now(), chipKey, callerKey)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(VerifyDisplayCodeResponse{Chip: req.Chip, Valid: valid})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// SealRequest is the body of POST /api/envelopes: a generate request, plus
+// labels recorded in the signed envelope metadata
+type SealRequest struct {
+	GenerateRequest
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// OpenEnvelopeResponse reports whether an envelope is genuine, with its
+// contents when it is
+type OpenEnvelopeResponse struct {
+	Valid    bool                  `json:"valid"`
+	Output   *hcs.OutputHCS        `json:"output,omitempty"`
+	Metadata *hcs.EnvelopeMetadata `json:"metadata,omitempty"`
+}
+
+// handleSealEnvelope generates codes like POST /api/generate and returns the
+// whole output sealed in a signed envelope. Only outputs generated here are
+// sealed, so an envelope vouches for every field of its output.
+func handleSealEnvelope(w http.ResponseWriter, r *http.Request) {
+	var req SealRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+	output, ok := generateForRequest(w, r, &req.GenerateRequest)
+	if !ok {
+		return
+	}
+	validateOutput(output)
+
+	env, err := generator.Seal(output, hcs.EnvelopeMetadata{Issuer: "hcs-lab-api/" + version, Labels: req.Labels})
+	if err != nil {
+		sendCodedError(w, err, errcode.GenerationFailed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(env)
+}
+
+// handleOpenEnvelope verifies an envelope sealed by this server, under any of
+// its salt epochs
+func handleOpenEnvelope(w http.ResponseWriter, r *http.Request) {
+	var env hcs.Envelope
+	if err := json.NewDecoder(r.Body).Decode(&env); err != nil {
+		sendError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+
+	output, err := generator.Open(&env)
+	if errors.Is(err, hcs.ErrEnvelopeSignature) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(OpenEnvelopeResponse{Valid: false})
+		return
+	}
+	if err != nil {
+		sendCodedError(w, err, errcode.VerificationFailed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(OpenEnvelopeResponse{Valid: true, Output: output, Metadata: &env.Metadata})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/i18n"
+)
+
+// handleErrors serves the error code catalog, so clients can map the
+// errorCode of any error response without parsing messages. Titles and
+// descriptions are in the negotiated language and the tenant's terminology.
+func handleErrors(w http.ResponseWriter, r *http.Request) {
+	lang := w.Header().Get("Content-Language")
+	t, err := requestTerminology(r)
+	if err != nil {
+		sendCodedError(w, err, errcode.TenantForbidden)
+		return
+	}
+	terms := t.Dictionary(lang)
+	catalog := errcode.Catalog()
+	for i := range catalog {
+		catalog[i].Title = terms.Apply(i18n.Translate(lang, catalog[i].Title))
+		catalog[i].Description = terms.Apply(i18n.Translate(lang, catalog[i].Description))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(catalog)
+}
+
+// negotiateLanguage picks the response language from ?lang= or the
+// Accept-Language header and announces it in Content-Language, where
+// sendError and handleErrors read it. Only error titles and catalog texts are
+// translated; messages, codes and profile data stay as they are.
+func negotiateLanguage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+		lang := i18n.Match(r.URL.Query().Get("lang"))
+		if lang == "" {
+			lang = i18n.MatchAcceptLanguage(r.Header.Get("Accept-Language"))
+		}
+		if lang != "" {
+			w.Header().Set("Content-Language", lang)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
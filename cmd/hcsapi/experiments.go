@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// loadExperiments reads the fusion configs of HCS_FUSION_CONFIGS (a JSON file),
+// registered once the whole configuration is valid, and returns them with the
+// tenant enrollments from HCS_TENANT_FUSION_CONFIGS ("tenant=config,...").
+// A config that changes the weights of a registered ID fails the load.
+func loadExperiments(env settings) ([]hcs.FusionConfig, map[string]string, error) {
+	var configs []hcs.FusionConfig
+	if path := env.get("HCS_FUSION_CONFIGS"); path != "" {
+		var err error
+		if configs, err = hcs.ReadFusionConfigs(path); err != nil {
+			return nil, nil, err
+		}
+	}
+	staged := map[string]bool{}
+	for _, fc := range configs {
+		if err := hcs.CheckFusionConfig(fc); err != nil {
+			return nil, nil, err
+		}
+		staged[fc.ID] = true
+	}
+
+	tenantFusionConfigs := map[string]string{}
+	if v := env.get("HCS_TENANT_FUSION_CONFIGS"); v != "" {
+		for _, pair := range strings.Split(v, ",") {
+			tenant, configID, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || tenant == "" {
+				return nil, nil, fmt.Errorf("invalid HCS_TENANT_FUSION_CONFIGS entry: %q", pair)
+			}
+			if !staged[configID] {
+				if _, err := hcs.LookupFusionConfig(configID); err != nil {
+					return nil, nil, fmt.Errorf("tenant %s: %w", tenant, err)
+				}
+			}
+			tenantFusionConfigs[tenant] = configID
+		}
+	}
+	return configs, tenantFusionConfigs, nil
+}
+
+// selectFusionConfig resolves the fusion config for a request: an explicit
+// request value wins over the tenant enrollment
+func (c *config) selectFusionConfig(requested, tenantID string) string {
+	if requested != "" {
+		return requested
+	}
+	return c.tenantFusionConfigs[tenantID]
+}
@@ -0,0 +1,6 @@
+//go:build goexperiment.boringcrypto
+
+package main
+
+// BoringCrypto builds also restrict TLS to FIPS-approved settings
+import _ "crypto/tls/fipsonly"
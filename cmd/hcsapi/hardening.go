@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+// loadCHIPHardening reads the Argon2id CHIP mode from HCS_CHIP_HARDENING=argon2id,
+// with optional HCS_ARGON2_TIME, HCS_ARGON2_MEMORY_KIB and HCS_ARGON2_THREADS
+// overriding the default cost. It returns nil when hardening is off.
+func loadCHIPHardening() (*hcs.CHIPHardening, error) {
+	mode := os.Getenv("HCS_CHIP_HARDENING")
+	if mode == "" || mode == "off" {
+		return nil, nil
+	}
+	if mode != hcs.CHIPAlgorithmArgon2id {
+		return nil, fmt.Errorf("unsupported HCS_CHIP_HARDENING: %q", mode)
+	}
+
+	h := hcs.DefaultCHIPHardening()
+	for _, param := range []struct {
+		name string
+		bits int
+		set  func(uint64)
+	}{
+		{"HCS_ARGON2_TIME", 32, func(v uint64) { h.Time = uint32(v) }},
+		{"HCS_ARGON2_MEMORY_KIB", 32, func(v uint64) { h.MemoryKiB = uint32(v) }},
+		{"HCS_ARGON2_THREADS", 8, func(v uint64) { h.Threads = uint8(v) }},
+	} {
+		if v := os.Getenv(param.name); v != "" {
+			n, err := strconv.ParseUint(v, 10, param.bits)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %q", param.name, v)
+			}
+			param.set(n)
+		}
+	}
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// storedCode returns the stored record of chip in tenant, or nil when
+// storage is off, chip is unknown or not stored
+func storedCode(ctx context.Context, tenant, chip string) (*store.Record, error) {
+	if codeStore == nil || chip == "" {
+		return nil, nil
+	}
+	rec, err := codeStore.Get(ctx, tenant, chip)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	return rec, err
+}
+
+// recordedHardening returns the CHIP hardening to verify a code with: the
+// cost recorded in the metadata of its stored record, nil for plain SHA256,
+// or the generator's current cost when the code is not stored
+func recordedHardening(rec *store.Record) *hcs.CHIPHardening {
+	if rec == nil {
+		return generator.CHIPHardening()
+	}
+	if rec.Output == nil || rec.Output.Metadata == nil {
+		return nil
+	}
+	return rec.Output.Metadata.CHIPHardening
+}
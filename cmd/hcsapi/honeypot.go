@@ -0,0 +1,58 @@
+package main
+
+import (
+	"expvar"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+var honeypotHits = expvar.NewMap("hcs_honeypot_hits_by_label")
+
+// HoneypotAlert is the payload of "honeypot.triggered" webhook events
+type HoneypotAlert struct {
+	Chip       string    `json:"chip"`
+	Label      string    `json:"label,omitempty"`
+	Route      string    `json:"route"`
+	Key        string    `json:"key"` // API key fingerprint, as in anomaly alerts
+	Tenant     string    `json:"tenant,omitempty"`
+	RemoteAddr string    `json:"remoteAddr"`
+	RequestID  string    `json:"requestId,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// watchHoneypot checks the {chip} of the routes it wraps against the canaries
+func watchHoneypot(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checkHoneypot(r, chi.URLParam(r, "chip"))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkHoneypot raises an alert when chip is a canary and the request's key
+// is not allowed to use it. The response is left unchanged, so whoever holds
+// the leaked codes is not tipped off.
+func checkHoneypot(r *http.Request, chip string) {
+	hp := cfg().honeypot
+	canary, ok := hp.Match(chip)
+	if !ok {
+		return
+	}
+	key, tenant := apiKeyFingerprint(r), apiKeyTenant(r)
+	if hp.Allowed(tenant, key) {
+		return
+	}
+
+	label := canary.Label
+	if label == "" {
+		label = "unlabeled"
+	}
+	honeypotHits.Add(label, 1)
+	alert := HoneypotAlert{Chip: chip, Label: canary.Label, Route: r.Method + " " + r.URL.Path, Key: key, Tenant: tenant,
+		RemoteAddr: r.RemoteAddr, RequestID: middleware.GetReqID(r.Context()), Time: clk.Now().UTC()}
+	log.Printf("Honeypot: canary %s (%s) used by %s (tenant %q) from %s on %s", chip, label, key, tenant, alert.RemoteAddr, alert.Route)
+	sendAlert(r.Context(), "honeypot.triggered", alert)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// KeysResponse lists the published post-quantum verification keys
+type KeysResponse struct {
+	Keys []hcs.PQPublicKey `json:"keys"`
+}
+
+// loadPQSigner builds the ML-DSA signer from the hex seed in HCS_PQ_SEED or
+// the file named by HCS_PQ_SEED_FILE. It returns nil when neither is set.
+func loadPQSigner() (*hcs.MLDSASigner, error) {
+	value := os.Getenv("HCS_PQ_SEED")
+	if path := os.Getenv("HCS_PQ_SEED_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read HCS_PQ_SEED_FILE: %w", err)
+		}
+		value = strings.TrimSpace(string(data))
+	}
+	if value == "" {
+		return nil, nil
+	}
+
+	seed, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HCS_PQ_SEED hex encoding: %w", err)
+	}
+	return hcs.NewMLDSASigner(seed)
+}
+
+func handlePublicKeys(w http.ResponseWriter, r *http.Request) {
+	response := KeysResponse{Keys: []hcs.PQPublicKey{}}
+	if generator != nil { // read-only servers hold no signing key
+		if key, ok := generator.PQPublicKey(); ok {
+			response.Keys = append(response.Keys, key)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
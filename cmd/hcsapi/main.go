@@ -1,37 +1,46 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"expvar"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/features"
 	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/saltstore"
+	"github.com/corehuman/hcs-lab-api/internal/store"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
 )
 
 const version = "1.0.0-hcs-lab"
 
 var (
-	startTime = time.Now()
+	clk       clock.Clock = clock.System{} // every handler and background job reads the time here
+	startTime time.Time
 	generator *hcs.Generator
+	secrets   secretProvider = hcs.NewEnvSecretProvider() // set by loadSecretProvider; reloaded with the configuration
+	codeStore store.Store                                 // nil when storage is disabled
 )
 
 type HealthResponse struct {
-	Status  string `json:"status"`
-	Version string `json:"version"`
-	Uptime  string `json:"uptime"`
-	Secure  bool   `json:"secure"`
-}
-
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
-	Code    int    `json:"code"`
+	Status   string          `json:"status"`
+	Version  string          `json:"version"`
+	Uptime   string          `json:"uptime"`
+	Secure   bool            `json:"secure"`
+	ReadOnly bool            `json:"readOnly,omitempty"`
+	Features map[string]bool `json:"features"`
 }
 
 // GenerateRequest wraps the input profile to support both flat and nested ("hcs") payloads
@@ -40,21 +49,155 @@ type GenerateRequest struct {
 	HCS *hcs.InputProfile `json:"hcs,omitempty"`
 	// Embedded InputProfile allows flat payloads { ...InputProfile... }
 	hcs.InputProfile
+	// ValidityMonths overrides HCS_VALIDITY_MONTHS for this request (0 = never expires)
+	ValidityMonths *int `json:"validityMonths,omitempty"`
+	// SubjectID links successive generations of the same subject in storage
+	SubjectID string `json:"subjectId,omitempty"`
+	// TenantID scopes the stored record; matchmaking never crosses tenants
+	TenantID string `json:"tenantId,omitempty"`
+	// MatchOptIn adds the stored record to the tenant's matchmaking pool
+	MatchOptIn bool `json:"matchOptIn,omitempty"`
+	// FusionConfig selects a registered experimental fusion configuration
+	FusionConfig string `json:"fusionConfig,omitempty"`
+	// Trace returns the intermediate generation artifacts (requires HCS_ALLOW_TRACE=on)
+	Trace bool `json:"trace,omitempty"`
+	// PostQuantum adds a detached ML-DSA signature (requires a configured HCS_PQ_SEED)
+	PostQuantum bool `json:"postQuantum,omitempty"`
+	// AutoNormalize rescales the modal values to sum to 1, overriding HCS_MODAL_AUTO_NORMALIZE
+	AutoNormalize *bool `json:"autoNormalize,omitempty"`
+	// WesternFromBirth derives the element and modal balances from the natal chart of birthInfo
+	WesternFromBirth bool `json:"westernFromBirth,omitempty"`
+	// ReferenceDate (YYYY-MM-DD) selects the current Luck Pillar, by default today
+	ReferenceDate string `json:"referenceDate,omitempty"`
+	// Quality adds an input confidence score to the metadata (always on with HCS_QUALITY_SCORE=on)
+	Quality bool `json:"quality,omitempty"`
+	// Percentiles ranks the cognition values against the tenant's norms (always on with HCS_PERCENTILES=on)
+	Percentiles bool `json:"percentiles,omitempty"`
+	// PreviousChip links the codes to the CHIP of the subject's previous codes
+	PreviousChip string `json:"previousChip,omitempty"`
+	// Lineage links the codes to the latest stored codes of SubjectID, if any
+	Lineage bool `json:"lineage,omitempty"`
+	// DeliverTo emails the report of the codes to this address (requires HCS_SMTP_ADDR)
+	DeliverTo string `json:"deliverTo,omitempty"`
+}
+
+// setupClock applies HCS_FROZEN_TIME, which stops the clock at a fixed
+// instant for integration tests
+func setupClock() {
+	v := os.Getenv("HCS_FROZEN_TIME")
+	if v == "" {
+		return
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		log.Fatalf("Invalid HCS_FROZEN_TIME %q: %v", v, err)
+	}
+	clk = clock.NewFrozen(t)
+	log.Printf("Clock frozen at %s", t.Format(time.RFC3339))
 }
 
 func main() {
+	setupClock()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "signer" {
+		runSigner()
+		return
+	}
+	storageDSN := flag.String("storage", os.Getenv("HCS_STORAGE"), "storage DSN (memory, file:<path>, sqlite:<path>); overrides HCS_STORAGE")
+	strictSalt := flag.Bool("strict", os.Getenv("HCS_SALT_STRICT") == "on", "refuse to start when the salt differs from the one recorded in storage; overrides HCS_SALT_STRICT")
+	migrateSeal := flag.Bool("migrate-salt-seal", os.Getenv("HCS_SALT_SEAL_MIGRATE") == "on", "seal existing salts that have no seal for the secret key; overrides HCS_SALT_SEAL_MIGRATE")
+	statelessFlag := flag.Bool("stateless", os.Getenv("HCS_STATELESS") == "on", "container mode: salt and secret from the environment, no storage or file writes, JSON logs; overrides HCS_STATELESS")
+	readOnlyFlag := flag.Bool("read-only", os.Getenv("HCS_READ_ONLY") == "on", "serve lookups only, without the secret key; overrides HCS_READ_ONLY")
+	flag.Parse()
+
 	// Get port from environment
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	// Initialize HCS generator
-	var err error
-	generator, err = hcs.NewGenerator()
+	stateless = *statelessFlag
+	if stateless {
+		setupJSONLogs()
+		if err := checkStateless(*storageDSN); err != nil {
+			log.Fatalf("Invalid stateless configuration: %v", err)
+		}
+	} else if os.Getenv("HCS_LOG_LEVEL") == "debug" {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+	}
+
+	startTime = clk.Now()
+
+	// Initialize HCS generator; a read-only server has none and never holds the secret key
+	readOnly = *readOnlyFlag
+	if readOnly {
+		if err := checkReadOnly(*storageDSN); err != nil {
+			log.Fatalf("Invalid read-only configuration: %v", err)
+		}
+		log.Printf("Read-only mode: generation and mutation endpoints are disabled")
+	} else {
+		generator = newGenerator(*migrateSeal)
+	}
+
+	// Load the reloadable configuration; SIGHUP or POST /api/admin/reload refreshes it
+	c, err := loadConfig()
 	if err != nil {
-		log.Fatalf("Failed to initialize HCS generator: %v", err)
+		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	if err := c.install(); err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	go watchReloadSignal()
+
+	// Optional persistence of generated codes, behind a circuit breaker
+	storageBreaker, webhookBreaker = newBreaker("storage"), newBreaker("webhook")
+	codeStore, err = store.Open(*storageDSN)
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	if s, ok := codeStore.(*store.SQLiteStore); ok {
+		s.Clock = clk
+	}
+	if err := checkMigrations(codeStore); err != nil {
+		log.Fatalf("Storage schema is not ready: %v", err)
+	}
+	if generator != nil {
+		if err := checkSaltFingerprint(codeStore, generator, *strictSalt); err != nil {
+			log.Fatalf("Salt check failed: %v", err)
+		}
+	}
+	codeStore = store.WithBreaker(codeStore, storageBreaker)
+
+	if codeStore != nil && !readOnly {
+		if url := getenv("HCS_WEBHOOK_URL"); url != "" && cfg().flags.Enabled(features.Webhooks, "") {
+			reminderOutbox = newReminderOutbox(url)
+		}
+		if reminderOutbox != nil || store.WebhooksOf(codeStore) != nil {
+			go runExpiryReminders(codeStore, reminderOutbox)
+		}
+		go runClusterAnalysis(codeStore)
+		go runNormsRecalculation(codeStore)
+	}
+	alertOutbox = newAlertOutbox()
+	if !readOnly {
+		anomalies = newAnomalyDetector()
+		displayAttempts = newDisplayAttempts()
+		if mailer, err = newMailer(); err != nil {
+			log.Fatalf("Invalid email delivery configuration: %v", err)
+		}
+		if mailer != nil {
+			log.Printf("Email delivery of reports enabled (relay %s)", mailer.Addr)
+			if overrides := cfg().reportTemplates.Overrides; len(overrides) > 0 {
+				log.Printf("Report templates overridden by %s", strings.Join(overrides, ", "))
+			}
+		}
+		verifyURL = getenv("HCS_VERIFY_URL")
+	}
+
+	debugCapture = newDebugCapture()
 
 	// Create router
 	r := chi.NewRouter()
@@ -62,41 +205,107 @@ func main() {
 	// Middleware
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
+	r.Use(requestLogger)
 	r.Use(middleware.Recoverer)
 
-	// CORS configuration
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins: []string{
-			"http://localhost:*",
-			"https://localhost:*",
-			"http://127.0.0.1:*",
-			"https://127.0.0.1:*",
-			"https://*.vercel.app",
-			"https://vercel.app",
-		},
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"},
-		ExposedHeaders:   []string{"X-Request-ID"},
-		AllowCredentials: true,
-		MaxAge:           300, // Maximum value not ignored by any of major browsers
-	}))
+	// CORS configuration, re-read from the active configuration on every request
+	r.Use(corsMiddleware)
+	r.Use(negotiateLanguage)
 
 	// Routes
 	r.Get("/", handleRoot)
 	r.Get("/health", handleHealth)
-	r.Post("/api/generate", handleGenerate)
+	if stateless {
+		r.Get("/healthz", handleLive)
+		r.Get("/readyz", handleReady)
+	}
+	// Versioned API routes: /v1, and the deprecated unversioned /api aliases of v1
+	if err := mountAPIVersions(r); err != nil {
+		log.Fatalf("Invalid API version configuration: %v", err)
+	}
+	if token := getenv("HCS_ADMIN_TOKEN"); token != "" {
+		r.With(requireAdminToken(token)).Post("/api/admin/reload", handleAdminReload)
+		r.With(requireAdminToken(token)).Get("/api/admin/metrics", expvar.Handler().ServeHTTP)
+		r.With(requireAdminToken(token)).Get("/api/admin/dashboard", handleDashboard)
+		r.With(requireAdminToken(token)).Get("/api/admin/grafana", handleGrafanaTest)
+		r.With(requireAdminToken(token)).Get("/api/admin/grafana/", handleGrafanaTest)
+		r.With(requireAdminToken(token)).Post("/api/admin/grafana/search", handleGrafanaSearch)
+		r.With(requireAdminToken(token)).Post("/api/admin/grafana/query", handleGrafanaQuery)
+		r.With(requireAdminToken(token)).Post("/api/admin/revocations", writable(handleRevoke))
+		if debugCapture != nil {
+			r.With(requireAdminToken(token)).Get("/api/admin/recent", handleRecent)
+		}
+	}
 
 	// Start server
 	addr := fmt.Sprintf(":%s", port)
 	log.Printf("HCS Lab API v%s starting on %s", version, addr)
 	log.Printf("Environment: PORT=%s", port)
+	if hcs.FIPSMode() {
+		log.Printf("FIPS mode (%s): only approved algorithms are enabled", hcs.FIPSModule())
+	}
 
 	if err := http.ListenAndServe(addr, r); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
 
+// newGenerator creates the generator from the salt store selected by
+// HCS_SALT_STORE, the secret key or remote signer, the optional post-quantum
+// signing key and the CHIP hardening settings. migrateSeal seals salts that
+// predate the secret key instead of refusing them.
+func newGenerator(migrateSeal bool) *hcs.Generator {
+	var err error
+	if secrets, err = loadSecretProvider(); err != nil {
+		log.Fatalf("Invalid secret key configuration: %v", err)
+	}
+	genOptions := []hcs.Option{hcs.WithSecretProvider(secrets), hcs.WithClock(clk)}
+	saltProvider, err := loadSaltProvider()
+	if err != nil {
+		log.Fatalf("Invalid salt store configuration: %v", err)
+	}
+	if migrateSeal {
+		genOptions = append(genOptions, hcs.WithSaltSealMigration())
+	}
+	newSaltFile := false
+	if saltProvider != nil {
+		genOptions = append(genOptions, hcs.WithSaltProvider(saltProvider))
+	} else if files, err := hcs.SaltFiles("."); err == nil && len(files) == 0 {
+		newSaltFile = true
+	}
+	if s, err := loadSigner(); err != nil {
+		log.Fatalf("Invalid signing service configuration: %v", err)
+	} else if s != nil {
+		genOptions = append(genOptions, hcs.WithSigner(s))
+	}
+	if signer, err := loadPQSigner(); err != nil {
+		log.Fatalf("Failed to load post-quantum signing key: %v", err)
+	} else if signer != nil {
+		genOptions = append(genOptions, hcs.WithPQSigner(signer))
+	}
+	if hardening, err := loadCHIPHardening(); err != nil {
+		log.Fatalf("Invalid CHIP hardening configuration: %v", err)
+	} else if hardening != nil {
+		genOptions = append(genOptions, hcs.WithHardenedCHIP(*hardening))
+	}
+	g, err := hcs.NewGenerator(genOptions...)
+	if err != nil {
+		log.Fatalf("Failed to initialize HCS generator: %v", err)
+	}
+	switch p, _ := saltProvider.(*saltstore.Provider); {
+	case p != nil && p.Created:
+		log.Printf("Created a new salt in %s", p.Backend)
+	case p != nil:
+		log.Printf("Salt loaded from %s", p.Backend)
+	case newSaltFile:
+		// On an ephemeral filesystem this happens on every deploy, silently
+		// changing every CHIP
+		log.Print("Warning: created a new salt in .hcs_salt; unless the working directory is persistent, " +
+			"set HCS_SALT_STORE (aws or redis) or HCS_SALT so it survives redeploys")
+	}
+	return g
+}
+
 func handleRoot(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -107,13 +316,15 @@ func handleRoot(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleHealth(w http.ResponseWriter, r *http.Request) {
-	uptime := time.Since(startTime)
+	uptime := clk.Now().Sub(startTime)
 
 	response := HealthResponse{
-		Status:  "healthy",
-		Version: version,
-		Uptime:  formatDuration(uptime),
-		Secure:  true,
+		Status:   "healthy",
+		Version:  version,
+		Uptime:   formatDuration(uptime),
+		Secure:   true,
+		ReadOnly: readOnly,
+		Features: cfg().flags.Snapshot(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -124,10 +335,58 @@ func handleGenerate(w http.ResponseWriter, r *http.Request) {
 	// Parse request body, accepting both flat and nested ("hcs") profiles
 	var req GenerateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		sendError(w, http.StatusBadRequest, "Invalid JSON", err.Error())
+		sendError(w, errcode.InvalidJSON, err.Error())
 		return
 	}
+	output, ok := generateForRequest(w, r, &req)
+	if !ok {
+		return
+	}
+
+	// Send response
+	validateOutput(output)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// generateForRequest generates and stores the codes of a generate request. On
+// failure it writes the error response and returns false.
+func generateForRequest(w http.ResponseWriter, r *http.Request, req *GenerateRequest) (*hcs.OutputHCS, bool) {
+	// Bound the whole generation, including storage, by the request compute budget
+	ctx, cancel := generationContext(r)
+	defer cancel()
+
+	output, rec, err := generateCodes(ctx, r, req)
+	if err != nil {
+		// Validation, deadline, key and salt errors carry their own code
+		sendCodedError(w, err, errcode.GenerationFailed)
+		return nil, false
+	}
+	if rec != nil {
+		if err := codeStore.Save(ctx, *rec); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				sendError(w, errcode.DeadlineExceeded, "storage did not respond within the request budget")
+				return nil, false
+			}
+			storageSkipped.Add(1)
+			log.Printf("Warning: failed to persist code %s: %v", output.Chip, err)
+		}
+	}
+	publishGenerated(ctx, req, output)
+	deliverReport(ctx, req, output)
+	return output, true
+}
+
+// generationContext bounds one generation by the request compute budget
+func generationContext(r *http.Request) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(r.Context(), cfg().requestTimeout)
+	return hcs.ContextWithLogger(ctx, slog.Default().With("requestId", middleware.GetReqID(r.Context()))), cancel
+}
 
+// generateCodes generates the codes of a generate request, and the record to
+// store when storage is enabled for its tenant. Errors carry their catalog code.
+func generateCodes(ctx context.Context, r *http.Request, req *GenerateRequest) (*hcs.OutputHCS, *store.Record, error) {
 	// Select the effective input profile
 	var input hcs.InputProfile
 	if req.HCS != nil {
@@ -135,37 +394,123 @@ func handleGenerate(w http.ResponseWriter, r *http.Request) {
 	} else {
 		input = req.InputProfile
 	}
+	noteProfile(r.Context(), &input)
+
+	tenant, err := callerTenant(r, req.TenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.TenantID = tenant
+
+	c := cfg()
+	opts := &hcs.GeneratorOptions{
+		ValidityMonths: c.defaultValidityMonths,
+		FusionConfigID: c.selectFusionConfig(req.FusionConfig, req.TenantID),
+		SkipU5:         !c.flags.Enabled(features.U5, req.TenantID),
+		SkipU6:         !c.flags.Enabled(features.U6, req.TenantID),
+		SkipU7:         !c.flags.Enabled(features.U7, req.TenantID),
+
+		U7SignatureLengths: c.signatureLengths,
+		KeyDerivation:      c.keyDerivation,
+		SecondaryDigest:    c.secondaryDigest,
+		PostQuantum:        req.PostQuantum || c.postQuantumDefault,
+		ModalValidation:    c.modalValidation,
+		Quality:            req.Quality || c.qualityDefault,
+		WesternFromBirth:   req.WesternFromBirth,
+	}
+	c.transition.apply(opts, clk.Now())
+	if req.AutoNormalize != nil {
+		opts.ModalValidation.Normalize = *req.AutoNormalize
+	}
+	if req.Percentiles || c.percentilesDefault {
+		opts.Norms = c.selectNorms(req.TenantID)
+	}
+	if req.Trace {
+		if !c.allowTrace {
+			return nil, nil, errcode.Errorf(errcode.TraceDisabled, "set HCS_ALLOW_TRACE=on to enable generation traces")
+		}
+		opts.Trace = true
+	}
+	if req.ValidityMonths != nil {
+		if *req.ValidityMonths < 0 {
+			return nil, nil, errcode.Errorf(errcode.InvalidRequest, "validityMonths must not be negative")
+		}
+		opts.ValidityMonths = *req.ValidityMonths
+	}
+	if req.ReferenceDate != "" {
+		reference, err := time.Parse(time.DateOnly, req.ReferenceDate)
+		if err != nil {
+			return nil, nil, errcode.Errorf(errcode.InvalidRequest, "referenceDate must be a YYYY-MM-DD date")
+		}
+		opts.ReferenceDate = reference
+	}
+	if err := checkDeliverTo(req.DeliverTo); err != nil {
+		return nil, nil, err
+	}
+
+	// The subject's latest codes, for drift warnings and lineage
+	storing := codeStore != nil && c.flags.Enabled(features.Storage, req.TenantID)
+	var prev *store.Record
+	if storing && req.SubjectID != "" {
+		prev, _ = store.LatestForSubject(ctx, codeStore, req.TenantID, req.SubjectID)
+	}
+	opts.PreviousChip = req.PreviousChip
+	if req.Lineage && opts.PreviousChip == "" {
+		if !storing || req.SubjectID == "" {
+			return nil, nil, errcode.Errorf(errcode.InvalidRequest, "lineage needs a subjectId and storage, or a previousChip")
+		}
+		if prev != nil {
+			opts.PreviousChip = prev.Chip
+		}
+	}
 
 	// Generate HCS codes
-	output, err := generator.Generate(&input)
+	started := clk.Now()
+	output, err := generator.GenerateContext(ctx, &input, opts)
 	if err != nil {
-		// Determine if it's a validation error or internal error
-		if contains(err.Error(), "invalid") || contains(err.Error(), "must be") {
-			sendError(w, http.StatusBadRequest, "Validation error", err.Error())
-		} else {
-			sendError(w, http.StatusInternalServerError, "Generation failed", err.Error())
+		return nil, nil, err
+	}
+	recordGeneration(output, clk.Now().Sub(started))
+
+	if !storing {
+		return output, nil, nil
+	}
+	if prev != nil {
+		for _, warning := range hcs.CheckProfileDrift(&prev.Input, &input, c.driftConfig) {
+			output.Warnings = append(output.Warnings, warning.String())
 		}
-		return
 	}
+	rec := store.NewRecord(input, output, clk.Now())
+	rec.SubjectID = req.SubjectID
+	rec.TenantID = req.TenantID
+	rec.MatchOptIn = req.MatchOptIn
+	return output, &rec, nil
+}
 
-	// Send response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(output)
+// publishGenerated sends the code.generated event of a generation to the
+// webhooks of its tenant, without the trace
+func publishGenerated(ctx context.Context, req *GenerateRequest, output *hcs.OutputHCS) {
+	published := *output
+	published.Trace = nil
+	publishEvent(ctx, req.TenantID, eventCodeGenerated, GeneratedEvent{Chip: output.Chip, TenantID: req.TenantID,
+		SubjectID: req.SubjectID, Output: &published})
 }
 
-func sendError(w http.ResponseWriter, code int, error string, message string) {
-	response := ErrorResponse{
-		Error:   error,
-		Message: message,
-		Code:    code,
-	}
+// sendError writes an error response with the status and title of code
+func sendError(w http.ResponseWriter, code errcode.Code, message string) {
+	response := errcode.NewResponse(w.Header().Get("Content-Language"), code, message)
+	errorsByCode.Add(string(response.ErrorCode), 1)
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
+	w.WriteHeader(response.Code)
 	json.NewEncoder(w).Encode(response)
 }
 
+// sendCodedError writes err with the code it is tagged with, or fallback
+func sendCodedError(w http.ResponseWriter, err error, fallback errcode.Code) {
+	sendError(w, errcode.Of(err, fallback), err.Error())
+}
+
 func formatDuration(d time.Duration) string {
 	days := int(d.Hours()) / 24
 	hours := int(d.Hours()) % 24
@@ -183,7 +528,3 @@ func formatDuration(d time.Duration) string {
 	}
 	return fmt.Sprintf("%ds", seconds)
 }
-
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && s[:len(substr)] == substr || len(s) > len(substr) && contains(s[1:], substr)
-}
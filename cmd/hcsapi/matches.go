@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultMatchLimit = 10
+	maxMatchLimit     = 100
+)
+
+// Match is a ranked candidate returned by the matchmaking endpoint
+type Match struct {
+	Chip          string            `json:"chip"`
+	Score         float64           `json:"score"`
+	Compatibility hcs.Compatibility `json:"compatibility"`
+}
+
+// MatchesResponse is the body of GET /api/codes/{chip}/matches
+type MatchesResponse struct {
+	Chip    string                   `json:"chip"`
+	Weights hcs.CompatibilityWeights `json:"weights"`
+	Matches []Match                  `json:"matches"`
+}
+
+// handleCodeMatches ranks the opt-in codes of the caller's tenant by
+// compatibility with the requested code, which must belong to the same
+// tenant. Weights are taken from the element, tempo and cognitive query
+// parameters.
+func handleCodeMatches(w http.ResponseWriter, r *http.Request) {
+	if codeStore == nil {
+		sendError(w, errcode.StorageDisabled, "set HCS_STORAGE to enable matchmaking")
+		return
+	}
+
+	tenant, err := callerTenant(r, r.URL.Query().Get("tenantId"))
+	if err != nil {
+		sendCodedError(w, err, errcode.TenantForbidden)
+		return
+	}
+	weights, limit, err := parseMatchQuery(r)
+	if err != nil {
+		sendError(w, errcode.InvalidRequest, err.Error())
+		return
+	}
+
+	target, err := codeStore.Get(r.Context(), tenant, chi.URLParam(r, "chip"))
+	if errors.Is(err, store.ErrNotFound) {
+		sendError(w, errcode.NotFound, "no code stored for this CHIP")
+		return
+	}
+	if err != nil {
+		sendLookupError(w, err)
+		return
+	}
+
+	records, err := codeStore.List(r.Context(), tenant)
+	if err != nil {
+		sendLookupError(w, err)
+		return
+	}
+
+	targetProfile := hcs.NormalizeProfile(&target.Input)
+	matches := []Match{}
+	for _, rec := range records {
+		if rec.Chip == target.Chip || !rec.MatchOptIn {
+			continue
+		}
+		compat := hcs.ComputeCompatibility(targetProfile, hcs.NormalizeProfile(&rec.Input))
+		matches = append(matches, Match{
+			Chip:          rec.Chip,
+			Score:         compat.Weighted(weights),
+			Compatibility: compat,
+		})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score == matches[j].Score {
+			return matches[i].Chip < matches[j].Chip
+		}
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MatchesResponse{
+		Chip:    target.Chip,
+		Weights: weights,
+		Matches: matches,
+	})
+}
+
+// parseMatchQuery reads the optional weighting and limit query parameters
+func parseMatchQuery(r *http.Request) (hcs.CompatibilityWeights, int, error) {
+	weights := hcs.DefaultCompatibilityWeights()
+	q := r.URL.Query()
+
+	for name, dst := range map[string]*float64{
+		"element":   &weights.Element,
+		"tempo":     &weights.Tempo,
+		"cognitive": &weights.Cognitive,
+	} {
+		if v := q.Get(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return weights, 0, fmt.Errorf("invalid %s weight: %q", name, v)
+			}
+			*dst = f
+		}
+	}
+	if err := weights.Validate(); err != nil {
+		return weights, 0, err
+	}
+
+	limit := defaultMatchLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxMatchLimit {
+			return weights, 0, fmt.Errorf("limit must be between 1 and %d", maxMatchLimit)
+		}
+		limit = n
+	}
+	return weights, limit, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// MergeRequest is the body of POST /api/profiles/merge
+type MergeRequest struct {
+	Ratings []hcs.Rating     `json:"ratings"`
+	Options hcs.MergeOptions `json:"options"`
+	// SubjectID, TenantID and MatchOptIn apply to the consensus like in a generate request
+	SubjectID  string `json:"subjectId,omitempty"`
+	TenantID   string `json:"tenantId,omitempty"`
+	MatchOptIn bool   `json:"matchOptIn,omitempty"`
+}
+
+// MergeResponse is the code of the consensus profile with the dispersion of the ratings
+type MergeResponse struct {
+	Output     *hcs.OutputHCS       `json:"output"`
+	Dispersion hcs.DispersionReport `json:"dispersion"`
+}
+
+// handleMergeProfiles generates the code of the consensus of several ratings
+// of one subject (e.g. a self-assessment and peer assessments), with a report
+// of how much the raters disagree
+func handleMergeProfiles(w http.ResponseWriter, r *http.Request) {
+	var req MergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+	if max := compareMax(); len(req.Ratings) > max {
+		sendError(w, errcode.InvalidRequest, fmt.Sprintf("ratings must contain at most %d entries, got %d", max, len(req.Ratings)))
+		return
+	}
+
+	merged, err := hcs.MergeProfiles(req.Ratings, req.Options)
+	if err != nil {
+		sendCodedError(w, err, errcode.InvalidRequest)
+		return
+	}
+	output, ok := generateForRequest(w, r, &GenerateRequest{
+		InputProfile: merged.Consensus,
+		SubjectID:    req.SubjectID,
+		TenantID:     req.TenantID,
+		MatchOptIn:   req.MatchOptIn,
+	})
+	if !ok {
+		return
+	}
+	validateOutput(output)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MergeResponse{Output: output, Dispersion: merged.Dispersion})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+// runMigrate implements `hcsapi migrate up|down|status` against HCS_STORAGE or --storage
+func runMigrate(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	steps := fs.Int("steps", 1, "number of migrations to revert with down")
+	dsn := fs.String("storage", getenv("HCS_STORAGE"), "storage DSN; overrides HCS_STORAGE")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: hcsapi migrate up|down|status [--steps N] [--storage DSN]")
+		fmt.Fprintln(os.Stderr, "Applies the schema migrations of the SQL store in HCS_STORAGE.")
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	command := args[0]
+	fs.Parse(args[1:])
+
+	s, err := store.Open(*dsn)
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	m, ok := s.(store.Migrator)
+	if !ok {
+		log.Fatalf("Storage %q has no versioned schema to migrate", *dsn)
+	}
+	runner := m.Migrations()
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		done, err := runner.Up(ctx, clk.Now())
+		for _, mig := range done {
+			fmt.Printf("applied %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(done) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		if *steps < 1 {
+			log.Fatal("--steps must be at least 1")
+		}
+		done, err := runner.Down(ctx, *steps)
+		for _, mig := range done {
+			fmt.Printf("reverted %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(done) == 0 {
+			fmt.Println("no applied migrations to revert")
+		}
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, st := range statuses {
+			state := "pending"
+			if st.Applied {
+				state = "applied " + st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", st.Version, st.Name, state)
+		}
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+// checkMigrations runs at startup for SQL stores. With HCS_MIGRATE_ON_BOOT=on
+// pending migrations are applied (never reverted, and never by a read-only
+// server); otherwise the server refuses to start on an outdated schema.
+func checkMigrations(s store.Store) error {
+	m, ok := s.(store.Migrator)
+	if !ok {
+		return nil
+	}
+	runner := m.Migrations()
+	ctx := context.Background()
+
+	if getenv("HCS_MIGRATE_ON_BOOT") == "on" && !readOnly {
+		done, err := runner.Up(ctx, clk.Now())
+		for _, mig := range done {
+			log.Printf("Applied migration %04d_%s", mig.Version, mig.Name)
+		}
+		return err
+	}
+
+	pending, err := runner.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		names := make([]string, len(pending))
+		for i, mig := range pending {
+			names[i] = fmt.Sprintf("%04d_%s", mig.Version, mig.Name)
+		}
+		return fmt.Errorf("%d pending migrations (%s); run `hcsapi migrate up` or set HCS_MIGRATE_ON_BOOT=on", len(pending), strings.Join(names, ", "))
+	}
+	return nil
+}
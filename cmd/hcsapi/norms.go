@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+const (
+	// normsReference ranks against the reference norms (HCS_NORMS_FILE or the built-in ones)
+	normsReference = "reference"
+	// normsTenant ranks against norms recalculated from the tenant's stored profiles
+	normsTenant = "tenant"
+
+	defaultNormsMinSample = 100
+)
+
+// tenantNorms selects the norms cognition values are ranked against
+type tenantNorms struct {
+	reference *hcs.Norms
+	fallback  string            // source of tenants not listed
+	tenants   map[string]string // source by tenant
+	// minSample is the fewest stored profiles tenant norms are computed from;
+	// smaller tenants are ranked against the reference norms
+	minSample int
+}
+
+var (
+	storedNormsMu sync.RWMutex
+	storedNorms   = map[string]*hcs.Norms{} // by tenant, from the latest recalculation
+)
+
+// loadNorms reads the reference norms of HCS_NORMS_FILE, the default source
+// of HCS_NORMS and the tenant sources of HCS_TENANT_NORMS (tenant=source
+// entries), where a source is reference or tenant
+func loadNorms(env settings) (*tenantNorms, error) {
+	n := &tenantNorms{reference: hcs.ReferenceNorms(), tenants: map[string]string{}, minSample: defaultNormsMinSample}
+	if path := env.get("HCS_NORMS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read HCS_NORMS_FILE: %w", err)
+		}
+		var norms hcs.Norms
+		if err := json.Unmarshal(data, &norms); err != nil {
+			return nil, fmt.Errorf("failed to parse HCS_NORMS_FILE %s: %w", path, err)
+		}
+		if err := norms.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid HCS_NORMS_FILE: %w", err)
+		}
+		n.reference = &norms
+	}
+
+	var err error
+	if n.fallback, err = parseNormsSource(env.get("HCS_NORMS")); err != nil {
+		return nil, fmt.Errorf("invalid HCS_NORMS: %w", err)
+	}
+	for _, pair := range splitList(env.get("HCS_TENANT_NORMS")) {
+		tenant, source, ok := strings.Cut(pair, "=")
+		if !ok || tenant == "" {
+			return nil, fmt.Errorf("invalid HCS_TENANT_NORMS entry: %q", pair)
+		}
+		if n.tenants[tenant], err = parseNormsSource(source); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+	}
+	if v := env.get("HCS_NORMS_MIN_SAMPLE"); v != "" {
+		if n.minSample, err = strconv.Atoi(v); err != nil || n.minSample < 2 {
+			return nil, fmt.Errorf("invalid HCS_NORMS_MIN_SAMPLE: %q", v)
+		}
+	}
+	return n, nil
+}
+
+func parseNormsSource(source string) (string, error) {
+	switch source {
+	case "", normsReference:
+		return normsReference, nil
+	case normsTenant:
+		return normsTenant, nil
+	}
+	return "", fmt.Errorf("unknown norms source %q (expected %s or %s)", source, normsReference, normsTenant)
+}
+
+// selectNorms returns the norms of a tenant: its recalculated norms when it
+// uses them and has enough stored profiles, the reference norms otherwise
+func (c *config) selectNorms(tenantID string) *hcs.Norms {
+	source, ok := c.norms.tenants[tenantID]
+	if !ok {
+		source = c.norms.fallback
+	}
+	if source == normsTenant {
+		storedNormsMu.RLock()
+		norms := storedNorms[tenantID]
+		storedNormsMu.RUnlock()
+		if norms != nil && norms.Sample >= c.norms.minSample {
+			return norms
+		}
+	}
+	return c.norms.reference
+}
+
+// runNormsRecalculation periodically recalculates the norms of every tenant
+// from its stored profiles
+func runNormsRecalculation(s store.Store) {
+	interval := envDuration("HCS_NORMS_INTERVAL", 24*time.Hour)
+	log.Printf("Norms recalculation enabled (interval=%s)", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := recalculateNorms(context.Background(), s); err != nil {
+			log.Printf("Warning: norms recalculation failed: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+func recalculateNorms(ctx context.Context, s store.Store) error {
+	records, err := s.ListAll(ctx)
+	if err != nil {
+		return err
+	}
+	byTenant := map[string][]hcs.CognitionProfile{}
+	for _, rec := range records {
+		byTenant[rec.TenantID] = append(byTenant[rec.TenantID], rec.Input.Cognition)
+	}
+
+	computed := make(map[string]*hcs.Norms, len(byTenant))
+	now := clk.Now().UTC().Format(time.RFC3339)
+	for tenant, profiles := range byTenant {
+		if len(profiles) < 2 {
+			continue
+		}
+		id := "tenant:" + tenant
+		if tenant == "" {
+			id = "stored" // profiles stored without a tenant
+		}
+		norms, err := hcs.NormsFromProfiles(id, profiles)
+		if err != nil {
+			return fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+		norms.ComputedAt = now
+		computed[tenant] = norms
+	}
+
+	storedNormsMu.Lock()
+	storedNorms = computed
+	storedNormsMu.Unlock()
+	return nil
+}
+
+// handleNorms serves the norms of the caller's tenant
+func handleNorms(w http.ResponseWriter, r *http.Request) {
+	tenant, err := callerTenant(r, r.URL.Query().Get("tenantId"))
+	if err != nil {
+		sendCodedError(w, err, errcode.TenantForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cfg().selectNorms(tenant))
+}
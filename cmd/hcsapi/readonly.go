@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+)
+
+// readOnly is set at startup by --read-only or HCS_READ_ONLY=on. A read-only
+// server, such as a public verification gateway in front of a replica, serves
+// lookups from storage without ever holding the secret key: there is no
+// generator, nothing is written to storage and no background job runs.
+// Endpoints that generate, need the key or depend on those jobs answer 403.
+var readOnly bool
+
+// secretVariables hold key material a read-only server must not be given
+var secretVariables = []string{"HCS_SECRET_KEY", "HCS_SECRET_KEY_FILE", "HCS_SECRET_KEYS", "HCS_SECRET_KEYS_FILE", "HCS_SECRET_SOURCE", "HCS_PQ_SEED", "HCS_PQ_SEED_FILE"}
+
+// checkReadOnly refuses a read-only setup that holds key material or has no
+// storage to serve from
+func checkReadOnly(storageDSN string) error {
+	for _, name := range secretVariables {
+		if os.Getenv(name) != "" {
+			return fmt.Errorf("%s must not be set in read-only mode", name)
+		}
+	}
+	if storageDSN == "" {
+		return fmt.Errorf("read-only mode requires storage (HCS_STORAGE or --storage)")
+	}
+	return nil
+}
+
+// writable returns h, or a handler rejecting the request in read-only mode
+func writable(h http.HandlerFunc) http.HandlerFunc {
+	if !readOnly {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		sendError(w, errcode.ReadOnly, "this server is read-only; generation and mutation endpoints are disabled")
+	}
+}
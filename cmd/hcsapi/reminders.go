@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/store"
+	"github.com/corehuman/hcs-lab-api/internal/webhook"
+)
+
+// ExpiryReminder is the payload of "code.expiring" webhook events
+type ExpiryReminder struct {
+	Chip       string    `json:"chip"`
+	ValidUntil time.Time `json:"validUntil"`
+	Stale      bool      `json:"stale"`
+}
+
+// newReminderOutbox returns the outbox delivering reminder events to url
+// through the webhook breaker
+func newReminderOutbox(url string) *webhook.Outbox {
+	notifier := webhook.NewNotifier(url)
+	notifier.Secret = getenv("HCS_WEBHOOK_SECRET")
+	notifier.Clock = clk
+	return webhook.NewOutbox(notifier, webhookBreaker)
+}
+
+// runExpiryReminders periodically emits a webhook for every stored code that
+// approaches its validUntil date, to HCS_WEBHOOK_URL through outbox (nil when
+// unset) and to its tenant's webhooks. Each code is reminded at most once.
+func runExpiryReminders(s store.Store, outbox *webhook.Outbox) {
+	interval := envDuration("HCS_REMINDER_INTERVAL", time.Hour)
+	window := envDuration("HCS_REMINDER_WINDOW", 30*24*time.Hour)
+
+	log.Printf("Expiry reminders enabled (interval=%s, window=%s)", interval, window)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		sendExpiryReminders(context.Background(), s, outbox, window)
+	}
+}
+
+// sendExpiryReminders hands a reminder for every due code to the outbox and
+// the tenant webhooks. A queued reminder counts as sent; it is delivered once
+// the endpoint recovers.
+func sendExpiryReminders(ctx context.Context, s store.Store, outbox *webhook.Outbox, window time.Duration) {
+	if outbox != nil {
+		outbox.Flush(ctx)
+	}
+	now := clk.Now().UTC()
+	due, err := store.ExpiringRecords(ctx, s, now, window)
+	if err != nil {
+		log.Printf("Warning: expiry reminder scan failed: %v", err)
+		return
+	}
+
+	var sent []store.Record
+	for _, rec := range due {
+		reminder := ExpiryReminder{
+			Chip:       rec.Chip,
+			ValidUntil: rec.ValidUntil,
+			Stale:      !now.Before(rec.ValidUntil),
+		}
+		subscribed := publishEvent(ctx, rec.TenantID, eventCodeExpiring, reminder)
+		if outbox != nil {
+			if err := outbox.Send(ctx, eventCodeExpiring, reminder); err != nil {
+				log.Printf("Warning: expiry reminder for %s failed: %v", rec.Chip, err)
+				continue
+			}
+		} else if subscribed == 0 {
+			// Nobody to remind: the code stays due until a webhook is registered
+			continue
+		}
+		rec.ReminderSentAt = now
+		sent = append(sent, rec)
+	}
+	for i, err := range store.SaveBatch(ctx, s, sent, store.ConflictUpsert, cfg().storeChunkSize) {
+		if err != nil {
+			log.Printf("Warning: failed to record reminder for %s: %v", sent[i].Chip, err)
+		}
+	}
+}
+
+// envDuration parses a Go duration from the environment, falling back to def
+func envDuration(name string, def time.Duration) time.Duration {
+	v := getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("Warning: invalid %s %q, using %s", name, v, def)
+		return def
+	}
+	return d
+}
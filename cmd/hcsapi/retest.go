@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// RetestResponse is the body of GET /api/subjects/{subjectID}/retest
+type RetestResponse struct {
+	SubjectID         string    `json:"subjectId"`
+	PreviousCreatedAt time.Time `json:"previousCreatedAt"`
+	CurrentCreatedAt  time.Time `json:"currentCreatedAt"`
+	IntervalDays      int       `json:"intervalDays"`
+	hcs.RetestReport
+}
+
+// handleRetest compares two stored generations of a subject. By default the
+// first generation is compared with the latest; ?from= and ?to= select them
+// by CHIP. Only records of the caller's tenant are considered.
+func handleRetest(w http.ResponseWriter, r *http.Request) {
+	if codeStore == nil {
+		sendError(w, errcode.StorageDisabled, "set HCS_STORAGE to enable retest reports")
+		return
+	}
+	tenant, err := callerTenant(r, r.URL.Query().Get("tenantId"))
+	if err != nil {
+		sendCodedError(w, err, errcode.TenantForbidden)
+		return
+	}
+
+	records, err := codeStore.List(r.Context(), tenant)
+	if err != nil {
+		sendLookupError(w, err)
+		return
+	}
+
+	subjectID := chi.URLParam(r, "subjectID")
+	var generations []store.Record
+	for _, rec := range records {
+		if rec.SubjectID == subjectID {
+			generations = append(generations, rec)
+		}
+	}
+	if len(generations) < 2 {
+		sendError(w, errcode.NotFound, "fewer than two generations are stored for this subject")
+		return
+	}
+
+	prev, curr := &generations[0], &generations[len(generations)-1]
+	if chip := r.URL.Query().Get("from"); chip != "" {
+		if prev = findGeneration(generations, chip); prev == nil {
+			sendError(w, errcode.NotFound, "no generation of this subject has CHIP "+chip)
+			return
+		}
+	}
+	if chip := r.URL.Query().Get("to"); chip != "" {
+		if curr = findGeneration(generations, chip); curr == nil {
+			sendError(w, errcode.NotFound, "no generation of this subject has CHIP "+chip)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RetestResponse{
+		SubjectID:         subjectID,
+		PreviousCreatedAt: prev.CreatedAt,
+		CurrentCreatedAt:  curr.CreatedAt,
+		IntervalDays:      int(curr.CreatedAt.Sub(prev.CreatedAt).Hours() / 24),
+		RetestReport:      hcs.CompareRetest(prev.Output, curr.Output),
+	})
+}
+
+// findGeneration returns the generation with the given CHIP, or nil
+func findGeneration(generations []store.Record, chip string) *store.Record {
+	for i := range generations {
+		if generations[i].Chip == chip {
+			return &generations[i]
+		}
+	}
+	return nil
+}
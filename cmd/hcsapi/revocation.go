@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+// RevokeRequest is the body of POST /api/admin/revocations. The CHIP may be
+// given directly or through an HCS-U3, HCS-U4 or HCS-U6 code carrying it.
+type RevokeRequest struct {
+	Chip   string `json:"chip,omitempty"`
+	Code   string `json:"code,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// VerifyRequest is the body of POST /api/verify. U5 and U7 codes do not carry
+// the CHIP, so it must be given alongside them for the revocation check, or
+// be computed from the profile.
+type VerifyRequest struct {
+	Code string `json:"code"`
+	Chip string `json:"chip,omitempty"`
+	// Profile is the input profile the code was generated from, to check the
+	// CHIP or signatures the server produces for it against the code
+	Profile *hcs.InputProfile `json:"profile,omitempty"`
+	// FusionConfig, TenantID, AutoNormalize and WesternFromBirth select the
+	// options the code was generated with, as in a generate request
+	FusionConfig     string `json:"fusionConfig,omitempty"`
+	TenantID         string `json:"tenantId,omitempty"`
+	AutoNormalize    *bool  `json:"autoNormalize,omitempty"`
+	WesternFromBirth bool   `json:"westernFromBirth,omitempty"`
+	// Engine selects the BaZi engine of codes with birth info issued under an
+	// earlier engine (empty uses the current one)
+	Engine string `json:"engine,omitempty"`
+}
+
+// VerifyResponse reports what the server can establish about a code
+type VerifyResponse struct {
+	Level     string `json:"level"` // U3, U4, U5, U6 or U7
+	Chip      string `json:"chip,omitempty"`
+	SaltEpoch int    `json:"saltEpoch"`
+	// ChipValid reports whether the CHIP of a U3, U4 or U6 code matches the
+	// profile it carries, under the salt of its epoch
+	ChipValid *bool `json:"chipValid,omitempty"`
+	// RevocationChecked is false when storage is disabled or no CHIP is known
+	RevocationChecked bool              `json:"revocationChecked"`
+	Revoked           bool              `json:"revoked"`
+	Revocation        *store.Revocation `json:"revocation,omitempty"`
+	// ValidUntil and Stale report the expiry of a stored code of the CHIP in
+	// the caller's tenant
+	ValidUntil string `json:"validUntil,omitempty"`
+	Stale      bool   `json:"stale,omitempty"`
+	// Profile reports whether the code is the one the server produces for the
+	// profile of the request, with its current secret key
+	Profile *hcs.ProfileVerification `json:"profile,omitempty"`
+}
+
+// revocations returns the revocation list of the store, writing an error
+// response when there is none
+func revocations(w http.ResponseWriter) store.Revocations {
+	revs := store.RevocationsOf(codeStore)
+	if revs == nil {
+		sendError(w, errcode.StorageDisabled, "set HCS_STORAGE to enable revocations")
+	}
+	return revs
+}
+
+// handleRevoke adds a CHIP to the revocation list. Revoking again replaces
+// the reason and time.
+func handleRevoke(w http.ResponseWriter, r *http.Request) {
+	var req RevokeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+	chip, ok := requestChip(w, req.Chip, req.Code)
+	if !ok {
+		return
+	}
+	if chip == "" {
+		sendError(w, errcode.InvalidRequest, "chip, or an HCS-U3, HCS-U4 or HCS-U6 code, is required")
+		return
+	}
+	revs := revocations(w)
+	if revs == nil {
+		return
+	}
+
+	rev := store.Revocation{Chip: chip, Reason: req.Reason, RevokedAt: clk.Now().UTC().Truncate(time.Second)}
+	if err := revs.Revoke(r.Context(), rev); err != nil {
+		sendLookupError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rev)
+}
+
+// handleRevocationList serves every revocation as a signed list, for
+// verifiers that check codes offline
+func handleRevocationList(w http.ResponseWriter, r *http.Request) {
+	revs := revocations(w)
+	if revs == nil {
+		return
+	}
+	stored, err := revs.ListRevocations(r.Context())
+	if err != nil {
+		sendLookupError(w, err)
+		return
+	}
+
+	entries := make([]hcs.RevokedCHIP, 0, len(stored))
+	for _, rev := range stored {
+		entries = append(entries, hcs.RevokedCHIP{Chip: rev.Chip, Reason: rev.Reason, RevokedAt: rev.RevokedAt.UTC().Format(time.RFC3339)})
+	}
+	list, err := generator.SignRevocationList(entries, "hcs-lab-api/"+version)
+	if err != nil {
+		sendCodedError(w, err, errcode.Internal)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// handleVerify checks the CHIP of a code against the profile it carries, or
+// the CHIP and signatures of any code against a given profile, and whether
+// the CHIP was revoked
+func handleVerify(w http.ResponseWriter, r *http.Request) {
+	var req VerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+	level, ok := codeLevel(req.Code)
+	if !ok {
+		sendError(w, errcode.InvalidCode, "code must be an HCS-U3, U4, U5, U6 or U7 code")
+		return
+	}
+	epoch, err := hcs.ParseSaltEpoch(req.Code)
+	if err != nil {
+		sendError(w, errcode.InvalidCode, err.Error())
+		return
+	}
+	chip, ok := requestChip(w, req.Chip, req.Code)
+	if !ok {
+		return
+	}
+	if req.Profile != nil && generator == nil {
+		sendError(w, errcode.ReadOnly, "read-only servers hold no secret key to verify codes against a profile")
+		return
+	}
+	tenant, err := callerTenant(r, req.TenantID)
+	if err != nil {
+		sendCodedError(w, err, errcode.TenantForbidden)
+		return
+	}
+
+	rec, err := storedCode(r.Context(), tenant, chip)
+	if err != nil {
+		sendLookupError(w, err)
+		return
+	}
+
+	response := VerifyResponse{Level: level, Chip: chip, SaltEpoch: epoch}
+	if req.Profile != nil {
+		c := cfg()
+		opts := &hcs.GeneratorOptions{
+			FusionConfigID:   c.selectFusionConfig(req.FusionConfig, tenant),
+			ModalValidation:  c.modalValidation,
+			WesternFromBirth: req.WesternFromBirth,
+			EngineVersion:    req.Engine,
+		}
+		if req.AutoNormalize != nil {
+			opts.ModalValidation.Normalize = *req.AutoNormalize
+		}
+		ctx, cancel := generationContext(r)
+		defer cancel()
+		v, err := generator.VerifyProfile(ctx, req.Code, req.Profile, recordedHardening(rec), opts)
+		if err != nil {
+			sendCodedError(w, err, errcode.VerificationFailed)
+			return
+		}
+		response.Profile = v
+		if chip == "" && v.Valid {
+			chip = v.Chip
+			response.Chip = chip
+			if rec, err = storedCode(r.Context(), tenant, chip); err != nil {
+				sendLookupError(w, err)
+				return
+			}
+		}
+	}
+	checkHoneypot(r, chip)
+	if chipLevels[level] && generator != nil { // read-only servers hold no salt
+		valid := generator.VerifyCHIP(req.Code, recordedHardening(rec)) == nil
+		response.ChipValid = &valid
+	}
+	if revs := store.RevocationsOf(codeStore); revs != nil && chip != "" {
+		rev, err := revs.GetRevocation(r.Context(), chip)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+		case err != nil:
+			sendLookupError(w, err)
+			return
+		default:
+			response.Revoked, response.Revocation = true, rev
+		}
+		response.RevocationChecked = true
+	}
+	if rec != nil && !rec.ValidUntil.IsZero() {
+		response.ValidUntil = rec.ValidUntil.Format(time.RFC3339)
+		response.Stale = hcs.IsStale(rec.Output.Metadata, clk.Now())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// requestChip returns the CHIP named by a request, given directly, through a
+// code of one of chipLevels, or both as long as they agree. Other codes do
+// not carry it.
+// On failure it writes the error response and returns false.
+func requestChip(w http.ResponseWriter, chip, code string) (string, bool) {
+	if chip != "" && !hcs.IsCHIP(chip) {
+		sendError(w, errcode.InvalidRequest, "chip must be 12 lowercase hex characters")
+		return "", false
+	}
+	if level, _ := codeLevel(code); !chipLevels[level] {
+		return chip, true
+	}
+	carried, err := hcs.ChipFromCode(code)
+	if err != nil {
+		sendError(w, errcode.InvalidCode, err.Error())
+		return "", false
+	}
+	if chip != "" && chip != carried {
+		sendError(w, errcode.InvalidRequest, fmt.Sprintf("chip %s does not match the CHIP of the code, %s", chip, carried))
+		return "", false
+	}
+	return carried, true
+}
+
+// chipLevels are the code levels that carry the CHIP
+var chipLevels = map[string]bool{"U3": true, "U4": true, "U6": true}
+
+// codeLevel returns the level of an HCS code from its prefix
+func codeLevel(code string) (string, bool) {
+	for _, level := range []string{"U3", "U4", "U5", "U6", "U7"} {
+		if strings.HasPrefix(code, "HCS-"+level+"|") {
+			return level, true
+		}
+	}
+	return "", false
+}
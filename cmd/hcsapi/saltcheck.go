@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"sort"
+	"strings"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+// saltFingerprintKey is the storage metadata key holding the fingerprint of
+// every salt epoch seen at the last start
+const saltFingerprintKey = "salt_fingerprints"
+
+// checkSaltFingerprint compares the salts of g with the fingerprints recorded
+// in storage at the last start. A changed or missing epoch means the salt
+// directory was lost or replaced, typically an ephemeral disk after a redeploy,
+// and no CHIP issued before verifies any more. It is logged loudly, or refused
+// with strict; otherwise the current fingerprints are recorded. New epochs from
+// rotations are expected. Without metadata storage there is nothing to compare.
+func checkSaltFingerprint(s store.Store, g *hcs.Generator, strict bool) error {
+	meta, ok := s.(store.Metadata)
+	if !ok {
+		if strict {
+			log.Printf("Warning: salt changes cannot be detected without storage")
+		}
+		return nil
+	}
+	ctx := context.Background()
+
+	var recorded map[int]string
+	value, err := meta.GetMeta(ctx, saltFingerprintKey)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+	case err != nil:
+		return fmt.Errorf("failed to read the recorded salt fingerprints: %w", err)
+	default:
+		if err := json.Unmarshal([]byte(value), &recorded); err != nil {
+			return fmt.Errorf("invalid recorded salt fingerprints: %w", err)
+		}
+	}
+
+	current := g.SaltFingerprints()
+	if changes := saltChanges(recorded, current); len(changes) > 0 {
+		msg := fmt.Sprintf("the salt differs from the one last used with this storage (%s): codes issued under it no longer verify. "+
+			"Restore the salt directory (hcsgen admin restore-salt) unless the change is intended", strings.Join(changes, "; "))
+		if strict {
+			return errors.New(msg)
+		}
+		log.Printf("WARNING: %s", msg)
+	}
+
+	if maps.Equal(recorded, current) {
+		return nil
+	}
+	data, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	return meta.SetMeta(ctx, saltFingerprintKey, string(data))
+}
+
+// saltChanges describes the recorded epochs whose salt is missing or different
+func saltChanges(recorded, current map[int]string) []string {
+	epochs := make([]int, 0, len(recorded))
+	for epoch := range recorded {
+		epochs = append(epochs, epoch)
+	}
+	sort.Ints(epochs)
+
+	var changes []string
+	for _, epoch := range epochs {
+		fingerprint, ok := current[epoch]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("epoch %d missing, was %s", epoch, recorded[epoch]))
+		case fingerprint != recorded[epoch]:
+			changes = append(changes, fmt.Sprintf("epoch %d is %s, was %s", epoch, fingerprint, recorded[epoch]))
+		}
+	}
+	return changes
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/saltstore"
+)
+
+// saltStore returns where the salt is kept, from HCS_SALT_STORE: file
+// (.hcs_salt in the working directory), env (HCS_SALT or HCS_SALT_FILE), aws
+// (AWS Secrets Manager) or redis. The default is env when HCS_SALT or
+// HCS_SALT_FILE is set, and file otherwise.
+func saltStore() string {
+	if store := os.Getenv("HCS_SALT_STORE"); store != "" {
+		return store
+	}
+	if os.Getenv("HCS_SALT") != "" || os.Getenv("HCS_SALT_FILE") != "" {
+		return "env"
+	}
+	return "file"
+}
+
+// loadSaltProvider returns the provider of the salt store; nil for the salt
+// directory, which the generator seals with the secret key
+func loadSaltProvider() (hcs.SaltProvider, error) {
+	switch store := saltStore(); store {
+	case "file":
+		return nil, nil
+	case "env":
+		return hcs.EnvSaltProvider{}, nil
+	case "aws":
+		region, err := awsRegion("HCS_SALT_STORE")
+		if err != nil {
+			return nil, err
+		}
+		if err := requireEnv("HCS_SALT_STORE", "HCS_AWS_SALT_ID", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"); err != nil {
+			return nil, err
+		}
+		return &saltstore.Provider{Backend: &saltstore.AWS{
+			Region:          region,
+			SecretID:        os.Getenv("HCS_AWS_SALT_ID"),
+			KMSKeyID:        os.Getenv("HCS_AWS_SALT_KMS_KEY"),
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			Clock:           clk,
+		}}, nil
+	case "redis":
+		if err := requireEnv("HCS_SALT_STORE", "HCS_REDIS_URL"); err != nil {
+			return nil, err
+		}
+		r, err := saltstore.ParseRedisURL(os.Getenv("HCS_REDIS_URL"), os.Getenv("HCS_REDIS_SALT_KEY"))
+		if err != nil {
+			return nil, err
+		}
+		return &saltstore.Provider{Backend: r}, nil
+	default:
+		return nil, fmt.Errorf("unsupported HCS_SALT_STORE: %q", store)
+	}
+}
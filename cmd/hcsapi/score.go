@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/scoring"
+)
+
+// ScoreRequest is the body of POST /api/score
+type ScoreRequest struct {
+	Answers map[string]int `json:"answers"` // item ID to a value from 1 to the bank's scale
+	// BankVersion selects the item bank the answers were given to; rescoring
+	// old responses should pass the bankVersion they were scored with
+	BankVersion string `json:"bankVersion,omitempty"`
+	// TenantID selects the tenant's frozen bank when no version is given
+	TenantID string `json:"tenantId,omitempty"`
+}
+
+// ItemBankInfo describes one registered item bank
+type ItemBankInfo struct {
+	Version string `json:"version"`
+	Items   int    `json:"items"`
+	Default bool   `json:"default"`
+}
+
+// loadItemBanks reads the item banks of the *.json files in HCS_ITEM_BANK_DIR,
+// registered once the whole configuration is valid, and returns them with the
+// tenant pins from HCS_TENANT_ITEM_BANKS ("tenant=version,...")
+func loadItemBanks(env settings) ([]*scoring.Bank, map[string]string, error) {
+	var banks []*scoring.Bank
+	if dir := env.get("HCS_ITEM_BANK_DIR"); dir != "" {
+		var err error
+		if banks, err = scoring.ReadBanks(dir); err != nil {
+			return nil, nil, err
+		}
+	}
+	staged := map[string]bool{}
+	for _, b := range banks {
+		staged[b.Version] = true
+	}
+
+	tenantItemBanks := map[string]string{}
+	for _, pair := range splitList(env.get("HCS_TENANT_ITEM_BANKS")) {
+		tenant, version, ok := strings.Cut(pair, "=")
+		if !ok || tenant == "" {
+			return nil, nil, fmt.Errorf("invalid HCS_TENANT_ITEM_BANKS entry: %q", pair)
+		}
+		if !staged[version] {
+			if _, err := scoring.LookupBank(version); err != nil {
+				return nil, nil, fmt.Errorf("tenant %s: %w", tenant, err)
+			}
+		}
+		tenantItemBanks[tenant] = version
+	}
+	return banks, tenantItemBanks, nil
+}
+
+// selectItemBank resolves the item bank for a request: an explicit version
+// wins over the tenant's frozen version, which wins over the default
+func (c *config) selectItemBank(requested, tenantID string) (*scoring.Bank, error) {
+	if requested == "" {
+		requested = c.tenantItemBanks[tenantID]
+	}
+	return scoring.LookupBank(requested)
+}
+
+// handleItemBanks lists the registered item bank versions
+func handleItemBanks(w http.ResponseWriter, r *http.Request) {
+	var infos []ItemBankInfo
+	for _, version := range scoring.BankVersions() {
+		bank, err := scoring.LookupBank(version)
+		if err != nil {
+			continue
+		}
+		infos = append(infos, ItemBankInfo{Version: version, Items: len(bank.Items), Default: version == scoring.DefaultBankVersion})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(infos)
+}
+
+// handleScoreItems serves an item bank, so clients can render the
+// questionnaire. ?version= and ?tenantId= select the bank as for scoring.
+func handleScoreItems(w http.ResponseWriter, r *http.Request) {
+	bank, err := cfg().selectItemBank(r.URL.Query().Get("version"), r.URL.Query().Get("tenantId"))
+	if err != nil {
+		sendError(w, errcode.NotFound, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bank)
+}
+
+// handleScore converts questionnaire answers into the modal, cognition and
+// interaction values of an input profile
+func handleScore(w http.ResponseWriter, r *http.Request) {
+	var req ScoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+
+	bank, err := cfg().selectItemBank(req.BankVersion, req.TenantID)
+	if err != nil {
+		sendError(w, errcode.InvalidRequest, err.Error())
+		return
+	}
+	result, err := bank.Score(req.Answers)
+	if err != nil {
+		sendError(w, errcode.InvalidRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
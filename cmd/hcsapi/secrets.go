@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/secretstore"
+)
+
+// secretProvider is a SecretProvider whose key can be reloaded on demand
+type secretProvider interface {
+	hcs.SecretProvider
+	Reload() ([]byte, error)
+}
+
+// loadSecretProvider selects where the secret key comes from with
+// HCS_SECRET_SOURCE: env (the default, HCS_SECRET_KEY or HCS_SECRET_KEY_FILE,
+// and the rotated keys of HCS_SECRET_KEYS), vault, aws or gcp. Keys from a
+// secret manager are cached for HCS_SECRET_TTL (default 5m) and refreshed in
+// the background; they sign as the legacy key.
+func loadSecretProvider() (secretProvider, error) {
+	var fetcher secretstore.Fetcher
+	switch source := os.Getenv("HCS_SECRET_SOURCE"); source {
+	case "", "env":
+		return hcs.NewEnvSecretProvider(), nil
+	case "vault":
+		if err := requireEnv("HCS_SECRET_SOURCE", "VAULT_ADDR", "VAULT_TOKEN", "HCS_VAULT_PATH"); err != nil {
+			return nil, err
+		}
+		fetcher = secretstore.NewVault(os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN"),
+			os.Getenv("HCS_VAULT_PATH"), os.Getenv("HCS_VAULT_FIELD"))
+	case "aws":
+		region, err := awsRegion("HCS_SECRET_SOURCE")
+		if err != nil {
+			return nil, err
+		}
+		if err := requireEnv("HCS_SECRET_SOURCE", "HCS_AWS_SECRET_ID", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"); err != nil {
+			return nil, err
+		}
+		fetcher = &secretstore.AWS{
+			Region:          region,
+			SecretID:        os.Getenv("HCS_AWS_SECRET_ID"),
+			Field:           os.Getenv("HCS_AWS_SECRET_FIELD"),
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	case "gcp":
+		if err := requireEnv("HCS_SECRET_SOURCE", "HCS_GCP_SECRET"); err != nil {
+			return nil, err
+		}
+		fetcher = &secretstore.GCP{Name: os.Getenv("HCS_GCP_SECRET"), Token: os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")}
+	default:
+		return nil, fmt.Errorf("unsupported HCS_SECRET_SOURCE: %q", source)
+	}
+
+	p := secretstore.NewProvider(fetcher, envDuration("HCS_SECRET_TTL", secretstore.DefaultTTL))
+	p.Clock = clk
+	if _, err := p.Reload(); err != nil {
+		log.Printf("Warning: %v; retrying on first use", err)
+	}
+	go p.Run(context.Background())
+	return p, nil
+}
+
+// requireEnv fails unless every named variable is set, naming the setting
+// that requires them
+func requireEnv(setting string, names ...string) error {
+	for _, name := range names {
+		if os.Getenv(name) == "" {
+			return fmt.Errorf("%s=%s requires %s", setting, os.Getenv(setting), name)
+		}
+	}
+	return nil
+}
+
+// awsRegion returns the region of AWS services, from AWS_REGION or
+// AWS_DEFAULT_REGION, naming the setting that requires it
+func awsRegion(setting string) (string, error) {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		return "", fmt.Errorf("%s=aws requires AWS_REGION", setting)
+	}
+	return region, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/signer"
+)
+
+// loadSigner returns a remote signer for the signing service at HCS_SIGNER_URL,
+// authenticated with HCS_SIGNER_TOKEN. It returns nil when U7 codes are signed
+// in process with HCS_SECRET_KEY.
+func loadSigner() (hcs.Signer, error) {
+	url := os.Getenv("HCS_SIGNER_URL")
+	if url == "" {
+		return nil, nil
+	}
+	token := os.Getenv("HCS_SIGNER_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("HCS_SIGNER_URL requires HCS_SIGNER_TOKEN")
+	}
+	return signer.NewRemote(url, token), nil
+}
+
+// holdsSecretKey reports whether this server is expected to hold the secret
+// key. With a remote signer it is optional and only needed for display codes.
+func holdsSecretKey() bool {
+	if readOnly {
+		return false
+	}
+	if os.Getenv("HCS_SIGNER_URL") != "" {
+		for _, name := range []string{"HCS_SECRET_KEY", "HCS_SECRET_KEY_FILE", "HCS_SECRET_KEYS", "HCS_SECRET_KEYS_FILE", "HCS_SECRET_SOURCE"} {
+			if os.Getenv(name) != "" {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// runSigner implements `hcsapi signer`: a signing service holding the secret
+// keys (HCS_SECRET_KEY, HCS_SECRET_KEYS or HCS_SECRET_SOURCE) for generation
+// servers configured with HCS_SIGNER_URL
+func runSigner() {
+	token := os.Getenv("HCS_SIGNER_TOKEN")
+	if token == "" {
+		log.Fatal("HCS_SIGNER_TOKEN must be set for the signing service")
+	}
+	var err error
+	if secrets, err = loadSecretProvider(); err != nil {
+		log.Fatalf("Invalid secret key configuration: %v", err)
+	}
+	if _, err := secrets.SecretKey(); err != nil {
+		log.Fatalf("Failed to load secret key: %v", err)
+	}
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8081"
+	}
+
+	addr := fmt.Sprintf(":%s", port)
+	log.Printf("HCS signing service v%s starting on %s", version, addr)
+	if err := http.ListenAndServe(addr, signer.Handler(hcs.NewLocalSigner(secrets), token)); err != nil {
+		log.Fatalf("Signing service failed to start: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// stateless is set by --stateless or HCS_STATELESS=on, for containers: the
+// salt and secret key come from the environment, nothing is written to disk,
+// logs are JSON on stdout and the /healthz and /readyz probes are served
+var stateless bool
+
+// checkStateless verifies that a stateless server has its salt in the
+// environment and no storage, so it never depends on a writable directory
+func checkStateless(storageDSN string) error {
+	if storageDSN != "" {
+		return fmt.Errorf("storage must be disabled (HCS_STORAGE or --storage is set)")
+	}
+	if saltStore() == "file" {
+		return fmt.Errorf("HCS_SALT, HCS_SALT_FILE or HCS_SALT_STORE must be set, since the salt cannot be created on disk")
+	}
+	return nil
+}
+
+// setupJSONLogs sends every log line, including the standard log package's,
+// to stdout as JSON
+func setupJSONLogs() {
+	level := slog.LevelInfo
+	if os.Getenv("HCS_LOG_LEVEL") == "debug" {
+		level = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})))
+}
+
+// requestLogger logs each request: as structured slog records in stateless
+// mode, otherwise with chi's text logger
+func requestLogger(next http.Handler) http.Handler {
+	if !stateless {
+		return middleware.Logger(next)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		next.ServeHTTP(ww, r)
+		slog.Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", ww.Status(),
+			"bytes", ww.BytesWritten(),
+			"duration", time.Since(start).String(),
+			"requestId", middleware.GetReqID(r.Context()))
+	})
+}
+
+// handleLive is the liveness probe: the process is serving requests
+func handleLive(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "alive"})
+}
+
+// handleReady is the readiness probe: the generator is up and, unless signing
+// is remote, the secret key can be loaded
+func handleReady(w http.ResponseWriter, r *http.Request) {
+	if generator == nil {
+		sendError(w, errcode.NotReady, "generator is not initialized")
+		return
+	}
+	if holdsSecretKey() {
+		if _, err := secrets.SecretKey(); err != nil {
+			sendError(w, errcode.NotReady, "secret key is unavailable")
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+}
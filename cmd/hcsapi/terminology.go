@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/i18n"
+	"github.com/corehuman/hcs-lab-api/internal/terminology"
+)
+
+// TerminologyResponse is the body of GET /api/terminology: the terms a
+// tenant's texts are reworded with, in the negotiated language, so clients
+// can word their own screens the same way
+type TerminologyResponse struct {
+	Name     string            `json:"name"`
+	Language string            `json:"language"`
+	Terms    map[string]string `json:"terms"`
+}
+
+// tenantTerminology is the terminology of every tenant, with the default of
+// tenants not listed
+type tenantTerminology struct {
+	fallback *terminology.Terminology
+	tenants  map[string]*terminology.Terminology
+}
+
+// loadTerminology reads the terminologies of HCS_TERMINOLOGY_FILE, the
+// default one named by HCS_TERMINOLOGY and the tenant assignments of
+// HCS_TENANT_TERMINOLOGY (tenant=name entries)
+func loadTerminology(env settings) (*tenantTerminology, error) {
+	catalog, err := terminology.Load(env.get("HCS_TERMINOLOGY_FILE"))
+	if err != nil {
+		return nil, err
+	}
+	t := &tenantTerminology{tenants: map[string]*terminology.Terminology{}}
+	if t.fallback, err = catalog.Lookup(env.get("HCS_TERMINOLOGY")); err != nil {
+		return nil, fmt.Errorf("invalid HCS_TERMINOLOGY: %w", err)
+	}
+	for _, pair := range splitList(env.get("HCS_TENANT_TERMINOLOGY")) {
+		tenant, name, ok := strings.Cut(pair, "=")
+		if !ok || tenant == "" {
+			return nil, fmt.Errorf("invalid HCS_TENANT_TERMINOLOGY entry: %q", pair)
+		}
+		if t.tenants[tenant], err = catalog.Lookup(name); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+	}
+	return t, nil
+}
+
+// selectTerminology returns the terminology of a tenant; nil for the
+// standard terms
+func (c *config) selectTerminology(tenantID string) *terminology.Terminology {
+	if t, ok := c.terminology.tenants[tenantID]; ok {
+		return t
+	}
+	return c.terminology.fallback
+}
+
+// requestTerminology returns the terminology of the caller's tenant, as
+// resolved by callerTenant from the API key and ?tenantId=
+func requestTerminology(r *http.Request) (*terminology.Terminology, error) {
+	tenant, err := callerTenant(r, r.URL.Query().Get("tenantId"))
+	if err != nil {
+		return nil, err
+	}
+	return cfg().selectTerminology(tenant), nil
+}
+
+// terminologyName names t, or the standard terms when nil
+func terminologyName(t *terminology.Terminology) string {
+	if t == nil {
+		return terminology.Standard
+	}
+	return t.Name
+}
+
+// handleTerminology serves the terms of the caller's tenant
+func handleTerminology(w http.ResponseWriter, r *http.Request) {
+	lang := w.Header().Get("Content-Language")
+	if lang == "" {
+		lang = i18n.Default
+	}
+	t, err := requestTerminology(r)
+	if err != nil {
+		sendCodedError(w, err, errcode.TenantForbidden)
+		return
+	}
+	response := TerminologyResponse{
+		Name:     terminologyName(t),
+		Language: lang,
+		Terms:    t.Dictionary(lang).Terms(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// Limits of a token batch
+const (
+	maxTokenBatch   = 1000
+	defaultTokenTTL = 24 * time.Hour
+	maxTokenTTL     = 366 * 24 * time.Hour
+)
+
+// tokenPrefix starts every verification token, so leaked tokens are easy to
+// recognize in logs and secret scanners
+const tokenPrefix = "hct_"
+
+// tokenEncoding writes tokens in lowercase base32, which survives being
+// typed or printed as a QR code
+var tokenEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// MintTokensRequest is the body of POST /api/tokens
+type MintTokensRequest struct {
+	Chip  string `json:"chip"`
+	Count int    `json:"count"`
+	Event string `json:"event,omitempty"` // e.g. the conference the tokens are for
+	// ExpiresIn is a Go duration such as "48h"; empty means 24h
+	ExpiresIn string `json:"expiresIn,omitempty"`
+}
+
+// MintTokensResponse holds the only copy of the tokens: the server keeps their hashes
+type MintTokensResponse struct {
+	BatchID   string    `json:"batchId"`
+	Chip      string    `json:"chip"`
+	Event     string    `json:"event,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Tokens    []string  `json:"tokens"`
+}
+
+// VerifyTokenRequest is the body of POST /api/verify/token
+type VerifyTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// VerifyTokenResponse reports whether a token was accepted. Reason explains a
+// rejection: unknown, used, expired or revoked.
+type VerifyTokenResponse struct {
+	Valid   bool       `json:"valid"`
+	Reason  string     `json:"reason,omitempty"`
+	Chip    string     `json:"chip,omitempty"`
+	BatchID string     `json:"batchId,omitempty"`
+	Event   string     `json:"event,omitempty"`
+	UsedAt  *time.Time `json:"usedAt,omitempty"`
+}
+
+// TokenUsageResponse summarizes the use of a token batch
+type TokenUsageResponse struct {
+	BatchID     string     `json:"batchId"`
+	Chip        string     `json:"chip"`
+	Event       string     `json:"event,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	Total       int        `json:"total"`
+	Used        int        `json:"used"`
+	Expired     int        `json:"expired"`   // unused tokens past their expiry
+	Remaining   int        `json:"remaining"` // tokens that can still be used
+	FirstUsedAt *time.Time `json:"firstUsedAt,omitempty"`
+	LastUsedAt  *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// tokens returns the token store, writing an error response when there is none
+func tokens(w http.ResponseWriter) store.Tokens {
+	t := store.TokensOf(codeStore)
+	if t == nil {
+		sendError(w, errcode.StorageDisabled, "set HCS_STORAGE to enable verification tokens")
+	}
+	return t
+}
+
+// handleMintTokens creates a batch of single-use verification tokens bound to
+// a CHIP. Revoked CHIPs get none.
+func handleMintTokens(w http.ResponseWriter, r *http.Request) {
+	var req MintTokensRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+	chip, ok := requestChip(w, req.Chip, "")
+	if !ok {
+		return
+	}
+	if chip == "" {
+		sendError(w, errcode.InvalidRequest, "chip is required")
+		return
+	}
+	checkHoneypot(r, chip)
+	if req.Count < 1 || req.Count > maxTokenBatch {
+		sendError(w, errcode.InvalidRequest, fmt.Sprintf("count must be between 1 and %d, got %d", maxTokenBatch, req.Count))
+		return
+	}
+	ttl := defaultTokenTTL
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || d <= 0 || d > maxTokenTTL {
+			sendError(w, errcode.InvalidRequest, fmt.Sprintf("expiresIn must be a positive duration of at most %s, got %q", maxTokenTTL, req.ExpiresIn))
+			return
+		}
+		ttl = d
+	}
+	ts := tokens(w)
+	if ts == nil {
+		return
+	}
+	if revs := store.RevocationsOf(codeStore); revs != nil {
+		if _, err := revs.GetRevocation(r.Context(), chip); err == nil {
+			sendError(w, errcode.InvalidRequest, "CHIP "+chip+" is revoked")
+			return
+		} else if !errors.Is(err, store.ErrNotFound) {
+			sendLookupError(w, err)
+			return
+		}
+	}
+
+	now := clk.Now().UTC().Truncate(time.Second)
+	batchID, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		sendError(w, errcode.Internal, err.Error())
+		return
+	}
+	response := MintTokensResponse{BatchID: batchID, Chip: chip, Event: req.Event, ExpiresAt: now.Add(ttl), Tokens: make([]string, req.Count)}
+	batch := make([]store.Token, req.Count)
+	for i := range batch {
+		token, err := randomString(20, tokenEncoding.EncodeToString)
+		if err != nil {
+			sendError(w, errcode.Internal, err.Error())
+			return
+		}
+		response.Tokens[i] = tokenPrefix + token
+		batch[i] = store.Token{Hash: store.TokenHash(response.Tokens[i]), BatchID: batchID, Chip: chip, Event: req.Event,
+			CreatedAt: now, ExpiresAt: response.ExpiresAt}
+	}
+	if err := ts.SaveTokens(r.Context(), batch); err != nil {
+		sendLookupError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleVerifyToken accepts a token once: it is consumed by the first
+// successful verification, even by concurrent requests
+func handleVerifyToken(w http.ResponseWriter, r *http.Request) {
+	var req VerifyTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+	if !strings.HasPrefix(req.Token, tokenPrefix) {
+		sendError(w, errcode.InvalidRequest, "token must start with "+tokenPrefix)
+		return
+	}
+	ts := tokens(w)
+	if ts == nil {
+		return
+	}
+
+	tok, err := ts.ConsumeToken(r.Context(), store.TokenHash(req.Token), clk.Now().UTC())
+	response := VerifyTokenResponse{}
+	if tok != nil {
+		response.Chip, response.BatchID, response.Event = tok.Chip, tok.BatchID, tok.Event
+		if !tok.UsedAt.IsZero() {
+			response.UsedAt = &tok.UsedAt
+		}
+	}
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		response.Reason = "unknown"
+	case errors.Is(err, store.ErrTokenUsed):
+		response.Reason = "used"
+	case errors.Is(err, store.ErrTokenExpired):
+		response.Reason = "expired"
+	case err != nil:
+		sendLookupError(w, err)
+		return
+	default:
+		response.Valid = true
+		// A CHIP revoked after minting voids its tokens; the token stays consumed
+		if revs := store.RevocationsOf(codeStore); revs != nil {
+			if _, err := revs.GetRevocation(r.Context(), tok.Chip); err == nil {
+				response.Valid, response.Reason = false, "revoked"
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleTokenUsage reports how many tokens of a batch were used
+func handleTokenUsage(w http.ResponseWriter, r *http.Request) {
+	ts := tokens(w)
+	if ts == nil {
+		return
+	}
+	batch, err := ts.TokenBatch(r.Context(), chi.URLParam(r, "batchID"))
+	if errors.Is(err, store.ErrNotFound) {
+		sendError(w, errcode.NotFound, "no token batch with this ID")
+		return
+	}
+	if err != nil {
+		sendLookupError(w, err)
+		return
+	}
+
+	now := clk.Now()
+	first := batch[0]
+	response := TokenUsageResponse{BatchID: first.BatchID, Chip: first.Chip, Event: first.Event,
+		CreatedAt: first.CreatedAt, ExpiresAt: first.ExpiresAt, Total: len(batch)}
+	for _, tok := range batch {
+		switch {
+		case !tok.UsedAt.IsZero():
+			response.Used++
+			usedAt := tok.UsedAt
+			if response.FirstUsedAt == nil || usedAt.Before(*response.FirstUsedAt) {
+				response.FirstUsedAt = &usedAt
+			}
+			if response.LastUsedAt == nil || usedAt.After(*response.LastUsedAt) {
+				response.LastUsedAt = &usedAt
+			}
+		case !now.Before(tok.ExpiresAt):
+			response.Expired++
+		default:
+			response.Remaining++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// randomString encodes n random bytes
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return encode(b), nil
+}
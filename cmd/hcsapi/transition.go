@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// codecTransition describes an in-progress HCS-U7 format upgrade
+type codecTransition struct {
+	primary string    // HCS_U7_VERSION; empty means hcs.CurrentU7Version
+	legacy  []string  // HCS_DUAL_WRITE_U7 versions emitted alongside
+	until   time.Time // HCS_DUAL_WRITE_UNTIL; zero means no end date
+}
+
+// loadCodecTransition reads the dual-write configuration
+func loadCodecTransition(env settings) (codecTransition, error) {
+	var transition codecTransition
+
+	supported := map[string]bool{}
+	for _, v := range hcs.SupportedU7Versions() {
+		supported[v] = true
+	}
+
+	transition.primary = env.get("HCS_U7_VERSION")
+	if transition.primary != "" && !supported[transition.primary] {
+		return codecTransition{}, fmt.Errorf("unsupported HCS_U7_VERSION: %s", transition.primary)
+	}
+
+	if v := env.get("HCS_DUAL_WRITE_U7"); v != "" {
+		for _, version := range strings.Split(v, ",") {
+			version = strings.TrimSpace(version)
+			if !supported[version] {
+				return codecTransition{}, fmt.Errorf("unsupported HCS_DUAL_WRITE_U7 version: %s", version)
+			}
+			transition.legacy = append(transition.legacy, version)
+		}
+	}
+
+	if v := env.get("HCS_DUAL_WRITE_UNTIL"); v != "" {
+		until, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return codecTransition{}, fmt.Errorf("invalid HCS_DUAL_WRITE_UNTIL: %w", err)
+		}
+		transition.until = until
+	}
+	return transition, nil
+}
+
+// apply sets the codec version options for a generation at now. Legacy
+// versions are only emitted until the end of the transition period.
+func (t codecTransition) apply(opts *hcs.GeneratorOptions, now time.Time) {
+	opts.U7Version = t.primary
+	if t.until.IsZero() || now.Before(t.until) {
+		opts.LegacyU7Versions = t.legacy
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"expvar"
+	"log"
+	"net/http"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/schema"
+)
+
+// Schema violation metrics, served by GET /api/admin/metrics
+var (
+	schemaViolations       = expvar.NewInt("hcs_schema_violations_total")
+	schemaViolationsByPath = expvar.NewMap("hcs_schema_violations_by_path")
+)
+
+// validateOutput checks an outgoing OutputHCS against its JSON Schema when
+// HCS_VALIDATE_RESPONSES is on. Violations are logged and counted; the
+// response is still sent, so a schema drift never takes the API down.
+func validateOutput(out *hcs.OutputHCS) {
+	if !cfg().validateResponses || out == nil {
+		return
+	}
+	violations, err := schema.ValidateOutput(out)
+	if err != nil {
+		log.Printf("Warning: failed to validate response for %s: %v", out.Chip, err)
+		return
+	}
+	for _, v := range violations {
+		log.Printf("Warning: response for %s violates the OutputHCS schema: %s", out.Chip, v)
+		schemaViolations.Add(1)
+		schemaViolationsByPath.Add(v.Path, 1)
+	}
+}
+
+// handleOutputSchema serves the OutputHCS JSON Schema, so clients can
+// validate responses the same way
+func handleOutputSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Write(schema.OutputJSON())
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// handleTestVectors serves the cross-language test vectors computed by this build
+func handleTestVectors(w http.ResponseWriter, r *http.Request) {
+	vectors, err := hcs.TestVectors()
+	if err != nil {
+		sendError(w, errcode.TestVectorsFailed, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(vectors)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// legacyDeprecatedAt is when the unversioned /api routes are deprecated in
+// favor of /v1, announced ahead in the Deprecation header. HCS_API_DEPRECATIONS
+// overrides it with an api=<date> entry.
+var legacyDeprecatedAt = time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// apiVersion is one version of the API contract, served under its own prefix
+// so that versions with different response shapes run side by side. A new
+// version registers its own routes, reusing the handlers it does not change.
+type apiVersion struct {
+	name      string // "v1"; "api" for the unversioned legacy routes
+	prefix    string
+	routes    func(r chi.Router, prefix string)
+	successor string // prefix that replaces this version once deprecated
+
+	deprecatedAt time.Time // zero while the version is current
+	sunset       time.Time // zero when no removal date is announced
+}
+
+// apiVersions lists the served versions, oldest first. The legacy /api routes
+// are the same contract as v1.
+func apiVersions() []*apiVersion {
+	return []*apiVersion{
+		{name: "api", prefix: "/api", routes: v1Routes, successor: "/v1", deprecatedAt: legacyDeprecatedAt},
+		{name: "v1", prefix: "/v1", routes: v1Routes},
+	}
+}
+
+// v1Routes registers the v1 contract under prefix
+func v1Routes(r chi.Router, prefix string) {
+	r.Get(prefix+"/testvectors", handleTestVectors)    // public: lets other implementations prove parity
+	r.Get(prefix+"/keys", handlePublicKeys)            // public: post-quantum verification keys
+	r.Get(prefix+"/schema/output", handleOutputSchema) // public: the response contract
+	r.Get(prefix+"/errors", handleErrors)              // public: the error code catalog
+	r.Get(prefix+"/capabilities", handleCapabilities)  // public: supported versions, algorithms and limits
+	r.Group(func(r chi.Router) {
+		r.Use(requireAPIKey)
+		r.Use(standardizeBody)
+		r.With(watchAnomalies).Post(prefix+"/generate", writable(handleGenerate))
+		r.With(watchAnomalies).Post(prefix+"/generate/batch", writable(handleGenerateBatch))
+		r.With(watchAnomalies).Post(prefix+"/envelopes", writable(handleSealEnvelope))
+		r.Post(prefix+"/envelopes/open", writable(handleOpenEnvelope))
+		r.With(watchAnomalies).Post(prefix+"/profiles/merge", writable(handleMergeProfiles))
+		r.Post(prefix+"/decode", handleDecode)
+		r.Post(prefix+"/verify", handleVerify)
+		r.Post(prefix+"/verify/token", writable(handleVerifyToken))
+		r.Post(prefix+"/tokens", writable(handleMintTokens))
+		r.Get(prefix+"/tokens/{batchID}", handleTokenUsage)
+		r.Get(prefix+"/revocations", writable(handleRevocationList))
+		r.With(watchHoneypot).Get(prefix+"/codes/{chip}", handleGetCode)
+		r.With(watchHoneypot).Get(prefix+"/codes/{chip}/matches", handleCodeMatches)
+		r.Get(prefix+"/subjects/{subjectID}/retest", handleRetest)
+		r.Post(prefix+"/compare", handleCompare)
+		r.Post(prefix+"/compare/matrix", handleCompareMatrix)
+		r.Post(prefix+"/display-codes", writable(handleDisplayCode))
+		r.Post(prefix+"/display-codes/verify", writable(handleVerifyDisplayCode))
+		r.Get(prefix+"/terminology", handleTerminology)
+		r.Get(prefix+"/norms", handleNorms)
+		r.Get(prefix+"/score/banks", handleItemBanks)
+		r.Get(prefix+"/score/items", handleScoreItems)
+		r.Post(prefix+"/score", handleScore)
+		r.Get(prefix+"/analytics/clusters", handleClusters)
+		r.Post(prefix+"/webhooks", writable(handleCreateWebhook))
+		r.Get(prefix+"/webhooks", handleListWebhooks)
+		r.Delete(prefix+"/webhooks/{webhookID}", writable(handleDeleteWebhook))
+		r.Post(prefix+"/webhooks/{webhookID}/test", writable(handleTestWebhook))
+		r.Post(prefix+"/webhooks/{webhookID}/rotate-secret", writable(handleRotateWebhookSecret))
+		r.Get(prefix+"/webhooks/{webhookID}/dead-letters", handleWebhookDeadLetters)
+	})
+}
+
+// mountAPIVersions registers every version, after applying the deprecation
+// and sunset dates of HCS_API_DEPRECATIONS
+func mountAPIVersions(r chi.Router) error {
+	versions := apiVersions()
+	if err := applyDeprecations(versions, getenv("HCS_API_DEPRECATIONS")); err != nil {
+		return err
+	}
+	for _, v := range versions {
+		r.Group(func(r chi.Router) {
+			if !v.deprecatedAt.IsZero() {
+				r.Use(v.deprecationHeaders)
+			}
+			if debugCapture != nil {
+				r.Use(debugCapture.middleware)
+			}
+			v.routes(r, v.prefix)
+		})
+	}
+	return nil
+}
+
+// applyDeprecations parses entries of the form version=deprecated[/sunset],
+// with RFC 3339 dates (e.g. v1=2027-01-01/2027-07-01 or api=/2027-04-30)
+func applyDeprecations(versions []*apiVersion, spec string) error {
+	byName := make(map[string]*apiVersion, len(versions))
+	for _, v := range versions {
+		byName[v.name] = v
+	}
+	for _, entry := range splitList(spec) {
+		name, dates, ok := strings.Cut(entry, "=")
+		v := byName[strings.TrimSpace(name)]
+		if !ok || v == nil {
+			return fmt.Errorf("invalid HCS_API_DEPRECATIONS entry %q: expected <version>=<date>[/<sunset>] for a served version", entry)
+		}
+		deprecated, sunset, _ := strings.Cut(dates, "/")
+		for _, d := range []struct {
+			value  string
+			target *time.Time
+		}{{deprecated, &v.deprecatedAt}, {sunset, &v.sunset}} {
+			if d.value == "" {
+				continue
+			}
+			t, err := parseDate(d.value)
+			if err != nil {
+				return fmt.Errorf("invalid HCS_API_DEPRECATIONS date for %s: %w", v.name, err)
+			}
+			*d.target = t
+		}
+		if !v.sunset.IsZero() && v.deprecatedAt.IsZero() {
+			return fmt.Errorf("HCS_API_DEPRECATIONS: %s has a sunset date but is not deprecated", v.name)
+		}
+	}
+	return nil
+}
+
+// parseDate accepts an RFC 3339 date or timestamp
+func parseDate(v string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// deprecationHeaders marks responses of a deprecated version with the
+// Deprecation (RFC 9745) and Sunset (RFC 8594) headers, and links the
+// successor version of the requested route
+func (v *apiVersion) deprecationHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", v.deprecatedAt.Unix()))
+		if !v.sunset.IsZero() {
+			w.Header().Set("Sunset", v.sunset.UTC().Format(http.TimeFormat))
+		}
+		if v.successor != "" {
+			w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", v.successor+strings.TrimPrefix(r.URL.Path, v.prefix)))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
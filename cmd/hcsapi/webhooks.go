@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/features"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+	"github.com/corehuman/hcs-lab-api/internal/webhook"
+	"github.com/go-chi/chi/v5"
+)
+
+// Event types delivered to tenant webhooks
+const (
+	eventCodeGenerated = "code.generated"
+	eventCodeExpiring  = "code.expiring"
+	eventWebhookTest   = "webhook.test"
+)
+
+// webhookEventTypes are the event types a webhook may subscribe to
+var webhookEventTypes = []string{eventCodeGenerated, eventCodeExpiring, eventWebhookTest}
+
+// webhookSecretPrefix starts every webhook secret, so leaked secrets are easy
+// to recognize in logs and secret scanners
+const webhookSecretPrefix = "whsec_"
+
+// maxPendingDeliveries bounds the deliveries waiting for a retry; events
+// beyond it go straight to the dead letters
+const maxPendingDeliveries = 1000
+
+var (
+	webhookDeliveries = expvar.NewMap("hcs_webhook_deliveries")
+	pendingDeliveries = make(chan struct{}, maxPendingDeliveries)
+	webhookClient     = webhook.NewPublicClient(10 * time.Second)
+)
+
+// CreateWebhookRequest is the body of POST /api/webhooks
+type CreateWebhookRequest struct {
+	URL string `json:"url"`
+	// Events are the event types to receive; empty receives all of them
+	Events []string `json:"events,omitempty"`
+}
+
+// WebhookResponse describes a webhook. Secret is only returned when the
+// webhook is created and when its secret is rotated.
+type WebhookResponse struct {
+	ID              string    `json:"id"`
+	TenantID        string    `json:"tenantId,omitempty"`
+	URL             string    `json:"url"`
+	Events          []string  `json:"events"`
+	Secret          string    `json:"secret,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	SecretRotatedAt time.Time `json:"secretRotatedAt"`
+}
+
+// WebhookListResponse is the response of GET /api/webhooks
+type WebhookListResponse struct {
+	Webhooks []WebhookResponse `json:"webhooks"`
+}
+
+// TestWebhookResponse reports a single test delivery
+type TestWebhookResponse struct {
+	Delivered  bool   `json:"delivered"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// DeadLettersResponse is the response of GET /api/webhooks/{id}/dead-letters
+type DeadLettersResponse struct {
+	DeadLetters []store.DeadLetter `json:"deadLetters"`
+}
+
+// GeneratedEvent is the payload of "code.generated" events
+type GeneratedEvent struct {
+	Chip      string         `json:"chip"`
+	TenantID  string         `json:"tenantId,omitempty"`
+	SubjectID string         `json:"subjectId,omitempty"`
+	Output    *hcs.OutputHCS `json:"output"`
+}
+
+// webhooks returns the webhook registry, writing an error response when there is none
+func webhooks(w http.ResponseWriter) store.Webhooks {
+	wh := store.WebhooksOf(codeStore)
+	if wh == nil {
+		sendError(w, errcode.StorageDisabled, "set HCS_STORAGE to enable webhooks")
+	}
+	return wh
+}
+
+// tenantWebhook loads the webhook of the URL, which must belong to the
+// tenant of the API key: those of other tenants are not found
+func tenantWebhook(w http.ResponseWriter, r *http.Request) (store.Webhooks, *store.Webhook) {
+	hooks := webhooks(w)
+	if hooks == nil {
+		return nil, nil
+	}
+	wh, err := hooks.GetWebhook(r.Context(), chi.URLParam(r, "webhookID"))
+	if errors.Is(err, store.ErrNotFound) || err == nil && wh.TenantID != apiKeyTenant(r) {
+		sendError(w, errcode.NotFound, "no webhook with this ID")
+		return nil, nil
+	}
+	if err != nil {
+		sendLookupError(w, err)
+		return nil, nil
+	}
+	return hooks, wh
+}
+
+func webhookResponse(wh *store.Webhook, withSecret bool) WebhookResponse {
+	response := WebhookResponse{ID: wh.ID, TenantID: wh.TenantID, URL: wh.URL, Events: wh.Events,
+		CreatedAt: wh.CreatedAt, SecretRotatedAt: wh.SecretRotatedAt}
+	if response.Events == nil {
+		response.Events = []string{}
+	}
+	if withSecret {
+		response.Secret = wh.Secret
+	}
+	return response
+}
+
+func newWebhookSecret() (string, error) {
+	secret, err := randomString(32, hex.EncodeToString)
+	return webhookSecretPrefix + secret, err
+}
+
+// handleCreateWebhook registers a webhook for the tenant of the API key
+func handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+	if err := webhook.CheckURL(r.Context(), req.URL); err != nil {
+		sendError(w, errcode.InvalidRequest, err.Error())
+		return
+	}
+	for _, event := range req.Events {
+		if !slices.Contains(webhookEventTypes, event) {
+			sendError(w, errcode.InvalidRequest, fmt.Sprintf("unknown event type %q (supported: %v)", event, webhookEventTypes))
+			return
+		}
+	}
+	hooks := webhooks(w)
+	if hooks == nil {
+		return
+	}
+
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		sendError(w, errcode.Internal, err.Error())
+		return
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		sendError(w, errcode.Internal, err.Error())
+		return
+	}
+	now := clk.Now().UTC().Truncate(time.Second)
+	wh := store.Webhook{ID: "wh_" + id, TenantID: apiKeyTenant(r), URL: req.URL, Events: req.Events, Secret: secret,
+		CreatedAt: now, SecretRotatedAt: now}
+	if err := hooks.SaveWebhook(r.Context(), wh); err != nil {
+		sendLookupError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhookResponse(&wh, true))
+}
+
+// handleListWebhooks lists the webhooks of the tenant of the API key
+func handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks := webhooks(w)
+	if hooks == nil {
+		return
+	}
+	all, err := hooks.ListWebhooks(r.Context())
+	if err != nil {
+		sendLookupError(w, err)
+		return
+	}
+	response := WebhookListResponse{Webhooks: []WebhookResponse{}}
+	tenant := apiKeyTenant(r)
+	for i := range all {
+		if all[i].TenantID == tenant {
+			response.Webhooks = append(response.Webhooks, webhookResponse(&all[i], false))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleDeleteWebhook deletes a webhook and its dead letters
+func handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	hooks, wh := tenantWebhook(w, r)
+	if wh == nil {
+		return
+	}
+	if err := hooks.DeleteWebhook(r.Context(), wh.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		sendLookupError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTestWebhook delivers a signed "webhook.test" event once, without
+// retries, and reports the outcome
+func handleTestWebhook(w http.ResponseWriter, r *http.Request) {
+	_, wh := tenantWebhook(w, r)
+	if wh == nil {
+		return
+	}
+	body, err := json.Marshal(webhook.Event{Type: eventWebhookTest, CreatedAt: clk.Now().UTC(),
+		Data: map[string]string{"webhookId": wh.ID}})
+	if err != nil {
+		sendError(w, errcode.Internal, err.Error())
+		return
+	}
+	started := clk.Now()
+	err = webhook.Post(r.Context(), webhookClient, wh.URL, wh.Secret, eventWebhookTest, body)
+	response := TestWebhookResponse{Delivered: err == nil, Error: webhook.PublicError(err),
+		DurationMs: clk.Now().Sub(started).Milliseconds()}
+	if err != nil {
+		log.Printf("Warning: test delivery to webhook %s failed: %v", wh.ID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleRotateWebhookSecret replaces the secret of a webhook. Deliveries are
+// signed with the new secret from then on.
+func handleRotateWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	hooks, wh := tenantWebhook(w, r)
+	if wh == nil {
+		return
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		sendError(w, errcode.Internal, err.Error())
+		return
+	}
+	wh.Secret, wh.SecretRotatedAt = secret, clk.Now().UTC().Truncate(time.Second)
+	if err := hooks.SaveWebhook(r.Context(), *wh); err != nil {
+		sendLookupError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhookResponse(wh, true))
+}
+
+// handleWebhookDeadLetters lists the events a webhook gave up on
+func handleWebhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	hooks, wh := tenantWebhook(w, r)
+	if wh == nil {
+		return
+	}
+	dls, err := hooks.ListDeadLetters(r.Context(), wh.ID)
+	if err != nil {
+		sendLookupError(w, err)
+		return
+	}
+	response := DeadLettersResponse{DeadLetters: dls}
+	if response.DeadLetters == nil {
+		response.DeadLetters = []store.DeadLetter{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// publishEvent delivers an event to the webhooks of tenant subscribed to its
+// type, in the background with retries, and returns how many there are
+func publishEvent(ctx context.Context, tenant, eventType string, data any) int {
+	hooks := store.WebhooksOf(codeStore)
+	if hooks == nil || readOnly || !cfg().flags.Enabled(features.Webhooks, tenant) {
+		return 0
+	}
+	all, err := hooks.ListWebhooks(ctx)
+	if err != nil {
+		log.Printf("Warning: failed to list webhooks for %s: %v", eventType, err)
+		return 0
+	}
+	var targets []store.Webhook
+	for _, wh := range all {
+		if wh.TenantID == tenant && (len(wh.Events) == 0 || slices.Contains(wh.Events, eventType)) {
+			targets = append(targets, wh)
+		}
+	}
+	if len(targets) == 0 {
+		return 0
+	}
+	body, err := json.Marshal(webhook.Event{Type: eventType, CreatedAt: clk.Now().UTC(), Data: data})
+	if err != nil {
+		log.Printf("Warning: failed to marshal %s event: %v", eventType, err)
+		return 0
+	}
+	for _, wh := range targets {
+		select {
+		case pendingDeliveries <- struct{}{}:
+			go func(wh store.Webhook) {
+				defer func() { <-pendingDeliveries }()
+				deliverEvent(hooks, wh, eventType, body)
+			}(wh)
+		default:
+			deadLetter(hooks, wh, eventType, body, 0, errors.New("too many pending deliveries"))
+		}
+	}
+	return len(targets)
+}
+
+// deliverEvent posts an event to a webhook with the configured retries, and
+// keeps it as a dead letter when they are exhausted
+func deliverEvent(hooks store.Webhooks, wh store.Webhook, eventType string, body []byte) {
+	attempts, err := webhook.PostWithRetry(context.Background(), webhookClient, wh.URL, wh.Secret, eventType, body, cfg().webhookRetry)
+	if err == nil {
+		webhookDeliveries.Add("delivered", 1)
+		return
+	}
+	deadLetter(hooks, wh, eventType, body, attempts, err)
+}
+
+func deadLetter(hooks store.Webhooks, wh store.Webhook, eventType string, body []byte, attempts int, cause error) {
+	webhookDeliveries.Add("dead_lettered", 1)
+	log.Printf("Warning: webhook %s gave up on %s after %d attempts: %v", wh.ID, eventType, attempts, cause)
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		log.Printf("Warning: failed to keep dead letter for webhook %s: %v", wh.ID, err)
+		return
+	}
+	dl := store.DeadLetter{ID: "dl_" + id, WebhookID: wh.ID, Type: eventType, Body: body, Attempts: attempts,
+		LastError: webhook.PublicError(cause), FailedAt: clk.Now().UTC()}
+	if err := hooks.SaveDeadLetter(context.Background(), dl); err != nil {
+		log.Printf("Warning: failed to keep dead letter for webhook %s: %v", wh.ID, err)
+	}
+}
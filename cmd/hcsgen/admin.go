@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// runAdmin implements the `hcsgen admin` operator commands
+func runAdmin(args []string) {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s admin rotate-salt|seal-salt|backup-salt|restore-salt [options]\n", os.Args[0])
+	}
+	if len(args) == 0 {
+		exitUsage(usage, "admin command required")
+	}
+
+	switch args[0] {
+	case "rotate-salt":
+		runRotateSalt(args[1:])
+	case "seal-salt":
+		runSealSalt(args[1:])
+	case "backup-salt":
+		runBackupSalt(args[1:])
+	case "restore-salt":
+		runRestoreSalt(args[1:])
+	default:
+		exitUsage(usage, "unknown admin command %q", args[0])
+	}
+}
+
+// runRotateSalt implements `hcsgen admin rotate-salt`
+func runRotateSalt(args []string) {
+	fs := flag.NewFlagSet("rotate-salt", flag.ExitOnError)
+	saltDir := fs.String("salt-dir", defaultSaltDir(), "Directory containing the salt files")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s admin rotate-salt [--salt-dir <dir>]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Create the salt of the next epoch. New codes use it once the service restarts;\n")
+		fmt.Fprintf(os.Stderr, "earlier salts are kept so older codes can still be verified.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if err := prepareSaltDir(*saltDir, false); err != nil {
+		exitError(errcode.SaltUnavailable, "preparing salt directory", err)
+	}
+	provider := hcs.DirSaltProvider{Dir: *saltDir, Secrets: hcs.NewEnvSecretProvider()}
+	epoch, err := provider.Rotate()
+	if err != nil {
+		exitError(errcode.SaltUnavailable, "rotating salt", err)
+	}
+	report(os.Stdout, map[string]any{"epoch": epoch, "saltDir": *saltDir}, "Created salt epoch %d in %s\n", epoch, *saltDir)
+}
+
+// runSealSalt implements `hcsgen admin seal-salt`
+func runSealSalt(args []string) {
+	fs := flag.NewFlagSet("seal-salt", flag.ExitOnError)
+	saltDir := fs.String("salt-dir", defaultSaltDir(), "Directory containing the salt files")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s admin seal-salt [--salt-dir <dir>]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Seal the existing salts with HCS_SECRET_KEY, once, e.g. after a secret key is\n")
+		fmt.Fprintf(os.Stderr, "first configured. Salts that already carry a seal for the key are verified.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	files, err := hcs.SaltFiles(*saltDir)
+	if err != nil {
+		exitError(errcode.SaltUnavailable, "listing salt files", err)
+	}
+	if len(files) == 0 {
+		exitError(errcode.SaltUnavailable, "sealing salt", fmt.Errorf("no salt in %s", *saltDir))
+	}
+	secrets := hcs.NewEnvSecretProvider()
+	if _, err := secrets.SecretKey(); err != nil {
+		exitError(errcode.MissingSecret, "loading secret key", err)
+	}
+	provider := hcs.DirSaltProvider{Dir: *saltDir, Secrets: secrets, MigrateSeal: true}
+	salts, epoch, err := provider.SaltEpochs()
+	if err != nil {
+		exitError(errcode.SaltUnavailable, "sealing salt", err)
+	}
+	report(os.Stdout, map[string]any{"epoch": epoch, "saltDir": *saltDir, "salts": len(salts)},
+		"Sealed %d salt(s) in %s, current epoch %d\n", len(salts), *saltDir, epoch)
+}
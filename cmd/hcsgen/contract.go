@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/contract"
+)
+
+// runContract implements `hcsgen contract`, certifying a deployment against
+// the API contract
+func runContract(args []string) {
+	fs := flag.NewFlagSet("contract", flag.ExitOnError)
+	baseURL := fs.String("base-url", "", "Base URL of the deployment to check (e.g. https://hcs.example.com)")
+	apiKey := fs.String("api-key", os.Getenv("HCS_API_KEY"), "X-API-Key to send (default $HCS_API_KEY)")
+	timeout := fs.Duration("timeout", 30*time.Second, "Overall time limit for the suite")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s contract --base-url <url> [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Run the black-box conformance suite (status codes, response schema,\n")
+		fmt.Fprintf(os.Stderr, "determinism, error model) against a deployment. Exits 1 if any check fails.\n")
+		fmt.Fprintf(os.Stderr, "With --json, prints the results as JSON; with --quiet, only the failed checks.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *baseURL == "" {
+		exitUsage(fs.Usage, "--base-url is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	suite := &contract.Suite{BaseURL: *baseURL, APIKey: *apiKey, Client: &http.Client{Timeout: 10 * time.Second}}
+	results := suite.Run(ctx)
+
+	if jsonOutput {
+		report(os.Stdout, results, "")
+	} else {
+		for _, r := range results {
+			if r.Passed {
+				if !quiet {
+					printf("PASS  %s\n", r.Name)
+				}
+			} else {
+				printf("FAIL  %s: %s\n", r.Name, r.Detail)
+			}
+		}
+	}
+	if !contract.Passed(results) {
+		os.Exit(exitFailed)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/i18n"
+)
+
+// Exit codes shared by every hcsgen command
+const (
+	exitOK         = 0
+	exitFailed     = 1 // the command ran, but checks or requests failed (contract, synth --target)
+	exitValidation = 2 // invalid arguments or input
+	exitConfig     = 3 // missing or invalid keys, salts or configuration
+	exitIO         = 4 // files, storage or the network
+	exitInternal   = 5
+)
+
+// exitCode maps an error code to the exit code of its range
+func exitCode(code errcode.Code) int {
+	switch {
+	case strings.HasPrefix(string(code), "HCS-1"):
+		return exitValidation
+	case strings.HasPrefix(string(code), "HCS-2"), strings.HasPrefix(string(code), "HCS-3"):
+		return exitConfig
+	case strings.HasPrefix(string(code), "HCS-4"), strings.HasPrefix(string(code), "HCS-5"):
+		return exitIO
+	}
+	return exitInternal
+}
+
+// cliError is the --json form of a failure, written to stderr
+type cliError struct {
+	Error     string       `json:"error"`
+	Step      string       `json:"step,omitempty"`
+	Message   string       `json:"message"`
+	ErrorCode errcode.Code `json:"errorCode"`
+	ExitCode  int          `json:"exitCode"`
+}
+
+// exitError reports a failed step with the stable code of err, or fallback
+// when err carries none, and exits with the code's exit code. An empty step
+// reports err alone.
+func exitError(fallback errcode.Code, step string, err error) {
+	code := errcode.Of(err, fallback)
+	status := exitCode(code)
+	switch {
+	case jsonOutput:
+		json.NewEncoder(os.Stderr).Encode(cliError{
+			Error: errcode.Lookup(code).Title, Step: step, Message: err.Error(), ErrorCode: code, ExitCode: status,
+		})
+	case step == "":
+		fmt.Fprint(os.Stderr, tr("Error [%s]: %v\n", code, err))
+	default:
+		fmt.Fprint(os.Stderr, tr("Error %s [%s]: %v\n", i18n.Translate(lang, step), code, err))
+	}
+	os.Exit(status)
+}
+
+// exitUsage reports invalid arguments, followed by the usage unless the
+// output is quiet or JSON, and exits with exitValidation
+func exitUsage(usage func(), format string, args ...any) {
+	if jsonOutput {
+		exitError(errcode.InvalidRequest, "", fmt.Errorf(format, args...))
+	}
+	fmt.Fprint(os.Stderr, tr("Error: %s\n", tr(format, args...)))
+	if !quiet {
+		usage()
+	}
+	os.Exit(exitValidation)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/export"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+// runExport implements `hcsgen export`
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	storeDSN := fs.String("store", "", "Storage DSN holding the records to export (e.g. file:./hcs_store.json)")
+	outFile := fs.String("output", "", "Parquet file to write, or - for stdout")
+	tenant := fs.String("tenant", "", "Only export records of this tenant")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s export --store <dsn> --output <file.parquet|-> [--tenant <id>]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Write stored normalized profiles and derived metrics to a Parquet file, one column\n")
+		fmt.Fprintf(os.Stderr, "per field. Use --output - to stream the file to an object storage upload.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *storeDSN == "" || *outFile == "" {
+		exitUsage(fs.Usage, "--store and --output are required")
+	}
+
+	s, err := store.Open(*storeDSN)
+	if err != nil {
+		exitError(errcode.StorageUnavailable, "opening store", err)
+	}
+	var records []store.Record
+	if *tenant != "" {
+		records, err = s.List(context.Background(), *tenant)
+	} else {
+		records, err = s.ListAll(context.Background())
+	}
+	if err != nil {
+		exitError(errcode.LookupFailed, "reading store", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *outFile != "-" {
+		f, err := os.Create(*outFile)
+		if err != nil {
+			exitError(errcode.FileIO, "creating output file", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := export.WriteParquet(w, records); err != nil {
+		exitError(errcode.FileIO, "writing Parquet", err)
+	}
+	if *outFile != "-" {
+		report(os.Stderr, map[string]any{"records": len(records), "output": *outFile}, "Exported %d records to %s\n", len(records), *outFile)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// defaultSaltDir returns where the CLI keeps its salt files: HCS_HOME when
+// set, else hcs-lab under the user's configuration directory
+// (~/.config/hcs-lab on Linux, ~/Library/Application Support/hcs-lab on
+// macOS, %AppData%\hcs-lab on Windows). Without a configuration directory it
+// falls back to the working directory, as earlier versions did.
+func defaultSaltDir() string {
+	if home := os.Getenv("HCS_HOME"); home != "" {
+		return home
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "."
+	}
+	return filepath.Join(dir, "hcs-lab")
+}
+
+// prepareSaltDir creates the salt directory. With migrate, when it has no
+// salt yet but the working directory has one, created by an earlier version
+// of the CLI, the salt files are copied over so that CHIPs stay the same.
+func prepareSaltDir(dir string, migrate bool) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create salt directory: %w", err)
+	}
+	if !migrate {
+		return nil
+	}
+	if same, err := samePath(dir, "."); err != nil || same {
+		return err
+	}
+	if existing, err := hcs.SaltFiles(dir); err != nil || len(existing) > 0 {
+		return err
+	}
+	copied, err := hcs.CopySaltFiles(".", dir)
+	if err != nil {
+		return fmt.Errorf("failed to migrate the salt of the working directory: %w", err)
+	}
+	if len(copied) > 0 {
+		notice("Copied the salt of the working directory (%s) to %s. The CLI now uses that copy;\n",
+			strings.Join(copied, ", "), dir)
+		notice("the originals can be removed unless a server still runs from this directory.\n")
+	}
+	return nil
+}
+
+// samePath reports whether a and b name the same directory
+func samePath(a, b string) (bool, error) {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(ai, bi), nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/honeypot"
+	"github.com/corehuman/hcs-lab-api/internal/synth"
+)
+
+// runHoneypot implements `hcsgen honeypot`: it generates canary profiles and
+// their codes, and adds them to the honeypot file read by the server
+func runHoneypot(args []string) {
+	fset := flag.NewFlagSet("honeypot", flag.ExitOnError)
+	count := fset.Int("count", 5, "Number of canary profiles")
+	label := fset.String("label", "", "Where the codes will be planted, reported in alerts (e.g. partner-export-2025-03)")
+	output := fset.String("output", "honeypot.json", "Honeypot file to create or add to")
+	saltDir := fset.String("salt-dir", defaultSaltDir(), "Directory holding the server's salt files")
+	fset.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s honeypot --label <where> [--count 5] [--output honeypot.json] [--salt-dir <dir>]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Generate random canary profiles and their codes with the server's secret key and salt,\n")
+		fmt.Fprintf(os.Stderr, "and add them to a honeypot file. Plant the codes in a dataset and point the server's\n")
+		fmt.Fprintf(os.Stderr, "HCS_HONEYPOT_FILE at the file: their use raises an alert.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fset.PrintDefaults()
+	}
+	fset.Parse(args)
+	if *count < 1 {
+		exitUsage(fset.Usage, "--count must be positive")
+	}
+
+	file, err := honeypot.Load(*output)
+	if errors.Is(err, fs.ErrNotExist) {
+		file, err = honeypot.Load("")
+	}
+	if err != nil {
+		exitError(errcode.FileIO, "reading honeypot file", err)
+	}
+	generator, err := hcs.NewGenerator(hcs.WithSaltDir(*saltDir), hcs.WithClock(clk))
+	if err != nil {
+		exitError(errcode.Internal, "initializing generator", err)
+	}
+
+	// A random seed, so the canaries cannot be told apart from real profiles
+	// by regenerating the synthetic stream
+	var seed [8]byte
+	if _, err := rand.Read(seed[:]); err != nil {
+		exitError(errcode.Internal, "seeding profiles", err)
+	}
+	profiles := synth.New(synth.Options{Seed: binary.LittleEndian.Uint64(seed[:]), BirthRate: 0.5})
+	now := clk.Now().UTC().Truncate(time.Second)
+	added := make([]honeypot.Canary, *count)
+	for i := range added {
+		input := profiles.Profile()
+		out, err := generator.Generate(input)
+		if err != nil {
+			exitError(errcode.GenerationFailed, "generating canary codes", err)
+		}
+		added[i] = honeypot.Canary{Chip: out.Chip, Label: *label, CreatedAt: now, Input: input, Output: out}
+	}
+	file.Add(added...)
+	if err := file.Save(*output); err != nil {
+		exitError(errcode.FileIO, "writing honeypot file", err)
+	}
+
+	report(os.Stdout, added, "Added %d canaries to %s (%d in total)\n", len(added), *output, file.Len())
+	for _, c := range added {
+		notice("  %s  %s\n", c.Chip, c.Output.CodeU3)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/ledger"
+)
+
+// runLedger implements the `hcsgen ledger` commands
+func runLedger(args []string) {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s ledger verify [--ledger <file>] [--head <hash>]\n", os.Args[0])
+	}
+	if len(args) == 0 {
+		exitUsage(usage, "ledger command required")
+	}
+
+	switch args[0] {
+	case "verify":
+		runLedgerVerify(args[1:])
+	default:
+		exitUsage(usage, "unknown ledger command %q", args[0])
+	}
+}
+
+// runLedgerVerify implements `hcsgen ledger verify`: it exits 0 when the
+// ledger is intact and 1 when entries were modified, removed or reordered
+func runLedgerVerify(args []string) {
+	fs := flag.NewFlagSet("ledger verify", flag.ExitOnError)
+	path := fs.String("ledger", os.Getenv("HCS_LEDGER"), "Ledger file (default $HCS_LEDGER)")
+	head := fs.String("head", "", "Hash of an earlier head that must still be in the ledger, to detect entries removed from its end")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s ledger verify [--ledger <file>] [--head <hash>]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Check the hash chain of a generation ledger and, with HCS_SECRET_KEY set, the MAC\n")
+		fmt.Fprintf(os.Stderr, "of every entry. Exits 0 when the ledger is intact and 1 otherwise.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *path == "" {
+		exitUsage(fs.Usage, "--ledger or HCS_LEDGER is required")
+	}
+
+	key := ledgerKey()
+	if key == nil {
+		notice("HCS_SECRET_KEY is not set: checking the hash chain only, not the entry MACs\n")
+	}
+	result, err := ledger.Verify(*path, key, *head)
+	if err != nil {
+		exitError(errcode.FileIO, "reading ledger", err)
+	}
+
+	switch {
+	case jsonOutput:
+		report(os.Stdout, result, "")
+	case result.Valid():
+		report(os.Stdout, result, "OK  %s  %d entries  head %s\n", *path, result.Entries, result.Head)
+	default:
+		for _, p := range result.Problems {
+			if p.Seq > 0 {
+				printf("FAIL  line %d (entry %d): %s\n", p.Line, p.Seq, p.Detail)
+			} else {
+				printf("FAIL  line %d: %s\n", p.Line, p.Detail)
+			}
+		}
+	}
+	if !result.Valid() {
+		os.Exit(exitFailed)
+	}
+}
+
+// appendLedger records a generation in the ledger at path
+func appendLedger(path string, generator *hcs.Generator, output *hcs.OutputHCS) error {
+	salt := generator.GetSalt()
+	entry := ledger.NewEntry(output, generator.SaltEpoch(), hcs.SaltFingerprint(salt), clk.Now())
+	key := ledgerKey()
+	if key == nil {
+		notice("HCS_SECRET_KEY is not set: the ledger entry has no MAC\n")
+	}
+	_, err := ledger.Append(path, entry, key)
+	return err
+}
+
+// ledgerKey derives the key of the entry MACs from the secret key, or returns
+// nil when no secret key is configured
+func ledgerKey() []byte {
+	secret, err := hcs.NewEnvSecretProvider().SecretKey()
+	if err != nil {
+		return nil
+	}
+	key, err := hcs.DeriveKey(secret, nil, hcs.PurposeLedger)
+	if err != nil {
+		return nil
+	}
+	return key
+}
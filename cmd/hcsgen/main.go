@@ -7,28 +7,118 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+	"github.com/corehuman/hcs-lab-api/internal/envsubst"
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
 	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/jsonc"
+	"github.com/corehuman/hcs-lab-api/internal/yamljson"
 )
 
 const version = "1.0.0-hcs-lab"
 
+// clk is the clock of every command, frozen by HCS_FROZEN_TIME like the server's
+var clk clock.Clock = clock.System{}
+
 func main() {
+	// --quiet and --json apply to every command, wherever they appear
+	os.Args = append(os.Args[:1], parseGlobalFlags(os.Args[1:])...)
+
+	// HCS_FROZEN_TIME (RFC3339) stops the clock, for reproducible runs
+	if v := os.Getenv("HCS_FROZEN_TIME"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			exitError(errcode.InvalidOptions, "parsing HCS_FROZEN_TIME", err)
+		}
+		clk = clock.NewFrozen(t)
+	}
+
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			runReplay(os.Args[2:])
+			return
+		case "vectors":
+			runVectors()
+			return
+		case "admin":
+			runAdmin(os.Args[2:])
+			return
+		case "contract":
+			runContract(os.Args[2:])
+			return
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "dataset":
+			runDataset(os.Args[2:])
+			return
+		case "synth":
+			runSynth(os.Args[2:])
+			return
+		case "ping":
+			runPing(os.Args[2:])
+			return
+		case "self-update":
+			runSelfUpdate(os.Args[2:])
+			return
+		case "ledger":
+			runLedger(os.Args[2:])
+			return
+		case "honeypot":
+			runHoneypot(os.Args[2:])
+			return
+		case "templates":
+			runTemplates(os.Args[2:])
+			return
+		}
+	}
+
 	// Define command line flags
 	var (
 		u3Only   = flag.Bool("u3-only", false, "Only compute and output U3 code")
 		u4Only   = flag.Bool("u4-only", false, "Only compute and output U4 code")
 		pretty   = flag.Bool("pretty", false, "Pretty print JSON output")
-		rawJSON  = flag.Bool("raw-json", false, "Print only JSON to stdout (no extra text)")
+		rawJSON  = flag.Bool("raw-json", false, "Print only JSON to stdout (no extra text); same as --json")
+		trace    = flag.Bool("trace", false, "Include intermediate artifacts (normalized profile, canonical bytes, pillars, digests) in the JSON output")
+		autoNorm = flag.Bool("auto-normalize", false, "Rescale the modal values to sum to 1 before generating")
+		western  = flag.Bool("western-from-birth", false, "Derive the element and modal balances from the natal chart of birthInfo")
+		refDate  = flag.String("reference-date", "", "Date (YYYY-MM-DD) of the current Luck Pillar when birthInfo has a gender (default today)")
+		lenient  = flag.Bool("lenient-input", false, "Accept comments and trailing commas in the input file")
+		saltDir  = flag.String("salt-dir", defaultSaltDir(), "Directory holding the salt files (default from HCS_HOME or the user config directory)")
+		previous = flag.String("previous-chip", "", "Link the codes to the CHIP of the subject's previous codes (adds an LN lineage segment)")
+		ledgerTo = flag.String("ledger", os.Getenv("HCS_LEDGER"), "Append the generation to this hash-chained ledger file (default $HCS_LEDGER)")
 		showHelp = flag.Bool("help", false, "Show help information")
 		showVer  = flag.Bool("version", false, "Show version information")
 	)
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] input.json\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] input.json|input.yaml\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s replay --store <dsn> [--engine <version>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s vectors > tests/testdata/vectors.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s admin rotate-salt [--salt-dir <dir>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s admin backup-salt --recipient <key> [--salt-dir <dir>] [--output <file>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s admin restore-salt --identity <file> [--input <file>] [--salt-dir <dir>] [--force]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s contract --base-url <url> [--api-key <key>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s export --store <dsn> --output <file.parquet>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s dataset --store <dsn> --output-dir <dir>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s synth --count <n> --seed <seed> [--target <url>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s ping [--url <url>] [--expect-chip <chip>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s self-update [--channel stable|beta] [--check]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s ledger verify [--ledger <file>] [--head <hash>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s honeypot --label <where> [--count <n>] [--output honeypot.json]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s templates validate [--dir <dir>]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Generate HCS codes from an input profile\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nGlobal options, for every command:\n")
+		fmt.Fprintf(os.Stderr, "  --quiet, -q\n    \tPrint no informational messages, only results and errors\n")
+		fmt.Fprintf(os.Stderr, "  --json\n    \tPrint results as JSON, and errors as JSON on stderr\n")
+		fmt.Fprintf(os.Stderr, "  --lang en|fr\n    \tLanguage of labels, notices and errors (default $HCS_LANG, then en)\n")
+		fmt.Fprintf(os.Stderr, "\nExit codes: 0 ok, 1 checks failed, 2 validation, 3 configuration, 4 I/O, 5 internal\n")
 		fmt.Fprintf(os.Stderr, "\nExample:\n")
 		fmt.Fprintf(os.Stderr, "  %s input.json\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --u3-only profile.json\n", os.Args[0])
@@ -40,20 +130,18 @@ func main() {
 	// Handle help and version flags
 	if *showHelp {
 		flag.Usage()
-		os.Exit(0)
+		os.Exit(exitOK)
 	}
 
 	if *showVer {
-		fmt.Printf("hcsgen version %s\n", version)
-		os.Exit(0)
+		report(os.Stdout, map[string]string{"version": version}, "hcsgen version %s\n", version)
+		os.Exit(exitOK)
 	}
 
 	// Check for input file argument
 	args := flag.Args()
 	if len(args) != 1 {
-		fmt.Fprintf(os.Stderr, "Error: input file required\n")
-		flag.Usage()
-		os.Exit(1)
+		exitUsage(flag.Usage, "input file required")
 	}
 
 	inputFile := args[0]
@@ -61,35 +149,64 @@ func main() {
 	// Read input file
 	inputData, err := os.ReadFile(inputFile)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading input file: %v\n", err)
-		os.Exit(1)
+		exitError(errcode.FileIO, "reading input file", err)
+	}
+
+	// Resolve ${VAR} placeholders from the environment
+	if inputData, err = envsubst.Expand(inputData); err != nil {
+		exitError(errcode.InvalidJSON, "expanding environment variables", err)
+	}
+
+	// Convert YAML input, and strip comments and trailing commas from
+	// hand-written JSON
+	switch {
+	case yamljson.IsFile(inputFile):
+		if inputData, err = yamljson.ToJSON(inputData); err != nil {
+			exitError(errcode.InvalidJSON, "parsing input YAML", err)
+		}
+	case *lenient:
+		if inputData, err = jsonc.Standardize(inputData); err != nil {
+			exitError(errcode.InvalidJSON, "parsing input JSON", err)
+		}
 	}
 
 	// Parse input JSON
 	var input hcs.InputProfile
 	if err := json.Unmarshal(inputData, &input); err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing input JSON: %v\n", err)
-		os.Exit(1)
+		exitError(errcode.InvalidJSON, "parsing input JSON", err)
 	}
 
-	// Create generator
-	generator, err := hcs.NewGenerator()
+	// Create generator, migrating a salt left in the working directory to the default location
+	saltDirSet := false
+	flag.Visit(func(f *flag.Flag) { saltDirSet = saltDirSet || f.Name == "salt-dir" })
+	if err := prepareSaltDir(*saltDir, !saltDirSet); err != nil {
+		exitError(errcode.SaltUnavailable, "preparing salt directory", err)
+	}
+	generator, err := hcs.NewGenerator(hcs.WithSaltDir(*saltDir), hcs.WithClock(clk))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error initializing generator: %v\n", err)
-		os.Exit(1)
+		exitError(errcode.Internal, "initializing generator", err)
 	}
 
 	// Set generation options
 	opts := &hcs.GeneratorOptions{
 		U3Only: *u3Only,
 		U4Only: *u4Only,
+		Trace:  *trace,
+
+		PreviousChip:     *previous,
+		ModalValidation:  hcs.ModalValidation{Normalize: *autoNorm},
+		WesternFromBirth: *western,
+	}
+	if *refDate != "" {
+		if opts.ReferenceDate, err = time.Parse(time.DateOnly, *refDate); err != nil {
+			exitError(errcode.InvalidRequest, "parsing --reference-date", err)
+		}
 	}
 
 	// Generate HCS codes
 	output, err := generator.GenerateWithOptions(&input, opts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error generating HCS codes: %v\n", err)
-		os.Exit(1)
+		exitError(errcode.GenerationFailed, "generating HCS codes", err)
 	}
 
 	// Prepare output file names
@@ -105,13 +222,11 @@ func main() {
 		jsonData, err = json.Marshal(output)
 	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error marshaling output: %v\n", err)
-		os.Exit(1)
+		exitError(errcode.Internal, "marshaling output", err)
 	}
 
 	if err := os.WriteFile(outputJSONFile, jsonData, 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "Error writing output.json: %v\n", err)
-		os.Exit(1)
+		exitError(errcode.FileIO, "writing output.json", err)
 	}
 
 	// Write HCS file (codes only)
@@ -125,43 +240,68 @@ func main() {
 	if output.CodeU5 != "" {
 		hcsContent = append(hcsContent, output.CodeU5)
 	}
+	if output.CodeU6 != "" {
+		hcsContent = append(hcsContent, output.CodeU6)
+	}
 	hcsData := []byte(strings.Join(hcsContent, "\n"))
 	if err := os.WriteFile(outputHCSFile, hcsData, 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "Error writing output.hcs: %v\n", err)
-		os.Exit(1)
+		exitError(errcode.FileIO, "writing output.hcs", err)
+	}
+
+	if *ledgerTo != "" {
+		if err := appendLedger(*ledgerTo, generator, output); err != nil {
+			exitError(errcode.FileIO, "appending to ledger", err)
+		}
 	}
 
 	// Output to stdout
-	if *rawJSON {
+	if quiet {
+		return
+	}
+	if *rawJSON || jsonOutput {
 		// Print only JSON
 		fmt.Print(string(jsonData))
 	} else {
 		// Print the HCS codes with labels
 		if output.CodeU3 != "" {
-			fmt.Printf("HCS-U3: %s\n", output.CodeU3)
+			printf("HCS-U3: %s\n", output.CodeU3)
 		}
 		if output.CodeU4 != "" {
-			fmt.Printf("HCS-U4: %s\n", output.CodeU4)
+			printf("HCS-U4: %s\n", output.CodeU4)
 		}
 		if output.CodeU5 != "" {
-			fmt.Printf("HCS-U5: %s\n", output.CodeU5)
+			printf("HCS-U5: %s\n", output.CodeU5)
 			if output.ChineseProfile != nil {
-				fmt.Printf("\nChinese BaZi Profile detected:\n")
-				fmt.Printf("  Four Pillars: %s | %s | %s | %s\n",
+				printf("\nChinese BaZi Profile detected:\n")
+				printf("  Four Pillars: %s | %s | %s | %s\n",
 					output.ChineseProfile.YearPillar,
 					output.ChineseProfile.MonthPillar,
 					output.ChineseProfile.DayPillar,
 					output.ChineseProfile.HourPillar)
-				fmt.Printf("  Day Master: %s (Strength: %.0f%%)\n",
+				printf("  Day Master: %s (Strength: %.0f%%)\n",
 					output.ChineseProfile.DayMaster,
 					output.ChineseProfile.DayMasterStrength*100)
-				fmt.Printf("  Yin/Yang Balance: %.0f%% Yang\n",
+				printf("  Yin/Yang Balance: %.0f%% Yang\n",
 					output.ChineseProfile.YinYangBalance*100)
 			}
 		}
-		fmt.Printf("\nCHIP: %s\n", output.Chip)
-		fmt.Printf("\nOutput written to:\n")
-		fmt.Printf("  - %s (full JSON)\n", outputJSONFile)
-		fmt.Printf("  - %s (codes only)\n", outputHCSFile)
+		if output.CodeU6 != "" {
+			printf("HCS-U6: %s\n", output.CodeU6)
+		}
+		if output.Archetype != nil {
+			printf("\nArchetype: %s (%s)\n", output.Archetype.Name, hcs.ArchetypeSegment(*output.Archetype))
+		}
+		for _, warning := range output.Warnings {
+			printf("Warning: %s\n", warning)
+		}
+		printf("\nCHIP: %s\n", output.Chip)
+		if output.Trace != nil {
+			printf("CHIP digest: %s\n", output.Trace.ChipDigest)
+			printf("Salt fingerprint: %s\n", output.Trace.SaltFingerprint)
+		}
+		printf("\nOutput written to:\n")
+		printf("  - %s (full JSON)\n", outputJSONFile)
+		printf("  - %s (codes only)\n", outputHCSFile)
 	}
+	noticeNewVersion()
 }
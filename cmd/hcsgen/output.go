@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/corehuman/hcs-lab-api/internal/i18n"
+)
+
+// Global flags, accepted anywhere on the command line by every command
+var (
+	// quiet (--quiet, -q) drops informational messages; results meant for
+	// piping, failures and errors are still written
+	quiet bool
+	// jsonOutput (--json) writes results as one JSON document and errors as
+	// JSON on stderr
+	jsonOutput bool
+	// lang (--lang, default $HCS_LANG) is the language of labels, notices and
+	// error messages; JSON output is never translated
+	lang = i18n.Match(os.Getenv("HCS_LANG"))
+)
+
+// parseGlobalFlags removes the global flags from args, up to a "--"
+// terminator, and applies them
+func parseGlobalFlags(args []string) []string {
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(out, args[i:]...)
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		var target *bool
+		switch {
+		case !strings.HasPrefix(arg, "-"):
+		case name == "quiet" || name == "q":
+			target = &quiet
+		case name == "json":
+			target = &jsonOutput
+		case name == "lang":
+			if !hasValue && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			if lang = i18n.Match(value); lang == "" {
+				fmt.Fprintf(os.Stderr, "Error: unsupported language %q for flag --lang (supported: %s)\n", value, strings.Join(i18n.Locales(), ", "))
+				os.Exit(exitValidation)
+			}
+			continue
+		}
+		if target == nil {
+			out = append(out, arg)
+			continue
+		}
+		on := true
+		if hasValue {
+			var err error
+			if on, err = strconv.ParseBool(value); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid value %q for flag %s\n", value, arg)
+				os.Exit(exitValidation)
+			}
+		}
+		*target = on
+	}
+	return out
+}
+
+// report writes the result of a command to w: v as JSON with --json, nothing
+// with --quiet, and otherwise the formatted message
+func report(w io.Writer, v any, format string, args ...any) {
+	switch {
+	case jsonOutput:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(v)
+	case !quiet:
+		fmt.Fprint(w, tr(format, args...))
+	}
+}
+
+// notice writes an informational message to stderr unless --quiet
+func notice(format string, args ...any) {
+	if !quiet {
+		fmt.Fprint(os.Stderr, tr(format, args...))
+	}
+}
+
+// printf writes a result line to stdout in the --lang language
+func printf(format string, args ...any) {
+	fmt.Print(tr(format, args...))
+}
+
+// tr formats args with the translation of format in the --lang language
+func tr(format string, args ...any) string {
+	return i18n.Sprintf(lang, format, args...)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/contract"
+)
+
+// runPing implements `hcsgen ping`, a healthcheck of a running server: it
+// exits 0 when the server is healthy and 1 otherwise, as Docker HEALTHCHECK
+// and uptime monitors expect
+func runPing(args []string) {
+	fs := flag.NewFlagSet("ping", flag.ExitOnError)
+	url := fs.String("url", defaultPingURL(), "Base URL of the server (default http://localhost:$PORT, or port 8080)")
+	expectChip := fs.String("expect-chip", os.Getenv("HCS_PING_CHIP"), "CHIP the canary profile must get (default $HCS_PING_CHIP); without it, two generations must agree")
+	apiKey := fs.String("api-key", os.Getenv("HCS_API_KEY"), "X-API-Key to send (default $HCS_API_KEY)")
+	timeout := fs.Duration("timeout", 5*time.Second, "Overall time limit")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s ping [--url <url>] [--expect-chip <chip>]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Check GET /health, then generate a built-in canary profile and check that its\n")
+		fmt.Fprintf(os.Stderr, "CHIP is deterministic. Exits 0 when healthy and 1 otherwise.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	suite := &contract.Suite{BaseURL: *url, APIKey: *apiKey, Client: &http.Client{}}
+	result := suite.Ping(ctx, *expectChip)
+
+	switch {
+	case jsonOutput:
+		report(os.Stdout, result, "")
+	case result.Healthy:
+		report(os.Stdout, result, "OK  %s  chip %s  %dms\n", *url, result.Chip, result.LatencyMs)
+	default:
+		for _, c := range result.Checks {
+			if !c.Passed {
+				printf("FAIL  %s: %s\n", c.Name, c.Detail)
+			}
+		}
+	}
+	if !result.Healthy {
+		os.Exit(exitFailed)
+	}
+}
+
+// defaultPingURL is the server of this host, on $PORT as the server reads it
+func defaultPingURL() string {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	return "http://localhost:" + port
+}
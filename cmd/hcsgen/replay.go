@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+// ReplayReport summarizes the impact of re-generating stored profiles with a
+// different engine or fusion configuration
+type ReplayReport struct {
+	Engine       string          `json:"engine"`
+	FusionConfig string          `json:"fusionConfig,omitempty"`
+	Total        int             `json:"total"`
+	Unchanged    int             `json:"unchanged"`
+	Failed       int             `json:"failed"`
+	Changed      map[string]int  `json:"changed"`      // per output field: chip, codeU3, ...
+	SegmentDiffs map[string]int  `json:"segmentDiffs"` // per code segment, e.g. "U5:C"
+	Failures     []ReplayFailure `json:"failures,omitempty"`
+}
+
+// ReplayFailure records a stored profile that could not be re-generated
+type ReplayFailure struct {
+	Chip  string `json:"chip"`
+	Error string `json:"error"`
+}
+
+// runReplay implements `hcsgen replay`
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	storeDSN := fs.String("store", "", "Storage DSN holding the records to replay (e.g. file:./hcs_store.json)")
+	engine := fs.String("engine", hcs.CurrentEngineVersion, "Engine version to replay with ("+strings.Join(hcs.SupportedEngineVersions(), ", ")+")")
+	fusionConfig := fs.String("fusion-config", "", "Fusion config ID to replay every record with (default: the one each record was issued with)")
+	fusionConfigs := fs.String("fusion-configs", "", "JSON file of fusion configs to register before replaying")
+	saltDir := fs.String("salt-dir", defaultSaltDir(), "Directory containing the .hcs_salt used at issuance")
+	outFile := fs.String("output", "", "Write the report to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s replay --store <dsn> [--engine v1] [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Re-generate stored input profiles and report how the codes would change\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *storeDSN == "" {
+		exitUsage(fs.Usage, "--store is required")
+	}
+	if _, err := hcs.ResolveEngineVersion(*engine); err != nil {
+		exitError(errcode.InvalidOptions, "", err)
+	}
+	if *fusionConfigs != "" {
+		if err := hcs.LoadFusionConfigs(*fusionConfigs); err != nil {
+			exitError(errcode.InvalidOptions, "loading fusion configs", err)
+		}
+	}
+	if *fusionConfig != "" {
+		if _, err := hcs.LookupFusionConfig(*fusionConfig); err != nil {
+			exitError(errcode.InvalidOptions, "", err)
+		}
+	}
+
+	s, err := store.Open(*storeDSN)
+	if err != nil {
+		exitError(errcode.StorageUnavailable, "opening store", err)
+	}
+	records, err := s.ListAll(context.Background())
+	if err != nil {
+		exitError(errcode.LookupFailed, "reading store", err)
+	}
+
+	// Each record is replayed at its own issuance time
+	clk := clock.NewFrozen(time.Time{})
+	generator, err := hcs.NewGenerator(hcs.WithSaltDir(*saltDir), hcs.WithExistingSalt(), hcs.WithClock(clk))
+	if err != nil {
+		exitError(errcode.Internal, "initializing generator", err)
+	}
+
+	opts := &hcs.GeneratorOptions{
+		EngineVersion:  *engine,
+		FusionConfigID: *fusionConfig,
+	}
+	report := replayRecords(generator, clk, records, opts)
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		exitError(errcode.Internal, "marshaling report", err)
+	}
+	if *outFile != "" {
+		if err := os.WriteFile(*outFile, data, 0644); err != nil {
+			exitError(errcode.FileIO, "writing report", err)
+		}
+		return
+	}
+	fmt.Println(string(data))
+}
+
+func replayRecords(generator *hcs.Generator, clk *clock.Frozen, records []store.Record, opts *hcs.GeneratorOptions) *ReplayReport {
+	report := &ReplayReport{
+		Engine:       opts.EngineVersion,
+		FusionConfig: opts.FusionConfigID,
+		Total:        len(records),
+		Changed:      map[string]int{},
+		SegmentDiffs: map[string]int{},
+	}
+
+	for _, rec := range records {
+		input := rec.Input
+		clk.Set(rec.CreatedAt)
+		recOpts := *opts
+		if recOpts.FusionConfigID == "" && rec.Output != nil && rec.Output.Metadata != nil {
+			recOpts.FusionConfigID = rec.Output.Metadata.FusionConfig
+		}
+		replayed, err := generator.GenerateWithOptions(&input, &recOpts)
+		if err != nil {
+			report.Failed++
+			report.Failures = append(report.Failures, ReplayFailure{Chip: rec.Chip, Error: err.Error()})
+			continue
+		}
+
+		stored := rec.Output
+		if stored == nil {
+			stored = &hcs.OutputHCS{Chip: rec.Chip}
+		}
+		fields := []struct {
+			name, level string
+			old, new    string
+		}{
+			{"chip", "", stored.Chip, replayed.Chip},
+			{"codeU3", "U3", stored.CodeU3, replayed.CodeU3},
+			{"codeU4", "U4", stored.CodeU4, replayed.CodeU4},
+			{"codeU5", "U5", stored.CodeU5, replayed.CodeU5},
+			{"codeU6", "U6", stored.CodeU6, replayed.CodeU6},
+			{"codeU7", "U7", stored.CodeU7, replayed.CodeU7},
+		}
+
+		changed := false
+		for _, f := range fields {
+			if f.old == f.new {
+				continue
+			}
+			changed = true
+			report.Changed[f.name]++
+			if f.level == "" || f.level == "U4" || f.level == "U6" {
+				continue // opaque values: no segment breakdown
+			}
+			for _, seg := range hcs.DiffCodeSegments(f.old, f.new) {
+				report.SegmentDiffs[f.level+":"+seg]++
+			}
+		}
+		if !changed {
+			report.Unchanged++
+		}
+	}
+	return report
+}
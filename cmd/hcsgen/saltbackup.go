@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/saltbackup"
+)
+
+// stringList is a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// runBackupSalt implements `hcsgen admin backup-salt`
+func runBackupSalt(args []string) {
+	fs := flag.NewFlagSet("backup-salt", flag.ExitOnError)
+	saltDir := fs.String("salt-dir", defaultSaltDir(), "Directory containing the salt files")
+	output := fs.String("output", "-", "Backup file, or - for stdout")
+	armored := fs.Bool("armor", false, "Write an ASCII-armored (PEM) backup")
+	var recipients stringList
+	fs.Var(&recipients, "recipient", "age (age1...) or SSH public key to encrypt to; repeatable")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s admin backup-salt --recipient <key> [--salt-dir <dir>] [--output <file>] [--armor]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Export every salt epoch and its seal, encrypted with age to the operator keys.\n")
+		fmt.Fprintf(os.Stderr, "Losing the salt invalidates every CHIP ever issued; keep the backup off the host.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if len(recipients) == 0 {
+		exitUsage(fs.Usage, "--recipient is required")
+	}
+
+	var parsed []age.Recipient
+	for _, r := range recipients {
+		recipient, err := saltbackup.ParseRecipient(r)
+		if err != nil {
+			exitError(errcode.InvalidRequest, fmt.Sprintf("parsing recipient %q", r), err)
+		}
+		parsed = append(parsed, recipient)
+	}
+
+	out := os.Stdout
+	if *output != "-" {
+		f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			exitError(errcode.FileIO, "creating backup", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	archive, err := saltbackup.Backup(*saltDir, out, *armored, clk.Now(), parsed...)
+	if err != nil {
+		if *output != "-" {
+			os.Remove(*output)
+		}
+		exitError(errcode.FileIO, "backing up salt", err)
+	}
+	// The backup itself may be on stdout, so the summary goes to stderr
+	report(os.Stderr, map[string]any{"files": archive.Files, "saltDir": *saltDir, "recipients": len(parsed)},
+		"Backed up %d files from %s for %d recipient(s)\n", len(archive.Files), *saltDir, len(parsed))
+}
+
+// runRestoreSalt implements `hcsgen admin restore-salt`
+func runRestoreSalt(args []string) {
+	fs := flag.NewFlagSet("restore-salt", flag.ExitOnError)
+	saltDir := fs.String("salt-dir", defaultSaltDir(), "Directory to restore the salt files into")
+	input := fs.String("input", "-", "Backup file, or - for stdin")
+	identity := fs.String("identity", "", "age identity file or SSH private key")
+	force := fs.Bool("force", false, "Replace salt files that differ from the backup (changes every CHIP issued under them)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s admin restore-salt --identity <file> [--input <file>] [--salt-dir <dir>] [--force]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Decrypt a backup-salt backup into the salt directory. Existing identical files are kept;\n")
+		fmt.Fprintf(os.Stderr, "differing ones are only replaced with --force.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *identity == "" {
+		exitUsage(fs.Usage, "--identity is required")
+	}
+
+	data, err := os.ReadFile(*identity)
+	if err != nil {
+		exitError(errcode.FileIO, "reading identity", err)
+	}
+	identities, err := saltbackup.ParseIdentities(data)
+	if err != nil {
+		exitError(errcode.InvalidRequest, "parsing identity", err)
+	}
+
+	in := os.Stdin
+	if *input != "-" {
+		f, err := os.Open(*input)
+		if err != nil {
+			exitError(errcode.FileIO, "opening backup", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	result, err := saltbackup.Restore(*saltDir, in, *force, identities...)
+	if err != nil {
+		exitError(errcode.FileIO, "restoring salt", err)
+	}
+
+	// With the secret key available, check the restored salts against their seals
+	provider := hcs.DirSaltProvider{Dir: *saltDir, Secrets: hcs.NewEnvSecretProvider()}
+	_, current, err := provider.SaltEpochs()
+	if err != nil {
+		exitError(errcode.SaltUnavailable, "loading restored salts", err)
+	}
+	report(os.Stdout, map[string]any{"written": result.Written, "unchanged": result.Unchanged, "saltDir": *saltDir, "currentEpoch": current},
+		"Restored %d files into %s (%d already identical)\nCurrent salt epoch: %d\n",
+		len(result.Written), *saltDir, len(result.Unchanged), current)
+}
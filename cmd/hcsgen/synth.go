@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/synth"
+)
+
+// runSynth implements `hcsgen synth`: it prints synthetic profiles, or fires
+// them at a deployment with --target
+func runSynth(args []string) {
+	fs := flag.NewFlagSet("synth", flag.ExitOnError)
+	count := fs.Int("count", 1000, "Number of profiles")
+	seed := fs.Uint64("seed", 1, "Random seed; the same seed gives the same profiles")
+	birthRate := fs.Float64("birth-rate", 0.5, "Share of profiles with birth info, from 0 to 1")
+	outFile := fs.String("output", "", "Write the profiles as JSON lines to this file instead of stdout")
+	target := fs.String("target", "", "Base URL to load test instead of printing profiles (e.g. http://localhost:8080)")
+	apiKey := fs.String("api-key", os.Getenv("HCS_API_KEY"), "X-API-Key to send (default $HCS_API_KEY)")
+	concurrency := fs.Int("concurrency", 8, "Concurrent requests in load-test mode")
+	timeout := fs.Duration("timeout", 10*time.Minute, "Overall time limit for the load test")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s synth [--count 100000] [--seed 42] [--output profiles.jsonl]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s synth --target <url> [--count N] [--concurrency 8]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Generate realistic random input profiles, one JSON object per line. With --target,\n")
+		fmt.Fprintf(os.Stderr, "send them to POST /api/generate instead and report latency percentiles (as JSON\n")
+		fmt.Fprintf(os.Stderr, "with --json, none with --quiet); the load test exits 1 if any request fails.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *count < 1 || *birthRate < 0 || *birthRate > 1 {
+		exitUsage(fs.Usage, "--count must be positive and --birth-rate between 0 and 1")
+	}
+	gen := synth.New(synth.Options{Seed: *seed, BirthRate: *birthRate})
+
+	if *target != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		lt := &synth.LoadTest{BaseURL: *target, APIKey: *apiKey, Client: &http.Client{Timeout: 30 * time.Second}, Concurrency: *concurrency}
+		report := lt.Run(ctx, gen, *count)
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(report)
+		} else if !quiet {
+			printf("Requests:   %d (%d errors)\n", report.Requests, report.Errors)
+			printf("Duration:   %.0f ms (%.2f req/s)\n", report.DurationMs, report.Throughput)
+			printf("Latency:    p50 %.2f ms, p90 %.2f ms, p99 %.2f ms, max %.2f ms\n", report.P50, report.P90, report.P99, report.Max)
+			for status, n := range report.StatusCodes {
+				printf("Status %d: %d\n", status, n)
+			}
+		}
+		if report.Errors > 0 {
+			os.Exit(exitFailed)
+		}
+		return
+	}
+
+	var w io.Writer = os.Stdout
+	if *outFile != "" {
+		f, err := os.Create(*outFile)
+		if err != nil {
+			exitError(errcode.FileIO, "creating output file", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	for i := 0; i < *count; i++ {
+		if err := enc.Encode(gen.Profile()); err != nil {
+			exitError(errcode.FileIO, "writing profiles", err)
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		exitError(errcode.FileIO, "writing profiles", err)
+	}
+}
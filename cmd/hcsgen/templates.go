@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	reports "github.com/corehuman/hcs-lab-api/internal/report"
+)
+
+// runTemplates implements the `hcsgen templates` commands
+func runTemplates(args []string) {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s templates validate [--dir <dir>]\n", os.Args[0])
+	}
+	if len(args) == 0 {
+		exitUsage(usage, "templates command required")
+	}
+
+	switch args[0] {
+	case "validate":
+		runTemplatesValidate(args[1:])
+	default:
+		exitUsage(usage, "unknown templates command %q", args[0])
+	}
+}
+
+// TemplatesResult is the outcome of `hcsgen templates validate`
+type TemplatesResult struct {
+	Dir       string   `json:"dir"`
+	Overrides []string `json:"overrides,omitempty"`
+	Samples   int      `json:"samples"`
+	Valid     bool     `json:"valid"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// runTemplatesValidate implements `hcsgen templates validate`: it exits 0
+// when the report templates of a directory load and render every sample
+// report, and 1 otherwise
+func runTemplatesValidate(args []string) {
+	fs := flag.NewFlagSet("templates validate", flag.ExitOnError)
+	dir := fs.String("dir", os.Getenv("HCS_REPORT_TEMPLATES"), "Directory of the override templates (default $HCS_REPORT_TEMPLATES)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s templates validate [--dir <dir>]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Check that the report templates of a directory, %s and %s,\n", reports.HTMLFile, reports.TextFile)
+		fmt.Fprintf(os.Stderr, "parse within the sandbox and render the sample reports, as the server does when it\n")
+		fmt.Fprintf(os.Stderr, "loads them. Exits 0 when they are valid and 1 otherwise.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *dir == "" {
+		exitUsage(fs.Usage, "--dir or HCS_REPORT_TEMPLATES is required")
+	}
+
+	result := TemplatesResult{Dir: *dir, Samples: len(reports.Samples())}
+	templates, err := reports.Load(*dir)
+	if err != nil {
+		result.Errors = strings.Split(err.Error(), "\n")
+	} else {
+		result.Overrides, result.Valid = templates.Overrides, true
+	}
+
+	switch {
+	case jsonOutput:
+		report(os.Stdout, result, "")
+	case result.Valid:
+		for _, path := range result.Overrides {
+			report(os.Stdout, result, "OK  %s  %d samples\n", path, result.Samples)
+		}
+	default:
+		for _, e := range result.Errors {
+			printf("FAIL  %s: %s\n", *dir, e)
+		}
+	}
+	if !result.Valid {
+		os.Exit(exitFailed)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/selfupdate"
+)
+
+// Release builds set these with
+// -ldflags "-X main.releaseKey=<base64 Ed25519 public key> -X main.releaseURL=<url>".
+// Without a release key, self-update and the update check are unavailable.
+var (
+	releaseKey = ""
+	releaseURL = "https://github.com/zefparis/HCS-LAB/releases/latest/download"
+)
+
+const (
+	// updateCheckInterval is how often the passive update check contacts the release server
+	updateCheckInterval = 24 * time.Hour
+	updateCheckTimeout  = 2 * time.Second
+	updateCheckFile     = "update-check.json"
+)
+
+// updateResult is the result of `hcsgen self-update`
+type updateResult struct {
+	Version       string `json:"version"`
+	Latest        string `json:"latest"`
+	Channel       string `json:"channel"`
+	EngineVersion string `json:"engineVersion,omitempty"`
+	Available     bool   `json:"available"`
+	Updated       bool   `json:"updated"`
+	Path          string `json:"path,omitempty"`
+}
+
+// updateCheck is the cached result of the passive update check
+type updateCheck struct {
+	CheckedAt     time.Time `json:"checkedAt"`
+	Channel       string    `json:"channel"`
+	Latest        string    `json:"latest"`
+	EngineVersion string    `json:"engineVersion,omitempty"`
+}
+
+// runSelfUpdate implements `hcsgen self-update`, installing the latest
+// release of a channel in place of the running binary
+func runSelfUpdate(args []string) {
+	fs := flag.NewFlagSet("self-update", flag.ExitOnError)
+	channel := fs.String("channel", updateChannel(), "Release channel: stable or beta (default $HCS_UPDATE_CHANNEL or stable)")
+	check := fs.Bool("check", false, "Only report whether a newer release is available")
+	timeout := fs.Duration("timeout", 5*time.Minute, "Time limit for the check and download")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s self-update [--channel stable|beta] [--check]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Install the latest release of a channel in place of this binary. The release\n")
+		fmt.Fprintf(os.Stderr, "manifest must be signed with the release key built into this binary, and the\n")
+		fmt.Fprintf(os.Stderr, "downloaded binary must match the checksum in the manifest.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	client, err := updateClient(nil)
+	if err != nil {
+		exitError(errcode.UpdateUnavailable, "checking for updates", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	release, err := client.Latest(ctx, *channel)
+	if err != nil {
+		exitError(errcode.UpdateUnavailable, "checking for updates", err)
+	}
+	saveUpdateCheck(release)
+
+	result := updateResult{
+		Version:       version,
+		Latest:        release.Version,
+		Channel:       release.Channel,
+		EngineVersion: release.EngineVersion,
+		Available:     selfupdate.Newer(release.Version, version),
+	}
+	if !result.Available {
+		report(os.Stdout, result, "hcsgen %s is up to date (%s channel: %s)\n", version, release.Channel, release.Version)
+		return
+	}
+	if *check {
+		report(os.Stdout, result, "hcsgen %s is available on the %s channel (you have %s)\n", release.Version, release.Channel, version)
+		return
+	}
+
+	asset, ok := release.Asset(runtime.GOOS, runtime.GOARCH)
+	if !ok {
+		exitError(errcode.UpdateUnavailable, "", fmt.Errorf("release %s has no build for %s/%s", release.Version, runtime.GOOS, runtime.GOARCH))
+	}
+	binary, err := client.Download(ctx, release, asset)
+	if err != nil {
+		exitError(errcode.UpdateUnavailable, "downloading release", err)
+	}
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		exitError(errcode.FileIO, "locating the hcsgen binary", err)
+	}
+	if err := selfupdate.Replace(exe, binary); err != nil {
+		exitError(errcode.FileIO, "installing release", err)
+	}
+
+	result.Updated, result.Path = true, exe
+	report(os.Stdout, result, "Updated hcsgen %s to %s (%s channel): %s\n", version, release.Version, release.Channel, exe)
+}
+
+// updateClient returns the release client of this build; HCS_UPDATE_URL
+// overrides the release server, e.g. for a mirror
+func updateClient(httpClient *http.Client) (*selfupdate.Client, error) {
+	if releaseKey == "" {
+		return nil, errcode.Errorf(errcode.UpdateUnavailable, "this build has no release key; install release builds to update in place")
+	}
+	key, err := selfupdate.ParsePublicKey(releaseKey)
+	if err != nil {
+		return nil, err
+	}
+	base := releaseURL
+	if v := os.Getenv("HCS_UPDATE_URL"); v != "" {
+		base = v
+	}
+	return &selfupdate.Client{BaseURL: base, PublicKey: key, HTTPClient: httpClient}, nil
+}
+
+// updateChannel returns the release channel of $HCS_UPDATE_CHANNEL, or stable
+func updateChannel() string {
+	if v := os.Getenv("HCS_UPDATE_CHANNEL"); v != "" {
+		return v
+	}
+	return selfupdate.Stable
+}
+
+// noticeNewVersion prints a notice when a newer release is available. It
+// contacts the release server at most once a day, caching the result in the
+// CLI's home directory, and is skipped with --quiet or --json, in CI, without
+// a release key, or when HCS_NO_UPDATE_CHECK is set.
+func noticeNewVersion() {
+	if quiet || jsonOutput || releaseKey == "" || os.Getenv("HCS_NO_UPDATE_CHECK") != "" || os.Getenv("CI") != "" {
+		return
+	}
+	channel := updateChannel()
+	last, ok := loadUpdateCheck()
+	if !ok || last.Channel != channel || clk.Now().Sub(last.CheckedAt) > updateCheckInterval {
+		client, err := updateClient(&http.Client{Timeout: updateCheckTimeout})
+		if err != nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+		defer cancel()
+		release, err := client.Latest(ctx, channel)
+		if err != nil {
+			return
+		}
+		last = saveUpdateCheck(release)
+	}
+
+	if !selfupdate.Newer(last.Latest, version) {
+		return
+	}
+	notice("\nhcsgen %s is available (you have %s); run `%s self-update` to install it.\n", last.Latest, version, filepath.Base(os.Args[0]))
+	if last.EngineVersion != "" && last.EngineVersion != hcs.CurrentEngineVersion {
+		notice("It generates codes with engine %s; this version uses %s.\n", last.EngineVersion, hcs.CurrentEngineVersion)
+	}
+	notice("Set HCS_NO_UPDATE_CHECK=1 to turn off this check.\n")
+}
+
+func loadUpdateCheck() (updateCheck, bool) {
+	var c updateCheck
+	data, err := os.ReadFile(filepath.Join(defaultSaltDir(), updateCheckFile))
+	if err != nil || json.Unmarshal(data, &c) != nil {
+		return c, false
+	}
+	return c, true
+}
+
+// saveUpdateCheck caches release as the latest of its channel. Failures are
+// ignored: the check is then repeated on the next run.
+func saveUpdateCheck(release *selfupdate.Release) updateCheck {
+	c := updateCheck{
+		CheckedAt:     clk.Now().UTC(),
+		Channel:       release.Channel,
+		Latest:        release.Version,
+		EngineVersion: release.EngineVersion,
+	}
+	if data, err := json.Marshal(c); err == nil {
+		dir := defaultSaltDir()
+		if os.MkdirAll(dir, 0700) == nil {
+			os.WriteFile(filepath.Join(dir, updateCheckFile), data, 0600)
+		}
+	}
+	return c
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// runVectors implements `hcsgen vectors`, printing the published test vectors
+func runVectors() {
+	vectors, err := hcs.TestVectors()
+	if err != nil {
+		exitError(errcode.TestVectorsFailed, "computing test vectors", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(vectors); err != nil {
+		exitError(errcode.FileIO, "writing test vectors", err)
+	}
+}
@@ -0,0 +1,32 @@
+// Command hcsrefgen writes the Python and TypeScript reference tables for HCS
+// ports. It runs via `go generate ./internal/hcs`.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/corehuman/hcs-lab-api/internal/refgen"
+)
+
+func main() {
+	out := flag.String("out", "ports", "Directory to write the python/ and typescript/ tables into")
+	flag.Parse()
+
+	files := map[string][]byte{
+		filepath.Join(*out, "python", "hcs_tables.py"):    refgen.Python(),
+		filepath.Join(*out, "typescript", "hcsTables.ts"): refgen.TypeScript(),
+	}
+	for path, data := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", filepath.Dir(path), err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+			os.Exit(1)
+		}
+	}
+}
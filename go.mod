@@ -1,10 +1,20 @@
 module github.com/corehuman/hcs-lab-api
 
-go 1.22
+go 1.22.0
 
 require (
+	filippo.io/age v1.2.1
+	github.com/cloudflare/circl v1.6.1
 	github.com/go-chi/chi/v5 v5.2.3
 	github.com/go-chi/cors v1.2.2
-	golang.org/x/crypto v0.31.0
+	github.com/mattn/go-sqlite3 v1.14.33
 	github.com/zeebo/blake3 v0.2.3
+	golang.org/x/crypto v0.31.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	filippo.io/edwards25519 v1.1.0 // indirect
+	github.com/klauspost/cpuid/v2 v2.0.12 // indirect
+	golang.org/x/sys v0.28.0 // indirect
 )
@@ -0,0 +1,194 @@
+// Package anomaly flags unusual generation traffic per API key: sudden
+// spikes, many validation failures, and the same profile generated over and
+// over. These are the usual signatures of a stolen key being scripted
+// against the API. Detection works on fixed windows kept in memory; it is a
+// tripwire for operators, not a rate limiter.
+package anomaly
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Kind names a detected pattern
+type Kind string
+
+const (
+	Spike              Kind = "spike"               // far more requests than the key's usual rate
+	ValidationFailures Kind = "validation_failures" // a high share of rejected inputs
+	RepeatedProfile    Kind = "repeated_profile"    // one profile generated many times
+)
+
+// Config sets the detection thresholds. Zero fields use the defaults.
+type Config struct {
+	Window time.Duration // length of a counting window; default 5m
+	// A window is a spike when it has at least SpikeMin requests and
+	// SpikeFactor times the key's average of previous windows
+	SpikeMin    int
+	SpikeFactor float64
+	// Validation failures are flagged from FailureMin failures making up at
+	// least FailureRatio of the window's requests
+	FailureMin   int
+	FailureRatio float64
+	RepeatMin    int // generations of one profile in a window that are flagged
+}
+
+// Defaults for zero Config fields
+const (
+	DefaultWindow       = 5 * time.Minute
+	DefaultSpikeMin     = 100
+	DefaultSpikeFactor  = 5
+	DefaultFailureMin   = 20
+	DefaultFailureRatio = 0.5
+	DefaultRepeatMin    = 10
+)
+
+// maxProfiles bounds the distinct profiles counted per key and window
+const maxProfiles = 10000
+
+// baselineWeight is the weight of the latest window in a key's average rate
+const baselineWeight = 0.3
+
+// Observation is one request made with a key
+type Observation struct {
+	Failed  bool   // rejected as invalid input
+	Profile string // fingerprint of the generated profile; empty when none
+}
+
+// Alert reports a pattern detected for a key. Each kind is reported at most
+// once per key and window.
+type Alert struct {
+	Kind        Kind      `json:"kind"`
+	Key         string    `json:"key"` // as passed to Observe
+	WindowStart time.Time `json:"windowStart"`
+	Requests    int       `json:"requests"` // in the window so far
+	Count       int       `json:"count"`    // requests, failures or repeats that triggered the alert
+	Detail      string    `json:"detail"`
+}
+
+// Detector tracks the traffic of each key
+type Detector struct {
+	cfg Config
+
+	mu   sync.Mutex
+	keys map[string]*keyState
+}
+
+// keyState counts one key's current window
+type keyState struct {
+	start    time.Time
+	requests int
+	failures int
+	profiles map[string]int
+	alerted  map[Kind]bool
+	baseline float64 // weighted average of the requests of previous windows
+	windows  int     // completed windows
+}
+
+// NewDetector creates a detector with the thresholds of cfg
+func NewDetector(cfg Config) *Detector {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultWindow
+	}
+	if cfg.SpikeMin <= 0 {
+		cfg.SpikeMin = DefaultSpikeMin
+	}
+	if cfg.SpikeFactor <= 0 {
+		cfg.SpikeFactor = DefaultSpikeFactor
+	}
+	if cfg.FailureMin <= 0 {
+		cfg.FailureMin = DefaultFailureMin
+	}
+	if cfg.FailureRatio <= 0 {
+		cfg.FailureRatio = DefaultFailureRatio
+	}
+	if cfg.RepeatMin <= 0 {
+		cfg.RepeatMin = DefaultRepeatMin
+	}
+	return &Detector{cfg: cfg, keys: make(map[string]*keyState)}
+}
+
+// Config returns the effective thresholds
+func (d *Detector) Config() Config {
+	return d.cfg
+}
+
+// Observe counts a request made with key at t and returns the patterns it
+// completes
+func (d *Detector) Observe(key string, t time.Time, obs Observation) []Alert {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s := d.keys[key]
+	if s == nil {
+		s = &keyState{start: t.Truncate(d.cfg.Window)}
+		s.reset()
+		d.keys[key] = s
+	}
+	d.roll(s, t)
+
+	s.requests++
+	if obs.Failed {
+		s.failures++
+	}
+	repeats := 0
+	if obs.Profile != "" {
+		if _, ok := s.profiles[obs.Profile]; ok || len(s.profiles) < maxProfiles {
+			s.profiles[obs.Profile]++
+			repeats = s.profiles[obs.Profile]
+		}
+	}
+
+	var alerts []Alert
+	alert := func(kind Kind, count int, detail string) {
+		if s.alerted[kind] {
+			return
+		}
+		s.alerted[kind] = true
+		alerts = append(alerts, Alert{Kind: kind, Key: key, WindowStart: s.start, Requests: s.requests, Count: count, Detail: detail})
+	}
+	if s.requests >= d.cfg.SpikeMin && float64(s.requests) >= d.cfg.SpikeFactor*s.baseline {
+		alert(Spike, s.requests, fmt.Sprintf("%d requests in %s, usually %.1f", s.requests, d.cfg.Window, s.baseline))
+	}
+	if s.failures >= d.cfg.FailureMin && float64(s.failures) >= d.cfg.FailureRatio*float64(s.requests) {
+		alert(ValidationFailures, s.failures, fmt.Sprintf("%d of %d requests failed validation", s.failures, s.requests))
+	}
+	if repeats >= d.cfg.RepeatMin {
+		alert(RepeatedProfile, repeats, fmt.Sprintf("one profile generated %d times", repeats))
+	}
+	return alerts
+}
+
+// roll closes the windows of s that ended before t, folding their request
+// counts into the baseline; idle windows count as zero
+func (d *Detector) roll(s *keyState, t time.Time) {
+	start := t.Truncate(d.cfg.Window)
+	if !start.After(s.start) {
+		return
+	}
+	s.fold(float64(s.requests))
+	// Idle windows decay the baseline; after a few dozen it is negligible
+	for idle := int(start.Sub(s.start)/d.cfg.Window) - 1; idle > 0 && s.baseline > 0.01; idle-- {
+		s.fold(0)
+	}
+	s.start = start
+	s.reset()
+}
+
+// fold adds a completed window to the baseline
+func (s *keyState) fold(requests float64) {
+	if s.windows == 0 {
+		s.baseline = requests
+	} else {
+		s.baseline = baselineWeight*requests + (1-baselineWeight)*s.baseline
+	}
+	s.windows++
+}
+
+// reset empties the counters of the current window
+func (s *keyState) reset() {
+	s.requests, s.failures = 0, 0
+	s.profiles = make(map[string]int)
+	s.alerted = make(map[Kind]bool)
+}
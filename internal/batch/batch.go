@@ -0,0 +1,126 @@
+// Package batch runs the items of POST /api/generate/batch. A failing item
+// does not fail the batch: its result carries the error, and the others are
+// generated and stored. Records are written in chunks once every item is
+// generated.
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/breaker"
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+// Response lists the outcome of every item, in request order
+type Response struct {
+	Results   []ItemResult `json:"results"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+}
+
+// ItemResult is either the output of an item or the error it failed with
+type ItemResult struct {
+	Index  int               `json:"index"`
+	Output *hcs.OutputHCS    `json:"output,omitempty"`
+	Error  *errcode.Response `json:"error,omitempty"`
+	// Stored reports whether the record was written, for tenants with storage
+	Stored       *bool             `json:"stored,omitempty"`
+	StorageError *errcode.Response `json:"storageError,omitempty"`
+}
+
+// TooLargeResponse is the error of a batch over the limit, naming the limit
+type TooLargeResponse struct {
+	errcode.Response
+	MaxItems int `json:"maxItems"`
+}
+
+// NewTooLargeResponse is the error of a batch of n items over limit
+func NewTooLargeResponse(lang string, n, limit int) TooLargeResponse {
+	return TooLargeResponse{
+		Response: errcode.NewResponse(lang, errcode.BatchTooLarge,
+			fmt.Sprintf("items must contain at most %d entries, got %d", limit, n)),
+		MaxItems: limit,
+	}
+}
+
+// GenerateFunc generates item i. The record is nil when the item is not
+// stored, e.g. for a tenant without storage.
+type GenerateFunc func(i int) (*hcs.OutputHCS, *store.Record, error)
+
+// Options configure a batch run
+type Options struct {
+	// Lang is the language of the error titles
+	Lang string
+	// Conflict decides what happens to records whose CHIP is already stored
+	Conflict store.Conflict
+	// ChunkSize is the number of records written per storage transaction
+	ChunkSize int
+	// Timeout bounds the storage of all records; zero means no bound
+	Timeout time.Duration
+}
+
+// Run generates n items in order, then saves the records of the items that
+// succeeded to s with store.SaveBatch
+func Run(ctx context.Context, n int, generate GenerateFunc, s store.Store, opts Options) *Response {
+	response := &Response{Results: make([]ItemResult, n)}
+	var records []store.Record
+	var stored []int // index of the item of each record
+	for i := range response.Results {
+		result := &response.Results[i]
+		result.Index = i
+		output, rec, err := generate(i)
+		if err != nil {
+			response.Failed++
+			e := errcode.NewResponse(opts.Lang, errcode.Of(err, errcode.GenerationFailed), err.Error())
+			result.Error = &e
+			continue
+		}
+		response.Succeeded++
+		result.Output = output
+		if rec != nil {
+			records = append(records, *rec)
+			stored = append(stored, i)
+		}
+	}
+	if len(records) == 0 {
+		return response
+	}
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	outcomes := store.SaveBatch(ctx, s, records, opts.Conflict, opts.ChunkSize)
+	for j, err := range outcomes {
+		result := &response.Results[stored[j]]
+		ok := err == nil
+		result.Stored = &ok
+		if !ok {
+			e := StorageError(opts.Lang, err)
+			result.StorageError = &e
+		}
+	}
+	return response
+}
+
+// StorageError describes why the record of an item was not written. Errors
+// other than a conflict, the storage budget or an open breaker are reported
+// as LookupFailed.
+func StorageError(lang string, err error) errcode.Response {
+	code, message := errcode.LookupFailed, err.Error()
+	switch {
+	case errors.Is(err, store.ErrConflict):
+		code = errcode.Conflict
+	case errors.Is(err, context.DeadlineExceeded):
+		code, message = errcode.DeadlineExceeded, "storage did not respond within the request budget"
+	case errors.Is(err, breaker.ErrOpen):
+		code, message = errcode.StorageUnavailable, "storage is failing and temporarily bypassed; retry later"
+	}
+	return errcode.NewResponse(lang, code, message)
+}
@@ -0,0 +1,145 @@
+// Package breaker implements circuit breakers for auxiliary dependencies
+// (storage, webhooks), so their outages fail fast instead of slowing down or
+// failing code generation.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+)
+
+// ErrOpen is returned without calling the dependency while the breaker is open
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a breaker
+type State string
+
+const (
+	Closed   State = "closed"    // calls go through
+	Open     State = "open"      // calls fail fast until the cooldown elapses
+	HalfOpen State = "half-open" // one trial call decides whether to close again
+)
+
+// Defaults for breakers created with zero settings
+const (
+	DefaultThreshold = 5
+	DefaultCooldown  = 30 * time.Second
+)
+
+// Breaker opens after Threshold consecutive failures and lets a single trial
+// call through once Cooldown has elapsed
+type Breaker struct {
+	Name      string
+	Threshold int           // consecutive failures that open the breaker; zero uses DefaultThreshold
+	Cooldown  time.Duration // time spent open before a trial call; zero uses DefaultCooldown
+	Clock     clock.Clock   // nil uses the system clock
+
+	mu       sync.Mutex
+	state    State
+	failures int // consecutive failures
+	openedAt time.Time
+	opens    int64 // times the breaker opened
+	trial    bool  // a half-open trial call is in flight
+}
+
+// New returns a closed breaker
+func New(name string, threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{Name: name, Threshold: threshold, Cooldown: cooldown}
+}
+
+// Do calls fn unless the breaker is open. Errors from fn count as failures,
+// except cancellations by the caller, which say nothing about the dependency.
+func (b *Breaker) Do(fn func() error) error {
+	if !b.allow() {
+		return ErrOpen
+	}
+	err := fn()
+	if errors.Is(err, context.Canceled) {
+		b.release()
+	} else {
+		b.record(err == nil)
+	}
+	return err
+}
+
+// release ends a call without recording an outcome
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.currentState() {
+	case Open:
+		return false
+	case HalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+	}
+	return true
+}
+
+func (b *Breaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasTrial := b.trial
+	b.trial = false
+	if success {
+		b.state = Closed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	threshold := b.Threshold
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	if wasTrial || b.failures >= threshold {
+		if b.state != Open {
+			b.opens++
+		}
+		b.state = Open
+		b.openedAt = clock.Or(b.Clock).Now()
+	}
+}
+
+// currentState resolves an open breaker whose cooldown has elapsed to half-open.
+// b.mu must be held.
+func (b *Breaker) currentState() State {
+	if b.state == "" {
+		return Closed
+	}
+	if b.state == Open {
+		cooldown := b.Cooldown
+		if cooldown <= 0 {
+			cooldown = DefaultCooldown
+		}
+		if clock.Or(b.Clock).Now().Sub(b.openedAt) >= cooldown {
+			return HalfOpen
+		}
+	}
+	return b.state
+}
+
+// Stats is a snapshot of a breaker, as exposed in metrics
+type Stats struct {
+	State    State `json:"state"`
+	Failures int   `json:"failures"` // consecutive failures
+	Opens    int64 `json:"opens"`    // times the breaker opened
+}
+
+// Stats returns the breaker's current state and counters
+func (b *Breaker) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return Stats{State: b.currentState(), Failures: b.failures, Opens: b.opens}
+}
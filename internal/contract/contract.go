@@ -0,0 +1,328 @@
+// Package contract is a black-box conformance suite for HCS API deployments.
+// It only speaks HTTP, so it can certify any instance, including self-hosted
+// ones running with their own secret and salt.
+package contract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/schema"
+)
+
+// Result is the outcome of one conformance check
+type Result struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail,omitempty"` // why the check failed
+}
+
+// Suite runs the conformance checks against one deployment
+type Suite struct {
+	BaseURL string
+	APIKey  string // sent as X-API-Key when set
+	Client  *http.Client
+}
+
+// check is one named conformance check; it returns nil when the deployment conforms
+type check struct {
+	name string
+	run  func(ctx context.Context, s *Suite) error
+}
+
+var checks = []check{
+	{"health", checkHealth},
+	{"generate-schema", checkGenerateSchema},
+	{"generate-determinism", checkDeterminism},
+	{"error-invalid-json", checkInvalidJSON},
+	{"error-invalid-profile", checkInvalidProfile},
+	{"error-unknown-code", checkUnknownCode},
+}
+
+// Run executes every check, in order, and returns their results
+func (s *Suite) Run(ctx context.Context) []Result {
+	results := make([]Result, 0, len(checks))
+	for _, c := range checks {
+		r := Result{Name: c.name, Passed: true}
+		if err := c.run(ctx, s); err != nil {
+			r.Passed = false
+			r.Detail = err.Error()
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// Passed reports whether every check passed
+func Passed(results []Result) bool {
+	for _, r := range results {
+		if !r.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// PingReport is the outcome of a ping
+type PingReport struct {
+	Healthy bool `json:"healthy"`
+	// Chip is the CHIP generated for the canary profile
+	Chip      string   `json:"chip,omitempty"`
+	LatencyMs int64    `json:"latencyMs"`
+	Checks    []Result `json:"checks"`
+}
+
+// Ping is a quick liveness probe for healthchecks and uptime monitors: it
+// checks /health, then generates the canary profile and checks its CHIP
+// against expectChip, or, when expectChip is empty, against a second
+// generation. It stops at the first failed check.
+func (s *Suite) Ping(ctx context.Context, expectChip string) PingReport {
+	start := time.Now()
+	var report PingReport
+	add := func(name string, err error) bool {
+		r := Result{Name: name, Passed: err == nil}
+		if err != nil {
+			r.Detail = err.Error()
+		}
+		report.Checks = append(report.Checks, r)
+		return r.Passed
+	}
+
+	report.Healthy = add("health", checkHealth(ctx, s))
+	if report.Healthy {
+		var err error
+		report.Chip, err = s.generateCanary(ctx)
+		report.Healthy = add("canary-generate", err)
+	}
+	if report.Healthy {
+		var err error
+		switch {
+		case expectChip != "" && report.Chip != expectChip:
+			err = fmt.Errorf("canary CHIP is %s, want %s: the secret, the salt or the engine changed", report.Chip, expectChip)
+		case expectChip == "":
+			var again string
+			if again, err = s.generateCanary(ctx); err == nil && again != report.Chip {
+				err = fmt.Errorf("canary CHIP changed between identical requests: %s, %s", report.Chip, again)
+			}
+		}
+		report.Healthy = add("canary-chip", err)
+	}
+	report.LatencyMs = time.Since(start).Milliseconds()
+	return report
+}
+
+// generateCanary generates the canary profile, the first test vector input,
+// and returns its CHIP
+func (s *Suite) generateCanary(ctx context.Context) (string, error) {
+	bodies, err := profiles()
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.do(ctx, http.MethodPost, "/api/generate", bodies[0].body)
+	if err != nil {
+		return "", err
+	}
+	if resp.status != http.StatusOK {
+		return "", fmt.Errorf("POST /api/generate: status %d, want 200: %s", resp.status, bytes.TrimSpace(resp.body))
+	}
+	var out struct {
+		Chip string `json:"chip"`
+	}
+	if err := json.Unmarshal(resp.body, &out); err != nil || out.Chip == "" {
+		return "", fmt.Errorf("POST /api/generate: response has no CHIP")
+	}
+	return out.Chip, nil
+}
+
+// response is a buffered HTTP response
+type response struct {
+	status int
+	body   []byte
+}
+
+func (s *Suite) do(ctx context.Context, method, path string, body []byte) (*response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(s.BaseURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.APIKey != "" {
+		req.Header.Set("X-API-Key", s.APIKey)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s %s: %w", method, path, err)
+	}
+	return &response{status: resp.StatusCode, body: data}, nil
+}
+
+// profile is a named generate request body
+type profile struct {
+	name string
+	body []byte
+}
+
+// profiles returns the published test vector inputs as request bodies. The
+// first one has no birth info.
+func profiles() ([]profile, error) {
+	vectors, err := hcs.TestVectors()
+	if err != nil {
+		return nil, err
+	}
+	bodies := make([]profile, 0, len(vectors))
+	for _, v := range vectors {
+		body, err := json.Marshal(v.Input)
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, profile{v.Name, body})
+	}
+	return bodies, nil
+}
+
+func checkHealth(ctx context.Context, s *Suite) error {
+	resp, err := s.do(ctx, http.MethodGet, "/health", nil)
+	if err != nil {
+		return err
+	}
+	if resp.status != http.StatusOK {
+		return fmt.Errorf("GET /health: status %d, want 200", resp.status)
+	}
+	var health struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(resp.body, &health); err != nil || health.Status != "healthy" {
+		return fmt.Errorf("GET /health: expected {\"status\":\"healthy\"}, got %s", resp.body)
+	}
+	return nil
+}
+
+func checkGenerateSchema(ctx context.Context, s *Suite) error {
+	bodies, err := profiles()
+	if err != nil {
+		return err
+	}
+	for _, p := range bodies {
+		name := p.name
+		resp, err := s.do(ctx, http.MethodPost, "/api/generate", p.body)
+		if err != nil {
+			return err
+		}
+		if resp.status != http.StatusOK {
+			return fmt.Errorf("profile %s: status %d, want 200: %s", name, resp.status, resp.body)
+		}
+		var doc any
+		if err := json.Unmarshal(resp.body, &doc); err != nil {
+			return fmt.Errorf("profile %s: response is not JSON: %w", name, err)
+		}
+		if violations := schema.Output().Validate(doc); len(violations) > 0 {
+			return fmt.Errorf("profile %s: %d schema violations, first %s", name, len(violations), violations[0])
+		}
+	}
+	return nil
+}
+
+// checkDeterminism generates the same profile twice. The codes depend only on
+// the profile, the secret and the salt, so they must not change.
+func checkDeterminism(ctx context.Context, s *Suite) error {
+	bodies, err := profiles()
+	if err != nil {
+		return err
+	}
+	var codes [2]map[string]string
+	for i := range codes {
+		resp, err := s.do(ctx, http.MethodPost, "/api/generate", bodies[0].body)
+		if err != nil {
+			return err
+		}
+		if resp.status != http.StatusOK {
+			return fmt.Errorf("status %d, want 200", resp.status)
+		}
+		var out map[string]any
+		if err := json.Unmarshal(resp.body, &out); err != nil {
+			return fmt.Errorf("response is not JSON: %w", err)
+		}
+		codes[i] = map[string]string{}
+		for _, field := range []string{"chip", "codeU3", "codeU4", "codeU7"} {
+			codes[i][field], _ = out[field].(string)
+		}
+	}
+	for field, first := range codes[0] {
+		if codes[1][field] != first {
+			return fmt.Errorf("%s changed between identical requests: %q, %q", field, first, codes[1][field])
+		}
+	}
+	return nil
+}
+
+// expectError checks that a response follows the error model:
+// {"error": ..., "message": ..., "code": <status>}
+func expectError(resp *response, statuses ...int) error {
+	ok := false
+	for _, status := range statuses {
+		ok = ok || resp.status == status
+	}
+	if !ok {
+		return fmt.Errorf("status %d, want one of %v", resp.status, statuses)
+	}
+	var e struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+		Code    int    `json:"code"`
+	}
+	if err := json.Unmarshal(resp.body, &e); err != nil {
+		return fmt.Errorf("error body is not JSON: %s", resp.body)
+	}
+	if e.Error == "" || e.Code != resp.status {
+		return fmt.Errorf("error body does not follow the error model: %s", resp.body)
+	}
+	return nil
+}
+
+func checkInvalidJSON(ctx context.Context, s *Suite) error {
+	resp, err := s.do(ctx, http.MethodPost, "/api/generate", []byte("{not json"))
+	if err != nil {
+		return err
+	}
+	return expectError(resp, http.StatusBadRequest)
+}
+
+func checkInvalidProfile(ctx context.Context, s *Suite) error {
+	body := []byte(`{"dominantElement":"Aether","modal":{"cardinal":0.3,"fixed":0.3,"mutable":0.4},` +
+		`"cognition":{"fluid":0.5,"crystallized":0.5,"verbal":0.5,"strategic":0.5,"creative":0.5},` +
+		`"interaction":{"pace":"balanced","structure":"medium","tone":"warm"}}`)
+	resp, err := s.do(ctx, http.MethodPost, "/api/generate", body)
+	if err != nil {
+		return err
+	}
+	return expectError(resp, http.StatusBadRequest)
+}
+
+// checkUnknownCode looks up a CHIP that cannot exist. Deployments without
+// storage answer 501, which is part of the contract too.
+func checkUnknownCode(ctx context.Context, s *Suite) error {
+	resp, err := s.do(ctx, http.MethodGet, "/api/codes/000000000000", nil)
+	if err != nil {
+		return err
+	}
+	return expectError(resp, http.StatusNotFound, http.StatusNotImplemented)
+}
@@ -0,0 +1,83 @@
+// Package envsubst expands ${NAME} placeholders in profile files, so values
+// such as birth data can be injected from secrets when the CLI runs instead
+// of being written into the file.
+package envsubst
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// MissingError lists the placeholders whose variable is not set
+type MissingError struct {
+	Names []string
+}
+
+func (e *MissingError) Error() string {
+	return "environment variables not set: " + strings.Join(e.Names, ", ")
+}
+
+// Expand replaces every ${NAME} in data with the value of the environment
+// variable NAME, as is. $${NAME} is an escape and gives a literal ${NAME};
+// a $ not followed by { is left alone. Every placeholder must name a set
+// variable, possibly empty: unset ones are reported together in a
+// *MissingError. NAME is made of letters, digits and underscores and does
+// not start with a digit.
+func Expand(data []byte) ([]byte, error) {
+	return ExpandFunc(data, os.LookupEnv)
+}
+
+// ExpandFunc is Expand with the variables looked up by lookup
+func ExpandFunc(data []byte, lookup func(string) (string, bool)) ([]byte, error) {
+	var out bytes.Buffer
+	var missing []string
+	for pos := 0; ; {
+		i := bytes.Index(data[pos:], []byte("${"))
+		if i < 0 {
+			out.Write(data[pos:])
+			break
+		}
+		i += pos
+		if i > pos && data[i-1] == '$' {
+			// $${ is an escape: drop one $ and keep the rest literally
+			out.Write(data[pos : i-1])
+			out.WriteString("${")
+			pos = i + 2
+			continue
+		}
+		end := bytes.IndexByte(data[i:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated placeholder at offset %d", i)
+		}
+		name := string(data[i+2 : i+end])
+		if !validName(name) {
+			return nil, fmt.Errorf("invalid placeholder ${%s} at offset %d", name, i)
+		}
+		out.Write(data[pos:i])
+		if value, ok := lookup(name); ok {
+			out.WriteString(value)
+		} else if !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+		pos = i + end + 1
+	}
+	if len(missing) > 0 {
+		return nil, &MissingError{Names: missing}
+	}
+	return out.Bytes(), nil
+}
+
+func validName(name string) bool {
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return false
+	}
+	for _, c := range name {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
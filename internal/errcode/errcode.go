@@ -0,0 +1,174 @@
+// Package errcode is the catalog of stable error codes carried by API error
+// responses and CLI failures, so that clients branch on codes instead of
+// messages. Codes are never renumbered or reused; messages may change.
+//
+// Ranges: 1xxx invalid input, 2xxx keys and salts, 3xxx access control,
+// 4xxx storage, 5xxx availability, 9xxx internal failures.
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code is a stable error code such as HCS-1001
+type Code string
+
+const (
+	InvalidJSON           Code = "HCS-1000"
+	InvalidElement        Code = "HCS-1001"
+	InvalidModal          Code = "HCS-1002"
+	InvalidCognition      Code = "HCS-1003"
+	InvalidInteraction    Code = "HCS-1004"
+	InvalidBirthInfo      Code = "HCS-1005"
+	InvalidElementBalance Code = "HCS-1006"
+	InvalidRequest        Code = "HCS-1007"
+	InvalidOptions        Code = "HCS-1008"
+	InvalidCode           Code = "HCS-1009"
+	InvalidEnvelope       Code = "HCS-1010"
+	InvalidRevocationList Code = "HCS-1011"
+	BatchTooLarge         Code = "HCS-1012"
+
+	MissingSecret    Code = "HCS-2001"
+	InvalidSecret    Code = "HCS-2002"
+	SigningFailed    Code = "HCS-2003"
+	UpdateUnverified Code = "HCS-2004"
+	SaltUnavailable  Code = "HCS-2101"
+	SaltTampered     Code = "HCS-2102"
+	SaltUnsealed     Code = "HCS-2103"
+
+	Unauthorized     Code = "HCS-3001"
+	OriginNotAllowed Code = "HCS-3002"
+	ReadOnly         Code = "HCS-3003"
+	TraceDisabled    Code = "HCS-3004"
+	DeliveryDisabled Code = "HCS-3005"
+	TenantForbidden  Code = "HCS-3006"
+	TooManyAttempts  Code = "HCS-3007"
+
+	NotFound           Code = "HCS-4001"
+	StorageDisabled    Code = "HCS-4002"
+	StorageUnavailable Code = "HCS-4003"
+	LookupFailed       Code = "HCS-4004"
+	FileIO             Code = "HCS-4005"
+	Conflict           Code = "HCS-4006"
+
+	DeadlineExceeded  Code = "HCS-5001"
+	NotReady          Code = "HCS-5002"
+	UpdateUnavailable Code = "HCS-5003"
+
+	Internal           Code = "HCS-9000"
+	GenerationFailed   Code = "HCS-9001"
+	VerificationFailed Code = "HCS-9002"
+	ReloadFailed       Code = "HCS-9003"
+	TestVectorsFailed  Code = "HCS-9004"
+)
+
+// Entry describes a code. Title is the short "error" string of API
+// responses, shared by related codes; Description tells them apart.
+type Entry struct {
+	Code        Code   `json:"code"`
+	Status      int    `json:"status"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// catalog lists every code in ascending order
+var catalog = []Entry{
+	{InvalidJSON, http.StatusBadRequest, "Invalid JSON", "The request body is not valid JSON"},
+	{InvalidElement, http.StatusBadRequest, "Validation error", "The dominant element is not Earth, Air, Water or Fire"},
+	{InvalidModal, http.StatusBadRequest, "Validation error", "A modal value is outside [0, 1], or the modal values do not sum to 1 under strict checking"},
+	{InvalidCognition, http.StatusBadRequest, "Validation error", "A cognition value is outside [0, 1]"},
+	{InvalidInteraction, http.StatusBadRequest, "Validation error", "The interaction pace, structure or tone is not one of the allowed values"},
+	{InvalidBirthInfo, http.StatusBadRequest, "Validation error", "The birth date, time or place is out of range"},
+	{InvalidElementBalance, http.StatusBadRequest, "Validation error", "The element balance has an unknown or negative element, or disagrees with the dominant element"},
+	{InvalidRequest, http.StatusBadRequest, "Validation error", "A request field other than the profile is missing or invalid"},
+	{InvalidOptions, http.StatusBadRequest, "Validation error", "A generation option (fusion config, engine, signature lengths, key derivation) is unknown or unavailable"},
+	{InvalidCode, http.StatusBadRequest, "Invalid code", "The HCS code is malformed"},
+	{InvalidEnvelope, http.StatusBadRequest, "Invalid envelope", "The envelope is unsigned, of an unsupported version, or sealed under an unknown salt epoch or key"},
+	{InvalidRevocationList, http.StatusBadRequest, "Invalid revocation list", "The revocation list is unsigned, of an unsupported version, or signed under an unknown salt epoch or key"},
+	{BatchTooLarge, http.StatusRequestEntityTooLarge, "Batch too large", "The batch has more items than the server accepts"},
+
+	{MissingSecret, http.StatusInternalServerError, "Generation failed", "The secret key is not configured"},
+	{InvalidSecret, http.StatusInternalServerError, "Generation failed", "The secret key is not valid hex of 32 or 64 bytes"},
+	{SigningFailed, http.StatusBadGateway, "Generation failed", "The remote signing service failed or is unreachable"},
+	{UpdateUnverified, http.StatusBadGateway, "Update failed", "A release manifest or binary does not match the release key"},
+	{SaltUnavailable, http.StatusInternalServerError, "Generation failed", "The salt is missing, unreadable or of the wrong size"},
+	{SaltTampered, http.StatusInternalServerError, "Generation failed", "A salt file changed since it was sealed with the secret key"},
+	{SaltUnsealed, http.StatusInternalServerError, "Generation failed", "A salt file has no seal for the secret key and was not migrated"},
+
+	{Unauthorized, http.StatusUnauthorized, "Unauthorized", "A valid API key or admin token is required"},
+	{OriginNotAllowed, http.StatusForbidden, "Origin not allowed", "The request origin is not allowed for the route or the API key's tenant"},
+	{ReadOnly, http.StatusForbidden, "Read-only", "The server is read-only and does not generate or mutate"},
+	{TraceDisabled, http.StatusForbidden, "Trace disabled", "Generation traces are disabled on this server"},
+	{DeliveryDisabled, http.StatusForbidden, "Delivery disabled", "Email delivery of reports is not configured on this server"},
+	{TenantForbidden, http.StatusForbidden, "Tenant not allowed", "The request names a tenant other than the one of its API key"},
+	{TooManyAttempts, http.StatusTooManyRequests, "Too many attempts", "Too many failed verifications; retry after the lockout ends"},
+
+	{NotFound, http.StatusNotFound, "Not found", "No stored record or registered resource matches the request"},
+	{StorageDisabled, http.StatusNotImplemented, "Storage disabled", "The endpoint needs storage, which is not configured"},
+	{StorageUnavailable, http.StatusServiceUnavailable, "Storage unavailable", "Storage is failing and temporarily bypassed; retry later"},
+	{LookupFailed, http.StatusInternalServerError, "Lookup failed", "Storage returned an error"},
+	{FileIO, http.StatusInternalServerError, "File error", "A local file could not be read or written"},
+	{Conflict, http.StatusConflict, "Conflict", "A record with the same CHIP is already stored"},
+
+	{DeadlineExceeded, http.StatusGatewayTimeout, "Deadline exceeded", "The request did not complete within its time budget"},
+	{NotReady, http.StatusServiceUnavailable, "Not ready", "The server or a background job is not ready yet"},
+	{UpdateUnavailable, http.StatusServiceUnavailable, "Update failed", "The release server is unreachable, or has no build for this platform or channel"},
+
+	{Internal, http.StatusInternalServerError, "Internal error", "An unexpected error"},
+	{GenerationFailed, http.StatusInternalServerError, "Generation failed", "Code generation failed unexpectedly"},
+	{VerificationFailed, http.StatusInternalServerError, "Verification failed", "Verification failed unexpectedly"},
+	{ReloadFailed, http.StatusInternalServerError, "Reload failed", "The new configuration did not load; the previous one stays active"},
+	{TestVectorsFailed, http.StatusInternalServerError, "Test vectors failed", "The test vectors could not be computed"},
+}
+
+// Catalog returns every code in ascending order
+func Catalog() []Entry {
+	return append([]Entry(nil), catalog...)
+}
+
+// Lookup returns the entry of code, or the Internal entry for unknown codes
+func Lookup(code Code) Entry {
+	for _, e := range catalog {
+		if e.Code == code {
+			return e
+		}
+	}
+	if code != Internal {
+		return Lookup(Internal)
+	}
+	return Entry{Code: Internal, Status: http.StatusInternalServerError, Title: "Internal error"}
+}
+
+// Error is an error tagged with a code
+type Error struct {
+	Code Code
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Errorf formats an error (wrapping %w operands) and tags it with code
+func Errorf(code Code, format string, args ...any) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// Wrap tags err with code; it returns nil for a nil err
+func Wrap(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Of returns the code of the outermost tagged error in err's chain, or fallback
+func Of(err error, fallback Code) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return fallback
+}
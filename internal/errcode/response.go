@@ -0,0 +1,22 @@
+package errcode
+
+import "github.com/corehuman/hcs-lab-api/internal/i18n"
+
+// Response is the body of an API error response
+type Response struct {
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Code      int    `json:"code"`
+	ErrorCode Code   `json:"errorCode"` // stable code from GET /v1/errors
+}
+
+// NewResponse builds the body of an error with the title of code in lang
+func NewResponse(lang string, code Code, message string) Response {
+	entry := Lookup(code)
+	return Response{
+		Error:     i18n.Translate(lang, entry.Title),
+		Message:   message,
+		Code:      entry.Status,
+		ErrorCode: entry.Code,
+	}
+}
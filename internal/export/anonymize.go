@@ -0,0 +1,193 @@
+package export
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/parquet"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+// DefaultNoise is the standard deviation, in percentage points, of the noise
+// added to continuous fields of a public dataset
+const DefaultNoise = 3.0
+
+// PublicColumns are the columns of an anonymized dataset. Identifiers, dates,
+// birth info and everything derived from it (BaZi and fusion metrics) are left
+// out; modal and cognition values carry noise.
+var PublicColumns = []parquet.Column{
+	{Name: "element", Type: parquet.String},
+	{Name: "modal_cardinal", Type: parquet.Int64},
+	{Name: "modal_fixed", Type: parquet.Int64},
+	{Name: "modal_mutable", Type: parquet.Int64},
+	{Name: "cog_fluid", Type: parquet.Int64},
+	{Name: "cog_crystallized", Type: parquet.Int64},
+	{Name: "cog_verbal", Type: parquet.Int64},
+	{Name: "cog_strategic", Type: parquet.Int64},
+	{Name: "cog_creative", Type: parquet.Int64},
+	{Name: "pace", Type: parquet.String},
+	{Name: "structure", Type: parquet.String},
+	{Name: "tone", Type: parquet.String},
+	{Name: "archetype", Type: parquet.String, Optional: true},
+	{Name: "quality_score", Type: parquet.Double, Optional: true},
+}
+
+// removedFields lists what a public dataset drops, for its documentation
+var removedFields = []string{
+	"chip", "subject_id", "tenant_id", "created_at", "valid_until", "cluster_id", "birth info",
+	"day_master", "day_master_strength", "yin_yang_balance", "fusion_id", "harmonic_resonance", "unified_balance", "fusion_config",
+}
+
+// AnonymizeOptions controls how a public dataset is sampled and perturbed
+type AnonymizeOptions struct {
+	Sample int     // maximum number of rows; 0 keeps every subject
+	Noise  float64 // standard deviation of the noise in percentage points; 0 uses DefaultNoise
+	// Seed makes sampling and noise reproducible, for tests. Zero draws a random
+	// seed. A seed must never be published with a dataset: it reveals the noise.
+	Seed uint64
+}
+
+// Perturbation documents how a public dataset was derived from stored records
+type Perturbation struct {
+	SourceRecords int      `json:"sourceRecords"`
+	Subjects      int      `json:"subjects"` // after keeping one record per subject
+	Rows          int      `json:"rows"`
+	Distribution  string   `json:"distribution"`
+	NoiseStdDev   float64  `json:"noiseStdDev"` // percentage points
+	NoisyFields   []string `json:"noisyFields"`
+	RemovedFields []string `json:"removedFields"`
+}
+
+// Anonymize samples records and strips and perturbs them into rows of
+// PublicColumns. Only the latest record of each subject is kept, so a subject
+// cannot be followed across generations. Rows are in random order.
+func Anonymize(records []store.Record, opts AnonymizeOptions) ([][]any, Perturbation, error) {
+	if opts.Sample < 0 {
+		return nil, Perturbation{}, fmt.Errorf("sample size must not be negative, got %d", opts.Sample)
+	}
+	if opts.Noise < 0 {
+		return nil, Perturbation{}, fmt.Errorf("noise must not be negative, got %g", opts.Noise)
+	}
+	if opts.Noise == 0 {
+		opts.Noise = DefaultNoise
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		var b [8]byte
+		if _, err := crand.Read(b[:]); err != nil {
+			return nil, Perturbation{}, fmt.Errorf("failed to draw a seed: %w", err)
+		}
+		seed = binary.LittleEndian.Uint64(b[:])
+	}
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+
+	// Records are ordered by creation time, so later ones replace earlier ones
+	latest := map[string]store.Record{}
+	var anonymous []store.Record
+	for _, rec := range records {
+		if rec.SubjectID == "" {
+			anonymous = append(anonymous, rec)
+			continue
+		}
+		latest[rec.SubjectID] = rec
+	}
+	subjects := make([]string, 0, len(latest))
+	for id := range latest {
+		subjects = append(subjects, id)
+	}
+	sort.Strings(subjects) // fixed order before shuffling keeps seeded runs reproducible
+	selected := anonymous
+	for _, id := range subjects {
+		selected = append(selected, latest[id])
+	}
+
+	rng.Shuffle(len(selected), func(i, j int) { selected[i], selected[j] = selected[j], selected[i] })
+	if opts.Sample > 0 && len(selected) > opts.Sample {
+		selected = selected[:opts.Sample]
+	}
+
+	noisy := func(v int) int {
+		return int(math.Max(0, math.Min(100, math.Round(float64(v)+rng.NormFloat64()*opts.Noise))))
+	}
+	rows := make([][]any, len(selected))
+	for i, rec := range selected {
+		n := hcs.NormalizeProfile(&rec.Input)
+		var archetype, quality any
+		if out := rec.Output; out != nil {
+			if out.Archetype != nil {
+				archetype = out.Archetype.Name
+			}
+			if out.Metadata != nil && out.Metadata.Quality != nil {
+				quality = out.Metadata.Quality.Score
+			}
+		}
+		rows[i] = []any{
+			n.Element, noisy(n.Modal.C), noisy(n.Modal.F), noisy(n.Modal.M),
+			noisy(n.Cog.F), noisy(n.Cog.C), noisy(n.Cog.V), noisy(n.Cog.S), noisy(n.Cog.Cr),
+			n.Int.PB, n.Int.SM, n.Int.TN,
+			archetype, quality,
+		}
+	}
+
+	var noisyFields []string
+	for _, c := range PublicColumns {
+		if strings.HasPrefix(c.Name, "modal_") || strings.HasPrefix(c.Name, "cog_") {
+			noisyFields = append(noisyFields, c.Name)
+		}
+	}
+	return rows, Perturbation{
+		SourceRecords: len(records),
+		Subjects:      len(anonymous) + len(subjects),
+		Rows:          len(rows),
+		Distribution:  "gaussian",
+		NoiseStdDev:   opts.Noise,
+		NoisyFields:   noisyFields,
+		RemovedFields: removedFields,
+	}, nil
+}
+
+// WritePublicParquet writes anonymized rows to w as a Parquet file of PublicColumns
+func WritePublicParquet(w io.Writer, rows [][]any) error {
+	pw := parquet.NewWriter(w, PublicColumns)
+	for _, row := range rows {
+		if err := pw.Write(row); err != nil {
+			return err
+		}
+	}
+	return pw.Close()
+}
+
+// WriteDatasetDoc writes the Markdown documentation shipped with a public
+// dataset: its columns and how it was perturbed
+func WriteDatasetDoc(w io.Writer, p Perturbation) error {
+	var b strings.Builder
+	b.WriteString("# HCS Anonymized Dataset\n\n")
+	fmt.Fprintf(&b, "%d rows sampled from %d subjects (%d stored records). Only the latest record of each subject\n", p.Rows, p.Subjects, p.SourceRecords)
+	b.WriteString("is included, and rows are in random order.\n\n")
+
+	b.WriteString("## Perturbation\n\n")
+	fmt.Fprintf(&b, "- Fields with noise: %s\n", strings.Join(p.NoisyFields, ", "))
+	fmt.Fprintf(&b, "- Noise: %s, standard deviation %g percentage points, added to the normalized integer\n", p.Distribution, p.NoiseStdDev)
+	b.WriteString("  percentages, then rounded and clamped to 0-100. Modal values therefore need not sum to 100.\n")
+	fmt.Fprintf(&b, "- Removed: %s\n", strings.Join(p.RemovedFields, ", "))
+	b.WriteString("- Element, interaction letters, archetype and quality score are exact; the archetype was derived\n")
+	b.WriteString("  from the values before noise was added.\n\n")
+
+	b.WriteString("## Columns\n\n")
+	b.WriteString("| Column | Type | Nullable |\n|---|---|---|\n")
+	for _, c := range PublicColumns {
+		fmt.Fprintf(&b, "| %s | %s | %t |\n", c.Name, c.Type, c.Optional)
+	}
+	b.WriteString("\nElements are F(ire), E(arth), A(ir), W(ater); pace S/B/F (slow/balanced/fast); structure L/M/H;\n")
+	b.WriteString("tone W/S/P/N (warm/sharp/precise/neutral).\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,106 @@
+// Package export turns stored records into flat tables for research, with one
+// column per normalized field and derived metric.
+package export
+
+import (
+	"io"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/parquet"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+// Columns are the columns of a cohort export. Profile values are the
+// normalized integer percentages and letters the codes encode; derived metrics
+// are null when the record does not have them.
+var Columns = []parquet.Column{
+	{Name: "chip", Type: parquet.String},
+	{Name: "subject_id", Type: parquet.String, Optional: true},
+	{Name: "tenant_id", Type: parquet.String, Optional: true},
+	{Name: "created_at", Type: parquet.Timestamp},
+	{Name: "valid_until", Type: parquet.Timestamp, Optional: true},
+
+	{Name: "element", Type: parquet.String},
+	{Name: "modal_cardinal", Type: parquet.Int64},
+	{Name: "modal_fixed", Type: parquet.Int64},
+	{Name: "modal_mutable", Type: parquet.Int64},
+	{Name: "cog_fluid", Type: parquet.Int64},
+	{Name: "cog_crystallized", Type: parquet.Int64},
+	{Name: "cog_verbal", Type: parquet.Int64},
+	{Name: "cog_strategic", Type: parquet.Int64},
+	{Name: "cog_creative", Type: parquet.Int64},
+	{Name: "pace", Type: parquet.String},
+	{Name: "structure", Type: parquet.String},
+	{Name: "tone", Type: parquet.String},
+
+	{Name: "archetype_id", Type: parquet.Int64, Optional: true},
+	{Name: "archetype", Type: parquet.String, Optional: true},
+	{Name: "cluster_id", Type: parquet.Int64, Optional: true},
+	{Name: "quality_score", Type: parquet.Double, Optional: true},
+	{Name: "day_master", Type: parquet.String, Optional: true},
+	{Name: "day_master_strength", Type: parquet.Double, Optional: true},
+	{Name: "yin_yang_balance", Type: parquet.Double, Optional: true},
+	{Name: "fusion_id", Type: parquet.String, Optional: true},
+	{Name: "harmonic_resonance", Type: parquet.Double, Optional: true},
+	{Name: "unified_balance", Type: parquet.Double, Optional: true},
+	{Name: "fusion_config", Type: parquet.String, Optional: true},
+}
+
+// Row converts a record into a row of Columns
+func Row(rec store.Record) []any {
+	n := hcs.NormalizeProfile(&rec.Input)
+
+	var validUntil, archetypeID, archetype, clusterID, quality any
+	var dayMaster, dayMasterStrength, yinYang any
+	var fusionID, resonance, balance, fusionConfig any
+	if !rec.ValidUntil.IsZero() {
+		validUntil = rec.ValidUntil
+	}
+	if rec.ClusterID != nil {
+		clusterID = *rec.ClusterID
+	}
+	if out := rec.Output; out != nil {
+		if a := out.Archetype; a != nil {
+			archetypeID, archetype = a.ID, a.Name
+		}
+		if out.Metadata != nil && out.Metadata.Quality != nil {
+			quality = out.Metadata.Quality.Score
+		}
+		if c := out.ChineseProfile; c != nil {
+			dayMaster, dayMasterStrength, yinYang = c.DayMaster, c.DayMasterStrength, c.YinYangBalance
+		}
+		if cp := out.CombinedProfile; cp != nil {
+			fusionID, resonance, balance = cp.Fusion.FusionID, cp.Fusion.HarmonicResonance, cp.Fusion.UnifiedBalance
+			fusionConfig = optionalString(cp.FusionConfigID)
+		}
+	}
+
+	return []any{
+		rec.Chip, optionalString(rec.SubjectID), optionalString(rec.TenantID), rec.CreatedAt, validUntil,
+		n.Element, n.Modal.C, n.Modal.F, n.Modal.M,
+		n.Cog.F, n.Cog.C, n.Cog.V, n.Cog.S, n.Cog.Cr,
+		n.Int.PB, n.Int.SM, n.Int.TN,
+		archetypeID, archetype, clusterID, quality,
+		dayMaster, dayMasterStrength, yinYang,
+		fusionID, resonance, balance, fusionConfig,
+	}
+}
+
+// optionalString maps an empty string to null
+func optionalString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// WriteParquet writes records to w as a Parquet file of Columns
+func WriteParquet(w io.Writer, records []store.Record) error {
+	pw := parquet.NewWriter(w, Columns)
+	for _, rec := range records {
+		if err := pw.Write(Row(rec)); err != nil {
+			return err
+		}
+	}
+	return pw.Close()
+}
@@ -0,0 +1,161 @@
+package features
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Names of the optional modules controlled by feature flags
+const (
+	U5       = "u5"
+	U6       = "u6"
+	U7       = "u7"
+	Storage  = "storage"
+	Webhooks = "webhooks"
+)
+
+// defaults lists every known flag with its default state
+var defaults = map[string]bool{
+	U5:       true,
+	U6:       true,
+	U7:       true,
+	Storage:  true,
+	Webhooks: true,
+}
+
+// File is the JSON layout of a feature flag configuration file
+type File struct {
+	Flags   map[string]bool            `json:"flags"`
+	Tenants map[string]map[string]bool `json:"tenants"`
+}
+
+// Flags resolves feature flags from defaults, a config file, environment
+// variables and per-tenant overrides (in increasing order of precedence)
+type Flags struct {
+	mu      sync.RWMutex
+	global  map[string]bool
+	tenants map[string]map[string]bool
+}
+
+// New returns flags set to their defaults
+func New() *Flags {
+	f := &Flags{
+		global:  make(map[string]bool, len(defaults)),
+		tenants: make(map[string]map[string]bool),
+	}
+	for name, on := range defaults {
+		f.global[name] = on
+	}
+	return f
+}
+
+// Load builds flags from the optional config file at path, then applies
+// HCS_FEATURE_<NAME>=on|off environment overrides
+func Load(path string) (*Flags, error) {
+	return LoadEnv(path, os.Getenv)
+}
+
+// LoadEnv is Load with the HCS_FEATURE_<NAME> overrides read through getenv
+func LoadEnv(path string, getenv func(string) string) (*Flags, error) {
+	f := New()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read feature file: %w", err)
+		}
+		var file File
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse feature file: %w", err)
+		}
+		for name, on := range file.Flags {
+			if err := f.Set(name, on); err != nil {
+				return nil, err
+			}
+		}
+		for tenant, overrides := range file.Tenants {
+			for name, on := range overrides {
+				if err := f.SetTenant(tenant, name, on); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for name := range defaults {
+		v := getenv("HCS_FEATURE_" + strings.ToUpper(name))
+		switch v {
+		case "":
+		case "on", "true", "1":
+			f.Set(name, true)
+		case "off", "false", "0":
+			f.Set(name, false)
+		default:
+			return nil, fmt.Errorf("invalid HCS_FEATURE_%s value: %q", strings.ToUpper(name), v)
+		}
+	}
+	return f, nil
+}
+
+// Set changes a global flag
+func (f *Flags) Set(name string, on bool) error {
+	if _, ok := defaults[name]; !ok {
+		return fmt.Errorf("unknown feature flag: %s", name)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.global[name] = on
+	return nil
+}
+
+// SetTenant overrides a flag for a single tenant
+func (f *Flags) SetTenant(tenant, name string, on bool) error {
+	if _, ok := defaults[name]; !ok {
+		return fmt.Errorf("unknown feature flag: %s", name)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.tenants[tenant] == nil {
+		f.tenants[tenant] = make(map[string]bool)
+	}
+	f.tenants[tenant][name] = on
+	return nil
+}
+
+// Enabled reports whether a module is enabled for tenant (empty for no tenant).
+// Unknown flags are disabled.
+func (f *Flags) Enabled(name, tenant string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if overrides, ok := f.tenants[tenant]; ok && tenant != "" {
+		if on, ok := overrides[name]; ok {
+			return on
+		}
+	}
+	return f.global[name]
+}
+
+// Snapshot returns the global flag states, for observability
+func (f *Flags) Snapshot() map[string]bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make(map[string]bool, len(f.global))
+	for name, on := range f.global {
+		out[name] = on
+	}
+	return out
+}
+
+// Names returns every known flag in sorted order
+func Names() []string {
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
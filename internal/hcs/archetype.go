@@ -0,0 +1,104 @@
+package hcs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Archetype is one of the 16 fixed HCS archetypes, derived from the dominant
+// element, the cognitive dominance and the tempo of a profile
+type Archetype struct {
+	ID        int    `json:"id"`   // 0-15, stable across versions
+	Code      string `json:"code"` // single hex digit used in code segments
+	Name      string `json:"name"`
+	Element   string `json:"element"`   // Fire | Earth | Air | Water
+	Cognition string `json:"cognition"` // Analytical | Expressive
+	Tempo     string `json:"tempo"`     // Swift | Steady
+}
+
+// archetypeNames is indexed by ID = element*4 + cognition*2 + tempo, with
+// elements ordered Fire, Earth, Air, Water; cognition Analytical, Expressive;
+// tempo Swift, Steady. The order must never change: IDs are encoded in codes.
+var archetypeNames = [16]string{
+	"Vanguard", "Forge", "Spark", "Beacon",
+	"Builder", "Architect", "Cultivator", "Guardian",
+	"Navigator", "Theorist", "Messenger", "Storyteller",
+	"Diver", "Sage", "Empath", "Healer",
+}
+
+var (
+	archetypeElements  = [4]string{"Fire", "Earth", "Air", "Water"}
+	archetypeCognition = [2]string{"Analytical", "Expressive"}
+	archetypeTempo     = [2]string{"Swift", "Steady"}
+)
+
+// Archetypes returns the full taxonomy ordered by ID
+func Archetypes() []Archetype {
+	out := make([]Archetype, len(archetypeNames))
+	for id := range archetypeNames {
+		out[id] = archetypeByID(id)
+	}
+	return out
+}
+
+func archetypeByID(id int) Archetype {
+	return Archetype{
+		ID:        id,
+		Code:      strconv.FormatInt(int64(id), 16),
+		Name:      archetypeNames[id],
+		Element:   archetypeElements[id/4],
+		Cognition: archetypeCognition[(id/2)%2],
+		Tempo:     archetypeTempo[id%2],
+	}
+}
+
+// AssignArchetype deterministically maps a normalized profile to its archetype.
+// Cognition is Analytical when the mean of fluid, crystallized and strategic is
+// at least the mean of verbal and creative; tempo is Swift only for a fast pace.
+func AssignArchetype(n *NormalizedProfile) Archetype {
+	element := 0
+	switch n.Element {
+	case "F":
+		element = 0
+	case "E":
+		element = 1
+	case "A":
+		element = 2
+	case "W":
+		element = 3
+	}
+
+	// Compare the means using integer cross-multiplication to avoid float ties
+	analytical := (n.Cog.F + n.Cog.C + n.Cog.S) * 2
+	expressive := (n.Cog.V + n.Cog.Cr) * 3
+	cognition := 0
+	if expressive > analytical {
+		cognition = 1
+	}
+
+	tempo := 1
+	if n.Int.PB == "F" {
+		tempo = 0
+	}
+
+	return archetypeByID(element*4 + cognition*2 + tempo)
+}
+
+// ArchetypeSegment formats the archetype as a code segment ("ARC:<hex>") for
+// inclusion in future code versions
+func ArchetypeSegment(a Archetype) string {
+	return "ARC:" + a.Code
+}
+
+// ParseArchetypeSegment parses an "ARC:<hex>" segment back into its archetype
+func ParseArchetypeSegment(segment string) (Archetype, error) {
+	if len(segment) != 5 || segment[:4] != "ARC:" {
+		return Archetype{}, fmt.Errorf("invalid archetype segment: %q", segment)
+	}
+	id := strings.IndexByte("0123456789abcdef", segment[4])
+	if id < 0 {
+		return Archetype{}, fmt.Errorf("invalid archetype segment: %q", segment)
+	}
+	return archetypeByID(id), nil
+}
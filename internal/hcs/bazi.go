@@ -65,10 +65,10 @@ var MonthBranchMapping = []int{
 
 // Pillar represents a BaZi pillar with Heavenly Stem and Earthly Branch
 type Pillar struct {
-	Stem       string `json:"stem"`
-	Branch     string `json:"branch"`
-	StemIndex  int    `json:"stemIndex"`
-	BranchIndex int   `json:"branchIndex"`
+	Stem        string `json:"stem"`
+	Branch      string `json:"branch"`
+	StemIndex   int    `json:"stemIndex"`
+	BranchIndex int    `json:"branchIndex"`
 }
 
 // PillarToString returns the string representation of a pillar
@@ -92,17 +92,17 @@ func ComputeYearPillar(year int) Pillar {
 	// BaZi year starts from Feb 4 (approximate)
 	// Using simple 60-year cycle calculation
 	cycleYear := year - 1924 // 1924 is Jia-Zi year (start of cycle)
-	
+
 	stemIndex := cycleYear % 10
 	if stemIndex < 0 {
 		stemIndex += 10
 	}
-	
+
 	branchIndex := cycleYear % 12
 	if branchIndex < 0 {
 		branchIndex += 12
 	}
-	
+
 	return Pillar{
 		Stem:        HeavenlyStems[stemIndex].Name,
 		Branch:      EarthlyBranches[branchIndex].Name,
@@ -116,14 +116,14 @@ func ComputeMonthPillar(year int, month int, day int) Pillar {
 	// Simplified month pillar calculation
 	// In real BaZi, this depends on solar terms
 	yearPillar := ComputeYearPillar(year)
-	
+
 	// Month branch is determined by solar month
 	monthBranchIndex := MonthBranchMapping[month-1]
-	
+
 	// Month stem calculation based on year stem
 	// Formula: (year stem * 2 + month number) % 10
 	monthStemIndex := (yearPillar.StemIndex*2 + month) % 10
-	
+
 	return Pillar{
 		Stem:        HeavenlyStems[monthStemIndex].Name,
 		Branch:      EarthlyBranches[monthBranchIndex].Name,
@@ -138,20 +138,20 @@ func ComputeDayPillar(year, month, day int) Pillar {
 	// Based on days since a reference date
 	refDate := time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
 	targetDate := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
-	
+
 	daysSinceRef := int(targetDate.Sub(refDate).Hours() / 24)
-	
+
 	// 60-day cycle for stems and branches
 	stemIndex := daysSinceRef % 10
 	if stemIndex < 0 {
 		stemIndex += 10
 	}
-	
+
 	branchIndex := daysSinceRef % 12
 	if branchIndex < 0 {
 		branchIndex += 12
 	}
-	
+
 	return Pillar{
 		Stem:        HeavenlyStems[stemIndex].Name,
 		Branch:      EarthlyBranches[branchIndex].Name,
@@ -165,12 +165,12 @@ func ComputeHourPillar(dayPillar Pillar, hour int) Pillar {
 	// Hour branch is determined by the time
 	// 23:00-01:00 = Zi (0), 01:00-03:00 = Chou (1), etc.
 	hourBranchIndex := ((hour + 1) / 2) % 12
-	
+
 	// Hour stem calculation based on day stem
 	// Formula varies based on day stem
 	hourStemBase := (dayPillar.StemIndex % 5) * 2
 	hourStemIndex := (hourStemBase + hourBranchIndex) % 10
-	
+
 	return Pillar{
 		Stem:        HeavenlyStems[hourStemIndex].Name,
 		Branch:      EarthlyBranches[hourBranchIndex].Name,
@@ -188,28 +188,28 @@ func CalculateElementBalance(pillars []Pillar) map[string]float64 {
 		"Metal": 0,
 		"Water": 0,
 	}
-	
+
 	// Count elements from stems and branches
 	for _, pillar := range pillars {
 		// Stem element (stronger influence)
 		stemElement := HeavenlyStems[pillar.StemIndex].Element
 		elements[stemElement] += 1.0
-		
+
 		// Branch element (lesser influence)
 		branchElement := EarthlyBranches[pillar.BranchIndex].Element
 		elements[branchElement] += 0.5
 	}
-	
+
 	// Normalize to percentages
 	total := 0.0
 	for _, v := range elements {
 		total += v
 	}
-	
+
 	for k := range elements {
 		elements[k] = elements[k] / total
 	}
-	
+
 	return elements
 }
 
@@ -217,21 +217,21 @@ func CalculateElementBalance(pillars []Pillar) map[string]float64 {
 func CalculateYinYangBalance(pillars []Pillar) float64 {
 	yangCount := 0.0
 	totalCount := 0.0
-	
+
 	for _, pillar := range pillars {
 		// Check stem Yin/Yang
 		if HeavenlyStems[pillar.StemIndex].YinYang == "Yang" {
 			yangCount += 1.0
 		}
 		totalCount += 1.0
-		
+
 		// Check branch Yin/Yang (lesser weight)
 		if EarthlyBranches[pillar.BranchIndex].YinYang == "Yang" {
 			yangCount += 0.5
 		}
 		totalCount += 0.5
 	}
-	
+
 	// Return Yang percentage (0 = pure Yin, 1 = pure Yang)
 	return yangCount / totalCount
 }
@@ -246,17 +246,17 @@ func GetDayMaster(dayPillar Pillar) string {
 func GetDayMasterStrength(pillars []Pillar, dayPillar Pillar) float64 {
 	dayElement := HeavenlyStems[dayPillar.StemIndex].Element
 	strength := 0.3 // Base strength
-	
+
 	// Check support from other pillars
 	for i, pillar := range pillars {
 		stemElement := HeavenlyStems[pillar.StemIndex].Element
 		branchElement := EarthlyBranches[pillar.BranchIndex].Element
-		
+
 		// Skip the day pillar itself
 		if i == 2 {
 			continue
 		}
-		
+
 		// Same element strengthens
 		if stemElement == dayElement {
 			strength += 0.15
@@ -264,7 +264,7 @@ func GetDayMasterStrength(pillars []Pillar, dayPillar Pillar) float64 {
 		if branchElement == dayElement {
 			strength += 0.1
 		}
-		
+
 		// Generating element strengthens (simplified cycle)
 		if isGeneratingElement(stemElement, dayElement) {
 			strength += 0.1
@@ -273,7 +273,7 @@ func GetDayMasterStrength(pillars []Pillar, dayPillar Pillar) float64 {
 			strength += 0.05
 		}
 	}
-	
+
 	// Cap between 0 and 1
 	return math.Min(math.Max(strength, 0), 1)
 }
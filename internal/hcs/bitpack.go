@@ -0,0 +1,61 @@
+package hcs
+
+import (
+	"fmt"
+	"math"
+)
+
+// quantizer maps a 0-1 value to a level of a bit field of the given width
+type quantizer func(value float64, width uint) uint16
+
+// QuantizeUnit maps a 0-1 value to the nearest of the levels 0 to 2^width-1
+// of a bit field: 0.9999 and 1 both pack as 7 in 3 bits. Values outside 0-1
+// are clamped and NaN maps to 0, so the level always fits the field.
+func QuantizeUnit(value float64, width uint) uint16 {
+	return uint16(math.Round(clampUnit(value) * float64(fieldMax(width))))
+}
+
+// truncateUnit is the quantization of the engines before v3. It scales like
+// QuantizeUnit but rounds down, so only 1 itself reaches the top level and
+// values just below a level, such as 3.0/7 after floating-point error, fall
+// to the one beneath. It clamps the same way.
+func truncateUnit(value float64, width uint) uint16 {
+	return uint16(clampUnit(value) * float64(fieldMax(width)))
+}
+
+// quantizerFor returns the U5 quantization of an engine version
+func quantizerFor(engineVersion string) quantizer {
+	if truncatingEngineVersions[engineVersion] {
+		return truncateUnit
+	}
+	return QuantizeUnit
+}
+
+// PackField masks value to a width-bit field and shifts it into place, so an
+// out-of-range value cannot spill into the neighbouring fields. Builds tagged
+// hcs_debug panic on such a value instead (see BitFieldAssertions).
+func PackField(value uint16, shift, width uint) uint16 {
+	if bitFieldAssertions && value > fieldMax(width) {
+		panic(fmt.Sprintf("hcs: value %d overflows the %d-bit field at bit %d", value, width, shift))
+	}
+	return (value & fieldMax(width)) << shift
+}
+
+// BitFieldAssertions reports whether PackField panics on values that do not
+// fit their field, in builds tagged hcs_debug
+func BitFieldAssertions() bool {
+	return bitFieldAssertions
+}
+
+// fieldMax returns the largest value of a width-bit field
+func fieldMax(width uint) uint16 {
+	return uint16(1)<<width - 1
+}
+
+// clampUnit clamps a value to 0-1, mapping NaN to 0
+func clampUnit(value float64) float64 {
+	if math.IsNaN(value) {
+		return 0
+	}
+	return clampValue(value)
+}
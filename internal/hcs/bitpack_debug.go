@@ -0,0 +1,7 @@
+//go:build hcs_debug
+
+package hcs
+
+// Built with the hcs_debug tag: PackField panics on a value that does not fit
+// its field instead of masking it, to catch packing bugs in tests
+const bitFieldAssertions = true
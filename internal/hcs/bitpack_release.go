@@ -0,0 +1,5 @@
+//go:build !hcs_debug
+
+package hcs
+
+const bitFieldAssertions = false
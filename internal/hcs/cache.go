@@ -0,0 +1,71 @@
+package hcs
+
+import (
+	"container/list"
+	"sync"
+)
+
+// profileCacheKey identifies a Chinese profile computation
+type profileCacheKey struct {
+	birth  BirthInfo
+	engine string
+}
+
+type profileCacheEntry struct {
+	key     profileCacheKey
+	profile ChineseProfile
+}
+
+// profileCache is a bounded LRU cache of computed Chinese profiles
+type profileCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // most recently used first
+	entries map[profileCacheKey]*list.Element
+}
+
+func newProfileCache(size int) *profileCache {
+	return &profileCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[profileCacheKey]*list.Element, size),
+	}
+}
+
+// get returns a copy of the cached profile for key
+func (c *profileCache) get(key profileCacheKey) (*ChineseProfile, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return copyChineseProfile(&el.Value.(*profileCacheEntry).profile), true
+}
+
+// put stores a copy of profile, evicting the least recently used entry when full
+func (c *profileCache) put(key profileCacheKey, profile *ChineseProfile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*profileCacheEntry).key)
+	}
+	c.entries[key] = c.order.PushFront(&profileCacheEntry{key: key, profile: *copyChineseProfile(profile)})
+}
+
+// copyChineseProfile returns a deep copy, so cached profiles are never shared with outputs
+func copyChineseProfile(p *ChineseProfile) *ChineseProfile {
+	cp := *p
+	cp.ElementBalance = make(map[string]float64, len(p.ElementBalance))
+	for k, v := range p.ElementBalance {
+		cp.ElementBalance[k] = v
+	}
+	return &cp
+}
@@ -33,6 +33,7 @@ type canonicalFusion struct {
 	FusionID          string `json:"fusionId"`
 	UnifiedBalance    fixed4 `json:"unifiedBalance"`
 	HarmonicResonance fixed4 `json:"harmonicResonance"`
+	ConfigID          string `json:"configId,omitempty"` // omitted for the default weights
 }
 
 type canonicalProfile struct {
@@ -84,6 +85,7 @@ func CanonicalProfileData(normalized *NormalizedProfile, combined *CombinedProfi
 			FusionID:          f.FusionID,
 			UnifiedBalance:    fixed4(f.UnifiedBalance),
 			HarmonicResonance: fixed4(f.HarmonicResonance),
+			ConfigID:          combined.FusionConfigID,
 		}
 	}
 
@@ -15,6 +15,8 @@ type ChineseProfile struct {
 	ElementBalance    map[string]float64 `json:"elementBalance"`    // Wood, Fire, Earth, Metal, Water percentages
 	DayMaster         string             `json:"dayMaster"`         // Day stem (most important in BaZi)
 	DayMasterStrength float64            `json:"dayMasterStrength"` // 0 = weak, 1 = strong
+	// LuckPillars are computed when the birth info has a gender
+	LuckPillars *LuckPillars `json:"luckPillars,omitempty"`
 }
 
 // BirthInfo contains the birth date and time information needed for BaZi
@@ -25,50 +27,35 @@ type BirthInfo struct {
 	Hour     int    `json:"hour"`
 	Minute   int    `json:"minute"`
 	Timezone string `json:"timezone"`
+	// Optional birth place in degrees (north and east positive), needed for
+	// the Western ascendant
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	// Optional gender, male or female, which sets the direction of the Luck
+	// Pillars
+	Gender string `json:"gender,omitempty"`
 }
 
 // ComputeChineseProfile generates a complete Chinese astrological profile
+// with the current engine
 func ComputeChineseProfile(birthInfo BirthInfo) (*ChineseProfile, error) {
+	return ComputeChineseProfileWithEngine(birthInfo, CurrentEngineVersion)
+}
+
+// ComputeChineseProfileWithEngine generates the Chinese profile computed by an
+// engine version, to reproduce codes issued under an earlier engine
+func ComputeChineseProfileWithEngine(birthInfo BirthInfo, engineVersion string) (*ChineseProfile, error) {
 	// Validate input
 	if err := validateBirthInfo(birthInfo); err != nil {
 		return nil, err
 	}
-
-	// Load timezone if specified
-	loc := time.UTC
-	if birthInfo.Timezone != "" && birthInfo.Timezone != "UTC" {
-		parsedLoc, err := time.LoadLocation(birthInfo.Timezone)
-		if err == nil {
-			loc = parsedLoc
-		}
-		// If timezone parsing fails, continue with UTC
+	engineVersion, err := ResolveEngineVersion(engineVersion)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create birth time in the specified timezone
-	birthTime := time.Date(
-		birthInfo.Year,
-		time.Month(birthInfo.Month),
-		birthInfo.Day,
-		birthInfo.Hour,
-		birthInfo.Minute,
-		0, 0, loc,
-	)
-
-	// Convert to local time for BaZi calculation
-	// BaZi traditionally uses local solar time
-	year := birthTime.Year()
-	month := int(birthTime.Month())
-	day := birthTime.Day()
-	hour := birthTime.Hour()
-
-	// Compute the four pillars
-	yearPillar := ComputeYearPillar(year)
-	monthPillar := ComputeMonthPillar(year, month, day)
-	dayPillar := ComputeDayPillar(year, month, day)
-	hourPillar := ComputeHourPillar(dayPillar, hour)
-
-	// Collect all pillars
-	pillars := []Pillar{yearPillar, monthPillar, dayPillar, hourPillar}
+	birthTime, pillars := computePillars(birthInfo, engineVersion)
+	yearPillar, monthPillar, dayPillar, hourPillar := pillars[0], pillars[1], pillars[2], pillars[3]
 
 	// Calculate element balance
 	elementBalance := CalculateElementBalance(pillars)
@@ -82,7 +69,7 @@ func ComputeChineseProfile(birthInfo BirthInfo) (*ChineseProfile, error) {
 	// Calculate Day Master strength
 	dayMasterStrength := GetDayMasterStrength(pillars, dayPillar)
 
-	return &ChineseProfile{
+	profile := &ChineseProfile{
 		YearPillar:        yearPillar.PillarToString(),
 		MonthPillar:       monthPillar.PillarToString(),
 		DayPillar:         dayPillar.PillarToString(),
@@ -91,7 +78,71 @@ func ComputeChineseProfile(birthInfo BirthInfo) (*ChineseProfile, error) {
 		ElementBalance:    elementBalance,
 		DayMaster:         dayMaster,
 		DayMasterStrength: dayMasterStrength,
-	}, nil
+	}
+	if birthInfo.Gender != "" {
+		profile.LuckPillars = computeLuckPillars(birthTime, yearPillar, monthPillar, birthInfo.Gender)
+	}
+	return profile, nil
+}
+
+// ComputeChineseProfileAt generates the Chinese profile computed by an engine
+// version, with the Luck Pillar running at reference as the current one
+func ComputeChineseProfileAt(birthInfo BirthInfo, engineVersion string, reference time.Time) (*ChineseProfile, error) {
+	profile, err := ComputeChineseProfileWithEngine(birthInfo, engineVersion)
+	if err != nil || profile.LuckPillars == nil {
+		return profile, err
+	}
+	profile.LuckPillars = profile.LuckPillars.At(reference)
+	return profile, nil
+}
+
+// computePillars returns the local birth time used for BaZi and the year,
+// month, day and hour pillars, in that order. Since v2 the year and month
+// pillars change at the solar terms; the calendar engine changes them with
+// the calendar year and month.
+func computePillars(birthInfo BirthInfo, engineVersion string) (time.Time, []Pillar) {
+	birthTime := birthTime(birthInfo)
+
+	// Convert to local time for BaZi calculation
+	// BaZi traditionally uses local solar time
+	year := birthTime.Year()
+	month := int(birthTime.Month())
+	day := birthTime.Day()
+	hour := birthTime.Hour()
+
+	// Compute the four pillars
+	yearPillar := ComputeSolarYearPillar(birthTime)
+	monthPillar := ComputeSolarMonthPillar(birthTime)
+	if engineVersion == calendarEngineVersion {
+		yearPillar = ComputeYearPillar(year)
+		monthPillar = ComputeMonthPillar(year, month, day)
+	}
+	dayPillar := ComputeDayPillar(year, month, day)
+	hourPillar := ComputeHourPillar(dayPillar, hour)
+
+	return birthTime, []Pillar{yearPillar, monthPillar, dayPillar, hourPillar}
+}
+
+// birthTime returns the birth time in its timezone
+func birthTime(birthInfo BirthInfo) time.Time {
+	// Load timezone if specified
+	loc := time.UTC
+	if birthInfo.Timezone != "" && birthInfo.Timezone != "UTC" {
+		parsedLoc, err := time.LoadLocation(birthInfo.Timezone)
+		if err == nil {
+			loc = parsedLoc
+		}
+		// If timezone parsing fails, continue with UTC
+	}
+
+	return time.Date(
+		birthInfo.Year,
+		time.Month(birthInfo.Month),
+		birthInfo.Day,
+		birthInfo.Hour,
+		birthInfo.Minute,
+		0, 0, loc,
+	)
 }
 
 // validateBirthInfo validates the birth information
@@ -121,22 +172,16 @@ func validateBirthInfo(info BirthInfo) error {
 		return fmt.Errorf("minute must be between 0 and 59, got %d", info.Minute)
 	}
 
-	return nil
+	if err := validateGender(info.Gender); err != nil {
+		return err
+	}
+	return validateBirthPlace(info)
 }
 
-// GetDominantChineseElement returns the most prominent element in the profile
+// GetDominantChineseElement returns the most prominent element in the
+// profile; ties go to the earliest in chineseElements
 func (cp *ChineseProfile) GetDominantChineseElement() string {
-	maxElement := ""
-	maxValue := 0.0
-
-	for element, value := range cp.ElementBalance {
-		if value > maxValue {
-			maxValue = value
-			maxElement = element
-		}
-	}
-
-	return maxElement
+	return getDominantElement(cp.ElementBalance)
 }
 
 // GetChineseElementStrength returns the strength of a specific element
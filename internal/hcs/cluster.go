@@ -0,0 +1,155 @@
+package hcs
+
+import (
+	"fmt"
+	"math"
+)
+
+// ProfileVectorDimensions names the components of the vector returned by ProfileVector
+var ProfileVectorDimensions = []string{
+	"element.earth", "element.air", "element.water", "element.fire",
+	"modal.cardinal", "modal.fixed", "modal.mutable",
+	"cog.fluid", "cog.crystallized", "cog.verbal", "cog.strategic", "cog.creative",
+	"int.pace", "int.structure", "int.tone",
+}
+
+// ProfileVector maps a normalized profile onto a fixed-length vector with every
+// component in the 0-1 range, suitable for distance-based analytics. The element
+// is one-hot encoded; interaction preferences are ordinal.
+func ProfileVector(n *NormalizedProfile) []float64 {
+	v := make([]float64, len(ProfileVectorDimensions))
+	switch n.Element {
+	case "E":
+		v[0] = 1
+	case "A":
+		v[1] = 1
+	case "W":
+		v[2] = 1
+	case "F":
+		v[3] = 1
+	}
+	v[4] = float64(n.Modal.C) / 100
+	v[5] = float64(n.Modal.F) / 100
+	v[6] = float64(n.Modal.M) / 100
+	v[7] = float64(n.Cog.F) / 100
+	v[8] = float64(n.Cog.C) / 100
+	v[9] = float64(n.Cog.V) / 100
+	v[10] = float64(n.Cog.S) / 100
+	v[11] = float64(n.Cog.Cr) / 100
+	v[12] = map[string]float64{"S": 0, "B": 0.5, "F": 1}[n.Int.PB]
+	v[13] = map[string]float64{"L": 0, "M": 0.5, "H": 1}[n.Int.SM]
+	v[14] = map[string]float64{"W": 0, "N": 1.0 / 3, "S": 2.0 / 3, "P": 1}[n.Int.TN]
+	return v
+}
+
+// ClusterResult is the outcome of a k-means run
+type ClusterResult struct {
+	Assignments []int       // cluster index per input vector
+	Centroids   [][]float64 // one centroid per cluster
+	Iterations  int
+}
+
+// KMeans partitions vectors into k clusters. Initialization uses deterministic
+// farthest-point seeding from the first vector, so identical inputs always yield
+// identical clusters. k is reduced to the number of vectors when larger.
+func KMeans(vectors [][]float64, k int, maxIter int) (*ClusterResult, error) {
+	if k < 1 {
+		return nil, fmt.Errorf("k must be at least 1, got %d", k)
+	}
+	if len(vectors) == 0 {
+		return &ClusterResult{}, nil
+	}
+	dim := len(vectors[0])
+	for i, v := range vectors {
+		if len(v) != dim {
+			return nil, fmt.Errorf("vector %d has %d dimensions, expected %d", i, len(v), dim)
+		}
+	}
+	if k > len(vectors) {
+		k = len(vectors)
+	}
+	if maxIter < 1 {
+		maxIter = 100
+	}
+
+	// Farthest-point seeding
+	centroids := [][]float64{append([]float64(nil), vectors[0]...)}
+	for len(centroids) < k {
+		best, bestDist := 0, -1.0
+		for i, v := range vectors {
+			d := nearestDistance(v, centroids)
+			if d > bestDist {
+				best, bestDist = i, d
+			}
+		}
+		centroids = append(centroids, append([]float64(nil), vectors[best]...))
+	}
+
+	assignments := make([]int, len(vectors))
+	iter := 0
+	for iter < maxIter {
+		iter++
+		changed := false
+		for i, v := range vectors {
+			if c := nearestCentroid(v, centroids); c != assignments[i] {
+				assignments[i] = c
+				changed = true
+			}
+		}
+
+		// Recompute centroids; empty clusters keep their previous position
+		sums := make([][]float64, k)
+		counts := make([]int, k)
+		for i := range sums {
+			sums[i] = make([]float64, dim)
+		}
+		for i, v := range vectors {
+			c := assignments[i]
+			counts[c]++
+			for d := range v {
+				sums[c][d] += v[d]
+			}
+		}
+		for c := range centroids {
+			if counts[c] == 0 {
+				continue
+			}
+			for d := range sums[c] {
+				centroids[c][d] = sums[c][d] / float64(counts[c])
+			}
+		}
+
+		if !changed && iter > 1 {
+			break
+		}
+	}
+
+	return &ClusterResult{
+		Assignments: assignments,
+		Centroids:   centroids,
+		Iterations:  iter,
+	}, nil
+}
+
+func nearestCentroid(v []float64, centroids [][]float64) int {
+	best, bestDist := 0, math.Inf(1)
+	for i, c := range centroids {
+		if d := squaredDistance(v, c); d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+func nearestDistance(v []float64, centroids [][]float64) float64 {
+	return squaredDistance(v, centroids[nearestCentroid(v, centroids)])
+}
+
+func squaredDistance(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return sum
+}
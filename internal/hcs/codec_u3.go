@@ -38,12 +38,21 @@ func EncodeU3(in *InputProfile, chip string) string {
 		elemSegment, modalSegment, cogSegment, intSegment, chipSegment)
 }
 
+// FormatHCSU3 assembles the HCS-U3 code of a normalized profile, as EncodeU3
+// does from the input profile
+func FormatHCSU3(profile *NormalizedProfile, chip string) string {
+	return fmt.Sprintf("HCS-U3|E:%s|MOD:c%02df%02dm%02d|COG:F%02dC%02dV%02dS%02dCr%02d|INT:PB=%s,SM=%s,TN=%s|CHIP:%s",
+		profile.Element, profile.Modal.C, profile.Modal.F, profile.Modal.M,
+		profile.Cog.F, profile.Cog.C, profile.Cog.V, profile.Cog.S, profile.Cog.Cr,
+		profile.Int.PB, profile.Int.SM, profile.Int.TN, chip)
+}
+
+// u3Pattern is the compiled HCS-U3 grammar
+var u3Pattern = regexp.MustCompile(U3Grammar)
+
 // ValidateU3Format checks if a string matches the expected HCS-U3 format
 func ValidateU3Format(code string) bool {
-	// Regex pattern for HCS-U3 format
-	pattern := `^HCS-U3\|E:[AEWF]\|MOD:c\d{2}f\d{2}m\d{2}\|COG:F\d{2}C\d{2}V\d{2}S\d{2}Cr\d{2}\|INT:PB=[BFS],SM=[LMH],TN=[WNSP]\|CHIP:[0-9a-f]{12}$`
-	matched, _ := regexp.MatchString(pattern, code)
-	return matched
+	return u3Pattern.MatchString(code)
 }
 
 // ParseU3 parses an HCS-U3 code and extracts components (optional utility)
@@ -56,10 +65,9 @@ func ParseU3(code string) (map[string]string, error) {
 	components := make(map[string]string)
 
 	// Extract components using regex groups
-	pattern := regexp.MustCompile(`HCS-U3\|E:([AEWF])\|MOD:c(\d{2})f(\d{2})m(\d{2})\|COG:F(\d{2})C(\d{2})V(\d{2})S(\d{2})Cr(\d{2})\|INT:PB=([BFS]),SM=([LMH]),TN=([WNSP])\|CHIP:([0-9a-f]{12})`)
-	matches := pattern.FindStringSubmatch(code)
+	matches := u3Pattern.FindStringSubmatch(code)
 
-	if len(matches) == 14 {
+	if len(matches) == 16 {
 		components["element"] = matches[1]
 		components["modal_cardinal"] = matches[2]
 		components["modal_fixed"] = matches[3]
@@ -73,6 +81,12 @@ func ParseU3(code string) (map[string]string, error) {
 		components["int_structure"] = matches[11]
 		components["int_tone"] = matches[12]
 		components["chip"] = matches[13]
+		if matches[14] != "" {
+			components["epoch"] = matches[14]
+		}
+		if matches[15] != "" {
+			components["lineage"] = matches[15]
+		}
 	}
 
 	return components, nil
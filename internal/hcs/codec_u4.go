@@ -10,11 +10,28 @@ import (
 // This is a stub implementation using base64 encoding
 // Can be replaced with base62 or other encoding schemes later
 func EncodeU4(normalized *NormalizedProfile, chip string) (string, error) {
+	return EncodeU4WithEpoch(normalized, chip, 0)
+}
+
+// EncodeU4WithEpoch is EncodeU4 for a CHIP computed with the salt of epoch.
+// Epoch 0 is not recorded, so those codes are identical to EncodeU4's.
+func EncodeU4WithEpoch(normalized *NormalizedProfile, chip string, epoch int) (string, error) {
+	return encodeU4(normalized, chip, epoch, "")
+}
+
+// encodeU4 is EncodeU4WithEpoch recording the lineage hash of a regenerated code
+func encodeU4(normalized *NormalizedProfile, chip string, epoch int, lineage string) (string, error) {
 	// Create a compact structure for U4 encoding
 	u4Data := map[string]interface{}{
 		"profile": normalized,
 		"chip":    chip,
 	}
+	if epoch > 0 {
+		u4Data["epoch"] = epoch
+	}
+	if lineage != "" {
+		u4Data["lineage"] = lineage
+	}
 
 	// Marshal to JSON
 	jsonData, err := json.Marshal(u4Data)
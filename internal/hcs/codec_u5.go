@@ -1,14 +1,28 @@
 package hcs
 
 import (
-	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"regexp"
+	"strconv"
 )
 
-// EncodeU5 generates the HCS-U5 code from combined profiles and CHIP
+// EncodeU5 generates the HCS-U5 code from combined profiles and CHIP with the
+// current engine
 // Format: HCS-U5|XX|W:<hex>|C:<hex>|F:<hex>|CHIP:<12hex>
 func EncodeU5(western *WesternProfile, chinese *ChineseProfile, fusion *FusionProfile, salt []byte) (string, error) {
+	return EncodeU5WithEngine(western, chinese, fusion, salt, CurrentEngineVersion)
+}
+
+// EncodeU5WithEngine generates the HCS-U5 code with the bit-field quantization
+// of an engine version, to reproduce codes issued under an earlier engine
+func EncodeU5WithEngine(western *WesternProfile, chinese *ChineseProfile, fusion *FusionProfile, salt []byte, engineVersion string) (string, error) {
+	engineVersion, err := ResolveEngineVersion(engineVersion)
+	if err != nil {
+		return "", err
+	}
+	quantize := quantizerFor(engineVersion)
+
 	// Generate fusion ID (2 chars)
 	fusionID := fusion.FusionID
 	if len(fusionID) != 2 {
@@ -16,13 +30,13 @@ func EncodeU5(western *WesternProfile, chinese *ChineseProfile, fusion *FusionPr
 	}
 
 	// Compress Western profile to 16-bit hex
-	westernHex := compressWesternProfile(western)
+	westernHex := compressWesternProfile(western, quantize)
 
 	// Compress Chinese profile to 16-bit hex
-	chineseHex := compressChineseProfile(chinese)
+	chineseHex := compressChineseProfile(chinese, quantize)
 
 	// Compress Fusion traits to 16-bit hex
-	fusionHex := compressFusionProfile(fusion)
+	fusionHex := compressFusionProfile(fusion, quantize)
 
 	// Generate CHIP for U5 (using combined data)
 	chipU5, err := generateU5Chip(western, chinese, fusion, salt)
@@ -38,7 +52,7 @@ func EncodeU5(western *WesternProfile, chinese *ChineseProfile, fusion *FusionPr
 }
 
 // compressWesternProfile compresses Western profile to 4 hex chars (16 bits)
-func compressWesternProfile(western *WesternProfile) string {
+func compressWesternProfile(western *WesternProfile, quantize quantizer) string {
 	// Allocate 16 bits:
 	// - 2 bits: element (4 options)
 	// - 3x3 bits: modal balance (each 0-7 scale)
@@ -60,17 +74,13 @@ func compressWesternProfile(western *WesternProfile) string {
 	case "Water":
 		elementBits = 3
 	}
-	bits |= (elementBits << 14)
+	bits |= PackField(elementBits, 14, 2)
 
-	// Modal balance (bits 13-5)
+	// Modal balance (bits 12-4)
 	// Convert to 3-bit values (0-7)
-	cardinalBits := uint16(western.Modal.Cardinal * 7)
-	fixedBits := uint16(western.Modal.Fixed * 7)
-	mutableBits := uint16(western.Modal.Mutable * 7)
-
-	bits |= (cardinalBits << 10)
-	bits |= (fixedBits << 7)
-	bits |= (mutableBits << 4)
+	bits |= PackField(quantize(western.Modal.Cardinal, 3), 10, 3)
+	bits |= PackField(quantize(western.Modal.Fixed, 3), 7, 3)
+	bits |= PackField(quantize(western.Modal.Mutable, 3), 4, 3)
 
 	// Pace (bits 3-2)
 	paceBits := uint16(0)
@@ -82,16 +92,16 @@ func compressWesternProfile(western *WesternProfile) string {
 	case "fast":
 		paceBits = 2
 	}
-	bits |= (paceBits << 2)
+	bits |= PackField(paceBits, 2, 2)
 
 	// Structure (bit 1)
 	if western.Interaction.Structure == "high" {
-		bits |= (1 << 1)
+		bits |= PackField(1, 1, 1)
 	}
 
 	// Tone category (bit 0)
 	if western.Interaction.Tone == "sharp" || western.Interaction.Tone == "precise" {
-		bits |= 1
+		bits |= PackField(1, 0, 1)
 	}
 
 	// Convert to 4-char hex
@@ -99,7 +109,7 @@ func compressWesternProfile(western *WesternProfile) string {
 }
 
 // compressChineseProfile compresses Chinese profile to 4 hex chars (16 bits)
-func compressChineseProfile(chinese *ChineseProfile) string {
+func compressChineseProfile(chinese *ChineseProfile, quantize quantizer) string {
 	// Allocate 16 bits:
 	// - 3 bits: dominant element (5 options + padding)
 	// - 3 bits: yin/yang balance (0-7 scale)
@@ -124,11 +134,10 @@ func compressChineseProfile(chinese *ChineseProfile) string {
 	case "Water":
 		elementBits = 4
 	}
-	bits |= (elementBits << 13)
+	bits |= PackField(elementBits, 13, 3)
 
 	// Yin/Yang balance (bits 12-10)
-	yinYangBits := uint16(chinese.YinYangBalance * 7)
-	bits |= (yinYangBits << 10)
+	bits |= PackField(quantize(chinese.YinYangBalance, 3), 10, 3)
 
 	// Day Master index (bits 9-6)
 	dayMasterBits := uint16(0)
@@ -138,24 +147,22 @@ func compressChineseProfile(chinese *ChineseProfile) string {
 			break
 		}
 	}
-	bits |= (dayMasterBits << 6)
+	bits |= PackField(dayMasterBits, 6, 4)
 
 	// Day Master strength (bits 5-3)
-	strengthBits := uint16(chinese.DayMasterStrength * 7)
-	bits |= (strengthBits << 3)
+	bits |= PackField(quantize(chinese.DayMasterStrength, 3), 3, 3)
 
 	// Element distribution pattern (bits 2-0)
 	// Encode whether elements are balanced or skewed
 	variance := calculateElementDistribution(chinese.ElementBalance)
-	patternBits := uint16(variance * 7)
-	bits |= patternBits
+	bits |= PackField(quantize(variance, 3), 0, 3)
 
 	// Convert to 4-char hex
 	return fmt.Sprintf("%04x", bits)
 }
 
 // compressFusionProfile compresses Fusion profile to 4 hex chars (16 bits)
-func compressFusionProfile(fusion *FusionProfile) string {
+func compressFusionProfile(fusion *FusionProfile, quantize quantizer) string {
 	// Allocate 16 bits:
 	// - 4 bits: cognitive fusion pattern
 	// - 3 bits: tempo pace
@@ -167,24 +174,21 @@ func compressFusionProfile(fusion *FusionProfile) string {
 
 	// Cognitive fusion pattern (bits 15-12)
 	// Encode which cognitive aspect is dominant
-	cogPattern := getCognitivePattern(fusion.CognitiveFusion)
-	bits |= (cogPattern << 12)
+	// An Expressive pattern (16 and up) keeps only its low bits, as it always has
+	cogPattern := getCognitivePattern(fusion.CognitiveFusion) & fieldMax(4)
+	bits |= PackField(cogPattern, 12, 4)
 
 	// Tempo pace (bits 11-9)
-	paceBits := uint16(fusion.TempoSignals.Pace * 7)
-	bits |= (paceBits << 9)
+	bits |= PackField(quantize(fusion.TempoSignals.Pace, 3), 9, 3)
 
 	// Intensity (bits 8-6)
-	intensityBits := uint16(fusion.TempoSignals.Intensity * 7)
-	bits |= (intensityBits << 6)
+	bits |= PackField(quantize(fusion.TempoSignals.Intensity, 3), 6, 3)
 
 	// Unified balance (bits 5-3)
-	balanceBits := uint16(fusion.UnifiedBalance * 7)
-	bits |= (balanceBits << 3)
+	bits |= PackField(quantize(fusion.UnifiedBalance, 3), 3, 3)
 
 	// Harmonic resonance (bits 2-0)
-	resonanceBits := uint16(fusion.HarmonicResonance * 7)
-	bits |= resonanceBits
+	bits |= PackField(quantize(fusion.HarmonicResonance, 3), 0, 3)
 
 	// Convert to 4-char hex
 	return fmt.Sprintf("%04x", bits)
@@ -192,20 +196,48 @@ func compressFusionProfile(fusion *FusionProfile) string {
 
 // generateU5Chip generates a unique CHIP for U5 using all profile data
 func generateU5Chip(western *WesternProfile, chinese *ChineseProfile, fusion *FusionProfile, salt []byte) (string, error) {
+	// Take first 12 hex characters (48 bits)
+	return u5ChipDigest(western, chinese, fusion, salt)[:12], nil
+}
+
+// u5ChipDigest returns the full SHA256 hex digest from which the U5 CHIP is truncated
+func u5ChipDigest(western *WesternProfile, chinese *ChineseProfile, fusion *FusionProfile, salt []byte) string {
 	// Create a deterministic string representation of all profiles
-	data := fmt.Sprintf("U5|W:%+v|C:%+v|F:%+v", western, chinese, fusion)
+	data := fmt.Sprintf("U5|W:%+v|C:%+v|F:%+v", western, u5ChipChinese(chinese), fusion)
 
 	// Concatenate salt + data
 	input := append(salt, []byte(data)...)
 
 	// Compute SHA256
-	hash := sha256.Sum256(input)
+	return hex.EncodeToString(hashOf(HashSHA256, input))
+}
 
-	// Take first 12 hex characters (48 bits)
-	hexHash := hex.EncodeToString(hash[:])
-	chip := hexHash[:12]
+// u5ChineseFields are the ChineseProfile fields in the U5 CHIP digest. Fields
+// added to the profile since, such as the Luck Pillars, are not encoded.
+type u5ChineseFields struct {
+	YearPillar        string
+	MonthPillar       string
+	DayPillar         string
+	HourPillar        string
+	YinYangBalance    float64
+	ElementBalance    map[string]float64
+	DayMaster         string
+	DayMasterStrength float64
+}
 
-	return chip, nil
+// u5ChipChinese selects the digested fields of a Chinese profile, formatted
+// by %+v like the profile itself
+func u5ChipChinese(cp *ChineseProfile) *u5ChineseFields {
+	return &u5ChineseFields{
+		YearPillar:        cp.YearPillar,
+		MonthPillar:       cp.MonthPillar,
+		DayPillar:         cp.DayPillar,
+		HourPillar:        cp.HourPillar,
+		YinYangBalance:    cp.YinYangBalance,
+		ElementBalance:    cp.ElementBalance,
+		DayMaster:         cp.DayMaster,
+		DayMasterStrength: cp.DayMasterStrength,
+	}
 }
 
 // Helper function to calculate element distribution variance
@@ -214,8 +246,8 @@ func calculateElementDistribution(elements map[string]float64) float64 {
 	mean := 0.2 // Expected mean for 5 elements
 	sumSquaredDiff := 0.0
 
-	for _, value := range elements {
-		diff := value - mean
+	for _, element := range chineseElements {
+		diff := elements[element] - mean
 		sumSquaredDiff += diff * diff
 	}
 
@@ -224,23 +256,25 @@ func calculateElementDistribution(elements map[string]float64) float64 {
 	return clampValue(variance * 10) // Scale and clamp
 }
 
-// getCognitivePattern returns a 4-bit pattern representing dominant cognitive trait
+// getCognitivePattern returns a 4-bit pattern representing dominant cognitive
+// trait. Ties go to the earliest trait in pattern order.
 func getCognitivePattern(cog CognitiveFusion) uint16 {
 	maxVal := 0.0
 	pattern := uint16(0)
 
-	traits := map[uint16]float64{
-		0: cog.Analytical,
-		1: cog.Creative,
-		2: cog.Grounded,
-		3: cog.Adaptive,
-		4: cog.Expressive,
+	// Indexed by pattern, in a fixed order so ties do not depend on map iteration
+	traits := []float64{
+		cog.Analytical,
+		cog.Creative,
+		cog.Grounded,
+		cog.Adaptive,
+		cog.Expressive,
 	}
 
 	for p, val := range traits {
 		if val > maxVal {
 			maxVal = val
-			pattern = p
+			pattern = uint16(p)
 		}
 	}
 
@@ -248,9 +282,9 @@ func getCognitivePattern(cog CognitiveFusion) uint16 {
 	secondMax := 0.0
 	secondPattern := uint16(0)
 	for p, val := range traits {
-		if p != pattern && val > secondMax {
+		if uint16(p) != pattern && val > secondMax {
 			secondMax = val
-			secondPattern = p
+			secondPattern = uint16(p)
 		}
 	}
 
@@ -289,55 +323,162 @@ func ValidateU5Format(code string) bool {
 	return true
 }
 
-// DecodeU5 decodes an HCS-U5 code to extract basic information
-// This is a simplified decoder for verification purposes
-func DecodeU5(code string) (map[string]string, error) {
-	if !ValidateU5Format(code) {
+// u5Pattern is the HCS-U5 grammar: fusion ID, the three 16-bit segments, the
+// CHIP and the optional epoch and lineage
+var u5Pattern = regexp.MustCompile(`^HCS-U5\|([0-9A-Za-z]{2})\|W:([0-9a-f]{4})\|C:([0-9a-f]{4})\|F:([0-9a-f]{4})\|CHIP:([0-9a-f]{12})(?:\|EP:([1-9]\d*))?(?:\|LN:([0-9a-f]{12}))?$`)
+
+// Values of the enumerated U5 fields, indexed by their bits
+var (
+	u5WesternElements = []string{"Fire", "Earth", "Air", "Water"}
+	u5Paces           = []string{"slow", "balanced", "fast"}
+	// Expressive, pattern 4, does not fit two bits and wraps to Analytical
+	u5CognitiveTraits = []string{"Analytical", "Creative", "Grounded", "Adaptive"}
+)
+
+// U5Components is the content of an HCS-U5 code, each 16-bit segment unpacked
+// back to the approximate profile values it was compressed from
+type U5Components struct {
+	FusionID string    `json:"fusionId"`
+	Western  U5Western `json:"western"`
+	Chinese  U5Chinese `json:"chinese"`
+	Fusion   U5Fusion  `json:"fusion"`
+	Chip     string    `json:"chip"`
+	Epoch    int       `json:"epoch"`
+	Lineage  string    `json:"lineage,omitempty"`
+}
+
+// U5Western is the W segment. The modal balance is quantized to eighths of
+// the 0-1 range (level/7), so it is only accurate to about 0.07.
+type U5Western struct {
+	Hex           string       `json:"hex"`
+	Element       string       `json:"element"` // Fire, Earth, Air or Water
+	Modal         ModalBalance `json:"modal"`
+	Pace          string       `json:"pace"`          // slow, balanced or fast
+	HighStructure bool         `json:"highStructure"` // structure "high"
+	SharpTone     bool         `json:"sharpTone"`     // tone "sharp" or "precise"
+}
+
+// U5Chinese is the C segment. Levels are unpacked to level/7.
+type U5Chinese struct {
+	Hex               string  `json:"hex"`
+	Element           string  `json:"element"` // dominant element, ties to the first
+	YinYangBalance    float64 `json:"yinYangBalance"`
+	YinYangZone       string  `json:"yinYangZone"` // as ChineseProfile.GetYinYangType
+	DayMasterIndex    int     `json:"dayMasterIndex"`
+	DayMaster         string  `json:"dayMaster"`
+	DayMasterStrength float64 `json:"dayMasterStrength"`
+	ElementSkew       float64 `json:"elementSkew"` // 0 for an even element balance, 1 for a skewed one
+}
+
+// U5Fusion is the F segment. The leading traits are the two strongest
+// cognitive fusion traits; Expressive reads as Analytical. Levels are
+// unpacked to level/7.
+type U5Fusion struct {
+	Hex               string  `json:"hex"`
+	PrimaryTrait      string  `json:"primaryTrait"`
+	SecondaryTrait    string  `json:"secondaryTrait"`
+	TempoPace         float64 `json:"tempoPace"`
+	TempoIntensity    float64 `json:"tempoIntensity"`
+	UnifiedBalance    float64 `json:"unifiedBalance"`
+	HarmonicResonance float64 `json:"harmonicResonance"`
+}
+
+// DecodeU5 decodes an HCS-U5 code into its components, unpacking the W, C and
+// F segments with the bit layout of EncodeU5. Codes with field values the
+// encoder never produces, such as an eleventh day master, are rejected.
+func DecodeU5(code string) (*U5Components, error) {
+	m := u5Pattern.FindStringSubmatch(code)
+	if m == nil {
 		return nil, fmt.Errorf("invalid HCS-U5 format")
 	}
-
-	components := make(map[string]string)
-
-	// Extract fusion ID (chars 7-8)
-	components["fusionId"] = code[7:9]
-
-	// Extract segments using simple parsing
-	// Find positions of each segment
-	wPos := -1
-	cPos := -1
-	fPos := -1
-	chipPos := -1
-
-	for i := 0; i < len(code)-2; i++ {
-		if code[i:i+2] == "W:" && wPos == -1 {
-			wPos = i + 2
-		} else if code[i:i+2] == "C:" && cPos == -1 {
-			cPos = i + 2
-		} else if code[i:i+2] == "F:" && fPos == -1 {
-			fPos = i + 2
+	c := &U5Components{FusionID: m[1], Chip: m[5], Lineage: m[7]}
+	if m[6] != "" {
+		epoch, err := strconv.Atoi(m[6])
+		if err != nil {
+			return nil, fmt.Errorf("invalid salt epoch: %w", err)
 		}
+		c.Epoch = epoch
 	}
 
-	for i := 0; i < len(code)-5; i++ {
-		if code[i:i+5] == "CHIP:" {
-			chipPos = i + 5
-			break
-		}
+	var err error
+	if c.Western, err = unpackWestern(m[2]); err != nil {
+		return nil, err
 	}
-
-	// Extract hex values
-	if wPos > 0 && cPos > wPos {
-		components["western"] = code[wPos : wPos+4]
+	if c.Chinese, err = unpackChinese(m[3]); err != nil {
+		return nil, err
 	}
-	if cPos > 0 && fPos > cPos {
-		components["chinese"] = code[cPos : cPos+4]
+	c.Fusion = unpackFusion(m[4])
+	return c, nil
+}
+
+// unpackField extracts the width-bit field at shift, the inverse of PackField
+func unpackField(bits uint16, shift, width uint) uint16 {
+	return bits >> shift & fieldMax(width)
+}
+
+// unpackLevel returns the 0-1 value of the width-bit level at shift
+func unpackLevel(bits uint16, shift, width uint) float64 {
+	return float64(unpackField(bits, shift, width)) / float64(fieldMax(width))
+}
+
+// parseSegment parses the 4 hex digits of a U5 segment
+func parseSegment(segment string) uint16 {
+	bits, _ := strconv.ParseUint(segment, 16, 16) // matched by u5Pattern
+	return uint16(bits)
+}
+
+// unpackWestern unpacks the W segment of compressWesternProfile
+func unpackWestern(segment string) (U5Western, error) {
+	bits := parseSegment(segment)
+	pace := unpackField(bits, 2, 2)
+	if int(pace) >= len(u5Paces) {
+		return U5Western{}, fmt.Errorf("invalid HCS-U5 western segment: %s", segment)
 	}
-	if fPos > 0 && chipPos > fPos {
-		components["fusion"] = code[fPos : fPos+4]
+	return U5Western{
+		Hex:     segment,
+		Element: u5WesternElements[unpackField(bits, 14, 2)],
+		Modal: ModalBalance{
+			Cardinal: unpackLevel(bits, 10, 3),
+			Fixed:    unpackLevel(bits, 7, 3),
+			Mutable:  unpackLevel(bits, 4, 3),
+		},
+		Pace:          u5Paces[pace],
+		HighStructure: unpackField(bits, 1, 1) == 1,
+		SharpTone:     unpackField(bits, 0, 1) == 1,
+	}, nil
+}
+
+// unpackChinese unpacks the C segment of compressChineseProfile
+func unpackChinese(segment string) (U5Chinese, error) {
+	bits := parseSegment(segment)
+	element, dayMaster := unpackField(bits, 13, 3), unpackField(bits, 6, 4)
+	if int(element) >= len(chineseElements) || int(dayMaster) >= len(HeavenlyStems) {
+		return U5Chinese{}, fmt.Errorf("invalid HCS-U5 chinese segment: %s", segment)
 	}
-	if chipPos > 0 && chipPos+12 <= len(code) {
-		components["chip"] = code[chipPos : chipPos+12]
+	c := U5Chinese{
+		Hex:               segment,
+		Element:           chineseElements[element],
+		YinYangBalance:    unpackLevel(bits, 10, 3),
+		DayMasterIndex:    int(dayMaster),
+		DayMaster:         HeavenlyStems[dayMaster].Name,
+		DayMasterStrength: unpackLevel(bits, 3, 3),
+		ElementSkew:       unpackLevel(bits, 0, 3),
 	}
+	c.YinYangZone = (&ChineseProfile{YinYangBalance: c.YinYangBalance}).GetYinYangType()
+	return c, nil
+}
 
-	return components, nil
+// unpackFusion unpacks the F segment of compressFusionProfile. Every 16-bit
+// value is a valid segment.
+func unpackFusion(segment string) U5Fusion {
+	bits := parseSegment(segment)
+	return U5Fusion{
+		Hex:               segment,
+		PrimaryTrait:      u5CognitiveTraits[unpackField(bits, 14, 2)],
+		SecondaryTrait:    u5CognitiveTraits[unpackField(bits, 12, 2)],
+		TempoPace:         unpackLevel(bits, 9, 3),
+		TempoIntensity:    unpackLevel(bits, 6, 3),
+		UnifiedBalance:    unpackLevel(bits, 3, 3),
+		HarmonicResonance: unpackLevel(bits, 0, 3),
+	}
 }
@@ -0,0 +1,447 @@
+package hcs
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// HCS-U6 packs the normalized profile, the CHIP and, when there is one, the
+// Chinese and fusion profiles into a bit string written in base62, small
+// enough for a QR code. Unlike U5 it keeps every normalized value, and the
+// 0-1 values of the Chinese and fusion profiles to the thousandth.
+//
+// Layout, most significant bit first, zero-padded to a whole byte:
+//
+//	version 4 | flags 4 | element 2 | modal c f m 7 each |
+//	cognition F C V S Cr 7 each | pace 2 | structure 2 | tone 2 | CHIP 48 |
+//	[epoch 16] [lineage 48] [Chinese and fusion block]
+//
+// The Chinese and fusion block holds the year, month, day and hour pillars
+// (stem 4 and branch 4 each), the Yin/Yang balance, the Wood, Fire, Earth,
+// Metal and Water balances and the Day Master strength, then the fusion ID
+// (5 and 4), the element signature in the same order, the analytical,
+// creative, grounded, adaptive and expressive fusion, the tempo pace,
+// variability and intensity (10 each), the rhythm (2), and the unified
+// balance and harmonic resonance (10 each).
+const (
+	u6Prefix  = "HCS-U6|"
+	u6Version = 1
+
+	u6FlagEpoch   = 1
+	u6FlagLineage = 2
+	u6FlagFusion  = 4
+
+	// u6MilliWidth holds a 0-1 value in thousandths
+	u6MilliWidth = 10
+)
+
+// Letters and values of the U6 enumerated fields, in field value order
+const (
+	u6Elements      = "EAWF"
+	u6Paces         = "BFS"
+	u6Structures    = "LMH"
+	u6Tones         = "WNSP"
+	u6FusionLetters = "ABCDEFGHIJKLMNOPQRSTX"
+	u6FusionDigits  = "123456789"
+	base62Alphabet  = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+var u6Rhythms = []string{"steady", "dynamic", "fluctuating"}
+
+// U6Data is the content of an HCS-U6 code. Chinese and Fusion are nil for
+// codes issued without birth info; their 0-1 values are to the thousandth
+// and the Chinese profile carries no Luck Pillars.
+type U6Data struct {
+	Profile NormalizedProfile `json:"profile"`
+	Chip    string            `json:"chip"`
+	Epoch   int               `json:"epoch"`
+	Lineage string            `json:"lineage,omitempty"`
+	Chinese *ChineseProfile   `json:"chinese,omitempty"`
+	Fusion  *FusionProfile    `json:"fusion,omitempty"`
+}
+
+// EncodeU6 generates the HCS-U6 code from the normalized profile, CHIP and,
+// when birth info was given, the combined profile
+func EncodeU6(normalized *NormalizedProfile, chip string, combined *CombinedProfile) (string, error) {
+	return encodeU6(normalized, chip, combined, 0, "")
+}
+
+// encodeU6 is EncodeU6 recording the salt epoch and lineage hash
+func encodeU6(normalized *NormalizedProfile, chip string, combined *CombinedProfile, epoch int, lineage string) (string, error) {
+	if normalized == nil {
+		return "", fmt.Errorf("normalized profile cannot be nil")
+	}
+	if !chipPattern.MatchString(chip) {
+		return "", fmt.Errorf("invalid CHIP: %q", chip)
+	}
+	if epoch < 0 || epoch > 0xffff {
+		return "", fmt.Errorf("salt epoch %d does not fit HCS-U6", epoch)
+	}
+	if lineage != "" && !chipPattern.MatchString(lineage) {
+		return "", fmt.Errorf("invalid lineage: %q", lineage)
+	}
+
+	var flags uint64
+	if epoch > 0 {
+		flags |= u6FlagEpoch
+	}
+	if lineage != "" {
+		flags |= u6FlagLineage
+	}
+	if combined != nil {
+		flags |= u6FlagFusion
+	}
+
+	w := &bitWriter{}
+	w.write(u6Version, 4)
+	w.write(flags, 4)
+	if err := w.writeLetter(u6Elements, normalized.Element, 2); err != nil {
+		return "", err
+	}
+	for _, v := range []int{normalized.Modal.C, normalized.Modal.F, normalized.Modal.M,
+		normalized.Cog.F, normalized.Cog.C, normalized.Cog.V, normalized.Cog.S, normalized.Cog.Cr} {
+		if v < 0 || v > 100 {
+			return "", fmt.Errorf("percentage %d out of range", v)
+		}
+		w.write(uint64(v), 7)
+	}
+	for _, f := range []struct{ letters, value string }{
+		{u6Paces, normalized.Int.PB}, {u6Structures, normalized.Int.SM}, {u6Tones, normalized.Int.TN},
+	} {
+		if err := w.writeLetter(f.letters, f.value, 2); err != nil {
+			return "", err
+		}
+	}
+	w.writeHex(chip)
+	if epoch > 0 {
+		w.write(uint64(epoch), 16)
+	}
+	if lineage != "" {
+		w.writeHex(lineage)
+	}
+	if combined != nil {
+		if err := writeU6Fusion(w, &combined.Chinese, &combined.Fusion); err != nil {
+			return "", err
+		}
+	}
+
+	return u6Prefix + encodeBase62(w.bytes()), nil
+}
+
+// writeU6Fusion writes the Chinese and fusion block
+func writeU6Fusion(w *bitWriter, chinese *ChineseProfile, fusion *FusionProfile) error {
+	for _, pillar := range []string{chinese.YearPillar, chinese.MonthPillar, chinese.DayPillar, chinese.HourPillar} {
+		p, err := parsePillar(pillar)
+		if err != nil {
+			return err
+		}
+		w.write(uint64(p.StemIndex), 4)
+		w.write(uint64(p.BranchIndex), 4)
+	}
+	w.writeMilli(chinese.YinYangBalance)
+	for _, element := range chineseElements {
+		w.writeMilli(chinese.ElementBalance[element])
+	}
+	w.writeMilli(chinese.DayMasterStrength)
+
+	if len(fusion.FusionID) != 2 {
+		return fmt.Errorf("invalid fusion ID: %q", fusion.FusionID)
+	}
+	if err := w.writeLetter(u6FusionLetters, fusion.FusionID[:1], 5); err != nil {
+		return err
+	}
+	if err := w.writeLetter(u6FusionDigits, fusion.FusionID[1:], 4); err != nil {
+		return err
+	}
+	for _, element := range chineseElements {
+		w.writeMilli(fusion.ElementSignature[element])
+	}
+	cog := fusion.CognitiveFusion
+	tempo := fusion.TempoSignals
+	for _, v := range []float64{cog.Analytical, cog.Creative, cog.Grounded, cog.Adaptive, cog.Expressive,
+		tempo.Pace, tempo.Variability, tempo.Intensity} {
+		w.writeMilli(v)
+	}
+	rhythm := indexOf(u6Rhythms, tempo.Rhythm)
+	if rhythm < 0 {
+		return fmt.Errorf("invalid rhythm: %q", tempo.Rhythm)
+	}
+	w.write(uint64(rhythm), 2)
+	w.writeMilli(fusion.UnifiedBalance)
+	w.writeMilli(fusion.HarmonicResonance)
+	return nil
+}
+
+// DecodeU6 decodes an HCS-U6 code back to its components
+func DecodeU6(code string) (*U6Data, error) {
+	encoded, ok := strings.CutPrefix(code, u6Prefix)
+	if !ok {
+		return nil, fmt.Errorf("invalid HCS-U6 format")
+	}
+	payload, err := decodeBase62(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode U6: %w", err)
+	}
+	if encodeBase62(payload) != encoded {
+		return nil, fmt.Errorf("non-canonical HCS-U6 encoding")
+	}
+
+	r := &bitReader{data: payload}
+	if version := r.read(4); version != u6Version {
+		return nil, fmt.Errorf("unsupported HCS-U6 version %d", version)
+	}
+	flags := r.read(4)
+	if flags&^(u6FlagEpoch|u6FlagLineage|u6FlagFusion) != 0 {
+		return nil, fmt.Errorf("unknown HCS-U6 flags %#x", flags)
+	}
+
+	d := &U6Data{}
+	p := &d.Profile
+	p.Element = r.readLetter(u6Elements, 2)
+	for _, v := range []*int{&p.Modal.C, &p.Modal.F, &p.Modal.M, &p.Cog.F, &p.Cog.C, &p.Cog.V, &p.Cog.S, &p.Cog.Cr} {
+		if *v = int(r.read(7)); *v > 100 {
+			r.fail(fmt.Errorf("percentage %d out of range", *v))
+		}
+	}
+	p.Int.PB = r.readLetter(u6Paces, 2)
+	p.Int.SM = r.readLetter(u6Structures, 2)
+	p.Int.TN = r.readLetter(u6Tones, 2)
+	d.Chip = r.readHex()
+	if flags&u6FlagEpoch != 0 {
+		if d.Epoch = int(r.read(16)); d.Epoch == 0 {
+			r.fail(fmt.Errorf("invalid salt epoch: 0"))
+		}
+	}
+	if flags&u6FlagLineage != 0 {
+		d.Lineage = r.readHex()
+	}
+	if flags&u6FlagFusion != 0 {
+		d.Chinese, d.Fusion = readU6Fusion(r)
+	}
+	if err := r.finish(); err != nil {
+		return nil, fmt.Errorf("invalid HCS-U6 payload: %w", err)
+	}
+	return d, nil
+}
+
+// readU6Fusion reads the Chinese and fusion block
+func readU6Fusion(r *bitReader) (*ChineseProfile, *FusionProfile) {
+	chinese := &ChineseProfile{ElementBalance: map[string]float64{}}
+	var day Pillar
+	for i, pillar := range []*string{&chinese.YearPillar, &chinese.MonthPillar, &chinese.DayPillar, &chinese.HourPillar} {
+		stem, branch := int(r.read(4)), int(r.read(4))
+		if stem >= len(HeavenlyStems) || branch >= len(EarthlyBranches) {
+			r.fail(fmt.Errorf("invalid pillar %d-%d", stem, branch))
+			return nil, nil
+		}
+		p := Pillar{Stem: HeavenlyStems[stem].Name, Branch: EarthlyBranches[branch].Name, StemIndex: stem, BranchIndex: branch}
+		if i == 2 {
+			day = p
+		}
+		*pillar = p.PillarToString()
+	}
+	chinese.DayMaster = GetDayMaster(day)
+	chinese.YinYangBalance = r.readMilli()
+	for _, element := range chineseElements {
+		chinese.ElementBalance[element] = r.readMilli()
+	}
+	chinese.DayMasterStrength = r.readMilli()
+
+	fusion := &FusionProfile{ElementSignature: map[string]float64{}}
+	fusion.FusionID = r.readLetter(u6FusionLetters, 5) + r.readLetter(u6FusionDigits, 4)
+	for _, element := range chineseElements {
+		fusion.ElementSignature[element] = r.readMilli()
+	}
+	cog := &fusion.CognitiveFusion
+	tempo := &fusion.TempoSignals
+	for _, v := range []*float64{&cog.Analytical, &cog.Creative, &cog.Grounded, &cog.Adaptive, &cog.Expressive,
+		&tempo.Pace, &tempo.Variability, &tempo.Intensity} {
+		*v = r.readMilli()
+	}
+	if rhythm := int(r.read(2)); rhythm < len(u6Rhythms) {
+		tempo.Rhythm = u6Rhythms[rhythm]
+	} else {
+		r.fail(fmt.Errorf("invalid rhythm %d", rhythm))
+	}
+	fusion.UnifiedBalance = r.readMilli()
+	fusion.HarmonicResonance = r.readMilli()
+	return chinese, fusion
+}
+
+// ValidateU6Format reports whether code is a well-formed HCS-U6 code
+func ValidateU6Format(code string) bool {
+	_, err := DecodeU6(code)
+	return err == nil
+}
+
+// parsePillar parses a pillar written by PillarToString, such as Jia-Zi
+func parsePillar(s string) (Pillar, error) {
+	stem, branch, _ := strings.Cut(s, "-")
+	for i, st := range HeavenlyStems {
+		if st.Name != stem {
+			continue
+		}
+		for j, br := range EarthlyBranches {
+			if br.Name == branch {
+				return Pillar{Stem: stem, Branch: branch, StemIndex: i, BranchIndex: j}, nil
+			}
+		}
+	}
+	return Pillar{}, fmt.Errorf("invalid pillar: %q", s)
+}
+
+// indexOf returns the index of s in list, or -1
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// encodeBase62 writes data as a base62 number. The first byte of a U6
+// payload holds the nonzero version, so no leading zero bytes are lost.
+func encodeBase62(data []byte) string {
+	n := new(big.Int).SetBytes(data)
+	if n.Sign() == 0 {
+		return "0"
+	}
+	base := big.NewInt(62)
+	var out []byte
+	mod := new(big.Int)
+	for n.Sign() > 0 {
+		n.DivMod(n, base, mod)
+		out = append(out, base62Alphabet[mod.Int64()])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// decodeBase62 is the inverse of encodeBase62
+func decodeBase62(s string) ([]byte, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty base62 string")
+	}
+	n := new(big.Int)
+	base := big.NewInt(62)
+	for i := 0; i < len(s); i++ {
+		digit := strings.IndexByte(base62Alphabet, s[i])
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base62 character %q", s[i])
+		}
+		n.Mul(n, base).Add(n, big.NewInt(int64(digit)))
+	}
+	return n.Bytes(), nil
+}
+
+// bitWriter appends fields to a bit string, most significant bit first
+type bitWriter struct {
+	buf  []byte
+	bits uint
+}
+
+func (w *bitWriter) write(value uint64, width uint) {
+	for i := int(width) - 1; i >= 0; i-- {
+		if w.bits%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		if value>>uint(i)&1 == 1 {
+			w.buf[len(w.buf)-1] |= 1 << (7 - w.bits%8)
+		}
+		w.bits++
+	}
+}
+
+// writeLetter writes the index of value in letters
+func (w *bitWriter) writeLetter(letters, value string, width uint) error {
+	i := strings.Index(letters, value)
+	if len(value) != 1 || i < 0 {
+		return fmt.Errorf("invalid value %q, expected one of %q", value, letters)
+	}
+	w.write(uint64(i), width)
+	return nil
+}
+
+// writeHex writes a 12-digit hex value such as a CHIP
+func (w *bitWriter) writeHex(value string) {
+	var v uint64
+	fmt.Sscanf(value, "%x", &v)
+	w.write(v, 48)
+}
+
+// writeMilli writes a 0-1 value in thousandths, clamped like QuantizeUnit
+func (w *bitWriter) writeMilli(value float64) {
+	w.write(uint64(math.Round(clampUnit(value)*1000)), u6MilliWidth)
+}
+
+func (w *bitWriter) bytes() []byte {
+	return w.buf
+}
+
+// bitReader reads the fields of a bitWriter. The first error sticks and
+// later reads return zero, so callers check it once with finish.
+type bitReader struct {
+	data []byte
+	bits uint
+	err  error
+}
+
+func (r *bitReader) read(width uint) uint64 {
+	if r.err != nil {
+		return 0
+	}
+	if r.bits+width > uint(len(r.data))*8 {
+		r.fail(fmt.Errorf("payload too short"))
+		return 0
+	}
+	var v uint64
+	for i := uint(0); i < width; i++ {
+		v = v<<1 | uint64(r.data[r.bits/8]>>(7-r.bits%8)&1)
+		r.bits++
+	}
+	return v
+}
+
+func (r *bitReader) readLetter(letters string, width uint) string {
+	i := r.read(width)
+	if i >= uint64(len(letters)) {
+		r.fail(fmt.Errorf("invalid field value %d", i))
+		return ""
+	}
+	return letters[i : i+1]
+}
+
+func (r *bitReader) readHex() string {
+	return fmt.Sprintf("%012x", r.read(48))
+}
+
+func (r *bitReader) readMilli() float64 {
+	v := r.read(u6MilliWidth)
+	if v > 1000 {
+		r.fail(fmt.Errorf("value %d out of range", v))
+	}
+	return float64(v) / 1000
+}
+
+func (r *bitReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// finish returns the first error, or an error if anything but the zero
+// padding of the last byte is left
+func (r *bitReader) finish() error {
+	if r.err != nil {
+		return r.err
+	}
+	if uint(len(r.data))*8-r.bits >= 8 || r.read(uint(len(r.data))*8-r.bits) != 0 {
+		return fmt.Errorf("trailing data")
+	}
+	return nil
+}
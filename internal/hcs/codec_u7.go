@@ -1,11 +1,68 @@
 package hcs
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Inline signature lengths in hex characters. Codes using the defaults keep
+// the plain "ALG:QS" segment; other lengths are declared as "ALG:QS.<qsig>.<b3>".
+const (
+	DefaultQSigInlineLength  = 24
+	DefaultB3InlineLength    = 32
+	MinSignatureInlineLength = 16 // 64 bits
+	MaxSignatureInlineLength = 64 // the full 256-bit digest
+)
+
+// SignatureLengths selects how many hex characters of each signature are
+// inlined in an HCS-U7 code. Zero values select the defaults.
+type SignatureLengths struct {
+	QSig int `json:"qsig,omitempty"`
+	B3   int `json:"b3,omitempty"`
+}
+
+// Resolve applies the defaults and enforces the allowed range
+func (l SignatureLengths) Resolve() (SignatureLengths, error) {
+	if l.QSig == 0 {
+		l.QSig = DefaultQSigInlineLength
+	}
+	if l.B3 == 0 {
+		l.B3 = DefaultB3InlineLength
+	}
+	for _, sig := range []struct {
+		name   string
+		length int
+	}{{"QSIG", l.QSig}, {"B3", l.B3}} {
+		if sig.length < MinSignatureInlineLength || sig.length > MaxSignatureInlineLength {
+			return l, fmt.Errorf("%s inline length must be between %d and %d hex characters, got %d",
+				sig.name, MinSignatureInlineLength, MaxSignatureInlineLength, sig.length)
+		}
+	}
+	return l, nil
+}
+
+// algSegment declares the signature algorithm and, when not the defaults, the inline lengths
+func (l SignatureLengths) algSegment() string {
+	if l.QSig == DefaultQSigInlineLength && l.B3 == DefaultB3InlineLength {
+		return "ALG:QS"
+	}
+	return fmt.Sprintf("ALG:QS.%d.%d", l.QSig, l.B3)
+}
 
 // FormatHCSU7 assembles the HCS-U7 code from the normalized profile and
 // cryptographic signatures. It reuses the same segment semantics as U3/U5
 // (E, MOD, COG, INT) while adding quantum-style signature fields.
 func FormatHCSU7(profile *NormalizedProfile, qsigHex, b3Hex string) (string, error) {
+	return FormatHCSU7WithLengths(profile, qsigHex, b3Hex, SignatureLengths{})
+}
+
+// FormatHCSU7WithLengths is FormatHCSU7 with configurable inline signature lengths
+func FormatHCSU7WithLengths(profile *NormalizedProfile, qsigHex, b3Hex string, lengths SignatureLengths) (string, error) {
+	lengths, err := lengths.Resolve()
+	if err != nil {
+		return "", err
+	}
 	if profile == nil {
 		return "", fmt.Errorf("normalized profile cannot be nil")
 	}
@@ -28,17 +85,18 @@ func FormatHCSU7(profile *NormalizedProfile, qsigHex, b3Hex string) (string, err
 
 	// Truncate signatures for the inline code while keeping full values in JSON metadata.
 	qsigInline := qsigHex
-	if len(qsigInline) > 24 {
-		qsigInline = qsigInline[:24]
+	if len(qsigInline) > lengths.QSig {
+		qsigInline = qsigInline[:lengths.QSig]
 	}
 
 	b3Inline := b3Hex
-	if len(b3Inline) > 32 {
-		b3Inline = b3Inline[:32]
+	if len(b3Inline) > lengths.B3 {
+		b3Inline = b3Inline[:lengths.B3]
 	}
 
 	return fmt.Sprintf(
-		"HCS-U7|V:7.0|ALG:QS|%s|%s|%s|%s|QSIG:%s|B3:%s",
+		"HCS-U7|V:7.0|%s|%s|%s|%s|%s|QSIG:%s|B3:%s",
+		lengths.algSegment(),
 		elemSegment,
 		modalSegment,
 		cogSegment,
@@ -47,3 +105,107 @@ func FormatHCSU7(profile *NormalizedProfile, qsigHex, b3Hex string) (string, err
 		b3Inline,
 	), nil
 }
+
+// ParseSignatureLengths returns the inline signature lengths declared by the
+// ALG segment of an HCS-U7 code, checking that the inlined signatures match
+// them. Verifiers compare exactly this many leading hex characters.
+func ParseSignatureLengths(code string) (SignatureLengths, error) {
+	if !u7Pattern.MatchString(code) {
+		return SignatureLengths{}, fmt.Errorf("invalid HCS-U7 format")
+	}
+
+	var alg, qsig, b3 string
+	for _, segment := range strings.Split(code, "|") {
+		name, value, _ := strings.Cut(segment, ":")
+		switch name {
+		case "ALG":
+			alg, _, _ = strings.Cut(value, "+") // drop the post-quantum algorithm
+			alg = strings.Replace(alg, hkdfALGMarker, "", 1)
+			alg = strings.Replace(alg, sha3ALGMarker, "", 1)
+		case "QSIG":
+			qsig = value
+		case "B3":
+			b3 = value
+		}
+	}
+
+	lengths := SignatureLengths{QSig: DefaultQSigInlineLength, B3: DefaultB3InlineLength}
+	if declared, ok := strings.CutPrefix(alg, "QS."); ok {
+		q, b, _ := strings.Cut(declared, ".")
+		lengths.QSig, _ = strconv.Atoi(q)
+		lengths.B3, _ = strconv.Atoi(b)
+		if _, err := lengths.Resolve(); err != nil {
+			return SignatureLengths{}, err
+		}
+	}
+
+	if len(qsig) != lengths.QSig || len(b3) != lengths.B3 {
+		return SignatureLengths{}, fmt.Errorf("inline signatures do not match the declared lengths %d/%d", lengths.QSig, lengths.B3)
+	}
+	return lengths, nil
+}
+
+// hkdfALGMarker follows "QS" in the ALG segment of codes signed with HKDF-derived keys
+const hkdfALGMarker = "-HKDF"
+
+// rewriteALG replaces the value of the ALG segment of an HCS-U7 code
+func rewriteALG(code string, rewrite func(alg string) string) string {
+	segments := strings.Split(code, "|")
+	for i, segment := range segments {
+		if alg, ok := strings.CutPrefix(segment, "ALG:"); ok {
+			segments[i] = "ALG:" + rewrite(alg)
+		}
+	}
+	return strings.Join(segments, "|")
+}
+
+// declareKeyDerivation marks codes whose signatures use a non-legacy key derivation
+func declareKeyDerivation(code string, derivation KeyDerivation) string {
+	if derivation != KeyDerivationHKDF {
+		return code
+	}
+	return rewriteALG(code, func(alg string) string {
+		return "QS" + hkdfALGMarker + strings.TrimPrefix(alg, "QS")
+	})
+}
+
+// ParseKeyDerivation returns the key derivation declared by the ALG segment of
+// an HCS-U7 code. Codes without a declaration use KeyDerivationLegacy.
+func ParseKeyDerivation(code string) (KeyDerivation, error) {
+	if !u7Pattern.MatchString(code) {
+		return "", fmt.Errorf("invalid HCS-U7 format")
+	}
+	if strings.Contains(code, "|ALG:QS"+hkdfALGMarker) {
+		return KeyDerivationHKDF, nil
+	}
+	return KeyDerivationLegacy, nil
+}
+
+// declareSecondaryDigest marks codes whose secondary digest is not BLAKE3,
+// after any key derivation marker
+func declareSecondaryDigest(code string, digest SecondaryDigest) string {
+	if digest != DigestSHA3 {
+		return code
+	}
+	return rewriteALG(code, func(alg string) string {
+		rest := strings.TrimPrefix(alg, "QS")
+		if after, ok := strings.CutPrefix(rest, hkdfALGMarker); ok {
+			return "QS" + hkdfALGMarker + sha3ALGMarker + after
+		}
+		return "QS" + sha3ALGMarker + rest
+	})
+}
+
+// ParseSecondaryDigest returns the secondary digest declared by the ALG
+// segment of an HCS-U7 code. Codes without a declaration use DigestBLAKE3.
+func ParseSecondaryDigest(code string) (SecondaryDigest, error) {
+	if !u7Pattern.MatchString(code) {
+		return "", fmt.Errorf("invalid HCS-U7 format")
+	}
+	for _, segment := range strings.Split(code, "|") {
+		if alg, ok := strings.CutPrefix(segment, "ALG:"); ok && strings.Contains(alg, sha3ALGMarker) {
+			return DigestSHA3, nil
+		}
+	}
+	return DigestBLAKE3, nil
+}
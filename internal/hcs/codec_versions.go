@@ -0,0 +1,58 @@
+package hcs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CurrentU7Version is the HCS-U7 format version emitted by default
+const CurrentU7Version = "7.0"
+
+// u7Formatter renders an HCS-U7 code in one specific format version
+type u7Formatter func(profile *NormalizedProfile, qsigHex, b3Hex string, lengths SignatureLengths) (string, error)
+
+// u7Formats maps HCS-U7 format versions to their formatter. Old versions stay
+// registered so they can be emitted side by side during codec upgrades.
+var u7Formats = map[string]u7Formatter{
+	"7.0": FormatHCSU7WithLengths,
+}
+
+// VersionedCode is a code emitted in a non-primary format version
+type VersionedCode struct {
+	Level   string `json:"level"`   // e.g. "U7"
+	Version string `json:"version"` // e.g. "7.0"
+	Code    string `json:"code"`
+}
+
+// SupportedU7Versions returns the registered HCS-U7 format versions in sorted order
+func SupportedU7Versions() []string {
+	versions := make([]string, 0, len(u7Formats))
+	for v := range u7Formats {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// U7Version returns the format version declared by the V segment of an
+// HCS-U7 code, or "" when it has none
+func U7Version(code string) string {
+	parts := strings.SplitN(code, "|", 3)
+	if len(parts) < 2 || parts[0] != "HCS-U7" {
+		return ""
+	}
+	return strings.TrimPrefix(parts[1], "V:")
+}
+
+// formatU7Version renders an HCS-U7 code in the requested format version
+func formatU7Version(version string, profile *NormalizedProfile, qsigHex, b3Hex string, lengths SignatureLengths) (string, error) {
+	if version == "" {
+		version = CurrentU7Version
+	}
+	format, ok := u7Formats[version]
+	if !ok {
+		return "", fmt.Errorf("unsupported HCS-U7 version: %s", version)
+	}
+	return format(profile, qsigHex, b3Hex, lengths)
+}
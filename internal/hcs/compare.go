@@ -0,0 +1,214 @@
+package hcs
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+)
+
+// Compatibility holds the pairwise compatibility dimensions of two profiles.
+// All values are in the 0-1 range.
+type Compatibility struct {
+	ElementSynergy           float64 `json:"elementSynergy"`
+	TempoAlignment           float64 `json:"tempoAlignment"`
+	CognitiveComplementarity float64 `json:"cognitiveComplementarity"`
+	Resonance                float64 `json:"resonance"` // weighted overall score
+}
+
+// CompatibilityWeights sets the relative importance of each dimension in a
+// weighted score. Weights need not sum to 1; they are normalized on use.
+type CompatibilityWeights struct {
+	Element   float64 `json:"element"`
+	Tempo     float64 `json:"tempo"`
+	Cognitive float64 `json:"cognitive"`
+}
+
+// DefaultCompatibilityWeights returns the weights used for the resonance score
+func DefaultCompatibilityWeights() CompatibilityWeights {
+	return CompatibilityWeights{Element: 0.4, Tempo: 0.3, Cognitive: 0.3}
+}
+
+// Validate rejects negative weights and an all-zero weighting
+func (w CompatibilityWeights) Validate() error {
+	if w.Element < 0 || w.Tempo < 0 || w.Cognitive < 0 {
+		return fmt.Errorf("compatibility weights must not be negative")
+	}
+	if w.Element+w.Tempo+w.Cognitive == 0 {
+		return fmt.Errorf("at least one compatibility weight must be positive")
+	}
+	return nil
+}
+
+// Weighted combines the dimensions of c using w. Invalid weights fall back to the
+// default resonance weighting.
+func (c Compatibility) Weighted(w CompatibilityWeights) float64 {
+	if w.Validate() != nil {
+		w = DefaultCompatibilityWeights()
+	}
+	total := w.Element + w.Tempo + w.Cognitive
+	score := (c.ElementSynergy*w.Element + c.TempoAlignment*w.Tempo + c.CognitiveComplementarity*w.Cognitive) / total
+	return round4(score)
+}
+
+// FieldChange is a normalized profile value that differs between two profiles
+type FieldChange struct {
+	Field    string `json:"field"`
+	Previous string `json:"previous"`
+	Current  string `json:"current"`
+	Delta    int    `json:"delta,omitempty"` // current - previous in percentage points, for numeric fields
+}
+
+// CompareNormalized lists the fields that differ between two normalized
+// profiles: the element, the percentages that moved beyond rounding (see
+// WithinRounding), then the interaction categories. It never returns nil.
+func CompareNormalized(prev, curr *NormalizedProfile) []FieldChange {
+	changes := []FieldChange{}
+	if prev.Element != curr.Element {
+		changes = append(changes, FieldChange{Field: "element", Previous: prev.Element, Current: curr.Element})
+	}
+	numeric := []struct {
+		name       string
+		prev, curr int
+	}{
+		{"modal.cardinal", prev.Modal.C, curr.Modal.C},
+		{"modal.fixed", prev.Modal.F, curr.Modal.F},
+		{"modal.mutable", prev.Modal.M, curr.Modal.M},
+		{"cognition.fluid", prev.Cog.F, curr.Cog.F},
+		{"cognition.crystallized", prev.Cog.C, curr.Cog.C},
+		{"cognition.verbal", prev.Cog.V, curr.Cog.V},
+		{"cognition.strategic", prev.Cog.S, curr.Cog.S},
+		{"cognition.creative", prev.Cog.Cr, curr.Cog.Cr},
+	}
+	for _, f := range numeric {
+		if !WithinRounding(f.prev, f.curr) {
+			changes = append(changes, FieldChange{
+				Field:    f.name,
+				Previous: fmt.Sprintf("%d", f.prev),
+				Current:  fmt.Sprintf("%d", f.curr),
+				Delta:    f.curr - f.prev,
+			})
+		}
+	}
+	categorical := []struct {
+		name       string
+		prev, curr string
+	}{
+		{"interaction.pace", prev.Int.PB, curr.Int.PB},
+		{"interaction.structure", prev.Int.SM, curr.Int.SM},
+		{"interaction.tone", prev.Int.TN, curr.Int.TN},
+	}
+	for _, f := range categorical {
+		if f.prev != f.curr {
+			changes = append(changes, FieldChange{Field: f.name, Previous: f.prev, Current: f.curr})
+		}
+	}
+	return changes
+}
+
+// ComputeCompatibility scores two normalized profiles against each other.
+// The result is symmetric: ComputeCompatibility(a, b) == ComputeCompatibility(b, a).
+func ComputeCompatibility(a, b *NormalizedProfile) Compatibility {
+	element := elementSynergy(a.Element, b.Element)
+	tempo := tempoAlignment(a.Int, b.Int)
+	cognitive := cognitiveComplementarity(a.Cog, b.Cog)
+
+	c := Compatibility{
+		ElementSynergy:           round4(element),
+		TempoAlignment:           round4(tempo),
+		CognitiveComplementarity: round4(cognitive),
+	}
+	c.Resonance = c.Weighted(DefaultCompatibilityWeights())
+	return c
+}
+
+// CompareProfiles validates two input profiles and scores their
+// compatibility. Codes are compared with ComputeCompatibility on the profiles
+// of NormalizedFromCode.
+func CompareProfiles(a, b *InputProfile) (Compatibility, error) {
+	for i, in := range []*InputProfile{a, b} {
+		if in == nil {
+			return Compatibility{}, errcode.Errorf(errcode.InvalidRequest, "profile %d cannot be nil", i+1)
+		}
+		if err := ValidateInput(in); err != nil {
+			return Compatibility{}, fmt.Errorf("invalid profile %d: %w", i+1, err)
+		}
+	}
+	return ComputeCompatibility(NormalizeProfile(a), NormalizeProfile(b)), nil
+}
+
+// CompatibilityMatrix returns the symmetric matrix of overall resonance scores
+// for every pair of profiles. The diagonal is 1.
+func CompatibilityMatrix(profiles []*NormalizedProfile) [][]float64 {
+	n := len(profiles)
+	matrix := make([][]float64, n)
+	for i := range matrix {
+		matrix[i] = make([]float64, n)
+		matrix[i][i] = 1
+	}
+
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			score := ComputeCompatibility(profiles[i], profiles[j]).Resonance
+			matrix[i][j] = score
+			matrix[j][i] = score
+		}
+	}
+	return matrix
+}
+
+// elementSynergy scores Western element letters: complementary pairs
+// (Fire/Air, Earth/Water) score highest, opposing pairs lowest
+func elementSynergy(a, b string) float64 {
+	if a == b {
+		return 0.8
+	}
+	pair := a + b
+	switch pair {
+	case "FA", "AF", "EW", "WE":
+		return 1.0
+	case "FW", "WF", "AE", "EA":
+		return 0.3
+	default:
+		return 0.5
+	}
+}
+
+// tempoAlignment compares pace (70%) and structure (30%) preferences
+func tempoAlignment(a, b NormalizedInteraction) float64 {
+	pace := 0.2
+	switch {
+	case a.PB == b.PB:
+		pace = 1.0
+	case a.PB == "B" || b.PB == "B":
+		pace = 0.6
+	}
+
+	levels := map[string]int{"L": 0, "M": 1, "H": 2}
+	diff := levels[a.SM] - levels[b.SM]
+	if diff < 0 {
+		diff = -diff
+	}
+	structure := 1.0 - float64(diff)*0.5
+
+	return pace*0.7 + structure*0.3
+}
+
+// cognitiveComplementarity measures the combined coverage of both profiles:
+// the mean of the stronger value on each cognitive dimension
+func cognitiveComplementarity(a, b NormalizedCognition) float64 {
+	pairs := [][2]int{{a.F, b.F}, {a.C, b.C}, {a.V, b.V}, {a.S, b.S}, {a.Cr, b.Cr}}
+	total := 0
+	for _, p := range pairs {
+		if p[0] > p[1] {
+			total += p[0]
+		} else {
+			total += p[1]
+		}
+	}
+	return clampValue(float64(total) / float64(len(pairs)) / 100)
+}
+
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
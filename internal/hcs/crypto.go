@@ -1,7 +1,6 @@
 package hcs
 
 import (
-	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -65,23 +64,38 @@ func NormalizeProfile(in *InputProfile) *NormalizedProfile {
 
 // GenerateCHIP computes the CHIP-96 (12 hex chars) from salt and normalized profile
 func GenerateCHIP(salt []byte, normalized *NormalizedProfile) (string, error) {
-	// Create canonical JSON with fixed field order
-	canonicalJSON, err := json.Marshal(normalized)
+	_, digest, err := chipDigest(salt, normalized)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal normalized profile: %w", err)
+		return "", err
+	}
+
+	// Take first 12 hex characters (48 bits)
+	return digest[:12], nil
+}
+
+// chipDigest returns the canonical JSON of the normalized profile and the full
+// SHA256 hex digest of salt + canonical JSON, from which the CHIP is truncated
+func chipDigest(salt []byte, normalized *NormalizedProfile) ([]byte, string, error) {
+	canonicalJSON, err := canonicalNormalizedJSON(normalized)
+	if err != nil {
+		return nil, "", err
 	}
 
 	// Concatenate salt + canonical JSON
 	data := append(salt, canonicalJSON...)
 
 	// Compute SHA256
-	hash := sha256.Sum256(data)
-
-	// Take first 12 hex characters (48 bits)
-	hexHash := hex.EncodeToString(hash[:])
-	chip := hexHash[:12]
+	return canonicalJSON, hex.EncodeToString(hashOf(HashSHA256, data)), nil
+}
 
-	return chip, nil
+// canonicalNormalizedJSON is the CHIP input: the normalized profile as JSON
+// with fixed field order
+func canonicalNormalizedJSON(normalized *NormalizedProfile) ([]byte, error) {
+	canonicalJSON, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal normalized profile: %w", err)
+	}
+	return canonicalJSON, nil
 }
 
 // clampAndRound clamps a float between 0 and 1, then converts to percentage
@@ -97,60 +111,28 @@ func clampAndRound(value float64) int {
 
 // mapElementToLetter maps element name to single letter
 func mapElementToLetter(element string) string {
-	switch element {
-	case "Earth":
-		return "E"
-	case "Air":
-		return "A"
-	case "Water":
-		return "W"
-	case "Fire":
-		return "F"
-	default:
-		return "E" // Default to Earth if unknown
-	}
+	return letterFor(ElementLetters, element, DefaultElementLetter) // Default to Earth if unknown
 }
 
 // mapPaceToLetter maps pace preference to single letter
 func mapPaceToLetter(pace string) string {
-	switch pace {
-	case "balanced":
-		return "B"
-	case "fast":
-		return "F"
-	case "slow":
-		return "S"
-	default:
-		return "B"
-	}
+	return letterFor(PaceLetters, pace, DefaultPaceLetter)
 }
 
 // mapStructureToLetter maps structure preference to single letter
 func mapStructureToLetter(structure string) string {
-	switch structure {
-	case "low":
-		return "L"
-	case "medium":
-		return "M"
-	case "high":
-		return "H"
-	default:
-		return "M"
-	}
+	return letterFor(StructureLetters, structure, DefaultStructureLetter)
 }
 
 // mapToneToLetter maps tone preference to single letter
 func mapToneToLetter(tone string) string {
-	switch tone {
-	case "warm":
-		return "W"
-	case "neutral":
-		return "N"
-	case "sharp":
-		return "S"
-	case "precise":
-		return "P"
-	default:
-		return "N"
+	return letterFor(ToneLetters, tone, DefaultToneLetter)
+}
+
+// letterFor looks value up in table, falling back to def
+func letterFor(table map[string]string, value, def string) string {
+	if letter, ok := table[value]; ok {
+		return letter
 	}
+	return def
 }
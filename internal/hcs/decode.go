@@ -0,0 +1,269 @@
+package hcs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// u7Pattern mirrors the segment grammar produced by FormatHCSU7
+var u7Pattern = regexp.MustCompile(U7Grammar)
+
+// NormalizedFromCode recovers the normalized profile carried by an HCS-U3,
+// HCS-U4, HCS-U6 or HCS-U7 code. HCS-U5 is lossy and cannot be converted.
+func NormalizedFromCode(code string) (*NormalizedProfile, error) {
+	switch {
+	case strings.HasPrefix(code, "HCS-U3|"):
+		c, err := ParseU3(code)
+		if err != nil {
+			return nil, err
+		}
+		return normalizedFromFields(c["element"],
+			[]string{c["modal_cardinal"], c["modal_fixed"], c["modal_mutable"]},
+			[]string{c["cog_fluid"], c["cog_crystallized"], c["cog_verbal"], c["cog_strategic"], c["cog_creative"]},
+			c["int_pace"], c["int_structure"], c["int_tone"])
+	case strings.HasPrefix(code, "HCS-U4|"):
+		profile, _, err := DecodeU4(code)
+		if err != nil {
+			return nil, err
+		}
+		if profile == nil {
+			return nil, fmt.Errorf("HCS-U4 code carries no profile")
+		}
+		return profile, nil
+	case strings.HasPrefix(code, u6Prefix):
+		d, err := DecodeU6(code)
+		if err != nil {
+			return nil, err
+		}
+		return &d.Profile, nil
+	case strings.HasPrefix(code, "HCS-U7|"):
+		m := u7Pattern.FindStringSubmatch(code)
+		if m == nil {
+			return nil, fmt.Errorf("invalid HCS-U7 format")
+		}
+		return normalizedFromFields(m[1], m[2:5], m[5:10], m[10], m[11], m[12])
+	case strings.HasPrefix(code, "HCS-U5|"):
+		return nil, fmt.Errorf("HCS-U5 codes are lossy and do not carry a full profile")
+	default:
+		return nil, fmt.Errorf("unrecognized HCS code level")
+	}
+}
+
+// normalizedFromFields assembles a NormalizedProfile from already-validated segment captures
+func normalizedFromFields(element string, modal, cog []string, pace, structure, tone string) (*NormalizedProfile, error) {
+	nums := make([]int, 0, len(modal)+len(cog))
+	for _, s := range append(append([]string{}, modal...), cog...) {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid numeric segment %q: %w", s, err)
+		}
+		nums = append(nums, v)
+	}
+
+	return &NormalizedProfile{
+		Element: element,
+		Modal:   NormalizedModal{C: nums[0], F: nums[1], M: nums[2]},
+		Cog:     NormalizedCognition{F: nums[3], C: nums[4], V: nums[5], S: nums[6], Cr: nums[7]},
+		Int:     NormalizedInteraction{PB: pace, SM: structure, TN: tone},
+	}, nil
+}
+
+// DecodedSignatures describes the inline signatures of an HCS-U7 code
+type DecodedSignatures struct {
+	QSig            string           `json:"qsig"`
+	B3              string           `json:"b3"`
+	Lengths         SignatureLengths `json:"lengths"`
+	KeyDerivation   KeyDerivation    `json:"keyDerivation"`
+	SecondaryDigest SecondaryDigest  `json:"secondaryDigest"`
+	KeyID           string           `json:"keyId"`                 // LegacyKeyID without a KID segment
+	PostQuantum     string           `json:"postQuantum,omitempty"` // e.g. MLDSA65
+	PQKeyID         string           `json:"pqKeyId,omitempty"`
+}
+
+// DecodedFusion holds the hashed segments of an HCS-U5 code
+type DecodedFusion struct {
+	ID      string `json:"id"`
+	Western string `json:"western"`
+	Chinese string `json:"chinese"`
+	Fusion  string `json:"fusion"`
+}
+
+// DecodedCode is the content of an HCS code of any level. Components a level
+// does not carry are left empty: U5 codes have no profile, U7 codes no CHIP.
+type DecodedCode struct {
+	Level       string                 `json:"level"`             // U3, U4, U5, U6 or U7
+	Version     string                 `json:"version,omitempty"` // U7 format version
+	Element     string                 `json:"element,omitempty"`
+	Modal       *NormalizedModal       `json:"modal,omitempty"`
+	Cognition   *NormalizedCognition   `json:"cognition,omitempty"`
+	Interaction *NormalizedInteraction `json:"interaction,omitempty"`
+	Chip        string                 `json:"chip,omitempty"`
+	Signatures  *DecodedSignatures     `json:"signatures,omitempty"`
+	Fusion      *DecodedFusion         `json:"fusion,omitempty"`
+	// Chinese and fusion profiles of U6 codes issued with birth info
+	ChineseProfile *ChineseProfile `json:"chineseProfile,omitempty"`
+	FusionProfile  *FusionProfile  `json:"fusionProfile,omitempty"`
+	// Approximate profile values unpacked from the segments of U5 codes
+	U5        *U5Components `json:"u5,omitempty"`
+	SaltEpoch int           `json:"saltEpoch"`
+	Lineage   string        `json:"lineage,omitempty"`
+}
+
+// Decode detects the level of an HCS code and returns its components. Only
+// codes in canonical form are accepted, so Encode of the result always
+// reproduces code exactly.
+func Decode(code string) (*DecodedCode, error) {
+	var d *DecodedCode
+	switch {
+	case strings.HasPrefix(code, "HCS-U5|"):
+		c, err := DecodeU5(code)
+		if err != nil {
+			return nil, err
+		}
+		d = &DecodedCode{Level: "U5", Chip: c.Chip, Fusion: &DecodedFusion{
+			ID: c.FusionID, Western: c.Western.Hex, Chinese: c.Chinese.Hex, Fusion: c.Fusion.Hex,
+		}, U5: c}
+	case strings.HasPrefix(code, u6Prefix):
+		c, err := DecodeU6(code)
+		if err != nil {
+			return nil, err
+		}
+		d = &DecodedCode{
+			Level:          "U6",
+			Element:        c.Profile.Element,
+			Modal:          &c.Profile.Modal,
+			Cognition:      &c.Profile.Cog,
+			Interaction:    &c.Profile.Int,
+			Chip:           c.Chip,
+			ChineseProfile: c.Chinese,
+			FusionProfile:  c.Fusion,
+		}
+	case strings.HasPrefix(code, "HCS-U3|"), strings.HasPrefix(code, "HCS-U4|"), strings.HasPrefix(code, "HCS-U7|"):
+		profile, err := NormalizedFromCode(code)
+		if err != nil {
+			return nil, err
+		}
+		d = &DecodedCode{
+			Level:       code[4:6],
+			Element:     profile.Element,
+			Modal:       &profile.Modal,
+			Cognition:   &profile.Cog,
+			Interaction: &profile.Int,
+		}
+		if d.Level == "U7" {
+			d.Version = U7Version(code)
+			if d.Signatures, err = decodeSignatures(code); err != nil {
+				return nil, err
+			}
+		} else if d.Chip, err = ChipFromCode(code); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unrecognized HCS code level")
+	}
+
+	epoch, err := ParseSaltEpoch(code)
+	if err != nil {
+		return nil, err
+	}
+	lineage, err := ParseLineage(code)
+	if err != nil {
+		return nil, err
+	}
+	d.SaltEpoch, d.Lineage = epoch, lineage
+
+	// Codes the grammar accepts in more than one spelling, such as c050 for
+	// c50 in U7, do not survive re-encoding
+	if encoded, err := Encode(d); err != nil || encoded != code {
+		return nil, fmt.Errorf("HCS-%s code is not in canonical form", d.Level)
+	}
+	return d, nil
+}
+
+// Encode assembles the HCS code of decoded components, the inverse of Decode
+func Encode(d *DecodedCode) (string, error) {
+	var profile *NormalizedProfile
+	if d.Modal != nil && d.Cognition != nil && d.Interaction != nil {
+		profile = &NormalizedProfile{Element: d.Element, Modal: *d.Modal, Cog: *d.Cognition, Int: *d.Interaction}
+	}
+	if profile == nil && d.Level != "U5" {
+		return "", fmt.Errorf("HCS-%s code needs a profile", d.Level)
+	}
+
+	var code string
+	switch d.Level {
+	case "U3":
+		code = FormatHCSU3(profile, d.Chip)
+	case "U4":
+		return encodeU4(profile, d.Chip, d.SaltEpoch, d.Lineage)
+	case "U5":
+		if d.Fusion == nil {
+			return "", fmt.Errorf("HCS-U5 code needs its fusion segments")
+		}
+		f := d.Fusion
+		code = fmt.Sprintf("HCS-U5|%s|W:%s|C:%s|F:%s|CHIP:%s", f.ID, f.Western, f.Chinese, f.Fusion, d.Chip)
+	case "U6":
+		var combined *CombinedProfile
+		if d.ChineseProfile != nil && d.FusionProfile != nil {
+			combined = &CombinedProfile{Chinese: *d.ChineseProfile, Fusion: *d.FusionProfile}
+		}
+		return encodeU6(profile, d.Chip, combined, d.SaltEpoch, d.Lineage)
+	case "U7":
+		s := d.Signatures
+		if s == nil {
+			return "", fmt.Errorf("HCS-U7 code needs its signatures")
+		}
+		var err error
+		if code, err = formatU7Version(d.Version, profile, s.QSig, s.B3, s.Lengths); err != nil {
+			return "", err
+		}
+		code = withKeyID(declareSecondaryDigest(declareKeyDerivation(code, s.KeyDerivation), s.SecondaryDigest), s.KeyID)
+		code = withLineage(withSaltEpoch(code, d.SaltEpoch), d.Lineage)
+		if s.PostQuantum != "" || s.PQKeyID != "" {
+			code = attachPQSegment(code, PQPublicKey{Algorithm: s.PostQuantum, KeyID: s.PQKeyID})
+		}
+		return code, nil
+	default:
+		return "", fmt.Errorf("unrecognized HCS code level %q", d.Level)
+	}
+	return withLineage(withSaltEpoch(code, d.SaltEpoch), d.Lineage), nil
+}
+
+// decodeSignatures reads the ALG, QSIG, B3 and PQ segments of an HCS-U7 code
+func decodeSignatures(code string) (*DecodedSignatures, error) {
+	lengths, err := ParseSignatureLengths(code)
+	if err != nil {
+		return nil, err
+	}
+	derivation, err := ParseKeyDerivation(code)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := ParseSecondaryDigest(code)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID, err := ParseKeyID(code)
+	if err != nil {
+		return nil, err
+	}
+
+	sigs := &DecodedSignatures{Lengths: lengths, KeyDerivation: derivation, SecondaryDigest: digest, KeyID: keyID}
+	for _, segment := range strings.Split(code, "|") {
+		name, value, _ := strings.Cut(segment, ":")
+		switch name {
+		case "ALG":
+			_, sigs.PostQuantum, _ = strings.Cut(value, "+")
+		case "QSIG":
+			sigs.QSig = value
+		case "B3":
+			sigs.B3 = value
+		case "PQ":
+			sigs.PQKeyID = value
+		}
+	}
+	return sigs, nil
+}
@@ -0,0 +1,123 @@
+package hcs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DiffCodeSegments compares two codes segment by segment and returns the labels
+// of the segments that differ. Segments are labeled by their "NAME:" prefix, or
+// by position when they have none (e.g. the U5 fusion ID). Codes of different
+// levels differ entirely and yield the single label "LEVEL".
+func DiffCodeSegments(oldCode, newCode string) []string {
+	if oldCode == newCode {
+		return nil
+	}
+
+	oldParts := strings.Split(oldCode, "|")
+	newParts := strings.Split(newCode, "|")
+	if oldParts[0] != newParts[0] {
+		return []string{"LEVEL"}
+	}
+
+	n := len(oldParts)
+	if len(newParts) > n {
+		n = len(newParts)
+	}
+
+	var diffs []string
+	for i := 1; i < n; i++ {
+		var o, nw string
+		if i < len(oldParts) {
+			o = oldParts[i]
+		}
+		if i < len(newParts) {
+			nw = newParts[i]
+		}
+		if o != nw {
+			diffs = append(diffs, segmentLabel(o, nw, i))
+		}
+	}
+	return diffs
+}
+
+// segmentLabel names a segment by its prefix, falling back to its position
+func segmentLabel(a, b string, index int) string {
+	for _, s := range []string{a, b} {
+		if name, _, ok := strings.Cut(s, ":"); ok && name != "" {
+			return name
+		}
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+// percentSegments are the segments that encode percentages, such as
+// MOD:c31f23m46
+var percentSegments = map[string]bool{"MOD": true, "COG": true}
+
+// DiffCodeSegmentsTolerant is DiffCodeSegments, except that percentage
+// segments whose values all moved within rounding (see WithinRounding) are
+// not reported
+func DiffCodeSegmentsTolerant(oldCode, newCode string) []string {
+	oldParts := strings.Split(oldCode, "|")
+	newParts := strings.Split(newCode, "|")
+
+	var diffs []string
+	for _, label := range DiffCodeSegments(oldCode, newCode) {
+		if !percentSegments[label] || !segmentsWithinRounding(findSegment(oldParts, label), findSegment(newParts, label)) {
+			diffs = append(diffs, label)
+		}
+	}
+	return diffs
+}
+
+// findSegment returns the value of the segment with the given name
+func findSegment(parts []string, name string) string {
+	for _, p := range parts[1:] {
+		if value, ok := strings.CutPrefix(p, name+":"); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// segmentsWithinRounding reports whether two segment values have the same
+// letters and numbers that are each within rounding
+func segmentsWithinRounding(a, b string) bool {
+	lettersA, numbersA := splitSegment(a)
+	lettersB, numbersB := splitSegment(b)
+	if lettersA != lettersB || len(numbersA) != len(numbersB) || len(numbersA) == 0 {
+		return false
+	}
+	for i := range numbersA {
+		if !WithinRounding(numbersA[i], numbersB[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitSegment separates a value such as c31f23m46 into its letters, with
+// each number replaced by "#", and its numbers
+func splitSegment(value string) (string, []int) {
+	var letters strings.Builder
+	var numbers []int
+	for i := 0; i < len(value); {
+		j := i
+		for j < len(value) && unicode.IsDigit(rune(value[j])) {
+			j++
+		}
+		if j == i {
+			letters.WriteByte(value[i])
+			i++
+			continue
+		}
+		n, _ := strconv.Atoi(value[i:j])
+		numbers = append(numbers, n)
+		letters.WriteByte('#')
+		i = j
+	}
+	return letters.String(), numbers
+}
@@ -0,0 +1,86 @@
+package hcs
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// SecondaryDigest selects the algorithm of the secondary U7 signature (the B3
+// segment and OutputHCS.B3Sig)
+type SecondaryDigest string
+
+const (
+	// DigestBLAKE3 is the original secondary digest, and the default
+	DigestBLAKE3 SecondaryDigest = "blake3"
+	// DigestSHA3 is SHA3-512, for builds without the BLAKE3 dependency (build
+	// tag hcs_noblake3). Codes signed this way declare it as "ALG:QS-SHA3".
+	DigestSHA3 SecondaryDigest = "sha3-512"
+)
+
+// sha3ALGMarker follows "QS" (and "-HKDF") in the ALG segment of codes whose
+// secondary digest is SHA3-512
+const sha3ALGMarker = "-SHA3"
+
+// BLAKE3Available reports whether a BLAKE3 hash backend is registered: the
+// built-in one, unless built with the hcs_noblake3 tag, or a replacement
+func BLAKE3Available() bool {
+	_, err := LookupHash(HashBLAKE3)
+	return err == nil
+}
+
+// DefaultSecondaryDigest returns DigestBLAKE3, or DigestSHA3 in builds without
+// BLAKE3 and in FIPS builds
+func DefaultSecondaryDigest() SecondaryDigest {
+	if BLAKE3Available() {
+		return DigestBLAKE3
+	}
+	return DigestSHA3
+}
+
+// ResolveSecondaryDigest maps an empty digest to DefaultSecondaryDigest and
+// rejects unknown ones and BLAKE3 in builds without it
+func ResolveSecondaryDigest(digest SecondaryDigest) (SecondaryDigest, error) {
+	switch digest {
+	case "":
+		return DefaultSecondaryDigest(), nil
+	case DigestBLAKE3:
+		if _, err := LookupHash(HashBLAKE3); err != nil {
+			return "", err
+		}
+		return digest, nil
+	case DigestSHA3:
+		return digest, nil
+	}
+	return "", fmt.Errorf("unknown secondary digest: %s", digest)
+}
+
+// errNoBLAKE3 is returned for BLAKE3 digests in builds tagged hcs_noblake3
+var errNoBLAKE3 = fmt.Errorf("BLAKE3 is not available in this build (hcs_noblake3); use the %s secondary digest", DigestSHA3)
+
+// secondaryDigest computes the secondary signature of canonical with the hash
+// backend of the digest. Legacy key derivation prefixes the shared key to the
+// data; HKDF derivation uses the keyed mode with its own key. An empty digest
+// is BLAKE3.
+func secondaryDigest(digest SecondaryDigest, derivation KeyDerivation, key, canonical []byte) (string, error) {
+	if digest == "" {
+		digest = DigestBLAKE3
+	}
+	if digest != DigestBLAKE3 && digest != DigestSHA3 {
+		return "", fmt.Errorf("unknown secondary digest: %s", digest)
+	}
+	if derivation == KeyDerivationHKDF {
+		sum, err := macOf(string(digest), key, canonical)
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(sum), nil
+	}
+	backend, err := LookupHash(string(digest))
+	if err != nil {
+		return "", err
+	}
+	h := backend.New()
+	h.Write(key)
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
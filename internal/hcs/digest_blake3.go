@@ -0,0 +1,22 @@
+//go:build !hcs_noblake3
+
+package hcs
+
+import (
+	"hash"
+
+	"github.com/zeebo/blake3"
+)
+
+// Builds tagged hcs_noblake3 leave out this file and the zeebo/blake3
+// dependency; the secondary digest then defaults to SHA3-512
+func init() {
+	RegisterHash(HashBLAKE3, blake3Backend{})
+}
+
+// blake3Backend is the built-in BLAKE3 backend; its keyed mode is BLAKE3's own
+type blake3Backend struct{}
+
+func (blake3Backend) New() hash.Hash { return blake3.New() }
+
+func (blake3Backend) NewKeyed(key []byte) (hash.Hash, error) { return blake3.NewKeyed(key) }
@@ -0,0 +1,100 @@
+package hcs
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Display code parameters. Codes follow RFC 6238 (TOTP) with HMAC-SHA256,
+// keyed per CHIP so a display code says nothing about other codes.
+const (
+	DefaultDisplayCodeDigits = 6
+	MinDisplayCodeDigits     = 6
+	MaxDisplayCodeDigits     = 8
+	DisplayCodeStep          = 30 * time.Second
+
+	// displayCodeSkew is the number of adjacent windows accepted on
+	// verification, to absorb the delay of reading a code out
+	displayCodeSkew = 1
+)
+
+// DisplayCode is a short-lived numeric code proving live possession of an HCS code
+type DisplayCode struct {
+	Chip       string    `json:"chip"`
+	Code       string    `json:"displayCode"`
+	Digits     int       `json:"digits"`
+	ValidUntil time.Time `json:"validUntil"` // end of the time window the code belongs to
+}
+
+// DisplayCode derives the display code of chip for the current time window.
+// Zero digits selects DefaultDisplayCodeDigits.
+func (g *Generator) DisplayCode(chip string, digits int) (*DisplayCode, error) {
+	if digits == 0 {
+		digits = DefaultDisplayCodeDigits
+	}
+	if digits < MinDisplayCodeDigits || digits > MaxDisplayCodeDigits {
+		return nil, fmt.Errorf("display codes have between %d and %d digits, got %d", MinDisplayCodeDigits, MaxDisplayCodeDigits, digits)
+	}
+	key, err := g.displayCodeKey()
+	if err != nil {
+		return nil, err
+	}
+
+	counter := uint64(g.clock.Now().Unix()) / uint64(DisplayCodeStep/time.Second)
+	return &DisplayCode{
+		Chip:       chip,
+		Code:       displayCodeAt(key, chip, counter, digits),
+		Digits:     digits,
+		ValidUntil: time.Unix(int64((counter+1)*uint64(DisplayCodeStep/time.Second)), 0).UTC(),
+	}, nil
+}
+
+// VerifyDisplayCode reports whether code is the display code of chip for the
+// current time window or an adjacent one
+func (g *Generator) VerifyDisplayCode(chip, code string) (bool, error) {
+	if len(code) < MinDisplayCodeDigits || len(code) > MaxDisplayCodeDigits {
+		return false, nil
+	}
+	key, err := g.displayCodeKey()
+	if err != nil {
+		return false, err
+	}
+
+	counter := uint64(g.clock.Now().Unix()) / uint64(DisplayCodeStep/time.Second)
+	valid := false
+	for c := counter - displayCodeSkew; c <= counter+displayCodeSkew; c++ {
+		want := displayCodeAt(key, chip, c, len(code))
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			valid = true
+		}
+	}
+	return valid, nil
+}
+
+// displayCodeKey derives the display code key from the secret key
+func (g *Generator) displayCodeKey() ([]byte, error) {
+	secret, err := g.secrets.SecretKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load secret key: %w", err)
+	}
+	return DeriveKey(secret, nil, PurposeDisplayCode)
+}
+
+// displayCodeAt computes the code of chip for one time window, using the
+// HOTP dynamic truncation of RFC 4226 over HMAC-SHA256(key, chip || counter)
+func displayCodeAt(key []byte, chip string, counter uint64, digits int) string {
+	var c [8]byte
+	binary.BigEndian.PutUint64(c[:], counter)
+	sum, _ := macOf(HashSHA256, key, []byte(chip), c[:]) // HMAC accepts any key
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
@@ -0,0 +1,85 @@
+package hcs
+
+import "fmt"
+
+// DriftConfig controls the rate-of-change guard applied between two versions
+// of the same subject's profile
+type DriftConfig struct {
+	// CognitionThreshold is the maximum accepted shift, in percentage points,
+	// of any single cognition value between versions
+	CognitionThreshold int
+	// FlagElementChange warns when the dominant element differs between versions
+	FlagElementChange bool
+}
+
+// DefaultDriftConfig returns the thresholds recommended for data-entry checks
+func DefaultDriftConfig() DriftConfig {
+	return DriftConfig{
+		CognitionThreshold: 40,
+		FlagElementChange:  true,
+	}
+}
+
+// DriftWarning describes a suspicious jump between two profile versions
+type DriftWarning struct {
+	Field    string `json:"field"`
+	Previous string `json:"previous"`
+	Current  string `json:"current"`
+	Message  string `json:"message"`
+}
+
+// CheckProfileDrift compares a subject's previous and current profiles and
+// returns a warning for each change that exceeds cfg. Large jumps usually
+// indicate data-entry mistakes rather than genuine change.
+func CheckProfileDrift(prev, curr *InputProfile, cfg DriftConfig) []DriftWarning {
+	if prev == nil || curr == nil {
+		return nil
+	}
+
+	var warnings []DriftWarning
+
+	if cfg.FlagElementChange && prev.DominantElement != curr.DominantElement {
+		warnings = append(warnings, DriftWarning{
+			Field:    "dominantElement",
+			Previous: prev.DominantElement,
+			Current:  curr.DominantElement,
+			Message:  "dominant element changed between versions",
+		})
+	}
+
+	if cfg.CognitionThreshold > 0 {
+		p := NormalizeProfile(prev).Cog
+		c := NormalizeProfile(curr).Cog
+		fields := []struct {
+			name       string
+			prev, curr int
+		}{
+			{"cognition.fluid", p.F, c.F},
+			{"cognition.crystallized", p.C, c.C},
+			{"cognition.verbal", p.V, c.V},
+			{"cognition.strategic", p.S, c.S},
+			{"cognition.creative", p.Cr, c.Cr},
+		}
+		for _, f := range fields {
+			shift := f.curr - f.prev
+			if shift < 0 {
+				shift = -shift
+			}
+			if shift > cfg.CognitionThreshold {
+				warnings = append(warnings, DriftWarning{
+					Field:    f.name,
+					Previous: fmt.Sprintf("%d", f.prev),
+					Current:  fmt.Sprintf("%d", f.curr),
+					Message:  fmt.Sprintf("shifted by %d points (threshold %d)", shift, cfg.CognitionThreshold),
+				})
+			}
+		}
+	}
+
+	return warnings
+}
+
+// String formats the warning for inclusion in OutputHCS.Warnings
+func (w DriftWarning) String() string {
+	return fmt.Sprintf("%s: %s (%s -> %s)", w.Field, w.Message, w.Previous, w.Current)
+}
@@ -0,0 +1,64 @@
+package hcs
+
+import "github.com/corehuman/hcs-lab-api/internal/errcode"
+
+// westernElements lists the Western elements; ties in an element balance go
+// to the earliest
+var westernElements = []string{"Earth", "Air", "Water", "Fire"}
+
+// validateElementBalance checks a Western element distribution and returns
+// its dominant element. Shares need not sum to 1; only their ratios are used.
+func validateElementBalance(balance map[string]float64) (string, error) {
+	for element, share := range balance {
+		if !isWesternElement(element) {
+			return "", errcode.Errorf(errcode.InvalidElementBalance, "invalid element in elementBalance: %s", element)
+		}
+		if share < 0 {
+			return "", errcode.Errorf(errcode.InvalidElementBalance, "elementBalance.%s must not be negative, got %f", element, share)
+		}
+	}
+	if westernElementTotal(balance) == 0 {
+		return "", errcode.Errorf(errcode.InvalidElementBalance, "elementBalance must have a positive share")
+	}
+
+	dominant := westernElements[0]
+	for _, element := range westernElements[1:] {
+		if balance[element] > balance[dominant] {
+			dominant = element
+		}
+	}
+	return dominant, nil
+}
+
+// westernElementTotal sums the shares of a Western element distribution, in a
+// fixed order so the result does not depend on map iteration
+func westernElementTotal(balance map[string]float64) float64 {
+	total := 0.0
+	for _, element := range westernElements {
+		total += balance[element]
+	}
+	return total
+}
+
+// chineseElements lists the Chinese elements, the keys of a Chinese or fused
+// element balance
+var chineseElements = []string{"Wood", "Fire", "Earth", "Metal", "Water"}
+
+// chineseElementTotal sums a Chinese or fused element balance in a fixed order,
+// so that fusion is reproducible to the last bit
+func chineseElementTotal(balance map[string]float64) float64 {
+	total := 0.0
+	for _, element := range chineseElements {
+		total += balance[element]
+	}
+	return total
+}
+
+func isWesternElement(element string) bool {
+	for _, e := range westernElements {
+		if e == element {
+			return true
+		}
+	}
+	return false
+}
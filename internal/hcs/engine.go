@@ -0,0 +1,47 @@
+package hcs
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// CurrentEngineVersion is the computation engine used when none is requested
+	CurrentEngineVersion = "v3"
+
+	// calendarEngineVersion bounds the BaZi months by calendar months and the
+	// year at January 1. Codes issued before v2 were computed with it.
+	calendarEngineVersion = "v1"
+)
+
+// engineVersions lists the selectable engine versions
+var engineVersions = map[string]string{
+	"v1": "calendar-month BaZi approximation",
+	"v2": "solar-term BaZi month and year boundaries",
+	"v3": "rounded HCS-U5 bit fields",
+}
+
+// truncatingEngineVersions packed the HCS-U5 bit fields by rounding the
+// scaled values down, see truncateUnit
+var truncatingEngineVersions = map[string]bool{"v1": true, "v2": true}
+
+// SupportedEngineVersions returns the selectable engine versions in sorted order
+func SupportedEngineVersions() []string {
+	versions := make([]string, 0, len(engineVersions))
+	for v := range engineVersions {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// ResolveEngineVersion validates a requested engine version; empty selects the current one
+func ResolveEngineVersion(version string) (string, error) {
+	if version == "" {
+		return CurrentEngineVersion, nil
+	}
+	if _, ok := engineVersions[version]; !ok {
+		return "", fmt.Errorf("unsupported engine version: %s", version)
+	}
+	return version, nil
+}
@@ -0,0 +1,211 @@
+package hcs
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cloudflare/circl/sign/mldsa/mldsa65"
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+)
+
+// EnvelopeVersion is the version of the envelope format this build seals
+const EnvelopeVersion = 1
+
+// EnvelopeAlgorithm is the algorithm of the envelope signature
+const EnvelopeAlgorithm = "HMAC-SHA3-256"
+
+// envelopeContext prefixes the signed bytes, so an envelope signature can
+// never be mistaken for the signature of a code made with the same key
+const envelopeContext = "hcs-envelope/1\n"
+
+// ErrEnvelopeSignature is returned when an envelope signature does not match
+// its contents
+var ErrEnvelopeSignature = errors.New("envelope signature does not match its contents")
+
+// Envelope is a signed container of a complete generation result, so the
+// whole document (profiles, metadata, warnings), not just its codes, can be
+// passed around and verified
+type Envelope struct {
+	Version   int                `json:"version"`
+	Output    *OutputHCS         `json:"output"`
+	Metadata  EnvelopeMetadata   `json:"metadata"`
+	Signature *EnvelopeSignature `json:"signature"`
+}
+
+// EnvelopeMetadata describes when and by whom an envelope was sealed
+type EnvelopeMetadata struct {
+	SealedAt  string            `json:"sealedAt"`            // RFC3339 UTC timestamp, set by Seal
+	SaltEpoch int               `json:"saltEpoch,omitempty"` // epoch of the salt the key is derived with, set by Seal
+	Issuer    string            `json:"issuer,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"` // free-form caller data, covered by the signature
+}
+
+// EnvelopeSignature is the detached signature over the version, output and
+// metadata of an envelope
+type EnvelopeSignature struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"value"` // hex HMAC-SHA3-256, keyed with HKDF(secret, salt, "envelope")
+	// PQ is an ML-DSA signature over the same bytes, when post-quantum signing
+	// is configured; anyone holding the published key can verify it
+	PQ *PQSignature `json:"pq,omitempty"`
+}
+
+// Seal signs output in an envelope. SealedAt and SaltEpoch of meta are set
+// by Seal; the envelope also gets a post-quantum signature when the generator
+// has a PQ signer.
+func (g *Generator) Seal(output *OutputHCS, meta EnvelopeMetadata) (*Envelope, error) {
+	if output == nil {
+		return nil, errcode.Errorf(errcode.InvalidRequest, "nothing to seal")
+	}
+	meta.SealedAt = g.clock.Now().UTC().Format(time.RFC3339)
+	meta.SaltEpoch = g.saltEpoch
+	env := &Envelope{Version: EnvelopeVersion, Output: output, Metadata: meta}
+
+	msg, err := envelopeMessage(env)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := g.envelopeMAC(meta.SaltEpoch, msg)
+	if err != nil {
+		return nil, err
+	}
+	env.Signature = &EnvelopeSignature{Algorithm: EnvelopeAlgorithm, Value: hex.EncodeToString(mac)}
+
+	if g.pqSigner != nil {
+		key := g.pqSigner.PublicKey()
+		sig, err := g.pqSigner.Sign(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute post-quantum envelope signature: %w", err)
+		}
+		env.Signature.PQ = &PQSignature{Algorithm: key.Algorithm, KeyID: key.KeyID, Signature: sig}
+	}
+	return env, nil
+}
+
+// Open verifies the signature of an envelope sealed with this generator's
+// secret, under the salt of any of its epochs, and returns its output. A
+// post-quantum signature by this generator's key is verified too. It returns
+// ErrEnvelopeSignature when the envelope was altered.
+func (g *Generator) Open(env *Envelope) (*OutputHCS, error) {
+	if err := checkEnvelope(env); err != nil {
+		return nil, err
+	}
+	if env.Signature.Algorithm != EnvelopeAlgorithm {
+		return nil, errcode.Errorf(errcode.InvalidEnvelope, "unsupported envelope algorithm: %s", env.Signature.Algorithm)
+	}
+	if _, ok := g.salts[env.Metadata.SaltEpoch]; !ok {
+		return nil, errcode.Errorf(errcode.InvalidEnvelope, "unknown salt epoch %d", env.Metadata.SaltEpoch)
+	}
+
+	msg, err := envelopeMessage(env)
+	if err != nil {
+		return nil, err
+	}
+	want, err := g.envelopeMAC(env.Metadata.SaltEpoch, msg)
+	if err != nil {
+		return nil, err
+	}
+	got, err := hex.DecodeString(env.Signature.Value)
+	if err != nil || subtle.ConstantTimeCompare(got, want) != 1 {
+		return nil, ErrEnvelopeSignature
+	}
+
+	if pq := env.Signature.PQ; pq != nil && g.pqSigner != nil && pq.KeyID == g.pqSigner.PublicKey().KeyID {
+		if err := VerifyEnvelopePQSignature(env, g.pqSigner.PublicKey()); err != nil {
+			return nil, err
+		}
+	}
+	return env.Output, nil
+}
+
+// VerifyEnvelopePQSignature checks the post-quantum signature of an envelope
+// against a published key, without the secret key
+func VerifyEnvelopePQSignature(env *Envelope, key PQPublicKey) error {
+	if err := checkEnvelope(env); err != nil {
+		return err
+	}
+	msg, err := envelopeMessage(env)
+	if err != nil {
+		return err
+	}
+	return verifyDetachedPQ(msg, env.Signature.PQ, key, errcode.InvalidEnvelope, ErrEnvelopeSignature)
+}
+
+// verifyDetachedPQ checks a detached post-quantum signature of msg against a
+// published key. Unverifiable signatures are reported with code, and a
+// signature that does not match msg with mismatch.
+func verifyDetachedPQ(msg []byte, sig *PQSignature, key PQPublicKey, code errcode.Code, mismatch error) error {
+	if sig == nil {
+		return errcode.Errorf(code, "missing post-quantum signature")
+	}
+	if sig.KeyID != key.KeyID {
+		return errcode.Errorf(code, "not signed with key %s", key.KeyID)
+	}
+	if sig.Algorithm != PQAlgorithmMLDSA65 || key.Algorithm != PQAlgorithmMLDSA65 {
+		return errcode.Errorf(code, "unsupported post-quantum algorithm: %s", sig.Algorithm)
+	}
+	if pqKeyID(key.PublicKey) != key.KeyID {
+		return errcode.Errorf(code, "public key does not match key ID %s", key.KeyID)
+	}
+
+	var pk mldsa65.PublicKey
+	if err := pk.UnmarshalBinary(key.PublicKey); err != nil {
+		return errcode.Errorf(code, "invalid public key: %w", err)
+	}
+	if !mldsa65.Verify(&pk, msg, pqContext, sig.Signature) {
+		return mismatch
+	}
+	return nil
+}
+
+// checkEnvelope rejects envelopes that cannot be verified at all
+func checkEnvelope(env *Envelope) error {
+	switch {
+	case env == nil || env.Output == nil:
+		return errcode.Errorf(errcode.InvalidEnvelope, "envelope has no output")
+	case env.Version != EnvelopeVersion:
+		return errcode.Errorf(errcode.InvalidEnvelope, "unsupported envelope version %d", env.Version)
+	case env.Signature == nil:
+		return errcode.Errorf(errcode.InvalidEnvelope, "envelope is not signed")
+	}
+	return nil
+}
+
+// envelopeMessage returns the signed bytes: the context, then the JSON of
+// everything but the signature. encoding/json writes struct fields in
+// declaration order and map keys sorted, so re-encoding a decoded envelope
+// yields the same bytes.
+func envelopeMessage(env *Envelope) ([]byte, error) {
+	body, err := json.Marshal(struct {
+		Version  int              `json:"version"`
+		Output   *OutputHCS       `json:"output"`
+		Metadata EnvelopeMetadata `json:"metadata"`
+	}{env.Version, env.Output, env.Metadata})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode envelope: %w", err)
+	}
+	return append([]byte(envelopeContext), body...), nil
+}
+
+// envelopeMAC computes the HMAC of msg with the envelope key of a salt epoch
+func (g *Generator) envelopeMAC(epoch int, msg []byte) ([]byte, error) {
+	return g.documentMAC(epoch, PurposeEnvelope, msg)
+}
+
+// documentMAC computes the HMAC-SHA3-256 of msg with the key derived for
+// purpose under the salt of epoch
+func (g *Generator) documentMAC(epoch int, purpose string, msg []byte) ([]byte, error) {
+	secret, err := g.secrets.SecretKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load secret key: %w", err)
+	}
+	key, err := DeriveKey(secret, g.salts[epoch], purpose)
+	if err != nil {
+		return nil, err
+	}
+	return macOf(HashSHA3256, key, msg)
+}
@@ -0,0 +1,152 @@
+package hcs
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// loadSalts loads every salt epoch from p. Providers without epochs have a
+// single salt at epoch 0.
+func loadSalts(p SaltProvider) (map[int][]byte, int, error) {
+	if ep, ok := p.(EpochSaltProvider); ok {
+		return ep.SaltEpochs()
+	}
+	salt, err := p.Salt()
+	if err != nil {
+		return nil, 0, err
+	}
+	return map[int][]byte{0: salt}, 0, nil
+}
+
+// withSaltEpoch appends the EP segment naming the salt epoch a code was
+// issued under. Epoch 0 is implicit, so codes from before any rotation are unchanged.
+func withSaltEpoch(code string, epoch int) string {
+	if epoch == 0 {
+		return code
+	}
+	return fmt.Sprintf("%s|EP:%d", code, epoch)
+}
+
+// ParseSaltEpoch returns the salt epoch an HCS code was issued under: the EP
+// segment of U3, U5 and U7 codes, or the epoch field of U4 and U6 codes.
+// Codes without one were issued under epoch 0.
+func ParseSaltEpoch(code string) (int, error) {
+	if strings.HasPrefix(code, u6Prefix) {
+		d, err := DecodeU6(code)
+		if err != nil {
+			return 0, err
+		}
+		return d.Epoch, nil
+	}
+	if encoded, ok := strings.CutPrefix(code, "HCS-U4|"); ok {
+		decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decode U4: %w", err)
+		}
+		var u4Data struct {
+			Epoch int `json:"epoch"`
+		}
+		if err := json.Unmarshal(decoded, &u4Data); err != nil {
+			return 0, fmt.Errorf("failed to unmarshal U4 data: %w", err)
+		}
+		if u4Data.Epoch < 0 {
+			return 0, fmt.Errorf("invalid salt epoch: %d", u4Data.Epoch)
+		}
+		return u4Data.Epoch, nil
+	}
+
+	for _, segment := range strings.Split(code, "|") {
+		if value, ok := strings.CutPrefix(segment, "EP:"); ok {
+			epoch, err := strconv.Atoi(value)
+			if err != nil || epoch <= 0 {
+				return 0, fmt.Errorf("invalid salt epoch: %q", value)
+			}
+			return epoch, nil
+		}
+	}
+	return 0, nil
+}
+
+// SaltEpoch returns the epoch of the salt new codes are issued under
+func (g *Generator) SaltEpoch() int {
+	return g.saltEpoch
+}
+
+// SaltFingerprints returns the fingerprint of every epoch's salt
+func (g *Generator) SaltFingerprints() map[int]string {
+	out := make(map[int]string, len(g.salts))
+	for epoch, salt := range g.salts {
+		out[epoch] = SaltFingerprint(salt)
+	}
+	return out
+}
+
+// chipPattern matches CHIPs and lineage hashes: 12 lowercase hex characters
+var chipPattern = regexp.MustCompile(`^[0-9a-f]{12}$`)
+
+// IsCHIP reports whether s is formatted as a CHIP
+func IsCHIP(s string) bool {
+	return chipPattern.MatchString(s)
+}
+
+// ChipFromCode returns the CHIP carried by an HCS-U3, HCS-U4 or HCS-U6 code
+func ChipFromCode(code string) (string, error) {
+	switch {
+	case strings.HasPrefix(code, "HCS-U3|"):
+		c, err := ParseU3(code)
+		if err != nil {
+			return "", err
+		}
+		return c["chip"], nil
+	case strings.HasPrefix(code, "HCS-U4|"):
+		_, chip, err := DecodeU4(code)
+		return chip, err
+	case strings.HasPrefix(code, u6Prefix):
+		d, err := DecodeU6(code)
+		if err != nil {
+			return "", err
+		}
+		return d.Chip, nil
+	}
+	return "", fmt.Errorf("only HCS-U3, HCS-U4 and HCS-U6 codes carry a CHIP")
+}
+
+// VerifyCHIP recomputes the CHIP of an HCS-U3, HCS-U4 or HCS-U6 code from the profile
+// it carries, using the salt of the epoch the code was issued under. hardening
+// is the Argon2id cost recorded in the code's metadata, nil for a plain
+// SHA256 CHIP, whatever the generator now issues.
+func (g *Generator) VerifyCHIP(code string, hardening *CHIPHardening) error {
+	if err := checkRecordedHardening(hardening); err != nil {
+		return err
+	}
+	chip, err := ChipFromCode(code)
+	if err != nil {
+		return err
+	}
+
+	epoch, err := ParseSaltEpoch(code)
+	if err != nil {
+		return err
+	}
+	salt, ok := g.salts[epoch]
+	if !ok {
+		return fmt.Errorf("unknown salt epoch %d", epoch)
+	}
+	normalized, err := NormalizedFromCode(code)
+	if err != nil {
+		return err
+	}
+
+	_, digest, err := chipDigestWith(salt, normalized, hardening)
+	if err != nil {
+		return err
+	}
+	if digest[:12] != chip {
+		return fmt.Errorf("CHIP does not match the profile for salt epoch %d", epoch)
+	}
+	return nil
+}
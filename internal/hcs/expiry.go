@@ -0,0 +1,47 @@
+package hcs
+
+import (
+	"fmt"
+	"time"
+)
+
+// ComputeValidUntil returns the instant after which a code issued at issuedAt
+// is considered stale. A non-positive months value means the code never expires.
+func ComputeValidUntil(issuedAt time.Time, months int) (time.Time, bool) {
+	if months <= 0 {
+		return time.Time{}, false
+	}
+	return issuedAt.UTC().AddDate(0, months, 0), true
+}
+
+// ParseValidUntil parses the validUntil metadata field. An empty value yields
+// the zero time and false.
+func ParseValidUntil(meta *OutputMetadata) (time.Time, bool, error) {
+	if meta == nil || meta.ValidUntil == "" {
+		return time.Time{}, false, nil
+	}
+	t, err := time.Parse(time.RFC3339, meta.ValidUntil)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid validUntil %q: %w", meta.ValidUntil, err)
+	}
+	return t, true, nil
+}
+
+// IsStale reports whether the codes described by meta have passed their validUntil date
+func IsStale(meta *OutputMetadata, now time.Time) bool {
+	validUntil, ok, err := ParseValidUntil(meta)
+	if err != nil || !ok {
+		return false
+	}
+	return !now.Before(validUntil)
+}
+
+// ExpiresWithin reports whether the codes described by meta expire within the given window
+// from now (including codes that are already stale)
+func ExpiresWithin(meta *OutputMetadata, now time.Time, window time.Duration) bool {
+	validUntil, ok, err := ParseValidUntil(meta)
+	if err != nil || !ok {
+		return false
+	}
+	return !now.Add(window).Before(validUntil)
+}
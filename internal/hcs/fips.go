@@ -0,0 +1,36 @@
+package hcs
+
+import (
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+)
+
+// fipsApprovedHashes are the hash algorithms a FIPS build may use (FIPS 180-4
+// and FIPS 202, keyed with HMAC and derived with HKDF)
+var fipsApprovedHashes = map[string]bool{
+	HashSHA256:  true,
+	HashSHA3256: true,
+	HashSHA3512: true,
+}
+
+// FIPSMode reports whether this is a FIPS build, which only uses approved
+// algorithms: BLAKE3, Argon2id CHIP hardening and the built-in ML-DSA signer
+// are refused, and the secondary digest defaults to SHA3-512
+func FIPSMode() bool {
+	return fipsModule != ""
+}
+
+// FIPSModule names the cryptographic module of a FIPS build: "boringcrypto"
+// for GOEXPERIMENT=boringcrypto builds, "hcs_fips" for builds tagged hcs_fips,
+// whose validated module is registered with RegisterHash. It is empty
+// otherwise.
+func FIPSModule() string {
+	return fipsModule
+}
+
+// checkFIPSApproved fails in FIPS builds for an algorithm that is not approved
+func checkFIPSApproved(algorithm string, approved bool) error {
+	if FIPSMode() && !approved {
+		return errcode.Errorf(errcode.InvalidOptions, "%s is not FIPS-approved and is disabled in this FIPS build (%s)", algorithm, fipsModule)
+	}
+	return nil
+}
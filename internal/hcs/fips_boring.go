@@ -0,0 +1,7 @@
+//go:build goexperiment.boringcrypto
+
+package hcs
+
+// Built with GOEXPERIMENT=boringcrypto: the crypto/... primitives run in the
+// FIPS-validated BoringCrypto module
+const fipsModule = "boringcrypto"
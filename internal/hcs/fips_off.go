@@ -0,0 +1,5 @@
+//go:build !hcs_fips && !goexperiment.boringcrypto
+
+package hcs
+
+const fipsModule = ""
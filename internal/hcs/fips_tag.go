@@ -0,0 +1,7 @@
+//go:build hcs_fips && !goexperiment.boringcrypto
+
+package hcs
+
+// Built with the hcs_fips tag: the embedder registers the back-ends of its
+// certified module with RegisterHash
+const fipsModule = "hcs_fips"
@@ -45,6 +45,9 @@ type CombinedProfile struct {
 	Western WesternProfile `json:"western"`
 	Chinese ChineseProfile `json:"chinese"`
 	Fusion  FusionProfile  `json:"fusion"`
+
+	// FusionConfigID names the experimental fusion weights used; empty for the default
+	FusionConfigID string `json:"fusionConfigId,omitempty"`
 }
 
 // WesternProfile represents the Western astrological profile (based on existing InputProfile)
@@ -53,21 +56,27 @@ type WesternProfile struct {
 	Modal           ModalBalance           `json:"modal"`
 	Cognition       CognitionProfile       `json:"cognition"`
 	Interaction     InteractionPreferences `json:"interaction"`
+	ElementBalance  map[string]float64     `json:"elementBalance,omitempty"` // optional Western element shares
 }
 
 // BuildFusionProfile creates a fusion profile from Western and Chinese profiles
 func BuildFusionProfile(western *WesternProfile, chinese *ChineseProfile) *FusionProfile {
+	return BuildFusionProfileWithConfig(western, chinese, DefaultFusionConfig())
+}
+
+// BuildFusionProfileWithConfig creates a fusion profile using the given fusion weights
+func BuildFusionProfileWithConfig(western *WesternProfile, chinese *ChineseProfile, cfg FusionConfig) *FusionProfile {
 	// Create element signature combining both systems
-	elementSig := buildElementSignature(western, chinese)
+	elementSig := buildElementSignature(western, chinese, cfg)
 
 	// Build cognitive fusion
-	cogFusion := buildCognitiveFusion(western, chinese)
+	cogFusion := buildCognitiveFusion(western, chinese, cfg)
 
 	// Build tempo signals
-	tempoSignals := buildTempoSignals(western, chinese)
+	tempoSignals := buildTempoSignals(western, chinese, cfg)
 
 	// Calculate unified balance
-	unifiedBalance := calculateUnifiedBalance(western, chinese)
+	unifiedBalance := calculateUnifiedBalance(western, chinese, cfg)
 
 	// Calculate harmonic resonance (how well systems align)
 	harmonicResonance := calculateHarmonicResonance(western, chinese)
@@ -86,38 +95,30 @@ func BuildFusionProfile(western *WesternProfile, chinese *ChineseProfile) *Fusio
 }
 
 // buildElementSignature combines Western 4 elements with Chinese 5 elements
-func buildElementSignature(western *WesternProfile, chinese *ChineseProfile) map[string]float64 {
+func buildElementSignature(western *WesternProfile, chinese *ChineseProfile, cfg FusionConfig) map[string]float64 {
 	signature := make(map[string]float64)
 
 	// Map Western elements to unified schema
 	// Western uses: Earth, Air, Water, Fire
-	westernWeight := 0.4 // 40% influence from Western
+	westernWeight := cfg.ElementWesternWeight // 40% influence from Western by default
 
-	switch western.DominantElement {
-	case "Earth":
-		signature["Earth"] += westernWeight
-	case "Air":
-		// Air maps to Wood and Metal in Chinese system
-		signature["Wood"] += westernWeight * 0.5
-		signature["Metal"] += westernWeight * 0.5
-	case "Water":
-		signature["Water"] += westernWeight
-	case "Fire":
-		signature["Fire"] += westernWeight
+	if total := westernElementTotal(western.ElementBalance); total > 0 {
+		// Spread the Western influence over the supplied distribution
+		for _, element := range westernElements {
+			addWesternElement(signature, element, westernWeight*western.ElementBalance[element]/total)
+		}
+	} else {
+		addWesternElement(signature, western.DominantElement, westernWeight)
 	}
 
-	// Add Chinese elements (60% influence)
-	chineseWeight := 0.6
+	// Add Chinese elements (60% influence by default)
+	chineseWeight := 1 - cfg.ElementWesternWeight
 	for element, balance := range chinese.ElementBalance {
 		signature[element] += balance * chineseWeight
 	}
 
 	// Normalize to sum to 1
-	total := 0.0
-	for _, v := range signature {
-		total += v
-	}
-	if total > 0 {
+	if total := chineseElementTotal(signature); total > 0 {
 		for k := range signature {
 			signature[k] /= total
 		}
@@ -126,8 +127,25 @@ func buildElementSignature(western *WesternProfile, chinese *ChineseProfile) map
 	return signature
 }
 
+// addWesternElement adds the weight of a Western element to the Chinese
+// elements it maps to
+func addWesternElement(signature map[string]float64, element string, weight float64) {
+	switch element {
+	case "Earth":
+		signature["Earth"] += weight
+	case "Air":
+		// Air maps to Wood and Metal in Chinese system
+		signature["Wood"] += weight * 0.5
+		signature["Metal"] += weight * 0.5
+	case "Water":
+		signature["Water"] += weight
+	case "Fire":
+		signature["Fire"] += weight
+	}
+}
+
 // buildCognitiveFusion merges cognitive patterns from both systems
-func buildCognitiveFusion(western *WesternProfile, chinese *ChineseProfile) CognitiveFusion {
+func buildCognitiveFusion(western *WesternProfile, chinese *ChineseProfile, cfg FusionConfig) CognitiveFusion {
 	// Extract Chinese element influences
 	metalInfluence := chinese.ElementBalance["Metal"]
 	waterInfluence := chinese.ElementBalance["Water"]
@@ -136,24 +154,29 @@ func buildCognitiveFusion(western *WesternProfile, chinese *ChineseProfile) Cogn
 	earthInfluence := chinese.ElementBalance["Earth"]
 	yangInfluence := chinese.YinYangBalance
 
+	// Western share of each trait; the Chinese terms below sum to 0.5 and are
+	// rescaled to the remaining share (a factor of exactly 1 for the default)
+	w := cfg.CognitiveWesternWeight
+	chineseScale := (1 - w) / 0.5
+
 	// Analytical: Strategic thinking + Metal/Water clarity
-	analytical := western.Cognition.Strategic*0.5 +
-		(metalInfluence*0.3 + waterInfluence*0.2)
+	analytical := western.Cognition.Strategic*w +
+		(metalInfluence*0.3+waterInfluence*0.2)*chineseScale
 
 	// Creative: Creative + Fire/Wood growth
-	creative := western.Cognition.Creative*0.5 +
-		(fireInfluence*0.3 + woodInfluence*0.2)
+	creative := western.Cognition.Creative*w +
+		(fireInfluence*0.3+woodInfluence*0.2)*chineseScale
 
 	// Grounded: Crystallized knowledge + Earth stability
-	grounded := western.Cognition.Crystallized*0.5 +
-		earthInfluence*0.5
+	grounded := western.Cognition.Crystallized*w +
+		earthInfluence*(1-w)
 
 	// Adaptive: Fluid intelligence + element variability
 	elementVariability := calculateElementVariability(chinese.ElementBalance)
-	adaptive := western.Cognition.Fluid*0.6 + elementVariability*0.4
+	adaptive := western.Cognition.Fluid*cfg.AdaptiveWesternWeight + elementVariability*(1-cfg.AdaptiveWesternWeight)
 
 	// Expressive: Verbal + Yang energy
-	expressive := western.Cognition.Verbal*0.5 + yangInfluence*0.5
+	expressive := western.Cognition.Verbal*w + yangInfluence*(1-w)
 
 	return CognitiveFusion{
 		Analytical: clampValue(analytical),
@@ -165,7 +188,7 @@ func buildCognitiveFusion(western *WesternProfile, chinese *ChineseProfile) Cogn
 }
 
 // buildTempoSignals creates tempo and rhythm preferences
-func buildTempoSignals(western *WesternProfile, chinese *ChineseProfile) TempoSignals {
+func buildTempoSignals(western *WesternProfile, chinese *ChineseProfile, cfg FusionConfig) TempoSignals {
 	// Pace influenced by Western pace and Chinese Yang energy
 	basePace := 0.5 // balanced default
 	switch western.Interaction.Pace {
@@ -175,7 +198,7 @@ func buildTempoSignals(western *WesternProfile, chinese *ChineseProfile) TempoSi
 		basePace = 0.2
 	}
 	// Yang energy speeds up, Yin slows down
-	pace := basePace*0.6 + chinese.YinYangBalance*0.4
+	pace := basePace*cfg.TempoWesternWeight + chinese.YinYangBalance*(1-cfg.TempoWesternWeight)
 
 	// Variability from modal balance and element distribution
 	modalVariability := calculateModalVariability(western.Modal)
@@ -203,12 +226,12 @@ func buildTempoSignals(western *WesternProfile, chinese *ChineseProfile) TempoSi
 }
 
 // calculateUnifiedBalance combines Yin/Yang with modal balance
-func calculateUnifiedBalance(western *WesternProfile, chinese *ChineseProfile) float64 {
+func calculateUnifiedBalance(western *WesternProfile, chinese *ChineseProfile, cfg FusionConfig) float64 {
 	// Modal balance center (Cardinal vs Fixed primarily)
 	modalBalance := western.Modal.Cardinal*0.5 + western.Modal.Mutable*0.3 + (1-western.Modal.Fixed)*0.2
 
 	// Combine with Yin/Yang
-	unified := modalBalance*0.4 + chinese.YinYangBalance*0.6
+	unified := modalBalance*cfg.BalanceWesternWeight + chinese.YinYangBalance*(1-cfg.BalanceWesternWeight)
 
 	return clampValue(unified)
 }
@@ -266,8 +289,8 @@ func calculateElementVariability(elements map[string]float64) float64 {
 	mean := 0.2 // Expected mean for 5 elements
 	variance := 0.0
 
-	for _, value := range elements {
-		diff := value - mean
+	for _, element := range chineseElements {
+		diff := elements[element] - mean
 		variance += diff * diff
 	}
 
@@ -291,11 +314,14 @@ func calculateModalVariability(modal ModalBalance) float64 {
 	return 1.0 - math.Min(stdDev*3, 1.0) // Invert so high variability = close to 1
 }
 
+// getDominantElement returns the largest element of a Chinese element
+// balance, in chineseElements order so ties do not depend on map iteration
 func getDominantElement(elements map[string]float64) string {
 	maxElement := ""
 	maxValue := 0.0
 
-	for element, value := range elements {
+	for _, element := range chineseElements {
+		value := elements[element]
 		if value > maxValue {
 			maxValue = value
 			maxElement = element
@@ -0,0 +1,164 @@
+package hcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// DefaultFusionConfigID identifies the built-in fusion weights
+const DefaultFusionConfigID = "default"
+
+// FusionConfig holds the tunable weights of the Western/Chinese fusion math.
+// Every weight is the Western share in [0, 1]; the Chinese share is 1 - weight.
+type FusionConfig struct {
+	ID                     string  `json:"id"`
+	ElementWesternWeight   float64 `json:"elementWesternWeight"`   // element signature
+	CognitiveWesternWeight float64 `json:"cognitiveWesternWeight"` // analytical, creative, grounded, expressive
+	AdaptiveWesternWeight  float64 `json:"adaptiveWesternWeight"`  // adaptive cognition
+	TempoWesternWeight     float64 `json:"tempoWesternWeight"`     // tempo pace
+	BalanceWesternWeight   float64 `json:"balanceWesternWeight"`   // unified balance
+}
+
+// DefaultFusionConfig returns the production fusion weights
+func DefaultFusionConfig() FusionConfig {
+	return FusionConfig{
+		ID:                     DefaultFusionConfigID,
+		ElementWesternWeight:   0.4,
+		CognitiveWesternWeight: 0.5,
+		AdaptiveWesternWeight:  0.6,
+		TempoWesternWeight:     0.6,
+		BalanceWesternWeight:   0.4,
+	}
+}
+
+// Validate checks the ID and that every weight lies in [0, 1]
+func (c FusionConfig) Validate() error {
+	if c.ID == "" {
+		return fmt.Errorf("fusion config ID must not be empty")
+	}
+	weights := map[string]float64{
+		"elementWesternWeight":   c.ElementWesternWeight,
+		"cognitiveWesternWeight": c.CognitiveWesternWeight,
+		"adaptiveWesternWeight":  c.AdaptiveWesternWeight,
+		"tempoWesternWeight":     c.TempoWesternWeight,
+		"balanceWesternWeight":   c.BalanceWesternWeight,
+	}
+	for name, w := range weights {
+		if w < 0 || w > 1 {
+			return fmt.Errorf("fusion config %s: %s must be between 0 and 1, got %f", c.ID, name, w)
+		}
+	}
+	return nil
+}
+
+var (
+	fusionConfigsMu sync.RWMutex
+	fusionConfigs   = map[string]FusionConfig{
+		DefaultFusionConfigID: DefaultFusionConfig(),
+	}
+)
+
+// RegisterFusionConfig makes an experimental fusion configuration selectable by ID.
+// The default configuration cannot be replaced, and neither can the weights
+// of a registered ID: registering the same configuration again is a no-op,
+// new weights need a new ID.
+func RegisterFusionConfig(cfg FusionConfig) error {
+	fusionConfigsMu.Lock()
+	defer fusionConfigsMu.Unlock()
+	if err := checkFusionConfig(cfg); err != nil {
+		return err
+	}
+	fusionConfigs[cfg.ID] = cfg
+	return nil
+}
+
+// CheckFusionConfig returns the error RegisterFusionConfig would return for
+// cfg, without registering it
+func CheckFusionConfig(cfg FusionConfig) error {
+	fusionConfigsMu.RLock()
+	defer fusionConfigsMu.RUnlock()
+	return checkFusionConfig(cfg)
+}
+
+func checkFusionConfig(cfg FusionConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if cfg.ID == DefaultFusionConfigID {
+		return fmt.Errorf("fusion config %q is reserved", DefaultFusionConfigID)
+	}
+	if registered, ok := fusionConfigs[cfg.ID]; ok && registered != cfg {
+		return fmt.Errorf("fusion config %q is already registered with other weights; register them under a new ID", cfg.ID)
+	}
+	return nil
+}
+
+// LookupFusionConfig returns the registered configuration for id. An empty id
+// selects the default configuration.
+func LookupFusionConfig(id string) (FusionConfig, error) {
+	if id == "" {
+		id = DefaultFusionConfigID
+	}
+
+	fusionConfigsMu.RLock()
+	defer fusionConfigsMu.RUnlock()
+	cfg, ok := fusionConfigs[id]
+	if !ok {
+		return FusionConfig{}, fmt.Errorf("unknown fusion config: %s", id)
+	}
+	return cfg, nil
+}
+
+// FusionConfigIDs lists the registered configuration IDs in sorted order
+func FusionConfigIDs() []string {
+	fusionConfigsMu.RLock()
+	defer fusionConfigsMu.RUnlock()
+	ids := make([]string, 0, len(fusionConfigs))
+	for id := range fusionConfigs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ReadFusionConfigs parses and validates the configurations of a JSON file
+// containing an array of FusionConfig objects without registering them
+func ReadFusionConfigs(path string) ([]FusionConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fusion configs: %w", err)
+	}
+
+	var configs []FusionConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse fusion configs: %w", err)
+	}
+
+	for _, cfg := range configs {
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+		if cfg.ID == DefaultFusionConfigID {
+			return nil, fmt.Errorf("fusion config %q is reserved", DefaultFusionConfigID)
+		}
+	}
+	return configs, nil
+}
+
+// LoadFusionConfigs registers every configuration in a JSON file containing an
+// array of FusionConfig objects. Nothing is registered when any is invalid.
+func LoadFusionConfigs(path string) error {
+	configs, err := ReadFusionConfigs(path)
+	if err != nil {
+		return err
+	}
+	for _, cfg := range configs {
+		if err := RegisterFusionConfig(cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
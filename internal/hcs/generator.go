@@ -1,43 +1,179 @@
 package hcs
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
 )
 
+// ErrDeadlineExceeded is returned when a generation exceeds its compute budget
+var ErrDeadlineExceeded = errors.New("generation deadline exceeded")
+
 // Generator handles HCS code generation with persistent salt
 type Generator struct {
-	salt []byte
+	salt      []byte
+	saltEpoch int            // epoch of salt, embedded in codes when above 0
+	salts     map[int][]byte // every epoch's salt, for verifying older codes
+	secrets   SecretProvider
+	signer    Signer // U7 signing; a LocalSigner over secrets unless set with WithSigner
+
+	engineVersion  string // default when GeneratorOptions.EngineVersion is empty
+	fusionConfigID string // default when GeneratorOptions.FusionConfigID is empty
+	cache          *profileCache
+	logger         *slog.Logger
+	pqSigner       PQSigner       // nil unless post-quantum signing is configured
+	chipHardening  *CHIPHardening // nil for plain SHA256 CHIPs
+	clock          clock.Clock
 }
 
 // GeneratorOptions allows customization of code generation
 type GeneratorOptions struct {
 	U3Only bool // Only generate U3 code
 	U4Only bool // Only generate U4 code
+
+	// ValidityMonths marks the generated codes as stale after the given number
+	// of months. Zero means the codes never expire.
+	ValidityMonths int
+
+	// FusionConfigID selects a registered fusion weight configuration for
+	// experiments. Empty uses the generator's config (the default weights
+	// unless set with WithFusionConfig).
+	FusionConfigID string
+
+	// EngineVersion selects the computation engine. Empty uses the generator's
+	// engine (CurrentEngineVersion unless set with WithEngineVersion).
+	EngineVersion string
+
+	// U7Version selects the HCS-U7 format version. Empty uses CurrentU7Version.
+	U7Version string
+
+	// U7SignatureLengths selects the inline QSIG/B3 lengths. Zero values use the defaults.
+	U7SignatureLengths SignatureLengths
+
+	// KeyDerivation selects how the QSIG and B3 keys are derived from the
+	// secret. Empty uses KeyDerivationHKDF, the only derivation for new codes.
+	KeyDerivation KeyDerivation
+
+	// SecondaryDigest selects the algorithm of the B3 signature. Empty uses
+	// DefaultSecondaryDigest: BLAKE3, or SHA3-512 in builds tagged hcs_noblake3.
+	SecondaryDigest SecondaryDigest
+
+	// PostQuantum adds a detached post-quantum signature (OutputHCS.PQSignature)
+	// referenced by a PQ segment of the U7 code. Requires WithPQSigner.
+	PostQuantum bool
+
+	// PreviousChip links the codes to the CHIP of the subject's previous codes
+	// when a profile is regenerated: its LineageHash goes into an LN segment
+	// (a lineage field in U4) and OutputHCS.Metadata, so verifiers can
+	// establish continuity across versions with VerifyLineage.
+	PreviousChip string
+
+	// LegacyU7Versions are additional HCS-U7 format versions emitted side by side
+	// in OutputHCS.LegacyCodes, so consumers can migrate during codec upgrades
+	LegacyU7Versions []string
+
+	SkipU5 bool // Do not generate the U5 code even when birth info is provided
+	SkipU6 bool // Do not generate the U6 code
+	SkipU7 bool // Do not sign and generate the U7 code (no secret key required)
+
+	// ModalValidation checks that the modal values sum to 1, or rescales them
+	// so they do. The zero value accepts any sum.
+	ModalValidation ModalValidation
+
+	// WesternFromBirth derives the dominant element, element balance and modal
+	// balance from the natal chart of the birth info, the way the Chinese
+	// profile is, replacing those sent. The chart goes in OutputHCS.WesternChart.
+	WesternFromBirth bool
+
+	// Quality scores the input as received and records it in OutputHCS.Metadata
+	Quality bool
+
+	// Norms ranks the cognition values against these norms in
+	// OutputHCS.Percentiles. Nil adds no percentiles.
+	Norms *Norms
+
+	// ReferenceDate selects the current Luck Pillar of a birth info with a
+	// gender. The zero value uses the generation time.
+	ReferenceDate time.Time
+
+	// Trace attaches the intermediate artifacts (normalized profile, canonical
+	// bytes, pillars, pre-truncation digests) to OutputHCS.Trace
+	Trace bool
 }
 
-// NewGenerator creates a new HCS code generator
-func NewGenerator() (*Generator, error) {
-	// Load or create salt from current directory
-	salt, err := LoadOrCreateSalt(".")
+// NewGenerator creates a new HCS code generator. By default the salt lives in
+// the current directory and the secret key is read from the environment.
+func NewGenerator(options ...Option) (*Generator, error) {
+	settings := generatorSettings{
+		secrets: NewEnvSecretProvider(),
+		logger:  slog.Default(),
+		clock:   clock.System{},
+	}
+	for _, opt := range options {
+		if err := opt(&settings); err != nil {
+			return nil, fmt.Errorf("invalid generator option: %w", err)
+		}
+	}
+
+	if settings.salt == nil {
+		settings.salt = DirSaltProvider{Dir: settings.saltDir, Secrets: settings.secrets, MigrateSeal: settings.migrateSeal, Existing: settings.existingSalt, Logger: settings.logger}
+	}
+	salts, epoch, err := loadSalts(settings.salt)
 	if err != nil {
+		if settings.saltDir != "" {
+			return nil, fmt.Errorf("failed to initialize generator with dir %s: %w", settings.saltDir, err)
+		}
 		return nil, fmt.Errorf("failed to initialize generator: %w", err)
 	}
+	if len(salts[epoch]) == 0 {
+		return nil, fmt.Errorf("failed to initialize generator: empty salt")
+	}
 
-	return &Generator{
-		salt: salt,
-	}, nil
+	g := &Generator{
+		salt:           salts[epoch],
+		saltEpoch:      epoch,
+		salts:          salts,
+		secrets:        settings.secrets,
+		signer:         settings.signer,
+		engineVersion:  settings.engineVersion,
+		fusionConfigID: settings.fusionConfigID,
+		logger:         settings.logger,
+		pqSigner:       settings.pqSigner,
+		chipHardening:  settings.chipHardening,
+		clock:          settings.clock,
+	}
+	if g.signer == nil {
+		g.signer = NewLocalSigner(settings.secrets)
+	}
+	if settings.cacheSize > 0 {
+		g.cache = newProfileCache(settings.cacheSize)
+	}
+	return g, nil
 }
 
 // NewGeneratorWithSaltDir creates a generator with a specific salt directory
 func NewGeneratorWithSaltDir(dir string) (*Generator, error) {
-	salt, err := LoadOrCreateSalt(dir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize generator with dir %s: %w", dir, err)
-	}
+	return NewGenerator(WithSaltDir(dir))
+}
 
-	return &Generator{
-		salt: salt,
-	}, nil
+// NewGeneratorWithSecrets creates a generator with a specific salt directory
+// and secret key provider
+func NewGeneratorWithSecrets(dir string, secrets SecretProvider) (*Generator, error) {
+	return NewGenerator(WithSaltDir(dir), WithSecretProvider(secrets))
+}
+
+// PQPublicKey returns the key verifying this generator's post-quantum
+// signatures, if post-quantum signing is configured
+func (g *Generator) PQPublicKey() (PQPublicKey, bool) {
+	if g.pqSigner == nil {
+		return PQPublicKey{}, false
+	}
+	return g.pqSigner.PublicKey(), true
 }
 
 // Generate creates HCS codes from an input profile
@@ -47,8 +183,83 @@ func (g *Generator) Generate(in *InputProfile) (*OutputHCS, error) {
 
 // GenerateWithOptions creates HCS codes with specific options
 func (g *Generator) GenerateWithOptions(in *InputProfile, opts *GeneratorOptions) (*OutputHCS, error) {
+	return g.GenerateContext(context.Background(), in, opts)
+}
+
+// GenerateContext creates HCS codes, checking ctx between the computation
+// stages (BaZi, fusion, signing). When ctx is done the generation is abandoned
+// with an error wrapping both ErrDeadlineExceeded and ctx.Err().
+func (g *Generator) GenerateContext(ctx context.Context, in *InputProfile, opts *GeneratorOptions) (*OutputHCS, error) {
 	if in == nil {
-		return nil, fmt.Errorf("input profile cannot be nil")
+		return nil, errcode.Errorf(errcode.InvalidRequest, "input profile cannot be nil")
+	}
+
+	// Default options, falling back to the generator's engine and fusion config
+	if opts == nil {
+		opts = &GeneratorOptions{}
+	}
+	fusionConfigID := opts.FusionConfigID
+	if fusionConfigID == "" {
+		fusionConfigID = g.fusionConfigID
+	}
+	engineVersion := opts.EngineVersion
+	if engineVersion == "" {
+		engineVersion = g.engineVersion
+	}
+
+	fusionConfig, err := LookupFusionConfig(fusionConfigID)
+	if err != nil {
+		return nil, errcode.Errorf(errcode.InvalidOptions, "invalid options: %w", err)
+	}
+	engineVersion, err = ResolveEngineVersion(engineVersion)
+	if err != nil {
+		return nil, errcode.Errorf(errcode.InvalidOptions, "invalid options: %w", err)
+	}
+	if _, err := opts.U7SignatureLengths.Resolve(); err != nil {
+		return nil, errcode.Errorf(errcode.InvalidOptions, "invalid options: %w", err)
+	}
+	if _, err := ResolveKeyDerivation(opts.KeyDerivation); err != nil {
+		return nil, errcode.Errorf(errcode.InvalidOptions, "invalid options: %w", err)
+	}
+	if _, err := ResolveSecondaryDigest(opts.SecondaryDigest); err != nil {
+		return nil, errcode.Errorf(errcode.InvalidOptions, "invalid options: %w", err)
+	}
+	if opts.PostQuantum && g.pqSigner == nil {
+		return nil, errcode.Errorf(errcode.InvalidOptions, "invalid options: post-quantum signing requested but no signer is configured")
+	}
+	if opts.PreviousChip != "" && !chipPattern.MatchString(opts.PreviousChip) {
+		return nil, errcode.Errorf(errcode.InvalidOptions, "invalid options: previous CHIP must be 12 lowercase hex characters, got %q", opts.PreviousChip)
+	}
+	modalValidation, err := opts.ModalValidation.Resolve()
+	if err != nil {
+		return nil, errcode.Errorf(errcode.InvalidOptions, "invalid options: %w", err)
+	}
+	if opts.Norms != nil {
+		if err := opts.Norms.Validate(); err != nil {
+			return nil, errcode.Errorf(errcode.InvalidOptions, "invalid options: %w", err)
+		}
+	}
+
+	// Derive the Western profile first: it stands for the values sent
+	var westernChart *WesternChart
+	if opts.WesternFromBirth {
+		if westernChart, err = deriveWesternProfile(in); err != nil {
+			return nil, err
+		}
+	}
+
+	// Score the input before any normalization or defaults hide what was sent
+	var quality *Quality
+	if opts.Quality {
+		q := ScoreQuality(in)
+		quality = &q
+	}
+
+	// Check or rescale the modal balance first, so percentages can be
+	// normalized before the range check
+	warnings, err := applyModalValidation(in, modalValidation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input profile: %w", err)
 	}
 
 	// Validate input
@@ -56,33 +267,43 @@ func (g *Generator) GenerateWithOptions(in *InputProfile, opts *GeneratorOptions
 		return nil, fmt.Errorf("invalid input profile: %w", err)
 	}
 
-	// Default options
-	if opts == nil {
-		opts = &GeneratorOptions{}
-	}
+	logger := g.loggerFor(ctx)
+	logger.DebugContext(ctx, "generation started", "engine", engineVersion, "fusionConfig", fusionConfig.ID)
 
 	// Normalize the profile for consistent processing
 	normalized := NormalizeProfile(in)
 
 	// Generate CHIP signature
-	chip, err := GenerateCHIP(g.salt, normalized)
+	normalizedJSON, chipHex, err := chipDigestWith(g.salt, normalized, g.chipHardening)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate CHIP: %w", err)
 	}
+	chip := chipHex[:12]
+	var lineage string
+	if opts.PreviousChip != "" {
+		lineage = LineageHash(opts.PreviousChip)
+	}
 
+	archetype := AssignArchetype(normalized)
 	output := &OutputHCS{
-		Input: *in,
-		Chip:  chip,
+		Input:        *in,
+		Chip:         chip,
+		Archetype:    &archetype,
+		WesternChart: westernChart,
+		Warnings:     warnings,
+	}
+	if opts.Norms != nil {
+		output.Percentiles = opts.Norms.Rank(in.Cognition)
 	}
 
 	// Generate U3 code unless U4Only is set
 	if !opts.U4Only {
-		output.CodeU3 = EncodeU3(in, chip)
+		output.CodeU3 = withLineage(withSaltEpoch(EncodeU3(in, chip), g.saltEpoch), lineage)
 	}
 
 	// Generate U4 code unless U3Only is set
 	if !opts.U3Only {
-		u4Code, err := EncodeU4(normalized, chip)
+		u4Code, err := encodeU4(normalized, chip, g.saltEpoch, lineage)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate U4 code: %w", err)
 		}
@@ -92,76 +313,261 @@ func (g *Generator) GenerateWithOptions(in *InputProfile, opts *GeneratorOptions
 	// Generate Chinese profile and U5 if birth info is provided
 	if in.BirthInfo != nil {
 		// Compute Chinese BaZi profile
-		chineseProfile, err := ComputeChineseProfile(*in.BirthInfo)
+		if err := enterStage(ctx, logger, "BaZi computation"); err != nil {
+			return nil, err
+		}
+		chineseProfile, err := g.chineseProfile(*in.BirthInfo, engineVersion)
 		if err != nil {
 			// Log error but don't fail the entire generation
 			// Chinese profile is optional enhancement
-			fmt.Printf("Warning: failed to compute Chinese profile: %v\n", err)
+			logger.WarnContext(ctx, "failed to compute Chinese profile", "error", err)
 		} else {
-			output.ChineseProfile = chineseProfile
-
-			// Create Western profile from input
-			westernProfile := &WesternProfile{
-				DominantElement: in.DominantElement,
-				Modal:           in.Modal,
-				Cognition:       in.Cognition,
-				Interaction:     in.Interaction,
+			if chineseProfile.LuckPillars != nil {
+				// The profile may be cached: date a copy
+				reference := opts.ReferenceDate
+				if reference.IsZero() {
+					reference = g.clock.Now()
+				}
+				dated := *chineseProfile
+				dated.LuckPillars = chineseProfile.LuckPillars.At(reference)
+				chineseProfile = &dated
 			}
+			output.ChineseProfile = chineseProfile
 
-			// Build fusion profile
-			fusionProfile := BuildFusionProfile(westernProfile, chineseProfile)
-
-			// Create combined profile
-			output.CombinedProfile = &CombinedProfile{
-				Western: *westernProfile,
-				Chinese: *chineseProfile,
-				Fusion:  *fusionProfile,
+			// Build fusion and combined profiles
+			if err := enterStage(ctx, logger, "fusion"); err != nil {
+				return nil, err
 			}
+			combined := combineProfiles(in, chineseProfile, fusionConfig)
+			output.CombinedProfile = combined
 
 			// Generate HCS-U5 code
-			u5Code, err := EncodeU5(westernProfile, chineseProfile, fusionProfile, g.salt)
-			if err != nil {
-				fmt.Printf("Warning: failed to generate U5 code: %v\n", err)
-			} else {
-				output.CodeU5 = u5Code
+			if !opts.SkipU5 {
+				u5Code, err := EncodeU5WithEngine(&combined.Western, &combined.Chinese, &combined.Fusion, g.salt, engineVersion)
+				if err != nil {
+					logger.WarnContext(ctx, "failed to generate U5 code", "error", err)
+				} else {
+					output.CodeU5 = withLineage(withSaltEpoch(u5Code, g.saltEpoch), lineage)
+				}
 			}
 		}
 	}
 
-	// Generate canonical profile data for U7 signatures (uses normalized + optional combined profile)
-	canonical, err := CanonicalProfileData(normalized, output.CombinedProfile)
+	// Generate U6 code, with the Chinese and fusion block when there is one
+	if !opts.SkipU6 && !opts.U3Only && !opts.U4Only {
+		u6Code, err := encodeU6(normalized, chip, output.CombinedProfile, g.saltEpoch, lineage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate U6 code: %w", err)
+		}
+		output.CodeU6 = u6Code
+	}
+
+	if !opts.SkipU7 {
+		if err := enterStage(ctx, logger, "signing"); err != nil {
+			return nil, err
+		}
+		if err := g.signU7(ctx, output, normalized, lineage, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	// Record issuance and expiry metadata when a validity period is requested
+	if opts.ValidityMonths > 0 {
+		issuedAt := g.clock.Now().UTC()
+		validUntil, _ := ComputeValidUntil(issuedAt, opts.ValidityMonths)
+		output.metadata().IssuedAt = issuedAt.Format(time.RFC3339)
+		output.metadata().ValidUntil = validUntil.Format(time.RFC3339)
+	}
+
+	if quality != nil {
+		output.metadata().Quality = quality
+	}
+
+	// Record the link to the previous codes of the subject
+	if lineage != "" {
+		output.metadata().Lineage = lineage
+	}
+
+	// Record an explicitly selected fusion configuration so experiments can be analyzed
+	if fusionConfigID != "" {
+		output.metadata().FusionConfig = fusionConfig.ID
+	}
+	// Record the Argon2id cost so hardened CHIPs can be recomputed
+	if g.chipHardening != nil {
+		hardening := *g.chipHardening
+		output.metadata().CHIPHardening = &hardening
+	}
+
+	if opts.Trace {
+		if output.Trace, err = g.buildTrace(output, normalized, normalizedJSON, chipHex, engineVersion, fusionConfig.ID); err != nil {
+			return nil, fmt.Errorf("failed to build trace: %w", err)
+		}
+	}
+
+	logger.DebugContext(ctx, "generation completed", "chip", output.Chip)
+	return output, nil
+}
+
+// chineseProfile computes the BaZi profile for birth, using the cache when enabled
+func (g *Generator) chineseProfile(birth BirthInfo, engineVersion string) (*ChineseProfile, error) {
+	if g.cache == nil {
+		return ComputeChineseProfileWithEngine(birth, engineVersion)
+	}
+
+	key := profileCacheKey{birth: birth, engine: engineVersion}
+	if profile, ok := g.cache.get(key); ok {
+		return profile, nil
+	}
+	profile, err := ComputeChineseProfileWithEngine(birth, engineVersion)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build canonical profile: %w", err)
+		return nil, err
 	}
+	g.cache.put(key, profile)
+	return profile, nil
+}
 
-	// Load secret key from environment (required for U7). This is a hard failure
-	// to avoid accidentally generating unsigned or weakly signed codes.
-	secret, err := LoadSecretKey()
+// combineProfiles fuses the Western profile of in with its Chinese profile
+func combineProfiles(in *InputProfile, chinese *ChineseProfile, fusionConfig FusionConfig) *CombinedProfile {
+	western := &WesternProfile{
+		DominantElement: in.DominantElement,
+		Modal:           in.Modal,
+		Cognition:       in.Cognition,
+		Interaction:     in.Interaction,
+		ElementBalance:  in.ElementBalance,
+	}
+	combined := &CombinedProfile{
+		Western: *western,
+		Chinese: *chinese,
+		Fusion:  *BuildFusionProfileWithConfig(western, chinese, fusionConfig),
+	}
+	if fusionConfig.ID != DefaultFusionConfigID {
+		combined.FusionConfigID = fusionConfig.ID
+	}
+	return combined
+}
+
+// chipDigestWith returns the CHIP input and full digest, hardened with
+// Argon2id at cost h unless h is nil
+func chipDigestWith(salt []byte, normalized *NormalizedProfile, h *CHIPHardening) ([]byte, string, error) {
+	if h != nil {
+		return hardenedChipDigest(salt, normalized, *h)
+	}
+	return chipDigest(salt, normalized)
+}
+
+// CHIPHardening returns the Argon2id cost of the CHIPs the generator issues,
+// nil when they are plain SHA256 CHIPs
+func (g *Generator) CHIPHardening() *CHIPHardening {
+	if g.chipHardening == nil {
+		return nil
+	}
+	h := *g.chipHardening
+	return &h
+}
+
+// enterStage traces the start of a named stage and fails when ctx is done before it starts
+func enterStage(ctx context.Context, logger *slog.Logger, stage string) error {
+	if err := ctx.Err(); err != nil {
+		logger.WarnContext(ctx, "generation abandoned", "stage", stage, "error", err)
+		return errcode.Errorf(errcode.DeadlineExceeded, "%w before %s: %w", ErrDeadlineExceeded, stage, err)
+	}
+	logger.DebugContext(ctx, "generation stage", "stage", stage)
+	return nil
+}
+
+// signU7 computes the quantum-style signatures and the HCS-U7 code (plus any
+// requested legacy formats) and stores them in output
+func (g *Generator) signU7(ctx context.Context, output *OutputHCS, normalized *NormalizedProfile, lineage string, opts *GeneratorOptions) error {
+	// Generate canonical profile data for U7 signatures (uses normalized + optional combined profile)
+	canonical, err := CanonicalProfileData(normalized, output.CombinedProfile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load secret key: %w", err)
+		return fmt.Errorf("failed to build canonical profile: %w", err)
 	}
 
 	// Compute quantum-style signatures using the canonical data, secret key, and persistent salt.
-	qsigHex, b3Hex, err := ComputeQuantumSignatures(canonical, secret, g.salt)
+	// A missing key or unreachable signer is a hard failure, to avoid
+	// accidentally generating unsigned or weakly signed codes.
+	// The BLAKE3 digest goes on the wire as empty, as signers predating
+	// SHA3-512 support expect.
+	digest, err := ResolveSecondaryDigest(opts.SecondaryDigest)
+	if err != nil {
+		return err
+	}
+	if digest == DigestBLAKE3 {
+		digest = ""
+	}
+	derivation, err := ResolveKeyDerivation(opts.KeyDerivation)
 	if err != nil {
-		return nil, fmt.Errorf("failed to compute quantum signatures: %w", err)
+		return err
+	}
+	sigs, err := g.signer.SignU7(ctx, U7SignRequest{Canonical: canonical, Salt: g.salt, KeyDerivation: derivation, SecondaryDigest: digest})
+	if err != nil {
+		return fmt.Errorf("failed to compute quantum signatures: %w", err)
+	}
+	if sigs.SecondaryDigest != digest {
+		return errcode.Errorf(errcode.SigningFailed, "the signer does not support the %s secondary digest", digest)
 	}
+	qsigHex, b3Hex, keyID := sigs.QSig, sigs.B3Sig, sigs.KeyID
 
 	// Format HCS-U7 code using the normalized profile and signatures.
-	u7, err := FormatHCSU7(normalized, qsigHex, b3Hex)
+	u7, err := formatU7Version(opts.U7Version, normalized, qsigHex, b3Hex, opts.U7SignatureLengths)
 	if err != nil {
-		return nil, fmt.Errorf("failed to format HCS-U7 code: %w", err)
+		return fmt.Errorf("failed to format HCS-U7 code: %w", err)
+	}
+	u7 = withLineage(withSaltEpoch(withKeyID(declareSecondaryDigest(declareKeyDerivation(u7, derivation), digest), keyID), g.saltEpoch), lineage)
+
+	// Dual-write: emit the requested legacy formats alongside the primary code
+	for _, version := range opts.LegacyU7Versions {
+		if version == opts.U7Version || (opts.U7Version == "" && version == CurrentU7Version) {
+			continue
+		}
+		legacy, err := formatU7Version(version, normalized, qsigHex, b3Hex, opts.U7SignatureLengths)
+		if err != nil {
+			return fmt.Errorf("failed to format legacy HCS-U7 code: %w", err)
+		}
+		legacy = withLineage(withSaltEpoch(withKeyID(declareSecondaryDigest(declareKeyDerivation(legacy, derivation), digest), keyID), g.saltEpoch), lineage)
+		output.LegacyCodes = append(output.LegacyCodes, VersionedCode{Level: "U7", Version: version, Code: legacy})
+	}
+
+	// Post-quantum mode: reference the signing key from the code, then sign the final code text
+	if opts.PostQuantum {
+		key := g.pqSigner.PublicKey()
+		u7 = attachPQSegment(u7, key)
+		sig, err := g.pqSigner.Sign([]byte(u7))
+		if err != nil {
+			return fmt.Errorf("failed to compute post-quantum signature: %w", err)
+		}
+		output.PQSignature = &PQSignature{Algorithm: key.Algorithm, KeyID: key.KeyID, Signature: sig}
 	}
 
 	output.CodeU7 = u7
 	output.QSig = qsigHex
 	output.B3Sig = b3Hex
 
-	return output, nil
+	return nil
 }
 
 // validateInput checks if the input profile has valid values
 func (g *Generator) validateInput(in *InputProfile) error {
+	return ValidateInput(in)
+}
+
+// ValidateInput checks if the input profile has valid values, applying
+// defaults for empty interaction preferences
+func ValidateInput(in *InputProfile) error {
+	// A Western element distribution implies the dominant element
+	if len(in.ElementBalance) > 0 {
+		dominant, err := validateElementBalance(in.ElementBalance)
+		if err != nil {
+			return err
+		}
+		if in.DominantElement == "" {
+			in.DominantElement = dominant
+		} else if in.ElementBalance[in.DominantElement] < in.ElementBalance[dominant] {
+			return errcode.Errorf(errcode.InvalidElementBalance, "dominant element %s disagrees with elementBalance, where %s is dominant", in.DominantElement, dominant)
+		}
+	}
+
 	// Validate element
 	validElements := map[string]bool{
 		"Earth": true,
@@ -170,34 +576,34 @@ func (g *Generator) validateInput(in *InputProfile) error {
 		"Fire":  true,
 	}
 	if !validElements[in.DominantElement] {
-		return fmt.Errorf("invalid dominant element: %s", in.DominantElement)
+		return errcode.Errorf(errcode.InvalidElement, "invalid dominant element: %s", in.DominantElement)
 	}
 
 	// Validate modal values (should be between 0 and 1)
-	if err := g.validateRange("modal.cardinal", in.Modal.Cardinal); err != nil {
+	if err := validateRange(errcode.InvalidModal, "modal.cardinal", in.Modal.Cardinal); err != nil {
 		return err
 	}
-	if err := g.validateRange("modal.fixed", in.Modal.Fixed); err != nil {
+	if err := validateRange(errcode.InvalidModal, "modal.fixed", in.Modal.Fixed); err != nil {
 		return err
 	}
-	if err := g.validateRange("modal.mutable", in.Modal.Mutable); err != nil {
+	if err := validateRange(errcode.InvalidModal, "modal.mutable", in.Modal.Mutable); err != nil {
 		return err
 	}
 
 	// Validate cognition values
-	if err := g.validateRange("cognition.fluid", in.Cognition.Fluid); err != nil {
+	if err := validateRange(errcode.InvalidCognition, "cognition.fluid", in.Cognition.Fluid); err != nil {
 		return err
 	}
-	if err := g.validateRange("cognition.crystallized", in.Cognition.Crystallized); err != nil {
+	if err := validateRange(errcode.InvalidCognition, "cognition.crystallized", in.Cognition.Crystallized); err != nil {
 		return err
 	}
-	if err := g.validateRange("cognition.verbal", in.Cognition.Verbal); err != nil {
+	if err := validateRange(errcode.InvalidCognition, "cognition.verbal", in.Cognition.Verbal); err != nil {
 		return err
 	}
-	if err := g.validateRange("cognition.strategic", in.Cognition.Strategic); err != nil {
+	if err := validateRange(errcode.InvalidCognition, "cognition.strategic", in.Cognition.Strategic); err != nil {
 		return err
 	}
-	if err := g.validateRange("cognition.creative", in.Cognition.Creative); err != nil {
+	if err := validateRange(errcode.InvalidCognition, "cognition.creative", in.Cognition.Creative); err != nil {
 		return err
 	}
 
@@ -215,33 +621,33 @@ func (g *Generator) validateInput(in *InputProfile) error {
 	// Validate interaction preferences
 	validPace := map[string]bool{"balanced": true, "fast": true, "slow": true}
 	if !validPace[in.Interaction.Pace] {
-		return fmt.Errorf("invalid pace: %s", in.Interaction.Pace)
+		return errcode.Errorf(errcode.InvalidInteraction, "invalid pace: %s", in.Interaction.Pace)
 	}
 
 	validStructure := map[string]bool{"low": true, "medium": true, "high": true}
 	if !validStructure[in.Interaction.Structure] {
-		return fmt.Errorf("invalid structure: %s", in.Interaction.Structure)
+		return errcode.Errorf(errcode.InvalidInteraction, "invalid structure: %s", in.Interaction.Structure)
 	}
 
 	validTone := map[string]bool{"warm": true, "neutral": true, "sharp": true, "precise": true}
 	if !validTone[in.Interaction.Tone] {
-		return fmt.Errorf("invalid tone: %s", in.Interaction.Tone)
+		return errcode.Errorf(errcode.InvalidInteraction, "invalid tone: %s", in.Interaction.Tone)
 	}
 
 	// Validate optional birth info if provided
 	if in.BirthInfo != nil {
 		if err := validateBirthInfo(*in.BirthInfo); err != nil {
-			return fmt.Errorf("invalid birth info: %w", err)
+			return errcode.Errorf(errcode.InvalidBirthInfo, "invalid birth info: %w", err)
 		}
 	}
 
 	return nil
 }
 
-// validateRange checks if a value is between 0 and 1
-func (g *Generator) validateRange(field string, value float64) error {
+// validateRange checks if a value is between 0 and 1, failing with code
+func validateRange(code errcode.Code, field string, value float64) error {
 	if value < 0 || value > 1 {
-		return fmt.Errorf("%s must be between 0 and 1, got %f", field, value)
+		return errcode.Errorf(code, "%s must be between 0 and 1, got %f", field, value)
 	}
 	return nil
 }
@@ -0,0 +1,80 @@
+package hcs
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"golang.org/x/crypto/argon2"
+)
+
+// CHIPAlgorithmArgon2id identifies Argon2id-hardened CHIPs in output metadata
+const CHIPAlgorithmArgon2id = "argon2id"
+
+// CHIPHardening is the Argon2id cost of a hardened CHIP. Normalized profiles
+// have little entropy, so a plain SHA256 CHIP can be brute-forced back to its
+// inputs once the salt leaks; Argon2id makes every guess expensive.
+type CHIPHardening struct {
+	Algorithm string `json:"algorithm"`
+	Time      uint32 `json:"time"`      // passes over memory
+	MemoryKiB uint32 `json:"memoryKiB"` // memory per hash
+	Threads   uint8  `json:"threads"`
+}
+
+// DefaultCHIPHardening returns the RFC 9106 second recommended Argon2id cost
+// (3 passes, 64 MiB, 4 lanes)
+func DefaultCHIPHardening() CHIPHardening {
+	return CHIPHardening{Algorithm: CHIPAlgorithmArgon2id, Time: 3, MemoryKiB: 64 * 1024, Threads: 4}
+}
+
+// Validate checks that the cost parameters are usable
+func (h CHIPHardening) Validate() error {
+	if h.Algorithm != CHIPAlgorithmArgon2id {
+		return fmt.Errorf("unsupported CHIP hardening algorithm: %q", h.Algorithm)
+	}
+	if h.Time < 1 {
+		return fmt.Errorf("argon2id time must be at least 1")
+	}
+	if h.Threads < 1 {
+		return fmt.Errorf("argon2id threads must be at least 1")
+	}
+	if h.MemoryKiB < 8*uint32(h.Threads) {
+		return fmt.Errorf("argon2id memory must be at least %d KiB for %d threads", 8*uint32(h.Threads), h.Threads)
+	}
+	return nil
+}
+
+// GenerateHardenedCHIP computes the CHIP from an Argon2id hash of the
+// canonical normalized profile, using the salt as Argon2 salt
+func GenerateHardenedCHIP(salt []byte, normalized *NormalizedProfile, h CHIPHardening) (string, error) {
+	_, digest, err := hardenedChipDigest(salt, normalized, h)
+	if err != nil {
+		return "", err
+	}
+	return digest[:12], nil
+}
+
+// hardenedChipDigest is chipDigest with Argon2id in place of SHA256
+func hardenedChipDigest(salt []byte, normalized *NormalizedProfile, h CHIPHardening) ([]byte, string, error) {
+	if err := h.Validate(); err != nil {
+		return nil, "", err
+	}
+	canonicalJSON, err := canonicalNormalizedJSON(normalized)
+	if err != nil {
+		return nil, "", err
+	}
+	hash := argon2.IDKey(canonicalJSON, salt, h.Time, h.MemoryKiB, h.Threads, 32)
+	return canonicalJSON, hex.EncodeToString(hash), nil
+}
+
+// checkRecordedHardening validates the cost recorded with a code before a
+// CHIP is recomputed with it
+func checkRecordedHardening(h *CHIPHardening) error {
+	if h == nil {
+		return nil
+	}
+	if err := h.Validate(); err != nil {
+		return errcode.Errorf(errcode.InvalidCode, "invalid CHIP hardening: %w", err)
+	}
+	return checkFIPSApproved("Argon2id CHIP hardening", false)
+}
@@ -0,0 +1,132 @@
+package hcs
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Hash algorithms of the registry. Every digest in CHIPs, codes, signatures,
+// seals and key derivation is built from one of these.
+const (
+	HashSHA256  = "sha256"
+	HashSHA3256 = "sha3-256"
+	HashSHA3512 = "sha3-512"
+	HashBLAKE3  = "blake3" // registered unless built with hcs_noblake3
+)
+
+// HashBackend implements one hash algorithm. Enterprise builds can swap in
+// hardware-accelerated or FIPS-validated implementations with RegisterHash;
+// codec logic only ever goes through the registry. A backend must produce
+// exactly the digests of the algorithm it is registered under.
+type HashBackend interface {
+	// New returns an unkeyed hash
+	New() hash.Hash
+	// NewKeyed returns the keyed mode used for MACs: HMAC for SHA-2 and SHA-3,
+	// the native keyed mode for BLAKE3
+	NewKeyed(key []byte) (hash.Hash, error)
+}
+
+var (
+	hashesMu sync.RWMutex
+	hashes   = map[string]HashBackend{
+		HashSHA256:  HMACBackend(sha256.New),
+		HashSHA3256: HMACBackend(sha3.New256),
+		HashSHA3512: HMACBackend(sha3.New512),
+	}
+)
+
+// RegisterHash installs backend for algorithm name, replacing the built-in
+// one. Register backends at startup, before the first generation.
+func RegisterHash(name string, backend HashBackend) {
+	if backend == nil {
+		panic("hcs: RegisterHash with a nil backend")
+	}
+	hashesMu.Lock()
+	defer hashesMu.Unlock()
+	hashes[name] = backend
+}
+
+// LookupHash returns the backend registered for algorithm name
+func LookupHash(name string) (HashBackend, error) {
+	hashesMu.RLock()
+	defer hashesMu.RUnlock()
+	if err := checkFIPSApproved(name, fipsApprovedHashes[name]); err != nil {
+		return nil, err
+	}
+	backend, ok := hashes[name]
+	if !ok {
+		if name == HashBLAKE3 {
+			return nil, errNoBLAKE3
+		}
+		return nil, fmt.Errorf("no hash backend registered for %s", name)
+	}
+	return backend, nil
+}
+
+// HashNames returns the registered algorithms in sorted order; FIPS builds
+// only list the approved ones
+func HashNames() []string {
+	hashesMu.RLock()
+	defer hashesMu.RUnlock()
+	names := make([]string, 0, len(hashes))
+	for name := range hashes {
+		if !FIPSMode() || fipsApprovedHashes[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HMACBackend returns a backend for a hash constructor, keyed with HMAC
+func HMACBackend(newHash func() hash.Hash) HashBackend {
+	return hmacBackend{newHash}
+}
+
+type hmacBackend struct {
+	newHash func() hash.Hash
+}
+
+func (b hmacBackend) New() hash.Hash { return b.newHash() }
+
+func (b hmacBackend) NewKeyed(key []byte) (hash.Hash, error) { return hmac.New(b.newHash, key), nil }
+
+// builtinHash returns the backend of a built-in algorithm, which is always registered
+func builtinHash(name string) HashBackend {
+	backend, err := LookupHash(name)
+	if err != nil {
+		panic(err)
+	}
+	return backend
+}
+
+// hashOf hashes the concatenation of parts with a built-in algorithm
+func hashOf(name string, parts ...[]byte) []byte {
+	h := builtinHash(name).New()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+// macOf computes the keyed digest of the concatenation of parts
+func macOf(name string, key []byte, parts ...[]byte) ([]byte, error) {
+	backend, err := LookupHash(name)
+	if err != nil {
+		return nil, err
+	}
+	h, err := backend.NewKeyed(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to key %s: %w", name, err)
+	}
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,111 @@
+package hcs
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+)
+
+// LegacyKeyID identifies the key of HCS-U7 codes without a KID segment: the
+// key of HCS_SECRET_KEY, or the only key of providers without key IDs
+const LegacyKeyID = "legacy"
+
+// keyIDPattern matches key IDs: short lowercase names safe in a code segment
+var keyIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,15}$`)
+
+// SecretKeyring is a SecretProvider holding several keys by key ID, so codes
+// signed before a key rotation still verify. SecretKey returns the active key,
+// which signs new codes.
+type SecretKeyring interface {
+	SecretProvider
+	SecretKeys() (keys map[string][]byte, active string, err error)
+}
+
+// loadSecretKeys loads every key of p by key ID and the ID of the active one.
+// Providers without key IDs have a single key under LegacyKeyID.
+func loadSecretKeys(p SecretProvider) (map[string][]byte, string, error) {
+	if kr, ok := p.(SecretKeyring); ok {
+		return kr.SecretKeys()
+	}
+	key, err := p.SecretKey()
+	if err != nil {
+		return nil, "", err
+	}
+	return map[string][]byte{LegacyKeyID: key}, LegacyKeyID, nil
+}
+
+// Keyring is a fixed set of keys by key ID, one of them active
+type Keyring struct {
+	keys   map[string][]byte
+	active string
+}
+
+// NewKeyring validates the key IDs and keys and returns a keyring signing
+// with the key of active
+func NewKeyring(keys map[string][]byte, active string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte, len(keys)), active: active}
+	for id, key := range keys {
+		if !keyIDPattern.MatchString(id) {
+			return nil, errcode.Errorf(errcode.InvalidSecret, "invalid key ID %q: use up to 16 lowercase letters, digits and dashes", id)
+		}
+		if err := validateSecretKey(key); err != nil {
+			return nil, errcode.Errorf(errcode.InvalidSecret, "key %s %w", id, err)
+		}
+		k.keys[id] = append([]byte(nil), key...)
+	}
+	if _, ok := k.keys[active]; !ok {
+		return nil, errcode.Errorf(errcode.MissingSecret, "active key %q is not in the keyring (known: %s)", active, strings.Join(k.KeyIDs(), ", "))
+	}
+	return k, nil
+}
+
+// SecretKey returns the active key
+func (k *Keyring) SecretKey() ([]byte, error) {
+	return k.keys[k.active], nil
+}
+
+// SecretKeys returns every key and the ID of the active one
+func (k *Keyring) SecretKeys() (map[string][]byte, string, error) {
+	return k.keys, k.active, nil
+}
+
+// ActiveKeyID returns the ID of the key signing new codes
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// KeyIDs returns the key IDs, sorted
+func (k *Keyring) KeyIDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// withKeyID appends the KID segment naming the key a U7 code was signed
+// with. The legacy key is implicit, so codes from before any rotation are unchanged.
+func withKeyID(code, keyID string) string {
+	if keyID == "" || keyID == LegacyKeyID {
+		return code
+	}
+	return fmt.Sprintf("%s|KID:%s", code, keyID)
+}
+
+// ParseKeyID returns the ID of the key an HCS-U7 code was signed with: its
+// KID segment, or LegacyKeyID for codes without one
+func ParseKeyID(code string) (string, error) {
+	for _, segment := range strings.Split(code, "|") {
+		if value, ok := strings.CutPrefix(segment, "KID:"); ok {
+			if !keyIDPattern.MatchString(value) || value == LegacyKeyID {
+				return "", fmt.Errorf("invalid key ID: %q", value)
+			}
+			return value, nil
+		}
+	}
+	return LegacyKeyID, nil
+}
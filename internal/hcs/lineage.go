@@ -0,0 +1,93 @@
+package hcs
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// lineageContext prefixes the CHIP hashed into a lineage hash
+const lineageContext = "hcs-lineage/1\n"
+
+// LineageHash returns the short hash linking a regenerated code to the CHIP
+// of the code it replaces. Anyone holding the previous CHIP can recompute it,
+// but it does not reveal the CHIP.
+func LineageHash(previousChip string) string {
+	return hex.EncodeToString(hashOf(HashSHA256, []byte(lineageContext), []byte(previousChip)))[:12]
+}
+
+// withLineage appends the LN segment linking a code to the previous CHIP of
+// the same subject. Codes without a lineage are unchanged.
+func withLineage(code, lineage string) string {
+	if lineage == "" {
+		return code
+	}
+	return fmt.Sprintf("%s|LN:%s", code, lineage)
+}
+
+// ParseLineage returns the lineage hash of an HCS code: the LN segment of U3,
+// U5 and U7 codes, or the lineage field of U4 and U6 codes. Codes that do
+// not continue an earlier one return "".
+func ParseLineage(code string) (string, error) {
+	if strings.HasPrefix(code, u6Prefix) {
+		d, err := DecodeU6(code)
+		if err != nil {
+			return "", err
+		}
+		return d.Lineage, nil
+	}
+	if encoded, ok := strings.CutPrefix(code, "HCS-U4|"); ok {
+		decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode U4: %w", err)
+		}
+		var u4Data struct {
+			Lineage string `json:"lineage"`
+		}
+		if err := json.Unmarshal(decoded, &u4Data); err != nil {
+			return "", fmt.Errorf("failed to unmarshal U4 data: %w", err)
+		}
+		if u4Data.Lineage != "" && !chipPattern.MatchString(u4Data.Lineage) {
+			return "", fmt.Errorf("invalid lineage: %q", u4Data.Lineage)
+		}
+		return u4Data.Lineage, nil
+	}
+
+	for _, segment := range strings.Split(code, "|") {
+		if value, ok := strings.CutPrefix(segment, "LN:"); ok {
+			if !chipPattern.MatchString(value) {
+				return "", fmt.Errorf("invalid lineage: %q", value)
+			}
+			return value, nil
+		}
+	}
+	return "", nil
+}
+
+// VerifyLineage checks that code continues previous, given either as the
+// previous CHIP or as a previous HCS-U3 or HCS-U4 code
+func VerifyLineage(code, previous string) error {
+	lineage, err := ParseLineage(code)
+	if err != nil {
+		return err
+	}
+	if lineage == "" {
+		return fmt.Errorf("code carries no lineage")
+	}
+
+	previousChip := previous
+	if strings.HasPrefix(previous, "HCS-") {
+		if previousChip, err = ChipFromCode(previous); err != nil {
+			return fmt.Errorf("invalid previous code: %w", err)
+		}
+	}
+	if !chipPattern.MatchString(previousChip) {
+		return fmt.Errorf("invalid previous CHIP: %q", previousChip)
+	}
+	if LineageHash(previousChip) != lineage {
+		return fmt.Errorf("code does not continue CHIP %s", previousChip)
+	}
+	return nil
+}
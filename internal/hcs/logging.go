@@ -0,0 +1,23 @@
+package hcs
+
+import (
+	"context"
+	"log/slog"
+)
+
+type loggerKey struct{}
+
+// ContextWithLogger returns a context whose generations log to logger instead
+// of the generator's logger, e.g. to tag messages with a request ID
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// loggerFor returns the logger attached to ctx, falling back to the generator's.
+// Profile and birth data are never passed to the logger.
+func (g *Generator) loggerFor(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok && logger != nil {
+		return logger
+	}
+	return g.logger
+}
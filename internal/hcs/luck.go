@@ -0,0 +1,135 @@
+package hcs
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// luckPillarCount is the number of ten-year Luck Pillars computed, covering
+// eighty years from the start age
+const luckPillarCount = 8
+
+// LuckPillars is the sequence of ten-year Luck Pillars (Da Yun). They step
+// from the month pillar through the sixty-pillar cycle, forward for a man
+// born in a Yang year or a woman born in a Yin year, backward otherwise.
+type LuckPillars struct {
+	Direction string       `json:"direction"` // forward or backward
+	StartAge  float64      `json:"startAge"`  // age in years at which the first pillar starts
+	Pillars   []LuckPillar `json:"pillars"`
+	// Current is the pillar running at ReferenceDate, if any: none before the
+	// start age or after the last pillar
+	Current       *LuckPillar `json:"current,omitempty"`
+	ReferenceDate string      `json:"referenceDate,omitempty"` // YYYY-MM-DD
+}
+
+// LuckPillar is one ten-year Luck Pillar
+type LuckPillar struct {
+	Pillar    string  `json:"pillar"`
+	StartAge  float64 `json:"startAge"`
+	StartDate string  `json:"startDate"` // YYYY-MM-DD
+}
+
+// Genders accepted in BirthInfo.Gender
+var Genders = []string{"male", "female"}
+
+// computeLuckPillars computes the Luck Pillars of a birth at birthTime with the
+// given year and month pillars. The start age counts three days from birth to
+// the next sectional solar term (going forward) or from the previous one
+// (going backward) as one year.
+func computeLuckPillars(birthTime time.Time, yearPillar, monthPillar Pillar, gender string) *LuckPillars {
+	yang := yearPillar.StemIndex%2 == 0
+	step, direction := 1, "forward"
+	if yang != (gender == "male") {
+		step, direction = -1, "backward"
+	}
+
+	days := math.Abs(solarTermInstant(birthTime, step > 0).Sub(birthTime).Hours()) / 24
+	// One day of the interval stands for four months of life
+	start := birthTime.Add(time.Duration(days * 365.2425 / 3 * 24 * float64(time.Hour)))
+	startAge := days / 3
+
+	luck := &LuckPillars{Direction: direction, StartAge: math.Round(startAge*10) / 10}
+	pillar := monthPillar
+	for i := 0; i < luckPillarCount; i++ {
+		pillar = nextPillar(pillar, step)
+		luck.Pillars = append(luck.Pillars, LuckPillar{
+			Pillar:    pillar.PillarToString(),
+			StartAge:  math.Round((startAge+float64(10*i))*10) / 10,
+			StartDate: start.AddDate(10*i, 0, 0).Format(time.DateOnly),
+		})
+	}
+	return luck
+}
+
+// At returns a copy of the Luck Pillars with the pillar running at reference
+// as Current
+func (lp *LuckPillars) At(reference time.Time) *LuckPillars {
+	at := *lp
+	at.Current = nil
+	at.ReferenceDate = reference.Format(time.DateOnly)
+	for i, p := range lp.Pillars {
+		// Each pillar runs until the next starts, and the last for ten years;
+		// dates in YYYY-MM-DD order as strings
+		end := nextDecade(p.StartDate)
+		if i+1 < len(lp.Pillars) {
+			end = lp.Pillars[i+1].StartDate
+		}
+		if p.StartDate <= at.ReferenceDate && at.ReferenceDate < end {
+			at.Current = &p
+		}
+	}
+	return &at
+}
+
+// nextDecade returns the date ten years after a YYYY-MM-DD date
+func nextDecade(date string) string {
+	t, _ := time.Parse(time.DateOnly, date)
+	return t.AddDate(10, 0, 0).Format(time.DateOnly)
+}
+
+// nextPillar steps a pillar by step through the sixty-pillar cycle
+func nextPillar(p Pillar, step int) Pillar {
+	stem := (p.StemIndex + step + 10) % 10
+	branch := (p.BranchIndex + step + 12) % 12
+	return Pillar{
+		Stem:        HeavenlyStems[stem].Name,
+		Branch:      EarthlyBranches[branch].Name,
+		StemIndex:   stem,
+		BranchIndex: branch,
+	}
+}
+
+// solarTermInstant returns the instant of the sectional solar term that ends
+// the BaZi month of t, or the one that opened it when forward is false
+func solarTermInstant(t time.Time, forward bool) time.Time {
+	month := solarMonth(t)
+	if forward {
+		month = (month + 1) % len(SolarTerms)
+	}
+	target := SolarTerms[month].Longitude
+
+	// Newton's method, with the Sun's mean motion of 0.9856° a day
+	instant := t
+	for i := 0; i < 10; i++ {
+		diff := math.Remainder(target-SolarLongitude(instant), 360)
+		if math.Abs(diff) < 1e-6 {
+			break
+		}
+		instant = instant.Add(time.Duration(diff / 0.9856 * 24 * float64(time.Hour)))
+	}
+	return instant
+}
+
+// validateGender accepts an empty gender or one of Genders
+func validateGender(gender string) error {
+	if gender == "" {
+		return nil
+	}
+	for _, g := range Genders {
+		if gender == g {
+			return nil
+		}
+	}
+	return fmt.Errorf("gender must be one of %v, got %q", Genders, gender)
+}
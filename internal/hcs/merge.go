@@ -0,0 +1,261 @@
+package hcs
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+)
+
+// Rating is one rater's assessment of a subject
+type Rating struct {
+	Rater   string       `json:"rater"`            // e.g. "self", "peer-1"
+	Weight  float64      `json:"weight,omitempty"` // relative weight; zero counts as 1
+	Profile InputProfile `json:"profile"`
+}
+
+// MergeOptions sets when the dispersion report flags a field as disputed
+type MergeOptions struct {
+	// MaxStdDev flags numeric fields whose weighted standard deviation across
+	// raters exceeds it. Zero uses DefaultMaxStdDev.
+	MaxStdDev float64 `json:"maxStdDev,omitempty"`
+	// MinAgreement flags categorical fields whose consensus value carries less
+	// than this share of the total weight. Zero uses DefaultMinAgreement.
+	MinAgreement float64 `json:"minAgreement,omitempty"`
+}
+
+// Default dispute thresholds of MergeOptions
+const (
+	DefaultMaxStdDev    = 0.15
+	DefaultMinAgreement = 0.5
+)
+
+// MergeResult is the consensus of several ratings of one subject
+type MergeResult struct {
+	Consensus  InputProfile     `json:"consensus"`
+	Dispersion DispersionReport `json:"dispersion"`
+}
+
+// DispersionReport describes how much the raters disagree
+type DispersionReport struct {
+	Raters int `json:"raters"`
+	// Fields are the numeric fields, e.g. modal.cardinal or cognition.fluid
+	Fields []FieldDispersion `json:"fields"`
+	// Choices are the categorical fields: dominant element and interaction
+	Choices []ChoiceAgreement `json:"choices"`
+	// MeanStdDev is the mean of the numeric standard deviations, an overall
+	// disagreement score between 0 and 0.5
+	MeanStdDev float64 `json:"meanStdDev"`
+	// Disputed lists the fields past the MergeOptions thresholds
+	Disputed []string `json:"disputed,omitempty"`
+	// Distances is the RMS distance of each rater's numeric fields to the consensus
+	Distances []RaterDistance `json:"distances"`
+}
+
+// FieldDispersion summarizes the ratings of a numeric field
+type FieldDispersion struct {
+	Field  string  `json:"field"`
+	Mean   float64 `json:"mean"` // the consensus value
+	StdDev float64 `json:"stdDev"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+// ChoiceAgreement summarizes the ratings of a categorical field
+type ChoiceAgreement struct {
+	Field     string             `json:"field"`
+	Value     string             `json:"value"`     // the consensus value
+	Agreement float64            `json:"agreement"` // share of the weight behind Value
+	Votes     map[string]float64 `json:"votes"`     // share of the weight behind each value
+}
+
+// RaterDistance is how far one rater is from the consensus
+type RaterDistance struct {
+	Rater    string  `json:"rater"`
+	Distance float64 `json:"distance"`
+}
+
+// Values of the categorical fields, in tie-breaking order
+var (
+	paceValues      = []string{"balanced", "fast", "slow"}
+	structureValues = []string{"medium", "low", "high"}
+	toneValues      = []string{"neutral", "warm", "sharp", "precise"}
+)
+
+// MergeProfiles combines several ratings of the same subject into a consensus
+// profile. Numeric values are weighted means; the element distribution is the
+// weighted mean of each rater's element balance, or of their dominant element
+// when they give none; interaction preferences are weighted votes, with ties
+// going to the most neutral value. Birth info, being a fact rather than an
+// assessment, must agree across the raters that give it.
+func MergeProfiles(ratings []Rating, opts MergeOptions) (*MergeResult, error) {
+	if len(ratings) < 2 {
+		return nil, errcode.Errorf(errcode.InvalidRequest, "merging needs at least two ratings, got %d", len(ratings))
+	}
+	if opts.MaxStdDev == 0 {
+		opts.MaxStdDev = DefaultMaxStdDev
+	}
+	if opts.MinAgreement == 0 {
+		opts.MinAgreement = DefaultMinAgreement
+	}
+	if opts.MaxStdDev < 0 || opts.MinAgreement < 0 || opts.MinAgreement > 1 {
+		return nil, errcode.Errorf(errcode.InvalidRequest, "maxStdDev must be positive and minAgreement between 0 and 1")
+	}
+
+	profiles := make([]InputProfile, len(ratings))
+	weights := make([]float64, len(ratings))
+	total := 0.0
+	var birth *BirthInfo
+	anyBalance := false
+	for i, r := range ratings {
+		p := r.Profile
+		if err := ValidateInput(&p); err != nil {
+			return nil, fmt.Errorf("rating %d (%s): %w", i, r.Rater, err)
+		}
+		if r.Weight < 0 || math.IsNaN(r.Weight) || math.IsInf(r.Weight, 0) {
+			return nil, errcode.Errorf(errcode.InvalidRequest, "rating %d (%s): weight must be a positive number", i, r.Rater)
+		}
+		weights[i] = r.Weight
+		if weights[i] == 0 {
+			weights[i] = 1
+		}
+		total += weights[i]
+		if p.BirthInfo != nil {
+			if birth != nil && *birth != *p.BirthInfo {
+				return nil, errcode.Errorf(errcode.InvalidBirthInfo, "rating %d (%s): birth info differs from an earlier rating", i, r.Rater)
+			}
+			birth = p.BirthInfo
+		}
+		anyBalance = anyBalance || len(p.ElementBalance) > 0
+		profiles[i] = p
+	}
+	for i := range weights {
+		weights[i] /= total
+	}
+
+	report := DispersionReport{Raters: len(ratings)}
+	consensus := InputProfile{BirthInfo: birth}
+
+	// Numeric fields, in code order
+	fields := []struct {
+		name   string
+		value  func(p *InputProfile) float64
+		target *float64
+	}{
+		{"modal.cardinal", func(p *InputProfile) float64 { return p.Modal.Cardinal }, &consensus.Modal.Cardinal},
+		{"modal.fixed", func(p *InputProfile) float64 { return p.Modal.Fixed }, &consensus.Modal.Fixed},
+		{"modal.mutable", func(p *InputProfile) float64 { return p.Modal.Mutable }, &consensus.Modal.Mutable},
+		{"cognition.fluid", func(p *InputProfile) float64 { return p.Cognition.Fluid }, &consensus.Cognition.Fluid},
+		{"cognition.crystallized", func(p *InputProfile) float64 { return p.Cognition.Crystallized }, &consensus.Cognition.Crystallized},
+		{"cognition.verbal", func(p *InputProfile) float64 { return p.Cognition.Verbal }, &consensus.Cognition.Verbal},
+		{"cognition.strategic", func(p *InputProfile) float64 { return p.Cognition.Strategic }, &consensus.Cognition.Strategic},
+		{"cognition.creative", func(p *InputProfile) float64 { return p.Cognition.Creative }, &consensus.Cognition.Creative},
+	}
+	sumStdDev := 0.0
+	for _, f := range fields {
+		d := FieldDispersion{Field: f.name, Min: math.Inf(1), Max: math.Inf(-1)}
+		for i := range profiles {
+			v := f.value(&profiles[i])
+			d.Mean += weights[i] * v
+			d.Min = math.Min(d.Min, v)
+			d.Max = math.Max(d.Max, v)
+		}
+		variance := 0.0
+		for i := range profiles {
+			diff := f.value(&profiles[i]) - d.Mean
+			variance += weights[i] * diff * diff
+		}
+		d.StdDev = math.Sqrt(variance)
+		*f.target = round4(d.Mean)
+		sumStdDev += d.StdDev
+		if d.StdDev > opts.MaxStdDev {
+			report.Disputed = append(report.Disputed, f.name)
+		}
+		d.Mean, d.StdDev = round4(d.Mean), round4(d.StdDev)
+		report.Fields = append(report.Fields, d)
+	}
+	report.MeanStdDev = round4(sumStdDev / float64(len(fields)))
+
+	// Element distribution: each rater's balance, or a one-hot dominant element
+	mean := make(map[string]float64, len(westernElements))
+	for i, p := range profiles {
+		total := westernElementTotal(p.ElementBalance)
+		for _, element := range westernElements {
+			share := 0.0
+			switch {
+			case total > 0:
+				share = p.ElementBalance[element] / total
+			case p.DominantElement == element:
+				share = 1
+			}
+			mean[element] += weights[i] * share
+		}
+	}
+	dominant := westernElements[0]
+	for _, element := range westernElements[1:] {
+		if mean[element] > mean[dominant] {
+			dominant = element
+		}
+	}
+	consensus.DominantElement = dominant
+	if anyBalance {
+		consensus.ElementBalance = make(map[string]float64, len(westernElements))
+		for _, element := range westernElements {
+			if share := round4(mean[element]); share > 0 {
+				consensus.ElementBalance[element] = share
+			}
+		}
+	}
+
+	// Categorical fields
+	choices := []struct {
+		name   string
+		values []string
+		value  func(p *InputProfile) string
+		target *string
+	}{
+		{"dominantElement", westernElements, func(p *InputProfile) string { return p.DominantElement }, nil},
+		{"interaction.pace", paceValues, func(p *InputProfile) string { return p.Interaction.Pace }, &consensus.Interaction.Pace},
+		{"interaction.structure", structureValues, func(p *InputProfile) string { return p.Interaction.Structure }, &consensus.Interaction.Structure},
+		{"interaction.tone", toneValues, func(p *InputProfile) string { return p.Interaction.Tone }, &consensus.Interaction.Tone},
+	}
+	for _, c := range choices {
+		votes := make(map[string]float64)
+		for i := range profiles {
+			votes[c.value(&profiles[i])] += weights[i]
+		}
+		winner := dominant // the element follows the mean distribution
+		if c.target != nil {
+			winner = c.values[0]
+			for _, v := range c.values[1:] {
+				if votes[v] > votes[winner] {
+					winner = v
+				}
+			}
+			*c.target = winner
+		}
+		agreement := ChoiceAgreement{Field: c.name, Value: winner, Agreement: round4(votes[winner]), Votes: votes}
+		for v := range votes {
+			votes[v] = round4(votes[v])
+		}
+		if agreement.Agreement < opts.MinAgreement {
+			report.Disputed = append(report.Disputed, c.name)
+		}
+		report.Choices = append(report.Choices, agreement)
+	}
+
+	// Distance of each rater to the consensus over the numeric fields
+	for i, r := range ratings {
+		sum := 0.0
+		for _, f := range fields {
+			diff := f.value(&profiles[i]) - *f.target
+			sum += diff * diff
+		}
+		report.Distances = append(report.Distances, RaterDistance{Rater: r.Rater, Distance: round4(math.Sqrt(sum / float64(len(fields))))})
+	}
+
+	if err := ValidateInput(&consensus); err != nil {
+		return nil, fmt.Errorf("invalid consensus profile: %w", err)
+	}
+	return &MergeResult{Consensus: consensus, Dispersion: report}, nil
+}
@@ -0,0 +1,80 @@
+package hcs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+)
+
+// ModalCheck selects how a modal balance whose values do not sum to 1 is handled
+type ModalCheck string
+
+const (
+	ModalCheckOff     ModalCheck = "off"     // accept any sum, as before the check existed
+	ModalCheckLenient ModalCheck = "lenient" // accept with a warning
+	ModalCheckStrict  ModalCheck = "strict"  // reject the input
+)
+
+// DefaultModalSumTolerance is how far Cardinal+Fixed+Mutable may be from 1
+// before the modal check reports it
+const DefaultModalSumTolerance = 0.05
+
+// ModalValidation configures the modal sum check. Modal values are shares of
+// one whole, so a sum far from 1 usually means the caller sent percentages of
+// something else, and the codes would misrepresent the subject.
+type ModalValidation struct {
+	Check     ModalCheck
+	Tolerance float64 // allowed |sum - 1|; zero uses DefaultModalSumTolerance
+	// Normalize rescales the modal values to sum to 1 before generation
+	// instead of checking them. The rescaling is reported as a warning.
+	Normalize bool
+}
+
+// Resolve applies the defaults and rejects unknown checks or negative tolerances
+func (v ModalValidation) Resolve() (ModalValidation, error) {
+	switch v.Check {
+	case "":
+		v.Check = ModalCheckOff
+	case ModalCheckOff, ModalCheckLenient, ModalCheckStrict:
+	default:
+		return v, fmt.Errorf("unknown modal check: %s", v.Check)
+	}
+	if v.Tolerance < 0 {
+		return v, fmt.Errorf("modal sum tolerance must not be negative, got %g", v.Tolerance)
+	}
+	if v.Tolerance == 0 {
+		v.Tolerance = DefaultModalSumTolerance
+	}
+	return v, nil
+}
+
+// applyModalValidation checks or normalizes the modal balance of in, in place,
+// and returns the warnings to attach to the output. v must be resolved.
+func applyModalValidation(in *InputProfile, v ModalValidation) ([]string, error) {
+	m := &in.Modal
+	sum := m.Cardinal + m.Fixed + m.Mutable
+
+	if v.Normalize {
+		if sum == 0 {
+			return nil, errcode.Errorf(errcode.InvalidModal, "cannot normalize modal values that are all zero")
+		}
+		if math.Abs(sum-1) < 1e-9 {
+			return nil, nil
+		}
+		before := *m
+		m.Cardinal, m.Fixed, m.Mutable = m.Cardinal/sum, m.Fixed/sum, m.Mutable/sum
+		return []string{fmt.Sprintf("modal values summed to %.4g and were rescaled to sum to 1: cardinal %.4g -> %.4g, fixed %.4g -> %.4g, mutable %.4g -> %.4g",
+			sum, before.Cardinal, m.Cardinal, before.Fixed, m.Fixed, before.Mutable, m.Mutable)}, nil
+	}
+
+	if v.Check == ModalCheckOff || math.Abs(sum-1) <= v.Tolerance {
+		return nil, nil
+	}
+	msg := fmt.Sprintf("modal values sum to %.4g, expected 1 ± %g", sum, v.Tolerance)
+	if v.Check == ModalCheckStrict {
+		return nil, errcode.Wrap(errcode.InvalidModal, errors.New(msg))
+	}
+	return []string{msg}, nil
+}
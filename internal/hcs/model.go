@@ -31,6 +31,9 @@ type InputProfile struct {
 	Interaction     InteractionPreferences `json:"interaction"`
 	// Optional birth info for Chinese astrology
 	BirthInfo *BirthInfo `json:"birthInfo,omitempty"`
+	// Optional share of each Western element. Fusion uses it instead of the
+	// dominant element alone; DominantElement is derived from it when empty.
+	ElementBalance map[string]float64 `json:"elementBalance,omitempty"`
 }
 
 // OutputHCS represents the generated HCS codes and metadata
@@ -39,10 +42,41 @@ type OutputHCS struct {
 	CodeU3          string           `json:"codeU3"`
 	CodeU4          string           `json:"codeU4,omitempty"`
 	CodeU5          string           `json:"codeU5,omitempty"` // NEW: HCS-U5 fusion code
+	CodeU6          string           `json:"codeU6,omitempty"` // Compact binary code, see EncodeU6
 	CodeU7          string           `json:"codeU7,omitempty"`
 	QSig            string           `json:"qsig,omitempty"`
 	B3Sig           string           `json:"b3sig,omitempty"`
+	PQSignature     *PQSignature     `json:"pqSignature,omitempty"` // Detached post-quantum signature over CodeU7
 	Chip            string           `json:"chip"`
+	Archetype       *Archetype       `json:"archetype,omitempty"`
+	Percentiles     *Percentiles     `json:"percentiles,omitempty"`     // Cognition percentiles against norms, only when requested
+	WesternChart    *WesternChart    `json:"westernChart,omitempty"`    // Natal chart, when the Western profile is derived from birth info
 	ChineseProfile  *ChineseProfile  `json:"chineseProfile,omitempty"`  // NEW: Chinese BaZi profile
 	CombinedProfile *CombinedProfile `json:"combinedProfile,omitempty"` // NEW: Combined profiles
+	LegacyCodes     []VersionedCode  `json:"legacyCodes,omitempty"`     // Older code formats emitted during codec transitions
+	Metadata        *OutputMetadata  `json:"metadata,omitempty"`
+	Warnings        []string         `json:"warnings,omitempty"` // Non-fatal issues detected during generation
+	Trace           *Trace           `json:"trace,omitempty"`    // Intermediate artifacts, only when requested
+}
+
+// OutputMetadata carries optional information about how and when the codes were issued
+type OutputMetadata struct {
+	IssuedAt   string `json:"issuedAt,omitempty"`   // RFC3339 UTC timestamp
+	ValidUntil string `json:"validUntil,omitempty"` // RFC3339 UTC timestamp after which the codes are considered stale
+
+	FusionConfig string `json:"fusionConfig,omitempty"` // fusion weight configuration explicitly selected for this output
+
+	CHIPHardening *CHIPHardening `json:"chipHardening,omitempty"` // Argon2id cost, when the CHIP is hardened
+
+	Quality *Quality `json:"quality,omitempty"` // input confidence score, when requested
+
+	Lineage string `json:"lineage,omitempty"` // LineageHash of the previous CHIP, when regenerated from it
+}
+
+// metadata returns the output metadata, creating it on first use
+func (o *OutputHCS) metadata() *OutputMetadata {
+	if o.Metadata == nil {
+		o.Metadata = &OutputMetadata{}
+	}
+	return o.Metadata
 }
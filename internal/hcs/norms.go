@@ -0,0 +1,156 @@
+package hcs
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// NormsDimensions are the cognition values norms rank, named as in CognitionProfile
+var NormsDimensions = []string{"fluid", "crystallized", "verbal", "strategic", "creative"}
+
+// ReferenceNormsID identifies the built-in reference norms
+const ReferenceNormsID = "reference-2024.1"
+
+// normsPoints is the number of quantiles computed per dimension: every fifth percentile
+const normsPoints = 21
+
+// Norms are reference distributions of the cognition values. Each dimension
+// holds its values at evenly spaced percentiles, from the 0th to the 100th.
+type Norms struct {
+	ID         string               `json:"id"`
+	Sample     int                  `json:"sample,omitempty"`     // profiles the quantiles were computed from; 0 for the reference norms
+	ComputedAt string               `json:"computedAt,omitempty"` // RFC3339 UTC timestamp of norms computed from stored profiles
+	Quantiles  map[string][]float64 `json:"quantiles"`
+}
+
+// Percentiles ranks the cognition values of a profile against norms
+type Percentiles struct {
+	Norms string `json:"norms"` // ID of the norms
+	// Cognition holds the share of the norms' profiles below each value, 0-100
+	Cognition map[string]int `json:"cognition"`
+}
+
+// referenceNorms approximate normal distributions of the cognition values
+// around the means observed in early deployments. Published norms are never
+// edited: changes go in a new ID, so annotated outputs stay comparable.
+var referenceNorms = &Norms{
+	ID: ReferenceNormsID,
+	Quantiles: map[string][]float64{
+		"fluid":        {0, 0.27, 0.33, 0.37, 0.41, 0.44, 0.46, 0.48, 0.51, 0.53, 0.55, 0.57, 0.59, 0.62, 0.64, 0.66, 0.69, 0.73, 0.77, 0.83, 1},
+		"crystallized": {0, 0.32, 0.37, 0.41, 0.45, 0.47, 0.5, 0.52, 0.54, 0.56, 0.58, 0.6, 0.62, 0.64, 0.66, 0.69, 0.71, 0.75, 0.79, 0.84, 1},
+		"verbal":       {0, 0.26, 0.33, 0.37, 0.41, 0.44, 0.47, 0.49, 0.51, 0.54, 0.56, 0.58, 0.61, 0.63, 0.65, 0.68, 0.71, 0.75, 0.79, 0.86, 1},
+		"strategic":    {0, 0.24, 0.3, 0.34, 0.38, 0.41, 0.43, 0.45, 0.48, 0.5, 0.52, 0.54, 0.56, 0.59, 0.61, 0.63, 0.66, 0.7, 0.74, 0.8, 1},
+		"creative":     {0, 0.23, 0.3, 0.34, 0.38, 0.41, 0.44, 0.47, 0.49, 0.52, 0.54, 0.56, 0.59, 0.61, 0.64, 0.67, 0.7, 0.74, 0.78, 0.85, 1},
+	},
+}
+
+// ReferenceNorms returns the built-in reference norms
+func ReferenceNorms() *Norms {
+	return referenceNorms
+}
+
+// cognitionValues returns the values of c by NormsDimensions name
+func cognitionValues(c CognitionProfile) map[string]float64 {
+	return map[string]float64{
+		"fluid":        c.Fluid,
+		"crystallized": c.Crystallized,
+		"verbal":       c.Verbal,
+		"strategic":    c.Strategic,
+		"creative":     c.Creative,
+	}
+}
+
+// Validate checks that every dimension has at least two quantiles, in the
+// 0-1 range and in ascending order
+func (n *Norms) Validate() error {
+	if n.ID == "" {
+		return fmt.Errorf("norms need an ID")
+	}
+	for _, dim := range NormsDimensions {
+		q := n.Quantiles[dim]
+		if len(q) < 2 {
+			return fmt.Errorf("norms %s: %s needs at least 2 quantiles, got %d", n.ID, dim, len(q))
+		}
+		for i, v := range q {
+			if v < 0 || v > 1 || math.IsNaN(v) {
+				return fmt.Errorf("norms %s: %s quantile %d is out of the 0-1 range: %g", n.ID, dim, i, v)
+			}
+			if i > 0 && v < q[i-1] {
+				return fmt.Errorf("norms %s: %s quantiles must be ascending", n.ID, dim)
+			}
+		}
+	}
+	if len(n.Quantiles) != len(NormsDimensions) {
+		return fmt.Errorf("norms %s: unknown dimension (expected %v)", n.ID, NormsDimensions)
+	}
+	return nil
+}
+
+// Percentile returns the share of the norms' profiles below value in
+// dimension, from 0 to 100. Values equal to a run of quantiles rank in the
+// middle of it.
+func (n *Norms) Percentile(dimension string, value float64) (int, error) {
+	q, ok := n.Quantiles[dimension]
+	if !ok {
+		return 0, fmt.Errorf("unknown norms dimension %q", dimension)
+	}
+	step := 100 / float64(len(q)-1)
+	lo := sort.SearchFloat64s(q, value)                                     // first quantile >= value
+	hi := sort.Search(len(q), func(i int) bool { return q[i] > value }) - 1 // last quantile <= value
+	var rank float64
+	switch {
+	case hi < 0:
+		rank = 0
+	case lo == len(q):
+		rank = 100
+	case lo <= hi:
+		rank = float64(lo+hi) / 2 * step
+	default:
+		rank = (float64(hi) + (value-q[hi])/(q[lo]-q[hi])) * step
+	}
+	return int(math.Round(rank)), nil
+}
+
+// Rank returns the percentiles of the cognition values of c
+func (n *Norms) Rank(c CognitionProfile) *Percentiles {
+	p := &Percentiles{Norms: n.ID, Cognition: make(map[string]int, len(NormsDimensions))}
+	for dim, v := range cognitionValues(c) {
+		p.Cognition[dim], _ = n.Percentile(dim, v)
+	}
+	return p
+}
+
+// NormsFromProfiles computes norms from the cognition values of profiles, such
+// as a tenant's stored ones. It needs at least two profiles; too small a
+// sample gives unstable percentiles, so callers set their own minimum.
+func NormsFromProfiles(id string, profiles []CognitionProfile) (*Norms, error) {
+	if len(profiles) < 2 {
+		return nil, fmt.Errorf("norms need at least 2 profiles, got %d", len(profiles))
+	}
+	n := &Norms{ID: id, Sample: len(profiles), Quantiles: make(map[string][]float64, len(NormsDimensions))}
+	values := make(map[string][]float64, len(NormsDimensions))
+	for _, c := range profiles {
+		for dim, v := range cognitionValues(c) {
+			values[dim] = append(values[dim], math.Min(math.Max(v, 0), 1))
+		}
+	}
+	for dim, v := range values {
+		sort.Float64s(v)
+		q := make([]float64, normsPoints)
+		for i := range q {
+			pos := float64(i) / (normsPoints - 1) * float64(len(v)-1)
+			below := int(pos)
+			value := v[below]
+			if below < len(v)-1 {
+				value += (pos - float64(below)) * (v[below+1] - v[below])
+			}
+			q[i] = math.Round(value*10000) / 10000
+		}
+		n.Quantiles[dim] = q
+	}
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
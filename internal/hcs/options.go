@@ -0,0 +1,176 @@
+package hcs
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+)
+
+// Option configures a Generator created by NewGenerator
+type Option func(*generatorSettings) error
+
+// generatorSettings collects the options before the Generator is built
+type generatorSettings struct {
+	salt           SaltProvider // nil for the salt file in saltDir
+	saltDir        string
+	migrateSeal    bool
+	existingSalt   bool
+	secrets        SecretProvider
+	signer         Signer
+	engineVersion  string
+	fusionConfigID string
+	cacheSize      int
+	logger         *slog.Logger
+	pqSigner       PQSigner
+	chipHardening  *CHIPHardening
+	clock          clock.Clock
+}
+
+// WithSaltDir loads (or creates) the persistent salt in dir. The default is
+// the current directory.
+func WithSaltDir(dir string) Option {
+	return func(s *generatorSettings) error {
+		s.saltDir = dir
+		s.salt = nil // sealed with the secret provider in NewGenerator
+		return nil
+	}
+}
+
+// WithSaltSealMigration seals the salts in the salt directory that have no
+// seal for the secret key, instead of refusing them (see DirSaltProvider)
+func WithSaltSealMigration() Option {
+	return func(s *generatorSettings) error {
+		s.migrateSeal = true
+		return nil
+	}
+}
+
+// WithExistingSalt fails NewGenerator when the salt directory has no salt
+// instead of creating one (see DirSaltProvider)
+func WithExistingSalt() Option {
+	return func(s *generatorSettings) error {
+		s.existingSalt = true
+		return nil
+	}
+}
+
+// WithSaltProvider supplies the persistent salt from p
+func WithSaltProvider(p SaltProvider) Option {
+	return func(s *generatorSettings) error {
+		if p == nil {
+			return fmt.Errorf("salt provider cannot be nil")
+		}
+		s.salt = p
+		return nil
+	}
+}
+
+// WithSecretProvider supplies the U7 signing key from p. The default reads
+// HCS_SECRET_KEY from the environment.
+func WithSecretProvider(p SecretProvider) Option {
+	return func(s *generatorSettings) error {
+		if p == nil {
+			return fmt.Errorf("secret provider cannot be nil")
+		}
+		s.secrets = p
+		return nil
+	}
+}
+
+// WithSigner delegates U7 signing to signer, e.g. a remote signing service,
+// instead of signing in process with the secret provider's key
+func WithSigner(signer Signer) Option {
+	return func(s *generatorSettings) error {
+		if signer == nil {
+			return fmt.Errorf("signer cannot be nil")
+		}
+		s.signer = signer
+		return nil
+	}
+}
+
+// WithEngineVersion sets the engine used when GeneratorOptions.EngineVersion is empty
+func WithEngineVersion(version string) Option {
+	return func(s *generatorSettings) error {
+		v, err := ResolveEngineVersion(version)
+		if err != nil {
+			return err
+		}
+		s.engineVersion = v
+		return nil
+	}
+}
+
+// WithFusionConfig sets the fusion config used when GeneratorOptions.FusionConfigID is empty
+func WithFusionConfig(id string) Option {
+	return func(s *generatorSettings) error {
+		if _, err := LookupFusionConfig(id); err != nil {
+			return err
+		}
+		s.fusionConfigID = id
+		return nil
+	}
+}
+
+// WithCache keeps up to size computed Chinese profiles in memory, so repeated
+// birth data skips the BaZi computation. Zero disables the cache.
+func WithCache(size int) Option {
+	return func(s *generatorSettings) error {
+		if size < 0 {
+			return fmt.Errorf("cache size must not be negative, got %d", size)
+		}
+		s.cacheSize = size
+		return nil
+	}
+}
+
+// WithLogger sends generation warnings and debug traces to logger instead of
+// slog.Default(). A logger attached with ContextWithLogger takes precedence.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *generatorSettings) error {
+		if logger == nil {
+			return fmt.Errorf("logger cannot be nil")
+		}
+		s.logger = logger
+		return nil
+	}
+}
+
+// WithPQSigner enables GeneratorOptions.PostQuantum with signer
+func WithPQSigner(signer PQSigner) Option {
+	return func(s *generatorSettings) error {
+		if signer == nil {
+			return fmt.Errorf("post-quantum signer cannot be nil")
+		}
+		s.pqSigner = signer
+		return nil
+	}
+}
+
+// WithHardenedCHIP computes CHIPs with Argon2id at cost h instead of SHA256.
+// The cost is recorded in each output's metadata; changing it changes every CHIP.
+func WithHardenedCHIP(h CHIPHardening) Option {
+	return func(s *generatorSettings) error {
+		if err := checkFIPSApproved("Argon2id CHIP hardening", false); err != nil {
+			return err
+		}
+		if err := h.Validate(); err != nil {
+			return err
+		}
+		s.chipHardening = &h
+		return nil
+	}
+}
+
+// WithClock reads the issuance time from c instead of the system clock, so
+// tests and replays can freeze it
+func WithClock(c clock.Clock) Option {
+	return func(s *generatorSettings) error {
+		if c == nil {
+			return fmt.Errorf("clock cannot be nil")
+		}
+		s.clock = c
+		return nil
+	}
+}
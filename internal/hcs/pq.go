@@ -0,0 +1,122 @@
+package hcs
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/cloudflare/circl/sign/mldsa/mldsa65"
+)
+
+// PQAlgorithmMLDSA65 is the ALG token of ML-DSA-65 (FIPS 204) detached signatures
+const PQAlgorithmMLDSA65 = "MLDSA65"
+
+// pqContext domain-separates HCS signatures from other uses of the same key
+var pqContext = []byte("hcs-u7")
+
+// PQSignature is a detached post-quantum signature over the HCS-U7 code text.
+// The code references it through its "PQ:<keyId>" segment.
+type PQSignature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"keyId"`
+	Signature []byte `json:"signature"` // base64 in JSON
+}
+
+// PQPublicKey is a published verification key
+type PQPublicKey struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"keyId"`
+	PublicKey []byte `json:"publicKey"` // base64 in JSON
+}
+
+// PQSigner produces detached post-quantum signatures. Unlike QSIG and B3, which
+// are symmetric HMAC/hash constructions, these can be verified by anyone
+// holding the published public key.
+type PQSigner interface {
+	PublicKey() PQPublicKey
+	Sign(msg []byte) ([]byte, error)
+}
+
+// MLDSASigner signs with an ML-DSA-65 key
+type MLDSASigner struct {
+	key    *mldsa65.PrivateKey
+	public PQPublicKey
+}
+
+// NewMLDSASigner derives an ML-DSA-65 key pair from a 32-byte seed
+func NewMLDSASigner(seed []byte) (*MLDSASigner, error) {
+	if err := checkFIPSApproved("ML-DSA-65 (circl, not a validated module)", false); err != nil {
+		return nil, err
+	}
+	if len(seed) != mldsa65.SeedSize {
+		return nil, fmt.Errorf("ML-DSA seed must be %d bytes, got %d", mldsa65.SeedSize, len(seed))
+	}
+	var s [mldsa65.SeedSize]byte
+	copy(s[:], seed)
+	pk, sk := mldsa65.NewKeyFromSeed(&s)
+
+	pub := pk.Bytes()
+	return &MLDSASigner{
+		key: sk,
+		public: PQPublicKey{
+			Algorithm: PQAlgorithmMLDSA65,
+			KeyID:     pqKeyID(pub),
+			PublicKey: pub,
+		},
+	}, nil
+}
+
+// PublicKey returns the verification key to publish
+func (s *MLDSASigner) PublicKey() PQPublicKey {
+	return s.public
+}
+
+// Sign produces a deterministic ML-DSA-65 signature over msg
+func (s *MLDSASigner) Sign(msg []byte) ([]byte, error) {
+	sig := make([]byte, mldsa65.SignatureSize)
+	if err := mldsa65.SignTo(s.key, msg, pqContext, false, sig); err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+	return sig, nil
+}
+
+// pqKeyID identifies a public key by the first 8 bytes of its SHA256
+func pqKeyID(publicKey []byte) string {
+	return hex.EncodeToString(hashOf(HashSHA256, publicKey)[:8])
+}
+
+// attachPQSegment declares the post-quantum algorithm in the ALG segment of a
+// U7 code and appends the segment referencing the signing key
+func attachPQSegment(code string, key PQPublicKey) string {
+	code = rewriteALG(code, func(alg string) string {
+		return alg + "+" + key.Algorithm
+	})
+	return code + "|PQ:" + key.KeyID
+}
+
+// VerifyPQSignature checks a detached signature against an HCS-U7 code and
+// the published key the code references
+func VerifyPQSignature(code string, sig *PQSignature, key PQPublicKey) error {
+	if sig == nil {
+		return fmt.Errorf("missing post-quantum signature")
+	}
+	if !strings.HasSuffix(code, "|PQ:"+key.KeyID) || sig.KeyID != key.KeyID {
+		return fmt.Errorf("code does not reference key %s", key.KeyID)
+	}
+	if sig.Algorithm != PQAlgorithmMLDSA65 || key.Algorithm != PQAlgorithmMLDSA65 ||
+		!strings.Contains(code, "+"+PQAlgorithmMLDSA65+"|") {
+		return fmt.Errorf("unsupported post-quantum algorithm: %s", sig.Algorithm)
+	}
+	if pqKeyID(key.PublicKey) != key.KeyID {
+		return fmt.Errorf("public key does not match key ID %s", key.KeyID)
+	}
+
+	var pk mldsa65.PublicKey
+	if err := pk.UnmarshalBinary(key.PublicKey); err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+	if !mldsa65.Verify(&pk, []byte(code), pqContext, sig.Signature) {
+		return fmt.Errorf("post-quantum signature verification failed")
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package hcs
+
+import (
+	"fmt"
+	"math"
+)
+
+// Quality is a 0-1 confidence score for an input profile, with the reasons it
+// was lowered. It flags inputs that look like untouched form defaults or
+// guesses, so consumers can weight how much to trust a code.
+type Quality struct {
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// qualityRule lowers the score by penalty when check reports a reason
+type qualityRule struct {
+	penalty float64
+	check   func(in *InputProfile) (string, bool)
+}
+
+// qualityRules are evaluated in order; their reasons appear in the same order
+var qualityRules = []qualityRule{
+	{0.4, func(in *InputProfile) (string, bool) {
+		c := in.Cognition
+		if c.Fluid == 0.5 && c.Crystallized == 0.5 && c.Verbal == 0.5 && c.Strategic == 0.5 && c.Creative == 0.5 {
+			return "all cognition values are 0.5, the usual untouched default", true
+		}
+		return "", false
+	}},
+	{0.2, func(in *InputProfile) (string, bool) {
+		c := in.Cognition
+		if c.Fluid != 0.5 && c.Fluid == c.Crystallized && c.Fluid == c.Verbal && c.Fluid == c.Strategic && c.Fluid == c.Creative {
+			return fmt.Sprintf("all cognition values are %g", c.Fluid), true
+		}
+		return "", false
+	}},
+	{0.1, func(in *InputProfile) (string, bool) {
+		m := in.Modal
+		if m.Cardinal == m.Fixed && m.Fixed == m.Mutable {
+			return "modal values are all equal", true
+		}
+		return "", false
+	}},
+	{0.1, func(in *InputProfile) (string, bool) {
+		m := in.Modal
+		if sum := m.Cardinal + m.Fixed + m.Mutable; math.Abs(sum-1) > DefaultModalSumTolerance {
+			return fmt.Sprintf("modal values sum to %.4g instead of 1", sum), true
+		}
+		return "", false
+	}},
+	{0.05, func(in *InputProfile) (string, bool) {
+		i := in.Interaction
+		if i.Pace == "" || i.Structure == "" || i.Tone == "" {
+			return "interaction preferences are incomplete and were defaulted", true
+		}
+		return "", false
+	}},
+	{0.15, func(in *InputProfile) (string, bool) {
+		if b := in.BirthInfo; b != nil && b.Hour == 12 && b.Minute == 0 {
+			return "birth time is exactly noon, a common placeholder for an unknown time", true
+		}
+		return "", false
+	}},
+	{0.1, func(in *InputProfile) (string, bool) {
+		if b := in.BirthInfo; b != nil && b.Minute == 0 {
+			return "birth minute is 0, which often means it was unknown", true
+		}
+		return "", false
+	}},
+	{0.1, func(in *InputProfile) (string, bool) {
+		if b := in.BirthInfo; b != nil {
+			switch b.Timezone {
+			case "", "UTC", "GMT", "Etc/UTC", "Etc/GMT":
+				return "timezone is UTC, which is often a guess rather than the birth location", true
+			}
+		}
+		return "", false
+	}},
+}
+
+// ScoreQuality scores an input profile as received, before defaults are
+// applied. A profile that triggers no rule scores 1.
+func ScoreQuality(in *InputProfile) Quality {
+	q := Quality{Score: 1}
+	for _, rule := range qualityRules {
+		if reason, ok := rule.check(in); ok {
+			q.Score -= rule.penalty
+			q.Reasons = append(q.Reasons, reason)
+		}
+	}
+	q.Score = math.Round(math.Max(q.Score, 0)*100) / 100
+	return q
+}
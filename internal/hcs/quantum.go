@@ -1,49 +1,141 @@
 package hcs
 
 import (
-	"crypto/hmac"
 	"encoding/hex"
 	"fmt"
+	"io"
 
-	"github.com/zeebo/blake3"
-	"golang.org/x/crypto/sha3"
+	"golang.org/x/crypto/hkdf"
 )
 
+// KeyDerivation selects how the signing keys are derived from the secret and salt
+type KeyDerivation string
+
+const (
+	// KeyDerivationLegacy derives a single HMAC-SHA3-256(secret, salt) key shared
+	// by QSIG and B3. It only verifies existing codes; new codes cannot use it.
+	KeyDerivationLegacy KeyDerivation = "legacy"
+	// KeyDerivationHKDF derives a separate key per purpose with HKDF-SHA3-256.
+	// It is the default, and codes signed this way declare it as "ALG:QS-HKDF".
+	KeyDerivationHKDF KeyDerivation = "hkdf"
+)
+
+// HKDF info strings, one per key purpose
+const (
+	PurposeU7QSig      = "u7-qsig"
+	PurposeU7B3        = "u7-b3"
+	PurposeSaltMAC     = "salt-mac"     // seals the salt file
+	PurposeDisplayCode = "display-code" // short-lived display codes
+	PurposeEnvelope    = "envelope"     // signed output envelopes
+	PurposeLedger      = "ledger"       // MACs of hcsgen ledger entries
+	PurposeRevocations = "revocations"  // signed revocation lists
+)
+
+// ResolveKeyDerivation returns the derivation new codes are signed with:
+// KeyDerivationHKDF, also for an empty derivation. It rejects
+// KeyDerivationLegacy, which only verifies existing codes, and unknown ones.
+func ResolveKeyDerivation(derivation KeyDerivation) (KeyDerivation, error) {
+	switch derivation {
+	case "", KeyDerivationHKDF:
+		return KeyDerivationHKDF, nil
+	case KeyDerivationLegacy:
+		return "", fmt.Errorf("the legacy key derivation only verifies existing codes; new codes use %s", KeyDerivationHKDF)
+	}
+	return "", fmt.Errorf("unknown key derivation: %s", derivation)
+}
+
+// DeriveKey derives the 32-byte key for one purpose from the master secret,
+// using the salt as HKDF salt and the purpose as info
+func DeriveKey(secret, salt []byte, purpose string) ([]byte, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("secret key must not be empty")
+	}
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(builtinHash(HashSHA3256).New, secret, salt, []byte(purpose)), key); err != nil {
+		return nil, fmt.Errorf("failed to derive %s key: %w", purpose, err)
+	}
+	return key, nil
+}
+
 // ComputeQuantumSignatures computes the primary HMAC-SHA3-256 based signature (QSIG)
 // and a secondary BLAKE3 digest over the canonical profile data. The design is
 // intentionally conservative: 256-bit symmetric hashes remain extremely strong
 // even under generic quantum attacks such as Grover's algorithm.
 func ComputeQuantumSignatures(canonical []byte, secret []byte, salt []byte) (qsigHex string, b3Hex string, err error) {
+	return ComputeQuantumSignaturesWithDerivation(canonical, secret, salt, KeyDerivationLegacy)
+}
+
+// ComputeQuantumSignaturesWithDerivation is ComputeQuantumSignatures with a
+// selectable key derivation
+func ComputeQuantumSignaturesWithDerivation(canonical, secret, salt []byte, derivation KeyDerivation) (qsigHex string, b3Hex string, err error) {
+	return ComputeQuantumSignaturesWithDigest(canonical, secret, salt, derivation, DigestBLAKE3)
+}
+
+// ComputeQuantumSignaturesWithDigest is ComputeQuantumSignaturesWithDerivation
+// with a selectable secondary digest. An empty digest is BLAKE3.
+func ComputeQuantumSignaturesWithDigest(canonical, secret, salt []byte, derivation KeyDerivation, digest SecondaryDigest) (qsigHex string, b3Hex string, err error) {
 	if len(secret) == 0 {
 		return "", "", fmt.Errorf("secret key must not be empty")
 	}
 
+	switch derivation {
+	case KeyDerivationLegacy, "":
+		return legacySignatures(canonical, secret, salt, digest)
+	case KeyDerivationHKDF:
+		return hkdfSignatures(canonical, secret, salt, digest)
+	}
+	return "", "", fmt.Errorf("unknown key derivation: %s", derivation)
+}
+
+// legacySignatures derives one key for both signatures, as codes issued before
+// HKDF derivation were signed
+func legacySignatures(canonical, secret, salt []byte, digest SecondaryDigest) (qsigHex string, b3Hex string, err error) {
 	// Derive a per-instance key from the master secret and salt using HMAC-SHA3-256.
 	// salt is treated as public diversification material; the secret remains private.
-	h := hmac.New(sha3.New256, secret)
-	if _, err = h.Write(salt); err != nil {
+	derivedKey, err := macOf(HashSHA3256, secret, salt)
+	if err != nil {
 		return "", "", fmt.Errorf("failed to derive key: %w", err)
 	}
-	derivedKey := h.Sum(nil)
 
 	// Primary quantum-style signature: HMAC-SHA3-256 over canonical profile.
-	h2 := hmac.New(sha3.New256, derivedKey)
-	if _, err = h2.Write(canonical); err != nil {
+	qsig, err := macOf(HashSHA3256, derivedKey, canonical)
+	if err != nil {
 		return "", "", fmt.Errorf("failed to compute primary signature: %w", err)
 	}
-	qsig := h2.Sum(nil)
 	qsigHex = hex.EncodeToString(qsig)
 
-	// Secondary digest: BLAKE3 over canonical profile (optionally salted via derived key).
-	b3 := blake3.New()
-	if _, err = b3.Write(derivedKey); err != nil {
-		return "", "", fmt.Errorf("failed to update BLAKE3 with key: %w", err)
+	// Secondary digest over canonical profile, salted via the derived key.
+	b3Hex, err = secondaryDigest(digest, KeyDerivationLegacy, derivedKey, canonical)
+	if err != nil {
+		return "", "", err
 	}
-	if _, err = b3.Write(canonical); err != nil {
-		return "", "", fmt.Errorf("failed to update BLAKE3 with canonical: %w", err)
+
+	return qsigHex, b3Hex, nil
+}
+
+// hkdfSignatures signs with independent QSIG and B3 keys, so neither signature
+// reveals anything about the key of the other
+func hkdfSignatures(canonical, secret, salt []byte, digest SecondaryDigest) (qsigHex string, b3Hex string, err error) {
+	qsigKey, err := DeriveKey(secret, salt, PurposeU7QSig)
+	if err != nil {
+		return "", "", err
+	}
+	b3Key, err := DeriveKey(secret, salt, PurposeU7B3)
+	if err != nil {
+		return "", "", err
+	}
+
+	qsig, err := macOf(HashSHA3256, qsigKey, canonical)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to compute primary signature: %w", err)
+	}
+	qsigHex = hex.EncodeToString(qsig)
+
+	// Secondary digest in keyed mode
+	b3Hex, err = secondaryDigest(digest, KeyDerivationHKDF, b3Key, canonical)
+	if err != nil {
+		return "", "", err
 	}
-	b3sum := b3.Sum(nil)
-	b3Hex = hex.EncodeToString(b3sum)
 
 	return qsigHex, b3Hex, nil
 }
@@ -0,0 +1,83 @@
+package hcs
+
+import (
+	"math"
+	"sort"
+)
+
+// FusionDrift describes how the fusion profile moved between two generations.
+// Differences are current minus previous.
+type FusionDrift struct {
+	PreviousFusionID  string             `json:"previousFusionId"`
+	CurrentFusionID   string             `json:"currentFusionId"`
+	ElementSignature  map[string]float64 `json:"elementSignature"`
+	MaxElementShift   float64            `json:"maxElementShift"` // largest absolute element change
+	HarmonicResonance float64            `json:"harmonicResonance"`
+	UnifiedBalance    float64            `json:"unifiedBalance"`
+}
+
+// RetestReport compares two generations for the same subject. Only normalized
+// values are compared, since they are what the codes encode, and a move of one
+// percentage point may be rounding alone, so it is not a change. A CHIP that
+// changed while no field moved means the values moved within rounding, or the
+// salt epoch or CHIP hardening differs between the generations.
+type RetestReport struct {
+	PreviousChip    string        `json:"previousChip"`
+	CurrentChip     string        `json:"currentChip"`
+	ChipChanged     bool          `json:"chipChanged"`
+	Stable          bool          `json:"stable"` // no normalized field moved beyond rounding
+	Changes         []FieldChange `json:"changes"`
+	ChangedSegments []string      `json:"changedSegments,omitempty"` // U3 segments that differ, see DiffCodeSegmentsTolerant
+	Fusion          *FusionDrift  `json:"fusion,omitempty"`          // only when both generations have a fusion profile
+}
+
+// CompareRetest reports which normalized fields moved between a subject's
+// previous and current generations, and how the fusion profile drifted
+func CompareRetest(prev, curr *OutputHCS) RetestReport {
+	p := NormalizeProfile(&prev.Input)
+	c := NormalizeProfile(&curr.Input)
+
+	report := RetestReport{
+		PreviousChip:    prev.Chip,
+		CurrentChip:     curr.Chip,
+		ChipChanged:     prev.Chip != curr.Chip,
+		Changes:         CompareNormalized(p, c),
+		ChangedSegments: DiffCodeSegmentsTolerant(prev.CodeU3, curr.CodeU3),
+	}
+	report.Stable = len(report.Changes) == 0
+
+	if prev.CombinedProfile != nil && curr.CombinedProfile != nil {
+		report.Fusion = fusionDrift(&prev.CombinedProfile.Fusion, &curr.CombinedProfile.Fusion)
+	}
+	return report
+}
+
+// fusionDrift compares two fusion profiles element by element, in sorted order
+// so the result does not depend on map iteration
+func fusionDrift(prev, curr *FusionProfile) *FusionDrift {
+	drift := &FusionDrift{
+		PreviousFusionID:  prev.FusionID,
+		CurrentFusionID:   curr.FusionID,
+		ElementSignature:  map[string]float64{},
+		HarmonicResonance: round4(curr.HarmonicResonance - prev.HarmonicResonance),
+		UnifiedBalance:    round4(curr.UnifiedBalance - prev.UnifiedBalance),
+	}
+
+	var elements []string
+	for e := range prev.ElementSignature {
+		elements = append(elements, e)
+	}
+	for e := range curr.ElementSignature {
+		if _, ok := prev.ElementSignature[e]; !ok {
+			elements = append(elements, e)
+		}
+	}
+	sort.Strings(elements)
+
+	for _, e := range elements {
+		shift := round4(curr.ElementSignature[e] - prev.ElementSignature[e])
+		drift.ElementSignature[e] = shift
+		drift.MaxElementShift = math.Max(drift.MaxElementShift, math.Abs(shift))
+	}
+	return drift
+}
@@ -0,0 +1,158 @@
+package hcs
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+)
+
+// RevocationListVersion is the version of the revocation list format this build signs
+const RevocationListVersion = 1
+
+// revocationContext prefixes the signed bytes of a revocation list
+const revocationContext = "hcs-revocations/1\n"
+
+// ErrRevocationSignature is returned when a revocation list signature does
+// not match its contents
+var ErrRevocationSignature = errors.New("revocation list signature does not match its contents")
+
+// RevocationList is a signed list of revoked CHIPs, in the spirit of an X.509
+// CRL, for verifiers that check codes offline
+type RevocationList struct {
+	Version   int           `json:"version"`
+	Issuer    string        `json:"issuer,omitempty"`
+	IssuedAt  string        `json:"issuedAt"`            // RFC3339 UTC timestamp, set by SignRevocationList
+	SaltEpoch int           `json:"saltEpoch,omitempty"` // epoch of the salt the key is derived with
+	Entries   []RevokedCHIP `json:"entries"`
+	// Signature is an HMAC for holders of the secret key and, when post-quantum
+	// signing is configured, an ML-DSA signature anyone can verify
+	Signature *EnvelopeSignature `json:"signature"`
+}
+
+// RevokedCHIP is one entry of a revocation list. Every code carrying or
+// derived with the CHIP is revoked.
+type RevokedCHIP struct {
+	Chip      string `json:"chip"`
+	Reason    string `json:"reason,omitempty"`
+	RevokedAt string `json:"revokedAt"` // RFC3339 UTC timestamp
+}
+
+// SignRevocationList signs entries as a revocation list issued now
+func (g *Generator) SignRevocationList(entries []RevokedCHIP, issuer string) (*RevocationList, error) {
+	if entries == nil {
+		entries = []RevokedCHIP{}
+	}
+	list := &RevocationList{
+		Version:   RevocationListVersion,
+		Issuer:    issuer,
+		IssuedAt:  g.clock.Now().UTC().Format(time.RFC3339),
+		SaltEpoch: g.saltEpoch,
+		Entries:   entries,
+	}
+
+	msg, err := revocationMessage(list)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := g.documentMAC(list.SaltEpoch, PurposeRevocations, msg)
+	if err != nil {
+		return nil, err
+	}
+	list.Signature = &EnvelopeSignature{Algorithm: EnvelopeAlgorithm, Value: hex.EncodeToString(mac)}
+
+	if g.pqSigner != nil {
+		key := g.pqSigner.PublicKey()
+		sig, err := g.pqSigner.Sign(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute post-quantum revocation list signature: %w", err)
+		}
+		list.Signature.PQ = &PQSignature{Algorithm: key.Algorithm, KeyID: key.KeyID, Signature: sig}
+	}
+	return list, nil
+}
+
+// VerifyRevocationList checks the HMAC of a revocation list signed with this
+// generator's secret, under the salt of any of its epochs. It returns
+// ErrRevocationSignature when the list was altered.
+func (g *Generator) VerifyRevocationList(list *RevocationList) error {
+	if err := checkRevocationList(list); err != nil {
+		return err
+	}
+	if list.Signature.Algorithm != EnvelopeAlgorithm {
+		return errcode.Errorf(errcode.InvalidRevocationList, "unsupported revocation list algorithm: %s", list.Signature.Algorithm)
+	}
+	if _, ok := g.salts[list.SaltEpoch]; !ok {
+		return errcode.Errorf(errcode.InvalidRevocationList, "unknown salt epoch %d", list.SaltEpoch)
+	}
+
+	msg, err := revocationMessage(list)
+	if err != nil {
+		return err
+	}
+	want, err := g.documentMAC(list.SaltEpoch, PurposeRevocations, msg)
+	if err != nil {
+		return err
+	}
+	got, err := hex.DecodeString(list.Signature.Value)
+	if err != nil || subtle.ConstantTimeCompare(got, want) != 1 {
+		return ErrRevocationSignature
+	}
+	return nil
+}
+
+// VerifyRevocationListPQSignature checks the post-quantum signature of a
+// revocation list against a published key, without the secret key
+func VerifyRevocationListPQSignature(list *RevocationList, key PQPublicKey) error {
+	if err := checkRevocationList(list); err != nil {
+		return err
+	}
+	msg, err := revocationMessage(list)
+	if err != nil {
+		return err
+	}
+	return verifyDetachedPQ(msg, list.Signature.PQ, key, errcode.InvalidRevocationList, ErrRevocationSignature)
+}
+
+// Revoked returns the entry revoking chip, if any
+func (l *RevocationList) Revoked(chip string) (*RevokedCHIP, bool) {
+	for i := range l.Entries {
+		if l.Entries[i].Chip == chip {
+			return &l.Entries[i], true
+		}
+	}
+	return nil, false
+}
+
+// checkRevocationList rejects lists that cannot be verified at all
+func checkRevocationList(list *RevocationList) error {
+	switch {
+	case list == nil:
+		return errcode.Errorf(errcode.InvalidRevocationList, "no revocation list")
+	case list.Version != RevocationListVersion:
+		return errcode.Errorf(errcode.InvalidRevocationList, "unsupported revocation list version %d", list.Version)
+	case list.Signature == nil:
+		return errcode.Errorf(errcode.InvalidRevocationList, "revocation list is not signed")
+	}
+	return nil
+}
+
+// revocationMessage returns the signed bytes: the context, then the JSON of
+// everything but the signature
+func revocationMessage(list *RevocationList) ([]byte, error) {
+	body, err := json.Marshal(struct {
+		Version   int           `json:"version"`
+		Issuer    string        `json:"issuer,omitempty"`
+		IssuedAt  string        `json:"issuedAt"`
+		SaltEpoch int           `json:"saltEpoch,omitempty"`
+		Entries   []RevokedCHIP `json:"entries"`
+	}{list.Version, list.Issuer, list.IssuedAt, list.SaltEpoch, list.Entries})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode revocation list: %w", err)
+	}
+	return append([]byte(revocationContext), body...), nil
+}
@@ -1,45 +1,561 @@
 package hcs
 
 import (
+	"bufio"
+	"crypto/hmac"
 	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
 )
 
 const (
-	saltFileName = ".hcs_salt"
-	saltSize     = 32
+	saltFileName   = ".hcs_salt"
+	saltSealSuffix = ".mac"
+	saltSize       = 32
+
+	// A salt loaded without a seal is re-checked this many times, this far
+	// apart, before it is refused
+	saltSealWaits        = 10
+	saltSealWaitInterval = 50 * time.Millisecond
 )
 
-// LoadOrCreateSalt loads the salt from file or creates a new one if not exists
-func LoadOrCreateSalt(dir string) ([]byte, error) {
+// saltPath returns the salt file of an epoch: .hcs_salt for epoch 0 and
+// .hcs_salt.<epoch> for the salts created by rotations
+func saltPath(dir string, epoch int) string {
 	if dir == "" {
 		dir = "."
 	}
+	if epoch == 0 {
+		return filepath.Join(dir, saltFileName)
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s.%d", saltFileName, epoch))
+}
+
+// SaltFingerprint identifies a salt (first 8 bytes of its SHA256, hex) without revealing it
+func SaltFingerprint(salt []byte) string {
+	return hex.EncodeToString(hashOf(HashSHA256, salt)[:8])
+}
+
+// LoadOrCreateSalt loads the salt from file or creates a new one if not exists.
+// An existing salt file of the wrong size is an error, not silently replaced.
+func LoadOrCreateSalt(dir string) ([]byte, error) {
+	salt, _, err := loadOrCreateSalt(dir)
+	return salt, err
+}
+
+// saltOrigin tells how a salt was obtained, which decides whether it may be
+// sealed without an existing seal
+type saltOrigin int
+
+const (
+	saltLoaded  saltOrigin = iota // the salt file existed
+	saltCreated                   // this run created it
+	saltRaced                     // a concurrent run created it while this run tried to
+)
 
-	saltPath := filepath.Join(dir, saltFileName)
+// loadOrCreateSalt is LoadOrCreateSalt, also reporting how the salt was obtained
+func loadOrCreateSalt(dir string) ([]byte, saltOrigin, error) {
+	path := saltPath(dir, 0)
 
 	// Try to read existing salt
-	salt, err := os.ReadFile(saltPath)
-	if err == nil && len(salt) == saltSize {
-		return salt, nil
+	salt, err := readSaltFile(path)
+	if err == nil {
+		return salt, saltLoaded, nil
+	}
+
+	// Generate new salt if file doesn't exist
+	if os.IsNotExist(err) {
+		salt, err := newSaltFile(path)
+		if errors.Is(err, fs.ErrExist) {
+			// A concurrent run created it first: converge on its salt
+			salt, err := readSaltFile(path)
+			return salt, saltRaced, err
+		}
+		if err != nil {
+			return nil, saltLoaded, err
+		}
+		return salt, saltCreated, nil
+	}
+
+	return nil, saltLoaded, err
+}
+
+// readSaltFile reads one salt file, rejecting files of the wrong size
+func readSaltFile(path string) ([]byte, error) {
+	salt, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errcode.Errorf(errcode.SaltUnavailable, "failed to read salt: %w", err)
+	}
+	if len(salt) != saltSize {
+		return nil, errcode.Errorf(errcode.SaltUnavailable, "salt file %s is corrupted: expected %d bytes, got %d", path, saltSize, len(salt))
+	}
+	return salt, nil
+}
+
+// GenerateSalt returns a new random salt
+func GenerateSalt() ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return salt, nil
+}
+
+// newSaltFile generates a salt and writes it to path, which must not exist
+// yet. The salt is written to a temporary file first and then hard-linked to
+// path, so concurrent processes never see a partial salt and exactly one of
+// them creates it; the others get an error matching fs.ErrExist.
+func newSaltFile(path string) ([]byte, error) {
+	salt, err := GenerateSalt()
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := writeTempFile(path, salt)
+	if err != nil {
+		return nil, errcode.Errorf(errcode.SaltUnavailable, "failed to save salt: %w", err)
+	}
+	defer os.Remove(tmp)
+	if err := os.Link(tmp, path); err != nil {
+		return nil, errcode.Errorf(errcode.SaltUnavailable, "failed to save salt: %w", err)
+	}
+	return salt, nil
+}
+
+// writeTempFile writes data to a new temporary file next to path, synced to
+// disk, and returns its name
+func writeTempFile(path string, data []byte) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// rotatedSaltEpochs lists the epochs above 0 that have a salt file in dir, in ascending order
+func rotatedSaltEpochs(dir string) ([]int, error) {
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list salt epochs: %w", err)
+	}
+
+	var epochs []int
+	for _, entry := range entries {
+		suffix, ok := strings.CutPrefix(entry.Name(), saltFileName+".")
+		if !ok {
+			continue
+		}
+		epoch, err := strconv.Atoi(suffix)
+		if err != nil || epoch <= 0 || strconv.Itoa(epoch) != suffix {
+			continue // seal files and unrelated names
+		}
+		epochs = append(epochs, epoch)
+	}
+	sort.Ints(epochs)
+	return epochs, nil
+}
+
+// SaltFiles lists the salt files in dir and their seals, if any, by epoch:
+// .hcs_salt, .hcs_salt.mac, .hcs_salt.1, .hcs_salt.1.mac, ... Names are relative to dir.
+func SaltFiles(dir string) ([]string, error) {
+	epochs, err := rotatedSaltEpochs(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, epoch := range append([]int{0}, epochs...) {
+		path := saltPath(dir, epoch)
+		for _, name := range []string{path, path + saltSealSuffix} {
+			if _, err := os.Stat(name); err == nil {
+				files = append(files, filepath.Base(name))
+			} else if !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to list salt files: %w", err)
+			}
+		}
+	}
+	return files, nil
+}
+
+// CopySaltFiles copies the salt files of src, with every epoch and seal, into
+// dst, which must not have a salt yet. Each file appears complete or not at
+// all, and files a concurrent run copied first are left as they are. It
+// returns the names of the copied files; none when src has no salt.
+func CopySaltFiles(src, dst string) ([]string, error) {
+	if existing, err := SaltFiles(dst); err != nil || len(existing) > 0 {
+		if err == nil {
+			err = fmt.Errorf("%s already has salt files", dst)
+		}
+		return nil, err
+	}
+	files, err := SaltFiles(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var copied []string
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(src, name))
+		if err != nil {
+			return copied, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		path := filepath.Join(dst, name)
+		tmp, err := writeTempFile(path, data)
+		if err != nil {
+			return copied, fmt.Errorf("failed to copy %s: %w", name, err)
+		}
+		err = os.Link(tmp, path)
+		os.Remove(tmp)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return copied, fmt.Errorf("failed to copy %s: %w", name, err)
+		}
+		copied = append(copied, name)
+	}
+	return copied, nil
+}
+
+// SaltProvider supplies the persistent salt used for CHIP and U5 hashing
+type SaltProvider interface {
+	Salt() ([]byte, error)
+}
+
+// EpochSaltProvider is a SaltProvider that keeps the salts of earlier epochs
+// after rotations, so codes issued under them can still be verified. Salt
+// returns the salt of the current epoch.
+type EpochSaltProvider interface {
+	SaltProvider
+	// SaltEpochs returns the salt of every epoch and the current epoch
+	SaltEpochs() (map[int][]byte, int, error)
+}
+
+// DirSaltProvider loads (or creates) the salt files in Dir. When Secrets is
+// set and yields a key, each salt is sealed with an HMAC keyed by that secret
+// and verified on every load. An existing salt without a seal for the key is
+// refused unless another key of the keyring vouches for it, or MigrateSeal
+// is set to seal it once.
+type DirSaltProvider struct {
+	Dir     string
+	Secrets SecretProvider
+	// MigrateSeal seals existing salts that have no seal for the key, e.g.
+	// salts created before a secret key was configured
+	MigrateSeal bool
+	// Existing refuses to create the salt when Dir has none, for tools that
+	// must use the salt existing codes were issued with
+	Existing bool
+	// Logger receives the warning for salts left unsealed; nil uses slog.Default
+	Logger *slog.Logger
+}
+
+// Salt loads or creates the salt of the current epoch, checking its seal
+func (p DirSaltProvider) Salt() ([]byte, error) {
+	salts, current, err := p.SaltEpochs()
+	if err != nil {
+		return nil, err
+	}
+	return salts[current], nil
+}
+
+// SaltEpochs loads the salt of every epoch, creating epoch 0 if there is
+// none yet. The highest epoch is the current one.
+func (p DirSaltProvider) SaltEpochs() (map[int][]byte, int, error) {
+	salt, origin, err := p.loadSalt()
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := p.checkSeal(0, salt, origin); err != nil {
+		return nil, 0, err
+	}
+	salts := map[int][]byte{0: salt}
+	current := 0
+
+	epochs, err := rotatedSaltEpochs(p.Dir)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, epoch := range epochs {
+		salt, err := readSaltFile(saltPath(p.Dir, epoch))
+		if err != nil {
+			return nil, 0, err
+		}
+		if err := p.checkSeal(epoch, salt, saltLoaded); err != nil {
+			return nil, 0, err
+		}
+		salts[epoch] = salt
+		current = epoch
+	}
+	return salts, current, nil
+}
+
+// loadSalt loads the salt of epoch 0, creating it unless Existing is set
+func (p DirSaltProvider) loadSalt() ([]byte, saltOrigin, error) {
+	if !p.Existing {
+		return loadOrCreateSalt(p.Dir)
+	}
+	salt, err := readSaltFile(saltPath(p.Dir, 0))
+	if os.IsNotExist(err) {
+		return nil, saltLoaded, errcode.Errorf(errcode.SaltUnavailable, "no salt in %s", p.Dir)
+	}
+	return salt, saltLoaded, err
+}
+
+// Rotate creates the salt of the next epoch, which becomes the current one.
+// Earlier salts are kept so codes issued under them can still be verified.
+func (p DirSaltProvider) Rotate() (int, error) {
+	_, current, err := p.SaltEpochs()
+	if err != nil {
+		return 0, err
 	}
 
-	// Generate new salt if file doesn't exist or is invalid
-	if os.IsNotExist(err) || len(salt) != saltSize {
-		salt = make([]byte, saltSize)
-		if _, err := rand.Read(salt); err != nil {
-			return nil, fmt.Errorf("failed to generate salt: %w", err)
+	next := current + 1
+	salt, err := newSaltFile(saltPath(p.Dir, next))
+	if err != nil {
+		return 0, err
+	}
+	if err := p.checkSeal(next, salt, saltCreated); err != nil {
+		return 0, err
+	}
+	return next, nil
+}
+
+// checkSeal verifies the seal of an epoch's salt when a secret key is available
+func (p DirSaltProvider) checkSeal(epoch int, salt []byte, origin saltOrigin) error {
+	if p.Secrets == nil {
+		return nil
+	}
+	secret, err := p.Secrets.SecretKey()
+	if err != nil {
+		// Deployments without a secret key (no U7) cannot seal the salt
+		logger := p.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Warn("salt is not sealed: no secret key is configured, so changes to it go undetected",
+			"file", saltPath(p.Dir, epoch), "error", err)
+		return nil
+	}
+	var others [][]byte
+	if ring, ok := p.Secrets.(SecretKeyring); ok {
+		if keys, _, err := ring.SecretKeys(); err == nil {
+			for _, key := range keys {
+				others = append(others, key)
+			}
+		}
+	}
+	return checkSaltSeal(saltPath(p.Dir, epoch), salt, secret, others, origin, p.MigrateSeal)
+}
+
+// saltSealKey derives the salt MAC key from secret, and the ID the seal is recorded under
+func saltSealKey(secret []byte) ([]byte, string, error) {
+	key, err := DeriveKey(secret, nil, PurposeSaltMAC)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, hex.EncodeToString(hashOf(HashSHA256, key)[:8]), nil
+}
+
+// checkSaltSeal verifies a salt against the seal recorded for secret next to
+// its file. The seal file holds one "<keyId> <hmac>" line per key, so
+// generators with different keys can share a salt directory. A seal for
+// secret is only recorded for a salt just created, one whose seal by another
+// of the keys others verifies, or with migrate: a missing seal is otherwise
+// an error, so deleting the seal file or changing the key does not bypass
+// the check.
+func checkSaltSeal(saltFile string, salt, secret []byte, others [][]byte, origin saltOrigin, migrate bool) error {
+	id, mac, err := saltSeal(secret, salt)
+	if err != nil {
+		return err
+	}
+
+	sealPath := saltFile + saltSealSuffix
+	seals := map[string]string{}
+	for wait := 0; ; wait++ {
+		if origin != saltCreated {
+			if seals, err = readSaltSeals(sealPath); err != nil {
+				return err
+			}
+		}
+		if want, ok := seals[id]; ok {
+			if !hmac.Equal([]byte(want), []byte(mac)) {
+				return saltTampered(saltFile, sealPath)
+			}
+			return nil
+		}
+
+		vouched := false
+		for _, other := range others {
+			otherID, otherMAC, err := saltSeal(other, salt)
+			if err != nil {
+				return err
+			}
+			if want, ok := seals[otherID]; ok {
+				if !hmac.Equal([]byte(want), []byte(otherMAC)) {
+					return saltTampered(saltFile, sealPath)
+				}
+				vouched = true
+			}
+		}
+		if origin != saltLoaded || vouched || migrate {
+			break
+		}
+		// A concurrent run may have created the salt and not sealed it yet
+		if wait == saltSealWaits {
+			return errcode.Errorf(errcode.SaltUnsealed, "salt file %s has no seal for the secret key in %s; if the salt is genuine, "+
+				"seal it once with `hcsgen admin seal-salt` or HCS_SALT_SEAL_MIGRATE=on", saltFile, sealPath)
 		}
+		time.Sleep(saltSealWaitInterval)
+	}
+
+	seals[id] = mac
+	return writeSaltSeals(sealPath, seals)
+}
+
+// saltSeal returns the ID of the seal key of secret and the seal of salt
+func saltSeal(secret, salt []byte) (string, string, error) {
+	key, id, err := saltSealKey(secret)
+	if err != nil {
+		return "", "", err
+	}
+	sum, err := macOf(HashSHA256, key, salt)
+	if err != nil {
+		return "", "", err
+	}
+	return id, hex.EncodeToString(sum), nil
+}
+
+// saltTampered is the error of a salt that does not match its seal
+func saltTampered(saltFile, sealPath string) error {
+	return errcode.Errorf(errcode.SaltTampered, "salt file %s failed its integrity check: it changed since it was sealed in %s",
+		saltFile, sealPath)
+}
 
-		// Write salt to file
-		if err := os.WriteFile(saltPath, salt, 0600); err != nil {
-			return nil, fmt.Errorf("failed to save salt: %w", err)
+// readSaltSeals reads the seal file; a missing file has no seals
+func readSaltSeals(path string) (map[string]string, error) {
+	seals := map[string]string{}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return seals, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read salt seal: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		id, mac, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if !ok {
+			return nil, errcode.Errorf(errcode.SaltTampered, "salt seal file %s is corrupted", path)
 		}
+		seals[id] = mac
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read salt seal: %w", err)
+	}
+	return seals, nil
+}
 
-		return salt, nil
+// writeSaltSeals replaces the seal file with seals, in key ID order
+func writeSaltSeals(path string, seals map[string]string) error {
+	ids := make([]string, 0, len(seals))
+	for id := range seals {
+		ids = append(ids, id)
 	}
+	sort.Strings(ids)
 
-	return nil, fmt.Errorf("failed to read salt: %w", err)
+	var b strings.Builder
+	for _, id := range ids {
+		fmt.Fprintf(&b, "%s %s\n", id, seals[id])
+	}
+	// Replace the file in one step, so concurrent runs never read a partial seal
+	tmp, err := writeTempFile(path, []byte(b.String()))
+	if err != nil {
+		return fmt.Errorf("failed to save salt seal: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save salt seal: %w", err)
+	}
+	return nil
+}
+
+// EnvSaltProvider reads the salts from HCS_SALT, or from the file named by
+// HCS_SALT_FILE: the hex salt of every epoch, comma-separated in epoch order,
+// so the last one is current. It never writes to disk, for containers without
+// a persistent volume; a missing salt is an error, not generated.
+type EnvSaltProvider struct{}
+
+// Salt returns the salt of the current epoch
+func (p EnvSaltProvider) Salt() ([]byte, error) {
+	salts, epoch, err := p.SaltEpochs()
+	if err != nil {
+		return nil, err
+	}
+	return salts[epoch], nil
+}
+
+// SaltEpochs decodes the salt of every epoch
+func (EnvSaltProvider) SaltEpochs() (map[int][]byte, int, error) {
+	value := os.Getenv("HCS_SALT")
+	if path := os.Getenv("HCS_SALT_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, 0, errcode.Errorf(errcode.SaltUnavailable, "failed to read HCS_SALT_FILE: %w", err)
+		}
+		value = string(data)
+	}
+	if strings.TrimSpace(value) == "" {
+		return nil, 0, errcode.Errorf(errcode.SaltUnavailable, "HCS_SALT is not set")
+	}
+	salts, current, err := ParseSalts(value)
+	if err != nil {
+		return nil, 0, errcode.Errorf(errcode.SaltUnavailable, "invalid HCS_SALT: %w", err)
+	}
+	return salts, current, nil
+}
+
+// ParseSalts decodes the salts of every epoch from the hex salt of each,
+// comma-separated in epoch order, and returns them with the current epoch:
+// the last one. Salt stores other than the salt directory hold this format.
+func ParseSalts(value string) (map[int][]byte, int, error) {
+	salts := make(map[int][]byte)
+	for epoch, part := range strings.Split(value, ",") {
+		salt, err := hex.DecodeString(strings.TrimSpace(part))
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid hex encoding for epoch %d: %w", epoch, err)
+		}
+		if len(salt) != saltSize {
+			return nil, 0, fmt.Errorf("epoch %d has %d bytes, expected %d", epoch, len(salt), saltSize)
+		}
+		salts[epoch] = salt
+	}
+	return salts, len(salts) - 1, nil
 }
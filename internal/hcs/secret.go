@@ -2,40 +2,208 @@ package hcs
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
-)
 
-var (
-	secretKeyOnce sync.Once
-	secretKey     []byte
-	secretKeyErr  error
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
 )
 
-// LoadSecretKey loads and caches the HCS secret key from the HCS_SECRET_KEY environment variable.
-// The key must be a hex string representing either 32 or 64 bytes. The raw key material is never logged.
-func LoadSecretKey() ([]byte, error) {
-	secretKeyOnce.Do(func() {
-		value := os.Getenv("HCS_SECRET_KEY")
-		if value == "" {
-			secretKeyErr = fmt.Errorf("HCS_SECRET_KEY is not set")
-			return
+// SecretProvider supplies the secret key used to sign HCS-U7 codes.
+// The key must be 32 or 64 bytes. The raw key material is never logged.
+type SecretProvider interface {
+	SecretKey() ([]byte, error)
+}
+
+// EnvSecretProvider reads the key from HCS_SECRET_KEY, or from the file named
+// by HCS_SECRET_KEY_FILE, on first use and caches it until Reload. With
+// HCS_SECRET_KEYS it is a SecretKeyring: see readEnvKeyring.
+type EnvSecretProvider struct {
+	mu      sync.RWMutex
+	keyring *Keyring
+}
+
+// NewEnvSecretProvider returns a provider backed by the process environment
+func NewEnvSecretProvider() *EnvSecretProvider {
+	return &EnvSecretProvider{}
+}
+
+// SecretKey returns the cached active key, loading it on first use
+func (p *EnvSecretProvider) SecretKey() ([]byte, error) {
+	keyring, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	return keyring.SecretKey()
+}
+
+// SecretKeys returns every cached key and the ID of the active one, loading
+// them on first use
+func (p *EnvSecretProvider) SecretKeys() (map[string][]byte, string, error) {
+	keyring, err := p.load()
+	if err != nil {
+		return nil, "", err
+	}
+	return keyring.SecretKeys()
+}
+
+// load returns the cached keys, reading them on first use
+func (p *EnvSecretProvider) load() (*Keyring, error) {
+	p.mu.RLock()
+	keyring := p.keyring
+	p.mu.RUnlock()
+	if keyring != nil {
+		return keyring, nil
+	}
+	return p.reload()
+}
+
+// Reload re-reads the keys, replaces the cached ones and returns the active
+// key. On error the previously cached keys stay in use, so a bad rotation
+// never breaks signing.
+func (p *EnvSecretProvider) Reload() ([]byte, error) {
+	keyring, err := p.reload()
+	if err != nil {
+		return nil, err
+	}
+	return keyring.SecretKey()
+}
+
+func (p *EnvSecretProvider) reload() (*Keyring, error) {
+	keyring, err := readEnvKeyring()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.keyring = keyring
+	p.mu.Unlock()
+	return keyring, nil
+}
+
+// StaticSecretProvider serves a fixed key, e.g. one per tenant or test
+type StaticSecretProvider struct {
+	key []byte
+}
+
+// NewStaticSecretProvider validates key and returns a provider serving it
+func NewStaticSecretProvider(key []byte) (*StaticSecretProvider, error) {
+	if err := validateSecretKey(key); err != nil {
+		return nil, err
+	}
+	return &StaticSecretProvider{key: append([]byte(nil), key...)}, nil
+}
+
+// SecretKey returns the fixed key
+func (p *StaticSecretProvider) SecretKey() ([]byte, error) {
+	return p.key, nil
+}
+
+// readEnvSecretKey decodes the key from HCS_SECRET_KEY, or from the file named
+// by HCS_SECRET_KEY_FILE when set
+func readEnvSecretKey() ([]byte, error) {
+	value := os.Getenv("HCS_SECRET_KEY")
+	if path := os.Getenv("HCS_SECRET_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errcode.Errorf(errcode.MissingSecret, "failed to read HCS_SECRET_KEY_FILE: %w", err)
 		}
+		value = strings.TrimSpace(string(data))
+	}
+	if value == "" {
+		return nil, errcode.Errorf(errcode.MissingSecret, "HCS_SECRET_KEY is not set")
+	}
 
-		decoded, err := hex.DecodeString(value)
+	decoded, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, errcode.Errorf(errcode.InvalidSecret, "invalid HCS_SECRET_KEY hex encoding: %w", err)
+	}
+
+	if err := validateSecretKey(decoded); err != nil {
+		return nil, errcode.Errorf(errcode.InvalidSecret, "HCS_SECRET_KEY %w", err)
+	}
+	return decoded, nil
+}
+
+// readEnvKeyring reads the keys of the environment. HCS_SECRET_KEYS, or the
+// file named by HCS_SECRET_KEYS_FILE, maps key IDs to hex-encoded keys, and
+// HCS_SECRET_KEY_ID names the active one, which may be left out when there is
+// a single key. HCS_SECRET_KEY, when also set, is the key of LegacyKeyID.
+func readEnvKeyring() (*Keyring, error) {
+	value := os.Getenv("HCS_SECRET_KEYS")
+	if path := os.Getenv("HCS_SECRET_KEYS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errcode.Errorf(errcode.MissingSecret, "failed to read HCS_SECRET_KEYS_FILE: %w", err)
+		}
+		value = string(data)
+	}
+	if strings.TrimSpace(value) == "" {
+		key, err := readEnvSecretKey()
 		if err != nil {
-			secretKeyErr = fmt.Errorf("invalid HCS_SECRET_KEY hex encoding: %w", err)
-			return
+			return nil, err
 		}
+		return NewKeyring(map[string][]byte{LegacyKeyID: key}, LegacyKeyID)
+	}
 
-		if l := len(decoded); l != 32 && l != 64 {
-			secretKeyErr = fmt.Errorf("HCS_SECRET_KEY must be 32 or 64 bytes, got %d bytes", l)
-			return
+	var encoded map[string]string
+	if err := json.Unmarshal([]byte(value), &encoded); err != nil {
+		return nil, errcode.Errorf(errcode.InvalidSecret, "invalid HCS_SECRET_KEYS: expected a JSON object of key IDs to hex keys: %w", err)
+	}
+	keys := make(map[string][]byte, len(encoded)+1)
+	for id, hexKey := range encoded {
+		key, err := ParseSecretKey(hexKey)
+		if err != nil {
+			return nil, errcode.Errorf(errcode.InvalidSecret, "HCS_SECRET_KEYS key %s: %w", id, err)
+		}
+		keys[id] = key
+	}
+	if os.Getenv("HCS_SECRET_KEY") != "" || os.Getenv("HCS_SECRET_KEY_FILE") != "" {
+		if _, ok := keys[LegacyKeyID]; ok {
+			return nil, errcode.Errorf(errcode.InvalidSecret, "HCS_SECRET_KEYS cannot list the %s key when HCS_SECRET_KEY is set", LegacyKeyID)
 		}
+		key, err := readEnvSecretKey()
+		if err != nil {
+			return nil, err
+		}
+		keys[LegacyKeyID] = key
+	}
 
-		secretKey = decoded
-	})
+	active := os.Getenv("HCS_SECRET_KEY_ID")
+	if active == "" {
+		if len(keys) != 1 {
+			return nil, errcode.Errorf(errcode.MissingSecret, "HCS_SECRET_KEY_ID must name the active key of HCS_SECRET_KEYS")
+		}
+		for id := range keys {
+			active = id
+		}
+	}
+	keyring, err := NewKeyring(keys, active)
+	if err != nil {
+		return nil, fmt.Errorf("HCS_SECRET_KEYS: %w", err)
+	}
+	return keyring, nil
+}
+
+// ParseSecretKey decodes a hex-encoded secret key and checks its length, for
+// SecretProviders reading the key from elsewhere than the environment
+func ParseSecretKey(value string) ([]byte, error) {
+	decoded, err := hex.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, errcode.Errorf(errcode.InvalidSecret, "invalid hex encoding: %w", err)
+	}
+	if err := validateSecretKey(decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
 
-	return secretKey, secretKeyErr
+// validateSecretKey checks the key length
+func validateSecretKey(key []byte) error {
+	if l := len(key); l != 32 && l != 64 {
+		return fmt.Errorf("must be 32 or 64 bytes, got %d bytes", l)
+	}
+	return nil
 }
@@ -0,0 +1,78 @@
+package hcs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+)
+
+// U7SignRequest is the input of the keyed step of U7 signing
+type U7SignRequest struct {
+	Canonical     []byte        `json:"canonical"`               // canonical profile data (CanonicalProfileData)
+	Salt          []byte        `json:"salt"`                    // salt of the generator's current epoch
+	KeyDerivation KeyDerivation `json:"keyDerivation,omitempty"` // empty for KeyDerivationLegacy
+	// SecondaryDigest is the algorithm of B3Sig; empty for DigestBLAKE3
+	SecondaryDigest SecondaryDigest `json:"secondaryDigest,omitempty"`
+	// KeyID names the key to sign with; empty for the signer's active key
+	KeyID string `json:"keyId,omitempty"`
+}
+
+// U7Signatures are the full-length hex signatures, before they are truncated
+// into the U7 code
+type U7Signatures struct {
+	QSig  string `json:"qsig"`
+	B3Sig string `json:"b3sig"`
+	// SecondaryDigest echoes the algorithm of B3Sig, so signers predating
+	// SHA3-512 support are detected; empty for DigestBLAKE3
+	SecondaryDigest SecondaryDigest `json:"secondaryDigest,omitempty"`
+	// KeyID names the key that signed; signers predating key IDs leave it
+	// empty, meaning LegacyKeyID
+	KeyID string `json:"keyId,omitempty"`
+}
+
+// Signer performs the keyed step of U7 signing. Everything else in a
+// generation is unkeyed, so a generator whose Signer delegates to a separate
+// signing service never needs the secret key. Post-quantum signatures are
+// delegated separately through PQSigner.
+type Signer interface {
+	SignU7(ctx context.Context, req U7SignRequest) (U7Signatures, error)
+}
+
+// LocalSigner signs in process with the key from a SecretProvider, or with
+// any key of a SecretKeyring. It is the default Signer, and the one a signing
+// service wraps.
+type LocalSigner struct {
+	Secrets SecretProvider
+}
+
+// NewLocalSigner returns a signer using the key from secrets
+func NewLocalSigner(secrets SecretProvider) *LocalSigner {
+	return &LocalSigner{Secrets: secrets}
+}
+
+// SignU7 computes the signatures with ComputeQuantumSignaturesWithDigest
+func (s *LocalSigner) SignU7(ctx context.Context, req U7SignRequest) (U7Signatures, error) {
+	if err := ctx.Err(); err != nil {
+		return U7Signatures{}, err
+	}
+	if len(req.Canonical) == 0 || len(req.Salt) == 0 {
+		return U7Signatures{}, fmt.Errorf("canonical data and salt are required")
+	}
+	keys, keyID, err := loadSecretKeys(s.Secrets)
+	if err != nil {
+		return U7Signatures{}, fmt.Errorf("failed to load secret key: %w", err)
+	}
+	if req.KeyID != "" {
+		keyID = req.KeyID
+	}
+	secret, ok := keys[keyID]
+	if !ok {
+		return U7Signatures{}, errcode.Errorf(errcode.InvalidCode, "unknown secret key ID %q", keyID)
+	}
+	qsig, b3, err := ComputeQuantumSignaturesWithDigest(req.Canonical, secret, req.Salt, req.KeyDerivation, req.SecondaryDigest)
+	if err != nil {
+		return U7Signatures{}, err
+	}
+	return U7Signatures{QSig: qsig, B3Sig: b3, SecondaryDigest: req.SecondaryDigest, KeyID: keyID}, nil
+}
@@ -0,0 +1,87 @@
+package hcs
+
+import (
+	"math"
+	"time"
+)
+
+// SolarTerm is one of the twelve sectional terms (Jie) that open the BaZi
+// months: the instant the Sun's apparent ecliptic longitude reaches Longitude
+type SolarTerm struct {
+	Name      string
+	Longitude float64 // degrees
+	Branch    int     // index in EarthlyBranches of the month it opens
+}
+
+// SolarTerms lists the sectional terms in BaZi month order, from Li Chun, which
+// also opens the BaZi year
+var SolarTerms = []SolarTerm{
+	{"Li Chun", 315, 2},
+	{"Jing Zhe", 345, 3},
+	{"Qing Ming", 15, 4},
+	{"Li Xia", 45, 5},
+	{"Mang Zhong", 75, 6},
+	{"Xiao Shu", 105, 7},
+	{"Li Qiu", 135, 8},
+	{"Bai Lu", 165, 9},
+	{"Han Lu", 195, 10},
+	{"Li Dong", 225, 11},
+	{"Da Xue", 255, 0},
+	{"Xiao Han", 285, 1},
+}
+
+// SolarLongitude returns the apparent ecliptic longitude of the Sun at t, in
+// degrees, from the low-accuracy theory of Meeus (Astronomical Algorithms,
+// chapter 25). It is within 0.01° between 1900 and 2100, so solar terms fall
+// within about a quarter of an hour of their published times.
+func SolarLongitude(t time.Time) float64 {
+	centuries := (julianDay(t.UTC()) - 2451545.0) / 36525
+	l0 := 280.46646 + 36000.76983*centuries + 0.0003032*centuries*centuries
+	m := radians(357.52911 + 35999.05029*centuries - 0.0001537*centuries*centuries)
+	center := (1.914602-0.004817*centuries-0.000014*centuries*centuries)*math.Sin(m) +
+		(0.019993-0.000101*centuries)*math.Sin(2*m) + 0.000289*math.Sin(3*m)
+	node := radians(125.04 - 1934.136*centuries)
+	// Correct the true longitude for nutation and aberration
+	return normalizeDegrees(l0 + center - 0.00569 - 0.00478*math.Sin(node))
+}
+
+// solarMonth returns the BaZi month of t, 0 for the month opened by Li Chun
+func solarMonth(t time.Time) int {
+	return int(normalizeDegrees(SolarLongitude(t)-SolarTerms[0].Longitude) / 30)
+}
+
+// solarYear returns the BaZi year of t, which starts at Li Chun (around
+// February 4) rather than January 1
+func solarYear(t time.Time) int {
+	year := t.Year()
+	// Before Li Chun, January and February belong to the previous BaZi year
+	if t.Month() <= time.February && solarMonth(t) >= 10 {
+		year--
+	}
+	return year
+}
+
+// ComputeSolarYearPillar computes the Year Pillar of the instant t, changing
+// at Li Chun
+func ComputeSolarYearPillar(t time.Time) Pillar {
+	return ComputeYearPillar(solarYear(t))
+}
+
+// ComputeSolarMonthPillar computes the Month Pillar of the instant t, bounded
+// by the sectional solar terms
+func ComputeSolarMonthPillar(t time.Time) Pillar {
+	month := solarMonth(t)
+	branchIndex := SolarTerms[month].Branch
+
+	// The stem of the Yin month follows the year stem (the "five tigers" rule),
+	// and each later month advances it by one
+	yearStem := ComputeSolarYearPillar(t).StemIndex
+	stemIndex := ((yearStem%5)*2 + 2 + month) % 10
+
+	return Pillar{
+		Stem:        HeavenlyStems[stemIndex].Name,
+		Branch:      EarthlyBranches[branchIndex].Name,
+		StemIndex:   stemIndex,
+		BranchIndex: branchIndex,
+	}
+}
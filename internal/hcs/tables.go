@@ -0,0 +1,28 @@
+package hcs
+
+//go:generate go run ../../cmd/hcsrefgen -out ../../ports
+
+// Letter tables used to normalize interaction and element values. These,
+// together with the BaZi tables and code grammars, are the source of truth
+// for the generated Python and TypeScript reference tables in ports/.
+var (
+	ElementLetters   = map[string]string{"Earth": "E", "Air": "A", "Water": "W", "Fire": "F"}
+	PaceLetters      = map[string]string{"balanced": "B", "fast": "F", "slow": "S"}
+	StructureLetters = map[string]string{"low": "L", "medium": "M", "high": "H"}
+	ToneLetters      = map[string]string{"warm": "W", "neutral": "N", "sharp": "S", "precise": "P"}
+)
+
+// Letters used for values missing from the letter tables
+const (
+	DefaultElementLetter   = "E"
+	DefaultPaceLetter      = "B"
+	DefaultStructureLetter = "M"
+	DefaultToneLetter      = "N"
+)
+
+// Code grammars as regular expressions in the syntax shared by Go, Python
+// and JavaScript. Capture groups follow segment order.
+const (
+	U3Grammar = `^HCS-U3\|E:([AEWF])\|MOD:c(\d{2})f(\d{2})m(\d{2})\|COG:F(\d{2})C(\d{2})V(\d{2})S(\d{2})Cr(\d{2})\|INT:PB=([BFS]),SM=([LMH]),TN=([WNSP])\|CHIP:([0-9a-f]{12})(?:\|EP:([1-9]\d*))?(?:\|LN:([0-9a-f]{12}))?$`
+	U7Grammar = `^HCS-U7\|V:7\.0\|ALG:QS(?:-HKDF)?(?:-SHA3)?(?:\.\d{2}\.\d{2})?(?:\+MLDSA65)?\|E:([AEWF])\|MOD:c(\d{2,3})f(\d{2,3})m(\d{2,3})\|COG:F(\d{2,3})C(\d{2,3})V(\d{2,3})S(\d{2,3})Cr(\d{2,3})\|INT:PB=([BFS]),SM=([LMH]),TN=([WNSP])\|QSIG:([0-9a-f]+)\|B3:([0-9a-f]+)(?:\|KID:[a-z0-9][a-z0-9-]{0,15})?(?:\|EP:([1-9]\d*))?(?:\|LN:([0-9a-f]{12}))?(?:\|PQ:[0-9a-f]{16})?$`
+)
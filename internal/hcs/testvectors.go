@@ -0,0 +1,139 @@
+package hcs
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// TestVector is a fixed input with the byte-exact intermediate data and
+// signatures it produces under a fixed secret and salt. Reimplementations in
+// other languages can prove parity by reproducing every field.
+type TestVector struct {
+	Name      string       `json:"name"`
+	Input     InputProfile `json:"input"`
+	SecretHex string       `json:"secretHex"`
+	SaltHex   string       `json:"saltHex"`
+	// EngineVersion computed the BaZi profile of vectors with birth info
+	EngineVersion string `json:"engineVersion"`
+
+	// NormalizedJSON is hashed (after the salt) with SHA256 into the CHIP
+	NormalizedJSON string `json:"normalizedJson"`
+	Chip           string `json:"chip"`
+	// CanonicalJSON is the exact byte sequence signed by QSIG and B3
+	CanonicalJSON string `json:"canonicalJson"`
+	QSig          string `json:"qsig"`
+	B3Sig         string `json:"b3sig"`
+	CodeU7        string `json:"codeU7"`
+}
+
+// testVectorSalt is the fixed salt used for every test vector
+type testVectorSalt []byte
+
+func (s testVectorSalt) Salt() ([]byte, error) { return s, nil }
+
+// testVectorInputs lists the published vector inputs. Append new vectors;
+// never change existing ones, including their engine.
+var testVectorInputs = []struct {
+	name   string
+	engine string
+	input  InputProfile
+}{
+	{
+		name:   "air-balanced",
+		engine: "v1",
+		input: InputProfile{
+			DominantElement: "Air",
+			Modal:           ModalBalance{Cardinal: 0.31, Fixed: 0.23, Mutable: 0.46},
+			Cognition:       CognitionProfile{Fluid: 0.72, Crystallized: 0.68, Verbal: 0.81, Strategic: 0.59, Creative: 0.77},
+			Interaction:     InteractionPreferences{Pace: "balanced", Structure: "medium", Tone: "warm"},
+		},
+	},
+	{
+		// Exact halves (12.5, 87.5) pin rounding half away from zero
+		name:   "water-extremes",
+		engine: "v1",
+		input: InputProfile{
+			DominantElement: "Water",
+			Modal:           ModalBalance{Cardinal: 0, Fixed: 1, Mutable: 0},
+			Cognition:       CognitionProfile{Fluid: 1, Crystallized: 0, Verbal: 0.125, Strategic: 0.875, Creative: 0.5},
+			Interaction:     InteractionPreferences{Pace: "slow", Structure: "high", Tone: "neutral"},
+		},
+	},
+	{
+		name:   "fire-with-birth",
+		engine: "v1",
+		input: InputProfile{
+			DominantElement: "Fire",
+			Modal:           ModalBalance{Cardinal: 0.5, Fixed: 0.3, Mutable: 0.2},
+			Cognition:       CognitionProfile{Fluid: 0.6, Crystallized: 0.4, Verbal: 0.55, Strategic: 0.7, Creative: 0.65},
+			Interaction:     InteractionPreferences{Pace: "fast", Structure: "low", Tone: "sharp"},
+			BirthInfo:       &BirthInfo{Year: 1990, Month: 6, Day: 15, Hour: 14, Minute: 30, Timezone: "UTC"},
+		},
+	},
+	{
+		// Born hours before Li Chun: the BaZi year and month are still those
+		// of 1989, which only the solar-term engine gets right
+		name:   "earth-before-li-chun",
+		engine: "v2",
+		input: InputProfile{
+			DominantElement: "Earth",
+			Modal:           ModalBalance{Cardinal: 0.2, Fixed: 0.45, Mutable: 0.35},
+			Cognition:       CognitionProfile{Fluid: 0.48, Crystallized: 0.74, Verbal: 0.6, Strategic: 0.66, Creative: 0.42},
+			Interaction:     InteractionPreferences{Pace: "slow", Structure: "medium", Tone: "warm"},
+			BirthInfo:       &BirthInfo{Year: 1990, Month: 2, Day: 4, Hour: 8, Minute: 0, Timezone: "Asia/Shanghai"},
+		},
+	},
+}
+
+// TestVectors computes the published test vectors with the fixed secret
+// (bytes 0x00..0x1f) and salt (bytes 0xa0..0xbf)
+func TestVectors() ([]TestVector, error) {
+	secret := make([]byte, 32)
+	salt := make(testVectorSalt, 32)
+	for i := range secret {
+		secret[i] = byte(i)
+		salt[i] = byte(0xa0 + i)
+	}
+
+	secrets, err := NewStaticSecretProvider(secret)
+	if err != nil {
+		return nil, err
+	}
+	gen, err := NewGenerator(WithSaltProvider(salt), WithSecretProvider(secrets))
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([]TestVector, 0, len(testVectorInputs))
+	for _, v := range testVectorInputs {
+		input := v.input
+		out, err := gen.GenerateWithOptions(&input, &GeneratorOptions{Trace: true, EngineVersion: v.engine})
+		if err != nil {
+			return nil, fmt.Errorf("test vector %s: %w", v.name, err)
+		}
+
+		normalized, err := hex.DecodeString(out.Trace.NormalizedJSONHex)
+		if err != nil {
+			return nil, fmt.Errorf("test vector %s: %w", v.name, err)
+		}
+		canonical, err := hex.DecodeString(out.Trace.CanonicalHex)
+		if err != nil {
+			return nil, fmt.Errorf("test vector %s: %w", v.name, err)
+		}
+
+		vectors = append(vectors, TestVector{
+			Name:           v.name,
+			Input:          v.input,
+			SecretHex:      hex.EncodeToString(secret),
+			SaltHex:        hex.EncodeToString(salt),
+			EngineVersion:  v.engine,
+			NormalizedJSON: string(normalized),
+			Chip:           out.Chip,
+			CanonicalJSON:  string(canonical),
+			QSig:           out.QSig,
+			B3Sig:          out.B3Sig,
+			CodeU7:         out.CodeU7,
+		})
+	}
+	return vectors, nil
+}
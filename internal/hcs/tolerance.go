@@ -0,0 +1,36 @@
+package hcs
+
+import "math"
+
+// QuantizationStep is the resolution of the encoded values, in percentage
+// points: raw 0-1 values are rounded to whole percentages
+const QuantizationStep = 1
+
+// ReliabilityBand is the range of raw values, in percentage points, that
+// encode as the same percentage
+type ReliabilityBand struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"` // excluded, except for 100
+}
+
+// BandOf returns the reliability band of an encoded percentage
+func BandOf(percent int) ReliabilityBand {
+	return ReliabilityBand{
+		Low:  math.Max(float64(percent)-0.5, 0),
+		High: math.Min(float64(percent)+0.5, 100),
+	}
+}
+
+// Distance returns the smallest difference, in points, between raw values of
+// the two bands
+func (b ReliabilityBand) Distance(other ReliabilityBand) float64 {
+	return math.Max(0, math.Max(other.Low-b.High, b.Low-other.High))
+}
+
+// WithinRounding reports whether two encoded percentages may stand for raw
+// values less than a quantization step apart, so that rounding alone may
+// explain their difference. Adjacent values are: 0.524 and 0.526 encode as 52
+// and 53.
+func WithinRounding(a, b int) bool {
+	return BandOf(a).Distance(BandOf(b)) < QuantizationStep
+}
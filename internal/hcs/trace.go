@@ -0,0 +1,80 @@
+package hcs
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Trace holds the intermediate artifacts of a generation. Comparing traces from
+// two deployments pinpoints the stage where their outputs diverge.
+type Trace struct {
+	EngineVersion string `json:"engineVersion"`
+	FusionConfig  string `json:"fusionConfig"`
+	// SaltFingerprint identifies the salt (first 8 bytes of its SHA256) without revealing it
+	SaltFingerprint string             `json:"saltFingerprint"`
+	SaltEpoch       int                `json:"saltEpoch"`
+	Normalized      *NormalizedProfile `json:"normalized"`
+	// NormalizedJSONHex is the canonical JSON hashed (after the salt) into the CHIP
+	NormalizedJSONHex string `json:"normalizedJsonHex"`
+	// ChipDigest is the full SHA256 (Argon2id when hardened) digest the CHIP is truncated from
+	ChipDigest string `json:"chipDigest"`
+
+	BirthTime    string        `json:"birthTime,omitempty"` // local time the pillars are computed from
+	Pillars      []PillarTrace `json:"pillars,omitempty"`
+	U5ChipDigest string        `json:"u5ChipDigest,omitempty"`
+
+	// CanonicalHex is the canonical profile data signed by the U7 signatures
+	CanonicalHex string `json:"canonicalHex,omitempty"`
+}
+
+// PillarTrace describes one computed BaZi pillar
+type PillarTrace struct {
+	Name    string `json:"name"`
+	Pillar  Pillar `json:"pillar"`
+	Element string `json:"element"`
+	YinYang string `json:"yinYang"`
+}
+
+// buildTrace recomputes the intermediate artifacts behind output, given the
+// CHIP input and digest generation computed
+func (g *Generator) buildTrace(output *OutputHCS, normalized *NormalizedProfile, normalizedJSON []byte, digest, engineVersion, fusionConfigID string) (*Trace, error) {
+	trace := &Trace{
+		EngineVersion:   engineVersion,
+		FusionConfig:    fusionConfigID,
+		SaltFingerprint: SaltFingerprint(g.salt),
+		SaltEpoch:       g.saltEpoch,
+		Normalized:      normalized,
+	}
+
+	trace.NormalizedJSONHex = hex.EncodeToString(normalizedJSON)
+	trace.ChipDigest = digest
+
+	if output.Input.BirthInfo != nil && output.CombinedProfile != nil {
+		birthTime, pillars := computePillars(*output.Input.BirthInfo, engineVersion)
+		trace.BirthTime = birthTime.Format(time.RFC3339)
+		for i, name := range []string{"year", "month", "day", "hour"} {
+			trace.Pillars = append(trace.Pillars, PillarTrace{
+				Name:    name,
+				Pillar:  pillars[i],
+				Element: pillars[i].GetElement(),
+				YinYang: pillars[i].GetYinYang(),
+			})
+		}
+
+	}
+	if output.CodeU5 != "" {
+		combined := output.CombinedProfile
+		trace.U5ChipDigest = u5ChipDigest(&combined.Western, &combined.Chinese, &combined.Fusion, g.salt)
+	}
+
+	if output.CodeU7 != "" {
+		canonical, err := CanonicalProfileData(normalized, output.CombinedProfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build canonical profile: %w", err)
+		}
+		trace.CanonicalHex = hex.EncodeToString(canonical)
+	}
+
+	return trace, nil
+}
@@ -0,0 +1,246 @@
+package hcs
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+)
+
+// ProfileVerification reports whether an HCS code is the one this generator
+// issues for a profile. Checks that do not apply to the level of the code are nil.
+type ProfileVerification struct {
+	// Chip is the CHIP of the profile under the salt of the code's epoch
+	Chip string `json:"chip"`
+	// ProfileMatches reports whether the profile segments of a U3, U4 or U7
+	// code, or the whole of a U5 or U6 code but its CHIP, are those of the
+	// profile
+	ProfileMatches bool  `json:"profileMatches"`
+	ChipValid      *bool `json:"chipValid,omitempty"` // U3, U4, U5 and U6
+	QSigValid      *bool `json:"qsigValid,omitempty"` // U7
+	B3Valid        *bool `json:"b3Valid,omitempty"`   // U7
+	// Valid is set when every check passed: the code was issued for the
+	// profile under this generator's salt and secret key
+	Valid bool `json:"valid"`
+}
+
+// VerifyProfile recomputes the CHIP or signatures of an HCS code from the
+// profile it was generated from, under the salt of the epoch the code was
+// issued under and with the generator's secret key or signer, and compares
+// them with those of the code. hardening is the Argon2id cost recorded in the
+// code's metadata, nil for a plain SHA256 CHIP. Of opts, only the options
+// that change the codes of a profile are used: FusionConfigID and
+// EngineVersion for profiles with birth info, ModalValidation and
+// WesternFromBirth.
+func (g *Generator) VerifyProfile(ctx context.Context, code string, in *InputProfile, hardening *CHIPHardening, opts *GeneratorOptions) (*ProfileVerification, error) {
+	if in == nil {
+		return nil, errcode.Errorf(errcode.InvalidRequest, "input profile cannot be nil")
+	}
+	if err := checkRecordedHardening(hardening); err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &GeneratorOptions{}
+	}
+	epoch, err := ParseSaltEpoch(code)
+	if err != nil {
+		return nil, errcode.Wrap(errcode.InvalidCode, err)
+	}
+	salt, ok := g.salts[epoch]
+	if !ok {
+		return nil, errcode.Errorf(errcode.InvalidCode, "unknown salt epoch %d", epoch)
+	}
+
+	// Normalize the profile as generation does, on a copy
+	profile := copyInputProfile(in)
+	if opts.WesternFromBirth {
+		if _, err := deriveWesternProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+	modalValidation, err := opts.ModalValidation.Resolve()
+	if err != nil {
+		return nil, errcode.Errorf(errcode.InvalidOptions, "invalid options: %w", err)
+	}
+	if _, err := applyModalValidation(profile, modalValidation); err != nil {
+		return nil, fmt.Errorf("invalid input profile: %w", err)
+	}
+	if err := ValidateInput(profile); err != nil {
+		return nil, fmt.Errorf("invalid input profile: %w", err)
+	}
+	normalized := NormalizeProfile(profile)
+	_, digest, err := chipDigestWith(salt, normalized, hardening)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute CHIP: %w", err)
+	}
+	v := &ProfileVerification{Chip: digest[:12]}
+
+	switch {
+	case strings.HasPrefix(code, "HCS-U3|"), strings.HasPrefix(code, "HCS-U4|"):
+		carried, err := NormalizedFromCode(code)
+		if err != nil {
+			return nil, errcode.Wrap(errcode.InvalidCode, err)
+		}
+		chip, err := ChipFromCode(code)
+		if err != nil {
+			return nil, errcode.Wrap(errcode.InvalidCode, err)
+		}
+		v.ProfileMatches = *carried == *normalized
+		v.ChipValid = check(chip, v.Chip)
+	case strings.HasPrefix(code, u6Prefix):
+		carried, err := DecodeU6(code)
+		if err != nil {
+			return nil, errcode.Wrap(errcode.InvalidCode, err)
+		}
+		combined, _, err := g.verificationCombined(profile, opts)
+		if err != nil {
+			return nil, err
+		}
+		expected, err := encodeU6(normalized, carried.Chip, combined, epoch, carried.Lineage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode U6 code: %w", err)
+		}
+		v.ProfileMatches = expected == code
+		v.ChipValid = check(carried.Chip, v.Chip)
+	case strings.HasPrefix(code, "HCS-U5|"):
+		carried, err := DecodeU5(code)
+		if err != nil {
+			return nil, errcode.Wrap(errcode.InvalidCode, err)
+		}
+		combined, engineVersion, err := g.verificationCombined(profile, opts)
+		if err != nil {
+			return nil, err
+		}
+		if combined == nil {
+			return nil, errcode.Errorf(errcode.InvalidRequest, "HCS-U5 codes can only be verified against a profile with birth info")
+		}
+		chip, err := generateU5Chip(&combined.Western, &combined.Chinese, &combined.Fusion, salt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute U5 CHIP: %w", err)
+		}
+		expected, err := EncodeU5WithEngine(&combined.Western, &combined.Chinese, &combined.Fusion, salt, engineVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode U5 code: %w", err)
+		}
+		lineage, err := ParseLineage(code)
+		if err != nil {
+			return nil, errcode.Wrap(errcode.InvalidCode, err)
+		}
+		v.ProfileMatches = withLineage(withSaltEpoch(expected, epoch), lineage) == code
+		v.ChipValid = check(carried.Chip, chip)
+	case strings.HasPrefix(code, "HCS-U7|"):
+		carried, err := NormalizedFromCode(code)
+		if err != nil {
+			return nil, errcode.Wrap(errcode.InvalidCode, err)
+		}
+		inline, err := decodeSignatures(code)
+		if err != nil {
+			return nil, errcode.Wrap(errcode.InvalidCode, err)
+		}
+		combined, _, err := g.verificationCombined(profile, opts)
+		if err != nil {
+			return nil, err
+		}
+		canonical, err := CanonicalProfileData(normalized, combined)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build canonical profile: %w", err)
+		}
+		secondary := inline.SecondaryDigest
+		if secondary == DigestBLAKE3 {
+			secondary = "" // as signU7 puts it on the wire
+		}
+		sigs, err := g.signer.SignU7(ctx, U7SignRequest{Canonical: canonical, Salt: salt, KeyDerivation: inline.KeyDerivation, SecondaryDigest: secondary, KeyID: inline.KeyID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute quantum signatures: %w", err)
+		}
+		if sigs.SecondaryDigest != secondary {
+			return nil, errcode.Errorf(errcode.SigningFailed, "the signer does not support the %s secondary digest", inline.SecondaryDigest)
+		}
+		if signed := sigs.KeyID; signed != inline.KeyID && !(signed == "" && inline.KeyID == LegacyKeyID) {
+			return nil, errcode.Errorf(errcode.SigningFailed, "the signer does not hold secret key %s", inline.KeyID)
+		}
+		v.ProfileMatches = *carried == *normalized
+		v.QSigValid = check(inline.QSig, prefix(sigs.QSig, inline.Lengths.QSig))
+		v.B3Valid = check(inline.B3, prefix(sigs.B3Sig, inline.Lengths.B3))
+	default:
+		return nil, errcode.Errorf(errcode.InvalidCode, "unrecognized HCS code level")
+	}
+
+	v.Valid = v.ProfileMatches
+	for _, c := range []*bool{v.ChipValid, v.QSigValid, v.B3Valid} {
+		if c != nil && !*c {
+			v.Valid = false
+		}
+	}
+	return v, nil
+}
+
+// verificationCombined rebuilds the combined profile of a profile with birth
+// info, as generation does, and returns the engine version used; profiles
+// without birth info have none
+func (g *Generator) verificationCombined(in *InputProfile, opts *GeneratorOptions) (*CombinedProfile, string, error) {
+	if in.BirthInfo == nil {
+		return nil, "", nil
+	}
+	fusionConfigID := opts.FusionConfigID
+	if fusionConfigID == "" {
+		fusionConfigID = g.fusionConfigID
+	}
+	fusionConfig, err := LookupFusionConfig(fusionConfigID)
+	if err != nil {
+		return nil, "", errcode.Errorf(errcode.InvalidOptions, "invalid options: %w", err)
+	}
+	engineVersion := opts.EngineVersion
+	if engineVersion == "" {
+		engineVersion = g.engineVersion
+	}
+	if engineVersion, err = ResolveEngineVersion(engineVersion); err != nil {
+		return nil, "", errcode.Errorf(errcode.InvalidOptions, "invalid options: %w", err)
+	}
+	chinese, err := g.chineseProfile(*in.BirthInfo, engineVersion)
+	if err != nil {
+		return nil, "", errcode.Errorf(errcode.InvalidBirthInfo, "failed to compute Chinese profile: %w", err)
+	}
+	return combineProfiles(in, chinese, fusionConfig), engineVersion, nil
+}
+
+// check compares a value carried by a code with the recomputed one in constant time
+func check(carried, expected string) *bool {
+	ok := subtle.ConstantTimeCompare([]byte(carried), []byte(expected)) == 1
+	return &ok
+}
+
+// prefix returns the first n characters of s, or s when it is shorter
+func prefix(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// copyInputProfile returns a deep copy, so verification never modifies the
+// caller's element balance or birth info
+func copyInputProfile(in *InputProfile) *InputProfile {
+	cp := *in
+	if in.ElementBalance != nil {
+		cp.ElementBalance = make(map[string]float64, len(in.ElementBalance))
+		for element, share := range in.ElementBalance {
+			cp.ElementBalance[element] = share
+		}
+	}
+	if in.BirthInfo != nil {
+		birthInfo := *in.BirthInfo
+		if in.BirthInfo.Latitude != nil {
+			latitude := *in.BirthInfo.Latitude
+			birthInfo.Latitude = &latitude
+		}
+		if in.BirthInfo.Longitude != nil {
+			longitude := *in.BirthInfo.Longitude
+			birthInfo.Longitude = &longitude
+		}
+		cp.BirthInfo = &birthInfo
+	}
+	return &cp
+}
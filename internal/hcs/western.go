@@ -0,0 +1,276 @@
+package hcs
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+)
+
+// WesternChart is the Western natal chart computed from birth info: the
+// tropical sign of each body, and the element and modality distributions
+// they weigh into
+type WesternChart struct {
+	Sun       string `json:"sun"`
+	Moon      string `json:"moon"`
+	Ascendant string `json:"ascendant,omitempty"` // only with the birth place
+	// Placements lists every body in chartBodies order, then the ascendant
+	Placements []Placement `json:"placements"`
+	// ElementBalance holds the weighted shares of Earth, Air, Water and Fire
+	ElementBalance  map[string]float64 `json:"elementBalance"`
+	DominantElement string             `json:"dominantElement"`
+	// Modal holds the weighted shares of the cardinal, fixed and mutable signs
+	Modal ModalBalance `json:"modal"`
+}
+
+// Placement is the position of a body on the ecliptic
+type Placement struct {
+	Body      string  `json:"body"`
+	Longitude float64 `json:"longitude"` // tropical ecliptic longitude in degrees, 0 at 0° Aries
+	Sign      string  `json:"sign"`
+}
+
+// ZodiacSign is a tropical sign with its element and modality
+type ZodiacSign struct {
+	Name     string
+	Element  string // Earth, Air, Water or Fire
+	Modality string // cardinal, fixed or mutable
+}
+
+// ZodiacSigns lists the signs in order, each spanning 30° from 0° Aries
+var ZodiacSigns = []ZodiacSign{
+	{"Aries", "Fire", "cardinal"},
+	{"Taurus", "Earth", "fixed"},
+	{"Gemini", "Air", "mutable"},
+	{"Cancer", "Water", "cardinal"},
+	{"Leo", "Fire", "fixed"},
+	{"Virgo", "Earth", "mutable"},
+	{"Libra", "Air", "cardinal"},
+	{"Scorpio", "Water", "fixed"},
+	{"Sagittarius", "Fire", "mutable"},
+	{"Capricorn", "Earth", "cardinal"},
+	{"Aquarius", "Air", "fixed"},
+	{"Pisces", "Water", "mutable"},
+}
+
+// chartBodies are the bodies of a chart with their weight in the element and
+// modality distributions: the lights and the ascendant count most, the outer
+// planets, which stay in a sign for years, least
+var chartBodies = []struct {
+	Name   string
+	Weight float64
+}{
+	{"Sun", 3}, {"Moon", 3},
+	{"Mercury", 2}, {"Venus", 2}, {"Mars", 2},
+	{"Jupiter", 1}, {"Saturn", 1},
+	{"Uranus", 0.5}, {"Neptune", 0.5}, {"Pluto", 0.5},
+}
+
+// ascendantWeight is the weight of the ascendant, when the birth place is known
+const ascendantWeight = 3
+
+// orbitalElements are the J2000 Keplerian elements of a planet and their
+// rates per Julian century, from JPL's "Approximate Positions of the Planets"
+// (valid 1800-2050): semi-major axis (au), eccentricity, inclination, mean
+// longitude, longitude of perihelion and longitude of the ascending node (degrees)
+type orbitalElements struct {
+	a, e, i, l, peri, node                         float64
+	aRate, eRate, iRate, lRate, periRate, nodeRate float64
+}
+
+var planetElements = map[string]orbitalElements{
+	"Mercury": {0.38709927, 0.20563593, 7.00497902, 252.25032350, 77.45779628, 48.33076593,
+		0.00000037, 0.00001906, -0.00594749, 149472.67411175, 0.16047689, -0.12534081},
+	"Venus": {0.72333566, 0.00677672, 3.39467605, 181.97909950, 131.60246718, 76.67984255,
+		0.00000390, -0.00004107, -0.00078890, 58517.81538729, 0.00268329, -0.27769418},
+	"Earth": {1.00000261, 0.01671123, -0.00001531, 100.46457166, 102.93768193, 0,
+		0.00000562, -0.00004392, -0.01294668, 35999.37244981, 0.32327364, 0},
+	"Mars": {1.52371034, 0.09339410, 1.84969142, -4.55343205, -23.94362959, 49.55953891,
+		0.00001847, 0.00007882, -0.00813131, 19140.30268499, 0.44441088, -0.29257343},
+	"Jupiter": {5.20288700, 0.04838624, 1.30439695, 34.39644051, 14.72847983, 100.47390909,
+		-0.00011607, -0.00013253, -0.00183714, 3034.74612775, 0.21252668, 0.20469106},
+	"Saturn": {9.53667594, 0.05386179, 2.48599187, 49.95424423, 92.59887831, 113.66242448,
+		-0.00125060, -0.00050991, 0.00193609, 1222.49362201, -0.41897216, -0.28867794},
+	"Uranus": {19.18916464, 0.04725744, 0.77263783, 313.23810451, 170.95427630, 74.01692503,
+		-0.00196176, -0.00004397, -0.00242939, 428.48202785, 0.40805281, 0.04240589},
+	"Neptune": {30.06992276, 0.00859048, 1.77004347, -55.12002969, 44.96476227, 131.78422574,
+		0.00026291, 0.00005105, 0.00035372, 218.45945325, -0.32241464, -0.00508664},
+	"Pluto": {39.48211675, 0.24882730, 17.14001206, 238.92903833, 224.06891629, 110.30393684,
+		-0.00031596, 0.00005170, 0.00004818, 145.20780515, -0.04062942, -0.01183482},
+}
+
+// ComputeWesternChart computes the natal chart of birthInfo. Positions come
+// from low-precision ephemerides, within a degree for every body, which is
+// enough to place it in its sign except within a degree of a cusp. The
+// ascendant needs the birth latitude and longitude.
+func ComputeWesternChart(birthInfo BirthInfo) (*WesternChart, error) {
+	if err := validateBirthInfo(birthInfo); err != nil {
+		return nil, err
+	}
+	t := birthTime(birthInfo).UTC()
+	d := julianDay(t) - 2451545.0 // days since J2000.0
+	centuries := d / 36525
+
+	chart := &WesternChart{}
+	elements := map[string]float64{}
+	modalities := map[string]float64{}
+	total := 0.0
+	place := func(body string, longitude, weight float64) {
+		longitude = math.Round(normalizeDegrees(longitude)*100) / 100
+		if longitude == 360 {
+			longitude = 0
+		}
+		sign := ZodiacSigns[int(longitude/30)]
+		chart.Placements = append(chart.Placements, Placement{Body: body, Longitude: longitude, Sign: sign.Name})
+		elements[sign.Element] += weight
+		modalities[sign.Modality] += weight
+		total += weight
+	}
+
+	earth := heliocentric(planetElements["Earth"], centuries)
+	for _, body := range chartBodies {
+		var longitude float64
+		switch body.Name {
+		case "Sun":
+			longitude = SolarLongitude(t)
+		case "Moon":
+			longitude = moonLongitude(d)
+		default:
+			p := heliocentric(planetElements[body.Name], centuries)
+			longitude = math.Atan2(p[1]-earth[1], p[0]-earth[0])*180/math.Pi + precession(centuries)
+		}
+		place(body.Name, longitude, body.Weight)
+	}
+	chart.Sun = chart.Placements[0].Sign
+	chart.Moon = chart.Placements[1].Sign
+	if birthInfo.Latitude != nil {
+		place("Ascendant", ascendantLongitude(d, *birthInfo.Latitude, *birthInfo.Longitude), ascendantWeight)
+		chart.Ascendant = chart.Placements[len(chart.Placements)-1].Sign
+	}
+
+	chart.ElementBalance = make(map[string]float64, len(westernElements))
+	for _, element := range westernElements {
+		chart.ElementBalance[element] = math.Round(elements[element]/total*10000) / 10000
+	}
+	chart.DominantElement, _ = validateElementBalance(elements)
+	chart.Modal = ModalBalance{
+		Cardinal: math.Round(modalities["cardinal"]/total*10000) / 10000,
+		Fixed:    math.Round(modalities["fixed"]/total*10000) / 10000,
+		Mutable:  math.Round(modalities["mutable"]/total*10000) / 10000,
+	}
+	return chart, nil
+}
+
+// deriveWesternProfile replaces the dominant element, element balance and
+// modal balance of in with those of the natal chart of its birth info
+func deriveWesternProfile(in *InputProfile) (*WesternChart, error) {
+	if in.BirthInfo == nil {
+		return nil, errcode.Errorf(errcode.InvalidBirthInfo, "deriving the Western profile requires birthInfo")
+	}
+	chart, err := ComputeWesternChart(*in.BirthInfo)
+	if err != nil {
+		return nil, errcode.Errorf(errcode.InvalidBirthInfo, "invalid birth info: %w", err)
+	}
+	in.DominantElement = chart.DominantElement
+	in.ElementBalance = make(map[string]float64, len(chart.ElementBalance))
+	for element, share := range chart.ElementBalance {
+		in.ElementBalance[element] = share
+	}
+	in.Modal = chart.Modal
+	return chart, nil
+}
+
+// julianDay returns the Julian day of t
+func julianDay(t time.Time) float64 {
+	return float64(t.Unix())/86400 + 2440587.5
+}
+
+// precession converts J2000 ecliptic longitudes to the equinox of date
+func precession(centuries float64) float64 {
+	return 1.396971 * centuries
+}
+
+// heliocentric returns the J2000 ecliptic coordinates (au) of the body with
+// elements el, centuries after J2000.0
+func heliocentric(el orbitalElements, centuries float64) [3]float64 {
+	a := el.a + el.aRate*centuries
+	e := el.e + el.eRate*centuries
+	i := radians(el.i + el.iRate*centuries)
+	l := el.l + el.lRate*centuries
+	peri := el.peri + el.periRate*centuries
+	node := el.node + el.nodeRate*centuries
+
+	w := radians(peri - node)
+	m := radians(normalizeDegrees(l-peri+180) - 180)
+	// Solve Kepler's equation by Newton's method
+	ecc := m + e*math.Sin(m)
+	for range 10 {
+		delta := (ecc - e*math.Sin(ecc) - m) / (1 - e*math.Cos(ecc))
+		ecc -= delta
+		if math.Abs(delta) < 1e-12 {
+			break
+		}
+	}
+	x := a * (math.Cos(ecc) - e)
+	y := a * math.Sqrt(1-e*e) * math.Sin(ecc)
+
+	n := radians(node)
+	cw, sw, cn, sn, ci, si := math.Cos(w), math.Sin(w), math.Cos(n), math.Sin(n), math.Cos(i), math.Sin(i)
+	return [3]float64{
+		(cw*cn-sw*sn*ci)*x + (-sw*cn-cw*sn*ci)*y,
+		(cw*sn+sw*cn*ci)*x + (-sw*sn+cw*cn*ci)*y,
+		sw*si*x + cw*si*y,
+	}
+}
+
+// moonLongitude returns the ecliptic longitude of the Moon, d days after
+// J2000.0, from the main terms of its series (within about 0.3°)
+func moonLongitude(d float64) float64 {
+	l := 218.316 + 13.176396*d            // mean longitude
+	mm := radians(134.963 + 13.064993*d)  // mean anomaly
+	ms := radians(357.529 + 0.98560028*d) // Sun's mean anomaly
+	el := radians(297.850 + 12.190749*d)  // mean elongation
+	f := radians(93.272 + 13.229350*d)    // argument of latitude
+	return l + 6.289*math.Sin(mm) + 1.274*math.Sin(2*el-mm) + 0.658*math.Sin(2*el) +
+		0.214*math.Sin(2*mm) - 0.186*math.Sin(ms) - 0.114*math.Sin(2*f)
+}
+
+// ascendantLongitude returns the ecliptic longitude rising on the eastern
+// horizon at latitude and longitude (degrees, east positive), d days after J2000.0
+func ascendantLongitude(d, latitude, longitude float64) float64 {
+	sidereal := radians(280.46061837 + 360.98564736629*d + longitude)
+	obliquity := radians(23.439 - 0.0000004*d)
+	return math.Atan2(math.Cos(sidereal), -(math.Sin(sidereal)*math.Cos(obliquity)+math.Tan(radians(latitude))*math.Sin(obliquity))) * 180 / math.Pi
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// normalizeDegrees maps an angle into [0, 360)
+func normalizeDegrees(degrees float64) float64 {
+	degrees = math.Mod(degrees, 360)
+	if degrees < 0 {
+		degrees += 360
+	}
+	return degrees
+}
+
+// validateBirthPlace checks the optional birth latitude and longitude, which
+// go together
+func validateBirthPlace(info BirthInfo) error {
+	if (info.Latitude == nil) != (info.Longitude == nil) {
+		return fmt.Errorf("latitude and longitude must be given together")
+	}
+	if info.Latitude == nil {
+		return nil
+	}
+	if lat := *info.Latitude; !(lat > -90 && lat < 90) {
+		return fmt.Errorf("latitude must be between -90 and 90, got %g", lat)
+	}
+	if lon := *info.Longitude; !(lon >= -180 && lon <= 180) {
+		return fmt.Errorf("longitude must be between -180 and 180, got %g", lon)
+	}
+	return nil
+}
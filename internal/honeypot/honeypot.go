@@ -0,0 +1,106 @@
+// Package honeypot holds the reserved canary profiles behind leak detection.
+// Their codes are planted in datasets; a server that sees one of their CHIPs
+// in verify or lookup traffic from a key that has no reason to know it
+// raises an alert, since the dataset has probably leaked.
+package honeypot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// Canary is one reserved profile and the codes generated for it
+type Canary struct {
+	Chip      string    `json:"chip"`
+	Label     string    `json:"label,omitempty"` // where the codes were planted, e.g. "partner-export-2025-03"
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+	// Input and Output are kept for planting; the server only reads the CHIP
+	Input  *hcs.InputProfile `json:"input,omitempty"`
+	Output *hcs.OutputHCS    `json:"output,omitempty"`
+}
+
+// File is a honeypot file. Requests from AllowedTenants or AllowedKeys (API
+// key fingerprints, as reported in alerts) may use the canaries, e.g. for QA.
+type File struct {
+	AllowedTenants []string `json:"allowedTenants,omitempty"`
+	AllowedKeys    []string `json:"allowedKeys,omitempty"`
+	Canaries       []Canary `json:"canaries"`
+
+	byChip map[string]*Canary
+}
+
+// Load reads a honeypot file. An empty path gives an empty set.
+func Load(path string) (*File, error) {
+	f := &File{}
+	if path == "" {
+		f.index()
+		return f, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read honeypot file: %w", err)
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse honeypot file %s: %w", path, err)
+	}
+	for i, c := range f.Canaries {
+		if !hcs.IsCHIP(c.Chip) {
+			return nil, fmt.Errorf("honeypot file %s: canary %d has an invalid chip %q", path, i, c.Chip)
+		}
+	}
+	f.index()
+	return f, nil
+}
+
+// Save writes the file, replacing path
+func (f *File) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal honeypot file: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write honeypot file: %w", err)
+	}
+	return nil
+}
+
+// Add appends canaries, replacing those with the same CHIP
+func (f *File) Add(canaries ...Canary) {
+	for _, c := range canaries {
+		if i := slices.IndexFunc(f.Canaries, func(e Canary) bool { return e.Chip == c.Chip }); i >= 0 {
+			f.Canaries[i] = c
+		} else {
+			f.Canaries = append(f.Canaries, c)
+		}
+	}
+	f.index()
+}
+
+// Match returns the canary with chip, if any
+func (f *File) Match(chip string) (*Canary, bool) {
+	c, ok := f.byChip[chip]
+	return c, ok
+}
+
+// Allowed tells whether a request from tenant with the key fingerprint may
+// use the canaries without raising an alert
+func (f *File) Allowed(tenant, key string) bool {
+	return (tenant != "" && slices.Contains(f.AllowedTenants, tenant)) || slices.Contains(f.AllowedKeys, key)
+}
+
+// Len returns the number of canaries
+func (f *File) Len() int {
+	return len(f.Canaries)
+}
+
+func (f *File) index() {
+	f.byChip = make(map[string]*Canary, len(f.Canaries))
+	for i := range f.Canaries {
+		f.byChip[f.Canaries[i].Chip] = &f.Canaries[i]
+	}
+}
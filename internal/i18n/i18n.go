@@ -0,0 +1,115 @@
+// Package i18n is the message catalog shared by hcsgen and the API. Messages
+// are keyed by their English text, gettext style: English needs no catalog,
+// and a message without a translation falls back to English. Translations are
+// JSON files in locales/, one per language.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default is the source language of every message
+const Default = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// catalogs maps a language to its translations, keyed by English text
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[string]map[string]string {
+	out := map[string]map[string]string{Default: {}}
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, e := range entries {
+		data, err := localeFiles.ReadFile("locales/" + e.Name())
+		if err != nil {
+			panic(err)
+		}
+		messages := map[string]string{}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: invalid catalog %s: %v", e.Name(), err))
+		}
+		out[strings.TrimSuffix(e.Name(), path.Ext(e.Name()))] = messages
+	}
+	return out
+}
+
+// Locales returns the supported languages, English first
+func Locales() []string {
+	out := []string{Default}
+	for lang := range catalogs {
+		if lang != Default {
+			out = append(out, lang)
+		}
+	}
+	sort.Strings(out[1:])
+	return out
+}
+
+// Messages returns the translations of lang, keyed by English text
+func Messages(lang string) map[string]string {
+	return catalogs[lang]
+}
+
+// Match returns the supported language of a locale name such as "fr",
+// "fr-CA" or "fr_FR.UTF-8", or "" when it is not supported
+func Match(locale string) string {
+	lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(locale)), ".")
+	lang, _, _ = strings.Cut(lang, "_")
+	lang, _, _ = strings.Cut(lang, "-")
+	if _, ok := catalogs[lang]; ok {
+		return lang
+	}
+	return ""
+}
+
+// MatchAcceptLanguage returns the supported language the client prefers in
+// an Accept-Language header, or "" when it accepts none of them
+func MatchAcceptLanguage(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if lang := Match(tag); lang != "" && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// Translate returns msg in lang, or msg itself when it has no translation.
+// Leading and trailing whitespace, such as newlines and indentation, is kept
+// as is and not part of the key.
+func Translate(lang, msg string) string {
+	messages := catalogs[lang]
+	if len(messages) == 0 {
+		return msg
+	}
+	core := strings.TrimSpace(msg)
+	t, ok := messages[core]
+	if !ok || core == "" {
+		return msg
+	}
+	start := strings.Index(msg, core)
+	return msg[:start] + t + msg[start+len(core):]
+}
+
+// Sprintf formats args with the translation of format in lang
+func Sprintf(lang, format string, args ...any) string {
+	return fmt.Sprintf(Translate(lang, format), args...)
+}
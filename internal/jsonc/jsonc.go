@@ -0,0 +1,77 @@
+// Package jsonc reads hand-written JSON: it accepts // and /* */ comments
+// and trailing commas, and rewrites them into standard JSON for
+// encoding/json. Nothing else of JSON5 is accepted.
+package jsonc
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ContentType is the media type under which the API accepts JSONC bodies
+const ContentType = "application/jsonc"
+
+// Standardize returns data as standard JSON. Comments and trailing commas
+// are replaced by spaces, keeping newlines, so offsets, line numbers and
+// syntax errors reported by encoding/json still point into the original.
+func Standardize(data []byte) ([]byte, error) {
+	out := bytes.Clone(data)
+	comma := -1 // offset of a comma that may be trailing
+	for i := 0; i < len(out); i++ {
+		switch c := out[i]; {
+		case c == '"':
+			end, err := stringEnd(out, i)
+			if err != nil {
+				return nil, err
+			}
+			i, comma = end, -1
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			end := bytes.IndexByte(out[i:], '\n')
+			if end < 0 {
+				end = len(out) - i
+			}
+			blank(out[i : i+end])
+			i += end - 1
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := bytes.Index(out[i+2:], []byte("*/"))
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment at offset %d", i)
+			}
+			blank(out[i : i+2+end+2])
+			i += 2 + end + 1
+		case c == ',':
+			comma = i
+		case c == '}' || c == ']':
+			if comma >= 0 {
+				out[comma] = ' '
+			}
+			comma = -1
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		default:
+			comma = -1
+		}
+	}
+	return out, nil
+}
+
+// stringEnd returns the offset of the quote closing the string that starts at i
+func stringEnd(data []byte, i int) (int, error) {
+	for j := i + 1; j < len(data); j++ {
+		switch data[j] {
+		case '\\':
+			j++
+		case '"':
+			return j, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string at offset %d", i)
+}
+
+// blank replaces b with spaces, keeping line breaks
+func blank(b []byte) {
+	for i, c := range b {
+		if c != '\n' && c != '\r' {
+			b[i] = ' '
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Package ledger keeps an append-only, hash-chained record of generations in
+// a local file, for labs that run hcsgen offline. Each line is one JSON entry
+// holding the hash of the entry before it, so editing, removing or reordering
+// entries breaks the chain. With a key, every entry is also MACed, so the
+// chain cannot be rebuilt by someone who can only write the file.
+package ledger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// Genesis is the previous hash of the first entry
+var Genesis = strings.Repeat("0", 64)
+
+// Entry is one generation recorded in the ledger
+type Entry struct {
+	Seq             uint64       `json:"seq"` // 1 for the first entry, then consecutive
+	Time            string       `json:"time"`
+	Chip            string       `json:"chip"`
+	Codes           []CodeRecord `json:"codes"`
+	SaltEpoch       int          `json:"saltEpoch,omitempty"`
+	SaltFingerprint string       `json:"saltFingerprint"`
+	Prev            string       `json:"prev"` // hash of the previous entry, Genesis for the first
+	Hash            string       `json:"hash"` // SHA-256 of the entry without Hash and MAC
+	MAC             string       `json:"mac,omitempty"`
+}
+
+// CodeRecord identifies an issued code without storing it
+type CodeRecord struct {
+	Level   string `json:"level"`             // U3, U4, U5, U6 or U7
+	Version string `json:"version,omitempty"` // format version of versioned levels, e.g. 7.0
+	SHA256  string `json:"sha256"`            // of the code text
+}
+
+// NewEntry describes a generation output, issued under the salt of saltEpoch
+func NewEntry(output *hcs.OutputHCS, saltEpoch int, saltFingerprint string, now time.Time) Entry {
+	e := Entry{
+		Time:            now.UTC().Format(time.RFC3339),
+		Chip:            output.Chip,
+		SaltEpoch:       saltEpoch,
+		SaltFingerprint: saltFingerprint,
+	}
+	codes := []hcs.VersionedCode{
+		{Level: "U3", Code: output.CodeU3},
+		{Level: "U4", Code: output.CodeU4},
+		{Level: "U5", Code: output.CodeU5},
+		{Level: "U6", Code: output.CodeU6},
+		{Level: "U7", Version: hcs.U7Version(output.CodeU7), Code: output.CodeU7},
+	}
+	for _, c := range append(codes, output.LegacyCodes...) {
+		if c.Code != "" {
+			sum := sha256.Sum256([]byte(c.Code))
+			e.Codes = append(e.Codes, CodeRecord{Level: c.Level, Version: c.Version, SHA256: hex.EncodeToString(sum[:])})
+		}
+	}
+	return e
+}
+
+// Append chains e to the last entry of the ledger at path, creating it if
+// needed, and writes it as one line. A nil key writes no MAC. Only one
+// process should append to a ledger at a time.
+func Append(path string, e Entry, key []byte) (Entry, error) {
+	last, err := lastEntry(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	e.Seq, e.Prev = 1, Genesis
+	if last != nil {
+		e.Seq, e.Prev = last.Seq+1, last.Hash
+	}
+	e.Hash = e.digest()
+	e.MAC = ""
+	if key != nil {
+		e.MAC = e.mac(key)
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return Entry{}, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to open ledger: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return Entry{}, fmt.Errorf("failed to append to ledger: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return Entry{}, fmt.Errorf("failed to append to ledger: %w", err)
+	}
+	return e, f.Close()
+}
+
+// Report is the result of verifying a ledger
+type Report struct {
+	Entries int    `json:"entries"`
+	Head    string `json:"head"` // hash of the last entry; record it to detect a truncated ledger later
+	// MACsChecked is set when the entries were checked against a key
+	MACsChecked bool      `json:"macsChecked"`
+	Problems    []Problem `json:"problems,omitempty"`
+}
+
+// Valid reports whether the ledger verified without problems
+func (r *Report) Valid() bool {
+	return len(r.Problems) == 0
+}
+
+// Problem is one inconsistency found in the ledger
+type Problem struct {
+	Line   int    `json:"line"`
+	Seq    uint64 `json:"seq,omitempty"`
+	Detail string `json:"detail"`
+}
+
+// Verify checks every entry of the ledger at path: its hash, its link to the
+// entry before it, consecutive sequence numbers and, with a key, its MAC. A
+// non-empty head must be the hash of one of the entries, which detects a
+// ledger truncated since that head was recorded. Problems are reported, not
+// returned as errors; the error is for a ledger that cannot be read.
+func Verify(path string, key []byte, head string) (*Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger: %w", err)
+	}
+	defer f.Close()
+
+	report := &Report{MACsChecked: key != nil}
+	problem := func(line int, seq uint64, format string, args ...any) {
+		report.Problems = append(report.Problems, Problem{Line: line, Seq: seq, Detail: fmt.Sprintf(format, args...)})
+	}
+	prev := Entry{Hash: Genesis}
+	headFound := head == ""
+	lineNo := 0
+	err = eachLine(f, func(line []byte) {
+		lineNo++
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			problem(lineNo, 0, "unreadable entry: %v", err)
+			return
+		}
+		report.Entries++
+		switch {
+		case e.Seq <= prev.Seq:
+			problem(lineNo, e.Seq, "entry out of order after entry %d", prev.Seq)
+		case e.Seq == prev.Seq+2:
+			problem(lineNo, e.Seq, "missing entry %d", prev.Seq+1)
+		case e.Seq > prev.Seq+2:
+			problem(lineNo, e.Seq, "missing entries %d to %d", prev.Seq+1, e.Seq-1)
+		}
+		if e.Prev != prev.Hash {
+			problem(lineNo, e.Seq, "does not chain to the entry before it")
+		}
+		if e.Hash != e.digest() {
+			problem(lineNo, e.Seq, "entry was modified")
+		}
+		if key != nil {
+			if e.MAC == "" {
+				problem(lineNo, e.Seq, "entry has no MAC")
+			} else if !hmac.Equal([]byte(e.MAC), []byte(e.mac(key))) {
+				problem(lineNo, e.Seq, "MAC does not match the key")
+			}
+		}
+		headFound = headFound || e.Hash == head
+		prev = e
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ledger: %w", err)
+	}
+	report.Head = prev.Hash
+	if !headFound {
+		problem(lineNo, 0, "head %s is not in the ledger: entries were removed from its end", head)
+	}
+	return report, nil
+}
+
+// digest hashes every field but Hash and MAC
+func (e Entry) digest() string {
+	e.Hash, e.MAC = "", ""
+	data, _ := json.Marshal(e) // only strings and numbers
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// mac authenticates the entry hash, which covers the rest of the entry
+func (e Entry) mac(key []byte) string {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(e.Hash))
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// lastEntry returns the last entry of the ledger, or nil when it is empty or
+// does not exist
+func lastEntry(path string) (*Entry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger: %w", err)
+	}
+	defer f.Close()
+
+	var last []byte
+	if err := eachLine(f, func(line []byte) { last = append(last[:0], line...) }); err != nil {
+		return nil, fmt.Errorf("failed to read ledger: %w", err)
+	}
+	if last == nil {
+		return nil, nil
+	}
+	var e Entry
+	if err := json.Unmarshal(last, &e); err != nil {
+		return nil, fmt.Errorf("the last ledger entry is unreadable; run hcsgen ledger verify: %w", err)
+	}
+	return &e, nil
+}
+
+// eachLine calls fn with every non-blank line of r
+func eachLine(r io.Reader, fn func(line []byte)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			fn(line)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,114 @@
+// Package lockout counts failed attempts at guessing a secret, such as a
+// display code, and locks a key out once it fails too often. Counters are
+// kept in memory, per process.
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+// Config sets the limits. Zero fields use the defaults.
+type Config struct {
+	MaxFailures int           // failures that lock a key out; default 5
+	Window      time.Duration // failures older than this are forgotten; default 15m
+	Lockout     time.Duration // how long a locked key stays locked; default 15m
+}
+
+// Defaults for zero Config fields
+const (
+	DefaultMaxFailures = 5
+	DefaultWindow      = 15 * time.Minute
+	DefaultLockout     = 15 * time.Minute
+)
+
+// maxKeys bounds the counters kept before expired ones are dropped
+const maxKeys = 100000
+
+// Limiter tracks the failures of each key
+type Limiter struct {
+	cfg Config
+
+	mu   sync.Mutex
+	keys map[string]*keyState
+}
+
+// keyState counts the failures of one key since start
+type keyState struct {
+	start       time.Time
+	failures    int
+	lockedUntil time.Time
+}
+
+// New returns a limiter with cfg, defaults filled in
+func New(cfg Config) *Limiter {
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = DefaultMaxFailures
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultWindow
+	}
+	if cfg.Lockout <= 0 {
+		cfg.Lockout = DefaultLockout
+	}
+	return &Limiter{cfg: cfg, keys: make(map[string]*keyState)}
+}
+
+// Config returns the limits in effect
+func (l *Limiter) Config() Config {
+	return l.cfg
+}
+
+// Locked reports whether any of keys is locked out at t, and until when
+func (l *Limiter) Locked(t time.Time, keys ...string) (bool, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var until time.Time
+	for _, key := range keys {
+		if s, ok := l.keys[key]; ok && t.Before(s.lockedUntil) && s.lockedUntil.After(until) {
+			until = s.lockedUntil
+		}
+	}
+	return !until.IsZero(), until
+}
+
+// Fail counts a failed attempt at t against each of keys, locking out those
+// that reach MaxFailures within Window
+func (l *Limiter) Fail(t time.Time, keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.keys) >= maxKeys {
+		l.prune(t)
+	}
+	for _, key := range keys {
+		s, ok := l.keys[key]
+		if !ok || t.Sub(s.start) >= l.cfg.Window && !t.Before(s.lockedUntil) {
+			s = &keyState{start: t}
+			l.keys[key] = s
+		}
+		s.failures++
+		if s.failures >= l.cfg.MaxFailures {
+			s.lockedUntil = t.Add(l.cfg.Lockout)
+			s.start, s.failures = t, 0
+		}
+	}
+}
+
+// Reset forgets the failures of keys, after a successful attempt made while
+// they were not locked
+func (l *Limiter) Reset(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		delete(l.keys, key)
+	}
+}
+
+// prune drops the counters that no longer lock or count anything at t
+func (l *Limiter) prune(t time.Time) {
+	for key, s := range l.keys {
+		if t.Sub(s.start) >= l.cfg.Window && !t.Before(s.lockedUntil) {
+			delete(l.keys, key)
+		}
+	}
+}
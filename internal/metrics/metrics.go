@@ -0,0 +1,123 @@
+// Package metrics keeps the in-process measurements behind the operator
+// dashboard: a sliding window of latencies and per-day counters. They are
+// lost on restart; long-term history belongs in storage or a TSDB.
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultWindowSize is the number of latency samples a Window keeps
+const DefaultWindowSize = 1024
+
+// Window keeps the most recent latency samples
+type Window struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	total   int64
+}
+
+// NewWindow creates a window of the last size samples; size <= 0 uses DefaultWindowSize
+func NewWindow(size int) *Window {
+	if size <= 0 {
+		size = DefaultWindowSize
+	}
+	return &Window{samples: make([]time.Duration, 0, size)}
+}
+
+// Observe records one sample, replacing the oldest when the window is full
+func (w *Window) Observe(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.total++
+	if len(w.samples) < cap(w.samples) {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % len(w.samples)
+}
+
+// Summary describes the samples of a window, in milliseconds
+type Summary struct {
+	Total   int64   `json:"total"`   // samples observed since start
+	Samples int     `json:"samples"` // samples in the window
+	P50     float64 `json:"p50Ms"`
+	P90     float64 `json:"p90Ms"`
+	P99     float64 `json:"p99Ms"`
+	Max     float64 `json:"maxMs"`
+}
+
+// Summary computes the percentiles of the samples in the window, by the
+// nearest-rank method
+func (w *Window) Summary() Summary {
+	w.mu.Lock()
+	sorted := append([]time.Duration(nil), w.samples...)
+	s := Summary{Total: w.total, Samples: len(sorted)}
+	w.mu.Unlock()
+	if len(sorted) == 0 {
+		return s
+	}
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return milliseconds(sorted[max(i, 0)])
+	}
+	s.P50, s.P90, s.P99 = rank(0.5), rank(0.9), rank(0.99)
+	s.Max = milliseconds(sorted[len(sorted)-1])
+	return s
+}
+
+// milliseconds converts d, rounded to microseconds
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Microsecond)) / 1000
+}
+
+// DayCount is the count of one UTC day
+type DayCount struct {
+	Date  string `json:"date"` // YYYY-MM-DD
+	Count int64  `json:"count"`
+}
+
+// Days counts events per UTC day
+type Days struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// NewDays creates an empty per-day counter
+func NewDays() *Days {
+	return &Days{counts: make(map[string]int64)}
+}
+
+// Add counts n events at t
+func (d *Days) Add(t time.Time, n int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.counts[t.UTC().Format(time.DateOnly)] += n
+}
+
+// Last returns the counts of the n days ending with the day of now, oldest
+// first, including days without events
+func (d *Days) Last(now time.Time, n int) []DayCount {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return LastDays(d.counts, now, n)
+}
+
+// LastDays returns the counts of the n days ending with the day of now,
+// oldest first, from counts keyed by YYYY-MM-DD
+func LastDays(counts map[string]int64, now time.Time, n int) []DayCount {
+	out := make([]DayCount, n)
+	day := now.UTC().AddDate(0, 0, -(n - 1))
+	for i := range out {
+		date := day.AddDate(0, 0, i).Format(time.DateOnly)
+		out[i] = DayCount{Date: date, Count: counts[date]}
+	}
+	return out
+}
@@ -0,0 +1,209 @@
+// Package migrate applies versioned SQL migrations to a database/sql
+// database, so SQL storage backends can evolve their schema without operators
+// running SQL by hand. Applied versions are recorded in schema_migrations.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is one versioned schema change and its inverse
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// fileName matches migration files: 0001_create_records.up.sql, 0001_create_records.down.sql
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load reads the migrations in the root of fsys. Every version needs an up
+// and a down file, and versions must run from 1 without gaps.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		m := fileName.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration: %w", err)
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if strings.TrimSpace(mig.Up) == "" || strings.TrimSpace(mig.Down) == "" {
+			return nil, fmt.Errorf("migration %d_%s needs non-empty up and down files", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, mig := range migrations {
+		if mig.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must run from 1 without gaps, found %d at position %d", mig.Version, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// Status is the state of one migration in a database
+type Status struct {
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`
+	Applied   bool      `json:"applied"`
+	AppliedAt time.Time `json:"appliedAt,omitempty"`
+}
+
+// Runner applies Migrations to DB
+type Runner struct {
+	DB         *sql.DB
+	Migrations []Migration
+}
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TEXT NOT NULL
+)`
+
+// applied returns the applied versions and when they were applied
+func (r *Runner) applied(ctx context.Context) (map[int]time.Time, error) {
+	if _, err := r.DB.ExecContext(ctx, createTable); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	rows, err := r.DB.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		applied[version], _ = time.Parse(time.RFC3339, at)
+	}
+	return applied, rows.Err()
+}
+
+// Status reports every known migration and whether it is applied
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, len(r.Migrations))
+	for i, mig := range r.Migrations {
+		at, ok := applied[mig.Version]
+		statuses[i] = Status{Version: mig.Version, Name: mig.Name, Applied: ok, AppliedAt: at}
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations not applied yet, in order
+func (r *Runner) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, mig := range r.Migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies the pending migrations in order, each in its own transaction,
+// and returns those it applied. It stops at the first failure.
+func (r *Runner) Up(ctx context.Context, now time.Time) ([]Migration, error) {
+	pending, err := r.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, mig := range pending {
+		record := fmt.Sprintf("INSERT INTO schema_migrations (version, name, applied_at) VALUES (%d, '%s', '%s')",
+			mig.Version, mig.Name, now.UTC().Format(time.RFC3339))
+		if err := r.exec(ctx, mig.Up, record); err != nil {
+			return done, fmt.Errorf("migration %d_%s failed: %w", mig.Version, mig.Name, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down reverts the latest steps applied migrations, newest first, and
+// returns those it reverted
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for i := len(r.Migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		mig := r.Migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		forget := fmt.Sprintf("DELETE FROM schema_migrations WHERE version = %d", mig.Version)
+		if err := r.exec(ctx, mig.Down, forget); err != nil {
+			return done, fmt.Errorf("reverting migration %d_%s failed: %w", mig.Version, mig.Name, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// exec runs statements in one transaction
+func (r *Runner) exec(ctx context.Context, statements ...string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,317 @@
+// Package parquet writes flat tables as Apache Parquet files, so research
+// exports can be loaded directly by pandas, DuckDB or Spark. It supports only
+// what exports need: one row group, uncompressed PLAIN pages, and required or
+// optional columns of a few primitive types.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Type is the logical type of a column
+type Type int
+
+const (
+	Bool      Type = iota // bool
+	Int64                 // int64 or int
+	Double                // float64
+	String                // string, stored as UTF-8 BYTE_ARRAY
+	Timestamp             // time.Time, stored as INT64 milliseconds since the Unix epoch (UTC)
+)
+
+// Column describes one column of the table. Optional columns accept nil values.
+type Column struct {
+	Name     string
+	Type     Type
+	Optional bool
+}
+
+// Parquet physical types, repetitions, converted types and encodings
+const (
+	physicalBoolean   = 0
+	physicalInt64     = 2
+	physicalDouble    = 5
+	physicalByteArray = 6
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	encodingPlain = 0
+	encodingRLE   = 3
+)
+
+const magic = "PAR1"
+
+// CreatedBy is recorded in the file metadata
+const CreatedBy = "hcs-lab-api"
+
+// Writer buffers rows in memory and writes them as a single row group on Close
+type Writer struct {
+	w       io.Writer
+	columns []Column
+	values  [][]any // per column
+	rows    int
+	closed  bool
+}
+
+// NewWriter returns a writer of the given columns to w
+func NewWriter(w io.Writer, columns []Column) *Writer {
+	return &Writer{w: w, columns: columns, values: make([][]any, len(columns))}
+}
+
+// Write appends a row, with one value per column in column order
+func (w *Writer) Write(row []any) error {
+	if w.closed {
+		return fmt.Errorf("parquet writer is closed")
+	}
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("row has %d values, expected %d", len(row), len(w.columns))
+	}
+	for i, v := range row {
+		c := w.columns[i]
+		if v == nil {
+			if !c.Optional {
+				return fmt.Errorf("column %s: required value is nil", c.Name)
+			}
+			continue
+		}
+		if n, ok := v.(int); ok && c.Type == Int64 {
+			row[i] = int64(n)
+			continue
+		}
+		if !c.Type.accepts(v) {
+			return fmt.Errorf("column %s: unexpected value type %T", c.Name, v)
+		}
+	}
+	for i, v := range row {
+		w.values[i] = append(w.values[i], v)
+	}
+	w.rows++
+	return nil
+}
+
+// String returns the type name used in dataset documentation
+func (t Type) String() string {
+	switch t {
+	case Bool:
+		return "bool"
+	case Int64:
+		return "int64"
+	case Double:
+		return "double"
+	case String:
+		return "string"
+	case Timestamp:
+		return "timestamp"
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
+func (t Type) accepts(v any) bool {
+	switch v.(type) {
+	case bool:
+		return t == Bool
+	case int64:
+		return t == Int64
+	case float64:
+		return t == Double
+	case string:
+		return t == String
+	case time.Time:
+		return t == Timestamp
+	}
+	return false
+}
+
+func (t Type) physical() int32 {
+	switch t {
+	case Bool:
+		return physicalBoolean
+	case Double:
+		return physicalDouble
+	case String:
+		return physicalByteArray
+	}
+	return physicalInt64
+}
+
+// columnChunk records where a column was written, for the footer
+type columnChunk struct {
+	offset int64
+	size   int64
+}
+
+// Close writes the file. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	var file bytes.Buffer
+	file.WriteString(magic)
+
+	chunks := make([]columnChunk, len(w.columns))
+	for i, c := range w.columns {
+		page := encodePage(c, w.values[i])
+
+		var header thriftWriter
+		header.beginStruct()
+		header.i32(1, 0) // DATA_PAGE
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(page)))
+		header.structField(5)
+		header.i32(1, int32(len(w.values[i])))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.endStruct()
+		header.endStruct()
+
+		chunks[i] = columnChunk{offset: int64(file.Len()), size: int64(header.buf.Len() + len(page))}
+		file.Write(header.buf.Bytes())
+		file.Write(page)
+	}
+
+	footer := w.footer(chunks)
+	file.Write(footer)
+	file.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	file.WriteString(magic)
+
+	_, err := w.w.Write(file.Bytes())
+	return err
+}
+
+// encodePage encodes the definition levels (for optional columns) and the
+// PLAIN-encoded non-null values of a column
+func encodePage(c Column, values []any) []byte {
+	var page bytes.Buffer
+	if c.Optional {
+		levels := encodeDefinitionLevels(values)
+		page.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(levels))))
+		page.Write(levels)
+	}
+
+	var bits []bool
+	for _, v := range values {
+		switch v := v.(type) {
+		case nil:
+		case bool:
+			bits = append(bits, v)
+		case int64:
+			page.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+		case float64:
+			page.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)))
+		case string:
+			page.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(v))))
+			page.WriteString(v)
+		case time.Time:
+			page.Write(binary.LittleEndian.AppendUint64(nil, uint64(v.UnixMilli())))
+		}
+	}
+	// Booleans are bit-packed, least significant bit first
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8 && i+j < len(bits); j++ {
+			if bits[i+j] {
+				b |= 1 << j
+			}
+		}
+		page.WriteByte(b)
+	}
+	return page.Bytes()
+}
+
+// encodeDefinitionLevels run-length encodes whether each value is present
+// (level 1) or null (level 0), using the RLE/bit-packing hybrid with bit width 1
+func encodeDefinitionLevels(values []any) []byte {
+	var out []byte
+	for i := 0; i < len(values); {
+		present := values[i] != nil
+		run := 1
+		for i+run < len(values) && (values[i+run] != nil) == present {
+			run++
+		}
+		out = binary.AppendUvarint(out, uint64(run)<<1)
+		if present {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		i += run
+	}
+	return out
+}
+
+// footer encodes the FileMetaData: the schema and the single row group
+func (w *Writer) footer(chunks []columnChunk) []byte {
+	var t thriftWriter
+	t.beginStruct()
+	t.i32(1, 1) // format version
+
+	t.list(2, thriftStruct, len(w.columns)+1)
+	t.beginStruct() // root
+	t.str(4, "schema")
+	t.i32(5, int32(len(w.columns)))
+	t.endStruct()
+	for _, c := range w.columns {
+		t.beginStruct()
+		t.i32(1, c.Type.physical())
+		if c.Optional {
+			t.i32(3, repetitionOptional)
+		} else {
+			t.i32(3, repetitionRequired)
+		}
+		t.str(4, c.Name)
+		switch c.Type {
+		case String:
+			t.i32(6, convertedUTF8)
+		case Timestamp:
+			t.i32(6, convertedTimestampMillis)
+		}
+		t.endStruct()
+	}
+
+	t.i64(3, int64(w.rows))
+
+	var total int64
+	for _, chunk := range chunks {
+		total += chunk.size
+	}
+	t.list(4, thriftStruct, 1)
+	t.beginStruct() // row group
+	t.list(1, thriftStruct, len(w.columns))
+	for i, c := range w.columns {
+		t.beginStruct() // column chunk
+		t.i64(2, chunks[i].offset)
+		t.structField(3) // column metadata
+		t.i32(1, c.Type.physical())
+		t.list(2, thriftI32, 2)
+		t.listI32(encodingPlain)
+		t.listI32(encodingRLE)
+		t.list(3, thriftBinary, 1)
+		t.listStr(c.Name)
+		t.i32(4, 0) // UNCOMPRESSED
+		t.i64(5, int64(len(w.values[i])))
+		t.i64(6, chunks[i].size)
+		t.i64(7, chunks[i].size)
+		t.i64(9, chunks[i].offset)
+		t.endStruct()
+		t.endStruct()
+	}
+	t.i64(2, total)
+	t.i64(3, int64(w.rows))
+	t.endStruct()
+
+	t.str(6, CreatedBy)
+	t.endStruct()
+	return t.buf.Bytes()
+}
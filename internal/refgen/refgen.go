@@ -0,0 +1,146 @@
+// Package refgen renders the HCS tables (BaZi stems and branches, letter
+// mappings, code grammars) as Python and TypeScript source, so ports are
+// generated from the Go source of truth instead of copied by hand.
+package refgen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// header is emitted at the top of every generated file
+const header = "Code generated by hcsrefgen from internal/hcs. DO NOT EDIT."
+
+// letterTable is one normalization table in output order
+type letterTable struct {
+	name    string
+	letters map[string]string
+	def     string
+}
+
+func letterTables() []letterTable {
+	return []letterTable{
+		{"ELEMENT", hcs.ElementLetters, hcs.DefaultElementLetter},
+		{"PACE", hcs.PaceLetters, hcs.DefaultPaceLetter},
+		{"STRUCTURE", hcs.StructureLetters, hcs.DefaultStructureLetter},
+		{"TONE", hcs.ToneLetters, hcs.DefaultToneLetter},
+	}
+}
+
+// sortedKeys returns the keys of m in sorted order, for stable output
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// q quotes s as a string literal valid in both Python and TypeScript
+func q(s string) string {
+	return strconv.Quote(s)
+}
+
+// Python renders the tables as a Python module
+func Python() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\"\"\"HCS reference tables for Python ports.\"\"\"\n\nimport math\n\n", header)
+
+	b.WriteString("# Heavenly stems: (name, element, yin/yang), indexed by stem index\nHEAVENLY_STEMS = [\n")
+	for _, s := range hcs.HeavenlyStems {
+		fmt.Fprintf(&b, "    (%s, %s, %s),\n", q(s.Name), q(s.Element), q(s.YinYang))
+	}
+	b.WriteString("]\n\n# Earthly branches: (name, element, yin/yang, animal), indexed by branch index\nEARTHLY_BRANCHES = [\n")
+	for _, br := range hcs.EarthlyBranches {
+		fmt.Fprintf(&b, "    (%s, %s, %s, %s),\n", q(br.Name), q(br.Element), q(br.YinYang), q(br.Animal))
+	}
+	b.WriteString("]\n\n# Branch index for each calendar month (January first), engine v1\nMONTH_BRANCH_MAPPING = [")
+	for i, v := range hcs.MonthBranchMapping {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%d", v)
+	}
+	b.WriteString("]\n\n# Sectional solar terms opening the BaZi months from engine v2, Li Chun first:\n# (name, apparent solar longitude in degrees, branch index)\nSOLAR_TERMS = [\n")
+	for _, t := range hcs.SolarTerms {
+		fmt.Fprintf(&b, "    (%s, %g, %d),\n", q(t.Name), t.Longitude, t.Branch)
+	}
+	b.WriteString("]\n")
+
+	for _, t := range letterTables() {
+		fmt.Fprintf(&b, "\n%s_LETTERS = {\n", t.name)
+		for _, k := range sortedKeys(t.letters) {
+			fmt.Fprintf(&b, "    %s: %s,\n", q(k), q(t.letters[k]))
+		}
+		fmt.Fprintf(&b, "}\nDEFAULT_%s_LETTER = %s\n", t.name, q(t.def))
+	}
+
+	b.WriteString("\n# Code grammars; match with re.fullmatch (Python's $ also matches before a trailing newline)\n")
+	fmt.Fprintf(&b, "U3_GRAMMAR = %s\n", q(hcs.U3Grammar))
+	fmt.Fprintf(&b, "U7_GRAMMAR = %s\n", q(hcs.U7Grammar))
+
+	b.WriteString(`
+
+def clamp_and_round(value: float) -> int:
+    """Clamp to [0, 1] and convert to a percentage, rounding half away from zero
+    (Python's round() rounds half to even and must not be used)."""
+    value = min(max(value, 0.0), 1.0) * 100
+    whole = math.floor(value)
+    return int(whole + 1 if value - whole >= 0.5 else whole)
+`)
+	return b.Bytes()
+}
+
+// TypeScript renders the tables as a TypeScript module
+func TypeScript() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// %s\n// HCS reference tables for TypeScript ports.\n\n", header)
+
+	b.WriteString("// Heavenly stems: [name, element, yin/yang], indexed by stem index\nexport const HEAVENLY_STEMS: ReadonlyArray<readonly [string, string, string]> = [\n")
+	for _, s := range hcs.HeavenlyStems {
+		fmt.Fprintf(&b, "  [%s, %s, %s],\n", q(s.Name), q(s.Element), q(s.YinYang))
+	}
+	b.WriteString("];\n\n// Earthly branches: [name, element, yin/yang, animal], indexed by branch index\nexport const EARTHLY_BRANCHES: ReadonlyArray<readonly [string, string, string, string]> = [\n")
+	for _, br := range hcs.EarthlyBranches {
+		fmt.Fprintf(&b, "  [%s, %s, %s, %s],\n", q(br.Name), q(br.Element), q(br.YinYang), q(br.Animal))
+	}
+	b.WriteString("];\n\n// Branch index for each calendar month (January first), engine v1\nexport const MONTH_BRANCH_MAPPING: ReadonlyArray<number> = [")
+	for i, v := range hcs.MonthBranchMapping {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%d", v)
+	}
+	b.WriteString("];\n\n// Sectional solar terms opening the BaZi months from engine v2, Li Chun first:\n// [name, apparent solar longitude in degrees, branch index]\nexport const SOLAR_TERMS: ReadonlyArray<readonly [string, number, number]> = [\n")
+	for _, t := range hcs.SolarTerms {
+		fmt.Fprintf(&b, "  [%s, %g, %d],\n", q(t.Name), t.Longitude, t.Branch)
+	}
+	b.WriteString("];\n")
+
+	for _, t := range letterTables() {
+		fmt.Fprintf(&b, "\nexport const %s_LETTERS: Readonly<Record<string, string>> = {\n", t.name)
+		for _, k := range sortedKeys(t.letters) {
+			fmt.Fprintf(&b, "  %s: %s,\n", q(k), q(t.letters[k]))
+		}
+		fmt.Fprintf(&b, "};\nexport const DEFAULT_%s_LETTER = %s;\n", t.name, q(t.def))
+	}
+
+	b.WriteString("\n// Code grammars\n")
+	fmt.Fprintf(&b, "export const U3_GRAMMAR = new RegExp(%s);\n", q(hcs.U3Grammar))
+	fmt.Fprintf(&b, "export const U7_GRAMMAR = new RegExp(%s);\n", q(hcs.U7Grammar))
+
+	b.WriteString(`
+// Clamp to [0, 1] and convert to a percentage, rounding half away from zero
+export function clampAndRound(value: number): number {
+  const v = Math.min(Math.max(value, 0), 1) * 100;
+  const whole = Math.floor(v);
+  return v - whole >= 0.5 ? whole + 1 : whole;
+}
+`)
+	return b.Bytes()
+}
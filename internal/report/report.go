@@ -0,0 +1,81 @@
+// Package report renders the human-readable report of a generated profile,
+// as sent to subjects by email: their codes, profile and archetype, and a
+// link where the codes can be verified.
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/terminology"
+)
+
+// Subject is the subject line of report emails
+const Subject = "Your HCS profile"
+
+var (
+	//go:embed report.html.tmpl
+	htmlSource string
+	//go:embed report.txt.tmpl
+	textSource string
+
+	// funcs are the only functions templates can call, besides the
+	// text/template builtins. term is bound to the report's terms when it is
+	// rendered.
+	funcs = map[string]any{
+		"percent": func(v float64) string { return fmt.Sprintf("%.0f%%", v*100) },
+		"codes":   codes,
+		"term":    (*terminology.Dictionary)(nil).Apply,
+	}
+)
+
+// codes lists the generated codes of output, most readable first
+func codes(output *hcs.OutputHCS) []string {
+	var out []string
+	for _, code := range []string{output.CodeU3, output.CodeU5, output.CodeU7, output.CodeU6, output.CodeU4} {
+		if code != "" {
+			out = append(out, code)
+		}
+	}
+	return out
+}
+
+// Report is the data a report is rendered from
+type Report struct {
+	Output *hcs.OutputHCS
+	// VerifyURL is where the codes can be verified; no link is rendered when empty
+	VerifyURL  string
+	ValidUntil string // date after which the codes are stale, when they expire
+	// Terms rewords the labels and values templates pass to term; nil keeps
+	// the standard terms
+	Terms *terminology.Dictionary
+}
+
+// New prepares the report of output, linking to verifyURL with {chip}
+// replaced by its CHIP
+func New(output *hcs.OutputHCS, verifyURL string) *Report {
+	r := &Report{Output: output, VerifyURL: strings.ReplaceAll(verifyURL, "{chip}", output.Chip)}
+	if validUntil, ok, err := hcs.ParseValidUntil(output.Metadata); err == nil && ok {
+		r.ValidUntil = validUntil.UTC().Format(time.DateOnly)
+	}
+	return r
+}
+
+// Subject returns the subject line of the report email, in its terms
+func (r *Report) Subject() string {
+	return r.Terms.Apply(Subject)
+}
+
+// HTML renders the report as an HTML document with the built-in template
+func (r *Report) HTML() (string, error) {
+	return builtin.HTML(r)
+}
+
+// Text renders the report as plain text with the built-in template, for mail
+// clients without HTML
+func (r *Report) Text() (string, error) {
+	return builtin.Text(r)
+}
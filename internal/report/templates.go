@@ -0,0 +1,312 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	texttemplate "text/template"
+	"text/template/parse"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/i18n"
+	"github.com/corehuman/hcs-lab-api/internal/terminology"
+)
+
+// File names of the templates in an override directory
+const (
+	HTMLFile = "report.html.tmpl"
+	TextFile = "report.txt.tmpl"
+)
+
+const (
+	// maxTemplateSize bounds the source of an override template
+	maxTemplateSize = 64 << 10
+	// maxReportSize bounds a rendered report; rendering stops beyond it
+	maxReportSize = 1 << 20
+	// maxRangeDepth bounds the nesting of range actions, so that the
+	// iterations of a template stay proportional to its data
+	maxRangeDepth = 2
+)
+
+var errReportTooLarge = fmt.Errorf("report exceeds %d bytes", maxReportSize)
+
+// Templates are the templates reports are rendered with. Overrides are
+// sandboxed: they can only call the report functions and text/template
+// builtins, cannot define or invoke other templates, and their output is
+// bounded.
+type Templates struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+	// Overrides lists the files the templates were loaded from; empty for
+	// the built-in templates
+	Overrides []string
+}
+
+var builtin = mustBuiltin()
+
+// mustBuiltin parses the embedded templates
+func mustBuiltin() *Templates {
+	html, err := parseHTML(htmlSource)
+	if err != nil {
+		panic(err)
+	}
+	text, err := parseText(textSource)
+	if err != nil {
+		panic(err)
+	}
+	return &Templates{html: html, text: text}
+}
+
+// Builtin returns the built-in templates
+func Builtin() *Templates {
+	return builtin
+}
+
+// Load reads the override templates of dir, report.html.tmpl and
+// report.txt.tmpl, and checks them against the sample reports. Either can be
+// missing to keep the built-in one, but not both. An empty dir returns the
+// built-in templates.
+func Load(dir string) (*Templates, error) {
+	if dir == "" {
+		return builtin, nil
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	t := &Templates{html: builtin.html, text: builtin.text}
+	for _, name := range []string{HTMLFile, TextFile} {
+		path := filepath.Join(dir, name)
+		src, err := readTemplate(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if name == HTMLFile {
+			t.html, err = parseHTML(src)
+		} else {
+			t.text, err = parseText(src)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		t.Overrides = append(t.Overrides, path)
+	}
+	if len(t.Overrides) == 0 {
+		return nil, fmt.Errorf("%s has no %s or %s", dir, HTMLFile, TextFile)
+	}
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// readTemplate reads the source of a template, up to maxTemplateSize
+func readTemplate(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	src, err := io.ReadAll(io.LimitReader(f, maxTemplateSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(src) > maxTemplateSize {
+		return "", fmt.Errorf("%s exceeds %d bytes", path, maxTemplateSize)
+	}
+	return string(src), nil
+}
+
+func parseHTML(src string) (*htmltemplate.Template, error) {
+	t, err := htmltemplate.New("report").Funcs(funcs).Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(t.Templates()) > 1 {
+		return nil, errors.New("templates cannot define other templates")
+	}
+	return t, checkTree(t.Tree)
+}
+
+func parseText(src string) (*texttemplate.Template, error) {
+	t, err := texttemplate.New("report").Funcs(funcs).Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(t.Templates()) > 1 {
+		return nil, errors.New("templates cannot define other templates")
+	}
+	return t, checkTree(t.Tree)
+}
+
+// checkTree rejects the actions a sandboxed template cannot use: invoking
+// templates, ranging over number literals, and deeply nested ranges
+func checkTree(tree *parse.Tree) error {
+	if tree == nil || tree.Root == nil {
+		return nil
+	}
+	var walk func(node parse.Node, depth int) error
+	walk = func(node parse.Node, depth int) error {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return nil
+			}
+			for _, child := range n.Nodes {
+				if err := walk(child, depth); err != nil {
+					return err
+				}
+			}
+		case *parse.TemplateNode:
+			return fmt.Errorf("line %d: templates cannot invoke other templates", n.Line)
+		case *parse.RangeNode:
+			if depth == maxRangeDepth {
+				return fmt.Errorf("line %d: ranges cannot nest more than %d deep", n.Line, maxRangeDepth)
+			}
+			for _, cmd := range n.Pipe.Cmds {
+				for _, arg := range cmd.Args {
+					if _, ok := arg.(*parse.NumberNode); ok {
+						return fmt.Errorf("line %d: templates cannot range over numbers", n.Line)
+					}
+				}
+			}
+			if err := walk(n.List, depth+1); err != nil {
+				return err
+			}
+			return walk(n.ElseList, depth)
+		case *parse.IfNode:
+			if err := walk(n.List, depth); err != nil {
+				return err
+			}
+			return walk(n.ElseList, depth)
+		case *parse.WithNode:
+			if err := walk(n.List, depth); err != nil {
+				return err
+			}
+			return walk(n.ElseList, depth)
+		}
+		return nil
+	}
+	return walk(tree.Root, 0)
+}
+
+// view is the data of templates: the fields of a Report without its methods,
+// which would let templates render reports recursively
+type view Report
+
+// executor is a parsed HTML or text template
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// render executes a template for r, stopping at maxReportSize
+func render(tmpl executor, r *Report, kind string) (string, error) {
+	w := &limitedBuffer{max: maxReportSize}
+	if err := tmpl.Execute(w, (*view)(r)); err != nil {
+		return "", fmt.Errorf("failed to render %s report: %w", kind, err)
+	}
+	return w.String(), nil
+}
+
+// limitedBuffer is a buffer that fails writes beyond max bytes
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		return 0, errReportTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// HTML renders a report as an HTML document. The parsed templates are never
+// executed themselves: each report renders a clone bound to its terms, since
+// html/template cannot clone a template once it has run.
+func (t *Templates) HTML(r *Report) (string, error) {
+	tmpl, err := t.html.Clone()
+	if err != nil {
+		return "", fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return render(tmpl.Funcs(htmltemplate.FuncMap{"term": r.Terms.Apply}), r, "HTML")
+}
+
+// Text renders a report as plain text, for mail clients without HTML
+func (t *Templates) Text(r *Report) (string, error) {
+	tmpl, err := t.text.Clone()
+	if err != nil {
+		return "", fmt.Errorf("failed to render text report: %w", err)
+	}
+	return render(tmpl.Funcs(texttemplate.FuncMap{"term": r.Terms.Apply}), r, "text")
+}
+
+// Validate renders both templates with every sample report, so that
+// templates relying on optional data fail when loaded rather than when sent
+func (t *Templates) Validate() error {
+	var errs []error
+	for _, sample := range Samples() {
+		if _, err := t.HTML(sample.Report); err != nil {
+			errs = append(errs, fmt.Errorf("%s sample: %w", sample.Name, err))
+		}
+		if _, err := t.Text(sample.Report); err != nil {
+			errs = append(errs, fmt.Errorf("%s sample: %w", sample.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Sample is a report templates are checked against
+type Sample struct {
+	Name   string
+	Report *Report
+}
+
+// Samples returns the sample reports: a full one, with every code, an
+// archetype, a Chinese profile, an expiry, a verification link and the
+// neutral terms, and a minimal one with a U3 code alone
+func Samples() []Sample {
+	input := hcs.InputProfile{
+		DominantElement: "Fire",
+		Modal:           hcs.ModalBalance{Cardinal: 0.45, Fixed: 0.30, Mutable: 0.25},
+		Cognition:       hcs.CognitionProfile{Fluid: 0.70, Crystallized: 0.60, Verbal: 0.55, Strategic: 0.65, Creative: 0.80},
+		Interaction:     hcs.InteractionPreferences{Pace: "balanced", Structure: "medium", Tone: "warm"},
+		BirthInfo:       &hcs.BirthInfo{Year: 1990, Month: 5, Day: 1, Hour: 12, Timezone: "UTC"},
+	}
+	archetype := hcs.AssignArchetype(hcs.NormalizeProfile(&input))
+	chinese, _ := hcs.ComputeChineseProfile(*input.BirthInfo)
+	full := &hcs.OutputHCS{
+		Input:          input,
+		Chip:           "aae673a93e1f",
+		CodeU3:         "HCS-U3|V:3.0|E:F|MOD:c45f30m25|COG:F70C60V55S65Cr80|INT:PB=B,SM=M,TN=W|CHIP:aae673a93e1f",
+		CodeU4:         "HCS-U4|V:4.0|E:F|MOD:2d1e19|COG:463c37414f|INT:BMW|CHIP:aae673a93e1f",
+		CodeU5:         "HCS-U5|K3|W:88b5|C:34a1|F:38e3|CHIP:4075975117fe",
+		CodeU7:         "HCS-U7|V:7.0|ALG:QS-HKDF|E:F|MOD:c45f30m25|COG:F70C60V55S65Cr80|INT:PB=B,SM=M,TN=W|QSIG:3f9a0c2d7e41b6a85c93d0e2|B3:8c01f4a7e92b3d56c0a1e8f47b2d9c3e",
+		Archetype:      &archetype,
+		ChineseProfile: chinese,
+		Metadata:       &hcs.OutputMetadata{IssuedAt: "2025-03-01T09:00:00Z", ValidUntil: "2026-03-01T09:00:00Z"},
+	}
+	minimal := &hcs.OutputHCS{
+		Input:  input,
+		Chip:   full.Chip,
+		CodeU3: full.CodeU3,
+	}
+	minimal.Input.BirthInfo = nil
+	neutral, _ := terminology.Builtin().Lookup("neutral")
+	fullReport := New(full, "https://lab.example.com/verify/{chip}")
+	fullReport.Terms = neutral.Dictionary(i18n.Default)
+	return []Sample{
+		{Name: "full", Report: fullReport},
+		{Name: "minimal", Report: New(minimal, "")},
+	}
+}
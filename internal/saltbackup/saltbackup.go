@@ -0,0 +1,258 @@
+// Package saltbackup exports the salt files encrypted to operator keys with
+// age, and restores them. Losing the salt silently invalidates every CHIP
+// ever issued, and a plaintext copy is as sensitive as the salt itself.
+package saltbackup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// FormatVersion is the version of the archive inside a backup
+const FormatVersion = 1
+
+// File is one salt or seal file in a backup
+type File struct {
+	Name   string `json:"name"`
+	Data   []byte `json:"data"`
+	SHA256 string `json:"sha256"`
+}
+
+// Archive is the plaintext of a backup
+type Archive struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Files     []File    `json:"files"`
+}
+
+// RestoreResult lists the files a restore wrote and those already identical on disk
+type RestoreResult struct {
+	Written   []string
+	Unchanged []string
+}
+
+// saltFileName matches the files of a salt directory: salts and their seals
+var saltFileName = regexp.MustCompile(`^\.hcs_salt(\.[1-9][0-9]*)?(\.mac)?$`)
+
+// saltSize is the size of every salt file
+const saltSize = 32
+
+// Backup encrypts the salt files of dir and their seals to recipients and
+// writes them to w, ASCII-armored when armored is set
+func Backup(dir string, w io.Writer, armored bool, now time.Time, recipients ...age.Recipient) (*Archive, error) {
+	if err := checkFIPS(); err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("at least one recipient is required")
+	}
+	names, err := hcs.SaltFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no salt files in %s", dir)
+	}
+
+	archive := &Archive{Version: FormatVersion, CreatedAt: now.UTC()}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		sum := sha256.Sum256(data)
+		archive.Files = append(archive.Files, File{Name: name, Data: data, SHA256: hex.EncodeToString(sum[:])})
+	}
+	if err := archive.validate(); err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	out := w
+	var armorWriter io.WriteCloser
+	if armored {
+		armorWriter = armor.NewWriter(w)
+		out = armorWriter
+	}
+	enc, err := age.Encrypt(out, recipients...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+	if _, err := enc.Write(plaintext); err != nil {
+		return nil, fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+	if armorWriter != nil {
+		if err := armorWriter.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encrypt backup: %w", err)
+		}
+	}
+	return archive, nil
+}
+
+// Open decrypts and validates a backup, armored or not
+func Open(r io.Reader, identities ...age.Identity) (*Archive, error) {
+	if err := checkFIPS(); err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(r)
+	var src io.Reader = br
+	if start, _ := br.Peek(len(armor.Header)); string(start) == armor.Header {
+		src = armor.NewReader(br)
+	}
+	dec, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt backup: %w", err)
+	}
+	var archive Archive
+	if err := json.NewDecoder(dec).Decode(&archive); err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	if archive.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", archive.Version)
+	}
+	if err := archive.validate(); err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
+
+// checkFIPS refuses backups in FIPS builds: age encrypts with X25519 and
+// ChaCha20-Poly1305, which are not FIPS-approved
+func checkFIPS() error {
+	if hcs.FIPSMode() {
+		return fmt.Errorf("salt backups use age (X25519, ChaCha20-Poly1305), which is not FIPS-approved and is disabled in this FIPS build (%s)", hcs.FIPSModule())
+	}
+	return nil
+}
+
+// Restore writes the files of a backup to dir. A file already on disk with
+// different contents is a conflict: nothing is written unless force is set,
+// since replacing a salt changes every CHIP issued under it.
+func Restore(dir string, r io.Reader, force bool, identities ...age.Identity) (*RestoreResult, error) {
+	archive, err := Open(r, identities...)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create salt directory: %w", err)
+	}
+
+	result := &RestoreResult{}
+	var pending, conflicts []File
+	for _, f := range archive.Files {
+		existing, err := os.ReadFile(filepath.Join(dir, f.Name))
+		switch {
+		case os.IsNotExist(err):
+			pending = append(pending, f)
+		case err != nil:
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		case bytes.Equal(existing, f.Data):
+			result.Unchanged = append(result.Unchanged, f.Name)
+		default:
+			conflicts = append(conflicts, f)
+		}
+	}
+	if len(conflicts) > 0 && !force {
+		names := make([]string, len(conflicts))
+		for i, f := range conflicts {
+			names[i] = f.Name
+		}
+		return nil, fmt.Errorf("%s already exist with different contents; use force to replace them", strings.Join(names, ", "))
+	}
+
+	for _, f := range append(pending, conflicts...) {
+		if err := writeFile(filepath.Join(dir, f.Name), f.Data); err != nil {
+			return nil, err
+		}
+		result.Written = append(result.Written, f.Name)
+	}
+	return result, nil
+}
+
+// validate checks the names, checksums and salt sizes of the files
+func (a *Archive) validate() error {
+	hasBase := false
+	for _, f := range a.Files {
+		if !saltFileName.MatchString(f.Name) {
+			return fmt.Errorf("unexpected file %q in backup", f.Name)
+		}
+		sum := sha256.Sum256(f.Data)
+		if hex.EncodeToString(sum[:]) != f.SHA256 {
+			return fmt.Errorf("checksum mismatch for %s", f.Name)
+		}
+		if !strings.HasSuffix(f.Name, ".mac") && len(f.Data) != saltSize {
+			return fmt.Errorf("salt %s has %d bytes, expected %d", f.Name, len(f.Data), saltSize)
+		}
+		hasBase = hasBase || f.Name == ".hcs_salt"
+	}
+	if !hasBase {
+		return fmt.Errorf("backup has no .hcs_salt")
+	}
+	return nil
+}
+
+// writeFile replaces path atomically with owner-only permissions
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".restore-*")
+	if err != nil {
+		return fmt.Errorf("failed to restore %s: %w", filepath.Base(path), err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to restore %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// ParseRecipient parses an age X25519 recipient (age1...) or an SSH public key
+// (ssh-ed25519 or ssh-rsa)
+func ParseRecipient(s string) (age.Recipient, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "ssh-") {
+		return agessh.ParseRecipient(s)
+	}
+	return age.ParseX25519Recipient(s)
+}
+
+// ParseIdentities parses an age identity file or an unencrypted SSH private key
+func ParseIdentities(data []byte) ([]age.Identity, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) {
+		id, err := agessh.ParseIdentity(data)
+		if err != nil {
+			return nil, err
+		}
+		return []age.Identity{id}, nil
+	}
+	return age.ParseIdentities(bytes.NewReader(data))
+}
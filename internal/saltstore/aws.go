@@ -0,0 +1,129 @@
+package saltstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+	"github.com/corehuman/hcs-lab-api/internal/secretstore"
+)
+
+// AWS keeps the salts in an AWS Secrets Manager secret. A secret it creates
+// is encrypted with KMSKeyID, or the account's aws/secretsmanager key.
+type AWS struct {
+	Region          string
+	SecretID        string // name or ARN of the secret
+	KMSKeyID        string // key ID, alias or ARN of the KMS key encrypting a created secret
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // set for temporary credentials
+	Endpoint        string // empty for https://secretsmanager.<region>.amazonaws.com
+	Client          *http.Client
+	Clock           clock.Clock // stamps the request signature; nil uses the system clock
+}
+
+// String names the secret
+func (a *AWS) String() string {
+	return "AWS Secrets Manager secret " + a.SecretID
+}
+
+// Get calls GetSecretValue for the current version of the secret
+func (a *AWS) Get(ctx context.Context) (string, error) {
+	var out struct {
+		SecretString string `json:"SecretString"`
+	}
+	err := a.call(ctx, "GetSecretValue", map[string]string{"SecretId": a.SecretID}, &out)
+	if isAWSError(err, "ResourceNotFoundException") {
+		return "", ErrNotFound
+	}
+	return out.SecretString, err
+}
+
+// Create calls CreateSecret, which fails when the secret exists
+func (a *AWS) Create(ctx context.Context, value string) error {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	in := map[string]string{
+		"Name":               a.SecretID,
+		"SecretString":       value,
+		"Description":        "HCS salt: the hex salt of every epoch, comma-separated in epoch order",
+		"ClientRequestToken": hex.EncodeToString(token),
+	}
+	if a.KMSKeyID != "" {
+		in["KmsKeyId"] = a.KMSKeyID
+	}
+	err := a.call(ctx, "CreateSecret", in, &struct{}{})
+	if isAWSError(err, "ResourceExistsException") {
+		return ErrExists
+	}
+	return err
+}
+
+// awsError is an error response of an AWS JSON API
+type awsError struct {
+	Status  int
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+func (e *awsError) Error() string {
+	return fmt.Sprintf("Secrets Manager returned status %d: %s: %s", e.Status, e.Type, e.Message)
+}
+
+// isAWSError reports whether err is an AWS error of the given type, which
+// may be qualified with a namespace
+func isAWSError(err error, typ string) bool {
+	e, ok := err.(*awsError)
+	return ok && (e.Type == typ || strings.HasSuffix(e.Type, "#"+typ))
+}
+
+// call invokes a Secrets Manager action with a signed request
+func (a *AWS) call(ctx context.Context, action string, in, out any) error {
+	endpoint := a.Endpoint
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + a.Region + ".amazonaws.com"
+	}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build Secrets Manager request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager."+action)
+	if a.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.SessionToken)
+	}
+	secretstore.SignV4(req, body, a.AccessKeyID, a.SecretAccessKey, a.Region, "secretsmanager", clock.Or(a.Clock).Now())
+
+	client := a.Client
+	if client == nil {
+		client = &http.Client{Timeout: Timeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Secrets Manager unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		e := &awsError{Status: resp.StatusCode}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		json.Unmarshal(data, e)
+		return e
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid Secrets Manager response: %w", err)
+	}
+	return nil
+}
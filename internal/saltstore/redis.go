@@ -0,0 +1,192 @@
+package saltstore
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultRedisKey is the key of the salts when none is configured
+const DefaultRedisKey = "hcs:salt"
+
+// maxReplySize bounds a bulk string reply; salts take 65 bytes per epoch
+const maxReplySize = 1 << 20
+
+// errNil is the nil reply of a Redis command
+var errNil = errors.New("nil reply")
+
+// Redis keeps the salts in a Redis string. Redis should persist to disk
+// (AOF or RDB): a salt lost with a Redis restart is replaced by a new one.
+type Redis struct {
+	Addr     string // host:port
+	Username string // ACL user; empty for the default user
+	Password string
+	DB       int
+	TLS      *tls.Config // set to connect over TLS
+	Key      string      // empty uses DefaultRedisKey
+}
+
+// ParseRedisURL configures a Redis store from a redis:// or rediss:// (TLS)
+// URL: redis://[[user]:password@]host[:port][/db]
+func ParseRedisURL(raw, key string) (*Redis, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	r := &Redis{Key: key}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		r.TLS = &tls.Config{ServerName: u.Hostname()}
+	default:
+		return nil, fmt.Errorf("invalid Redis URL: scheme must be redis or rediss, got %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid Redis URL: missing host")
+	}
+	port := u.Port()
+	if port == "" {
+		port = "6379"
+	}
+	r.Addr = net.JoinHostPort(u.Hostname(), port)
+	if u.User != nil {
+		r.Username = u.User.Username()
+		r.Password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if r.DB, err = strconv.Atoi(db); err != nil || r.DB < 0 {
+			return nil, fmt.Errorf("invalid Redis URL: database must be a number, got %q", db)
+		}
+	}
+	return r, nil
+}
+
+// String names the key and server
+func (r *Redis) String() string {
+	return fmt.Sprintf("Redis key %s at %s", r.key(), r.Addr)
+}
+
+func (r *Redis) key() string {
+	if r.Key == "" {
+		return DefaultRedisKey
+	}
+	return r.Key
+}
+
+// Get reads the key with GET
+func (r *Redis) Get(ctx context.Context) (string, error) {
+	value, err := r.do(ctx, "GET", r.key())
+	if errors.Is(err, errNil) {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+// Create sets the key with SET NX, which does nothing when it exists
+func (r *Redis) Create(ctx context.Context, value string) error {
+	_, err := r.do(ctx, "SET", r.key(), value, "NX")
+	if errors.Is(err, errNil) {
+		return ErrExists
+	}
+	return err
+}
+
+// do runs one command on a new connection, after authenticating and
+// selecting the database
+func (r *Redis) do(ctx context.Context, args ...string) (string, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", r.Addr)
+	if err != nil {
+		return "", fmt.Errorf("Redis unreachable: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if r.TLS != nil {
+		tlsConn := tls.Client(conn, r.TLS)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return "", fmt.Errorf("Redis TLS handshake failed: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	rd := bufio.NewReader(conn)
+	command := func(args ...string) (string, error) {
+		if err := writeCommand(conn, args); err != nil {
+			return "", fmt.Errorf("failed to send Redis command: %w", err)
+		}
+		return readReply(rd)
+	}
+	if r.Password != "" {
+		auth := []string{"AUTH", r.Password}
+		if r.Username != "" {
+			auth = []string{"AUTH", r.Username, r.Password}
+		}
+		if _, err := command(auth...); err != nil {
+			return "", fmt.Errorf("Redis authentication failed: %w", err)
+		}
+	}
+	if r.DB != 0 {
+		if _, err := command("SELECT", strconv.Itoa(r.DB)); err != nil {
+			return "", fmt.Errorf("failed to select Redis database %d: %w", r.DB, err)
+		}
+	}
+	return command(args...)
+}
+
+// writeCommand sends a command as a RESP array of bulk strings
+func writeCommand(w io.Writer, args []string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// readReply reads a simple string, error, integer or bulk string reply.
+// Nil replies return errNil.
+func readReply(rd *bufio.Reader) (string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read Redis reply: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return "", fmt.Errorf("invalid Redis reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", fmt.Errorf("Redis error: %s", line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("invalid Redis reply %q", line)
+		}
+		if n < 0 {
+			return "", errNil
+		}
+		if n > maxReplySize {
+			return "", fmt.Errorf("Redis reply of %d bytes exceeds %d", n, maxReplySize)
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, data); err != nil {
+			return "", fmt.Errorf("failed to read Redis reply: %w", err)
+		}
+		return string(data[:n]), nil
+	case '_':
+		return "", errNil // RESP3 null
+	default:
+		return "", fmt.Errorf("unexpected Redis reply %q", line)
+	}
+}
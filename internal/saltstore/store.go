@@ -0,0 +1,94 @@
+// Package saltstore keeps the persistent salt in a shared store instead of
+// the local salt directory (AWS Secrets Manager, encrypted with a KMS key, or
+// Redis), for containers whose filesystem does not survive a deploy.
+package saltstore
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// Timeout bounds each load of the salts from a store
+const Timeout = 10 * time.Second
+
+var (
+	// ErrNotFound is returned by Backend.Get when the store holds no salt
+	ErrNotFound = errors.New("salt not found")
+	// ErrExists is returned by Backend.Create when the store already holds a salt
+	ErrExists = errors.New("salt already exists")
+)
+
+// Backend holds the salts as one value: the hex salt of every epoch,
+// comma-separated in epoch order, as in HCS_SALT
+type Backend interface {
+	// Get returns the stored value, or ErrNotFound
+	Get(ctx context.Context) (string, error)
+	// Create stores value unless the store holds one already, in which case
+	// it returns ErrExists and leaves the stored value as it is
+	Create(ctx context.Context, value string) error
+	// String names the store in errors and logs
+	String() string
+}
+
+// Provider is an hcs.EpochSaltProvider reading the salts from a Backend. A
+// store without a salt gets a new epoch 0 salt on first use; when several
+// instances start at once, they all converge on the salt of whichever created
+// it first.
+type Provider struct {
+	Backend Backend
+	// Created is set when this provider created the salt
+	Created bool
+}
+
+// Salt returns the salt of the current epoch
+func (p *Provider) Salt() ([]byte, error) {
+	salts, current, err := p.SaltEpochs()
+	if err != nil {
+		return nil, err
+	}
+	return salts[current], nil
+}
+
+// SaltEpochs loads the salt of every epoch, creating epoch 0 if the store has none
+func (p *Provider) SaltEpochs() (map[int][]byte, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	value, err := p.Backend.Get(ctx)
+	if errors.Is(err, ErrNotFound) {
+		value, err = p.create(ctx)
+	}
+	if err != nil {
+		return nil, 0, errcode.Errorf(errcode.SaltUnavailable, "failed to load salt from %s: %w", p.Backend, err)
+	}
+	salts, current, err := hcs.ParseSalts(value)
+	if err != nil {
+		return nil, 0, errcode.Errorf(errcode.SaltUnavailable, "invalid salt in %s: %w", p.Backend, err)
+	}
+	return salts, current, nil
+}
+
+// create stores a new salt and returns the stored value
+func (p *Provider) create(ctx context.Context) (string, error) {
+	salt, err := hcs.GenerateSalt()
+	if err != nil {
+		return "", err
+	}
+	value := hex.EncodeToString(salt)
+	err = p.Backend.Create(ctx, value)
+	if errors.Is(err, ErrExists) {
+		// A concurrent instance created it first: converge on its salt
+		return p.Backend.Get(ctx)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create salt: %w", err)
+	}
+	p.Created = true
+	return value, nil
+}
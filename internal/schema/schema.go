@@ -0,0 +1,295 @@
+// Package schema validates JSON documents against the JSON Schemas that
+// describe the API's responses. It implements the subset of JSON Schema those
+// schemas use: type, properties, required, additionalProperties, items, enum,
+// pattern, minimum, maximum and local $ref into $defs.
+package schema
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+//go:embed output.schema.json
+var outputSchemaJSON []byte
+
+// output is the compiled OutputHCS schema
+var output = MustParse(outputSchemaJSON)
+
+// OutputJSON returns the raw OutputHCS JSON Schema document
+func OutputJSON() []byte {
+	return outputSchemaJSON
+}
+
+// Output returns the compiled OutputHCS schema
+func Output() *Schema {
+	return output
+}
+
+// Schema is one compiled JSON Schema node
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Defs                 map[string]*Schema `json:"$defs"`
+	Type                 types              `json:"type"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *additional        `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []any              `json:"enum"`
+	Pattern              string             `json:"pattern"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+
+	pattern *regexp.Regexp
+	ref     *Schema
+}
+
+// types is the "type" keyword, which may be a single name or a list
+type types []string
+
+func (t *types) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = types{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+	*t = many
+	return nil
+}
+
+// additional is the "additionalProperties" keyword: false forbids unknown
+// properties, a schema constrains them
+type additional struct {
+	forbidden bool
+	schema    *Schema
+}
+
+func (a *additional) UnmarshalJSON(data []byte) error {
+	var allowed bool
+	if err := json.Unmarshal(data, &allowed); err == nil {
+		a.forbidden = !allowed
+		return nil
+	}
+	a.schema = new(Schema)
+	return json.Unmarshal(data, a.schema)
+}
+
+// Violation is one place where a document does not match its schema
+type Violation struct {
+	Path    string `json:"path"` // JSON Pointer to the offending value
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
+
+// Parse compiles a schema document, resolving its $refs and patterns
+func Parse(data []byte) (*Schema, error) {
+	root := new(Schema)
+	if err := json.Unmarshal(data, root); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	if err := root.compile(root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// MustParse is Parse for schemas embedded in the binary
+func MustParse(data []byte) *Schema {
+	s, err := Parse(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s *Schema) compile(root *Schema) error {
+	if s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/$defs/")
+		if !ok {
+			return fmt.Errorf("unsupported $ref: %s", s.Ref)
+		}
+		if s.ref = root.Defs[name]; s.ref == nil {
+			return fmt.Errorf("unresolved $ref: %s", s.Ref)
+		}
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+
+	children := []*Schema{s.Items}
+	for _, def := range s.Defs {
+		children = append(children, def)
+	}
+	for _, prop := range s.Properties {
+		children = append(children, prop)
+	}
+	if s.AdditionalProperties != nil {
+		children = append(children, s.AdditionalProperties.schema)
+	}
+	for _, child := range children {
+		if child == nil {
+			continue
+		}
+		if err := child.compile(root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks a decoded JSON document (as produced by json.Unmarshal into
+// an any) and returns every violation, in document order
+func (s *Schema) Validate(doc any) []Violation {
+	var violations []Violation
+	s.validate(doc, "", &violations)
+	return violations
+}
+
+// ValidateOutput validates an OutputHCS as it will be serialized
+func ValidateOutput(out *hcs.OutputHCS) ([]Violation, error) {
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode output: %w", err)
+	}
+	return output.Validate(doc), nil
+}
+
+func (s *Schema) validate(v any, path string, violations *[]Violation) {
+	fail := func(format string, args ...any) {
+		p := path
+		if p == "" {
+			p = "/"
+		}
+		*violations = append(*violations, Violation{Path: p, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.ref != nil {
+		s.ref.validate(v, path, violations)
+	}
+	if len(s.Type) > 0 && !hasType(v, s.Type) {
+		fail("expected %s, got %s", strings.Join(s.Type, " or "), typeOf(v))
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(v, s.Enum) {
+		fail("value %v is not one of %v", v, s.Enum)
+	}
+
+	switch v := v.(type) {
+	case string:
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("value %q does not match %s", v, s.Pattern)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("value %v is below the minimum %v", v, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			fail("value %v is above the maximum %v", v, *s.Maximum)
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, fmt.Sprintf("%s/%d", path, i), violations)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		for _, name := range sortedKeys(v) {
+			child := path + "/" + escapePointer(name)
+			if prop, ok := s.Properties[name]; ok {
+				prop.validate(v[name], child, violations)
+				continue
+			}
+			if s.AdditionalProperties == nil {
+				continue
+			}
+			if s.AdditionalProperties.forbidden {
+				*violations = append(*violations, Violation{Path: child, Message: "unknown property"})
+			} else if s.AdditionalProperties.schema != nil {
+				s.AdditionalProperties.schema.validate(v[name], child, violations)
+			}
+		}
+	}
+}
+
+func hasType(v any, names []string) bool {
+	for _, name := range names {
+		switch name {
+		case "integer":
+			if f, ok := v.(float64); ok && f == math.Trunc(f) {
+				return true
+			}
+		default:
+			if typeOf(v) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func inEnum(v any, enum []any) bool {
+	for _, e := range enum {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapePointer escapes a property name as a JSON Pointer reference token
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
@@ -0,0 +1,148 @@
+package scoring
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// DefaultBankVersion is the item bank used when no version is selected
+const DefaultBankVersion = "2024.1"
+
+// embeddedBanks are the item banks shipped with the binary, one file per
+// version. Published versions are never edited: changes go in a new version,
+// so earlier responses can always be rescored with the bank they answered.
+//
+//go:embed banks/*.json
+var embeddedBanks embed.FS
+
+var (
+	banksMu  sync.RWMutex
+	banks    = map[string]*Bank{}
+	embedded = map[string]bool{}
+)
+
+func init() {
+	entries, err := embeddedBanks.ReadDir("banks")
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		data, err := embeddedBanks.ReadFile("banks/" + entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		b, err := LoadBank(data)
+		if err != nil {
+			panic(fmt.Errorf("embedded item bank %s: %w", entry.Name(), err))
+		}
+		banks[b.Version] = b
+		embedded[b.Version] = true
+	}
+	if banks[DefaultBankVersion] == nil {
+		panic("default item bank " + DefaultBankVersion + " is not embedded")
+	}
+}
+
+// RegisterBank makes an item bank selectable by version. A registered
+// version is immutable: embedded versions cannot be replaced, registering
+// the same bank again is a no-op, and different items need a new version.
+func RegisterBank(b *Bank) error {
+	banksMu.Lock()
+	defer banksMu.Unlock()
+	if err := checkBank(b); err != nil {
+		return err
+	}
+	banks[b.Version] = b
+	return nil
+}
+
+func checkBank(b *Bank) error {
+	if embedded[b.Version] {
+		return fmt.Errorf("item bank %q is built in and cannot be replaced", b.Version)
+	}
+	if registered, ok := banks[b.Version]; ok && (registered.Scale != b.Scale || !reflect.DeepEqual(registered.Items, b.Items)) {
+		return fmt.Errorf("item bank %q is already registered with other items; publish them under a new version", b.Version)
+	}
+	return nil
+}
+
+// LookupBank returns the item bank with the given version. An empty version
+// selects DefaultBankVersion.
+func LookupBank(version string) (*Bank, error) {
+	if version == "" {
+		version = DefaultBankVersion
+	}
+	banksMu.RLock()
+	defer banksMu.RUnlock()
+	b, ok := banks[version]
+	if !ok {
+		return nil, fmt.Errorf("unknown item bank version: %s", version)
+	}
+	return b, nil
+}
+
+// DefaultBank returns the item bank with DefaultBankVersion
+func DefaultBank() *Bank {
+	b, _ := LookupBank(DefaultBankVersion)
+	return b
+}
+
+// BankVersions lists the registered item bank versions in sorted order
+func BankVersions() []string {
+	banksMu.RLock()
+	defer banksMu.RUnlock()
+	versions := make([]string, 0, len(banks))
+	for v := range banks {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// ReadBanks parses every *.json item bank in dir without registering them.
+// Banks RegisterBank would refuse are rejected.
+func ReadBanks(dir string) ([]*Bank, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var read []*Bank
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read item bank: %w", err)
+		}
+		b, err := LoadBank(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		banksMu.RLock()
+		err = checkBank(b)
+		banksMu.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+		read = append(read, b)
+	}
+	return read, nil
+}
+
+// LoadBanks registers every *.json item bank in dir. Nothing is registered
+// when any is invalid.
+func LoadBanks(dir string) error {
+	read, err := ReadBanks(dir)
+	if err != nil {
+		return err
+	}
+	for _, b := range read {
+		if err := RegisterBank(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+// Package scoring converts questionnaire answers into the modal, cognition
+// and interaction values of an HCS input profile. The item banks and their keys
+// live here so that every client scores answers the same way.
+package scoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// Item is one questionnaire statement, answered on the bank's Likert scale
+type Item struct {
+	ID     string  `json:"id"`
+	Text   string  `json:"text"`
+	Target string  `json:"target"` // the value it measures, e.g. "cognition.fluid" or "interaction.tone:warm"
+	Weight float64 `json:"weight"`
+	// Reverse items measure the opposite of their target: agreeing lowers it
+	Reverse bool `json:"reverse,omitempty"`
+}
+
+// Bank is a versioned set of items. Answers run from 1 (strongly disagree) to Scale.
+type Bank struct {
+	Version string `json:"version"`
+	Scale   int    `json:"scale"`
+	Items   []Item `json:"items"`
+
+	byID map[string]Item
+}
+
+// numericTargets are the profile values scored as weighted means
+var numericTargets = []string{
+	"modal.cardinal", "modal.fixed", "modal.mutable",
+	"cognition.fluid", "cognition.crystallized", "cognition.verbal", "cognition.strategic", "cognition.creative",
+	"interaction.pace", "interaction.structure",
+}
+
+// toneTargets are the tones an answer set can select; neutral is the fallback
+var toneTargets = []string{"warm", "sharp", "precise"}
+
+// LoadBank parses and checks an item bank
+func LoadBank(data []byte) (*Bank, error) {
+	var b Bank
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse item bank: %w", err)
+	}
+	if b.Version == "" {
+		return nil, fmt.Errorf("item bank has no version")
+	}
+	if b.Scale < 2 {
+		return nil, fmt.Errorf("item bank scale must be at least 2, got %d", b.Scale)
+	}
+
+	b.byID = make(map[string]Item, len(b.Items))
+	for _, item := range b.Items {
+		if item.ID == "" {
+			return nil, fmt.Errorf("item bank has an item without id")
+		}
+		if _, dup := b.byID[item.ID]; dup {
+			return nil, fmt.Errorf("duplicate item id: %s", item.ID)
+		}
+		if !validTarget(item.Target) {
+			return nil, fmt.Errorf("item %s: unknown target %q", item.ID, item.Target)
+		}
+		if item.Weight <= 0 {
+			return nil, fmt.Errorf("item %s: weight must be positive", item.ID)
+		}
+		b.byID[item.ID] = item
+	}
+	return &b, nil
+}
+
+func validTarget(target string) bool {
+	if tone, ok := strings.CutPrefix(target, "interaction.tone:"); ok {
+		for _, t := range toneTargets {
+			if t == tone {
+				return true
+			}
+		}
+		return false
+	}
+	for _, t := range numericTargets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Result is a scored questionnaire, ready to be merged into an InputProfile
+type Result struct {
+	BankVersion string                     `json:"bankVersion"`
+	Modal       hcs.ModalBalance           `json:"modal"`
+	Cognition   hcs.CognitionProfile       `json:"cognition"`
+	Interaction hcs.InteractionPreferences `json:"interaction"`
+	// Unscored lists the targets no answer measured; they hold neutral defaults
+	Unscored []string `json:"unscored,omitempty"`
+}
+
+// Score converts answers (item ID to a value from 1 to Scale) into profile
+// values. Numeric values are weighted means of the answers rescaled to 0-1;
+// modal values are then rescaled to sum to 1.
+func (b *Bank) Score(answers map[string]int) (*Result, error) {
+	sums := map[string]float64{}
+	weights := map[string]float64{}
+
+	ids := make([]string, 0, len(answers))
+	for id := range answers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids) // fixed summation order keeps results reproducible
+
+	for _, id := range ids {
+		item, ok := b.byID[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown item: %s", id)
+		}
+		answer := answers[id]
+		if answer < 1 || answer > b.Scale {
+			return nil, fmt.Errorf("item %s: answer must be between 1 and %d, got %d", id, b.Scale, answer)
+		}
+		value := float64(answer-1) / float64(b.Scale-1)
+		if item.Reverse {
+			value = 1 - value
+		}
+		sums[item.Target] += value * item.Weight
+		weights[item.Target] += item.Weight
+	}
+
+	r := &Result{BankVersion: b.Version}
+	mean := func(target string) float64 {
+		if weights[target] == 0 {
+			r.Unscored = append(r.Unscored, target)
+			return 0.5
+		}
+		return sums[target] / weights[target]
+	}
+
+	r.Modal = normalizeModal(hcs.ModalBalance{
+		Cardinal: mean("modal.cardinal"),
+		Fixed:    mean("modal.fixed"),
+		Mutable:  mean("modal.mutable"),
+	})
+	r.Cognition = hcs.CognitionProfile{
+		Fluid:        round(mean("cognition.fluid")),
+		Crystallized: round(mean("cognition.crystallized")),
+		Verbal:       round(mean("cognition.verbal")),
+		Strategic:    round(mean("cognition.strategic")),
+		Creative:     round(mean("cognition.creative")),
+	}
+	r.Interaction = hcs.InteractionPreferences{
+		Pace:      level(mean("interaction.pace"), "slow", "balanced", "fast"),
+		Structure: level(mean("interaction.structure"), "low", "medium", "high"),
+		Tone:      "neutral",
+	}
+
+	// The tone is the most endorsed one, if any is clearly endorsed
+	best := 0.6
+	for _, tone := range toneTargets {
+		target := "interaction.tone:" + tone
+		if weights[target] == 0 {
+			continue
+		}
+		if m := sums[target] / weights[target]; m > best {
+			best = m
+			r.Interaction.Tone = tone
+		}
+	}
+	return r, nil
+}
+
+// normalizeModal rescales modal values to sum to 1, splitting evenly when all are 0
+func normalizeModal(m hcs.ModalBalance) hcs.ModalBalance {
+	total := m.Cardinal + m.Fixed + m.Mutable
+	if total == 0 {
+		return hcs.ModalBalance{Cardinal: 1.0 / 3, Fixed: 1.0 / 3, Mutable: 1.0 / 3}
+	}
+	return hcs.ModalBalance{
+		Cardinal: round(m.Cardinal / total),
+		Fixed:    round(m.Fixed / total),
+		Mutable:  round(m.Mutable / total),
+	}
+}
+
+// level maps a 0-1 score onto three ordered categories
+func level(v float64, low, mid, high string) string {
+	switch {
+	case v < 0.4:
+		return low
+	case v > 0.6:
+		return high
+	}
+	return mid
+}
+
+func round(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -0,0 +1,77 @@
+package secretstore
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// gcpMetadataToken is the access token endpoint of the GCE/GKE/Cloud Run metadata server
+const gcpMetadataToken = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// GCP reads the key from Google Cloud Secret Manager
+type GCP struct {
+	Name string // secret version, e.g. projects/p/secrets/hcs-key/versions/latest
+	// Token is an OAuth access token; empty fetches one for the attached
+	// service account from the metadata server
+	Token    string
+	Endpoint string // empty for https://secretmanager.googleapis.com
+	Client   *http.Client
+}
+
+// Fetch accesses the secret version
+func (g *GCP) Fetch(ctx context.Context) (string, error) {
+	token := g.Token
+	if token == "" {
+		var err error
+		if token, err = g.metadataToken(ctx); err != nil {
+			return "", err
+		}
+	}
+	endpoint := g.Endpoint
+	if endpoint == "" {
+		endpoint = "https://secretmanager.googleapis.com"
+	}
+	name := strings.Trim(g.Name, "/")
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/v1/"+name+":access", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build Secret Manager request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var out struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := doJSON(g.Client, req, "Secret Manager", &out); err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(out.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("invalid payload in secret %s: %w", name, err)
+	}
+	return string(data), nil
+}
+
+// metadataToken fetches an access token for the attached service account
+func (g *GCP) metadataToken(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataToken, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	var out struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doJSON(g.Client, req, "GCP metadata server", &out); err != nil {
+		return "", err
+	}
+	return out.AccessToken, nil
+}
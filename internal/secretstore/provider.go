@@ -0,0 +1,113 @@
+// Package secretstore fetches the HCS secret key from an external secret
+// manager (HashiCorp Vault, AWS Secrets Manager, GCP Secret Manager), so it
+// never has to live in a plain environment variable.
+package secretstore
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// DefaultTTL is how long a fetched key is served before it is fetched again
+const DefaultTTL = 5 * time.Minute
+
+// fetchTimeout bounds a fetch made on the signing path
+const fetchTimeout = 10 * time.Second
+
+// Fetcher reads the current secret value (the hex-encoded key) from a secret manager
+type Fetcher interface {
+	Fetch(ctx context.Context) (string, error)
+}
+
+// Provider is an hcs.SecretProvider caching the key from a Fetcher for TTL.
+// Once a key has been fetched, failed refreshes keep the previous key in use,
+// so a secret manager outage never breaks signing.
+type Provider struct {
+	Fetcher Fetcher
+	TTL     time.Duration // zero uses DefaultTTL
+	Clock   clock.Clock   // nil uses the system clock
+
+	mu        sync.Mutex
+	key       []byte
+	fetchedAt time.Time
+}
+
+// NewProvider returns a provider caching the key from f for ttl
+func NewProvider(f Fetcher, ttl time.Duration) *Provider {
+	return &Provider{Fetcher: f, TTL: ttl}
+}
+
+// SecretKey returns the cached key, fetching it when missing or older than TTL
+func (p *Provider) SecretKey() ([]byte, error) {
+	p.mu.Lock()
+	key, fresh := p.key, clock.Or(p.Clock).Now().Sub(p.fetchedAt) < p.ttl()
+	p.mu.Unlock()
+	if key != nil && fresh {
+		return key, nil
+	}
+
+	refreshed, err := p.Reload()
+	if err != nil {
+		if key != nil {
+			// Retry after another TTL rather than on every signature
+			slog.Warn("secret key refresh failed, keeping the previous key", "error", err)
+			p.mu.Lock()
+			p.fetchedAt = clock.Or(p.Clock).Now()
+			p.mu.Unlock()
+			return key, nil
+		}
+		return nil, err
+	}
+	return refreshed, nil
+}
+
+// Reload fetches the key now and replaces the cached one. On error the
+// previously cached key stays in use.
+func (p *Provider) Reload() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	value, err := p.Fetcher.Fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch secret key: %w", err)
+	}
+	key, err := hcs.ParseSecretKey(value)
+	if err != nil {
+		return nil, fmt.Errorf("fetched secret key is invalid: %w", err)
+	}
+
+	p.mu.Lock()
+	p.key = key
+	p.fetchedAt = clock.Or(p.Clock).Now()
+	p.mu.Unlock()
+	return key, nil
+}
+
+// Run refreshes the key every TTL until ctx is done, so rotations are picked
+// up without a fetch on the signing path
+func (p *Provider) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.ttl())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.Reload(); err != nil {
+				slog.Warn("secret key refresh failed, keeping the previous key", "error", err)
+			}
+		}
+	}
+}
+
+func (p *Provider) ttl() time.Duration {
+	if p.TTL <= 0 {
+		return DefaultTTL
+	}
+	return p.TTL
+}
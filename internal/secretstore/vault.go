@@ -0,0 +1,79 @@
+package secretstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Vault reads the key from a HashiCorp Vault KV version 2 secret
+type Vault struct {
+	Addr   string // e.g. https://vault.example.com:8200
+	Token  string
+	Path   string // API path of the secret, e.g. secret/data/hcs
+	Field  string // field holding the hex key; empty uses "key"
+	Client *http.Client
+}
+
+// NewVault creates a fetcher for the secret at path with a conservative client timeout
+func NewVault(addr, token, path, field string) *Vault {
+	return &Vault{
+		Addr:   strings.TrimSuffix(addr, "/"),
+		Token:  token,
+		Path:   strings.Trim(path, "/"),
+		Field:  field,
+		Client: &http.Client{Timeout: fetchTimeout},
+	}
+}
+
+// Fetch reads the latest version of the secret
+func (v *Vault) Fetch(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.Addr+"/v1/"+v.Path, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build Vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+
+	var body struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := doJSON(v.Client, req, "Vault", &body); err != nil {
+		return "", err
+	}
+	return stringField(body.Data.Data, v.Field, "Vault secret "+v.Path)
+}
+
+// doJSON sends req and decodes a 200 JSON response into out
+func doJSON(client *http.Client, req *http.Request, service string, out any) error {
+	if client == nil {
+		client = &http.Client{Timeout: fetchTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s unreachable: %w", service, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", service, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid %s response: %w", service, err)
+	}
+	return nil
+}
+
+// stringField returns the string field of a secret, "key" by default
+func stringField(data map[string]any, field, secret string) (string, error) {
+	if field == "" {
+		field = "key"
+	}
+	value, ok := data[field].(string)
+	if !ok || value == "" {
+		return "", fmt.Errorf("%s has no %q field", secret, field)
+	}
+	return value, nil
+}
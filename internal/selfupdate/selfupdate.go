@@ -0,0 +1,254 @@
+// Package selfupdate checks the release channels of the CLI and installs
+// newer releases. A channel is a JSON manifest signed with the project's
+// Ed25519 release key; the manifest carries the SHA-256 of every binary, so
+// nothing is installed unless both the signature and the checksum match.
+package selfupdate
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+)
+
+// Release channels
+const (
+	Stable = "stable"
+	Beta   = "beta"
+)
+
+// maxManifestSize and maxBinarySize bound the downloads
+const (
+	maxManifestSize = 1 << 20
+	maxBinarySize   = 256 << 20
+)
+
+// Asset is the binary of a release for one platform
+type Asset struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// URL is absolute, or relative to the manifest
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// Release is the manifest of a release channel
+type Release struct {
+	Version string    `json:"version"`
+	Channel string    `json:"channel"`
+	Date    time.Time `json:"date"`
+	// EngineVersion is the default BaZi engine version of the release
+	EngineVersion string  `json:"engineVersion,omitempty"`
+	Notes         string  `json:"notes,omitempty"`
+	Assets        []Asset `json:"assets"`
+
+	manifestURL string
+}
+
+// Asset returns the binary of the release for goos/goarch
+func (r *Release) Asset(goos, goarch string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.OS == goos && a.Arch == goarch {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Client fetches release manifests from BaseURL/<channel>.json, signed by
+// PublicKey in BaseURL/<channel>.json.sig (base64)
+type Client struct {
+	BaseURL    string
+	PublicKey  ed25519.PublicKey
+	HTTPClient *http.Client
+}
+
+// ParsePublicKey parses a base64 Ed25519 public key
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("release key must be a base64 Ed25519 public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// ValidChannel reports whether channel is a release channel
+func ValidChannel(channel string) bool {
+	return channel == Stable || channel == Beta
+}
+
+// Latest returns the current release of channel, after verifying the
+// signature of its manifest
+func (c *Client) Latest(ctx context.Context, channel string) (*Release, error) {
+	if !ValidChannel(channel) {
+		return nil, errcode.Errorf(errcode.InvalidRequest, "unknown release channel %q (want %s or %s)", channel, Stable, Beta)
+	}
+	if len(c.PublicKey) != ed25519.PublicKeySize {
+		return nil, errcode.Errorf(errcode.UpdateUnavailable, "this build has no release key")
+	}
+	manifestURL := strings.TrimSuffix(c.BaseURL, "/") + "/" + channel + ".json"
+	manifest, err := c.get(ctx, manifestURL, maxManifestSize)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := c.get(ctx, manifestURL+".sig", maxManifestSize)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil || !ed25519.Verify(c.PublicKey, manifest, raw) {
+		return nil, errcode.Errorf(errcode.UpdateUnverified, "the signature of the %s manifest does not match the release key", channel)
+	}
+
+	var r Release
+	if err := json.Unmarshal(manifest, &r); err != nil {
+		return nil, errcode.Errorf(errcode.UpdateUnavailable, "invalid %s manifest: %w", channel, err)
+	}
+	if r.Channel != channel {
+		return nil, errcode.Errorf(errcode.UpdateUnverified, "the %s manifest is signed for channel %q", channel, r.Channel)
+	}
+	if _, ok := parseVersion(r.Version); !ok {
+		return nil, errcode.Errorf(errcode.UpdateUnavailable, "invalid version %q in the %s manifest", r.Version, channel)
+	}
+	r.manifestURL = manifestURL
+	return &r, nil
+}
+
+// Download fetches the binary of asset and checks it against the manifest
+func (c *Client) Download(ctx context.Context, r *Release, asset Asset) ([]byte, error) {
+	want, err := hex.DecodeString(asset.SHA256)
+	if err != nil || len(want) != sha256.Size {
+		return nil, errcode.Errorf(errcode.UpdateUnavailable, "invalid checksum for %s/%s in the manifest", asset.OS, asset.Arch)
+	}
+	url := asset.URL
+	if !strings.Contains(url, "://") {
+		url = r.manifestURL[:strings.LastIndex(r.manifestURL, "/")+1] + strings.TrimPrefix(url, "/")
+	}
+	binary, err := c.get(ctx, url, maxBinarySize)
+	if err != nil {
+		return nil, err
+	}
+	if got := sha256.Sum256(binary); !bytes.Equal(got[:], want) {
+		return nil, errcode.Errorf(errcode.UpdateUnverified, "the downloaded binary does not match the checksum of the signed manifest")
+	}
+	return binary, nil
+}
+
+func (c *Client) get(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errcode.Errorf(errcode.UpdateUnavailable, "failed to reach the release server: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errcode.Errorf(errcode.UpdateUnavailable, "GET %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, errcode.Errorf(errcode.UpdateUnavailable, "GET %s: %w", url, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, errcode.Errorf(errcode.UpdateUnavailable, "GET %s: response larger than %d bytes", url, limit)
+	}
+	return data, nil
+}
+
+// Newer reports whether version a is newer than b. Versions are dotted
+// numbers with an optional suffix (1.2.0, 1.0.0-hcs-lab), compared on the
+// numbers only; unparsable versions are never newer.
+func Newer(a, b string) bool {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	if !okA || !okB {
+		return false
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			return va[i] > vb[i]
+		}
+	}
+	return false
+}
+
+func parseVersion(v string) ([3]int, bool) {
+	var out [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return out, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return out, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
+
+// Replace installs binary as the executable at exe. The new binary is written
+// next to exe and renamed over it; the previous binary is moved aside first,
+// since a running executable cannot be overwritten on Windows, and removed
+// when possible.
+func Replace(exe string, binary []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(exe), "."+filepath.Base(exe)+".new-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0111); err != nil {
+		return err
+	}
+
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		os.Rename(old, exe)
+		return err
+	}
+	os.Remove(old)
+	return nil
+}
@@ -0,0 +1,122 @@
+// Package signer splits U7 signing into a separate service, so generation
+// nodes never hold the secret key: Handler serves an hcs.Signer over HTTP on
+// the node holding the key, and Remote is the hcs.Signer calling it.
+package signer
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// SignU7Path is the endpoint of U7 signing requests
+const SignU7Path = "/sign/u7"
+
+// maxRequestBytes bounds a signing request; canonical profiles are a few KB
+const maxRequestBytes = 1 << 20
+
+// Remote is an hcs.Signer delegating to a signing service
+type Remote struct {
+	URL    string // base URL of the signing service
+	Token  string // bearer token the service requires
+	Client *http.Client
+}
+
+// NewRemote creates a signer for the service at url with a conservative client timeout
+func NewRemote(url, token string) *Remote {
+	return &Remote{
+		URL:    strings.TrimSuffix(url, "/"),
+		Token:  token,
+		Client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// SignU7 posts the request to the signing service
+func (r *Remote) SignU7(ctx context.Context, req hcs.U7SignRequest) (hcs.U7Signatures, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return hcs.U7Signatures{}, errcode.Errorf(errcode.SigningFailed, "failed to marshal signing request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL+SignU7Path, bytes.NewReader(body))
+	if err != nil {
+		return hcs.U7Signatures{}, errcode.Errorf(errcode.SigningFailed, "failed to build signing request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+r.Token)
+
+	resp, err := r.Client.Do(httpReq)
+	if err != nil {
+		return hcs.U7Signatures{}, errcode.Errorf(errcode.SigningFailed, "signing service unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return hcs.U7Signatures{}, errcode.Errorf(errcode.SigningFailed, "signing service returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var sigs hcs.U7Signatures
+	if err := json.NewDecoder(resp.Body).Decode(&sigs); err != nil {
+		return hcs.U7Signatures{}, errcode.Errorf(errcode.SigningFailed, "invalid signing service response: %w", err)
+	}
+	if sigs.QSig == "" || sigs.B3Sig == "" {
+		return hcs.U7Signatures{}, errcode.Errorf(errcode.SigningFailed, "signing service returned empty signatures")
+	}
+	return sigs, nil
+}
+
+// Handler serves signing requests with s to callers presenting token. An
+// empty token rejects every request.
+func Handler(s hcs.Signer, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(SignU7Path, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		presented := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			http.Error(w, "invalid signing token", http.StatusUnauthorized)
+			return
+		}
+
+		var req hcs.U7SignRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBytes)).Decode(&req); err != nil {
+			http.Error(w, "invalid signing request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(req.Canonical) == 0 || len(req.Salt) == 0 {
+			http.Error(w, "canonical data and salt are required", http.StatusBadRequest)
+			return
+		}
+		// Legacy requests recompute the signatures of existing codes
+		switch req.KeyDerivation {
+		case "", hcs.KeyDerivationLegacy, hcs.KeyDerivationHKDF:
+		default:
+			http.Error(w, "unknown key derivation: "+string(req.KeyDerivation), http.StatusBadRequest)
+			return
+		}
+		if req.SecondaryDigest != "" {
+			if _, err := hcs.ResolveSecondaryDigest(req.SecondaryDigest); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		sigs, err := s.SignU7(r.Context(), req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sigs)
+	})
+	return mux
+}
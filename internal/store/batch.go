@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DefaultChunkSize is the number of records SaveBatch writes per transaction
+const DefaultChunkSize = 500
+
+// ErrConflict is the outcome of a record rejected because its tenant
+// already stored its CHIP
+var ErrConflict = errors.New("a record with this CHIP is already stored")
+
+// Conflict decides what a batch save does with a record whose CHIP its
+// tenant already stored, or that appears earlier in the same batch
+type Conflict string
+
+const (
+	// ConflictUpsert replaces the stored record
+	ConflictUpsert Conflict = "upsert"
+	// ConflictReject keeps the stored record and reports ErrConflict
+	ConflictReject Conflict = "reject"
+)
+
+// ParseConflict returns the conflict mode named s; empty means upsert
+func ParseConflict(s string) (Conflict, error) {
+	switch c := Conflict(s); c {
+	case "":
+		return ConflictUpsert, nil
+	case ConflictUpsert, ConflictReject:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown conflict mode %q (want upsert or reject)", s)
+}
+
+// Batch is implemented by stores that write many records at once
+type Batch interface {
+	// SaveChunk writes recs in one transaction and returns the outcome of
+	// each, in order: nil when saved, ErrConflict when rejected. Conflicts
+	// are only checked, and records only replaced, within the tenant of each
+	// record. An error for the whole chunk means none of them was written.
+	SaveChunk(ctx context.Context, recs []Record, conflict Conflict) ([]error, error)
+}
+
+// SaveBatch writes recs in chunks of chunkSize records (DefaultChunkSize
+// when not positive), each chunk in one transaction when s implements
+// Batch, and returns the outcome of every record, nil when saved. A chunk
+// that fails as a whole fails each of its records with that error, and the
+// next chunks are still attempted. Other stores get one Save per record,
+// preceded by a Get under ConflictReject. A record never conflicts with, nor
+// replaces, the record of another tenant, so outcomes reveal nothing about
+// what other tenants store.
+func SaveBatch(ctx context.Context, s Store, recs []Record, conflict Conflict, chunkSize int) []error {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	b := batchOf(s)
+	outcomes := make([]error, len(recs))
+	for start := 0; start < len(recs); start += chunkSize {
+		chunk := recs[start:min(start+chunkSize, len(recs))]
+		if b == nil {
+			for i, rec := range chunk {
+				outcomes[start+i] = saveOne(ctx, s, rec, conflict)
+			}
+			continue
+		}
+		chunkOutcomes, err := b.SaveChunk(ctx, chunk, conflict)
+		for i := range chunk {
+			if err != nil {
+				outcomes[start+i] = err
+			} else {
+				outcomes[start+i] = chunkOutcomes[i]
+			}
+		}
+	}
+	return outcomes
+}
+
+// saveOne saves rec on a store without Batch
+func saveOne(ctx context.Context, s Store, rec Record, conflict Conflict) error {
+	if conflict == ConflictReject {
+		_, err := s.Get(ctx, rec.TenantID, rec.Chip)
+		if err == nil {
+			return ErrConflict
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return s.Save(ctx, rec)
+}
+
+// batchOf returns the batch writer of s, or nil when s has none. It sees
+// through WithBreaker, routing the calls through the breaker.
+func batchOf(s Store) Batch {
+	switch s := s.(type) {
+	case *breakerStore:
+		if inner := batchOf(s.s); inner != nil {
+			return &breakerBatch{w: inner, b: s.b}
+		}
+		return nil
+	case Batch:
+		return s
+	}
+	return nil
+}
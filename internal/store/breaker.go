@@ -0,0 +1,229 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/breaker"
+)
+
+// breakerStore routes every call of a store through a circuit breaker
+type breakerStore struct {
+	s Store
+	b *breaker.Breaker
+}
+
+// WithBreaker wraps s so that its calls fail fast with breaker.ErrOpen while b
+// is open. ErrNotFound is an answer, not a failure, and does not trip b.
+func WithBreaker(s Store, b *breaker.Breaker) Store {
+	if s == nil {
+		return nil
+	}
+	return &breakerStore{s: s, b: b}
+}
+
+func (bs *breakerStore) Save(ctx context.Context, rec Record) error {
+	return bs.b.Do(func() error { return bs.s.Save(ctx, rec) })
+}
+
+func (bs *breakerStore) Get(ctx context.Context, tenantID, chip string) (*Record, error) {
+	var rec *Record
+	var err error
+	if berr := bs.b.Do(func() error {
+		rec, err = bs.s.Get(ctx, tenantID, chip)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}); berr != nil {
+		return nil, berr
+	}
+	return rec, err
+}
+
+func (bs *breakerStore) List(ctx context.Context, tenantID string) ([]Record, error) {
+	var records []Record
+	err := bs.b.Do(func() error {
+		var err error
+		records, err = bs.s.List(ctx, tenantID)
+		return err
+	})
+	return records, err
+}
+
+func (bs *breakerStore) ListAll(ctx context.Context) ([]Record, error) {
+	var records []Record
+	err := bs.b.Do(func() error {
+		var err error
+		records, err = bs.s.ListAll(ctx)
+		return err
+	})
+	return records, err
+}
+
+// breakerRevocations routes the revocation calls of a store through its breaker
+type breakerRevocations struct {
+	r Revocations
+	b *breaker.Breaker
+}
+
+func (br *breakerRevocations) Revoke(ctx context.Context, rev Revocation) error {
+	return br.b.Do(func() error { return br.r.Revoke(ctx, rev) })
+}
+
+func (br *breakerRevocations) GetRevocation(ctx context.Context, chip string) (*Revocation, error) {
+	var rev *Revocation
+	var err error
+	if berr := br.b.Do(func() error {
+		rev, err = br.r.GetRevocation(ctx, chip)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}); berr != nil {
+		return nil, berr
+	}
+	return rev, err
+}
+
+func (br *breakerRevocations) ListRevocations(ctx context.Context) ([]Revocation, error) {
+	var revs []Revocation
+	err := br.b.Do(func() error {
+		var err error
+		revs, err = br.r.ListRevocations(ctx)
+		return err
+	})
+	return revs, err
+}
+
+// breakerTokens routes the token calls of a store through its breaker. Unusable
+// tokens are answers, not failures, and do not trip it.
+type breakerTokens struct {
+	t Tokens
+	b *breaker.Breaker
+}
+
+func (bt *breakerTokens) SaveTokens(ctx context.Context, tokens []Token) error {
+	return bt.b.Do(func() error { return bt.t.SaveTokens(ctx, tokens) })
+}
+
+func (bt *breakerTokens) ConsumeToken(ctx context.Context, hash string, now time.Time) (*Token, error) {
+	var tok *Token
+	var err error
+	if berr := bt.b.Do(func() error {
+		tok, err = bt.t.ConsumeToken(ctx, hash, now)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrTokenUsed) || errors.Is(err, ErrTokenExpired) {
+			return nil
+		}
+		return err
+	}); berr != nil {
+		return nil, berr
+	}
+	return tok, err
+}
+
+func (bt *breakerTokens) TokenBatch(ctx context.Context, batchID string) ([]Token, error) {
+	var tokens []Token
+	var err error
+	if berr := bt.b.Do(func() error {
+		tokens, err = bt.t.TokenBatch(ctx, batchID)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}); berr != nil {
+		return nil, berr
+	}
+	return tokens, err
+}
+
+// breakerBatch routes the batch writes of a store through its breaker.
+// Rejected records are answers, not failures, and do not trip it.
+type breakerBatch struct {
+	w Batch
+	b *breaker.Breaker
+}
+
+func (bb *breakerBatch) SaveChunk(ctx context.Context, recs []Record, conflict Conflict) ([]error, error) {
+	var outcomes []error
+	err := bb.b.Do(func() error {
+		var err error
+		outcomes, err = bb.w.SaveChunk(ctx, recs, conflict)
+		return err
+	})
+	return outcomes, err
+}
+
+// breakerWebhooks routes the webhook calls of a store through its breaker
+type breakerWebhooks struct {
+	w Webhooks
+	b *breaker.Breaker
+}
+
+func (bw *breakerWebhooks) SaveWebhook(ctx context.Context, wh Webhook) error {
+	return bw.b.Do(func() error { return bw.w.SaveWebhook(ctx, wh) })
+}
+
+func (bw *breakerWebhooks) GetWebhook(ctx context.Context, id string) (*Webhook, error) {
+	var wh *Webhook
+	var err error
+	if berr := bw.b.Do(func() error {
+		wh, err = bw.w.GetWebhook(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}); berr != nil {
+		return nil, berr
+	}
+	return wh, err
+}
+
+func (bw *breakerWebhooks) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var webhooks []Webhook
+	err := bw.b.Do(func() error {
+		var err error
+		webhooks, err = bw.w.ListWebhooks(ctx)
+		return err
+	})
+	return webhooks, err
+}
+
+func (bw *breakerWebhooks) DeleteWebhook(ctx context.Context, id string) error {
+	var err error
+	if berr := bw.b.Do(func() error {
+		err = bw.w.DeleteWebhook(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}); berr != nil {
+		return berr
+	}
+	return err
+}
+
+func (bw *breakerWebhooks) SaveDeadLetter(ctx context.Context, dl DeadLetter) error {
+	return bw.b.Do(func() error { return bw.w.SaveDeadLetter(ctx, dl) })
+}
+
+func (bw *breakerWebhooks) ListDeadLetters(ctx context.Context, webhookID string) ([]DeadLetter, error) {
+	var dls []DeadLetter
+	err := bw.b.Do(func() error {
+		var err error
+		dls, err = bw.w.ListDeadLetters(ctx, webhookID)
+		return err
+	})
+	return dls, err
+}
+
+// breakerClusters routes the cluster labels of a store through its breaker
+type breakerClusters struct {
+	c Clusters
+	b *breaker.Breaker
+}
+
+func (bc *breakerClusters) SetClusterIDs(ctx context.Context, tenantID string, ids map[string]int) error {
+	return bc.b.Do(func() error { return bc.c.SetClusterIDs(ctx, tenantID, ids) })
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Clusters is implemented by stores that update the cluster labels of
+// records in place, leaving the rest of each record as it is
+type Clusters interface {
+	// SetClusterIDs sets the ClusterID of the records of tenantID named by
+	// the CHIPs of ids. CHIPs that are no longer stored are skipped.
+	SetClusterIDs(ctx context.Context, tenantID string, ids map[string]int) error
+}
+
+// ClustersOf returns the cluster labels of s, or nil when it has none
+func ClustersOf(s Store) Clusters {
+	switch s := s.(type) {
+	case *breakerStore:
+		if inner := ClustersOf(s.s); inner != nil {
+			return &breakerClusters{c: inner, b: s.b}
+		}
+		return nil
+	case Clusters:
+		return s
+	}
+	return nil
+}
+
+// SetClusterIDs sets the ClusterID of the records of tenantID named by the
+// CHIPs of ids, in place when s implements Clusters. Other stores get a Get
+// and a Save of each record.
+func SetClusterIDs(ctx context.Context, s Store, tenantID string, ids map[string]int) error {
+	if c := ClustersOf(s); c != nil {
+		return c.SetClusterIDs(ctx, tenantID, ids)
+	}
+	for chip, id := range ids {
+		rec, err := s.Get(ctx, tenantID, chip)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to label %s: %w", chip, err)
+		}
+		rec.ClusterID = &id
+		if err := s.Save(ctx, *rec); err != nil {
+			return fmt.Errorf("failed to label %s: %w", chip, err)
+		}
+	}
+	return nil
+}
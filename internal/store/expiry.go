@@ -0,0 +1,27 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ExpiringRecords returns the records of every tenant that expire within
+// window of now and have not been reminded yet
+func ExpiringRecords(ctx context.Context, s Store, now time.Time, window time.Duration) ([]Record, error) {
+	records, err := s.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list records: %w", err)
+	}
+
+	var due []Record
+	for _, rec := range records {
+		if rec.ValidUntil.IsZero() || !rec.ReminderSentAt.IsZero() {
+			continue
+		}
+		if !now.Add(window).Before(rec.ValidUntil) {
+			due = append(due, rec)
+		}
+	}
+	return due, nil
+}
@@ -0,0 +1,426 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileStore persists records as a single JSON document, rewritten atomically on
+// every change. It suits small self-hosted installs and offline CLI tooling.
+// Metadata, revocations, verification tokens and webhooks are kept in
+// documents next to it: <path>.meta, <path>.revocations, <path>.tokens and
+// <path>.webhooks.
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+	mem  *MemoryStore
+}
+
+// OpenFileStore loads the store at path and its metadata, starting empty if the files do not exist
+func OpenFileStore(path string) (*FileStore, error) {
+	fs := &FileStore{path: path, mem: NewMemoryStore()}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read store file: %w", err)
+	default:
+		var records []Record
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, fmt.Errorf("failed to parse store file %s: %w", path, err)
+		}
+		for _, rec := range records {
+			fs.mem.put(rec)
+		}
+	}
+
+	meta, err := os.ReadFile(fs.metaPath())
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read store metadata: %w", err)
+	default:
+		if err := json.Unmarshal(meta, &fs.mem.meta); err != nil {
+			return nil, fmt.Errorf("failed to parse store metadata %s: %w", fs.metaPath(), err)
+		}
+	}
+
+	var revocations []Revocation
+	if err := readDocument(fs.revocationsPath(), "revocations", &revocations); err != nil {
+		return nil, err
+	}
+	for _, rev := range revocations {
+		fs.mem.revoked[rev.Chip] = rev
+	}
+	var tokens []Token
+	if err := readDocument(fs.tokensPath(), "tokens", &tokens); err != nil {
+		return nil, err
+	}
+	for _, tok := range tokens {
+		fs.mem.tokens[tok.Hash] = tok
+	}
+	var hooks webhooksDocument
+	if err := readDocument(fs.webhooksPath(), "webhooks", &hooks); err != nil {
+		return nil, err
+	}
+	for _, wh := range hooks.Webhooks {
+		fs.mem.hooks[wh.ID] = wh
+	}
+	for _, dl := range hooks.DeadLetters {
+		fs.mem.dead[dl.WebhookID] = append(fs.mem.dead[dl.WebhookID], dl)
+	}
+	return fs, nil
+}
+
+// readDocument decodes the JSON document at path into v, leaving v unchanged
+// when the file does not exist
+func readDocument(path, name string, v any) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read store %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse store %s %s: %w", name, path, err)
+	}
+	return nil
+}
+
+// Save inserts or replaces the record for rec.Chip within rec.TenantID and
+// flushes the file
+func (f *FileStore) Save(ctx context.Context, rec Record) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.mem.Save(ctx, rec); err != nil {
+		return err
+	}
+	return f.flush(ctx)
+}
+
+// SaveChunk writes recs at once and flushes the file once
+func (f *FileStore) SaveChunk(ctx context.Context, recs []Record, conflict Conflict) ([]error, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	type key struct{ tenantID, chip string }
+	f.mem.mu.RLock()
+	previous := make(map[key]*Record, len(recs))
+	for _, rec := range recs {
+		if old, ok := f.mem.records[rec.TenantID][rec.Chip]; ok {
+			previous[key{rec.TenantID, rec.Chip}] = &old
+		} else {
+			previous[key{rec.TenantID, rec.Chip}] = nil
+		}
+	}
+	f.mem.mu.RUnlock()
+
+	outcomes, err := f.mem.SaveChunk(ctx, recs, conflict)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.flush(ctx); err != nil {
+		// Not on disk, so the chunk must not be visible either
+		f.mem.mu.Lock()
+		for k, old := range previous {
+			if old == nil {
+				delete(f.mem.records[k.tenantID], k.chip)
+			} else {
+				f.mem.put(*old)
+			}
+		}
+		f.mem.mu.Unlock()
+		return nil, err
+	}
+	return outcomes, nil
+}
+
+// SetClusterIDs sets the ClusterID of the stored records of tenantID among
+// ids and flushes the file once
+func (f *FileStore) SetClusterIDs(ctx context.Context, tenantID string, ids map[string]int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.mem.mu.RLock()
+	previous := make(map[string]*int, len(ids))
+	for chip := range ids {
+		if rec, ok := f.mem.records[tenantID][chip]; ok {
+			previous[chip] = rec.ClusterID
+		}
+	}
+	f.mem.mu.RUnlock()
+
+	if err := f.mem.SetClusterIDs(ctx, tenantID, ids); err != nil {
+		return err
+	}
+	if err := f.flush(ctx); err != nil {
+		// Not on disk, so the labels must not be visible either
+		f.mem.mu.Lock()
+		for chip, old := range previous {
+			if rec, ok := f.mem.records[tenantID][chip]; ok {
+				rec.ClusterID = old
+				f.mem.records[tenantID][chip] = rec
+			}
+		}
+		f.mem.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Get returns the record of tenantID for chip or ErrNotFound
+func (f *FileStore) Get(ctx context.Context, tenantID, chip string) (*Record, error) {
+	return f.mem.Get(ctx, tenantID, chip)
+}
+
+// List returns the records of tenantID ordered by creation time
+func (f *FileStore) List(ctx context.Context, tenantID string) ([]Record, error) {
+	return f.mem.List(ctx, tenantID)
+}
+
+// ListAll returns the records of every tenant ordered by creation time
+func (f *FileStore) ListAll(ctx context.Context) ([]Record, error) {
+	return f.mem.ListAll(ctx)
+}
+
+// GetMeta returns the value of key or ErrNotFound
+func (f *FileStore) GetMeta(ctx context.Context, key string) (string, error) {
+	return f.mem.GetMeta(ctx, key)
+}
+
+// SetMeta inserts or replaces the value of key and flushes the metadata file
+func (f *FileStore) SetMeta(ctx context.Context, key, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.mem.SetMeta(ctx, key, value); err != nil {
+		return err
+	}
+	f.mem.mu.RLock()
+	data, err := json.Marshal(f.mem.meta)
+	f.mem.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal store metadata: %w", err)
+	}
+	return replaceFile(f.metaPath(), data)
+}
+
+func (f *FileStore) metaPath() string {
+	return f.path + ".meta"
+}
+
+// Revoke inserts or replaces the revocation of rev.Chip and flushes the revocations file
+func (f *FileStore) Revoke(ctx context.Context, rev Revocation) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.mem.Revoke(ctx, rev); err != nil {
+		return err
+	}
+	revocations, err := f.mem.ListRevocations(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(revocations)
+	if err != nil {
+		return fmt.Errorf("failed to marshal store revocations: %w", err)
+	}
+	return replaceFile(f.revocationsPath(), data)
+}
+
+// GetRevocation returns the revocation of chip or ErrNotFound
+func (f *FileStore) GetRevocation(ctx context.Context, chip string) (*Revocation, error) {
+	return f.mem.GetRevocation(ctx, chip)
+}
+
+// ListRevocations returns every revocation ordered by revocation time
+func (f *FileStore) ListRevocations(ctx context.Context) ([]Revocation, error) {
+	return f.mem.ListRevocations(ctx)
+}
+
+func (f *FileStore) revocationsPath() string {
+	return f.path + ".revocations"
+}
+
+// SaveTokens inserts a batch of unused tokens and flushes the tokens file
+func (f *FileStore) SaveTokens(ctx context.Context, tokens []Token) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.mem.SaveTokens(ctx, tokens); err != nil {
+		return err
+	}
+	return f.flushTokens()
+}
+
+// ConsumeToken marks the token with hash as used at now, atomically, and
+// flushes the tokens file
+func (f *FileStore) ConsumeToken(ctx context.Context, hash string, now time.Time) (*Token, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tok, err := f.mem.ConsumeToken(ctx, hash, now)
+	if err != nil {
+		return tok, err
+	}
+	if err := f.flushTokens(); err != nil {
+		// Not recorded as used, so it must not be accepted either
+		f.mem.mu.Lock()
+		unused := *tok
+		unused.UsedAt = time.Time{}
+		f.mem.tokens[hash] = unused
+		f.mem.mu.Unlock()
+		return nil, err
+	}
+	return tok, nil
+}
+
+// TokenBatch returns the tokens of a batch or ErrNotFound
+func (f *FileStore) TokenBatch(ctx context.Context, batchID string) ([]Token, error) {
+	return f.mem.TokenBatch(ctx, batchID)
+}
+
+func (f *FileStore) tokensPath() string {
+	return f.path + ".tokens"
+}
+
+// flushTokens rewrites the tokens file
+func (f *FileStore) flushTokens() error {
+	f.mem.mu.RLock()
+	tokens := make([]Token, 0, len(f.mem.tokens))
+	for _, tok := range f.mem.tokens {
+		tokens = append(tokens, tok)
+	}
+	f.mem.mu.RUnlock()
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Hash < tokens[j].Hash })
+
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return fmt.Errorf("failed to marshal store tokens: %w", err)
+	}
+	return replaceFile(f.tokensPath(), data)
+}
+
+// webhooksDocument is the content of the webhooks file
+type webhooksDocument struct {
+	Webhooks    []Webhook    `json:"webhooks"`
+	DeadLetters []DeadLetter `json:"deadLetters"`
+}
+
+// SaveWebhook inserts or replaces the webhook wh.ID and flushes the webhooks file
+func (f *FileStore) SaveWebhook(ctx context.Context, wh Webhook) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.mem.SaveWebhook(ctx, wh); err != nil {
+		return err
+	}
+	return f.flushWebhooks(ctx)
+}
+
+// GetWebhook returns the webhook id or ErrNotFound
+func (f *FileStore) GetWebhook(ctx context.Context, id string) (*Webhook, error) {
+	return f.mem.GetWebhook(ctx, id)
+}
+
+// ListWebhooks returns every webhook ordered by creation time
+func (f *FileStore) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	return f.mem.ListWebhooks(ctx)
+}
+
+// DeleteWebhook deletes the webhook id and its dead letters, and flushes the
+// webhooks file
+func (f *FileStore) DeleteWebhook(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.mem.DeleteWebhook(ctx, id); err != nil {
+		return err
+	}
+	return f.flushWebhooks(ctx)
+}
+
+// SaveDeadLetter inserts a dead letter and flushes the webhooks file
+func (f *FileStore) SaveDeadLetter(ctx context.Context, dl DeadLetter) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.mem.SaveDeadLetter(ctx, dl); err != nil {
+		return err
+	}
+	return f.flushWebhooks(ctx)
+}
+
+// ListDeadLetters returns the dead letters of a webhook, oldest first
+func (f *FileStore) ListDeadLetters(ctx context.Context, webhookID string) ([]DeadLetter, error) {
+	return f.mem.ListDeadLetters(ctx, webhookID)
+}
+
+func (f *FileStore) webhooksPath() string {
+	return f.path + ".webhooks"
+}
+
+// flushWebhooks rewrites the webhooks file
+func (f *FileStore) flushWebhooks(ctx context.Context) error {
+	var doc webhooksDocument
+	var err error
+	if doc.Webhooks, err = f.mem.ListWebhooks(ctx); err != nil {
+		return err
+	}
+	doc.DeadLetters = []DeadLetter{}
+	for _, wh := range doc.Webhooks {
+		dls, err := f.mem.ListDeadLetters(ctx, wh.ID)
+		if err != nil {
+			return err
+		}
+		doc.DeadLetters = append(doc.DeadLetters, dls...)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal store webhooks: %w", err)
+	}
+	return replaceFile(f.webhooksPath(), data)
+}
+
+// flush writes all records to a temporary file and renames it over the store file
+func (f *FileStore) flush(ctx context.Context) error {
+	records, err := f.mem.ListAll(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to marshal store: %w", err)
+	}
+	return replaceFile(f.path, data)
+}
+
+// replaceFile writes data to a temporary file and renames it over path
+func replaceFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".hcs_store_*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary store file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write store file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write store file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace store file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,355 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a process-local Store, suitable for tests and single-instance deployments
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string]map[string]Record // by tenant ID, then CHIP
+	meta    map[string]string
+	revoked map[string]Revocation
+	tokens  map[string]Token
+	hooks   map[string]Webhook
+	dead    map[string][]DeadLetter // by webhook ID, oldest first
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[string]map[string]Record),
+		meta:    make(map[string]string),
+		revoked: make(map[string]Revocation),
+		tokens:  make(map[string]Token),
+		hooks:   make(map[string]Webhook),
+		dead:    make(map[string][]DeadLetter),
+	}
+}
+
+// Save inserts or replaces the record for rec.Chip within rec.TenantID
+func (m *MemoryStore) Save(ctx context.Context, rec Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.put(rec)
+	return nil
+}
+
+// put stores rec; the caller holds the write lock
+func (m *MemoryStore) put(rec Record) {
+	tenant := m.records[rec.TenantID]
+	if tenant == nil {
+		tenant = make(map[string]Record)
+		m.records[rec.TenantID] = tenant
+	}
+	tenant[rec.Chip] = rec
+}
+
+// SaveChunk writes recs at once, in order
+func (m *MemoryStore) SaveChunk(ctx context.Context, recs []Record, conflict Conflict) ([]error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	outcomes := make([]error, len(recs))
+	for i, rec := range recs {
+		if _, ok := m.records[rec.TenantID][rec.Chip]; ok && conflict == ConflictReject {
+			outcomes[i] = ErrConflict
+			continue
+		}
+		m.put(rec)
+	}
+	return outcomes, nil
+}
+
+// Get returns the record of tenantID for chip or ErrNotFound
+func (m *MemoryStore) Get(ctx context.Context, tenantID, chip string) (*Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rec, ok := m.records[tenantID][chip]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &rec, nil
+}
+
+// List returns the records of tenantID ordered by creation time (CHIP breaks ties)
+func (m *MemoryStore) List(ctx context.Context, tenantID string) ([]Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Record, 0, len(m.records[tenantID]))
+	for _, rec := range m.records[tenantID] {
+		out = append(out, rec)
+	}
+	sortRecords(out)
+	return out, nil
+}
+
+// ListAll returns the records of every tenant ordered by creation time
+// (tenant ID, then CHIP, breaks ties)
+func (m *MemoryStore) ListAll(ctx context.Context) ([]Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Record, 0)
+	for _, tenant := range m.records {
+		for _, rec := range tenant {
+			out = append(out, rec)
+		}
+	}
+	sortRecords(out)
+	return out, nil
+}
+
+// sortRecords orders records by creation time, tenant ID and CHIP
+func sortRecords(records []Record) {
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		if a.TenantID != b.TenantID {
+			return a.TenantID < b.TenantID
+		}
+		return a.Chip < b.Chip
+	})
+}
+
+// SetClusterIDs sets the ClusterID of the stored records of tenantID among ids
+func (m *MemoryStore) SetClusterIDs(ctx context.Context, tenantID string, ids map[string]int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for chip, id := range ids {
+		if rec, ok := m.records[tenantID][chip]; ok {
+			rec.ClusterID = &id
+			m.records[tenantID][chip] = rec
+		}
+	}
+	return nil
+}
+
+// GetMeta returns the value of key or ErrNotFound
+func (m *MemoryStore) GetMeta(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	value, ok := m.meta[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// SetMeta inserts or replaces the value of key
+func (m *MemoryStore) SetMeta(ctx context.Context, key, value string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.meta[key] = value
+	return nil
+}
+
+// Revoke inserts or replaces the revocation of rev.Chip
+func (m *MemoryStore) Revoke(ctx context.Context, rev Revocation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revoked[rev.Chip] = rev
+	return nil
+}
+
+// GetRevocation returns the revocation of chip or ErrNotFound
+func (m *MemoryStore) GetRevocation(ctx context.Context, chip string) (*Revocation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rev, ok := m.revoked[chip]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &rev, nil
+}
+
+// ListRevocations returns every revocation ordered by revocation time (CHIP breaks ties)
+func (m *MemoryStore) ListRevocations(ctx context.Context) ([]Revocation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Revocation, 0, len(m.revoked))
+	for _, rev := range m.revoked {
+		out = append(out, rev)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].RevokedAt.Equal(out[j].RevokedAt) {
+			return out[i].Chip < out[j].Chip
+		}
+		return out[i].RevokedAt.Before(out[j].RevokedAt)
+	})
+	return out, nil
+}
+
+// SaveTokens inserts a batch of unused tokens
+func (m *MemoryStore) SaveTokens(ctx context.Context, tokens []Token) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, tok := range tokens {
+		m.tokens[tok.Hash] = tok
+	}
+	return nil
+}
+
+// ConsumeToken marks the token with hash as used at now, atomically
+func (m *MemoryStore) ConsumeToken(ctx context.Context, hash string, now time.Time) (*Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tok, ok := m.tokens[hash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if err := consumable(&tok, now); err != nil {
+		return &tok, err
+	}
+	tok.UsedAt = now
+	m.tokens[hash] = tok
+	return &tok, nil
+}
+
+// TokenBatch returns the tokens of a batch, ordered by hash, or ErrNotFound
+func (m *MemoryStore) TokenBatch(ctx context.Context, batchID string) ([]Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []Token
+	for _, tok := range m.tokens {
+		if tok.BatchID == batchID {
+			out = append(out, tok)
+		}
+	}
+	if len(out) == 0 {
+		return nil, ErrNotFound
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Hash < out[j].Hash })
+	return out, nil
+}
+
+// SaveWebhook inserts or replaces the webhook wh.ID
+func (m *MemoryStore) SaveWebhook(ctx context.Context, wh Webhook) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks[wh.ID] = wh
+	return nil
+}
+
+// GetWebhook returns the webhook id or ErrNotFound
+func (m *MemoryStore) GetWebhook(ctx context.Context, id string) (*Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	wh, ok := m.hooks[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &wh, nil
+}
+
+// ListWebhooks returns every webhook ordered by creation time (ID breaks ties)
+func (m *MemoryStore) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Webhook, 0, len(m.hooks))
+	for _, wh := range m.hooks {
+		out = append(out, wh)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+// DeleteWebhook deletes the webhook id and its dead letters, or returns ErrNotFound
+func (m *MemoryStore) DeleteWebhook(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.hooks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.hooks, id)
+	delete(m.dead, id)
+	return nil
+}
+
+// SaveDeadLetter inserts a dead letter, dropping the oldest ones of its
+// webhook beyond MaxDeadLetters
+func (m *MemoryStore) SaveDeadLetter(ctx context.Context, dl DeadLetter) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	dls := append(m.dead[dl.WebhookID], dl)
+	if len(dls) > MaxDeadLetters {
+		dls = dls[len(dls)-MaxDeadLetters:]
+	}
+	m.dead[dl.WebhookID] = dls
+	return nil
+}
+
+// ListDeadLetters returns the dead letters of a webhook, oldest first
+func (m *MemoryStore) ListDeadLetters(ctx context.Context, webhookID string) ([]DeadLetter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]DeadLetter(nil), m.dead[webhookID]...), nil
+}
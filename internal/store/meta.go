@@ -0,0 +1,12 @@
+package store
+
+import "context"
+
+// Metadata is implemented by stores that keep key/value settings next to
+// their records, such as the salt fingerprints checked at startup
+type Metadata interface {
+	// GetMeta returns the value of key or ErrNotFound
+	GetMeta(ctx context.Context, key string) (string, error)
+	// SetMeta inserts or replaces the value of key
+	SetMeta(ctx context.Context, key, value string) error
+}
@@ -0,0 +1,9 @@
+package store
+
+import "github.com/corehuman/hcs-lab-api/internal/migrate"
+
+// Migrator is implemented by stores with a versioned SQL schema. Opening such
+// a store does not change its schema; the caller applies the migrations.
+type Migrator interface {
+	Migrations() *migrate.Runner
+}
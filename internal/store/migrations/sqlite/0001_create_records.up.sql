@@ -0,0 +1,15 @@
+CREATE TABLE records (
+	chip TEXT PRIMARY KEY,
+	subject_id TEXT NOT NULL DEFAULT '',
+	tenant_id TEXT NOT NULL DEFAULT '',
+	match_opt_in INTEGER NOT NULL DEFAULT 0,
+	input TEXT NOT NULL,
+	output TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	valid_until TEXT NOT NULL DEFAULT '',
+	cluster_id INTEGER,
+	reminder_sent_at TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX records_created_at ON records (created_at, chip);
+CREATE INDEX records_subject_id ON records (subject_id);
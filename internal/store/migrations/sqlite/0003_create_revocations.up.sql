@@ -0,0 +1,7 @@
+CREATE TABLE revocations (
+	chip TEXT PRIMARY KEY,
+	reason TEXT NOT NULL DEFAULT '',
+	revoked_at TEXT NOT NULL
+);
+
+CREATE INDEX revocations_revoked_at ON revocations (revoked_at, chip);
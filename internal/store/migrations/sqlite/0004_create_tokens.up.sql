@@ -0,0 +1,11 @@
+CREATE TABLE tokens (
+	hash TEXT PRIMARY KEY,
+	batch_id TEXT NOT NULL,
+	chip TEXT NOT NULL,
+	event TEXT NOT NULL DEFAULT '',
+	created_at TEXT NOT NULL,
+	expires_at TEXT NOT NULL,
+	used_at TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX tokens_batch_id ON tokens (batch_id);
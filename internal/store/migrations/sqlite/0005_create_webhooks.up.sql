@@ -0,0 +1,21 @@
+CREATE TABLE webhooks (
+	id TEXT PRIMARY KEY,
+	tenant_id TEXT NOT NULL DEFAULT '',
+	url TEXT NOT NULL,
+	events TEXT NOT NULL DEFAULT '[]',
+	secret TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	secret_rotated_at TEXT NOT NULL
+);
+
+CREATE TABLE dead_letters (
+	id TEXT PRIMARY KEY,
+	webhook_id TEXT NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+	type TEXT NOT NULL,
+	body TEXT NOT NULL,
+	attempts INTEGER NOT NULL,
+	last_error TEXT NOT NULL DEFAULT '',
+	failed_at TEXT NOT NULL
+);
+
+CREATE INDEX dead_letters_webhook_id ON dead_letters (webhook_id, failed_at);
@@ -0,0 +1,23 @@
+CREATE TABLE records_by_chip (
+	chip TEXT PRIMARY KEY,
+	subject_id TEXT NOT NULL DEFAULT '',
+	tenant_id TEXT NOT NULL DEFAULT '',
+	match_opt_in INTEGER NOT NULL DEFAULT 0,
+	input TEXT NOT NULL,
+	output TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	valid_until TEXT NOT NULL DEFAULT '',
+	cluster_id INTEGER,
+	reminder_sent_at TEXT NOT NULL DEFAULT ''
+);
+
+-- A CHIP stored by several tenants keeps only its most recent record
+INSERT OR REPLACE INTO records_by_chip
+	(chip, subject_id, tenant_id, match_opt_in, input, output, created_at, valid_until, cluster_id, reminder_sent_at)
+	SELECT chip, subject_id, tenant_id, match_opt_in, input, output, created_at, valid_until, cluster_id, reminder_sent_at
+	FROM records ORDER BY created_at, tenant_id;
+DROP TABLE records;
+ALTER TABLE records_by_chip RENAME TO records;
+
+CREATE INDEX records_created_at ON records (created_at, chip);
+CREATE INDEX records_subject_id ON records (subject_id);
@@ -0,0 +1,24 @@
+CREATE TABLE records_by_tenant (
+	chip TEXT NOT NULL,
+	subject_id TEXT NOT NULL DEFAULT '',
+	tenant_id TEXT NOT NULL DEFAULT '',
+	match_opt_in INTEGER NOT NULL DEFAULT 0,
+	input TEXT NOT NULL,
+	output TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	valid_until TEXT NOT NULL DEFAULT '',
+	cluster_id INTEGER,
+	reminder_sent_at TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (tenant_id, chip)
+);
+
+INSERT INTO records_by_tenant
+	(chip, subject_id, tenant_id, match_opt_in, input, output, created_at, valid_until, cluster_id, reminder_sent_at)
+	SELECT chip, subject_id, tenant_id, match_opt_in, input, output, created_at, valid_until, cluster_id, reminder_sent_at
+	FROM records;
+DROP TABLE records;
+ALTER TABLE records_by_tenant RENAME TO records;
+
+CREATE INDEX records_created_at ON records (created_at, tenant_id, chip);
+CREATE INDEX records_tenant_created_at ON records (tenant_id, created_at, chip);
+CREATE INDEX records_subject_id ON records (tenant_id, subject_id);
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Open creates a store from a DSN:
+//
+//	memory            process-local, lost on restart
+//	file:<path>       JSON document on local disk
+//	sqlite:<path>     SQLite database on local disk, with a migrated schema
+//
+// An empty DSN returns a nil store (storage disabled).
+func Open(dsn string) (Store, error) {
+	switch {
+	case dsn == "":
+		return nil, nil
+	case dsn == "memory":
+		return NewMemoryStore(), nil
+	case strings.HasPrefix(dsn, "file:"):
+		path := strings.TrimPrefix(dsn, "file:")
+		if path == "" {
+			return nil, fmt.Errorf("file store DSN requires a path")
+		}
+		fs, err := OpenFileStore(path)
+		if err != nil {
+			return nil, err
+		}
+		return fs, nil
+	case strings.HasPrefix(dsn, "sqlite:"):
+		path := strings.TrimPrefix(dsn, "sqlite:")
+		if path == "" {
+			return nil, fmt.Errorf("sqlite store DSN requires a path")
+		}
+		s, err := OpenSQLiteStore(path)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage DSN: %q", dsn)
+	}
+}
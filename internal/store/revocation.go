@@ -0,0 +1,40 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Revocation marks a CHIP whose codes must no longer be accepted, e.g. after
+// a compromise or a mistaken generation
+type Revocation struct {
+	Chip      string    `json:"chip"`
+	Reason    string    `json:"reason,omitempty"`
+	RevokedAt time.Time `json:"revokedAt"`
+}
+
+// Revocations is implemented by stores that keep a revocation list next to
+// their records. Revocations are kept even when the record of the CHIP is not.
+type Revocations interface {
+	// Revoke inserts or replaces the revocation of rev.Chip
+	Revoke(ctx context.Context, rev Revocation) error
+	// GetRevocation returns the revocation of chip or ErrNotFound
+	GetRevocation(ctx context.Context, chip string) (*Revocation, error)
+	// ListRevocations returns every revocation ordered by revocation time
+	ListRevocations(ctx context.Context) ([]Revocation, error)
+}
+
+// RevocationsOf returns the revocation list of s, or nil when s keeps none.
+// It sees through WithBreaker, routing the calls through the breaker.
+func RevocationsOf(s Store) Revocations {
+	switch s := s.(type) {
+	case *breakerStore:
+		if inner := RevocationsOf(s.s); inner != nil {
+			return &breakerRevocations{r: inner, b: s.b}
+		}
+		return nil
+	case Revocations:
+		return s
+	}
+	return nil
+}
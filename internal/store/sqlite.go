@@ -0,0 +1,569 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+	"github.com/corehuman/hcs-lab-api/internal/migrate"
+)
+
+//go:embed migrations/sqlite/*.sql
+var sqliteMigrationFiles embed.FS
+
+// sqliteTime stores times in UTC with a fixed width, so that they sort as text
+const sqliteTime = "2006-01-02T15:04:05.000000000Z"
+
+// SQLiteStore persists records in a local SQLite database, for single-binary
+// deployments. Its schema is managed by Migrations.
+type SQLiteStore struct {
+	db         *sql.DB
+	migrations []migrate.Migration
+	Clock      clock.Clock // stamps metadata updates; nil uses the system clock
+}
+
+// SQLiteAvailable reports whether SQLite stores can be opened: always, unless
+// built with the hcs_nosqlite tag
+func SQLiteAvailable() bool {
+	return sqliteDriver != ""
+}
+
+// OpenSQLiteStore opens (or creates) the database at path. It does not apply
+// migrations.
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	if !SQLiteAvailable() {
+		return nil, errors.New("SQLite storage is not available: this binary was built with the hcs_nosqlite tag")
+	}
+	sub, err := fs.Sub(sqliteMigrationFiles, "migrations/sqlite")
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := migrate.Load(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(sqliteDriver, "file:"+path+"?_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	// SQLite allows a single writer; one connection avoids lock contention
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open SQLite database %s: %w", path, err)
+	}
+	return &SQLiteStore{db: db, migrations: migrations}, nil
+}
+
+// Migrations returns the runner for the store's schema
+func (s *SQLiteStore) Migrations() *migrate.Runner {
+	return &migrate.Runner{DB: s.db, Migrations: s.migrations}
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Save inserts or replaces the record for rec.Chip within rec.TenantID
+func (s *SQLiteStore) Save(ctx context.Context, rec Record) error {
+	args, err := recordArgs(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, "INSERT OR REPLACE"+insertRecord, args...); err != nil {
+		return fmt.Errorf("failed to save record: %w", err)
+	}
+	return nil
+}
+
+// insertRecord completes an INSERT statement of a record with the
+// arguments of recordArgs
+const insertRecord = ` INTO records
+	(chip, subject_id, tenant_id, match_opt_in, input, output, created_at, valid_until, cluster_id, reminder_sent_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// recordArgs returns the column values of rec, in insertRecord order
+func recordArgs(rec Record) ([]any, error) {
+	input, err := json.Marshal(rec.Input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode record input: %w", err)
+	}
+	output, err := json.Marshal(rec.Output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode record output: %w", err)
+	}
+	var clusterID sql.NullInt64
+	if rec.ClusterID != nil {
+		clusterID = sql.NullInt64{Int64: int64(*rec.ClusterID), Valid: true}
+	}
+	return []any{rec.Chip, rec.SubjectID, rec.TenantID, rec.MatchOptIn, string(input), string(output),
+		formatSQLiteTime(rec.CreatedAt), formatSQLiteTime(rec.ValidUntil), clusterID, formatSQLiteTime(rec.ReminderSentAt)}, nil
+}
+
+// SaveChunk writes recs in one transaction with a single prepared statement.
+// A record that cannot be encoded fails alone.
+func (s *SQLiteStore) SaveChunk(ctx context.Context, recs []Record, conflict Conflict) ([]error, error) {
+	verb := "INSERT OR REPLACE"
+	if conflict == ConflictReject {
+		verb = "INSERT OR IGNORE"
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save records: %w", err)
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, verb+insertRecord)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save records: %w", err)
+	}
+	defer stmt.Close()
+
+	outcomes := make([]error, len(recs))
+	for i, rec := range recs {
+		args, err := recordArgs(rec)
+		if err != nil {
+			outcomes[i] = err
+			continue
+		}
+		res, err := stmt.ExecContext(ctx, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save records: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to save records: %w", err)
+		} else if n == 0 {
+			outcomes[i] = ErrConflict
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to save records: %w", err)
+	}
+	return outcomes, nil
+}
+
+// SetClusterIDs sets the cluster_id of the stored records of tenantID among
+// ids in one transaction, without rewriting the rest of them
+func (s *SQLiteStore) SetClusterIDs(ctx context.Context, tenantID string, ids map[string]int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to label records: %w", err)
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, "UPDATE records SET cluster_id = ? WHERE tenant_id = ? AND chip = ?")
+	if err != nil {
+		return fmt.Errorf("failed to label records: %w", err)
+	}
+	defer stmt.Close()
+	for chip, id := range ids {
+		if _, err := stmt.ExecContext(ctx, id, tenantID, chip); err != nil {
+			return fmt.Errorf("failed to label %s: %w", chip, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to label records: %w", err)
+	}
+	return nil
+}
+
+// GetMeta returns the value of key or ErrNotFound
+func (s *SQLiteStore) GetMeta(ctx context.Context, key string) (string, error) {
+	var value string
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM metadata WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read metadata %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// SetMeta inserts or replaces the value of key
+func (s *SQLiteStore) SetMeta(ctx context.Context, key, value string) error {
+	_, err := s.db.ExecContext(ctx, "INSERT OR REPLACE INTO metadata (key, value, updated_at) VALUES (?, ?, ?)",
+		key, value, formatSQLiteTime(clock.Or(s.Clock).Now()))
+	if err != nil {
+		return fmt.Errorf("failed to save metadata %s: %w", key, err)
+	}
+	return nil
+}
+
+// Revoke inserts or replaces the revocation of rev.Chip
+func (s *SQLiteStore) Revoke(ctx context.Context, rev Revocation) error {
+	_, err := s.db.ExecContext(ctx, "INSERT OR REPLACE INTO revocations (chip, reason, revoked_at) VALUES (?, ?, ?)",
+		rev.Chip, rev.Reason, formatSQLiteTime(rev.RevokedAt))
+	if err != nil {
+		return fmt.Errorf("failed to save revocation %s: %w", rev.Chip, err)
+	}
+	return nil
+}
+
+// GetRevocation returns the revocation of chip or ErrNotFound
+func (s *SQLiteStore) GetRevocation(ctx context.Context, chip string) (*Revocation, error) {
+	rev, err := scanRevocation(s.db.QueryRowContext(ctx, "SELECT chip, reason, revoked_at FROM revocations WHERE chip = ?", chip))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// ListRevocations returns every revocation ordered by revocation time (CHIP breaks ties)
+func (s *SQLiteStore) ListRevocations(ctx context.Context) ([]Revocation, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT chip, reason, revoked_at FROM revocations ORDER BY revoked_at, chip")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revocations: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Revocation
+	for rows.Next() {
+		rev, err := scanRevocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *rev)
+	}
+	return out, rows.Err()
+}
+
+func scanRevocation(row interface{ Scan(...any) error }) (*Revocation, error) {
+	var rev Revocation
+	var revokedAt string
+	if err := row.Scan(&rev.Chip, &rev.Reason, &revokedAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if rev.RevokedAt, err = parseSQLiteTime(revokedAt); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// SaveTokens inserts a batch of unused tokens in one transaction
+func (s *SQLiteStore) SaveTokens(ctx context.Context, tokens []Token) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to save tokens: %w", err)
+	}
+	defer tx.Rollback()
+	for _, tok := range tokens {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO tokens (hash, batch_id, chip, event, created_at, expires_at, used_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`, tok.Hash, tok.BatchID, tok.Chip, tok.Event,
+			formatSQLiteTime(tok.CreatedAt), formatSQLiteTime(tok.ExpiresAt), formatSQLiteTime(tok.UsedAt)); err != nil {
+			return fmt.Errorf("failed to save tokens: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save tokens: %w", err)
+	}
+	return nil
+}
+
+// ConsumeToken marks the token with hash as used at now, with a single
+// conditional update so that only one of concurrent calls succeeds
+func (s *SQLiteStore) ConsumeToken(ctx context.Context, hash string, now time.Time) (*Token, error) {
+	res, err := s.db.ExecContext(ctx, "UPDATE tokens SET used_at = ? WHERE hash = ? AND used_at = '' AND expires_at > ?",
+		formatSQLiteTime(now), hash, formatSQLiteTime(now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume token: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume token: %w", err)
+	}
+
+	tok, err := scanToken(s.db.QueryRowContext(ctx, selectTokens+" WHERE hash = ?", hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		if err := consumable(tok, now); err != nil {
+			return tok, err
+		}
+		return tok, ErrTokenUsed
+	}
+	return tok, nil
+}
+
+// TokenBatch returns the tokens of a batch, ordered by hash, or ErrNotFound
+func (s *SQLiteStore) TokenBatch(ctx context.Context, batchID string) ([]Token, error) {
+	rows, err := s.db.QueryContext(ctx, selectTokens+" WHERE batch_id = ? ORDER BY hash", batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Token
+	for rows.Next() {
+		tok, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *tok)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, ErrNotFound
+	}
+	return out, nil
+}
+
+const selectTokens = "SELECT hash, batch_id, chip, event, created_at, expires_at, used_at FROM tokens"
+
+func scanToken(row interface{ Scan(...any) error }) (*Token, error) {
+	var tok Token
+	var createdAt, expiresAt, usedAt string
+	if err := row.Scan(&tok.Hash, &tok.BatchID, &tok.Chip, &tok.Event, &createdAt, &expiresAt, &usedAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if tok.CreatedAt, err = parseSQLiteTime(createdAt); err != nil {
+		return nil, err
+	}
+	if tok.ExpiresAt, err = parseSQLiteTime(expiresAt); err != nil {
+		return nil, err
+	}
+	if tok.UsedAt, err = parseSQLiteTime(usedAt); err != nil {
+		return nil, err
+	}
+	return &tok, nil
+}
+
+const selectRecords = `SELECT chip, subject_id, tenant_id, match_opt_in, input, output, created_at, valid_until,
+	cluster_id, reminder_sent_at FROM records`
+
+// Get returns the record of tenantID for chip or ErrNotFound
+func (s *SQLiteStore) Get(ctx context.Context, tenantID, chip string) (*Record, error) {
+	rec, err := scanRecord(s.db.QueryRowContext(ctx, selectRecords+" WHERE tenant_id = ? AND chip = ?", tenantID, chip))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// List returns the records of tenantID ordered by creation time (CHIP breaks ties)
+func (s *SQLiteStore) List(ctx context.Context, tenantID string) ([]Record, error) {
+	return s.listRecords(ctx, selectRecords+" WHERE tenant_id = ? ORDER BY created_at, chip", tenantID)
+}
+
+// ListAll returns the records of every tenant ordered by creation time
+// (tenant ID, then CHIP, breaks ties)
+func (s *SQLiteStore) ListAll(ctx context.Context) ([]Record, error) {
+	return s.listRecords(ctx, selectRecords+" ORDER BY created_at, tenant_id, chip")
+}
+
+func (s *SQLiteStore) listRecords(ctx context.Context, query string, args ...any) ([]Record, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list records: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Record
+	for rows.Next() {
+		rec, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *rec)
+	}
+	return out, rows.Err()
+}
+
+func scanRecord(row interface{ Scan(...any) error }) (*Record, error) {
+	var rec Record
+	var input, output, createdAt, validUntil, reminderSentAt string
+	var clusterID sql.NullInt64
+	if err := row.Scan(&rec.Chip, &rec.SubjectID, &rec.TenantID, &rec.MatchOptIn, &input, &output,
+		&createdAt, &validUntil, &clusterID, &reminderSentAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(input), &rec.Input); err != nil {
+		return nil, fmt.Errorf("failed to decode record %s: %w", rec.Chip, err)
+	}
+	if err := json.Unmarshal([]byte(output), &rec.Output); err != nil {
+		return nil, fmt.Errorf("failed to decode record %s: %w", rec.Chip, err)
+	}
+	if clusterID.Valid {
+		id := int(clusterID.Int64)
+		rec.ClusterID = &id
+	}
+	var err error
+	if rec.CreatedAt, err = parseSQLiteTime(createdAt); err != nil {
+		return nil, err
+	}
+	if rec.ValidUntil, err = parseSQLiteTime(validUntil); err != nil {
+		return nil, err
+	}
+	if rec.ReminderSentAt, err = parseSQLiteTime(reminderSentAt); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// SaveWebhook inserts or replaces the webhook wh.ID
+func (s *SQLiteStore) SaveWebhook(ctx context.Context, wh Webhook) error {
+	events, err := json.Marshal(wh.Events)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook events: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO webhooks (id, tenant_id, url, events, secret, created_at, secret_rotated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET tenant_id = excluded.tenant_id, url = excluded.url, events = excluded.events,
+			secret = excluded.secret, created_at = excluded.created_at, secret_rotated_at = excluded.secret_rotated_at`,
+		wh.ID, wh.TenantID, wh.URL, string(events), wh.Secret, formatSQLiteTime(wh.CreatedAt), formatSQLiteTime(wh.SecretRotatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to save webhook %s: %w", wh.ID, err)
+	}
+	return nil
+}
+
+// GetWebhook returns the webhook id or ErrNotFound
+func (s *SQLiteStore) GetWebhook(ctx context.Context, id string) (*Webhook, error) {
+	wh, err := scanWebhook(s.db.QueryRowContext(ctx, selectWebhooks+" WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return wh, err
+}
+
+// ListWebhooks returns every webhook ordered by creation time (ID breaks ties)
+func (s *SQLiteStore) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, selectWebhooks+" ORDER BY created_at, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Webhook
+	for rows.Next() {
+		wh, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *wh)
+	}
+	return out, rows.Err()
+}
+
+// DeleteWebhook deletes the webhook id, and its dead letters by cascade
+func (s *SQLiteStore) DeleteWebhook(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook %s: %w", id, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to delete webhook %s: %w", id, err)
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SaveDeadLetter inserts a dead letter and drops the oldest ones of its
+// webhook beyond MaxDeadLetters, in one transaction
+func (s *SQLiteStore) SaveDeadLetter(ctx context.Context, dl DeadLetter) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to save dead letter: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO dead_letters (id, webhook_id, type, body, attempts, last_error, failed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, dl.ID, dl.WebhookID, dl.Type, string(dl.Body), dl.Attempts, dl.LastError,
+		formatSQLiteTime(dl.FailedAt)); err != nil {
+		return fmt.Errorf("failed to save dead letter: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM dead_letters WHERE webhook_id = ? AND id NOT IN
+		(SELECT id FROM dead_letters WHERE webhook_id = ? ORDER BY failed_at DESC, id DESC LIMIT ?)`,
+		dl.WebhookID, dl.WebhookID, MaxDeadLetters); err != nil {
+		return fmt.Errorf("failed to save dead letter: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save dead letter: %w", err)
+	}
+	return nil
+}
+
+// ListDeadLetters returns the dead letters of a webhook, oldest first
+func (s *SQLiteStore) ListDeadLetters(ctx context.Context, webhookID string) ([]DeadLetter, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, webhook_id, type, body, attempts, last_error, failed_at
+		FROM dead_letters WHERE webhook_id = ? ORDER BY failed_at, id`, webhookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var out []DeadLetter
+	for rows.Next() {
+		var dl DeadLetter
+		var body, failedAt string
+		if err := rows.Scan(&dl.ID, &dl.WebhookID, &dl.Type, &body, &dl.Attempts, &dl.LastError, &failedAt); err != nil {
+			return nil, err
+		}
+		dl.Body = json.RawMessage(body)
+		if dl.FailedAt, err = parseSQLiteTime(failedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, dl)
+	}
+	return out, rows.Err()
+}
+
+const selectWebhooks = "SELECT id, tenant_id, url, events, secret, created_at, secret_rotated_at FROM webhooks"
+
+func scanWebhook(row interface{ Scan(...any) error }) (*Webhook, error) {
+	var wh Webhook
+	var events, createdAt, rotatedAt string
+	if err := row.Scan(&wh.ID, &wh.TenantID, &wh.URL, &events, &wh.Secret, &createdAt, &rotatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(events), &wh.Events); err != nil {
+		return nil, fmt.Errorf("invalid stored webhook events %q: %w", events, err)
+	}
+	var err error
+	if wh.CreatedAt, err = parseSQLiteTime(createdAt); err != nil {
+		return nil, err
+	}
+	if wh.SecretRotatedAt, err = parseSQLiteTime(rotatedAt); err != nil {
+		return nil, err
+	}
+	return &wh, nil
+}
+
+// formatSQLiteTime encodes the zero time as an empty string
+func formatSQLiteTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(sqliteTime)
+}
+
+func parseSQLiteTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(sqliteTime, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid stored time %q: %w", v, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,10 @@
+//go:build !hcs_nosqlite
+
+package store
+
+// The SQLite driver links a C library through cgo; builds tagged hcs_nosqlite
+// leave it out so the binaries build with CGO_ENABLED=0
+import _ "github.com/mattn/go-sqlite3"
+
+// sqliteDriver is the database/sql driver of SQLite stores
+const sqliteDriver = "sqlite3"
@@ -0,0 +1,6 @@
+//go:build hcs_nosqlite
+
+package store
+
+// sqliteDriver is empty in builds without SQLite
+const sqliteDriver = ""
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// ErrNotFound is returned when the tenant has no record for the requested CHIP
+var ErrNotFound = errors.New("record not found")
+
+// Record is a generated HCS output persisted together with its input profile
+type Record struct {
+	Chip       string           `json:"chip"`
+	SubjectID  string           `json:"subjectId,omitempty"`  // caller-supplied identifier linking versions of one subject
+	TenantID   string           `json:"tenantId,omitempty"`   // isolates records of different organizations
+	MatchOptIn bool             `json:"matchOptIn,omitempty"` // record may be suggested to others by matchmaking
+	Input      hcs.InputProfile `json:"input"`
+	Output     *hcs.OutputHCS   `json:"output"`
+	CreatedAt  time.Time        `json:"createdAt"`
+	ValidUntil time.Time        `json:"validUntil,omitempty"` // zero when the codes never expire
+
+	// ClusterID is the archetype cluster assigned by the latest analytics run
+	ClusterID *int `json:"clusterId,omitempty"`
+
+	// ReminderSentAt records when an expiry reminder was emitted for this record
+	ReminderSentAt time.Time `json:"reminderSentAt,omitempty"`
+}
+
+// Store persists generated HCS records keyed by tenant ID and CHIP, so that
+// tenants generating the same profile each keep their own record
+type Store interface {
+	// Save inserts or replaces the record for rec.Chip within rec.TenantID
+	Save(ctx context.Context, rec Record) error
+	// Get returns the record of tenantID for chip or ErrNotFound
+	Get(ctx context.Context, tenantID, chip string) (*Record, error)
+	// List returns the records of tenantID ordered by creation time
+	List(ctx context.Context, tenantID string) ([]Record, error)
+	// ListAll returns the records of every tenant ordered by creation time,
+	// for background jobs and operator tooling
+	ListAll(ctx context.Context) ([]Record, error)
+}
+
+// NewRecord builds a record from a generation result, deriving the expiry from its metadata
+func NewRecord(in hcs.InputProfile, out *hcs.OutputHCS, now time.Time) Record {
+	// Traces are debugging aids and are not persisted
+	if out.Trace != nil {
+		stored := *out
+		stored.Trace = nil
+		out = &stored
+	}
+
+	rec := Record{
+		Chip:      out.Chip,
+		Input:     in,
+		Output:    out,
+		CreatedAt: now.UTC(),
+	}
+	if validUntil, ok, err := hcs.ParseValidUntil(out.Metadata); err == nil && ok {
+		rec.ValidUntil = validUntil
+	}
+	return rec
+}
+
+// LatestForSubject returns the most recent record of tenantID for subjectID
+// or ErrNotFound
+func LatestForSubject(ctx context.Context, s Store, tenantID, subjectID string) (*Record, error) {
+	records, err := s.List(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].SubjectID == subjectID {
+			return &records[i], nil
+		}
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// Errors of ConsumeToken for tokens that exist but cannot be used
+var (
+	ErrTokenUsed    = errors.New("token already used")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// Token is a single-use verification token bound to a CHIP, e.g. for a
+// conference check-in. Only the hash of the token is stored.
+type Token struct {
+	Hash      string    `json:"hash"` // TokenHash of the token
+	BatchID   string    `json:"batchId"`
+	Chip      string    `json:"chip"`
+	Event     string    `json:"event,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	UsedAt    time.Time `json:"usedAt,omitempty"` // zero until consumed
+}
+
+// Tokens is implemented by stores that keep verification tokens
+type Tokens interface {
+	// SaveTokens inserts a batch of unused tokens
+	SaveTokens(ctx context.Context, tokens []Token) error
+	// ConsumeToken marks the token with hash as used at now, atomically: of
+	// concurrent calls for one token, only one succeeds. It returns the token
+	// as consumed, or ErrNotFound, ErrTokenUsed or ErrTokenExpired with the
+	// token as stored.
+	ConsumeToken(ctx context.Context, hash string, now time.Time) (*Token, error)
+	// TokenBatch returns the tokens of a batch or ErrNotFound
+	TokenBatch(ctx context.Context, batchID string) ([]Token, error)
+}
+
+// TokenHash returns the stored form of a token
+func TokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokensOf returns the token store of s, or nil when s keeps no tokens. It
+// sees through WithBreaker, routing the calls through the breaker.
+func TokensOf(s Store) Tokens {
+	switch s := s.(type) {
+	case *breakerStore:
+		if inner := TokensOf(s.s); inner != nil {
+			return &breakerTokens{t: inner, b: s.b}
+		}
+		return nil
+	case Tokens:
+		return s
+	}
+	return nil
+}
+
+// consumable checks that tok can be consumed at now
+func consumable(tok *Token, now time.Time) error {
+	switch {
+	case !tok.UsedAt.IsZero():
+		return ErrTokenUsed
+	case !now.Before(tok.ExpiresAt):
+		return ErrTokenExpired
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// MaxDeadLetters is how many dead letters a store keeps per webhook; older
+// ones are dropped
+const MaxDeadLetters = 1000
+
+// Webhook is an endpoint registered by a tenant to receive events about its
+// codes. Deliveries are signed with Secret.
+type Webhook struct {
+	ID       string   `json:"id"`
+	TenantID string   `json:"tenantId,omitempty"`
+	URL      string   `json:"url"`
+	Events   []string `json:"events,omitempty"` // empty receives every event type
+	Secret   string   `json:"secret"`
+	// CreatedAt and SecretRotatedAt are when the webhook and its current
+	// secret were created
+	CreatedAt       time.Time `json:"createdAt"`
+	SecretRotatedAt time.Time `json:"secretRotatedAt"`
+}
+
+// DeadLetter is an event that could not be delivered to a webhook after
+// every retry
+type DeadLetter struct {
+	ID        string          `json:"id"`
+	WebhookID string          `json:"webhookId"`
+	Type      string          `json:"type"`
+	Body      json.RawMessage `json:"body"` // the event as it was posted
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"lastError"`
+	FailedAt  time.Time       `json:"failedAt"`
+}
+
+// Webhooks is implemented by stores that keep webhook registrations and
+// their dead letters
+type Webhooks interface {
+	// SaveWebhook inserts or replaces the webhook wh.ID
+	SaveWebhook(ctx context.Context, wh Webhook) error
+	// GetWebhook returns the webhook id or ErrNotFound
+	GetWebhook(ctx context.Context, id string) (*Webhook, error)
+	// ListWebhooks returns every webhook ordered by creation time
+	ListWebhooks(ctx context.Context) ([]Webhook, error)
+	// DeleteWebhook deletes the webhook id and its dead letters, or returns
+	// ErrNotFound
+	DeleteWebhook(ctx context.Context, id string) error
+	// SaveDeadLetter inserts a dead letter, dropping the oldest ones of its
+	// webhook beyond MaxDeadLetters
+	SaveDeadLetter(ctx context.Context, dl DeadLetter) error
+	// ListDeadLetters returns the dead letters of a webhook, oldest first
+	ListDeadLetters(ctx context.Context, webhookID string) ([]DeadLetter, error)
+}
+
+// WebhooksOf returns the webhook registry of s, or nil when s keeps none. It
+// sees through WithBreaker, routing the calls through the breaker.
+func WebhooksOf(s Store) Webhooks {
+	switch s := s.(type) {
+	case *breakerStore:
+		if inner := WebhooksOf(s.s); inner != nil {
+			return &breakerWebhooks{w: inner, b: s.b}
+		}
+		return nil
+	case Webhooks:
+		return s
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package synth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoadTest fires generated profiles at POST /api/generate of a deployment
+type LoadTest struct {
+	BaseURL     string
+	APIKey      string // sent as X-API-Key when set
+	Client      *http.Client
+	Concurrency int // concurrent requests; at least 1
+}
+
+// LoadReport summarizes a load test. Latencies are in milliseconds and
+// include failed requests.
+type LoadReport struct {
+	Requests    int         `json:"requests"`
+	Errors      int         `json:"errors"`                // transport errors and non-200 responses
+	StatusCodes map[int]int `json:"statusCodes,omitempty"` // count per HTTP status
+	DurationMs  float64     `json:"durationMs"`
+	Throughput  float64     `json:"throughput"` // requests per second
+	P50         float64     `json:"p50"`
+	P90         float64     `json:"p90"`
+	P99         float64     `json:"p99"`
+	Max         float64     `json:"max"`
+}
+
+// Run sends count profiles from g and waits for every response, or for ctx
+// to be done
+func (l *LoadTest) Run(ctx context.Context, g *Generator, count int) LoadReport {
+	client := l.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	workers := max(l.Concurrency, 1)
+	url := strings.TrimRight(l.BaseURL, "/") + "/api/generate"
+
+	// Profiles are generated up front so generation cost does not skew latencies
+	bodies := make(chan []byte, count)
+	for i := 0; i < count; i++ {
+		body, _ := json.Marshal(g.Profile())
+		bodies <- body
+	}
+	close(bodies)
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		report    = LoadReport{StatusCodes: map[int]int{}}
+		wg        sync.WaitGroup
+	)
+	start := time.Now()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for body := range bodies {
+				if ctx.Err() != nil {
+					return
+				}
+				sent := time.Now()
+				status := l.send(ctx, client, url, body)
+				elapsed := time.Since(sent)
+
+				mu.Lock()
+				latencies = append(latencies, elapsed)
+				report.Requests++
+				if status != 0 {
+					report.StatusCodes[status]++
+				}
+				if status != http.StatusOK {
+					report.Errors++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	duration := time.Since(start)
+	report.DurationMs = ms(duration)
+	if duration > 0 {
+		report.Throughput = math.Round(float64(report.Requests)/duration.Seconds()*100) / 100
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report.P50 = percentile(latencies, 50)
+	report.P90 = percentile(latencies, 90)
+	report.P99 = percentile(latencies, 99)
+	if len(latencies) > 0 {
+		report.Max = ms(latencies[len(latencies)-1])
+	}
+	return report
+}
+
+// send posts one profile and returns the response status, or 0 on a transport error
+func (l *LoadTest) send(ctx context.Context, client *http.Client, url string, body []byte) int {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.APIKey != "" {
+		req.Header.Set("X-API-Key", l.APIKey)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body) // drain so the connection is reused
+	return resp.StatusCode
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p int) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(float64(p) / 100 * float64(len(sorted))))
+	return ms(sorted[max(rank, 1)-1])
+}
+
+func ms(d time.Duration) float64 {
+	return math.Round(float64(d.Microseconds())/10) / 100
+}
@@ -0,0 +1,113 @@
+// Package synth generates realistic random input profiles, for load testing
+// and for exercising the engine with inputs no fixture covers.
+package synth
+
+import (
+	"math"
+	"math/rand/v2"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// Options controls the generated profiles
+type Options struct {
+	Seed uint64 // the same seed gives the same sequence of profiles
+	// BirthRate is the share of profiles, from 0 to 1, that carry birth info
+	BirthRate float64
+}
+
+// Generator produces a reproducible sequence of valid input profiles
+type Generator struct {
+	rng       *rand.Rand
+	birthRate float64
+}
+
+// timezones are drawn for birth info
+var timezones = []string{
+	"UTC", "Europe/Paris", "Europe/London", "America/New_York", "America/Los_Angeles",
+	"America/Sao_Paulo", "Asia/Shanghai", "Asia/Tokyo", "Asia/Kolkata", "Australia/Sydney",
+}
+
+// weighted is a categorical value and its relative frequency
+type weighted struct {
+	value  string
+	weight float64
+}
+
+// Interaction preferences lean towards the middle, as answers to forms do
+var (
+	paces      = []weighted{{"balanced", 0.5}, {"fast", 0.25}, {"slow", 0.25}}
+	structures = []weighted{{"medium", 0.5}, {"high", 0.25}, {"low", 0.25}}
+	tones      = []weighted{{"neutral", 0.3}, {"warm", 0.3}, {"precise", 0.25}, {"sharp", 0.15}}
+)
+
+// New returns a generator for opts
+func New(opts Options) *Generator {
+	return &Generator{
+		rng:       rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15)),
+		birthRate: opts.BirthRate,
+	}
+}
+
+// Profile returns the next profile. Modal values are drawn from a symmetric
+// Dirichlet distribution and sum to 1; cognition values are normal around 0.5.
+// Every value has two decimals, like form input.
+func (g *Generator) Profile() *hcs.InputProfile {
+	in := &hcs.InputProfile{
+		DominantElement: []string{"Fire", "Earth", "Air", "Water"}[g.rng.IntN(4)],
+		Cognition: hcs.CognitionProfile{
+			Fluid:        g.cognition(),
+			Crystallized: g.cognition(),
+			Verbal:       g.cognition(),
+			Strategic:    g.cognition(),
+			Creative:     g.cognition(),
+		},
+		Interaction: hcs.InteractionPreferences{
+			Pace:      g.pick(paces),
+			Structure: g.pick(structures),
+			Tone:      g.pick(tones),
+		},
+	}
+
+	// Dirichlet(2, 2, 2) via Gamma(2, 1) draws, each the sum of two exponentials
+	c, f, m := g.gamma2(), g.gamma2(), g.gamma2()
+	sum := c + f + m
+	in.Modal.Cardinal = round2(c / sum)
+	in.Modal.Fixed = round2(f / sum)
+	in.Modal.Mutable = math.Max(0, round2(1-in.Modal.Cardinal-in.Modal.Fixed))
+
+	if g.rng.Float64() < g.birthRate {
+		in.BirthInfo = &hcs.BirthInfo{
+			Year:     1950 + g.rng.IntN(56),
+			Month:    1 + g.rng.IntN(12),
+			Day:      1 + g.rng.IntN(28), // valid in every month
+			Hour:     g.rng.IntN(24),
+			Minute:   g.rng.IntN(60),
+			Timezone: timezones[g.rng.IntN(len(timezones))],
+		}
+	}
+	return in
+}
+
+func (g *Generator) cognition() float64 {
+	return round2(math.Max(0.02, math.Min(0.98, 0.5+g.rng.NormFloat64()*0.15)))
+}
+
+func (g *Generator) gamma2() float64 {
+	return g.rng.ExpFloat64() + g.rng.ExpFloat64()
+}
+
+func (g *Generator) pick(values []weighted) string {
+	r := g.rng.Float64()
+	for _, v := range values {
+		if r < v.weight {
+			return v.value
+		}
+		r -= v.weight
+	}
+	return values[len(values)-1].value
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,263 @@
+// Package terminology rewords the texts shown to people, such as report
+// labels and localized error descriptions, for tenants that prefer neutral
+// terms ("primary energy") over astrological ones ("dominant element").
+// Codes, profile data and API field values are never reworded.
+package terminology
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/corehuman/hcs-lab-api/internal/i18n"
+)
+
+// Standard names the original terms, which need no dictionary
+const Standard = "standard"
+
+//go:embed neutral.json
+var neutralSource []byte
+
+// Dictionary replaces the terms of one language. Terms match whole words,
+// ignoring case, longest first. Text spelled as the term is replaced as the
+// replacement is spelled; otherwise the replacement takes the capitalization
+// of the text, such as a capitalized first word.
+type Dictionary struct {
+	terms   map[string]entry // by lowercased term
+	entries []string         // lowercased terms, longest first
+}
+
+type entry struct {
+	term, replacement string
+}
+
+// NewDictionary builds a dictionary from terms mapped to their replacements
+func NewDictionary(terms map[string]string) (*Dictionary, error) {
+	d := &Dictionary{terms: make(map[string]entry, len(terms))}
+	for term, replacement := range terms {
+		key := strings.ToLower(strings.TrimSpace(term))
+		if key == "" || strings.TrimSpace(replacement) == "" {
+			return nil, fmt.Errorf("empty term or replacement: %q: %q", term, replacement)
+		}
+		if _, ok := d.terms[key]; ok {
+			return nil, fmt.Errorf("term %q is listed twice", term)
+		}
+		d.terms[key] = entry{term: strings.TrimSpace(term), replacement: replacement}
+		d.entries = append(d.entries, key)
+	}
+	sort.Slice(d.entries, func(i, j int) bool {
+		if len(d.entries[i]) != len(d.entries[j]) {
+			return len(d.entries[i]) > len(d.entries[j])
+		}
+		return d.entries[i] < d.entries[j]
+	})
+	return d, nil
+}
+
+// Terms returns the terms and their replacements
+func (d *Dictionary) Terms() map[string]string {
+	out := map[string]string{}
+	if d != nil {
+		for _, e := range d.terms {
+			out[e.term] = e.replacement
+		}
+	}
+	return out
+}
+
+// Apply replaces the terms of text. A nil dictionary returns text as is.
+func (d *Dictionary) Apply(text string) string {
+	if d == nil || len(d.entries) == 0 {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(text); {
+		if wordBoundary(text, i) {
+			if term, n := d.match(text[i:]); n > 0 {
+				b.WriteString(text[last:i])
+				b.WriteString(d.terms[term].replace(text[i : i+n]))
+				i += n
+				last = i
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+	}
+	if last == 0 {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// match returns the longest term text starts with that ends on a word
+// boundary, and its length in text
+func (d *Dictionary) match(text string) (string, int) {
+	for _, term := range d.entries {
+		// Case folding keeps the length of the letters terms are made of
+		if len(term) <= len(text) && strings.EqualFold(text[:len(term)], term) && wordBoundary(text, len(term)) {
+			return term, len(term)
+		}
+	}
+	return "", 0
+}
+
+// wordBoundary reports whether a word can start or end at byte i of text
+func wordBoundary(text string, i int) bool {
+	if i == 0 || i == len(text) {
+		return true
+	}
+	before, _ := utf8.DecodeLastRuneInString(text[:i])
+	after, _ := utf8.DecodeRuneInString(text[i:])
+	return !isWordRune(before) || !isWordRune(after)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// replace returns the replacement of original, capitalized like it unless
+// it is spelled as the term: fully for an upper case word, or its first
+// letter for a capitalized one
+func (e entry) replace(original string) string {
+	replacement := e.replacement
+	first, _ := utf8.DecodeRuneInString(original)
+	switch {
+	case original == e.term || !unicode.IsUpper(first):
+		return replacement
+	case utf8.RuneCountInString(original) > 1 && strings.ToUpper(original) == original:
+		return strings.ToUpper(replacement)
+	default:
+		r, size := utf8.DecodeRuneInString(replacement)
+		return string(unicode.ToUpper(r)) + replacement[size:]
+	}
+}
+
+// Terminology is a named set of dictionaries, one per language
+type Terminology struct {
+	Name         string
+	dictionaries map[string]*Dictionary
+}
+
+// Dictionary returns the dictionary of lang ("" for English), or nil when
+// the terminology does not reword that language. A nil terminology has none.
+func (t *Terminology) Dictionary(lang string) *Dictionary {
+	if t == nil {
+		return nil
+	}
+	if lang == "" {
+		lang = i18n.Default
+	}
+	return t.dictionaries[lang]
+}
+
+// Apply replaces the terms of text, in lang
+func (t *Terminology) Apply(lang, text string) string {
+	return t.Dictionary(lang).Apply(text)
+}
+
+// File is the JSON layout of a terminology file: dictionaries by name, then
+// by language, each mapping terms to their replacements
+type File map[string]map[string]map[string]string
+
+// Catalog holds the terminologies tenants can be assigned
+type Catalog struct {
+	byName map[string]*Terminology
+	// Path is the file the catalog was loaded from; empty for the built-in one
+	Path string
+}
+
+var builtin = mustBuiltin()
+
+func mustBuiltin() *Catalog {
+	var neutral map[string]map[string]string
+	if err := json.Unmarshal(neutralSource, &neutral); err != nil {
+		panic(fmt.Sprintf("terminology: invalid neutral.json: %v", err))
+	}
+	c := &Catalog{byName: map[string]*Terminology{}}
+	if err := c.add("neutral", neutral); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Builtin returns the built-in catalog, with the "neutral" terminology
+func Builtin() *Catalog {
+	return builtin
+}
+
+// Load reads the terminologies of a terminology file on top of the built-in
+// ones, which it can redefine. An empty path returns the built-in catalog.
+func Load(path string) (*Catalog, error) {
+	if path == "" {
+		return builtin, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terminology file: %w", err)
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse terminology file %s: %w", path, err)
+	}
+	c := &Catalog{byName: map[string]*Terminology{}, Path: path}
+	for name, t := range builtin.byName {
+		c.byName[name] = t
+	}
+	for name, languages := range f {
+		if err := c.add(name, languages); err != nil {
+			return nil, fmt.Errorf("terminology file %s: %w", path, err)
+		}
+	}
+	return c, nil
+}
+
+// add validates and registers a terminology
+func (c *Catalog) add(name string, languages map[string]map[string]string) error {
+	if name == "" || name == Standard {
+		return fmt.Errorf("invalid terminology name %q", name)
+	}
+	t := &Terminology{Name: name, dictionaries: map[string]*Dictionary{}}
+	for lang, terms := range languages {
+		if i18n.Match(lang) != lang {
+			return fmt.Errorf("terminology %s: unsupported language %q (supported: %s)", name, lang, strings.Join(i18n.Locales(), ", "))
+		}
+		d, err := NewDictionary(terms)
+		if err != nil {
+			return fmt.Errorf("terminology %s (%s): %w", name, lang, err)
+		}
+		t.dictionaries[lang] = d
+	}
+	c.byName[name] = t
+	return nil
+}
+
+// Lookup returns the terminology registered under name. The standard
+// terminology, also selected by an empty name, is nil: it rewords nothing.
+func (c *Catalog) Lookup(name string) (*Terminology, error) {
+	if name == "" || name == Standard {
+		return nil, nil
+	}
+	t, ok := c.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown terminology %q (known: %s)", name, strings.Join(c.Names(), ", "))
+	}
+	return t, nil
+}
+
+// Names returns the names of the registered terminologies and Standard, sorted
+func (c *Catalog) Names() []string {
+	out := []string{Standard}
+	for name := range c.byName {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
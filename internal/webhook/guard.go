@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"time"
+)
+
+// ErrBlockedTarget is the error of a webhook URL that resolves to a loopback,
+// private, link-local or otherwise non-public address
+var ErrBlockedTarget = errors.New("webhook target is not a public address")
+
+// sharedAddressSpace is the carrier-grade NAT range, internal like RFC 1918
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// PublicAddr reports whether webhooks may connect to addr: a global unicast
+// address outside the private, loopback, link-local and shared ranges
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// CheckURL verifies that rawURL is an absolute http or https URL whose host
+// resolves only to public addresses
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	_, err = publicAddrs(ctx, u.Hostname())
+	return err
+}
+
+// publicAddrs resolves host and fails with ErrBlockedTarget when any of its
+// addresses is not public, so a name cannot mix internal and public records
+func publicAddrs(ctx context.Context, host string) ([]netip.Addr, error) {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve webhook host %s: %w", host, err)
+	}
+	for i, addr := range addrs {
+		addr = addr.Unmap()
+		addrs[i] = addr
+		if !PublicAddr(addr) {
+			return nil, fmt.Errorf("%w: %s resolves to %s", ErrBlockedTarget, host, addr)
+		}
+	}
+	return addrs, nil
+}
+
+// NewPublicClient returns a client for tenant webhooks. It checks the
+// addresses again when it dials, so a name re-resolving to an internal
+// address after CheckURL is refused, connects to the checked address itself,
+// bypasses proxies and does not follow redirects.
+func NewPublicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := publicAddrs(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var dialErr error
+		for _, addr := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = err
+		}
+		return nil, dialErr
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// PublicError describes a delivery error for the tenant that owns the
+// webhook, leaving out the transport details of the server's network
+func PublicError(err error) string {
+	var urlErr *url.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrBlockedTarget):
+		return ErrBlockedTarget.Error()
+	case errors.As(err, &urlErr) && urlErr.Timeout():
+		return "webhook endpoint timed out"
+	case errors.As(err, &urlErr):
+		return "webhook endpoint could not be reached"
+	default:
+		return err.Error()
+	}
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/corehuman/hcs-lab-api/internal/breaker"
+)
+
+// DefaultOutboxSize is how many undelivered events an Outbox keeps by default
+const DefaultOutboxSize = 1000
+
+// ErrOutboxFull is returned when an event could neither be delivered nor queued
+var ErrOutboxFull = errors.New("webhook outbox is full")
+
+// Outbox delivers events through a circuit breaker and queues those that
+// cannot be delivered, so a webhook outage neither blocks nor loses events
+// while the process runs. Queued events are retried, oldest first, by Flush
+// and before each Send. The queue is in memory and does not survive a restart.
+type Outbox struct {
+	Notifier *Notifier
+	Breaker  *breaker.Breaker
+	Max      int // queue capacity; zero uses DefaultOutboxSize
+
+	mu    sync.Mutex
+	queue []Event
+}
+
+// NewOutbox returns an outbox delivering to n through b
+func NewOutbox(n *Notifier, b *breaker.Breaker) *Outbox {
+	return &Outbox{Notifier: n, Breaker: b}
+}
+
+// Send delivers an event, or queues it when delivery fails or earlier events
+// are still queued. It fails only when the queue is full.
+func (o *Outbox) Send(ctx context.Context, eventType string, data interface{}) error {
+	event := o.Notifier.event(eventType, data)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.flushLocked(ctx)
+	if len(o.queue) == 0 && o.deliver(ctx, event) == nil {
+		return nil
+	}
+	return o.enqueueLocked(event)
+}
+
+// Flush retries the queued events in order, stopping at the first failure
+func (o *Outbox) Flush(ctx context.Context) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.flushLocked(ctx)
+}
+
+// Len returns the number of queued events
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.queue)
+}
+
+func (o *Outbox) deliver(ctx context.Context, event Event) error {
+	return o.Breaker.Do(func() error { return o.Notifier.deliver(ctx, event) })
+}
+
+func (o *Outbox) flushLocked(ctx context.Context) {
+	for len(o.queue) > 0 {
+		if o.deliver(ctx, o.queue[0]) != nil {
+			return
+		}
+		o.queue = o.queue[1:]
+	}
+}
+
+func (o *Outbox) enqueueLocked(event Event) error {
+	max := o.Max
+	if max <= 0 {
+		max = DefaultOutboxSize
+	}
+	if len(o.queue) >= max {
+		return ErrOutboxFull
+	}
+	o.queue = append(o.queue, event)
+	return nil
+}
@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Retry is an exponential backoff policy for webhook deliveries
+type Retry struct {
+	Attempts int           // deliveries before giving up, the first included
+	Base     time.Duration // delay before the first retry, doubled for each next one
+	Max      time.Duration // cap on a single delay
+}
+
+// DefaultRetry gives up after 6 attempts spread over about 30 seconds
+var DefaultRetry = Retry{Attempts: 6, Base: time.Second, Max: time.Minute}
+
+// Delay returns the wait before the n-th retry, counted from 1
+func (r Retry) Delay(n int) time.Duration {
+	d := r.Base
+	for i := 1; i < n && d < r.Max; i++ {
+		d *= 2
+	}
+	return min(d, r.Max)
+}
+
+// PostWithRetry posts like Post until a delivery succeeds, the attempts run
+// out, the endpoint answers with a status that retrying will not change, or
+// ctx ends. It returns the number of attempts made and the last error.
+func PostWithRetry(ctx context.Context, client *http.Client, url, secret, eventType string, body []byte, r Retry) (int, error) {
+	attempts := max(r.Attempts, 1)
+	var err error
+	for n := 1; ; n++ {
+		if err = Post(ctx, client, url, secret, eventType, body); err == nil || n == attempts || permanent(err) {
+			return n, err
+		}
+		select {
+		case <-ctx.Done():
+			return n, errors.Join(err, ctx.Err())
+		case <-time.After(r.Delay(n)):
+		}
+	}
+}
+
+// permanent reports whether err is a blocked target, or a client error other
+// than a timeout or a rate limit, which a retry would only repeat
+func permanent(err error) bool {
+	if errors.Is(err, ErrBlockedTarget) {
+		return true
+	}
+	var status *StatusError
+	if !errors.As(err, &status) {
+		return false
+	}
+	code := status.StatusCode
+	return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
@@ -0,0 +1,34 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Headers of webhook deliveries
+const (
+	// EventHeader carries the event type, so receivers can route before parsing
+	EventHeader = "X-HCS-Event"
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the body
+	// under the endpoint's secret
+	SignatureHeader = "X-HCS-Signature"
+)
+
+// Sign returns the SignatureHeader value of body under secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether header is a valid signature of body under secret,
+// in constant time. Receivers should also reject events whose createdAt is
+// too old, so captured deliveries cannot be replayed.
+func Verify(secret string, body []byte, header string) bool {
+	if !strings.HasPrefix(header, "sha256=") {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(header))
+}
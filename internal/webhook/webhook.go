@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+)
+
+// Event is the JSON envelope posted to webhook endpoints
+type Event struct {
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// Notifier posts events to a single webhook URL
+type Notifier struct {
+	URL    string
+	Secret string // signs deliveries when set
+	Client *http.Client
+	Clock  clock.Clock // stamps CreatedAt; nil uses the system clock
+}
+
+// NewNotifier creates a notifier for url with a conservative client timeout
+func NewNotifier(url string) *Notifier {
+	return &Notifier{
+		URL:    url,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send delivers an event and fails on any non-2xx response
+func (n *Notifier) Send(ctx context.Context, eventType string, data interface{}) error {
+	return n.deliver(ctx, n.event(eventType, data))
+}
+
+// event stamps a new event with the current time
+func (n *Notifier) event(eventType string, data interface{}) Event {
+	return Event{
+		Type:      eventType,
+		CreatedAt: clock.Or(n.Clock).Now().UTC(),
+		Data:      data,
+	}
+}
+
+// deliver posts an event and fails on any non-2xx response
+func (n *Notifier) deliver(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+	return Post(ctx, n.Client, n.URL, n.Secret, event.Type, body)
+}
+
+// StatusError is a non-2xx response of a webhook endpoint
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhook endpoint returned status %d", e.StatusCode)
+}
+
+// Post posts the JSON body of an event to url, with its type in EventHeader
+// and, when secret is set, its signature in SignatureHeader. A non-2xx
+// response fails with a *StatusError.
+func Post(ctx context.Context, client *http.Client, url, secret, eventType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	if secret != "" {
+		req.Header.Set(SignatureHeader, Sign(secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Package yamljson converts YAML documents to JSON, so profiles written by
+// hand in YAML go through the same decoding and validation as JSON ones.
+package yamljson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ContentType is the media type under which the API accepts YAML bodies
+const ContentType = "application/yaml"
+
+// contentTypes are the YAML media types in use besides ContentType
+var contentTypes = []string{ContentType, "application/x-yaml", "text/yaml", "text/x-yaml"}
+
+// IsContentType reports whether mediaType denotes YAML
+func IsContentType(mediaType string) bool {
+	for _, t := range contentTypes {
+		if strings.EqualFold(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFile reports whether path has a YAML extension (.yaml or .yml)
+func IsFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// ToJSON converts a single YAML document to JSON. Mapping keys are taken as
+// written, and dates are kept as strings.
+func ToJSON(data []byte) ([]byte, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var doc yaml.Node
+	if err := dec.Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("empty YAML document")
+		}
+		return nil, err
+	}
+	if err := dec.Decode(new(yaml.Node)); !errors.Is(err, io.EOF) {
+		return nil, errors.New("expected a single YAML document")
+	}
+	v, err := convert(&doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// convert turns a node into the value encoding/json would have decoded from
+// the equivalent JSON
+func convert(n *yaml.Node) (any, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		return convert(n.Content[0])
+	case yaml.AliasNode:
+		return convert(n.Alias)
+	case yaml.MappingNode:
+		out := make(map[string]any, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if k.Kind != yaml.ScalarNode || k.ShortTag() == "!!null" {
+				return nil, fmt.Errorf("line %d: mapping keys must be scalars", k.Line)
+			}
+			if k.ShortTag() == "!!merge" {
+				return nil, fmt.Errorf("line %d: merge keys are not supported", k.Line)
+			}
+			value, err := convert(v)
+			if err != nil {
+				return nil, err
+			}
+			out[k.Value] = value
+		}
+		return out, nil
+	case yaml.SequenceNode:
+		out := make([]any, len(n.Content))
+		for i, c := range n.Content {
+			v, err := convert(c)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	}
+	switch n.ShortTag() {
+	case "!!str", "!!timestamp", "!!binary":
+		return n.Value, nil
+	}
+	var v any
+	if err := n.Decode(&v); err != nil {
+		return nil, err
+	}
+	if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return nil, fmt.Errorf("line %d: %s is not a JSON number", n.Line, n.Value)
+	}
+	return v, nil
+}
@@ -0,0 +1,49 @@
+package hcstest
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/schema"
+)
+
+// AssertValidOutput fails the test unless body is a generate response that
+// matches the OutputHCS JSON Schema and carries a well-formed U3 code
+func AssertValidOutput(t testing.TB, body []byte) {
+	t.Helper()
+	var doc map[string]any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("hcstest: response is not a JSON object: %v", err)
+	}
+	for _, v := range schema.Output().Validate(doc) {
+		t.Errorf("hcstest: response violates the OutputHCS schema: %s", v)
+	}
+	if code, _ := doc["codeU3"].(string); code != "" && !hcs.ValidateU3Format(code) {
+		t.Errorf("hcstest: malformed HCS-U3 code: %s", code)
+	}
+}
+
+// AssertChip fails the test unless body is a generate response for the given CHIP
+func AssertChip(t testing.TB, body []byte, chip string) {
+	t.Helper()
+	var out struct {
+		Chip string `json:"chip"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		t.Fatalf("hcstest: response is not a JSON object: %v", err)
+	}
+	if out.Chip != chip {
+		t.Errorf("hcstest: chip = %q, want %q", out.Chip, chip)
+	}
+}
+
+// AssertCodeLevel fails the test unless code is an HCS code of the given
+// level, e.g. "U3" or "U7"
+func AssertCodeLevel(t testing.TB, code, level string) {
+	t.Helper()
+	if !strings.HasPrefix(code, "HCS-"+level+"|") {
+		t.Errorf("hcstest: %q is not an HCS-%s code", code, level)
+	}
+}
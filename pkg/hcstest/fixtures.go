@@ -0,0 +1,75 @@
+package hcstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// Fixture is a named request body for POST /api/generate together with the
+// exact response a fresh mock Server returns for it
+type Fixture struct {
+	Name   string
+	Input  json.RawMessage
+	Output json.RawMessage
+	Chip   string
+	CodeU3 string
+}
+
+// Fixtures returns the published test vector profiles as fixtures. They cover
+// Western-only profiles and one with birth info (and therefore a U5 code).
+func Fixtures() ([]Fixture, error) {
+	gen, err := newGenerator(clock.NewFrozen(FixedTime))
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := hcs.TestVectors()
+	if err != nil {
+		return nil, err
+	}
+
+	fixtures := make([]Fixture, 0, len(vectors))
+	for _, v := range vectors {
+		input := v.Input
+		out, err := gen.Generate(&input)
+		if err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", v.Name, err)
+		}
+		inputJSON, err := json.Marshal(v.Input)
+		if err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", v.Name, err)
+		}
+		outputJSON, err := json.Marshal(out)
+		if err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", v.Name, err)
+		}
+		fixtures = append(fixtures, Fixture{
+			Name:   v.Name,
+			Input:  inputJSON,
+			Output: outputJSON,
+			Chip:   out.Chip,
+			CodeU3: out.CodeU3,
+		})
+	}
+	return fixtures, nil
+}
+
+// MustFixture returns the fixture with the given name, failing the test if
+// it does not exist
+func MustFixture(t testing.TB, name string) Fixture {
+	t.Helper()
+	fixtures, err := Fixtures()
+	if err != nil {
+		t.Fatalf("hcstest: %v", err)
+	}
+	for _, f := range fixtures {
+		if f.Name == name {
+			return f
+		}
+	}
+	t.Fatalf("hcstest: no fixture named %q", name)
+	return Fixture{}
+}
@@ -0,0 +1,157 @@
+// Package hcstest provides a deterministic in-process HCS API, fixture
+// profiles and assertions, so services integrating with HCS can be tested
+// without network access or a real secret key.
+//
+// The mock signs with the fixed test vector key material and a frozen clock,
+// so the same input always produces the same response.
+package hcstest
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/go-chi/chi/v5"
+)
+
+// FixedTime is the time the mock clock starts at
+var FixedTime = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+// Server is a mock HCS API serving POST /api/generate, GET /api/codes/{chip}
+// (both also under /v1) and GET /health. Generated codes are kept in memory for lookup.
+type Server struct {
+	*httptest.Server
+	// Clock drives issuedAt and expiry; advance it to test stale codes
+	Clock *clock.Frozen
+
+	gen   *hcs.Generator
+	mu    sync.Mutex
+	codes map[string]storedCode
+}
+
+type storedCode struct {
+	createdAt time.Time
+	output    *hcs.OutputHCS
+}
+
+// generateRequest mirrors the request body accepted by the real API
+type generateRequest struct {
+	HCS *hcs.InputProfile `json:"hcs,omitempty"`
+	hcs.InputProfile
+	ValidityMonths int `json:"validityMonths,omitempty"`
+}
+
+// NewServer starts a mock HCS API that is closed when the test ends
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	s := &Server{Clock: clock.NewFrozen(FixedTime), codes: map[string]storedCode{}}
+	gen, err := newGenerator(s.Clock)
+	if err != nil {
+		t.Fatalf("hcstest: failed to create generator: %v", err)
+	}
+	s.gen = gen
+
+	r := chi.NewRouter()
+	r.Get("/health", s.handleHealth)
+	for _, prefix := range []string{"/api", "/v1"} {
+		r.Post(prefix+"/generate", s.handleGenerate)
+		r.Get(prefix+"/codes/{chip}", s.handleGetCode)
+	}
+	s.Server = httptest.NewServer(r)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newGenerator creates a generator keyed with the test vector secret and salt
+func newGenerator(c clock.Clock) (*hcs.Generator, error) {
+	vectors, err := hcs.TestVectors()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := hex.DecodeString(vectors[0].SecretHex)
+	if err != nil {
+		return nil, err
+	}
+	salt, err := hex.DecodeString(vectors[0].SaltHex)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := hcs.NewStaticSecretProvider(secret)
+	if err != nil {
+		return nil, err
+	}
+	return hcs.NewGenerator(hcs.WithSaltProvider(fixedSalt(salt)), hcs.WithSecretProvider(secrets), hcs.WithClock(c))
+}
+
+// fixedSalt is a SaltProvider that never touches the filesystem
+type fixedSalt []byte
+
+func (s fixedSalt) Salt() ([]byte, error) { return s, nil }
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
+}
+
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	var req generateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, errcode.InvalidJSON, err.Error())
+		return
+	}
+	input := req.InputProfile
+	if req.HCS != nil {
+		input = *req.HCS
+	}
+
+	output, err := s.gen.GenerateWithOptions(&input, &hcs.GeneratorOptions{ValidityMonths: req.ValidityMonths})
+	if err != nil {
+		writeError(w, errcode.Of(err, errcode.GenerationFailed), err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.codes[output.Chip] = storedCode{createdAt: s.Clock.Now(), output: output}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, output)
+}
+
+func (s *Server) handleGetCode(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	code, ok := s.codes[chi.URLParam(r, "chip")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, errcode.NotFound, "no code stored for this CHIP")
+		return
+	}
+
+	response := map[string]any{
+		"chip":      code.output.Chip,
+		"createdAt": code.createdAt,
+		"stale":     hcs.IsStale(code.output.Metadata, s.Clock.Now()),
+		"output":    code.output,
+	}
+	if code.output.Metadata != nil && code.output.Metadata.ValidUntil != "" {
+		response["validUntil"] = code.output.Metadata.ValidUntil
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes the error body of the real API, with the status, title
+// and errorCode of code
+func writeError(w http.ResponseWriter, code errcode.Code, message string) {
+	response := errcode.NewResponse("", code, message)
+	writeJSON(w, response.Code, response)
+}
@@ -0,0 +1,96 @@
+# Code generated by hcsrefgen from internal/hcs. DO NOT EDIT.
+"""HCS reference tables for Python ports."""
+
+import math
+
+# Heavenly stems: (name, element, yin/yang), indexed by stem index
+HEAVENLY_STEMS = [
+    ("Jia", "Wood", "Yang"),
+    ("Yi", "Wood", "Yin"),
+    ("Bing", "Fire", "Yang"),
+    ("Ding", "Fire", "Yin"),
+    ("Wu", "Earth", "Yang"),
+    ("Ji", "Earth", "Yin"),
+    ("Geng", "Metal", "Yang"),
+    ("Xin", "Metal", "Yin"),
+    ("Ren", "Water", "Yang"),
+    ("Gui", "Water", "Yin"),
+]
+
+# Earthly branches: (name, element, yin/yang, animal), indexed by branch index
+EARTHLY_BRANCHES = [
+    ("Zi", "Water", "Yang", "Rat"),
+    ("Chou", "Earth", "Yin", "Ox"),
+    ("Yin", "Wood", "Yang", "Tiger"),
+    ("Mao", "Wood", "Yin", "Rabbit"),
+    ("Chen", "Earth", "Yang", "Dragon"),
+    ("Si", "Fire", "Yin", "Snake"),
+    ("Wu", "Fire", "Yang", "Horse"),
+    ("Wei", "Earth", "Yin", "Goat"),
+    ("Shen", "Metal", "Yang", "Monkey"),
+    ("You", "Metal", "Yin", "Rooster"),
+    ("Xu", "Earth", "Yang", "Dog"),
+    ("Hai", "Water", "Yin", "Pig"),
+]
+
+# Branch index for each calendar month (January first), engine v1
+MONTH_BRANCH_MAPPING = [2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 0, 1]
+
+# Sectional solar terms opening the BaZi months from engine v2, Li Chun first:
+# (name, apparent solar longitude in degrees, branch index)
+SOLAR_TERMS = [
+    ("Li Chun", 315, 2),
+    ("Jing Zhe", 345, 3),
+    ("Qing Ming", 15, 4),
+    ("Li Xia", 45, 5),
+    ("Mang Zhong", 75, 6),
+    ("Xiao Shu", 105, 7),
+    ("Li Qiu", 135, 8),
+    ("Bai Lu", 165, 9),
+    ("Han Lu", 195, 10),
+    ("Li Dong", 225, 11),
+    ("Da Xue", 255, 0),
+    ("Xiao Han", 285, 1),
+]
+
+ELEMENT_LETTERS = {
+    "Air": "A",
+    "Earth": "E",
+    "Fire": "F",
+    "Water": "W",
+}
+DEFAULT_ELEMENT_LETTER = "E"
+
+PACE_LETTERS = {
+    "balanced": "B",
+    "fast": "F",
+    "slow": "S",
+}
+DEFAULT_PACE_LETTER = "B"
+
+STRUCTURE_LETTERS = {
+    "high": "H",
+    "low": "L",
+    "medium": "M",
+}
+DEFAULT_STRUCTURE_LETTER = "M"
+
+TONE_LETTERS = {
+    "neutral": "N",
+    "precise": "P",
+    "sharp": "S",
+    "warm": "W",
+}
+DEFAULT_TONE_LETTER = "N"
+
+# Code grammars; match with re.fullmatch (Python's $ also matches before a trailing newline)
+U3_GRAMMAR = "^HCS-U3\\|E:([AEWF])\\|MOD:c(\\d{2})f(\\d{2})m(\\d{2})\\|COG:F(\\d{2})C(\\d{2})V(\\d{2})S(\\d{2})Cr(\\d{2})\\|INT:PB=([BFS]),SM=([LMH]),TN=([WNSP])\\|CHIP:([0-9a-f]{12})(?:\\|EP:([1-9]\\d*))?(?:\\|LN:([0-9a-f]{12}))?$"
+U7_GRAMMAR = "^HCS-U7\\|V:7\\.0\\|ALG:QS(?:-HKDF)?(?:-SHA3)?(?:\\.\\d{2}\\.\\d{2})?(?:\\+MLDSA65)?\\|E:([AEWF])\\|MOD:c(\\d{2,3})f(\\d{2,3})m(\\d{2,3})\\|COG:F(\\d{2,3})C(\\d{2,3})V(\\d{2,3})S(\\d{2,3})Cr(\\d{2,3})\\|INT:PB=([BFS]),SM=([LMH]),TN=([WNSP])\\|QSIG:([0-9a-f]+)\\|B3:([0-9a-f]+)(?:\\|KID:[a-z0-9][a-z0-9-]{0,15})?(?:\\|EP:([1-9]\\d*))?(?:\\|LN:([0-9a-f]{12}))?(?:\\|PQ:[0-9a-f]{16})?$"
+
+
+def clamp_and_round(value: float) -> int:
+    """Clamp to [0, 1] and convert to a percentage, rounding half away from zero
+    (Python's round() rounds half to even and must not be used)."""
+    value = min(max(value, 0.0), 1.0) * 100
+    whole = math.floor(value)
+    return int(whole + 1 if value - whole >= 0.5 else whole)
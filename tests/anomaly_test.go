@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/anomaly"
+)
+
+// TestAnomalyDetector verifies each pattern is flagged once per window, and
+// that a key's usual rate raises its spike threshold.
+func TestAnomalyDetector(t *testing.T) {
+	d := anomaly.NewDetector(anomaly.Config{Window: time.Minute, SpikeMin: 20, SpikeFactor: 3, FailureMin: 4, FailureRatio: 0.5, RepeatMin: 3})
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	kinds := func(key string, at time.Time, n int, obs anomaly.Observation) []anomaly.Kind {
+		var got []anomaly.Kind
+		for i := 0; i < n; i++ {
+			for _, a := range d.Observe(key, at, obs) {
+				if a.Key != key {
+					t.Errorf("alert for %q reported for %q", key, a.Key)
+				}
+				got = append(got, a.Kind)
+			}
+		}
+		return got
+	}
+
+	// A new key jumping to the minimum is a spike
+	if got := kinds("a", start, 24, anomaly.Observation{}); len(got) != 1 || got[0] != anomaly.Spike {
+		t.Errorf("expected one spike alert, got %v", got)
+	}
+	// 24 requests per window become usual: 40 is not three times as many
+	if got := kinds("a", start.Add(time.Minute), 24, anomaly.Observation{}); len(got) != 0 {
+		t.Errorf("usual traffic flagged: %v", got)
+	}
+	if got := kinds("a", start.Add(2*time.Minute), 40, anomaly.Observation{}); len(got) != 0 {
+		t.Errorf("moderate increase flagged: %v", got)
+	}
+	if got := kinds("a", start.Add(3*time.Minute), 120, anomaly.Observation{}); len(got) != 1 || got[0] != anomaly.Spike {
+		t.Errorf("expected a spike over the baseline, got %v", got)
+	}
+
+	// Failures are flagged once they are both numerous and the majority
+	kinds("b", start, 5, anomaly.Observation{})
+	if got := kinds("b", start, 4, anomaly.Observation{Failed: true}); len(got) != 0 {
+		t.Errorf("minority of failures flagged: %v", got)
+	}
+	if got := kinds("b", start, 1, anomaly.Observation{Failed: true}); len(got) != 1 || got[0] != anomaly.ValidationFailures {
+		t.Errorf("expected a validation failure alert, got %v", got)
+	}
+
+	// Repeats count per profile and reset with the window
+	kinds("c", start, 2, anomaly.Observation{Profile: "p1"})
+	kinds("c", start, 2, anomaly.Observation{Profile: "p2"})
+	if got := kinds("c", start.Add(time.Minute), 2, anomaly.Observation{Profile: "p1"}); len(got) != 0 {
+		t.Errorf("repeats of an earlier window flagged: %v", got)
+	}
+	if got := kinds("c", start.Add(time.Minute), 5, anomaly.Observation{Profile: "p1"}); len(got) != 1 || got[0] != anomaly.RepeatedProfile {
+		t.Errorf("expected one repeated profile alert, got %v", got)
+	}
+}
@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+func TestArchetypeTaxonomy(t *testing.T) {
+	all := hcs.Archetypes()
+	if len(all) != 16 {
+		t.Fatalf("expected 16 archetypes, got %d", len(all))
+	}
+
+	names := make(map[string]bool)
+	for i, a := range all {
+		if a.ID != i {
+			t.Errorf("archetype %d has ID %d", i, a.ID)
+		}
+		if names[a.Name] {
+			t.Errorf("duplicate archetype name %s", a.Name)
+		}
+		names[a.Name] = true
+
+		parsed, err := hcs.ParseArchetypeSegment(hcs.ArchetypeSegment(a))
+		if err != nil || parsed != a {
+			t.Errorf("segment round trip failed for %+v: %+v, %v", a, parsed, err)
+		}
+	}
+
+	for _, bad := range []string{"ARC:", "ARC:g", "ARC:F", "ARX:1", "ARC:10"} {
+		if _, err := hcs.ParseArchetypeSegment(bad); err == nil {
+			t.Errorf("segment %q should be rejected", bad)
+		}
+	}
+}
+
+func TestAssignArchetype(t *testing.T) {
+	// Air, analytical mean (52+13+15)/3=26.7 < expressive mean (53+33)/2=43, balanced pace
+	a := hcs.AssignArchetype(hcs.NormalizeProfile(getTestInput()))
+	if a.Name != "Storyteller" || a.Element != "Air" || a.Cognition != "Expressive" || a.Tempo != "Steady" {
+		t.Errorf("unexpected archetype: %+v", a)
+	}
+
+	fast := getTestInput()
+	fast.DominantElement = "Fire"
+	fast.Interaction.Pace = "fast"
+	fast.Cognition.Strategic = 0.9
+	fast.Cognition.Crystallized = 0.9
+	if a := hcs.AssignArchetype(hcs.NormalizeProfile(fast)); a.Name != "Vanguard" {
+		t.Errorf("expected Vanguard, got %+v", a)
+	}
+}
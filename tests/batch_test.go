@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/batch"
+	"github.com/corehuman/hcs-lab-api/internal/breaker"
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+// TestBatchRun verifies that failing items do not fail a batch, that results
+// keep the request order and that each stored item reports its own outcome.
+func TestBatchRun(t *testing.T) {
+	setTestSecretKey(t)
+	ctx := context.Background()
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+
+	inputs := []*hcs.InputProfile{getTestInput(), getTestInput(), getTestInput(), getTestInput(), getTestInput()}
+	inputs[1].DominantElement = "Metal"
+	inputs[2].DominantElement = "Water"
+	inputs[3].DominantElement = "Fire"
+	inputs[4].DominantElement = "Earth"
+	invalid := 1  // fails generation
+	existing := 2 // already stored, rejected on conflict
+	unstored := 3 // generated without a record, as for a tenant without storage
+
+	s := store.NewMemoryStore()
+	prior, err := gen.Generate(inputs[existing])
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if err := s.Save(ctx, store.Record{Chip: prior.Chip, SubjectID: "prior", Output: prior, CreatedAt: time.Unix(1700000000, 0).UTC()}); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	generate := func(i int) (*hcs.OutputHCS, *store.Record, error) {
+		out, err := gen.Generate(inputs[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		if i == unstored {
+			return out, nil, nil
+		}
+		return out, &store.Record{Chip: out.Chip, SubjectID: "batch", Output: out, CreatedAt: time.Unix(1700000100, 0).UTC()}, nil
+	}
+	response := batch.Run(ctx, len(inputs), generate, s, batch.Options{Conflict: store.ConflictReject, ChunkSize: 2})
+
+	if len(response.Results) != len(inputs) || response.Succeeded != 4 || response.Failed != 1 {
+		t.Fatalf("expected 4 succeeded and 1 failed of %d, got %+v", len(inputs), response)
+	}
+	for i, result := range response.Results {
+		if result.Index != i {
+			t.Errorf("result %d has index %d", i, result.Index)
+		}
+		if i == invalid {
+			continue
+		}
+		want, _ := gen.Generate(inputs[i])
+		if result.Output == nil || result.Output.Chip != want.Chip {
+			t.Errorf("result %d is not the output of item %d", i, i)
+		}
+	}
+
+	failed := response.Results[invalid]
+	if failed.Output != nil || failed.Stored != nil || failed.Error == nil || failed.Error.ErrorCode != errcode.InvalidElement {
+		t.Errorf("an invalid item should fail with %s and not be stored, got %+v", errcode.InvalidElement, failed)
+	}
+	if r := response.Results[unstored]; r.Stored != nil || r.StorageError != nil {
+		t.Errorf("an item without a record should report no storage outcome, got %+v", r)
+	}
+	if r := response.Results[existing]; r.Stored == nil || *r.Stored || r.StorageError == nil || r.StorageError.ErrorCode != errcode.Conflict {
+		t.Errorf("a stored CHIP should be rejected with %s, got %+v", errcode.Conflict, r)
+	}
+	for _, i := range []int{0, 4} {
+		r := response.Results[i]
+		if r.Stored == nil || !*r.Stored || r.StorageError != nil {
+			t.Errorf("item %d should be stored, got %+v", i, r)
+			continue
+		}
+		rec, err := s.Get(ctx, "", r.Output.Chip)
+		if err != nil || rec.SubjectID != "batch" {
+			t.Errorf("item %d: stored record = %+v, %v", i, rec, err)
+		}
+	}
+	if rec, err := s.Get(ctx, "", prior.Chip); err != nil || rec.SubjectID != "prior" {
+		t.Errorf("a rejected item must not replace the stored record, got %+v, %v", rec, err)
+	}
+
+	// Upsert replaces the stored record instead
+	response = batch.Run(ctx, len(inputs), generate, s, batch.Options{Conflict: store.ConflictUpsert})
+	if r := response.Results[existing]; r.Stored == nil || !*r.Stored {
+		t.Errorf("upsert should store the item, got %+v", r)
+	}
+	if rec, _ := s.Get(ctx, "", prior.Chip); rec == nil || rec.SubjectID != "batch" {
+		t.Errorf("upsert should replace the stored record, got %+v", rec)
+	}
+
+	// Another tenant's record of the same CHIP is neither a conflict nor replaced
+	other := store.NewMemoryStore()
+	if err := other.Save(ctx, store.Record{Chip: prior.Chip, TenantID: "beta", SubjectID: "beta", Output: prior}); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	for _, conflict := range []store.Conflict{store.ConflictReject, store.ConflictUpsert} {
+		response = batch.Run(ctx, len(inputs), generate, other, batch.Options{Conflict: conflict})
+		if r := response.Results[existing]; r.Stored == nil || !*r.Stored || r.StorageError != nil {
+			t.Errorf("%s: another tenant's CHIP should not conflict, got %+v", conflict, r)
+		}
+	}
+	if rec, err := other.Get(ctx, "beta", prior.Chip); err != nil || rec.SubjectID != "beta" {
+		t.Errorf("a batch must not replace another tenant's record, got %+v, %v", rec, err)
+	}
+
+	// A batch without records never touches the store
+	response = batch.Run(ctx, 1, func(int) (*hcs.OutputHCS, *store.Record, error) { return prior, nil, nil }, nil, batch.Options{})
+	if response.Succeeded != 1 || response.Results[0].Stored != nil {
+		t.Errorf("unexpected result without storage: %+v", response)
+	}
+}
+
+func TestBatchStorageError(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		code errcode.Code
+	}{
+		{store.ErrConflict, errcode.Conflict},
+		{context.DeadlineExceeded, errcode.DeadlineExceeded},
+		{breaker.ErrOpen, errcode.StorageUnavailable},
+		{errors.New("disk full"), errcode.LookupFailed},
+	} {
+		if got := batch.StorageError("", tc.err); got.ErrorCode != tc.code || got.Code != errcode.Lookup(tc.code).Status {
+			t.Errorf("StorageError(%v) = %+v, want %s", tc.err, got, tc.code)
+		}
+	}
+}
+
+func TestBatchTooLargeResponse(t *testing.T) {
+	data, err := json.Marshal(batch.NewTooLargeResponse("", 12, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		Error     string       `json:"error"`
+		Message   string       `json:"message"`
+		Code      int          `json:"code"`
+		ErrorCode errcode.Code `json:"errorCode"`
+		MaxItems  int          `json:"maxItems"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ErrorCode != errcode.BatchTooLarge || body.Code != http.StatusRequestEntityTooLarge || body.MaxItems != 10 {
+		t.Errorf("unexpected body: %s", data)
+	}
+	if body.Message != "items must contain at most 10 entries, got 12" || body.Error != errcode.Lookup(errcode.BatchTooLarge).Title {
+		t.Errorf("unexpected message: %s", data)
+	}
+
+	if fr := batch.NewTooLargeResponse("fr", 12, 10); fr.Error == body.Error {
+		t.Errorf("the title should be translated, got %q", fr.Error)
+	}
+}
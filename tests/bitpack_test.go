@@ -0,0 +1,238 @@
+package tests
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestQuantizeUnit checks every level and rounding boundary of the bit-field
+// widths, and the clamping of out-of-range values.
+func TestQuantizeUnit(t *testing.T) {
+	const eps = 1e-9
+	for width := uint(1); width <= 4; width++ {
+		max := uint16(1)<<width - 1
+		for level := uint16(0); level <= max; level++ {
+			exact := float64(level) / float64(max)
+			for _, v := range []float64{exact, exact - eps, exact + eps} {
+				if got := hcs.QuantizeUnit(v, width); got != level {
+					t.Errorf("QuantizeUnit(%v, %d) = %d, want %d", v, width, got, level)
+				}
+			}
+			if level == max {
+				continue
+			}
+			// Halfway to the next level
+			boundary := (float64(level) + 0.5) / float64(max)
+			if got := hcs.QuantizeUnit(boundary-eps, width); got != level {
+				t.Errorf("QuantizeUnit just below %v (width %d) = %d, want %d", boundary, width, got, level)
+			}
+			if got := hcs.QuantizeUnit(boundary+eps, width); got != level+1 {
+				t.Errorf("QuantizeUnit just above %v (width %d) = %d, want %d", boundary, width, got, level+1)
+			}
+		}
+
+		for v, want := range map[float64]uint16{-0.5: 0, math.Inf(-1): 0, 1.5: max, math.Inf(1): max, 0.9999: max} {
+			if got := hcs.QuantizeUnit(v, width); got != want {
+				t.Errorf("QuantizeUnit(%v, %d) = %d, want %d", v, width, got, want)
+			}
+		}
+		if got := hcs.QuantizeUnit(math.NaN(), width); got != 0 {
+			t.Errorf("QuantizeUnit(NaN, %d) = %d, want 0", width, got)
+		}
+		for i := -500; i <= 1500; i++ {
+			if got := hcs.QuantizeUnit(float64(i)/1000, width); got > max {
+				t.Fatalf("QuantizeUnit(%v, %d) = %d overflows the field", float64(i)/1000, width, got)
+			}
+		}
+	}
+}
+
+// TestPackField verifies that a packed value stays within its field.
+func TestPackField(t *testing.T) {
+	for _, tc := range []struct {
+		value        uint16
+		shift, width uint
+		want         uint16
+	}{
+		{5, 10, 3, 5 << 10},
+		{8, 10, 3, 0},
+		{0xffff, 10, 3, 0x1c00},
+		{0xffff, 0, 3, 0x7},
+		{17, 12, 4, 1 << 12},
+		{3, 14, 2, 0xc000},
+	} {
+		if hcs.BitFieldAssertions() && tc.value >= 1<<tc.width {
+			continue // panics, see TestPackFieldAssertions
+		}
+		if got := hcs.PackField(tc.value, tc.shift, tc.width); got != tc.want {
+			t.Errorf("PackField(%d, %d, %d) = %#04x, want %#04x", tc.value, tc.shift, tc.width, got, tc.want)
+		}
+	}
+}
+
+// TestU5Quantization verifies the U5 bit fields of engine v3 against the
+// truncation of earlier engines, and that out-of-range values do not spill.
+func TestU5Quantization(t *testing.T) {
+	western := &hcs.WesternProfile{
+		DominantElement: "Air",
+		Modal:           hcs.ModalBalance{Cardinal: 0.9999, Fixed: 3.0 / 7, Mutable: 0},
+		Interaction:     hcs.InteractionPreferences{Pace: "balanced", Structure: "medium", Tone: "precise"},
+	}
+	chinese := &hcs.ChineseProfile{DayMaster: "Jia", ElementBalance: map[string]float64{"Wood": 1}}
+	fusion := &hcs.FusionProfile{FusionID: "A1"}
+	salt := []byte("salt")
+
+	// Modal fields: cardinal in bits 12-10, fixed in 9-7, mutable in 6-4
+	modal := func(engine string) [3]uint64 {
+		code, err := hcs.EncodeU5WithEngine(western, chinese, fusion, salt, engine)
+		if err != nil {
+			t.Fatalf("EncodeU5WithEngine(%s): %v", engine, err)
+		}
+		c, _ := hcs.DecodeU5(code)
+		bits, _ := strconv.ParseUint(c.Western.Hex, 16, 16)
+		return [3]uint64{bits >> 10 & 7, bits >> 7 & 7, bits >> 4 & 7}
+	}
+	if got := modal("v3"); got != [3]uint64{7, 3, 0} {
+		t.Errorf("v3 modal fields = %v, want [7 3 0]", got)
+	}
+	if got := modal("v2"); got[0] != 6 {
+		t.Errorf("v2 should truncate 0.9999 to 6, got %v", got)
+	}
+	current, _ := hcs.EncodeU5(western, chinese, fusion, salt)
+	if v3, _ := hcs.EncodeU5WithEngine(western, chinese, fusion, salt, "v3"); current != v3 {
+		t.Errorf("EncodeU5 should use the current engine, got %s and %s", current, v3)
+	}
+	if _, err := hcs.EncodeU5WithEngine(western, chinese, fusion, salt, "v9"); err == nil {
+		t.Error("an unknown engine should be rejected")
+	}
+
+	// Out-of-range values saturate their own field only
+	western.Modal = hcs.ModalBalance{Cardinal: 1.8, Fixed: -0.4, Mutable: 0}
+	for _, engine := range []string{"v2", "v3"} {
+		if got := modal(engine); got != [3]uint64{7, 0, 0} {
+			t.Errorf("%s: out-of-range modal fields = %v, want [7 0 0]", engine, got)
+		}
+	}
+	code, _ := hcs.EncodeU5(western, chinese, fusion, salt)
+	c, _ := hcs.DecodeU5(code)
+	if c.Western.Element != "Air" || c.Western.Pace != "balanced" || c.Western.HighStructure || !c.Western.SharpTone {
+		t.Errorf("the element, pace and tone fields should be untouched, got W:%s", c.Western.Hex)
+	}
+}
+
+// TestPackFieldAssertions verifies that an overflowing value is masked, or
+// panics in builds tagged hcs_debug.
+func TestPackFieldAssertions(t *testing.T) {
+	defer func() {
+		if r := recover(); (r != nil) != hcs.BitFieldAssertions() {
+			t.Errorf("recovered %v with assertions %v", r, hcs.BitFieldAssertions())
+		}
+	}()
+	if got := hcs.PackField(8, 4, 3); got != 0 {
+		t.Errorf("PackField(8, 4, 3) = %#04x, want 0", got)
+	}
+}
+
+// u5Fields encodes a U5 code with engine v3 and returns the 16 bits of each
+// of its W, C and F segments
+func u5Fields(t *testing.T, western *hcs.WesternProfile, chinese *hcs.ChineseProfile, fusion *hcs.FusionProfile) (w, c, f uint64) {
+	t.Helper()
+	code, err := hcs.EncodeU5WithEngine(western, chinese, fusion, []byte("salt"), "v3")
+	if err != nil {
+		t.Fatalf("EncodeU5WithEngine: %v", err)
+	}
+	decoded, err := hcs.DecodeU5(code)
+	if err != nil {
+		t.Fatalf("DecodeU5(%s): %v", code, err)
+	}
+	var bits [3]uint64
+	for i, segment := range []string{decoded.Western.Hex, decoded.Chinese.Hex, decoded.Fusion.Hex} {
+		if bits[i], err = strconv.ParseUint(segment, 16, 16); err != nil {
+			t.Fatalf("segment %q: %v", segment, err)
+		}
+	}
+	return bits[0], bits[1], bits[2]
+}
+
+// TestU5Lattice encodes every level of every U5 bit field, each value on the
+// lattice of its field, and verifies that each field decodes to its own level
+// and leaves its neighbours untouched.
+func TestU5Lattice(t *testing.T) {
+	level := func(l uint64) float64 { return float64(l) / 7 }
+	chinese := &hcs.ChineseProfile{DayMaster: "Jia", ElementBalance: map[string]float64{"Wood": 1}}
+	fusion := &hcs.FusionProfile{FusionID: "A1"}
+
+	elements := []string{"Fire", "Earth", "Air", "Water"}
+	paces := []string{"slow", "balanced", "fast"}
+	for e, element := range elements {
+		for p, pace := range paces {
+			for _, structure := range []string{"low", "medium", "high"} {
+				for _, tone := range []string{"warm", "neutral", "sharp", "precise"} {
+					for m := uint64(0); m < 512; m++ {
+						cardinal, fixed, mutable := m>>6, m>>3&7, m&7
+						western := &hcs.WesternProfile{
+							DominantElement: element,
+							Modal:           hcs.ModalBalance{Cardinal: level(cardinal), Fixed: level(fixed), Mutable: level(mutable)},
+							Interaction:     hcs.InteractionPreferences{Pace: pace, Structure: structure, Tone: tone},
+						}
+						var want uint64 = uint64(e)<<14 | cardinal<<10 | fixed<<7 | mutable<<4 | uint64(p)<<2
+						if structure == "high" {
+							want |= 2
+						}
+						if tone == "sharp" || tone == "precise" {
+							want |= 1
+						}
+						if w, _, _ := u5Fields(t, western, chinese, fusion); w != want {
+							t.Fatalf("%s %s %s %s modal %d/%d/%d: W = %016b, want %016b",
+								element, pace, structure, tone, cardinal, fixed, mutable, w, want)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	western := &hcs.WesternProfile{DominantElement: "Fire"}
+	for e, element := range []string{"Wood", "Fire", "Earth", "Metal", "Water"} {
+		for stem := range hcs.HeavenlyStems {
+			for m := uint64(0); m < 64; m++ {
+				yinYang, strength := m>>3, m&7
+				chinese := &hcs.ChineseProfile{
+					YinYangBalance:    level(yinYang),
+					DayMaster:         hcs.HeavenlyStems[stem].Name,
+					DayMasterStrength: level(strength),
+					ElementBalance:    map[string]float64{element: 1}, // fully skewed: distribution 7
+				}
+				want := uint64(e)<<13 | yinYang<<10 | uint64(stem)<<6 | strength<<3 | 7
+				if _, c, _ := u5Fields(t, western, chinese, fusion); c != want {
+					t.Fatalf("%s %s yin/yang %d strength %d: C = %016b, want %016b",
+						element, chinese.DayMaster, yinYang, strength, c, want)
+				}
+			}
+		}
+	}
+
+	for trait := 0; trait < 5; trait++ {
+		for m := uint64(0); m < 4096; m++ {
+			pace, intensity, unified, harmonic := m>>9, m>>6&7, m>>3&7, m&7
+			traits := make([]float64, 5)
+			traits[trait] = 1
+			fusion := &hcs.FusionProfile{
+				FusionID:          "A1",
+				CognitiveFusion:   hcs.CognitiveFusion{Analytical: traits[0], Creative: traits[1], Grounded: traits[2], Adaptive: traits[3], Expressive: traits[4]},
+				TempoSignals:      hcs.TempoSignals{Pace: level(pace), Intensity: level(intensity)},
+				UnifiedBalance:    level(unified),
+				HarmonicResonance: level(harmonic),
+			}
+			// The pattern of a lone trait is trait<<2; Expressive's keeps its low bits
+			want := uint64(trait<<2&0xf)<<12 | pace<<9 | intensity<<6 | unified<<3 | harmonic
+			if _, _, f := u5Fields(t, western, chinese, fusion); f != want {
+				t.Fatalf("trait %d tempo %d/%d balance %d/%d: F = %016b, want %016b",
+					trait, pace, intensity, unified, harmonic, f, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/breaker"
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+	"github.com/corehuman/hcs-lab-api/internal/webhook"
+)
+
+// failingStore fails every call while down is set
+type failingStore struct {
+	store.Store
+	down  bool
+	calls int
+}
+
+func (f *failingStore) Save(ctx context.Context, rec store.Record) error {
+	f.calls++
+	if f.down {
+		return errors.New("connection refused")
+	}
+	return f.Store.Save(ctx, rec)
+}
+
+func (f *failingStore) Get(ctx context.Context, tenantID, chip string) (*store.Record, error) {
+	f.calls++
+	if f.down {
+		return nil, errors.New("connection refused")
+	}
+	return f.Store.Get(ctx, tenantID, chip)
+}
+
+// TestCircuitBreaker verifies the closed, open and half-open transitions.
+func TestCircuitBreaker(t *testing.T) {
+	clk := clock.NewFrozen(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	b := breaker.New("test", 2, time.Minute)
+	b.Clock = clk
+	fail := func() error { return errors.New("down") }
+	ok := func() error { return nil }
+
+	b.Do(fail)
+	if s := b.Stats(); s.State != breaker.Closed || s.Failures != 1 {
+		t.Fatalf("one failure should not open the breaker: %+v", s)
+	}
+	b.Do(fail)
+	if err := b.Do(ok); !errors.Is(err, breaker.ErrOpen) {
+		t.Fatalf("the breaker should fail fast once open, got %v", err)
+	}
+	if err := b.Do(func() error { return context.Canceled }); !errors.Is(err, breaker.ErrOpen) {
+		t.Fatal("the breaker should stay open during the cooldown")
+	}
+
+	clk.Advance(time.Minute)
+	if s := b.Stats(); s.State != breaker.HalfOpen || s.Opens != 1 {
+		t.Fatalf("the breaker should be half-open after the cooldown: %+v", s)
+	}
+	b.Do(fail) // a failed trial reopens immediately
+	if s := b.Stats(); s.State != breaker.Open {
+		t.Fatalf("a failed trial should reopen the breaker: %+v", s)
+	}
+
+	clk.Advance(time.Minute)
+	if err := b.Do(ok); err != nil {
+		t.Fatalf("the trial call should go through: %v", err)
+	}
+	if s := b.Stats(); s.State != breaker.Closed || s.Failures != 0 {
+		t.Errorf("a successful trial should close the breaker: %+v", s)
+	}
+
+	b.Do(fail)
+	b.Do(func() error { return context.Canceled })
+	b.Do(fail)
+	if b.Stats().State != breaker.Open {
+		t.Error("a caller cancellation should not reset the failure count")
+	}
+}
+
+// TestStoreBreaker verifies that a failing store is bypassed and not-found is not a failure.
+func TestStoreBreaker(t *testing.T) {
+	ctx := context.Background()
+	backend := &failingStore{Store: store.NewMemoryStore()}
+	s := store.WithBreaker(backend, breaker.New("storage", 2, time.Hour))
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.Get(ctx, "", "missing"); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+	}
+
+	backend.down = true
+	s.Save(ctx, store.Record{Chip: "a"})
+	s.Save(ctx, store.Record{Chip: "b"})
+	calls := backend.calls
+	if err := s.Save(ctx, store.Record{Chip: "c"}); !errors.Is(err, breaker.ErrOpen) {
+		t.Fatalf("expected ErrOpen, got %v", err)
+	}
+	if backend.calls != calls {
+		t.Error("an open breaker should not call the store")
+	}
+	if store.WithBreaker(nil, breaker.New("storage", 0, 0)) != nil {
+		t.Error("a disabled store should stay nil")
+	}
+}
+
+// TestWebhookOutbox verifies that undeliverable events are queued and delivered in order later.
+func TestWebhookOutbox(t *testing.T) {
+	var up atomic.Bool
+	var delivered []any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var event webhook.Event
+		json.NewDecoder(r.Body).Decode(&event)
+		delivered = append(delivered, event.Data)
+	}))
+	defer srv.Close()
+
+	clk := clock.NewFrozen(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	b := breaker.New("webhook", 1, time.Minute)
+	b.Clock = clk
+	outbox := webhook.NewOutbox(webhook.NewNotifier(srv.URL), b)
+	outbox.Max = 2
+	ctx := context.Background()
+
+	if err := outbox.Send(ctx, "code.expiring", 1); err != nil {
+		t.Fatalf("a failed delivery should be queued, got %v", err)
+	}
+	if err := outbox.Send(ctx, "code.expiring", 2); err != nil {
+		t.Fatalf("events should be queued while the breaker is open, got %v", err)
+	}
+	if err := outbox.Send(ctx, "code.expiring", 3); !errors.Is(err, webhook.ErrOutboxFull) {
+		t.Fatalf("expected ErrOutboxFull, got %v", err)
+	}
+
+	up.Store(true)
+	outbox.Flush(ctx)
+	if outbox.Len() != 2 || len(delivered) != 0 {
+		t.Fatal("nothing should be delivered before the cooldown")
+	}
+	clk.Advance(time.Minute)
+	outbox.Flush(ctx)
+	if outbox.Len() != 0 || !reflect.DeepEqual(delivered, []any{1.0, 2.0}) {
+		t.Errorf("queued events should be delivered in order after recovery, %d queued, delivered %v", outbox.Len(), delivered)
+	}
+}
@@ -0,0 +1,40 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestGeneratorClock verifies that issuance metadata follows an injected clock.
+func TestGeneratorClock(t *testing.T) {
+	frozen := clock.NewFrozen(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	gen, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()), hcs.WithClock(frozen))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	opts := &hcs.GeneratorOptions{ValidityMonths: 1, SkipU7: true}
+
+	out, err := gen.GenerateWithOptions(getTestInput(), opts)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if out.Metadata.IssuedAt != "2024-01-15T12:00:00Z" || out.Metadata.ValidUntil != "2024-02-15T12:00:00Z" {
+		t.Errorf("unexpected metadata with frozen clock: %+v", out.Metadata)
+	}
+
+	frozen.Advance(24 * time.Hour)
+	out, err = gen.GenerateWithOptions(getTestInput(), opts)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if out.Metadata.IssuedAt != "2024-01-16T12:00:00Z" {
+		t.Errorf("IssuedAt = %s, want the advanced clock", out.Metadata.IssuedAt)
+	}
+
+	if _, err := hcs.NewGenerator(hcs.WithClock(nil)); err == nil {
+		t.Error("expected error for a nil clock")
+	}
+}
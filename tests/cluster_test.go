@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+func TestProfileVector(t *testing.T) {
+	v := hcs.ProfileVector(hcs.NormalizeProfile(getTestInput()))
+	if len(v) != len(hcs.ProfileVectorDimensions) {
+		t.Fatalf("vector has %d dimensions, expected %d", len(v), len(hcs.ProfileVectorDimensions))
+	}
+	if v[1] != 1 || v[0] != 0 {
+		t.Errorf("Air should be one-hot encoded in dimension 1, got %v", v[:4])
+	}
+	for i, x := range v {
+		if x < 0 || x > 1 {
+			t.Errorf("dimension %s out of range: %f", hcs.ProfileVectorDimensions[i], x)
+		}
+	}
+}
+
+func TestKMeans(t *testing.T) {
+	vectors := [][]float64{
+		{0, 0}, {0.1, 0}, {0, 0.1},
+		{1, 1}, {0.9, 1}, {1, 0.9},
+	}
+
+	result, err := hcs.KMeans(vectors, 2, 50)
+	if err != nil {
+		t.Fatalf("KMeans failed: %v", err)
+	}
+	a := result.Assignments
+	if a[0] != a[1] || a[1] != a[2] || a[3] != a[4] || a[4] != a[5] || a[0] == a[3] {
+		t.Errorf("unexpected assignments: %v", a)
+	}
+
+	again, _ := hcs.KMeans(vectors, 2, 50)
+	for i := range a {
+		if again.Assignments[i] != a[i] {
+			t.Fatalf("KMeans is not deterministic: %v != %v", again.Assignments, a)
+		}
+	}
+
+	small, err := hcs.KMeans(vectors[:1], 5, 10)
+	if err != nil || len(small.Centroids) != 1 {
+		t.Errorf("k should shrink to the number of vectors, got %+v, %v", small, err)
+	}
+	if _, err := hcs.KMeans(vectors, 0, 10); err == nil {
+		t.Errorf("k=0 should be rejected")
+	}
+}
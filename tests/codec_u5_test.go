@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"math"
 	"strings"
 	"testing"
 
@@ -152,45 +153,98 @@ func TestValidateU5Format(t *testing.T) {
 	}
 }
 
-// TestDecodeU5 tests the U5 decoding
+// TestDecodeU5 verifies that DecodeU5 splits an HCS-U5 code into its segments
+// and unpacks each field of the W, C and F bit layouts
 func TestDecodeU5(t *testing.T) {
-	validCode := "HCS-U5|A1|W:1234|C:5678|F:9abc|CHIP:def012345678"
+	validCode := "HCS-U5|A1|W:1234|C:5678|F:9abc|CHIP:def012345678|EP:2"
 
-	components, err := hcs.DecodeU5(validCode)
+	c, err := hcs.DecodeU5(validCode)
 	if err != nil {
 		t.Fatalf("Failed to decode valid U5: %v", err)
 	}
-
-	// Check fusion ID
-	if components["fusionId"] != "A1" {
-		t.Errorf("Expected fusion ID 'A1', got '%s'", components["fusionId"])
+	if c.FusionID != "A1" || c.Chip != "def012345678" || c.Epoch != 2 || c.Lineage != "" {
+		t.Errorf("unexpected fusion ID, CHIP, epoch or lineage: %+v", c)
 	}
-
-	// Check western hex
-	if components["western"] != "1234" {
-		t.Errorf("Expected western hex '1234', got '%s'", components["western"])
+	if c.Western.Hex != "1234" || c.Chinese.Hex != "5678" || c.Fusion.Hex != "9abc" {
+		t.Errorf("unexpected segments: %s %s %s", c.Western.Hex, c.Chinese.Hex, c.Fusion.Hex)
 	}
 
-	// Check chinese hex
-	if components["chinese"] != "5678" {
-		t.Errorf("Expected chinese hex '5678', got '%s'", components["chinese"])
+	// W:1234 is Fire, modal levels 4, 4 and 3 of 7, balanced pace, neither high structure nor sharp tone
+	wantWestern := hcs.U5Western{Hex: "1234", Element: "Fire", Modal: hcs.ModalBalance{Cardinal: 4.0 / 7, Fixed: 4.0 / 7, Mutable: 3.0 / 7}, Pace: "balanced"}
+	if c.Western != wantWestern {
+		t.Errorf("western = %+v, want %+v", c.Western, wantWestern)
+	}
+	wantChinese := hcs.U5Chinese{Hex: "5678", Element: "Earth", YinYangBalance: 5.0 / 7, YinYangZone: "Yang-dominant",
+		DayMasterIndex: 9, DayMaster: hcs.HeavenlyStems[9].Name, DayMasterStrength: 1, ElementSkew: 0}
+	if c.Chinese != wantChinese {
+		t.Errorf("chinese = %+v, want %+v", c.Chinese, wantChinese)
+	}
+	wantFusion := hcs.U5Fusion{Hex: "9abc", PrimaryTrait: "Grounded", SecondaryTrait: "Creative",
+		TempoPace: 5.0 / 7, TempoIntensity: 2.0 / 7, UnifiedBalance: 1, HarmonicResonance: 4.0 / 7}
+	if c.Fusion != wantFusion {
+		t.Errorf("fusion = %+v, want %+v", c.Fusion, wantFusion)
 	}
 
-	// Check fusion hex
-	if components["fusion"] != "9abc" {
-		t.Errorf("Expected fusion hex '9abc', got '%s'", components["fusion"])
+	for _, invalid := range []string{
+		"HCS-U3|invalid",
+		"HCS-U5|A1|W:1234|C:5678|F:9abc|CHIP:def",               // short CHIP
+		"HCS-U5|A1|W:1234|C:5678|F:9abc|CHIP:def012345678|EP:0", // epoch 0 is implicit
+		"HCS-U5|A1|W:123|C:5678|F:9abc|CHIP:def012345678",
+		"HCS-U5|A1|W:000c|C:5678|F:9abc|CHIP:def012345678", // no pace 3
+		"HCS-U5|A1|W:1234|C:a000|F:9abc|CHIP:def012345678", // no sixth element
+		"HCS-U5|A1|W:1234|C:0280|F:9abc|CHIP:def012345678", // no eleventh day master
+	} {
+		if _, err := hcs.DecodeU5(invalid); err == nil {
+			t.Errorf("decoding %s should fail", invalid)
+		}
 	}
+}
 
-	// Check CHIP
-	if components["chip"] != "def012345678" {
-		t.Errorf("Expected CHIP 'def012345678', got '%s'", components["chip"])
+// TestDecodeU5Profile verifies that the components decoded from a generated
+// U5 code approximate the combined profile it was encoded from.
+func TestDecodeU5Profile(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	input := getTestInput()
+	input.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 5, Day: 1, Hour: 12, Timezone: "UTC"}
+	out, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{PreviousChip: "0123456789ab"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	c, err := hcs.DecodeU5(out.CodeU5)
+	if err != nil {
+		t.Fatalf("failed to decode %s: %v", out.CodeU5, err)
+	}
+	western, chinese, fusion := out.CombinedProfile.Western, out.CombinedProfile.Chinese, out.CombinedProfile.Fusion
+
+	if c.FusionID != fusion.FusionID || c.Lineage != hcs.LineageHash("0123456789ab") ||
+		c.Western.Element != western.DominantElement || c.Western.Pace != western.Interaction.Pace ||
+		c.Chinese.Element != chinese.GetDominantChineseElement() || c.Chinese.DayMaster != chinese.DayMaster {
+		t.Errorf("unexpected decoded components: %+v", c)
+	}
+	// Each 3-bit level is the nearest of eight, within half a step
+	for _, pair := range [][2]float64{
+		{c.Western.Modal.Cardinal, western.Modal.Cardinal},
+		{c.Western.Modal.Fixed, western.Modal.Fixed},
+		{c.Western.Modal.Mutable, western.Modal.Mutable},
+		{c.Chinese.YinYangBalance, chinese.YinYangBalance},
+		{c.Chinese.DayMasterStrength, chinese.DayMasterStrength},
+		{c.Fusion.TempoPace, fusion.TempoSignals.Pace},
+		{c.Fusion.TempoIntensity, fusion.TempoSignals.Intensity},
+		{c.Fusion.UnifiedBalance, fusion.UnifiedBalance},
+		{c.Fusion.HarmonicResonance, fusion.HarmonicResonance},
+	} {
+		if math.Abs(pair[0]-pair[1]) > 0.5/7+1e-9 {
+			t.Errorf("decoded %v, want %v within half a level", pair[0], pair[1])
+		}
 	}
 
-	// Test invalid code
-	invalidCode := "HCS-U3|invalid"
-	_, err = hcs.DecodeU5(invalidCode)
-	if err == nil {
-		t.Error("Decoding invalid U5 should return error")
+	d, err := hcs.Decode(out.CodeU5)
+	if err != nil || d.U5 == nil || *d.U5 != *c {
+		t.Errorf("Decode should carry the U5 components: %+v, %v", d, err)
 	}
 }
 
@@ -296,7 +350,7 @@ func TestFusionProfile(t *testing.T) {
 // TestFullIntegration tests the complete flow with generator
 func TestFullIntegration(t *testing.T) {
 	// Create generator
-	gen, err := hcs.NewGeneratorWithSaltDir(".")
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
 	if err != nil {
 		t.Fatalf("Failed to create generator: %v", err)
 	}
@@ -418,3 +472,51 @@ func extractChip(code string) string {
 	}
 	return code[start : start+12]
 }
+
+// TestU5TieBreaking verifies that tied elements and cognitive traits resolve
+// to the earliest in their fixed order, the same way on every run.
+func TestU5TieBreaking(t *testing.T) {
+	// Born 1990-06-01 04:00 UTC: Fire and Metal both hold a third of the pillars
+	birth := hcs.BirthInfo{Year: 1990, Month: 6, Day: 1, Hour: 4, Timezone: "UTC"}
+	chinese, err := hcs.ComputeChineseProfile(birth)
+	if err != nil {
+		t.Fatalf("ComputeChineseProfile: %v", err)
+	}
+	if chinese.ElementBalance["Fire"] != chinese.ElementBalance["Metal"] {
+		t.Fatalf("expected a Fire-Metal tie, got %v", chinese.ElementBalance)
+	}
+
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	input := getTestInput()
+	input.BirthInfo = &birth
+	first, err := gen.Generate(input)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// Tied cognitive traits: Analytical leads and Creative comes second
+	fusion := first.CombinedProfile.Fusion
+	fusion.CognitiveFusion = hcs.CognitiveFusion{Analytical: 0.5, Creative: 0.5, Grounded: 0.5, Adaptive: 0.5, Expressive: 0.5}
+	tied, _ := hcs.EncodeU5(&first.CombinedProfile.Western, chinese, &fusion, []byte("salt"))
+
+	for i := 0; i < 50; i++ {
+		if got := chinese.GetDominantChineseElement(); got != "Fire" {
+			t.Fatalf("run %d: dominant element %s, want Fire (the earlier of the tie)", i, got)
+		}
+		out, _ := gen.Generate(input)
+		if out.CodeU5 != first.CodeU5 || out.CombinedProfile.Fusion.FusionID != first.CombinedProfile.Fusion.FusionID {
+			t.Fatalf("run %d: U5 %s differs from %s", i, out.CodeU5, first.CodeU5)
+		}
+		code, _ := hcs.EncodeU5(&first.CombinedProfile.Western, chinese, &fusion, []byte("salt"))
+		if code != tied {
+			t.Fatalf("run %d: tied cognitive traits gave %s, then %s", i, tied, code)
+		}
+	}
+	if c, _ := hcs.DecodeU5(tied); c.Fusion.PrimaryTrait != "Analytical" || c.Fusion.SecondaryTrait != "Creative" {
+		t.Errorf("tied traits should encode Analytical, then Creative, got %+v", c.Fusion)
+	}
+}
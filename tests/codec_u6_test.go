@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestEncodeU6 verifies that an HCS-U6 code decodes back to the normalized
+// profile, CHIP, epoch and lineage it was generated with, and to the Chinese
+// and fusion profiles to the thousandth.
+func TestEncodeU6(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	input := getTestInput()
+
+	plain, err := gen.Generate(input)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if !hcs.ValidateU6Format(plain.CodeU6) {
+		t.Fatalf("invalid U6 code: %s", plain.CodeU6)
+	}
+	d, err := hcs.DecodeU6(plain.CodeU6)
+	if err != nil {
+		t.Fatalf("failed to decode %s: %v", plain.CodeU6, err)
+	}
+	if d.Profile != *hcs.NormalizeProfile(input) || d.Chip != plain.Chip || d.Epoch != 0 || d.Lineage != "" || d.Chinese != nil || d.Fusion != nil {
+		t.Errorf("unexpected decoded code: %+v", d)
+	}
+	if len(plain.CodeU6) >= len(plain.CodeU4)/4 {
+		t.Errorf("U6 code should be far shorter than U4: %d vs %d characters", len(plain.CodeU6), len(plain.CodeU4))
+	}
+	if code, err := hcs.EncodeU6(&d.Profile, d.Chip, nil); err != nil || code != plain.CodeU6 {
+		t.Errorf("EncodeU6 = %s, %v; want %s", code, err, plain.CodeU6)
+	}
+
+	input.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 5, Day: 1, Hour: 12, Timezone: "UTC"}
+	out, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{PreviousChip: plain.Chip})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	d, err = hcs.DecodeU6(out.CodeU6)
+	if err != nil {
+		t.Fatalf("failed to decode %s: %v", out.CodeU6, err)
+	}
+	if d.Lineage != hcs.LineageHash(plain.Chip) || d.Chinese == nil || d.Fusion == nil {
+		t.Fatalf("unexpected decoded code: %+v", d)
+	}
+	chinese, fusion := out.CombinedProfile.Chinese, out.CombinedProfile.Fusion
+	if d.Chinese.YearPillar != chinese.YearPillar || d.Chinese.HourPillar != chinese.HourPillar ||
+		d.Chinese.DayMaster != chinese.DayMaster || d.Fusion.FusionID != fusion.FusionID ||
+		d.Fusion.TempoSignals.Rhythm != fusion.TempoSignals.Rhythm {
+		t.Errorf("unexpected Chinese and fusion profiles: %+v %+v", d.Chinese, d.Fusion)
+	}
+	for _, pair := range [][2]float64{
+		{d.Chinese.YinYangBalance, chinese.YinYangBalance},
+		{d.Chinese.ElementBalance["Fire"], chinese.ElementBalance["Fire"]},
+		{d.Chinese.DayMasterStrength, chinese.DayMasterStrength},
+		{d.Fusion.ElementSignature["Water"], fusion.ElementSignature["Water"]},
+		{d.Fusion.CognitiveFusion.Creative, fusion.CognitiveFusion.Creative},
+		{d.Fusion.HarmonicResonance, fusion.HarmonicResonance},
+	} {
+		if math.Abs(pair[0]-pair[1]) > 0.0005 {
+			t.Errorf("decoded %v, want %v to the thousandth", pair[0], pair[1])
+		}
+	}
+	if chip, err := hcs.ChipFromCode(out.CodeU6); err != nil || chip != out.Chip {
+		t.Errorf("ChipFromCode = %s, %v; want %s", chip, err, out.Chip)
+	}
+	if err := gen.VerifyCHIP(out.CodeU6, nil); err != nil {
+		t.Errorf("VerifyCHIP: %v", err)
+	}
+	if got, err := hcs.NormalizedFromCode(out.CodeU6); err != nil || *got != *hcs.NormalizeProfile(input) {
+		t.Errorf("NormalizedFromCode = %+v, %v", got, err)
+	}
+
+	skipped, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{SkipU6: true})
+	if err != nil || skipped.CodeU6 != "" {
+		t.Errorf("SkipU6 should not generate a U6 code: %q, %v", skipped.CodeU6, err)
+	}
+}
+
+// TestDecodeU6Invalid verifies that malformed and non-canonical HCS-U6 codes
+// are rejected.
+func TestDecodeU6Invalid(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	out, err := gen.Generate(getTestInput())
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	payload := strings.TrimPrefix(out.CodeU6, "HCS-U6|")
+
+	for _, code := range []string{
+		"HCS-U6|",
+		"HCS-U6|" + payload + "!",
+		"HCS-U6|0" + payload, // leading zero digit
+		"HCS-U6|" + payload[:len(payload)-3],
+		"HCS-U6|" + payload + "00",
+		"HCS-U4|" + payload,
+	} {
+		if hcs.ValidateU6Format(code) {
+			t.Errorf("%s should be rejected", code)
+		}
+	}
+
+	if _, err := hcs.EncodeU6(hcs.NormalizeProfile(getTestInput()), "not-a-chip", nil); err == nil {
+		t.Error("EncodeU6 should reject an invalid CHIP")
+	}
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+func TestNormalizedFromCode(t *testing.T) {
+	setTestSecretKey(t)
+
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	input := getTestInput()
+	out, err := gen.Generate(input)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	want := *hcs.NormalizeProfile(input)
+
+	for _, code := range []string{out.CodeU3, out.CodeU4, out.CodeU7} {
+		got, err := hcs.NormalizedFromCode(code)
+		if err != nil {
+			t.Errorf("NormalizedFromCode(%q) failed: %v", code, err)
+			continue
+		}
+		if *got != want {
+			t.Errorf("NormalizedFromCode(%q) = %+v, want %+v", code, *got, want)
+		}
+	}
+
+	if _, err := hcs.NormalizedFromCode("HCS-U5|A1|W:0000|C:0000|F:0000|CHIP:000000000000"); err == nil {
+		t.Errorf("U5 codes should be rejected")
+	}
+	if _, err := hcs.NormalizedFromCode("HCS-U9|garbage"); err == nil {
+		t.Errorf("unknown levels should be rejected")
+	}
+}
+
+func TestCompatibilityMatrix(t *testing.T) {
+	a := hcs.NormalizeProfile(getTestInput())
+
+	fire := getTestInput()
+	fire.DominantElement = "Fire"
+	b := hcs.NormalizeProfile(fire)
+
+	water := getTestInput()
+	water.DominantElement = "Water"
+	water.Interaction.Pace = "slow"
+	c := hcs.NormalizeProfile(water)
+
+	matrix := hcs.CompatibilityMatrix([]*hcs.NormalizedProfile{a, b, c})
+	if len(matrix) != 3 {
+		t.Fatalf("expected 3x3 matrix, got %d rows", len(matrix))
+	}
+	for i := range matrix {
+		if matrix[i][i] != 1 {
+			t.Errorf("diagonal [%d][%d] = %f, want 1", i, i, matrix[i][i])
+		}
+		for j := range matrix[i] {
+			if matrix[i][j] != matrix[j][i] {
+				t.Errorf("matrix not symmetric at [%d][%d]", i, j)
+			}
+			if matrix[i][j] < 0 || matrix[i][j] > 1 {
+				t.Errorf("score out of range at [%d][%d]: %f", i, j, matrix[i][j])
+			}
+		}
+	}
+
+	// Air/Fire is a complementary pair; Air/Water with mismatched pace is not
+	if matrix[0][1] <= matrix[0][2] {
+		t.Errorf("expected Air-Fire (%f) to score above Air-Water slow (%f)", matrix[0][1], matrix[0][2])
+	}
+}
+
+func TestCompatibilityWeights(t *testing.T) {
+	c := hcs.Compatibility{ElementSynergy: 1, TempoAlignment: 0.5, CognitiveComplementarity: 0}
+
+	if got := c.Weighted(hcs.CompatibilityWeights{Element: 1}); got != 1 {
+		t.Errorf("element-only weighting = %f, want 1", got)
+	}
+	if got := c.Weighted(hcs.CompatibilityWeights{Element: 2, Tempo: 2}); got != 0.75 {
+		t.Errorf("element+tempo weighting = %f, want 0.75", got)
+	}
+	if err := (hcs.CompatibilityWeights{Element: -1, Tempo: 1}).Validate(); err == nil {
+		t.Errorf("negative weights should be rejected")
+	}
+	if err := (hcs.CompatibilityWeights{}).Validate(); err == nil {
+		t.Errorf("all-zero weights should be rejected")
+	}
+
+	a := hcs.NormalizeProfile(getTestInput())
+	compat := hcs.ComputeCompatibility(a, a)
+	if compat.Resonance != compat.Weighted(hcs.DefaultCompatibilityWeights()) {
+		t.Errorf("resonance should use the default weights")
+	}
+}
+
+// TestCompareProfiles verifies the pairwise report of two input profiles and
+// of their codes.
+func TestCompareProfiles(t *testing.T) {
+	setTestSecretKey(t)
+	a := getTestInput()
+	b := getTestInput()
+	b.DominantElement = "Fire"
+	b.Interaction.Pace = "fast"
+
+	report, err := hcs.CompareProfiles(a, b)
+	if err != nil {
+		t.Fatalf("CompareProfiles: %v", err)
+	}
+	if report.ElementSynergy != 1 {
+		t.Errorf("Air and Fire should have full element synergy, got %v", report.ElementSynergy)
+	}
+	if report.TempoAlignment >= 1 || report.Resonance != report.Weighted(hcs.DefaultCompatibilityWeights()) {
+		t.Errorf("unexpected report for different paces: %+v", report)
+	}
+	if reverse, _ := hcs.CompareProfiles(b, a); reverse != report {
+		t.Errorf("the report should be symmetric, got %+v and %+v", report, reverse)
+	}
+
+	// Codes of the same profiles give the same report
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	outA, _ := gen.Generate(a)
+	outB, _ := gen.Generate(b)
+	pa, _ := hcs.NormalizedFromCode(outA.CodeU7)
+	pb, _ := hcs.NormalizedFromCode(outB.CodeU3)
+	if fromCodes := hcs.ComputeCompatibility(pa, pb); fromCodes != report {
+		t.Errorf("codes should compare like their profiles, got %+v and %+v", fromCodes, report)
+	}
+
+	invalid := getTestInput()
+	invalid.DominantElement = "Metal"
+	if _, err := hcs.CompareProfiles(a, invalid); err == nil {
+		t.Error("an invalid profile should be rejected")
+	}
+	if _, err := hcs.CompareProfiles(a, nil); err == nil {
+		t.Error("a missing profile should be rejected")
+	}
+}
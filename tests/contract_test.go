@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/contract"
+	"github.com/corehuman/hcs-lab-api/pkg/hcstest"
+)
+
+// TestContractSuite verifies that the conformance suite certifies a
+// conforming deployment and rejects a broken one.
+func TestContractSuite(t *testing.T) {
+	srv := hcstest.NewServer(t)
+	results := (&contract.Suite{BaseURL: srv.URL}).Run(context.Background())
+	for _, r := range results {
+		if !r.Passed {
+			t.Errorf("check %s failed against the mock API: %s", r.Name, r.Detail)
+		}
+	}
+	if !contract.Passed(results) {
+		t.Error("Passed should be true when every check passes")
+	}
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"healthy"}`))
+	}))
+	defer broken.Close()
+	failed := map[string]bool{}
+	for _, r := range (&contract.Suite{BaseURL: broken.URL}).Run(context.Background()) {
+		failed[r.Name] = !r.Passed
+	}
+	for _, name := range []string{"generate-schema", "error-invalid-json", "error-unknown-code"} {
+		if !failed[name] {
+			t.Errorf("check %s should fail against a broken deployment", name)
+		}
+	}
+	if failed["health"] {
+		t.Error("health should pass: the broken deployment reports healthy")
+	}
+}
+
+// TestContractPing verifies the healthcheck ping against a healthy deployment,
+// a pinned CHIP and a deployment that cannot generate.
+func TestContractPing(t *testing.T) {
+	srv := hcstest.NewServer(t)
+	suite := &contract.Suite{BaseURL: srv.URL}
+	report := suite.Ping(context.Background(), "")
+	if !report.Healthy || report.Chip == "" {
+		t.Fatalf("ping of a healthy deployment failed: %+v", report)
+	}
+	if again := suite.Ping(context.Background(), report.Chip); !again.Healthy {
+		t.Errorf("ping with the expected CHIP failed: %+v", again)
+	}
+	if pinned := suite.Ping(context.Background(), "HCS-CHIP-OTHER"); pinned.Healthy {
+		t.Error("ping should fail when the canary CHIP differs from the expected one")
+	}
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"healthy"}`))
+	}))
+	defer broken.Close()
+	report = (&contract.Suite{BaseURL: broken.URL}).Ping(context.Background(), "")
+	if report.Healthy {
+		t.Error("ping should fail when generation returns no CHIP")
+	}
+	if n := len(report.Checks); n != 2 || !report.Checks[0].Passed {
+		t.Errorf("expected health to pass and ping to stop after canary-generate, got %+v", report.Checks)
+	}
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"encoding/hex"
 	"testing"
 
@@ -142,6 +143,101 @@ func TestGenerateCHIP(t *testing.T) {
 	t.Logf("CHIP with different salt: %s", chip3)
 }
 
+// TestHardenedCHIP verifies the Argon2id CHIP mode and its recorded cost.
+func TestHardenedCHIP(t *testing.T) {
+	skipInFIPSMode(t)
+	// Cheap cost keeps the test fast; production uses DefaultCHIPHardening
+	cost := hcs.CHIPHardening{Algorithm: hcs.CHIPAlgorithmArgon2id, Time: 1, MemoryKiB: 64, Threads: 1}
+	dir := t.TempDir()
+
+	plain, err := hcs.NewGeneratorWithSaltDir(dir)
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	hardened, err := hcs.NewGenerator(hcs.WithSaltDir(dir), hcs.WithHardenedCHIP(cost))
+	if err != nil {
+		t.Fatalf("failed to create hardened generator: %v", err)
+	}
+
+	input := &hcs.InputProfile{
+		DominantElement: "Air",
+		Modal:           hcs.ModalBalance{Cardinal: 0.31, Fixed: 0.23, Mutable: 0.46},
+		Cognition:       hcs.CognitionProfile{Fluid: 0.52, Crystallized: 0.13, Verbal: 0.53, Strategic: 0.15, Creative: 0.33},
+		Interaction:     hcs.InteractionPreferences{Pace: "balanced", Structure: "medium", Tone: "precise"},
+	}
+	opts := &hcs.GeneratorOptions{SkipU7: true}
+
+	a, err := hardened.GenerateWithOptions(input, opts)
+	if err != nil {
+		t.Fatalf("hardened generation failed: %v", err)
+	}
+	b, err := hardened.GenerateWithOptions(input, opts)
+	if err != nil {
+		t.Fatalf("hardened generation failed: %v", err)
+	}
+	p, err := plain.GenerateWithOptions(input, opts)
+	if err != nil {
+		t.Fatalf("plain generation failed: %v", err)
+	}
+
+	if a.Chip != b.Chip {
+		t.Errorf("hardened CHIP not deterministic: %s != %s", a.Chip, b.Chip)
+	}
+	if a.Chip == p.Chip {
+		t.Error("hardened CHIP should differ from the SHA256 CHIP")
+	}
+	if a.Metadata == nil || a.Metadata.CHIPHardening == nil || *a.Metadata.CHIPHardening != cost {
+		t.Errorf("hardening cost not recorded in metadata: %+v", a.Metadata)
+	}
+	if p.Metadata != nil && p.Metadata.CHIPHardening != nil {
+		t.Error("plain CHIPs should not record a hardening cost")
+	}
+
+	salt, err := hcs.LoadOrCreateSalt(dir)
+	if err != nil {
+		t.Fatalf("failed to load salt: %v", err)
+	}
+	chip, err := hcs.GenerateHardenedCHIP(salt, hcs.NormalizeProfile(input), cost)
+	if err != nil || chip != a.Chip {
+		t.Errorf("GenerateHardenedCHIP = %s, %v; want %s", chip, err, a.Chip)
+	}
+
+	// Codes verify with the cost recorded in their metadata, whatever the
+	// generator now issues
+	raised := cost
+	raised.Time = 2
+	current, err := hcs.NewGenerator(hcs.WithSaltDir(dir), hcs.WithHardenedCHIP(raised))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	if err := current.VerifyCHIP(a.CodeU3, a.Metadata.CHIPHardening); err != nil {
+		t.Errorf("hardened code with its recorded cost: %v", err)
+	}
+	if err := current.VerifyCHIP(p.CodeU3, nil); err != nil {
+		t.Errorf("plain code: %v", err)
+	}
+	if err := current.VerifyCHIP(a.CodeU3, current.CHIPHardening()); err == nil {
+		t.Error("hardened code verified with another cost")
+	}
+	if v, err := current.VerifyProfile(context.Background(), a.CodeU3, input, a.Metadata.CHIPHardening, opts); err != nil || !v.Valid {
+		t.Errorf("VerifyProfile with the recorded cost = %+v, %v", v, err)
+	}
+
+	traced, err := hardened.GenerateWithOptions(input, &hcs.GeneratorOptions{SkipU7: true, Trace: true})
+	if err != nil || traced.Trace == nil || traced.Trace.ChipDigest[:12] != a.Chip {
+		t.Errorf("trace of a hardened CHIP = %+v, %v", traced.Trace, err)
+	}
+
+	invalid := cost
+	invalid.Time = 0
+	if err := current.VerifyCHIP(a.CodeU3, &invalid); err == nil {
+		t.Error("expected error for a recorded zero Argon2id time")
+	}
+	if _, err := hcs.NewGenerator(hcs.WithSaltDir(dir), hcs.WithHardenedCHIP(invalid)); err == nil {
+		t.Error("expected error for zero Argon2id time")
+	}
+}
+
 func TestClampingBehavior(t *testing.T) {
 	tests := []struct {
 		name  string
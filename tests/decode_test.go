@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestDecode verifies that every code level of a generated profile decodes
+// back to the profile, CHIP and signatures it was issued with.
+func TestDecode(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	input := getTestInput()
+	input.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 5, Day: 1, Hour: 12, Timezone: "UTC"}
+	first, err := gen.Generate(input)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	out, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{PreviousChip: first.Chip})
+	if err != nil {
+		t.Fatalf("failed to regenerate: %v", err)
+	}
+	want := hcs.NormalizeProfile(input)
+	lineage := hcs.LineageHash(first.Chip)
+
+	for level, code := range map[string]string{"U3": out.CodeU3, "U4": out.CodeU4, "U6": out.CodeU6, "U7": out.CodeU7} {
+		d, err := hcs.Decode(code)
+		if err != nil {
+			t.Fatalf("%s: failed to decode %s: %v", level, code, err)
+		}
+		if d.Level != level || d.Element != want.Element || *d.Modal != want.Modal ||
+			*d.Cognition != want.Cog || *d.Interaction != want.Int || d.Lineage != lineage {
+			t.Errorf("%s: unexpected decoded code: %+v", level, d)
+		}
+		switch level {
+		case "U7":
+			s := d.Signatures
+			if d.Chip != "" || d.Version != hcs.CurrentU7Version || s == nil ||
+				s.QSig != out.QSig[:s.Lengths.QSig] || s.B3 != out.B3Sig[:s.Lengths.B3] {
+				t.Errorf("U7: unexpected signatures: %+v", d)
+			}
+		default:
+			if d.Chip != out.Chip || d.Signatures != nil {
+				t.Errorf("%s: chip = %q, signatures = %+v", level, d.Chip, d.Signatures)
+			}
+		}
+	}
+
+	d, err := hcs.Decode(out.CodeU5)
+	if err != nil {
+		t.Fatalf("U5: failed to decode %s: %v", out.CodeU5, err)
+	}
+	if d.Level != "U5" || d.Modal != nil || d.Fusion == nil || d.Fusion.Western == "" || d.Lineage != lineage {
+		t.Errorf("U5: unexpected decoded code: %+v", d)
+	}
+
+	for _, code := range []string{"", "HCS-U6|x", "HCS-U3|E:A", "HCS-U4|!!", "HCS-U7|V:7.0|ALG:QS"} {
+		if _, err := hcs.Decode(code); err == nil {
+			t.Errorf("decoding %q should fail", code)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+func TestDiffCodeSegments(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []string
+	}{
+		{"identical", "HCS-U3|E:A|CHIP:1", "HCS-U3|E:A|CHIP:1", nil},
+		{"named segments", "HCS-U3|E:A|MOD:c1|CHIP:1", "HCS-U3|E:F|MOD:c1|CHIP:2", []string{"E", "CHIP"}},
+		{"positional segment", "HCS-U5|A1|W:0000", "HCS-U5|B2|W:0000", []string{"#1"}},
+		{"different level", "HCS-U3|E:A", "HCS-U5|E:A", []string{"LEVEL"}},
+		{"extra segment", "HCS-U7|E:A", "HCS-U7|E:A|KID:01", []string{"KID"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hcs.DiffCodeSegments(tt.old, tt.new); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffCodeSegments() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
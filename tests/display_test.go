@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestDisplayCodes verifies the time-window behavior of display codes.
+func TestDisplayCodes(t *testing.T) {
+	setTestSecretKey(t)
+	frozen := clock.NewFrozen(time.Date(2024, 5, 1, 9, 0, 10, 0, time.UTC))
+	gen, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()), hcs.WithClock(frozen))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	out, err := gen.Generate(getTestInput())
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if err := gen.VerifyCHIP(out.CodeU3, nil); err != nil {
+		t.Fatalf("generated code should verify: %v", err)
+	}
+
+	code, err := gen.DisplayCode(out.Chip, 0)
+	if err != nil {
+		t.Fatalf("DisplayCode failed: %v", err)
+	}
+	if len(code.Code) != hcs.DefaultDisplayCodeDigits {
+		t.Errorf("display code %q should have %d digits", code.Code, hcs.DefaultDisplayCodeDigits)
+	}
+	if !code.ValidUntil.Equal(time.Date(2024, 5, 1, 9, 0, 30, 0, time.UTC)) {
+		t.Errorf("ValidUntil = %s, want the end of the 30s window", code.ValidUntil)
+	}
+
+	frozen.Advance(5 * time.Second)
+	again, _ := gen.DisplayCode(out.Chip, 0)
+	if again.Code != code.Code {
+		t.Errorf("display code changed within its window: %s != %s", again.Code, code.Code)
+	}
+
+	frozen.Advance(30 * time.Second) // next window: still accepted
+	if ok, err := gen.VerifyDisplayCode(out.Chip, code.Code); err != nil || !ok {
+		t.Errorf("display code should be accepted one window later: %v, %v", ok, err)
+	}
+	if ok, _ := gen.VerifyDisplayCode("000000000000", code.Code); ok {
+		t.Error("display code should not verify for another CHIP")
+	}
+
+	frozen.Advance(60 * time.Second)
+	if ok, _ := gen.VerifyDisplayCode(out.Chip, code.Code); ok {
+		t.Error("display code should expire after the accepted skew")
+	}
+
+	long, err := gen.DisplayCode(out.Chip, 8)
+	if err != nil || len(long.Code) != 8 {
+		t.Errorf("8-digit display code = %+v, %v", long, err)
+	}
+	if _, err := gen.DisplayCode(out.Chip, 5); err == nil {
+		t.Error("expected error for 5 digits")
+	}
+}
@@ -0,0 +1,39 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+func TestCheckProfileDrift(t *testing.T) {
+	cfg := hcs.DefaultDriftConfig()
+	prev := getTestInput()
+
+	if w := hcs.CheckProfileDrift(prev, getTestInput(), cfg); len(w) != 0 {
+		t.Errorf("identical profiles should not drift, got %+v", w)
+	}
+
+	small := getTestInput()
+	small.Cognition.Fluid += 0.40 // exactly at the threshold
+	if w := hcs.CheckProfileDrift(prev, small, cfg); len(w) != 0 {
+		t.Errorf("shift equal to threshold should be accepted, got %+v", w)
+	}
+
+	jump := getTestInput()
+	jump.DominantElement = "Fire"
+	jump.Cognition.Verbal = 0.95
+	warnings := hcs.CheckProfileDrift(prev, jump, cfg)
+	if len(warnings) != 2 {
+		t.Fatalf("expected element and verbal warnings, got %+v", warnings)
+	}
+	if warnings[0].Field != "dominantElement" || warnings[1].Field != "cognition.verbal" {
+		t.Errorf("unexpected warning fields: %+v", warnings)
+	}
+
+	cfg.FlagElementChange = false
+	cfg.CognitionThreshold = 0
+	if w := hcs.CheckProfileDrift(prev, jump, cfg); len(w) != 0 {
+		t.Errorf("disabled checks should not warn, got %+v", w)
+	}
+}
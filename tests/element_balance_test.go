@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"math"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/schema"
+)
+
+// TestWesternElementBalance verifies that a supplied Western element
+// distribution drives the fusion signature and implies the dominant element.
+func TestWesternElementBalance(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	birth := &hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Hour: 14, Minute: 30, Timezone: "UTC"}
+
+	plain := getTestInput()
+	plain.BirthInfo = birth
+	plainOut, err := gen.Generate(plain)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	// A single-element balance is the dominant element alone
+	single := getTestInput()
+	single.BirthInfo = birth
+	single.DominantElement = ""
+	single.ElementBalance = map[string]float64{"Air": 1}
+	singleOut, err := gen.Generate(single)
+	if err != nil {
+		t.Fatalf("failed to generate with element balance: %v", err)
+	}
+	if singleOut.Input.DominantElement != "Air" || singleOut.Chip != plainOut.Chip {
+		t.Errorf("dominant element should be derived from the balance: %s, chip %s != %s",
+			singleOut.Input.DominantElement, singleOut.Chip, plainOut.Chip)
+	}
+	for element, v := range plainOut.CombinedProfile.Fusion.ElementSignature {
+		if math.Abs(singleOut.CombinedProfile.Fusion.ElementSignature[element]-v) > 1e-12 {
+			t.Errorf("single-element balance should match the dominant mapping for %s", element)
+		}
+	}
+
+	mixed := getTestInput()
+	mixed.BirthInfo = birth
+	mixed.ElementBalance = map[string]float64{"Air": 0.5, "Fire": 0.3, "Water": 0.2}
+	mixedOut, err := gen.Generate(mixed)
+	if err != nil {
+		t.Fatalf("failed to generate with mixed balance: %v", err)
+	}
+	if mixedOut.Chip != plainOut.Chip || mixedOut.CodeU3 != plainOut.CodeU3 {
+		t.Error("legacy codes should only depend on the dominant element")
+	}
+	sig, plainSig := mixedOut.CombinedProfile.Fusion.ElementSignature, plainOut.CombinedProfile.Fusion.ElementSignature
+	if sig["Fire"] <= plainSig["Fire"] || sig["Water"] <= plainSig["Water"] || sig["Wood"] >= plainSig["Wood"] {
+		t.Errorf("secondary elements should shift the signature: %v vs %v", sig, plainSig)
+	}
+	if violations, _ := schema.ValidateOutput(mixedOut); len(violations) != 0 {
+		t.Errorf("output with element balance violates the schema: %v", violations)
+	}
+
+	for name, balance := range map[string]map[string]float64{
+		"unknown element": {"Aether": 1},
+		"negative share":  {"Air": 1, "Fire": -0.5},
+		"all zero":        {"Air": 0},
+		"disagreement":    {"Fire": 0.7, "Air": 0.3}, // input says Air
+	} {
+		input := getTestInput()
+		input.ElementBalance = balance
+		if _, err := gen.Generate(input); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestEnvelope verifies that a sealed envelope survives a JSON round trip,
+// and that any change to its output, metadata or signature is detected.
+func TestEnvelope(t *testing.T) {
+	setTestSecretKey(t)
+	frozen := clock.NewFrozen(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC))
+	gen, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()), hcs.WithClock(frozen))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	output, err := gen.GenerateWithOptions(getTestInput(), &hcs.GeneratorOptions{Quality: true, Trace: true, ValidityMonths: 12})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	env, err := gen.Seal(output, hcs.EnvelopeMetadata{Issuer: "lab-1", Labels: map[string]string{"study": "s-42"}})
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	if env.Metadata.SealedAt != "2025-03-01T12:00:00Z" || env.Signature.Algorithm != hcs.EnvelopeAlgorithm {
+		t.Errorf("unexpected envelope metadata %+v, signature %+v", env.Metadata, env.Signature)
+	}
+	if env.Signature.PQ != nil {
+		t.Error("envelope has a post-quantum signature without a PQ signer")
+	}
+
+	// reopen decodes a fresh copy of the envelope, as a recipient would
+	data, err := json.Marshal(env)
+	if err != nil {
+		t.Fatalf("failed to encode envelope: %v", err)
+	}
+	reopen := func(edit func(*hcs.Envelope)) (*hcs.OutputHCS, error) {
+		var e hcs.Envelope
+		if err := json.Unmarshal(data, &e); err != nil {
+			t.Fatalf("failed to decode envelope: %v", err)
+		}
+		edit(&e)
+		return gen.Open(&e)
+	}
+
+	opened, err := reopen(func(*hcs.Envelope) {})
+	if err != nil {
+		t.Fatalf("Open failed after a JSON round trip: %v", err)
+	}
+	if opened.Chip != output.Chip || opened.CodeU7 != output.CodeU7 {
+		t.Errorf("opened output differs: %s vs %s", opened.Chip, output.Chip)
+	}
+
+	tampered := map[string]func(*hcs.Envelope){
+		"code":      func(e *hcs.Envelope) { e.Output.CodeU3 += "0" },
+		"profile":   func(e *hcs.Envelope) { e.Output.Input.Cognition.Fluid = 0.99 },
+		"warnings":  func(e *hcs.Envelope) { e.Output.Warnings = append(e.Output.Warnings, "added") },
+		"label":     func(e *hcs.Envelope) { e.Metadata.Labels["study"] = "s-43" },
+		"sealedAt":  func(e *hcs.Envelope) { e.Metadata.SealedAt = "2030-01-01T00:00:00Z" },
+		"signature": func(e *hcs.Envelope) { e.Signature.Value = "00" + e.Signature.Value[2:] },
+	}
+	for name, edit := range tampered {
+		if _, err := reopen(edit); !errors.Is(err, hcs.ErrEnvelopeSignature) {
+			t.Errorf("tampered %s: got %v, want ErrEnvelopeSignature", name, err)
+		}
+	}
+
+	malformed := map[string]func(*hcs.Envelope){
+		"version":   func(e *hcs.Envelope) { e.Version = 2 },
+		"unsigned":  func(e *hcs.Envelope) { e.Signature = nil },
+		"empty":     func(e *hcs.Envelope) { e.Output = nil },
+		"algorithm": func(e *hcs.Envelope) { e.Signature.Algorithm = "HMAC-MD5" },
+		"epoch":     func(e *hcs.Envelope) { e.Metadata.SaltEpoch = 7 },
+	}
+	for name, edit := range malformed {
+		if _, err := reopen(edit); errcode.Of(err, "") != errcode.InvalidEnvelope {
+			t.Errorf("%s envelope: got %v, want %s", name, err, errcode.InvalidEnvelope)
+		}
+	}
+
+	// Another secret cannot open the envelope, even with the same salt
+	t.Setenv("HCS_SALT", hex.EncodeToString(gen.GetSalt()))
+	secrets, err := hcs.NewStaticSecretProvider(bytes.Repeat([]byte{0x42}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := hcs.NewGenerator(hcs.WithSaltProvider(hcs.EnvSaltProvider{}), hcs.WithSecretProvider(secrets))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	if _, err := other.Open(env); !errors.Is(err, hcs.ErrEnvelopeSignature) {
+		t.Errorf("envelope opened with another secret: %v", err)
+	}
+}
+
+// TestEnvelopePostQuantum verifies that the post-quantum signature of an
+// envelope is verifiable with the published key alone.
+func TestEnvelopePostQuantum(t *testing.T) {
+	skipInFIPSMode(t)
+	setTestSecretKey(t)
+	signer, err := hcs.NewMLDSASigner(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	gen, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()), hcs.WithPQSigner(signer))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	output, err := gen.Generate(getTestInput())
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	env, err := gen.Seal(output, hcs.EnvelopeMetadata{})
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	if env.Signature.PQ == nil {
+		t.Fatal("envelope has no post-quantum signature")
+	}
+
+	key, _ := gen.PQPublicKey()
+	if err := hcs.VerifyEnvelopePQSignature(env, key); err != nil {
+		t.Errorf("post-quantum signature should verify: %v", err)
+	}
+	if _, err := gen.Open(env); err != nil {
+		t.Errorf("Open failed: %v", err)
+	}
+
+	env.Output.Chip = "000000000000"
+	if err := hcs.VerifyEnvelopePQSignature(env, key); !errors.Is(err, hcs.ErrEnvelopeSignature) {
+		t.Errorf("tampered envelope: got %v, want ErrEnvelopeSignature", err)
+	}
+}
@@ -0,0 +1,40 @@
+package tests
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/envsubst"
+)
+
+// TestEnvExpansion verifies placeholder substitution, the $${...} escape,
+// and that every unset variable is reported at once.
+func TestEnvExpansion(t *testing.T) {
+	env := map[string]string{"BIRTH_YEAR": "1990", "TZ_NAME": "Europe/Paris", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	in := `{"birthInfo": {"year": ${BIRTH_YEAR}, "timezone": "${TZ_NAME}"}, "note": "$${BIRTH_YEAR} costs $5${EMPTY}"}`
+	want := `{"birthInfo": {"year": 1990, "timezone": "Europe/Paris"}, "note": "${BIRTH_YEAR} costs $5"}`
+	out, err := envsubst.ExpandFunc([]byte(in), lookup)
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if string(out) != want {
+		t.Errorf("got  %s\nwant %s", out, want)
+	}
+
+	_, err = envsubst.ExpandFunc([]byte(`${BIRTH_MONTH} ${BIRTH_YEAR} ${BIRTH_DAY} ${BIRTH_MONTH}`), lookup)
+	var missing *envsubst.MissingError
+	if !errors.As(err, &missing) || !slices.Equal(missing.Names, []string{"BIRTH_MONTH", "BIRTH_DAY"}) {
+		t.Errorf("expected BIRTH_MONTH and BIRTH_DAY missing, got %v", err)
+	}
+	for _, bad := range []string{`"${BIRTH_YEAR"`, `${}`, `${1X}`, `${A-B}`} {
+		if _, err := envsubst.ExpandFunc([]byte(bad), lookup); err == nil {
+			t.Errorf("expected an error for %s", bad)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestSaltEpochs verifies that codes issued before and after a salt rotation
+// both verify, each against the salt of its own epoch.
+func TestSaltEpochs(t *testing.T) {
+	setTestSecretKey(t)
+	tempDir := t.TempDir()
+	input := getTestInput()
+
+	gen0, err := hcs.NewGeneratorWithSaltDir(tempDir)
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	before, err := gen0.Generate(input)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if strings.Contains(before.CodeU3, "|EP:") || strings.Contains(before.CodeU7, "|EP:") {
+		t.Errorf("epoch 0 codes should not carry an EP segment: %s", before.CodeU3)
+	}
+
+	epoch, err := hcs.DirSaltProvider{Dir: tempDir, Secrets: hcs.NewEnvSecretProvider()}.Rotate()
+	if err != nil || epoch != 1 {
+		t.Fatalf("Rotate = %d, %v; want 1", epoch, err)
+	}
+
+	gen1, err := hcs.NewGeneratorWithSaltDir(tempDir)
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	if gen1.SaltEpoch() != 1 {
+		t.Fatalf("SaltEpoch = %d, want 1", gen1.SaltEpoch())
+	}
+	after, err := gen1.Generate(input)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if after.Chip == before.Chip {
+		t.Error("CHIP should change with the rotated salt")
+	}
+	if !strings.HasSuffix(after.CodeU3, "|EP:1") || !hcs.ValidateU3Format(after.CodeU3) {
+		t.Errorf("unexpected U3 code after rotation: %s", after.CodeU3)
+	}
+	for _, code := range []string{after.CodeU3, after.CodeU4, after.CodeU6, after.CodeU7} {
+		if got, err := hcs.ParseSaltEpoch(code); err != nil || got != 1 {
+			t.Errorf("ParseSaltEpoch(%s) = %d, %v; want 1", code, got, err)
+		}
+	}
+	if _, err := hcs.NormalizedFromCode(after.CodeU7); err != nil {
+		t.Errorf("U7 code with epoch should decode: %v", err)
+	}
+
+	for _, code := range []string{before.CodeU3, before.CodeU4, after.CodeU3, after.CodeU4} {
+		if err := gen1.VerifyCHIP(code, nil); err != nil {
+			t.Errorf("VerifyCHIP(%s): %v", code, err)
+		}
+	}
+	forged := strings.Replace(after.CodeU3, "|EP:1", "", 1)
+	if err := gen1.VerifyCHIP(forged, nil); err == nil {
+		t.Error("a CHIP should not verify against another epoch's salt")
+	}
+	if err := gen1.VerifyCHIP(after.CodeU3+"0", nil); err == nil {
+		t.Error("expected error for an unknown salt epoch")
+	}
+}
+
+// TestSaltFingerprints verifies that every epoch is fingerprinted and that
+// rotating keeps the fingerprints of earlier epochs.
+func TestSaltFingerprints(t *testing.T) {
+	dir := t.TempDir()
+	g1, err := hcs.NewGeneratorWithSaltDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := g1.SaltFingerprints()
+	if len(before) != 1 || before[0] != hcs.SaltFingerprint(g1.GetSalt()) || len(before[0]) != 16 {
+		t.Fatalf("unexpected fingerprints %v", before)
+	}
+
+	if _, err := (hcs.DirSaltProvider{Dir: dir, Secrets: hcs.NewEnvSecretProvider()}).Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	g2, err := hcs.NewGeneratorWithSaltDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := g2.SaltFingerprints()
+	if len(after) != 2 || after[0] != before[0] || after[1] == before[0] {
+		t.Errorf("rotation should add an epoch and keep epoch 0, got %v", after)
+	}
+}
+
+// TestEnvSaltProvider verifies that salts from HCS_SALT produce the same codes
+// as the same salts on disk, without writing any file.
+func TestEnvSaltProvider(t *testing.T) {
+	dir := t.TempDir()
+	provider := hcs.DirSaltProvider{Dir: dir, Secrets: hcs.NewEnvSecretProvider()}
+	if _, err := provider.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	salts, current, err := provider.SaltEpochs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HCS_SALT", hex.EncodeToString(salts[0])+", "+hex.EncodeToString(salts[1]))
+
+	fromDir, err := hcs.NewGeneratorWithSaltDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromEnv, err := hcs.NewGenerator(hcs.WithSaltProvider(hcs.EnvSaltProvider{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromEnv.SaltEpoch() != current {
+		t.Errorf("the last HCS_SALT entry should be the current epoch, got %d", fromEnv.SaltEpoch())
+	}
+	a, err := fromDir.Generate(getTestInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fromEnv.Generate(getTestInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Chip != b.Chip || a.CodeU4 != b.CodeU4 {
+		t.Errorf("salts from HCS_SALT should match the salt files: %s vs %s", a.Chip, b.Chip)
+	}
+
+	for _, value := range []string{"", "zz", hex.EncodeToString(salts[0][:16])} {
+		t.Setenv("HCS_SALT", value)
+		if _, err := (hcs.EnvSaltProvider{}).Salt(); err == nil {
+			t.Errorf("HCS_SALT %q should be rejected", value)
+		}
+	}
+}
+
+// TestConcurrentSaltCreation verifies that concurrent first runs in an empty
+// directory converge on one salt and one seal.
+func TestConcurrentSaltCreation(t *testing.T) {
+	dir := t.TempDir()
+	secrets, err := hcs.NewStaticSecretProvider(bytes.Repeat([]byte{0x42}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const runs = 16
+	salts := make([][]byte, runs)
+	errs := make([]error, runs)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			salts[i], errs[i] = hcs.DirSaltProvider{Dir: dir, Secrets: secrets}.Salt()
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	for i := range salts {
+		if errs[i] != nil {
+			t.Fatalf("run %d failed: %v", i, errs[i])
+		}
+		if !bytes.Equal(salts[i], salts[0]) {
+			t.Fatalf("run %d got a different salt", i)
+		}
+	}
+	files, err := hcs.SaltFiles(dir)
+	if err != nil || strings.Join(files, ",") != ".hcs_salt,.hcs_salt.mac" {
+		t.Errorf("unexpected salt files: %v, %v", files, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != len(files) {
+		t.Errorf("temporary files left behind: %d entries", len(entries))
+	}
+	if salt, err := hcs.LoadOrCreateSalt(dir); err != nil || !bytes.Equal(salt, salts[0]) {
+		t.Errorf("reloading returned a different salt: %v", err)
+	}
+}
+
+// TestCopySaltFiles verifies that copying a salt directory keeps every epoch,
+// its seals and the CHIPs issued under it.
+func TestCopySaltFiles(t *testing.T) {
+	setTestSecretKey(t)
+	src, dst := t.TempDir(), t.TempDir()
+	if _, err := (hcs.DirSaltProvider{Dir: src, Secrets: hcs.NewEnvSecretProvider()}).Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	before, err := hcs.NewGeneratorWithSaltDir(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copied, err := hcs.CopySaltFiles(src, dst)
+	if err != nil || strings.Join(copied, ",") != ".hcs_salt,.hcs_salt.mac,.hcs_salt.1,.hcs_salt.1.mac" {
+		t.Fatalf("unexpected copied files: %v, %v", copied, err)
+	}
+	after, err := hcs.NewGeneratorWithSaltDir(dst)
+	if err != nil {
+		t.Fatalf("copied salts do not load: %v", err)
+	}
+	a, _ := before.Generate(getTestInput())
+	b, _ := after.Generate(getTestInput())
+	if a == nil || b == nil || a.Chip != b.Chip {
+		t.Error("copied salts should keep the CHIP")
+	}
+
+	if _, err := hcs.CopySaltFiles(src, dst); err == nil {
+		t.Error("copying into a directory with a salt should fail")
+	}
+	if copied, err := hcs.CopySaltFiles(t.TempDir(), t.TempDir()); err != nil || len(copied) != 0 {
+		t.Errorf("copying an empty directory should copy nothing, got %v, %v", copied, err)
+	}
+}
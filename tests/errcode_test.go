@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestErrorCatalog verifies that codes are unique, well-formed and sorted.
+func TestErrorCatalog(t *testing.T) {
+	format := regexp.MustCompile(`^HCS-\d{4}$`)
+	catalog := errcode.Catalog()
+	for i, e := range catalog {
+		if !format.MatchString(string(e.Code)) || http.StatusText(e.Status) == "" || e.Title == "" || e.Description == "" {
+			t.Errorf("malformed catalog entry: %+v", e)
+		}
+		if i > 0 && catalog[i-1].Code >= e.Code {
+			t.Errorf("codes not in ascending order: %s before %s", catalog[i-1].Code, e.Code)
+		}
+		if got := errcode.Lookup(e.Code); got != e {
+			t.Errorf("lookup of %s returned %+v", e.Code, got)
+		}
+	}
+	if got := errcode.Lookup("HCS-0000"); got.Code != errcode.Internal {
+		t.Errorf("unknown codes should fall back to %s, got %s", errcode.Internal, got.Code)
+	}
+}
+
+// TestErrorCodes verifies that generation errors carry their catalog code.
+func TestErrorCodes(t *testing.T) {
+	element := getTestInput()
+	element.DominantElement = "Metal"
+	modal := getTestInput()
+	modal.Modal.Fixed = 1.5
+	cognition := getTestInput()
+	cognition.Cognition.Verbal = -0.1
+	interaction := getTestInput()
+	interaction.Interaction.Pace = "frantic"
+
+	for _, tc := range []struct {
+		input *hcs.InputProfile
+		want  errcode.Code
+	}{
+		{element, errcode.InvalidElement},
+		{modal, errcode.InvalidModal},
+		{cognition, errcode.InvalidCognition},
+		{interaction, errcode.InvalidInteraction},
+	} {
+		err := hcs.ValidateInput(tc.input)
+		if got := errcode.Of(err, errcode.Internal); got != tc.want {
+			t.Errorf("expected %s, got %s (%v)", tc.want, got, err)
+		}
+	}
+
+	t.Setenv("HCS_SECRET_KEY", "")
+	t.Setenv("HCS_SECRET_KEY_FILE", "")
+	gen, err := hcs.NewGeneratorWithSecrets(t.TempDir(), hcs.NewEnvSecretProvider())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = gen.Generate(getTestInput())
+	if got := errcode.Of(err, errcode.Internal); got != errcode.MissingSecret {
+		t.Errorf("expected %s without a secret key, got %s (%v)", errcode.MissingSecret, got, err)
+	}
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+func TestComputeValidUntil(t *testing.T) {
+	issued := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	if _, ok := hcs.ComputeValidUntil(issued, 0); ok {
+		t.Errorf("zero months should never expire")
+	}
+
+	validUntil, ok := hcs.ComputeValidUntil(issued, 6)
+	if !ok {
+		t.Fatalf("expected an expiry for 6 months")
+	}
+	if !validUntil.After(issued.AddDate(0, 5, 28)) {
+		t.Errorf("validUntil too early: %s", validUntil)
+	}
+}
+
+func TestIsStaleAndExpiresWithin(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	meta := &hcs.OutputMetadata{ValidUntil: now.Add(10 * 24 * time.Hour).Format(time.RFC3339)}
+
+	if hcs.IsStale(meta, now) {
+		t.Errorf("code should not be stale before validUntil")
+	}
+	if !hcs.IsStale(meta, now.Add(11*24*time.Hour)) {
+		t.Errorf("code should be stale after validUntil")
+	}
+	if !hcs.ExpiresWithin(meta, now, 30*24*time.Hour) {
+		t.Errorf("code should expire within 30 days")
+	}
+	if hcs.ExpiresWithin(meta, now, 5*24*time.Hour) {
+		t.Errorf("code should not expire within 5 days")
+	}
+	if hcs.IsStale(nil, now) || hcs.ExpiresWithin(&hcs.OutputMetadata{}, now, time.Hour) {
+		t.Errorf("codes without validUntil never expire")
+	}
+}
+
+func TestGeneratorValidityMetadata(t *testing.T) {
+	setTestSecretKey(t)
+
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+
+	out, err := gen.GenerateWithOptions(getTestInput(), &hcs.GeneratorOptions{ValidityMonths: 12})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if out.Metadata == nil || out.Metadata.ValidUntil == "" {
+		t.Fatalf("expected validUntil metadata, got %+v", out.Metadata)
+	}
+
+	plain, err := gen.Generate(getTestInput())
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if plain.Metadata != nil {
+		t.Errorf("metadata should be omitted without a validity period")
+	}
+	if plain.CodeU7 != out.CodeU7 {
+		t.Errorf("validity metadata must not change the codes")
+	}
+}
+
+func TestExpiringRecords(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	s := store.NewMemoryStore()
+
+	records := []store.Record{
+		{Chip: "000000000001", CreatedAt: now, ValidUntil: now.Add(24 * time.Hour)},
+		{Chip: "000000000002", CreatedAt: now, ValidUntil: now.Add(90 * 24 * time.Hour)},
+		{Chip: "000000000003", CreatedAt: now},
+		{Chip: "000000000004", CreatedAt: now, ValidUntil: now.Add(time.Hour), ReminderSentAt: now},
+	}
+	for _, rec := range records {
+		if err := s.Save(ctx, rec); err != nil {
+			t.Fatalf("failed to save record: %v", err)
+		}
+	}
+
+	due, err := store.ExpiringRecords(ctx, s, now, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("ExpiringRecords failed: %v", err)
+	}
+	if len(due) != 1 || due[0].Chip != "000000000001" {
+		t.Errorf("expected only the first record to be due, got %+v", due)
+	}
+}
@@ -0,0 +1,412 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/export"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/parquet"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+// TestParquetExport verifies the export rows and decodes the Parquet file
+// back to check the value of every column.
+func TestParquetExport(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	in := getTestInput()
+	out, err := gen.Generate(in)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	rec := store.NewRecord(*in, out, time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	rec.SubjectID = "s-1"
+
+	row := export.Row(rec)
+	if len(row) != len(export.Columns) {
+		t.Fatalf("row has %d values for %d columns", len(row), len(export.Columns))
+	}
+	byName := map[string]any{}
+	for i, c := range export.Columns {
+		byName[c.Name] = row[i]
+	}
+	if byName["chip"] != out.Chip || byName["subject_id"] != "s-1" || byName["tenant_id"] != nil {
+		t.Errorf("unexpected identifiers: %v %v %v", byName["chip"], byName["subject_id"], byName["tenant_id"])
+	}
+	if byName["element"] != "A" || byName["cog_verbal"] != 53 || byName["tone"] != "P" {
+		t.Errorf("profile columns should hold normalized values, got %v %v %v", byName["element"], byName["cog_verbal"], byName["tone"])
+	}
+	if byName["fusion_id"] != nil || byName["quality_score"] != nil {
+		t.Error("missing derived metrics should be null")
+	}
+
+	// A second record fills the columns the first leaves null
+	born := getTestInput()
+	born.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 5, Day: 1, Hour: 12, Timezone: "UTC"}
+	bornOut, err := gen.Generate(born)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	full := store.NewRecord(*born, bornOut, time.Date(2024, 2, 1, 8, 30, 0, 0, time.UTC))
+	full.TenantID = "t-1"
+	full.ValidUntil = time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	cluster := 3
+	full.ClusterID = &cluster
+
+	records := []store.Record{rec, full}
+	var buf bytes.Buffer
+	if err := export.WriteParquet(&buf, records); err != nil {
+		t.Fatalf("WriteParquet failed: %v", err)
+	}
+	names, rows := readParquet(t, buf.Bytes())
+	if len(names) != len(export.Columns) || len(rows) != len(records) {
+		t.Fatalf("read %d columns and %d rows, want %d and %d", len(names), len(rows), len(export.Columns), len(records))
+	}
+	for i, c := range export.Columns {
+		if names[i] != c.Name {
+			t.Errorf("column %d is %s, want %s", i, names[i], c.Name)
+		}
+	}
+	for r, record := range records {
+		want := export.Row(record)
+		for i, c := range export.Columns {
+			if got := rows[r][i]; !reflect.DeepEqual(got, parquetValue(want[i])) {
+				t.Errorf("row %d column %s = %#v, want %#v", r, c.Name, got, want[i])
+			}
+		}
+	}
+	seen := map[parquet.Type]bool{}
+	for _, row := range rows {
+		for i, c := range export.Columns {
+			seen[c.Type] = seen[c.Type] || row[i] != nil
+		}
+	}
+	for _, typ := range []parquet.Type{parquet.Int64, parquet.Double, parquet.String, parquet.Timestamp} {
+		if !seen[typ] {
+			t.Errorf("the export should decode %s values", typ)
+		}
+	}
+
+	// Every type, with nulls between values and more booleans than one byte holds
+	columns := []parquet.Column{
+		{Name: "flag", Type: parquet.Bool},
+		{Name: "n", Type: parquet.Int64, Optional: true},
+		{Name: "x", Type: parquet.Double, Optional: true},
+		{Name: "s", Type: parquet.String, Optional: true},
+		{Name: "at", Type: parquet.Timestamp, Optional: true},
+	}
+	var table [][]any
+	for i := 0; i < 11; i++ {
+		row := []any{i%3 == 0, nil, nil, nil, nil}
+		if i%2 == 0 {
+			row[1], row[2], row[3], row[4] = int64(-i), float64(i)/4, fmt.Sprintf("é%d", i), time.UnixMilli(1700000000000+int64(i)).UTC()
+		}
+		table = append(table, row)
+	}
+	buf.Reset()
+	pw := parquet.NewWriter(&buf, columns)
+	for _, row := range table {
+		if err := pw.Write(append([]any(nil), row...)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, got := readParquet(t, buf.Bytes()); !reflect.DeepEqual(got, table) {
+		t.Errorf("decoded rows differ:\n got %v\nwant %v", got, table)
+	}
+
+	w := parquet.NewWriter(&bytes.Buffer{}, []parquet.Column{{Name: "a", Type: parquet.Int64}, {Name: "b", Type: parquet.String, Optional: true}})
+	if err := w.Write([]any{nil, "x"}); err == nil {
+		t.Error("a nil required value should be rejected")
+	}
+	if err := w.Write([]any{1.5, nil}); err == nil {
+		t.Error("a value of the wrong type should be rejected")
+	}
+	if err := w.Write([]any{1, nil}); err != nil {
+		t.Errorf("an int for an Int64 column and a nil optional value should be accepted: %v", err)
+	}
+}
+
+// TestAnonymizedDataset verifies sampling, identifier removal and noise of public datasets.
+func TestAnonymizedDataset(t *testing.T) {
+	var records []store.Record
+	for i, subject := range []string{"a", "b", "a", "", ""} {
+		in := getTestInput()
+		in.Cognition.Verbal = 0.1 * float64(i+1)
+		rec := store.NewRecord(*in, &hcs.OutputHCS{Chip: fmt.Sprintf("chip%d", i)}, time.Now())
+		rec.SubjectID = subject
+		records = append(records, rec)
+	}
+
+	rows, p, err := export.Anonymize(records, export.AnonymizeOptions{Seed: 42})
+	if err != nil {
+		t.Fatalf("Anonymize failed: %v", err)
+	}
+	if len(rows) != 4 || p.Subjects != 4 || p.SourceRecords != 5 || p.NoiseStdDev != export.DefaultNoise {
+		t.Fatalf("expected one row per subject and per anonymous record, got %d rows, %+v", len(rows), p)
+	}
+	for _, row := range rows {
+		if len(row) != len(export.PublicColumns) {
+			t.Fatalf("row has %d values for %d columns", len(row), len(export.PublicColumns))
+		}
+		for _, v := range row[1:9] {
+			if n := v.(int); n < 0 || n > 100 {
+				t.Errorf("noisy value %d is out of range", n)
+			}
+		}
+	}
+
+	again, _, _ := export.Anonymize(records, export.AnonymizeOptions{Seed: 42})
+	if !reflect.DeepEqual(rows, again) {
+		t.Error("the same seed should give the same dataset")
+	}
+	other, _, _ := export.Anonymize(records, export.AnonymizeOptions{Seed: 43, Noise: 20})
+	if reflect.DeepEqual(rows, other) {
+		t.Error("a different seed and noise should perturb differently")
+	}
+
+	sampled, p, err := export.Anonymize(records, export.AnonymizeOptions{Seed: 42, Sample: 2})
+	if err != nil || len(sampled) != 2 || p.Rows != 2 {
+		t.Errorf("sample should cap the rows, got %d rows, %v", len(sampled), err)
+	}
+	if _, _, err := export.Anonymize(records, export.AnonymizeOptions{Noise: -1}); err == nil {
+		t.Error("negative noise should be rejected")
+	}
+
+	var doc bytes.Buffer
+	if err := export.WriteDatasetDoc(&doc, p); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(doc.Bytes(), []byte("standard deviation 3 percentage points")) || !bytes.Contains(doc.Bytes(), []byte("| cog_verbal | int64 | false |")) {
+		t.Errorf("documentation is missing the perturbation or columns:\n%s", doc.String())
+	}
+	var buf bytes.Buffer
+	if err := export.WritePublicParquet(&buf, rows); err != nil {
+		t.Errorf("WritePublicParquet failed: %v", err)
+	}
+}
+
+// readParquet decodes a Parquet file written by parquet.Writer independently
+// of the writer: it reads the schema and row group from the footer, then the
+// definition levels and PLAIN values of each column chunk. It returns the
+// column names and the rows, with nil for nulls.
+func readParquet(t *testing.T, data []byte) ([]string, [][]any) {
+	t.Helper()
+	if len(data) < 12 || !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+		t.Fatal("missing Parquet magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if footerLen <= 0 || footerLen > len(data)-12 {
+		t.Fatalf("invalid footer length %d", footerLen)
+	}
+	footer := &thriftReader{data: data[len(data)-8-footerLen : len(data)-8]}
+	meta := footer.structValue()
+	if footer.err != nil {
+		t.Fatalf("invalid footer: %v", footer.err)
+	}
+
+	schema := meta[2].([]any)[1:] // the first element is the root
+	numRows := int(meta[3].(int64))
+	chunks := meta[4].([]any)[0].(map[int16]any)[1].([]any)
+	if len(chunks) != len(schema) {
+		t.Fatalf("row group has %d column chunks for %d columns", len(chunks), len(schema))
+	}
+	rows := make([][]any, numRows)
+	for r := range rows {
+		rows[r] = make([]any, len(schema))
+	}
+
+	var names []string
+	for i, element := range schema {
+		e := element.(map[int16]any)
+		name := string(e[4].([]byte))
+		names = append(names, name)
+		physical, optional := e[1].(int64), e[3].(int64) == 1
+		converted, hasConverted := e[6].(int64)
+
+		offset := int(chunks[i].(map[int16]any)[3].(map[int16]any)[9].(int64))
+		pageReader := &thriftReader{data: data[offset:]}
+		header := pageReader.structValue()
+		start := offset + pageReader.pos
+		page := data[start : start+int(header[3].(int64))]
+		if n := int(header[5].(map[int16]any)[1].(int64)); n != numRows {
+			t.Fatalf("column %s has %d values for %d rows", name, n, numRows)
+		}
+
+		present := make([]bool, numRows)
+		for r := range present {
+			present[r] = true
+		}
+		if optional {
+			n := int(binary.LittleEndian.Uint32(page))
+			present = decodeDefinitionLevels(page[4:4+n], numRows)
+			page = page[4+n:]
+		}
+
+		bit := 0
+		for r := range rows {
+			if !present[r] {
+				continue
+			}
+			var v any
+			switch physical {
+			case 0: // BOOLEAN, bit-packed
+				v = page[bit/8]>>(bit%8)&1 == 1
+				bit++
+			case 2: // INT64
+				n := int64(binary.LittleEndian.Uint64(page))
+				page = page[8:]
+				v = n
+				if hasConverted && converted == 9 { // TIMESTAMP_MILLIS
+					v = time.UnixMilli(n).UTC()
+				}
+			case 5: // DOUBLE
+				v = math.Float64frombits(binary.LittleEndian.Uint64(page))
+				page = page[8:]
+			case 6: // BYTE_ARRAY
+				n := int(binary.LittleEndian.Uint32(page))
+				v = string(page[4 : 4+n])
+				page = page[4+n:]
+			default:
+				t.Fatalf("column %s has unexpected physical type %d", name, physical)
+			}
+			rows[r][i] = v
+		}
+	}
+	return names, rows
+}
+
+// decodeDefinitionLevels decodes n definition levels of bit width 1 from the
+// RLE/bit-packing hybrid encoding
+func decodeDefinitionLevels(data []byte, n int) []bool {
+	var levels []bool
+	for len(levels) < n && len(data) > 0 {
+		header, k := binary.Uvarint(data)
+		data = data[k:]
+		if header&1 == 0 { // RLE run
+			for j := uint64(0); j < header>>1; j++ {
+				levels = append(levels, data[0] == 1)
+			}
+			data = data[1:]
+			continue
+		}
+		groups := int(header >> 1) // bit-packed groups of 8
+		for j := 0; j < groups*8; j++ {
+			levels = append(levels, data[j/8]>>(j%8)&1 == 1)
+		}
+		data = data[groups:]
+	}
+	return levels[:n]
+}
+
+// parquetValue is v as readParquet decodes it
+func parquetValue(v any) any {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case time.Time:
+		return time.UnixMilli(v.UnixMilli()).UTC()
+	}
+	return v
+}
+
+// thriftReader decodes Thrift compact protocol structs generically: fields
+// map to their ID, integers to int64, binaries to []byte and lists to []any
+type thriftReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *thriftReader) next(n int) []byte {
+	if r.err != nil || r.pos+n > len(r.data) {
+		r.err = io.ErrUnexpectedEOF
+		return make([]byte, n)
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	var v uint64
+	for shift := 0; shift < 64; shift += 7 {
+		b := r.next(1)[0]
+		v |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) structValue() map[int16]any {
+	fields := map[int16]any{}
+	var id int16
+	for r.err == nil {
+		b := r.next(1)[0]
+		if b == 0 { // stop
+			break
+		}
+		typ := b & 0x0f
+		if delta := int16(b >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.zigzag())
+		}
+		switch typ {
+		case 1, 2: // booleans carry their value in the type
+			fields[id] = typ == 1
+		default:
+			fields[id] = r.value(typ)
+		}
+	}
+	return fields
+}
+
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case 1, 2: // list element booleans take a byte
+		return r.next(1)[0] == 1
+	case 3:
+		return int64(int8(r.next(1)[0]))
+	case 4, 5, 6:
+		return r.zigzag()
+	case 7:
+		return math.Float64frombits(binary.LittleEndian.Uint64(r.next(8)))
+	case 8:
+		return append([]byte(nil), r.next(int(r.uvarint()))...)
+	case 9, 10:
+		header := r.next(1)[0]
+		n, elemType := int(header>>4), header&0x0f
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		var list []any
+		for i := 0; i < n && r.err == nil; i++ {
+			list = append(list, r.value(elemType))
+		}
+		return list
+	case 12:
+		return r.structValue()
+	}
+	r.err = fmt.Errorf("unsupported thrift type %d", typ)
+	return nil
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/features"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+func TestFeatureFlagPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "features.json")
+	config := `{"flags": {"u5": false, "webhooks": true}, "tenants": {"acme": {"u5": true, "u7": false}}}`
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	t.Setenv("HCS_FEATURE_WEBHOOKS", "off")
+
+	flags, err := features.Load(path)
+	if err != nil {
+		t.Fatalf("failed to load flags: %v", err)
+	}
+
+	if flags.Enabled(features.U5, "") {
+		t.Errorf("file should disable u5 globally")
+	}
+	if !flags.Enabled(features.U5, "acme") || flags.Enabled(features.U7, "acme") {
+		t.Errorf("tenant overrides not applied")
+	}
+	if flags.Enabled(features.Webhooks, "") {
+		t.Errorf("environment should override the file")
+	}
+	if !flags.Enabled(features.Storage, "other") {
+		t.Errorf("unset flags should keep their defaults")
+	}
+	if flags.Enabled("unknown", "") {
+		t.Errorf("unknown flags should be disabled")
+	}
+	if len(flags.Snapshot()) != len(features.Names()) {
+		t.Errorf("snapshot should report every flag")
+	}
+}
+
+func TestFeatureFlagValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "features.json")
+	if err := os.WriteFile(path, []byte(`{"flags": {"teleport": true}}`), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if _, err := features.Load(path); err == nil {
+		t.Errorf("unknown flag in file should be rejected")
+	}
+
+	t.Setenv("HCS_FEATURE_U7", "maybe")
+	if _, err := features.Load(""); err == nil {
+		t.Errorf("invalid env value should be rejected")
+	}
+}
+
+func TestGeneratorSkipModules(t *testing.T) {
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	input := getTestInput()
+	input.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Hour: 14, Minute: 30, Timezone: "UTC"}
+
+	out, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{SkipU5: true, SkipU7: true})
+	if err != nil {
+		t.Fatalf("generation without U7 should not need a secret: %v", err)
+	}
+	if out.CodeU5 != "" || out.CodeU7 != "" || out.QSig != "" {
+		t.Errorf("skipped modules should produce no codes: %+v", out)
+	}
+	if out.CodeU3 == "" || out.ChineseProfile == nil {
+		t.Errorf("core outputs should still be produced")
+	}
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/saltbackup"
+)
+
+// skipInFIPSMode skips tests of algorithms that FIPS builds refuse
+func skipInFIPSMode(t *testing.T) {
+	t.Helper()
+	if hcs.FIPSMode() {
+		t.Skipf("FIPS build (%s)", hcs.FIPSModule())
+	}
+}
+
+// TestFIPSMode verifies that FIPS builds (-tags hcs_fips, or
+// GOEXPERIMENT=boringcrypto) refuse every non-approved algorithm, and that
+// other builds restrict nothing.
+func TestFIPSMode(t *testing.T) {
+	pqSeed := make([]byte, 32)
+	hardening := hcs.CHIPHardening{Algorithm: hcs.CHIPAlgorithmArgon2id, Time: 1, MemoryKiB: 64, Threads: 1}
+
+	if !hcs.FIPSMode() {
+		if hcs.FIPSModule() != "" {
+			t.Errorf("non-FIPS build reports module %q", hcs.FIPSModule())
+		}
+		if _, err := hcs.NewMLDSASigner(pqSeed); err != nil {
+			t.Errorf("ML-DSA signer refused outside FIPS mode: %v", err)
+		}
+		if _, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()), hcs.WithHardenedCHIP(hardening)); err != nil {
+			t.Errorf("CHIP hardening refused outside FIPS mode: %v", err)
+		}
+		return
+	}
+
+	if hcs.FIPSModule() == "" {
+		t.Error("FIPS build reports no module")
+	}
+	for _, name := range []string{hcs.HashSHA256, hcs.HashSHA3256, hcs.HashSHA3512} {
+		if _, err := hcs.LookupHash(name); err != nil {
+			t.Errorf("approved hash %s refused: %v", name, err)
+		}
+	}
+	if _, err := hcs.LookupHash(hcs.HashBLAKE3); err == nil {
+		t.Error("BLAKE3 available in FIPS mode")
+	}
+	for _, name := range hcs.HashNames() {
+		if name == hcs.HashBLAKE3 {
+			t.Error("HashNames lists BLAKE3 in FIPS mode")
+		}
+	}
+	if got := hcs.DefaultSecondaryDigest(); got != hcs.DigestSHA3 {
+		t.Errorf("default secondary digest: got %s, want %s", got, hcs.DigestSHA3)
+	}
+	if _, err := hcs.ResolveSecondaryDigest(hcs.DigestBLAKE3); err == nil {
+		t.Error("BLAKE3 secondary digest accepted in FIPS mode")
+	}
+	if _, err := hcs.NewMLDSASigner(pqSeed); err == nil {
+		t.Error("ML-DSA signer accepted in FIPS mode")
+	}
+	if _, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()), hcs.WithHardenedCHIP(hardening)); err == nil {
+		t.Error("Argon2id CHIP hardening accepted in FIPS mode")
+	}
+	if _, err := saltbackup.Open(strings.NewReader("")); err == nil || !strings.Contains(err.Error(), "FIPS") {
+		t.Errorf("salt backup not refused in FIPS mode: %v", err)
+	}
+
+	// Generation works with approved algorithms only, and fails fast otherwise
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	output, err := gen.GenerateWithOptions(getTestInput(), &hcs.GeneratorOptions{})
+	if err != nil {
+		t.Fatalf("failed to generate in FIPS mode: %v", err)
+	}
+	if digest, _ := hcs.ParseSecondaryDigest(output.CodeU7); digest != hcs.DigestSHA3 {
+		t.Errorf("FIPS code declares secondary digest %s, want %s", digest, hcs.DigestSHA3)
+	}
+	if _, err := gen.GenerateWithOptions(getTestInput(), &hcs.GeneratorOptions{SecondaryDigest: hcs.DigestBLAKE3}); err == nil {
+		t.Error("generation with BLAKE3 succeeded in FIPS mode")
+	}
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+func TestFusionConfigRegistry(t *testing.T) {
+	if _, err := hcs.LookupFusionConfig(""); err != nil {
+		t.Fatalf("empty ID should select the default config: %v", err)
+	}
+	if err := hcs.RegisterFusionConfig(hcs.DefaultFusionConfig()); err == nil {
+		t.Errorf("default config must not be replaceable")
+	}
+
+	bad := hcs.DefaultFusionConfig()
+	bad.ID = "bad"
+	bad.TempoWesternWeight = 1.5
+	if err := hcs.RegisterFusionConfig(bad); err == nil {
+		t.Errorf("out-of-range weight should be rejected")
+	}
+	if _, err := hcs.LookupFusionConfig("does-not-exist"); err == nil {
+		t.Errorf("unknown config should be rejected")
+	}
+
+	registered := hcs.DefaultFusionConfig()
+	registered.ID = "registry-reload"
+	registered.TempoWesternWeight = 0.3
+	if err := hcs.RegisterFusionConfig(registered); err != nil {
+		t.Fatalf("failed to register config: %v", err)
+	}
+	if err := hcs.RegisterFusionConfig(registered); err != nil {
+		t.Errorf("registering the same config again should succeed: %v", err)
+	}
+	changed := registered
+	changed.TempoWesternWeight = 0.9
+	if err := hcs.CheckFusionConfig(changed); err == nil {
+		t.Errorf("check should reject new weights for a registered ID")
+	}
+	if err := hcs.RegisterFusionConfig(changed); err == nil {
+		t.Errorf("new weights for a registered ID should be rejected")
+	}
+	if got, _ := hcs.LookupFusionConfig(registered.ID); got != registered {
+		t.Errorf("a rejected config must not replace the registered one, got %+v", got)
+	}
+}
+
+func TestFusionConfigExperiment(t *testing.T) {
+	setTestSecretKey(t)
+
+	exp := hcs.DefaultFusionConfig()
+	exp.ID = "western-heavy"
+	exp.ElementWesternWeight = 0.7
+	exp.CognitiveWesternWeight = 0.8
+	if err := hcs.RegisterFusionConfig(exp); err != nil {
+		t.Fatalf("failed to register config: %v", err)
+	}
+
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	input := getTestInput()
+	input.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Hour: 14, Minute: 30, Timezone: "UTC"}
+
+	base, err := gen.Generate(input)
+	if err != nil {
+		t.Fatalf("failed to generate baseline: %v", err)
+	}
+	explicitDefault, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{FusionConfigID: hcs.DefaultFusionConfigID})
+	if err != nil {
+		t.Fatalf("failed to generate with default config: %v", err)
+	}
+	if base.CodeU7 != explicitDefault.CodeU7 {
+		t.Errorf("selecting the default config must not change codes")
+	}
+
+	out1, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{FusionConfigID: exp.ID})
+	if err != nil {
+		t.Fatalf("failed to generate with experiment: %v", err)
+	}
+	out2, _ := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{FusionConfigID: exp.ID})
+	if out1.CodeU7 != out2.CodeU7 {
+		t.Errorf("experimental config must stay deterministic")
+	}
+	if out1.Metadata == nil || out1.Metadata.FusionConfig != exp.ID {
+		t.Errorf("config ID should be recorded in metadata, got %+v", out1.Metadata)
+	}
+	if reflect.DeepEqual(out1.CombinedProfile.Fusion, base.CombinedProfile.Fusion) {
+		t.Errorf("experimental weights should change the fusion profile")
+	}
+	if out1.QSig == base.QSig {
+		t.Errorf("experimental fusion must be bound into the U7 signature")
+	}
+
+	if _, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{FusionConfigID: "missing"}); err == nil {
+		t.Errorf("unknown config should fail generation")
+	}
+}
+
+// TestLoadFusionConfigsAllOrNothing verifies that a file with an invalid entry registers none of its configs.
+func TestLoadFusionConfigsAllOrNothing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fusion.json")
+	data := `[{"id":"staged-ok","elementWesternWeight":0.5},{"id":"staged-bad","tempoWesternWeight":2}]`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := hcs.ReadFusionConfigs(path); err == nil {
+		t.Fatal("an invalid weight should fail the whole file")
+	}
+	if err := hcs.LoadFusionConfigs(path); err == nil {
+		t.Fatal("LoadFusionConfigs should reject the file")
+	}
+	if _, err := hcs.LookupFusionConfig("staged-ok"); err == nil {
+		t.Error("no config of a rejected file may be registered")
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"id":"staged-ok","elementWesternWeight":0.5}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configs, err := hcs.ReadFusionConfigs(path)
+	if err != nil || len(configs) != 1 {
+		t.Fatalf("ReadFusionConfigs = %v, %v", configs, err)
+	}
+	if _, err := hcs.LookupFusionConfig("staged-ok"); err == nil {
+		t.Error("ReadFusionConfigs must not register anything")
+	}
+}
@@ -1,10 +1,14 @@
 package tests
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
 	"github.com/corehuman/hcs-lab-api/internal/hcs"
 )
 
@@ -124,6 +128,110 @@ func TestSaltPersistence(t *testing.T) {
 	t.Logf("New CHIP after salt regeneration: %s", output2.Chip)
 }
 
+// TestExistingSalt verifies that WithExistingSalt reuses a salt but never creates one.
+func TestExistingSalt(t *testing.T) {
+	setTestSecretKey(t)
+	dir := t.TempDir()
+
+	if _, err := hcs.NewGenerator(hcs.WithSaltDir(dir), hcs.WithExistingSalt()); errcode.Of(err, "") != errcode.SaltUnavailable {
+		t.Fatalf("a missing salt should fail with %s, got %v", errcode.SaltUnavailable, err)
+	}
+	if files, _ := hcs.SaltFiles(dir); len(files) != 0 {
+		t.Fatalf("no salt may be created, found %v", files)
+	}
+
+	gen1, err := hcs.NewGeneratorWithSaltDir(dir)
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	gen2, err := hcs.NewGenerator(hcs.WithSaltDir(dir), hcs.WithExistingSalt())
+	if err != nil {
+		t.Fatalf("an existing salt should load: %v", err)
+	}
+	out1, _ := gen1.Generate(getTestInput())
+	out2, _ := gen2.Generate(getTestInput())
+	if out1 == nil || out2 == nil || out1.Chip != out2.Chip {
+		t.Error("both generators should use the same salt")
+	}
+}
+
+// TestSaltIntegrity verifies that a modified or truncated salt file is
+// rejected instead of silently changing every CHIP.
+func TestSaltIntegrity(t *testing.T) {
+	setTestSecretKey(t)
+	tempDir := t.TempDir()
+	saltPath := filepath.Join(tempDir, ".hcs_salt")
+
+	if _, err := hcs.NewGeneratorWithSaltDir(tempDir); err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, ".hcs_salt.mac")); err != nil {
+		t.Fatalf("salt seal was not written: %v", err)
+	}
+	if _, err := hcs.NewGeneratorWithSaltDir(tempDir); err != nil {
+		t.Fatalf("sealed salt should load: %v", err)
+	}
+
+	salt, err := os.ReadFile(saltPath)
+	if err != nil {
+		t.Fatalf("Failed to read salt: %v", err)
+	}
+	tampered := append([]byte(nil), salt...)
+	tampered[0] ^= 0xff
+	if err := os.WriteFile(saltPath, tampered, 0600); err != nil {
+		t.Fatalf("Failed to write salt: %v", err)
+	}
+	if _, err := hcs.NewGeneratorWithSaltDir(tempDir); err == nil {
+		t.Error("expected error for a modified salt file")
+	}
+
+	if err := os.WriteFile(saltPath, salt[:16], 0600); err != nil {
+		t.Fatalf("Failed to write salt: %v", err)
+	}
+	if _, err := hcs.NewGeneratorWithSaltDir(tempDir); err == nil {
+		t.Error("expected error for a truncated salt file")
+	}
+}
+
+func TestSaltSealMigration(t *testing.T) {
+	setTestSecretKey(t)
+	dir := t.TempDir()
+	keyA := bytes.Repeat([]byte{0xa1}, 32)
+	keyB := bytes.Repeat([]byte{0xb2}, 32)
+	provider := func(keys map[string][]byte, active string, migrate bool) hcs.DirSaltProvider {
+		t.Helper()
+		keyring, err := hcs.NewKeyring(keys, active)
+		if err != nil {
+			t.Fatalf("NewKeyring: %v", err)
+		}
+		return hcs.DirSaltProvider{Dir: dir, Secrets: keyring, MigrateSeal: migrate}
+	}
+
+	if _, err := provider(map[string][]byte{"a": keyA}, "a", false).Salt(); err != nil {
+		t.Fatalf("creating salt: %v", err)
+	}
+	if _, err := provider(map[string][]byte{"b": keyB}, "b", false).Salt(); errcode.Of(err, "") != errcode.SaltUnsealed {
+		t.Errorf("salt sealed by another key: got %v, want %s", err, errcode.SaltUnsealed)
+	}
+	// A seal by another key of the keyring vouches for the salt
+	if _, err := provider(map[string][]byte{"a": keyA, "b": keyB}, "b", false).Salt(); err != nil {
+		t.Errorf("rotated keyring: %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, ".hcs_salt.mac")); err != nil {
+		t.Fatalf("removing seal: %v", err)
+	}
+	if _, err := provider(map[string][]byte{"a": keyA}, "a", false).Salt(); errcode.Of(err, "") != errcode.SaltUnsealed {
+		t.Errorf("salt without seal file: got %v, want %s", err, errcode.SaltUnsealed)
+	}
+	if _, err := provider(map[string][]byte{"a": keyA}, "a", true).Salt(); err != nil {
+		t.Fatalf("migrating seal: %v", err)
+	}
+	if _, err := provider(map[string][]byte{"a": keyA}, "a", false).Salt(); err != nil {
+		t.Errorf("migrated salt should load: %v", err)
+	}
+}
+
 func TestGeneratorValidation(t *testing.T) {
 	tempDir := t.TempDir()
 	gen, err := hcs.NewGeneratorWithSaltDir(tempDir)
@@ -182,3 +290,23 @@ func TestGeneratorValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateContextDeadline(t *testing.T) {
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	input := getTestInput()
+	input.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Hour: 14, Minute: 30, Timezone: "UTC"}
+	_, err = gen.GenerateContext(ctx, input, nil)
+	if !errors.Is(err, hcs.ErrDeadlineExceeded) {
+		t.Errorf("expected ErrDeadlineExceeded, got %v", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error should wrap the context error, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"crypto/sha256"
+	"hash"
+	"sync/atomic"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// countingBackend wraps a hash backend and counts the hashes it constructs
+type countingBackend struct {
+	hcs.HashBackend
+	calls *atomic.Int64
+}
+
+func (b countingBackend) New() hash.Hash {
+	b.calls.Add(1)
+	return b.HashBackend.New()
+}
+
+func (b countingBackend) NewKeyed(key []byte) (hash.Hash, error) {
+	b.calls.Add(1)
+	return b.HashBackend.NewKeyed(key)
+}
+
+// TestHashBackends verifies that generation goes through the hash registry,
+// and that a replacement backend of the same algorithm changes no output.
+func TestHashBackends(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	input := getTestInput()
+	before, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{KeyDerivation: hcs.KeyDerivationHKDF})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	// Replace SHA3-256 by a wrapper of itself, and SHA-256 by an external
+	// implementation, as an accelerated module would be
+	var calls atomic.Int64
+	replacements := map[string]hcs.HashBackend{hcs.HashSHA256: hcs.HMACBackend(sha256.New)}
+	replacements[hcs.HashSHA3256], err = hcs.LookupHash(hcs.HashSHA3256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, backend := range replacements {
+		original, _ := hcs.LookupHash(name)
+		hcs.RegisterHash(name, countingBackend{backend, &calls})
+		t.Cleanup(func() { hcs.RegisterHash(name, original) })
+	}
+
+	after, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{KeyDerivation: hcs.KeyDerivationHKDF})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if calls.Load() == 0 {
+		t.Error("generation should construct its hashes through the registered backends")
+	}
+	if after.Chip != before.Chip || after.CodeU7 != before.CodeU7 || after.B3Sig != before.B3Sig {
+		t.Error("a backend of the same algorithm should not change any output")
+	}
+
+	if _, err := hcs.LookupHash("md5"); err == nil {
+		t.Error("expected error for an unregistered algorithm")
+	}
+	registered := map[string]bool{}
+	for _, name := range hcs.HashNames() {
+		registered[name] = true
+	}
+	for _, name := range []string{hcs.HashSHA256, hcs.HashSHA3256, hcs.HashSHA3512} {
+		if !registered[name] {
+			t.Errorf("%s should be registered", name)
+		}
+	}
+}
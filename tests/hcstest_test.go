@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/pkg/hcstest"
+)
+
+// TestMockServer verifies that the mock API is deterministic and matches its fixtures.
+func TestMockServer(t *testing.T) {
+	t.Setenv("HCS_SECRET_KEY", "") // the mock must not need a real secret
+	srv := hcstest.NewServer(t)
+	fixture := hcstest.MustFixture(t, "air-balanced")
+
+	post := func(input []byte) []byte {
+		resp, err := http.Post(srv.URL+"/api/generate", "application/json", bytes.NewReader(input))
+		if err != nil {
+			t.Fatalf("generate request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("generate status = %d", resp.StatusCode)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return body
+	}
+
+	body := post(fixture.Input)
+	hcstest.AssertValidOutput(t, body)
+	hcstest.AssertChip(t, body, fixture.Chip)
+	if !bytes.Equal(bytes.TrimSpace(body), fixture.Output) {
+		t.Errorf("mock response differs from the fixture output:\n%s\n%s", body, fixture.Output)
+	}
+	if again := post(fixture.Input); !bytes.Equal(again, body) {
+		t.Error("mock responses should be deterministic")
+	}
+
+	resp, err := http.Get(srv.URL + "/api/codes/" + fixture.Chip)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("code lookup failed: %v, %v", resp, err)
+	}
+	resp.Body.Close()
+	errorCode := func(resp *http.Response, status int, want errcode.Code) {
+		t.Helper()
+		defer resp.Body.Close()
+		var body struct {
+			Code      int          `json:"code"`
+			ErrorCode errcode.Code `json:"errorCode"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		if resp.StatusCode != status || body.Code != status || body.ErrorCode != want {
+			t.Errorf("error = %d %+v, want %d %s", resp.StatusCode, body, status, want)
+		}
+	}
+	if resp, err := http.Get(srv.URL + "/api/codes/000000000000"); err == nil {
+		errorCode(resp, http.StatusNotFound, errcode.NotFound)
+	}
+	if resp, err := http.Post(srv.URL+"/api/generate", "application/json", bytes.NewReader([]byte(`{"dominantElement":"Metal"}`))); err == nil {
+		errorCode(resp, http.StatusBadRequest, errcode.InvalidElement)
+	}
+	if resp, err := http.Post(srv.URL+"/api/generate", "application/json", bytes.NewReader([]byte(`{`))); err == nil {
+		errorCode(resp, http.StatusBadRequest, errcode.InvalidJSON)
+	}
+
+	expiring := []byte(`{"hcs":` + string(fixture.Input) + `,"validityMonths":1}`)
+	first := post(expiring)
+	srv.Clock.Advance(time.Hour)
+	if again := post(expiring); bytes.Equal(again, first) {
+		t.Error("advancing the mock clock should change issuedAt")
+	}
+}
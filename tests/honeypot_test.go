@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/honeypot"
+)
+
+// TestHoneypotFile verifies that canaries survive a save and reload, that
+// re-adding a CHIP replaces it, and that allowed tenants and keys are exempt.
+func TestHoneypotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "honeypot.json")
+	f, err := honeypot.Load("")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	f.AllowedTenants = []string{"qa"}
+	f.AllowedKeys = []string{"key-1f3a9c02"}
+	f.Add(honeypot.Canary{Chip: "aae673a93e1f", Label: "first"}, honeypot.Canary{Chip: "bbbbbbbbbbbb", Label: "second"})
+	f.Add(honeypot.Canary{Chip: "aae673a93e1f", Label: "replaced"})
+	if err := f.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	f, err = honeypot.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if f.Len() != 2 {
+		t.Errorf("expected 2 canaries, got %d", f.Len())
+	}
+	if c, ok := f.Match("aae673a93e1f"); !ok || c.Label != "replaced" {
+		t.Errorf("Match = %+v, %v", c, ok)
+	}
+	if _, ok := f.Match("cccccccccccc"); ok {
+		t.Error("unknown CHIP matched")
+	}
+	for _, tc := range []struct {
+		tenant, key string
+		want        bool
+	}{{"qa", "key-00000000", true}, {"", "key-1f3a9c02", true}, {"acme", "key-00000000", false}, {"", "anonymous", false}} {
+		if got := f.Allowed(tc.tenant, tc.key); got != tc.want {
+			t.Errorf("Allowed(%q, %q) = %v, want %v", tc.tenant, tc.key, got, tc.want)
+		}
+	}
+
+	os.WriteFile(path, []byte(`{"canaries": [{"chip": "not-a-chip"}]}`), 0600)
+	if _, err := honeypot.Load(path); err == nil {
+		t.Error("expected an error for an invalid CHIP")
+	}
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/i18n"
+)
+
+// formatVerbs matches the fmt verbs of a message, including %%
+var formatVerbs = regexp.MustCompile(`%[-+# 0]*[0-9]*(?:\.[0-9]+)?[a-zA-Z%]`)
+
+// TestTranslationsKeepFormatVerbs verifies that every translation takes the
+// same arguments as its English message, in the same order.
+func TestTranslationsKeepFormatVerbs(t *testing.T) {
+	for _, lang := range i18n.Locales() {
+		for en, tr := range i18n.Messages(lang) {
+			if want, got := formatVerbs.FindAllString(en, -1), formatVerbs.FindAllString(tr, -1); !slices.Equal(want, got) {
+				t.Errorf("%s: %q has verbs %v, its translation %q has %v", lang, en, want, tr, got)
+			}
+		}
+	}
+}
+
+// TestErrorCatalogTranslated verifies that every error title and description
+// has a translation, since the API serves them in the negotiated language.
+func TestErrorCatalogTranslated(t *testing.T) {
+	for _, lang := range i18n.Locales()[1:] {
+		for _, e := range errcode.Catalog() {
+			for _, text := range []string{e.Title, e.Description} {
+				if i18n.Translate(lang, text) == text {
+					t.Errorf("%s: %s has no translation for %q", lang, e.Code, text)
+				}
+			}
+		}
+	}
+}
+
+// TestLanguageMatching verifies locale names and Accept-Language headers.
+func TestLanguageMatching(t *testing.T) {
+	for locale, want := range map[string]string{"fr": "fr", "fr-CA": "fr", "fr_FR.UTF-8": "fr", "EN": "en", "de": "", "": ""} {
+		if got := i18n.Match(locale); got != want {
+			t.Errorf("Match(%q) = %q, want %q", locale, got, want)
+		}
+	}
+	for header, want := range map[string]string{
+		"fr-FR,fr;q=0.9,en;q=0.8":   "fr",
+		"de-DE, en;q=0.5, fr;q=0.7": "fr",
+		"de, *;q=0.1":               "",
+	} {
+		if got := i18n.MatchAcceptLanguage(header); got != want {
+			t.Errorf("MatchAcceptLanguage(%q) = %q, want %q", header, got, want)
+		}
+	}
+	if got := i18n.Sprintf("fr", "\n  Warning: %s\n", "x"); got != "\n  Avertissement : x\n" {
+		t.Errorf("surrounding whitespace not kept: %q", got)
+	}
+	if got := i18n.Sprintf("fr", "untranslated %d", 1); got != "untranslated 1" {
+		t.Errorf("untranslated message: %q", got)
+	}
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"encoding/base64"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/synth"
+)
+
+// invarianceOptions are the generation options cycled through, covering every
+// optional segment and declaration of each level
+func invarianceOptions(previousChip string) []*hcs.GeneratorOptions {
+	opts := []*hcs.GeneratorOptions{
+		{},
+		{KeyDerivation: hcs.KeyDerivationHKDF},
+		{SecondaryDigest: hcs.DigestSHA3},
+		{KeyDerivation: hcs.KeyDerivationHKDF, SecondaryDigest: hcs.DigestSHA3, U7SignatureLengths: hcs.SignatureLengths{QSig: 32, B3: 48}},
+		{PreviousChip: previousChip, LegacyU7Versions: []string{hcs.CurrentU7Version}},
+		{EngineVersion: "v2"},
+	}
+	if !hcs.FIPSMode() {
+		opts = append(opts, &hcs.GeneratorOptions{PostQuantum: true})
+	}
+	return opts
+}
+
+// TestDecodeEncodeInvariance verifies that every code the generator issues
+// decodes, and that encoding the decoded components reproduces the code
+// exactly, across synthetic profiles, salt epochs and generation options.
+func TestDecodeEncodeInvariance(t *testing.T) {
+	setTestSecretKey(t)
+	dir := t.TempDir()
+	profiles := synth.New(synth.Options{Seed: 7, BirthRate: 0.5})
+	levels := map[string]int{}
+
+	for epoch := 0; epoch < 2; epoch++ {
+		if epoch > 0 {
+			if _, err := (hcs.DirSaltProvider{Dir: dir, Secrets: hcs.NewEnvSecretProvider()}).Rotate(); err != nil {
+				t.Fatalf("failed to rotate the salt: %v", err)
+			}
+		}
+		genOpts := []hcs.Option{hcs.WithSaltDir(dir)}
+		if !hcs.FIPSMode() {
+			signer, err := hcs.NewMLDSASigner(make([]byte, 32))
+			if err != nil {
+				t.Fatalf("failed to create signer: %v", err)
+			}
+			genOpts = append(genOpts, hcs.WithPQSigner(signer))
+		}
+		gen, err := hcs.NewGenerator(genOpts...)
+		if err != nil {
+			t.Fatalf("failed to create generator: %v", err)
+		}
+
+		previous := "0123456789ab"
+		for i := 0; i < 140; i++ {
+			in := profiles.Profile()
+			// U3 has two digits per value, so keep values below 1
+			for _, v := range []*float64{&in.Modal.Cardinal, &in.Modal.Fixed, &in.Modal.Mutable,
+				&in.Cognition.Fluid, &in.Cognition.Crystallized, &in.Cognition.Verbal, &in.Cognition.Strategic, &in.Cognition.Creative} {
+				*v = math.Min(*v, 0.99)
+			}
+			options := invarianceOptions(previous)
+			out, err := gen.GenerateWithOptions(in, options[i%len(options)])
+			if err != nil {
+				t.Fatalf("profile %d: failed to generate: %v", i, err)
+			}
+			previous = out.Chip
+
+			codes := []string{out.CodeU3, out.CodeU4, out.CodeU5, out.CodeU6, out.CodeU7}
+			for _, legacy := range out.LegacyCodes {
+				codes = append(codes, legacy.Code)
+			}
+			for _, code := range codes {
+				if code == "" {
+					continue
+				}
+				d, err := hcs.Decode(code)
+				if err != nil {
+					t.Fatalf("profile %d: failed to decode %s: %v", i, code, err)
+				}
+				levels[d.Level]++
+				if encoded, err := hcs.Encode(d); err != nil || encoded != code {
+					t.Fatalf("profile %d: Encode(Decode(code)) = %s, %v; want %s", i, encoded, err, code)
+				}
+			}
+		}
+	}
+
+	for _, level := range []string{"U3", "U4", "U5", "U6", "U7"} {
+		if levels[level] == 0 {
+			t.Errorf("no %s code was checked", level)
+		}
+	}
+}
+
+// TestDecodeRejectsNonCanonical verifies that codes the grammar accepts in a
+// spelling the encoder never produces are rejected rather than decoded to
+// components that encode differently.
+func TestDecodeRejectsNonCanonical(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	out, err := gen.Generate(getTestInput())
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	// The same U4 content with its keys in another order
+	payload, _ := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(out.CodeU4, "HCS-U4|"))
+	chip, profile, _ := strings.Cut(strings.TrimPrefix(strings.TrimSuffix(string(payload), "}"), "{"), ",")
+	reordered := "HCS-U4|" + base64.RawURLEncoding.EncodeToString([]byte("{"+profile+","+chip+"}"))
+
+	for _, tc := range []struct {
+		name, code string
+		parses     bool // NormalizedFromCode accepts the variant
+	}{
+		{"U7 three-digit value", strings.Replace(out.CodeU7, "MOD:c31", "MOD:c031", 1), true},
+		{"U7 default lengths", strings.Replace(out.CodeU7, "|E:", ".24.32|E:", 1), true},
+		{"U7 post-quantum, no key", strings.Replace(out.CodeU7, "|E:", "+MLDSA65|E:", 1), true},
+		{"U7 key without algorithm", out.CodeU7 + "|PQ:0123456789abcdef", true},
+		{"U4 reordered keys", reordered, true},
+		{"U6 leading zero", strings.Replace(out.CodeU6, "HCS-U6|", "HCS-U6|0", 1), false},
+	} {
+		if _, err := hcs.NormalizedFromCode(tc.code); (err == nil) != tc.parses {
+			t.Fatalf("%s: NormalizedFromCode error %v", tc.name, err)
+		}
+		if d, err := hcs.Decode(tc.code); err == nil {
+			t.Errorf("%s: %s should be rejected, decoded %+v", tc.name, tc.code, d)
+		}
+	}
+}
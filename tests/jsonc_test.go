@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/jsonc"
+)
+
+// TestJSONCStandardize verifies that comments and trailing commas are
+// blanked out without moving the rest of the text, and that strings holding
+// comment markers or commas are left alone.
+func TestJSONCStandardize(t *testing.T) {
+	in := `{
+  // element of the subject
+  "dominantElement": "Air", /* the "a//b" below is a string */
+  "note": "a//b, /*c*/ \"d,\" ]",
+  "modal": [0.31, 0.23, 0.46,],
+}`
+	out, err := jsonc.Standardize([]byte(in))
+	if err != nil {
+		t.Fatalf("Standardize failed: %v", err)
+	}
+	if len(out) != len(in) {
+		t.Errorf("length changed from %d to %d", len(in), len(out))
+	}
+	for i := range in {
+		if in[i] == '\n' && out[i] != '\n' {
+			t.Errorf("newline at offset %d was not kept", i)
+		}
+	}
+	var v struct {
+		DominantElement string    `json:"dominantElement"`
+		Note            string    `json:"note"`
+		Modal           []float64 `json:"modal"`
+	}
+	if err := json.Unmarshal(out, &v); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out)
+	}
+	if v.DominantElement != "Air" || v.Note != `a//b, /*c*/ "d," ]` || len(v.Modal) != 3 {
+		t.Errorf("unexpected values %+v", v)
+	}
+
+	for _, bad := range []string{`{"a": 1 /* open`, `{"a": "open}`} {
+		if _, err := jsonc.Standardize([]byte(bad)); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+	if out, err := jsonc.Standardize([]byte(`{a: 'x'}`)); err != nil || json.Valid(out) {
+		t.Errorf("JSON5 syntax should pass through and stay invalid: %v", err)
+	}
+}
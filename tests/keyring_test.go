@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/signer"
+)
+
+const rotatedKeyHex = "2122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f40"
+
+// TestSecretKeyRotation verifies that codes name the key they were signed
+// with, and that codes signed before a rotation still verify.
+func TestSecretKeyRotation(t *testing.T) {
+	setTestSecretKey(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	input := getTestInput()
+
+	before, err := hcs.NewGeneratorWithSaltDir(dir)
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	old, err := before.Generate(input)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if strings.Contains(old.CodeU7, "|KID:") {
+		t.Errorf("codes of the legacy key should carry no KID segment: %s", old.CodeU7)
+	}
+
+	t.Setenv("HCS_SECRET_KEYS", `{"2025-06": "`+rotatedKeyHex+`"}`)
+	t.Setenv("HCS_SECRET_KEY_ID", "2025-06")
+	after, err := hcs.NewGeneratorWithSaltDir(dir)
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	rotated, err := after.Generate(input)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !strings.Contains(rotated.CodeU7, "|B3:"+rotated.B3Sig[:32]+"|KID:2025-06") || rotated.QSig == old.QSig {
+		t.Fatalf("expected a code signed with the new key after B3, got %s", rotated.CodeU7)
+	}
+	decoded, err := hcs.Decode(rotated.CodeU7)
+	if err != nil || decoded.Signatures.KeyID != "2025-06" {
+		t.Errorf("Decode should report the key ID, got %+v, %v", decoded, err)
+	}
+	if keyID, _ := hcs.ParseKeyID(old.CodeU7); keyID != hcs.LegacyKeyID {
+		t.Errorf("ParseKeyID(old) = %q, want %q", keyID, hcs.LegacyKeyID)
+	}
+
+	for name, code := range map[string]string{"old": old.CodeU7, "rotated": rotated.CodeU7} {
+		v, err := after.VerifyProfile(ctx, code, input, nil, nil)
+		if err != nil || !v.Valid {
+			t.Errorf("the %s code should verify after the rotation, got %+v, %v", name, v, err)
+		}
+	}
+
+	// A code naming another key has no signature to compare with
+	forged := strings.Replace(rotated.CodeU7, "|KID:2025-06", "|KID:2024-01", 1)
+	if _, err := after.VerifyProfile(ctx, forged, input, nil, nil); err == nil {
+		t.Error("a code naming an unknown key should fail verification")
+	}
+
+	// Retiring the legacy key stops its codes from verifying
+	t.Setenv("HCS_SECRET_KEY", "")
+	retired, err := hcs.NewGeneratorWithSaltDir(dir)
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	if _, err := retired.VerifyProfile(ctx, old.CodeU7, input, nil, nil); err == nil {
+		t.Error("codes of a retired key should fail verification")
+	}
+	if v, err := retired.VerifyProfile(ctx, rotated.CodeU7, input, nil, nil); err != nil || !v.Valid {
+		t.Errorf("codes of the active key should verify, got %+v, %v", v, err)
+	}
+}
+
+// TestSecretKeysConfig verifies the rejected HCS_SECRET_KEYS setups.
+func TestSecretKeysConfig(t *testing.T) {
+	setTestSecretKey(t)
+	for name, env := range map[string][2]string{
+		"json":           {`{"k2": `, "k2"},
+		"key ID":         {`{"K2": "` + rotatedKeyHex + `"}`, "K2"},
+		"key":            {`{"k2": "abcd"}`, "k2"},
+		"no active key":  {`{"k2": "` + rotatedKeyHex + `"}`, ""},
+		"unlisted key":   {`{"k2": "` + rotatedKeyHex + `"}`, "k3"},
+		"duplicate key":  {`{"legacy": "` + rotatedKeyHex + `"}`, "legacy"},
+		"empty keyring":  {`{}`, "k2"},
+		"invalid object": {`["k2"]`, "k2"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("HCS_SECRET_KEYS", env[0])
+			t.Setenv("HCS_SECRET_KEY_ID", env[1])
+			if _, err := hcs.NewEnvSecretProvider().SecretKey(); err == nil {
+				t.Error("expected an invalid secret key configuration")
+			}
+		})
+	}
+
+	// A single key needs no HCS_SECRET_KEY_ID
+	t.Setenv("HCS_SECRET_KEY", "")
+	t.Setenv("HCS_SECRET_KEYS", `{"k2": "`+rotatedKeyHex+`"}`)
+	t.Setenv("HCS_SECRET_KEY_ID", "")
+	keys, active, err := hcs.NewEnvSecretProvider().SecretKeys()
+	if err != nil || active != "k2" || len(keys) != 1 {
+		t.Errorf("SecretKeys = %d keys, active %q, %v; want k2", len(keys), active, err)
+	}
+}
+
+// legacySigner stands for a signing service predating key IDs
+type legacySigner struct{ hcs.Signer }
+
+func (s legacySigner) SignU7(ctx context.Context, req hcs.U7SignRequest) (hcs.U7Signatures, error) {
+	req.KeyID = ""
+	sigs, err := s.Signer.SignU7(ctx, req)
+	sigs.KeyID = ""
+	return sigs, err
+}
+
+// TestRemoteSignerKeyring verifies that a signing service signs with the key
+// a code names, and that a signer ignoring key IDs is detected.
+func TestRemoteSignerKeyring(t *testing.T) {
+	keyring, err := hcs.NewKeyring(map[string][]byte{
+		hcs.LegacyKeyID: bytes.Repeat([]byte{0x5a}, 32),
+		"k2":            bytes.Repeat([]byte{0x6b}, 32),
+	}, "k2")
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	srv := httptest.NewServer(signer.Handler(hcs.NewLocalSigner(keyring), "s3cret"))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	local, err := hcs.NewGenerator(hcs.WithSaltDir(dir), hcs.WithSecretProvider(keyring))
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := hcs.NewGenerator(hcs.WithSaltDir(dir), hcs.WithSecretProvider(noSecret{}),
+		hcs.WithSigner(signer.NewRemote(srv.URL, "s3cret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := local.Generate(getTestInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := remote.Generate(getTestInput())
+	if err != nil || got.CodeU7 != want.CodeU7 || !strings.Contains(got.CodeU7, "|KID:k2") {
+		t.Fatalf("remote signing should match local signing with k2, got %v:\n%s\n%s", err, got.CodeU7, want.CodeU7)
+	}
+	if v, err := remote.VerifyProfile(context.Background(), got.CodeU7, getTestInput(), nil, nil); err != nil || !v.Valid {
+		t.Errorf("the remote signer should verify codes of k2, got %+v, %v", v, err)
+	}
+
+	outdated, err := hcs.NewGenerator(hcs.WithSaltDir(dir), hcs.WithSecretProvider(noSecret{}),
+		hcs.WithSigner(legacySigner{signer.NewRemote(srv.URL, "s3cret")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := outdated.VerifyProfile(context.Background(), got.CodeU7, getTestInput(), nil, nil); err == nil || !strings.Contains(err.Error(), "does not hold") {
+		t.Errorf("a signer ignoring key IDs should be detected, got %v", err)
+	}
+
+	if _, err := hcs.NewKeyring(map[string][]byte{"k2": {1, 2}}, "k2"); err == nil {
+		t.Error("a short key should be rejected")
+	}
+}
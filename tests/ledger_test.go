@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/ledger"
+)
+
+// TestLedger verifies that the ledger chain detects modified, removed,
+// reordered and truncated entries, and entries rewritten without the key.
+func TestLedger(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	output, err := gen.Generate(getTestInput())
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	key := bytes.Repeat([]byte{0x11}, 32)
+	fingerprint := hcs.SaltFingerprint(gen.GetSalt())
+
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	start := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	var entries []ledger.Entry
+	for i := 0; i < 4; i++ {
+		e, err := ledger.Append(path, ledger.NewEntry(output, gen.SaltEpoch(), fingerprint, start.Add(time.Duration(i)*time.Minute)), key)
+		if err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+		entries = append(entries, e)
+	}
+	if entries[0].Seq != 1 || entries[0].Prev != ledger.Genesis || entries[3].Seq != 4 || entries[3].Prev != entries[2].Hash {
+		t.Fatalf("entries are not chained: %+v", entries)
+	}
+	if len(entries[0].Codes) < 3 || entries[0].Codes[len(entries[0].Codes)-1].Version != hcs.CurrentU7Version {
+		t.Errorf("unexpected code records: %+v", entries[0].Codes)
+	}
+
+	report, err := ledger.Verify(path, key, entries[1].Hash)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !report.Valid() || report.Entries != 4 || report.Head != entries[3].Hash || !report.MACsChecked {
+		t.Fatalf("intact ledger should verify: %+v", report)
+	}
+
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(strings.TrimSpace(string(original)), "\n")
+
+	cases := map[string]struct {
+		content string
+		head    string
+		want    string
+	}{
+		"modified":  {strings.Replace(string(original), entries[1].Time, "2024-01-01T00:00:00Z", 1), "", "modified"},
+		"removed":   {lines[0] + lines[2] + lines[3], "", "missing entry 2"},
+		"reordered": {lines[0] + lines[2] + lines[1] + lines[3], "", "out of order"},
+		"truncated": {lines[0] + lines[1], entries[3].Hash, "removed from its end"},
+	}
+	for name, c := range cases {
+		if err := os.WriteFile(path, []byte(c.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		report, err := ledger.Verify(path, key, c.head)
+		if err != nil {
+			t.Fatalf("%s: Verify failed: %v", name, err)
+		}
+		if report.Valid() || !hasProblem(report, c.want) {
+			t.Errorf("%s: got problems %+v, want %q", name, report.Problems, c.want)
+		}
+	}
+
+	// A chain rebuilt from scratch without the key has consistent hashes, but
+	// no valid MACs
+	forged := filepath.Join(t.TempDir(), "forged.jsonl")
+	for _, e := range entries {
+		if _, err := ledger.Append(forged, e, bytes.Repeat([]byte{0x22}, 32)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if report, _ := ledger.Verify(forged, nil, ""); !report.Valid() || report.MACsChecked {
+		t.Errorf("without a key only the chain is checked: %+v", report)
+	}
+	if report, _ := ledger.Verify(forged, key, ""); report.Valid() || !hasProblem(report, "MAC does not match") {
+		t.Errorf("forged chain should fail the MAC check: %+v", report.Problems)
+	}
+}
+
+// hasProblem reports whether a ledger report has a problem containing detail
+func hasProblem(report *ledger.Report, detail string) bool {
+	for _, p := range report.Problems {
+		if strings.Contains(p.Detail, detail) {
+			return true
+		}
+	}
+	return false
+}
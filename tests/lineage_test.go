@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestLineage verifies that a regenerated profile links every code level to
+// the CHIP of the previous codes, and that the link verifies only against it.
+func TestLineage(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	input := getTestInput()
+	input.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 5, Day: 1, Hour: 12, Timezone: "UTC"}
+	first, err := gen.Generate(input)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if lineage, err := hcs.ParseLineage(first.CodeU3); err != nil || lineage != "" || strings.Contains(first.CodeU7, "|LN:") {
+		t.Errorf("codes without a previous CHIP should carry no lineage: %q, %v", lineage, err)
+	}
+
+	updated := *input
+	updated.Cognition.Fluid = 0.9
+	second, err := gen.GenerateWithOptions(&updated, &hcs.GeneratorOptions{PreviousChip: first.Chip})
+	if err != nil {
+		t.Fatalf("failed to regenerate: %v", err)
+	}
+	lineage := hcs.LineageHash(first.Chip)
+	if second.Metadata == nil || second.Metadata.Lineage != lineage {
+		t.Fatalf("metadata lineage = %+v, want %s", second.Metadata, lineage)
+	}
+	if !strings.HasSuffix(second.CodeU3, "|LN:"+lineage) || !hcs.ValidateU3Format(second.CodeU3) {
+		t.Errorf("unexpected U3 code: %s", second.CodeU3)
+	}
+	for _, code := range []string{second.CodeU3, second.CodeU4, second.CodeU5, second.CodeU7} {
+		if got, err := hcs.ParseLineage(code); err != nil || got != lineage {
+			t.Errorf("ParseLineage(%s) = %q, %v; want %s", code, got, err, lineage)
+		}
+		for _, previous := range []string{first.Chip, first.CodeU3, first.CodeU4} {
+			if err := hcs.VerifyLineage(code, previous); err != nil {
+				t.Errorf("VerifyLineage(%s, %s): %v", code, previous, err)
+			}
+		}
+		if err := hcs.VerifyLineage(code, second.Chip); err == nil {
+			t.Errorf("%s should not continue its own CHIP", code)
+		}
+	}
+	if err := hcs.VerifyLineage(first.CodeU3, first.Chip); err == nil {
+		t.Error("a code without lineage should not verify")
+	}
+
+	// The LN segment leaves the other segments readable
+	for _, code := range []string{second.CodeU3, second.CodeU4} {
+		if err := gen.VerifyCHIP(code, nil); err != nil {
+			t.Errorf("VerifyCHIP(%s): %v", code, err)
+		}
+	}
+	if _, err := hcs.NormalizedFromCode(second.CodeU7); err != nil {
+		t.Errorf("U7 code with lineage should decode: %v", err)
+	}
+
+	_, err = gen.GenerateWithOptions(&updated, &hcs.GeneratorOptions{PreviousChip: "HCS-U3|E:A"})
+	if errcode.Of(err, "") != errcode.InvalidOptions {
+		t.Errorf("invalid previous CHIP: got %v, want %s", err, errcode.InvalidOptions)
+	}
+}
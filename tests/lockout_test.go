@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/lockout"
+)
+
+// TestLockout verifies that keys are locked after too many failures within a
+// window, for the lockout duration, and that any locked key locks a request.
+func TestLockout(t *testing.T) {
+	l := lockout.New(lockout.Config{MaxFailures: 3, Window: time.Minute, Lockout: 10 * time.Minute})
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	l.Fail(start, "chip:a", "key:1")
+	l.Fail(start.Add(10*time.Second), "chip:a", "key:1")
+	if locked, _ := l.Locked(start.Add(20*time.Second), "chip:a", "key:1"); locked {
+		t.Error("locked before MaxFailures")
+	}
+	l.Fail(start.Add(20*time.Second), "chip:b", "key:1")
+	locked, until := l.Locked(start.Add(30*time.Second), "chip:c", "key:1")
+	if !locked || !until.Equal(start.Add(20*time.Second+10*time.Minute)) {
+		t.Errorf("key after 3 failures: locked=%v until %s", locked, until)
+	}
+	if locked, _ := l.Locked(start.Add(30*time.Second), "chip:a", "key:2"); locked {
+		t.Error("a CHIP with 2 failures locked another key")
+	}
+	if locked, _ := l.Locked(start.Add(11*time.Minute), "key:1"); locked {
+		t.Error("still locked after the lockout")
+	}
+
+	// Failures spread over more than the window never lock
+	for i := 0; i < 6; i++ {
+		l.Fail(start.Add(time.Duration(i)*40*time.Second), "chip:slow")
+	}
+	if locked, _ := l.Locked(start.Add(4*time.Minute), "chip:slow"); locked {
+		t.Error("failures older than the window counted")
+	}
+
+	// A success forgets the failures
+	l.Fail(start, "chip:d")
+	l.Fail(start, "chip:d")
+	l.Reset("chip:d")
+	l.Fail(start, "chip:d")
+	if locked, _ := l.Locked(start, "chip:d"); locked {
+		t.Error("failures counted across a reset")
+	}
+
+	if c := lockout.New(lockout.Config{}).Config(); c.MaxFailures != lockout.DefaultMaxFailures || c.Lockout != lockout.DefaultLockout {
+		t.Errorf("zero config not defaulted: %+v", c)
+	}
+}
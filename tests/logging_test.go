@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+func TestGeneratorLogging(t *testing.T) {
+	setTestSecretKey(t)
+
+	var base, scoped bytes.Buffer
+	gen, err := hcs.NewGenerator(
+		hcs.WithSaltDir(t.TempDir()),
+		hcs.WithLogger(slog.New(slog.NewTextHandler(&base, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+	)
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+
+	input := getTestInput()
+	input.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Hour: 14, Minute: 30, Timezone: "UTC"}
+	if _, err := gen.Generate(input); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if !strings.Contains(base.String(), "stage=fusion") {
+		t.Errorf("expected stage traces in generator logger, got %q", base.String())
+	}
+
+	// A context logger takes precedence over the generator's logger
+	base.Reset()
+	logger := slog.New(slog.NewTextHandler(&scoped, &slog.HandlerOptions{Level: slog.LevelDebug})).With("requestId", "r1")
+	ctx := hcs.ContextWithLogger(context.Background(), logger)
+	if _, err := gen.GenerateContext(ctx, input, nil); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if base.Len() != 0 {
+		t.Errorf("generator logger should be unused, got %q", base.String())
+	}
+	if !strings.Contains(scoped.String(), "requestId=r1") {
+		t.Errorf("expected request-scoped traces, got %q", scoped.String())
+	}
+	if strings.Contains(scoped.String(), "1990") {
+		t.Errorf("birth data must not be logged: %q", scoped.String())
+	}
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/schema"
+)
+
+// TestLuckPillars checks the direction, start age and sequence of the Luck
+// Pillars. Born 1990-06-15 14:30 UTC in a Geng (Yang) year, a man counts the
+// 21.7 days to Xiao Shu (1990-07-07 08:00 UTC) and a woman the 9.7 days from
+// Mang Zhong (1990-06-05 21:46 UTC).
+func TestLuckPillars(t *testing.T) {
+	for _, tc := range []struct {
+		gender    string
+		direction string
+		startAge  float64
+		first     []string
+	}{
+		{"male", "forward", 7.2, []string{"Gui-Wei", "Jia-Shen", "Yi-You"}},
+		{"female", "backward", 3.2, []string{"Xin-Si", "Geng-Chen", "Ji-Mao"}},
+	} {
+		birth := hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Hour: 14, Minute: 30, Timezone: "UTC", Gender: tc.gender}
+		profile, err := hcs.ComputeChineseProfile(birth)
+		if err != nil {
+			t.Fatalf("ComputeChineseProfile: %v", err)
+		}
+		luck := profile.LuckPillars
+		if luck == nil || luck.Direction != tc.direction || math.Abs(luck.StartAge-tc.startAge) > 0.15 || len(luck.Pillars) != 8 {
+			t.Fatalf("%s: got %+v, want %s from %.1f", tc.gender, luck, tc.direction, tc.startAge)
+		}
+		var names []string
+		for i, p := range luck.Pillars[:3] {
+			names = append(names, p.Pillar)
+			if p.StartAge != math.Round((luck.StartAge+float64(10*i))*10)/10 {
+				t.Errorf("%s: pillar %d starts at %.1f", tc.gender, i, p.StartAge)
+			}
+		}
+		if !reflect.DeepEqual(names, tc.first) {
+			t.Errorf("%s: pillars %v, want %v", tc.gender, names, tc.first)
+		}
+		if luck.Current != nil {
+			t.Error("a profile without reference date should have no current pillar")
+		}
+	}
+
+	// A Yin year reverses the directions
+	yin := hcs.BirthInfo{Year: 1991, Month: 6, Day: 15, Timezone: "UTC", Gender: "male"}
+	if profile, _ := hcs.ComputeChineseProfile(yin); profile.LuckPillars.Direction != "backward" {
+		t.Errorf("a man born in a Xin year should go backward, got %s", profile.LuckPillars.Direction)
+	}
+
+	if profile, _ := hcs.ComputeChineseProfile(hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Timezone: "UTC"}); profile.LuckPillars != nil {
+		t.Error("the Luck Pillars need a gender")
+	}
+	if _, err := hcs.ComputeChineseProfile(hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Gender: "m"}); err == nil {
+		t.Error("an unknown gender should be rejected")
+	}
+}
+
+// TestCurrentLuckPillar verifies the pillar running at a reference date, in
+// the profile and in generated outputs.
+func TestCurrentLuckPillar(t *testing.T) {
+	birth := hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Hour: 14, Minute: 30, Timezone: "UTC", Gender: "male"}
+	for date, want := range map[string]string{
+		"1995-01-01": "",
+		"2000-01-01": "Gui-Wei",
+		"2024-01-01": "Yi-You",
+		"2070-01-01": "Geng-Yin",
+		"2078-01-01": "",
+	} {
+		reference, _ := time.Parse(time.DateOnly, date)
+		profile, err := hcs.ComputeChineseProfileAt(birth, hcs.CurrentEngineVersion, reference)
+		if err != nil {
+			t.Fatalf("ComputeChineseProfileAt: %v", err)
+		}
+		got := ""
+		if profile.LuckPillars.Current != nil {
+			got = profile.LuckPillars.Current.Pillar
+		}
+		if got != want || profile.LuckPillars.ReferenceDate != date {
+			t.Errorf("current pillar at %s = %q, want %q", date, got, want)
+		}
+	}
+
+	setTestSecretKey(t)
+	frozen := clock.NewFrozen(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	gen, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()), hcs.WithClock(frozen), hcs.WithCache(8))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	input := getTestInput()
+	input.BirthInfo = &birth
+	out, err := gen.Generate(input)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if c := out.ChineseProfile.LuckPillars.Current; c == nil || c.Pillar != "Yi-You" {
+		t.Errorf("the current pillar should follow the clock, got %+v", c)
+	}
+	if violations, err := schema.ValidateOutput(out); err != nil || len(violations) != 0 {
+		t.Errorf("an output with Luck Pillars should match the schema: %v, %v", violations, err)
+	}
+
+	// The cached profile is not dated by an earlier generation
+	dated, _ := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{ReferenceDate: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if c := dated.ChineseProfile.LuckPillars.Current; c == nil || c.Pillar != "Gui-Wei" {
+		t.Errorf("the reference date should select the current pillar, got %+v", c)
+	}
+	if c := out.ChineseProfile.LuckPillars.Current; c.Pillar != "Yi-You" {
+		t.Errorf("a later generation changed an earlier output: %+v", c)
+	}
+
+	// The gender is not encoded
+	input.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Hour: 14, Minute: 30, Timezone: "UTC"}
+	plain, _ := gen.Generate(input)
+	if plain.CodeU5 != out.CodeU5 || plain.CodeU7 != out.CodeU7 || plain.Chip != out.Chip {
+		t.Errorf("the codes should not depend on the gender: %s %s %s / %s %s %s", plain.CodeU5, plain.CodeU7, plain.Chip, out.CodeU5, out.CodeU7, out.Chip)
+	}
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	netmail "net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/mail"
+)
+
+// fakeSMTP accepts one message over plain SMTP and returns its envelope and data
+func fakeSMTP(t *testing.T) (addr string, received <-chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	out := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r, w := bufio.NewReader(conn), conn
+		var lines []string
+		io.WriteString(w, "220 fake ESMTP\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				io.WriteString(w, "250 fake\r\n")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				lines = append(lines, strings.TrimSpace(line))
+				io.WriteString(w, "250 OK\r\n")
+			case cmd == "DATA":
+				io.WriteString(w, "354 go ahead\r\n")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(strings.TrimPrefix(l, "."))
+				}
+				lines = append(lines, data.String())
+				io.WriteString(w, "250 queued\r\n")
+			case cmd == "QUIT":
+				io.WriteString(w, "221 bye\r\n")
+				out <- lines
+				return
+			default:
+				io.WriteString(w, "502 unsupported\r\n")
+			}
+		}
+	}()
+	return ln.Addr().String(), out
+}
+
+// TestMailSend verifies that a report is relayed as a multipart message with
+// its text and HTML renderings.
+func TestMailSend(t *testing.T) {
+	addr, received := fakeSMTP(t)
+	s, err := mail.NewSender(addr, "HCS Lab <lab@example.com>")
+	if err != nil {
+		t.Fatalf("failed to create sender: %v", err)
+	}
+	if err := s.Send(context.Background(), "subject@example.org", "Your HCS profile", "plain body", "<p>html body</p>"); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+
+	var lines []string
+	select {
+	case lines = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the relay received nothing")
+	}
+	if len(lines) != 3 || lines[0] != "MAIL FROM:<lab@example.com>" || lines[1] != "RCPT TO:<subject@example.org>" {
+		t.Fatalf("unexpected envelope: %q", lines)
+	}
+	msg, err := netmail.ReadMessage(strings.NewReader(lines[2]))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	if msg.Header.Get("Subject") != "Your HCS profile" || msg.Header.Get("To") != "<subject@example.org>" {
+		t.Errorf("unexpected headers: %v", msg.Header)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type %q: %v", msg.Header.Get("Content-Type"), err)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", "plain body"},
+		{"text/html; charset=utf-8", "<p>html body</p>"},
+	} {
+		part, err := parts.NextPart() // decodes quoted-printable
+		if err != nil {
+			t.Fatalf("missing %s part: %v", want.contentType, err)
+		}
+		body, _ := io.ReadAll(part)
+		if part.Header.Get("Content-Type") != want.contentType || string(body) != want.body {
+			t.Errorf("unexpected part %v: %q", part.Header, body)
+		}
+	}
+}
+
+// TestMailRejectsAddresses verifies that addresses cannot inject headers.
+func TestMailRejectsAddresses(t *testing.T) {
+	if _, err := mail.NewSender("relay.example.com", "lab@example.com"); err == nil {
+		t.Error("an address without a port should be rejected")
+	}
+	if _, err := mail.NewSender("relay.example.com:587", "not an address"); err == nil {
+		t.Error("an invalid sender should be rejected")
+	}
+	msg := &mail.Message{From: "lab@example.com", To: "a@example.org\r\nBcc: b@example.org", Subject: "x"}
+	if _, err := msg.Bytes(time.Now()); err == nil {
+		t.Error("a recipient with a line break should be rejected")
+	}
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"math"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestMergeProfiles verifies the weighted consensus of a self-assessment and
+// two peer assessments, and the dispersion report.
+func TestMergeProfiles(t *testing.T) {
+	self := *getTestInput()
+	peer1 := *getTestInput()
+	peer1.DominantElement = "Fire"
+	peer1.Cognition.Fluid = 0.92
+	peer1.Interaction.Tone = "warm"
+	peer2 := *getTestInput()
+	peer2.Cognition.Fluid = 0.12
+	peer2.Interaction.Tone = "warm"
+
+	ratings := []hcs.Rating{
+		{Rater: "self", Weight: 2, Profile: self},
+		{Rater: "peer-1", Profile: peer1},
+		{Rater: "peer-2", Profile: peer2},
+	}
+	merged, err := hcs.MergeProfiles(ratings, hcs.MergeOptions{})
+	if err != nil {
+		t.Fatalf("MergeProfiles failed: %v", err)
+	}
+	c := merged.Consensus
+	d := merged.Dispersion
+
+	// Weights 2:1:1, so fluid is (2*0.52 + 0.92 + 0.12) / 4
+	if math.Abs(c.Cognition.Fluid-0.52) > 1e-9 || c.Cognition.Verbal != self.Cognition.Verbal {
+		t.Errorf("consensus cognition = %+v", c.Cognition)
+	}
+	if c.DominantElement != "Air" || c.ElementBalance != nil {
+		t.Errorf("consensus element = %s %v, want Air without a balance", c.DominantElement, c.ElementBalance)
+	}
+	// warm and precise tie at half the weight each; the tie goes to the earlier value
+	if c.Interaction.Tone != "warm" || c.Interaction.Pace != "balanced" {
+		t.Errorf("consensus interaction = %+v", c.Interaction)
+	}
+
+	if d.Raters != 3 || len(d.Fields) != 8 || len(d.Choices) != 4 || len(d.Distances) != 3 {
+		t.Fatalf("unexpected dispersion report shape: %+v", d)
+	}
+	fluid := d.Fields[3]
+	if fluid.Field != "cognition.fluid" || fluid.Min != 0.12 || fluid.Max != 0.92 || fluid.StdDev < 0.28 || fluid.StdDev > 0.29 {
+		t.Errorf("fluid dispersion = %+v", fluid)
+	}
+	if d.Fields[0].StdDev != 0 {
+		t.Errorf("unanimous field has dispersion: %+v", d.Fields[0])
+	}
+	element := d.Choices[0]
+	if element.Value != "Air" || element.Agreement != 0.75 || element.Votes["Fire"] != 0.25 {
+		t.Errorf("element agreement = %+v", element)
+	}
+	want := map[string]bool{"cognition.fluid": true}
+	if len(d.Disputed) != len(want) || !want[d.Disputed[0]] {
+		t.Errorf("disputed = %v, want %v", d.Disputed, want)
+	}
+	if d.Distances[0].Distance >= d.Distances[1].Distance {
+		t.Errorf("the weighted self rating should be closest to the consensus: %+v", d.Distances)
+	}
+
+	// The consensus generates like any profile
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	if _, err := gen.Generate(&c); err != nil {
+		t.Errorf("consensus profile does not generate: %v", err)
+	}
+}
+
+// TestMergeProfilesElementBalance verifies that element balances are averaged,
+// with raters who give only a dominant element counting as all in it.
+func TestMergeProfilesElementBalance(t *testing.T) {
+	a := *getTestInput()
+	a.DominantElement = ""
+	a.ElementBalance = map[string]float64{"Fire": 0.6, "Water": 0.4}
+	b := *getTestInput()
+	b.DominantElement = "Water"
+
+	merged, err := hcs.MergeProfiles([]hcs.Rating{{Rater: "a", Profile: a}, {Rater: "b", Profile: b}}, hcs.MergeOptions{})
+	if err != nil {
+		t.Fatalf("MergeProfiles failed: %v", err)
+	}
+	if c := merged.Consensus; c.DominantElement != "Water" || c.ElementBalance["Water"] != 0.7 || c.ElementBalance["Fire"] != 0.3 {
+		t.Errorf("consensus element = %s %v", c.DominantElement, c.ElementBalance)
+	}
+}
+
+// TestMergeProfilesErrors verifies the rejected merges.
+func TestMergeProfilesErrors(t *testing.T) {
+	valid := *getTestInput()
+	invalid := *getTestInput()
+	invalid.Cognition.Fluid = 2
+	born := *getTestInput()
+	born.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 5, Day: 1, Hour: 12, Timezone: "UTC"}
+	bornLater := *getTestInput()
+	bornLater.BirthInfo = &hcs.BirthInfo{Year: 1991, Month: 5, Day: 1, Hour: 12, Timezone: "UTC"}
+
+	cases := map[string]struct {
+		ratings []hcs.Rating
+		opts    hcs.MergeOptions
+		want    errcode.Code
+	}{
+		"single":    {[]hcs.Rating{{Profile: valid}}, hcs.MergeOptions{}, errcode.InvalidRequest},
+		"invalid":   {[]hcs.Rating{{Profile: valid}, {Profile: invalid}}, hcs.MergeOptions{}, errcode.InvalidCognition},
+		"weight":    {[]hcs.Rating{{Profile: valid}, {Profile: valid, Weight: -1}}, hcs.MergeOptions{}, errcode.InvalidRequest},
+		"birth":     {[]hcs.Rating{{Profile: born}, {Profile: valid}, {Profile: bornLater}}, hcs.MergeOptions{}, errcode.InvalidBirthInfo},
+		"agreement": {[]hcs.Rating{{Profile: valid}, {Profile: valid}}, hcs.MergeOptions{MinAgreement: 2}, errcode.InvalidRequest},
+	}
+	for name, c := range cases {
+		if _, err := hcs.MergeProfiles(c.ratings, c.opts); errcode.Of(err, "") != c.want {
+			t.Errorf("%s: got %v, want %s", name, err, c.want)
+		}
+	}
+}
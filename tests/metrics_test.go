@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/metrics"
+)
+
+// TestMetricsWindow verifies nearest-rank percentiles and that the window
+// keeps only the most recent samples.
+func TestMetricsWindow(t *testing.T) {
+	w := metrics.NewWindow(100)
+	if s := w.Summary(); s.Samples != 0 || s.P99 != 0 {
+		t.Errorf("empty window: %+v", s)
+	}
+	for i := 1; i <= 100; i++ {
+		w.Observe(time.Duration(i) * time.Millisecond)
+	}
+	s := w.Summary()
+	if s.Total != 100 || s.P50 != 50 || s.P90 != 90 || s.P99 != 99 || s.Max != 100 {
+		t.Errorf("unexpected summary: %+v", s)
+	}
+
+	// A full window drops its oldest samples
+	for i := 0; i < 100; i++ {
+		w.Observe(time.Second)
+	}
+	s = w.Summary()
+	if s.Total != 200 || s.Samples != 100 || s.P50 != 1000 {
+		t.Errorf("window should hold only the last 100 samples: %+v", s)
+	}
+}
+
+// TestMetricsDays verifies that per-day counts are listed oldest first,
+// including days without events.
+func TestMetricsDays(t *testing.T) {
+	d := metrics.NewDays()
+	now := time.Date(2025, 3, 3, 23, 30, 0, 0, time.UTC)
+	d.Add(now, 2)
+	d.Add(now.Add(-48*time.Hour), 1)
+	d.Add(now.Add(-30*24*time.Hour), 5) // outside the window
+
+	got := d.Last(now, 3)
+	want := []metrics.DayCount{{Date: "2025-03-01", Count: 1}, {Date: "2025-03-02", Count: 0}, {Date: "2025-03-03", Count: 2}}
+	if len(got) != len(want) {
+		t.Fatalf("got %d days, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("day %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/corehuman/hcs-lab-api/internal/migrate"
+)
+
+// TestMigrationLoad verifies that migration files are paired, ordered and checked for gaps.
+func TestMigrationLoad(t *testing.T) {
+	file := func(sql string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(sql)} }
+
+	migrations, err := migrate.Load(fstest.MapFS{
+		"0002_add_index.up.sql":        file("CREATE INDEX i ON records (subject_id);"),
+		"0002_add_index.down.sql":      file("DROP INDEX i;"),
+		"0001_create_records.up.sql":   file("CREATE TABLE records (chip TEXT);"),
+		"0001_create_records.down.sql": file("DROP TABLE records;"),
+		"README.md":                    file("ignored"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Name != "create_records" || migrations[1].Version != 2 {
+		t.Fatalf("unexpected migrations: %+v", migrations)
+	}
+	if migrations[1].Down != "DROP INDEX i;" {
+		t.Errorf("down migration not loaded: %q", migrations[1].Down)
+	}
+
+	invalid := map[string]fstest.MapFS{
+		"missing down": {"0001_a.up.sql": file("SELECT 1;")},
+		"gap": {
+			"0001_a.up.sql": file("SELECT 1;"), "0001_a.down.sql": file("SELECT 1;"),
+			"0003_c.up.sql": file("SELECT 1;"), "0003_c.down.sql": file("SELECT 1;"),
+		},
+		"bad name":      {"0001_Create-Table.up.sql": file("SELECT 1;")},
+		"renamed down":  {"0001_a.up.sql": file("SELECT 1;"), "0001_b.down.sql": file("SELECT 1;")},
+		"empty up file": {"0001_a.up.sql": file(" \n"), "0001_a.down.sql": file("SELECT 1;")},
+	}
+	for name, fsys := range invalid {
+		if _, err := migrate.Load(fsys); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestModalSumValidation verifies the lenient, strict and normalizing modal checks.
+func TestModalSumValidation(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	skewed := func() *hcs.InputProfile {
+		input := getTestInput()
+		input.Modal = hcs.ModalBalance{Cardinal: 0.6, Fixed: 0.6, Mutable: 0.3}
+		return input
+	}
+
+	// Off by default: any sum is accepted silently
+	out, err := gen.Generate(skewed())
+	if err != nil || len(out.Warnings) != 0 {
+		t.Fatalf("default check should accept silently: %v, %v", out, err)
+	}
+
+	lenient := &hcs.GeneratorOptions{ModalValidation: hcs.ModalValidation{Check: hcs.ModalCheckLenient}}
+	out, err = gen.GenerateWithOptions(skewed(), lenient)
+	if err != nil {
+		t.Fatalf("lenient check should accept: %v", err)
+	}
+	if len(out.Warnings) != 1 || !strings.Contains(out.Warnings[0], "sum to 1.5") {
+		t.Errorf("lenient check should warn about the sum, got %v", out.Warnings)
+	}
+	if out, _ := gen.GenerateWithOptions(getTestInput(), lenient); len(out.Warnings) != 0 {
+		t.Errorf("a sum of 1 should not warn, got %v", out.Warnings)
+	}
+
+	strict := &hcs.GeneratorOptions{ModalValidation: hcs.ModalValidation{Check: hcs.ModalCheckStrict}}
+	if _, err := gen.GenerateWithOptions(skewed(), strict); err == nil {
+		t.Error("strict check should reject a sum of 1.5")
+	}
+	loose := &hcs.GeneratorOptions{ModalValidation: hcs.ModalValidation{Check: hcs.ModalCheckStrict, Tolerance: 0.6}}
+	if _, err := gen.GenerateWithOptions(skewed(), loose); err != nil {
+		t.Errorf("a sum within the tolerance should pass: %v", err)
+	}
+
+	normalize := &hcs.GeneratorOptions{ModalValidation: hcs.ModalValidation{Check: hcs.ModalCheckStrict, Normalize: true}}
+	out, err = gen.GenerateWithOptions(skewed(), normalize)
+	if err != nil {
+		t.Fatalf("normalization should replace the check: %v", err)
+	}
+	m := out.Input.Modal
+	if math.Abs(m.Cardinal+m.Fixed+m.Mutable-1) > 1e-9 || math.Abs(m.Cardinal-0.4) > 1e-9 {
+		t.Errorf("modal values should be rescaled proportionally, got %+v", m)
+	}
+	if len(out.Warnings) != 1 || !strings.Contains(out.Warnings[0], "rescaled") {
+		t.Errorf("normalization should be reported, got %v", out.Warnings)
+	}
+
+	zero := getTestInput()
+	zero.Modal = hcs.ModalBalance{}
+	if _, err := gen.GenerateWithOptions(zero, normalize); err == nil {
+		t.Error("all-zero modal values cannot be normalized")
+	}
+	if _, err := gen.GenerateWithOptions(getTestInput(), &hcs.GeneratorOptions{ModalValidation: hcs.ModalValidation{Check: "loose"}}); err == nil {
+		t.Error("an unknown modal check should be rejected")
+	}
+}
+
+// TestModalAutoNormalizePercentages verifies that modal values sent as
+// percentages are rescaled before the 0-1 range check.
+func TestModalAutoNormalizePercentages(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	percentages := getTestInput()
+	percentages.Modal = hcs.ModalBalance{Cardinal: 31, Fixed: 23, Mutable: 46}
+
+	if _, err := gen.Generate(percentages); err == nil {
+		t.Fatal("percentages should fail the range check without normalization")
+	}
+	out, err := gen.GenerateWithOptions(percentages, &hcs.GeneratorOptions{ModalValidation: hcs.ModalValidation{Normalize: true}})
+	if err != nil {
+		t.Fatalf("normalized percentages should be accepted: %v", err)
+	}
+	want, _ := gen.Generate(getTestInput())
+	if out.Chip != want.Chip {
+		t.Errorf("normalized percentages should yield the CHIP of the equivalent shares: %s != %s", out.Chip, want.Chip)
+	}
+	if len(out.Warnings) != 1 || !strings.Contains(out.Warnings[0], "cardinal 31 -> 0.31") {
+		t.Errorf("the warning should record the adjustment, got %v", out.Warnings)
+	}
+}
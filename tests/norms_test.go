@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/schema"
+)
+
+// TestNormsPercentile verifies ranking values against the reference norms.
+func TestNormsPercentile(t *testing.T) {
+	norms := hcs.ReferenceNorms()
+	if err := norms.Validate(); err != nil {
+		t.Fatalf("the reference norms should be valid: %v", err)
+	}
+	for value, want := range map[float64]int{0: 0, 0.55: 50, 0.72: 84, 0.9: 97, 1: 100} {
+		got, err := norms.Percentile("fluid", value)
+		if err != nil || got != want {
+			t.Errorf("Percentile(fluid, %g) = %d, %v; want %d", value, got, err, want)
+		}
+	}
+	if _, err := norms.Percentile("memory", 0.5); err == nil {
+		t.Error("an unknown dimension should fail")
+	}
+
+	// Values equal to a run of quantiles rank in its middle
+	flat := &hcs.Norms{ID: "flat", Quantiles: map[string][]float64{}}
+	for _, dim := range hcs.NormsDimensions {
+		flat.Quantiles[dim] = []float64{0, 0.5, 0.5, 0.5, 1}
+	}
+	if got, _ := flat.Percentile("verbal", 0.5); got != 50 {
+		t.Errorf("a tied value should rank at 50, got %d", got)
+	}
+
+	for name, invalid := range map[string]*hcs.Norms{
+		"missing dimension": {ID: "x", Quantiles: map[string][]float64{"fluid": {0, 1}}},
+		"descending":        {ID: "x", Quantiles: withQuantiles([]float64{0, 0.6, 0.4, 1})},
+		"out of range":      {ID: "x", Quantiles: withQuantiles([]float64{0, 1.5})},
+		"single quantile":   {ID: "x", Quantiles: withQuantiles([]float64{0.5})},
+		"no ID":             {Quantiles: withQuantiles([]float64{0, 1})},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("norms with a %s should be invalid", name)
+		}
+	}
+}
+
+func withQuantiles(q []float64) map[string][]float64 {
+	out := map[string][]float64{}
+	for _, dim := range hcs.NormsDimensions {
+		out[dim] = q
+	}
+	return out
+}
+
+// TestNormsFromProfiles verifies norms recalculated from a sample.
+func TestNormsFromProfiles(t *testing.T) {
+	profiles := make([]hcs.CognitionProfile, 101)
+	for i := range profiles {
+		v := float64(i) / 100
+		profiles[i] = hcs.CognitionProfile{Fluid: v, Crystallized: v, Verbal: v, Strategic: 1 - v, Creative: v / 2}
+	}
+	norms, err := hcs.NormsFromProfiles("tenant:acme", profiles)
+	if err != nil {
+		t.Fatalf("NormsFromProfiles: %v", err)
+	}
+	if norms.Sample != 101 || len(norms.Quantiles["verbal"]) != 21 {
+		t.Errorf("unexpected norms %+v", norms)
+	}
+	p := norms.Rank(hcs.CognitionProfile{Fluid: 0.72, Crystallized: 0.72, Verbal: 0.72, Strategic: 0.72, Creative: 0.25})
+	if p.Norms != "tenant:acme" || p.Cognition["verbal"] != 72 || p.Cognition["strategic"] != 72 || p.Cognition["creative"] != 50 {
+		t.Errorf("unexpected percentiles %+v", p)
+	}
+	if _, err := hcs.NormsFromProfiles("tenant:tiny", profiles[:1]); err == nil {
+		t.Error("a single profile should not make norms")
+	}
+}
+
+// TestGeneratePercentiles verifies that percentiles are only added when norms
+// are requested, and match the output schema.
+func TestGeneratePercentiles(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	input := getTestInput()
+	out, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{Norms: hcs.ReferenceNorms()})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if out.Percentiles == nil || out.Percentiles.Norms != hcs.ReferenceNormsID || len(out.Percentiles.Cognition) != 5 {
+		t.Fatalf("expected percentiles against the reference norms, got %+v", out.Percentiles)
+	}
+	want, _ := hcs.ReferenceNorms().Percentile("verbal", input.Cognition.Verbal)
+	if out.Percentiles.Cognition["verbal"] != want {
+		t.Errorf("verbal percentile = %d, want %d", out.Percentiles.Cognition["verbal"], want)
+	}
+	if violations, err := schema.ValidateOutput(out); err != nil || len(violations) != 0 {
+		t.Errorf("an output with percentiles should match the schema: %v, %v", violations, err)
+	}
+
+	if plain, _ := gen.Generate(getTestInput()); plain.Percentiles != nil || plain.CodeU7 != out.CodeU7 {
+		t.Error("percentiles should only be added when requested, without changing the codes")
+	}
+	if _, err := gen.GenerateWithOptions(getTestInput(), &hcs.GeneratorOptions{Norms: &hcs.Norms{ID: "empty"}}); err == nil {
+		t.Error("invalid norms should be rejected")
+	}
+}
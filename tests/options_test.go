@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+type fixedSalt []byte
+
+func (s fixedSalt) Salt() ([]byte, error) { return s, nil }
+
+func TestGeneratorOptions(t *testing.T) {
+	setTestSecretKey(t)
+	salt := make(fixedSalt, 32)
+
+	plain, err := hcs.NewGenerator(hcs.WithSaltProvider(salt))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	cached, err := hcs.NewGenerator(hcs.WithSaltProvider(salt), hcs.WithCache(4), hcs.WithEngineVersion(hcs.CurrentEngineVersion))
+	if err != nil {
+		t.Fatalf("failed to create cached generator: %v", err)
+	}
+
+	input := getTestInput()
+	input.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Hour: 14, Minute: 30, Timezone: "UTC"}
+
+	want, err := plain.Generate(input)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		got, err := cached.Generate(input)
+		if err != nil {
+			t.Fatalf("failed to generate with cache: %v", err)
+		}
+		if got.Chip != want.Chip || got.CodeU7 != want.CodeU7 ||
+			got.ChineseProfile.ElementBalance["Wood"] != want.ChineseProfile.ElementBalance["Wood"] {
+			t.Errorf("run %d: cached generator output differs", i)
+		}
+		// Outputs must not share the cached profile
+		got.ChineseProfile.ElementBalance["Wood"] = -1
+	}
+
+	invalid := []hcs.Option{
+		hcs.WithEngineVersion("v0"),
+		hcs.WithFusionConfig("missing"),
+		hcs.WithCache(-1),
+		hcs.WithSecretProvider(nil),
+	}
+	for _, opt := range invalid {
+		if _, err := hcs.NewGenerator(hcs.WithSaltProvider(salt), opt); err == nil {
+			t.Errorf("expected error for invalid option")
+		}
+	}
+}
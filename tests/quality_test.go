@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestQualityScore verifies the input confidence score and its reasons.
+func TestQualityScore(t *testing.T) {
+	careful := getTestInput()
+	careful.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Hour: 14, Minute: 30, Timezone: "Europe/Paris"}
+	if q := hcs.ScoreQuality(careful); q.Score != 1 || len(q.Reasons) != 0 {
+		t.Errorf("a carefully filled profile should score 1, got %+v", q)
+	}
+
+	untouched := &hcs.InputProfile{
+		DominantElement: "Fire",
+		Modal:           hcs.ModalBalance{Cardinal: 0.5, Fixed: 0.5, Mutable: 0.5},
+		Cognition:       hcs.CognitionProfile{Fluid: 0.5, Crystallized: 0.5, Verbal: 0.5, Strategic: 0.5, Creative: 0.5},
+		BirthInfo:       &hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Hour: 12, Minute: 0, Timezone: "UTC"},
+	}
+	q := hcs.ScoreQuality(untouched)
+	if q.Score != 0 {
+		t.Errorf("an untouched form should score 0, got %v", q.Score)
+	}
+	for _, want := range []string{"cognition values are 0.5", "modal values are all equal", "sum to 1.5", "incomplete", "noon", "minute is 0", "timezone is UTC"} {
+		found := false
+		for _, reason := range q.Reasons {
+			found = found || strings.Contains(reason, want)
+		}
+		if !found {
+			t.Errorf("missing reason %q in %v", want, q.Reasons)
+		}
+	}
+
+	setTestSecretKey(t)
+	gen, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	input := getTestInput()
+	input.Interaction.Tone = ""
+	out, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{Quality: true})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if out.Metadata == nil || out.Metadata.Quality == nil || out.Metadata.Quality.Score != 0.95 {
+		t.Errorf("the score should reflect the input before defaults were applied, got %+v", out.Metadata)
+	}
+	if plain, _ := gen.Generate(getTestInput()); plain.Metadata != nil {
+		t.Errorf("the score should only be recorded when requested, got %+v", plain.Metadata)
+	}
+}
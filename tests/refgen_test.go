@@ -0,0 +1,27 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/refgen"
+)
+
+// TestReferenceTablesUpToDate fails when the Go tables change without
+// regenerating the ports (run `go generate ./internal/hcs`).
+func TestReferenceTablesUpToDate(t *testing.T) {
+	files := map[string][]byte{
+		"../ports/python/hcs_tables.py":    refgen.Python(),
+		"../ports/typescript/hcsTables.ts": refgen.TypeScript(),
+	}
+	for path, want := range files {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is stale; run go generate ./internal/hcs", path)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/report"
+)
+
+// TestReport verifies that reports show the codes, profile and verification link.
+func TestReport(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	out, err := gen.GenerateWithOptions(getTestInput(), &hcs.GeneratorOptions{ValidityMonths: 12})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	rep := report.New(out, "https://lab.example.com/verify/{chip}?ref=<mail>")
+	html, err := rep.HTML()
+	if err != nil {
+		t.Fatalf("failed to render HTML: %v", err)
+	}
+	text, err := rep.Text()
+	if err != nil {
+		t.Fatalf("failed to render text: %v", err)
+	}
+	for name, body := range map[string]string{"html": html, "text": text} {
+		for _, want := range []string{out.Chip, out.Archetype.Name, "valid until", "/verify/" + out.Chip} {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s report is missing %q:\n%s", name, want, body)
+			}
+		}
+	}
+	if !strings.Contains(text, out.CodeU3) || !strings.Contains(html, "<code style=\"word-break: break-all;\">"+out.CodeU3) {
+		t.Errorf("reports should show the U3 code")
+	}
+	if strings.Contains(html, "<mail>") {
+		t.Error("the HTML report should escape the verification link")
+	}
+
+	if html, _ := report.New(out, "").HTML(); strings.Contains(html, "href") {
+		t.Error("reports without a verification URL should have no link")
+	}
+}
+
+// TestReportTemplateOverrides verifies that override templates replace the
+// built-in ones, and that templates escaping the sandbox or failing on a
+// sample report are rejected when loaded.
+func TestReportTemplateOverrides(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if _, err := report.Load(dir); err == nil {
+		t.Error("a directory without templates should be rejected")
+	}
+
+	write(report.TextFile, "Codes of {{.Output.Chip}}:{{range codes .Output}} {{.}}{{end}}")
+	templates, err := report.Load(dir)
+	if err != nil {
+		t.Fatalf("failed to load templates: %v", err)
+	}
+	if len(templates.Overrides) != 1 {
+		t.Errorf("overrides = %v", templates.Overrides)
+	}
+	sample := report.Samples()[0].Report
+	text, err := templates.Text(sample)
+	if err != nil || !strings.HasPrefix(text, "Codes of "+sample.Output.Chip+": HCS-U3|") {
+		t.Errorf("unexpected override rendering %q: %v", text, err)
+	}
+	builtin, _ := sample.HTML()
+	if html, err := templates.HTML(sample); err != nil || html != builtin {
+		t.Errorf("the HTML template should stay the built-in one: %v", err)
+	}
+
+	for name, src := range map[string]string{
+		"define":        `{{define "x"}}x{{end}}{{template "x"}}`,
+		"invoke":        `{{template "report" .}}`,
+		"number range":  `{{range 1000000000}}{{end}}`,
+		"nested ranges": `{{range codes .Output}}{{range codes $.Output}}{{range codes $.Output}}{{end}}{{end}}{{end}}`,
+		"recursion":     `{{.HTML}}`,
+		"optional data": `{{.Output.Archetype.Name}}`,
+		"unknown func":  `{{exec "ls"}}`,
+		"too large":     `{{range codes .Output}}{{printf "%999999d" 1}}{{end}}`,
+	} {
+		write(report.TextFile, src)
+		if _, err := report.Load(dir); err == nil {
+			t.Errorf("%s: the template should be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestCompareRetest verifies the stability report between two generations of a subject.
+func TestCompareRetest(t *testing.T) {
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	generate := func(in *hcs.InputProfile) *hcs.OutputHCS {
+		out, err := gen.Generate(in)
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		return out
+	}
+
+	baseline := generate(getTestInput())
+
+	// A sub-point change normalizes to the same values and keeps the CHIP
+	noise := getTestInput()
+	noise.Cognition.Fluid += 0.004
+	r := hcs.CompareRetest(baseline, generate(noise))
+	if !r.Stable || r.ChipChanged || len(r.Changes) != 0 || len(r.ChangedSegments) != 0 {
+		t.Errorf("sub-point noise should be stable, got %+v", r)
+	}
+
+	moved := getTestInput()
+	moved.Cognition.Verbal = 0.61
+	moved.Interaction.Tone = "warm"
+	r = hcs.CompareRetest(baseline, generate(moved))
+	if r.Stable || !r.ChipChanged || len(r.Changes) != 2 {
+		t.Fatalf("expected verbal and tone changes, got %+v", r)
+	}
+	if c := r.Changes[0]; c.Field != "cognition.verbal" || c.Previous != "53" || c.Current != "61" || c.Delta != 8 {
+		t.Errorf("unexpected verbal change: %+v", c)
+	}
+	if c := r.Changes[1]; c.Field != "interaction.tone" || c.Delta != 0 {
+		t.Errorf("unexpected tone change: %+v", c)
+	}
+	if r.Fusion != nil {
+		t.Error("no fusion drift without birth info")
+	}
+
+	// Fusion drift is reported when both generations have a fusion profile
+	withBirth := func(hour int) *hcs.OutputHCS {
+		in := getTestInput()
+		in.BirthInfo = &hcs.BirthInfo{Year: 1985, Month: 7, Day: 20, Hour: hour, Minute: 45, Timezone: "UTC"}
+		return generate(in)
+	}
+	r = hcs.CompareRetest(withBirth(10), withBirth(10))
+	if r.Fusion == nil || r.Fusion.MaxElementShift != 0 || r.Fusion.PreviousFusionID != r.Fusion.CurrentFusionID {
+		t.Errorf("identical birth info should not drift, got %+v", r.Fusion)
+	}
+	r = hcs.CompareRetest(withBirth(10), withBirth(23))
+	if r.Fusion == nil || r.Fusion.MaxElementShift == 0 {
+		t.Errorf("a different hour pillar should shift the element signature, got %+v", r.Fusion)
+	}
+	if !r.Stable {
+		t.Errorf("birth info is not a normalized field, got %+v", r.Changes)
+	}
+}
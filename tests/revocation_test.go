@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/breaker"
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+// TestStoreRevocations verifies that revocations survive reopening file and
+// SQLite stores, and are reachable through a circuit breaker.
+func TestStoreRevocations(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	for _, dsn := range storeDSNs(dir) {
+		open := func() store.Revocations {
+			s, err := store.Open(dsn)
+			if err != nil {
+				t.Fatalf("%s: failed to open store: %v", dsn, err)
+			}
+			if m, ok := s.(store.Migrator); ok {
+				if _, err := m.Migrations().Up(ctx, now); err != nil {
+					t.Fatalf("%s: failed to migrate: %v", dsn, err)
+				}
+			}
+			revs := store.RevocationsOf(store.WithBreaker(s, breaker.New("storage", 2, time.Hour)))
+			if revs == nil {
+				t.Fatalf("%s: store keeps no revocations", dsn)
+			}
+			return revs
+		}
+
+		revs := open()
+		if _, err := revs.GetRevocation(ctx, "aae673a93e1f"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", dsn, err)
+		}
+		for i, chip := range []string{"bbbbbbbbbbbb", "aae673a93e1f"} {
+			if err := revs.Revoke(ctx, store.Revocation{Chip: chip, Reason: "leaked", RevokedAt: now.Add(time.Duration(i) * time.Hour)}); err != nil {
+				t.Fatalf("%s: Revoke failed: %v", dsn, err)
+			}
+		}
+		revs.Revoke(ctx, store.Revocation{Chip: "bbbbbbbbbbbb", Reason: "mistaken", RevokedAt: now.Add(2 * time.Hour)})
+		if dsn != "memory" {
+			revs = open()
+		}
+
+		rev, err := revs.GetRevocation(ctx, "aae673a93e1f")
+		if err != nil || rev.Reason != "leaked" || !rev.RevokedAt.Equal(now.Add(time.Hour)) {
+			t.Errorf("%s: revocation not persisted: %+v, %v", dsn, rev, err)
+		}
+		list, err := revs.ListRevocations(ctx)
+		if err != nil || len(list) != 2 || list[0].Chip != "aae673a93e1f" || list[1].Reason != "mistaken" {
+			t.Errorf("%s: revocations should be listed by time, got %+v, %v", dsn, list, err)
+		}
+	}
+}
+
+// TestRevocationList verifies that a signed revocation list detects changes
+// and only verifies with the issuing secret.
+func TestRevocationList(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	entries := []hcs.RevokedCHIP{{Chip: "aae673a93e1f", Reason: "leaked", RevokedAt: "2025-03-01T09:00:00Z"}}
+	list, err := gen.SignRevocationList(entries, "test")
+	if err != nil {
+		t.Fatalf("SignRevocationList failed: %v", err)
+	}
+	if err := gen.VerifyRevocationList(list); err != nil {
+		t.Errorf("signed list should verify: %v", err)
+	}
+	if rev, ok := list.Revoked("aae673a93e1f"); !ok || rev.Reason != "leaked" {
+		t.Errorf("Revoked = %+v, %v", rev, ok)
+	}
+	if _, ok := list.Revoked("bbbbbbbbbbbb"); ok {
+		t.Error("unlisted CHIP reported as revoked")
+	}
+
+	// Dropping an entry is the change an attacker would make
+	dropped := *list
+	dropped.Entries = nil
+	if err := gen.VerifyRevocationList(&dropped); !errors.Is(err, hcs.ErrRevocationSignature) {
+		t.Errorf("list without its entry: got %v, want ErrRevocationSignature", err)
+	}
+	unsigned := *list
+	unsigned.Signature = nil
+	if err := gen.VerifyRevocationList(&unsigned); errcode.Of(err, "") != errcode.InvalidRevocationList {
+		t.Errorf("unsigned list: got %v, want %s", err, errcode.InvalidRevocationList)
+	}
+	if list.Signature.PQ != nil {
+		t.Error("no post-quantum signature without a PQ signer")
+	}
+}
+
+// TestRevocationListPostQuantum verifies that offline verifiers can check a
+// revocation list with the published key alone.
+func TestRevocationListPostQuantum(t *testing.T) {
+	skipInFIPSMode(t)
+	setTestSecretKey(t)
+	signer, err := hcs.NewMLDSASigner(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	gen, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()), hcs.WithPQSigner(signer))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	list, err := gen.SignRevocationList([]hcs.RevokedCHIP{{Chip: "aae673a93e1f", RevokedAt: "2025-03-01T09:00:00Z"}}, "test")
+	if err != nil {
+		t.Fatalf("SignRevocationList failed: %v", err)
+	}
+	key, _ := gen.PQPublicKey()
+	if err := hcs.VerifyRevocationListPQSignature(list, key); err != nil {
+		t.Errorf("post-quantum signature should verify: %v", err)
+	}
+	list.Entries[0].Chip = "bbbbbbbbbbbb"
+	if err := hcs.VerifyRevocationListPQSignature(list, key); !errors.Is(err, hcs.ErrRevocationSignature) {
+		t.Errorf("tampered list: got %v, want ErrRevocationSignature", err)
+	}
+}
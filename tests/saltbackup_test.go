@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/saltbackup"
+)
+
+// TestSaltBackup verifies that an encrypted salt backup restores every epoch and seal.
+func TestSaltBackup(t *testing.T) {
+	skipInFIPSMode(t)
+	secrets, err := hcs.NewStaticSecretProvider(bytes.Repeat([]byte{0x42}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	provider := hcs.DirSaltProvider{Dir: dir, Secrets: secrets}
+	if _, err := provider.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	files, err := hcs.SaltFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{".hcs_salt", ".hcs_salt.mac", ".hcs_salt.1", ".hcs_salt.1.mac"}; !reflect.DeepEqual(files, want) {
+		t.Fatalf("unexpected salt files %v", files)
+	}
+
+	operator, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient, err := saltbackup.ParseRecipient(operator.Recipient().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var backup bytes.Buffer
+	if _, err := saltbackup.Backup(dir, &backup, true, time.Now(), recipient); err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	if !strings.HasPrefix(backup.String(), "-----BEGIN AGE ENCRYPTED FILE-----") {
+		t.Error("an armored backup should be PEM encoded")
+	}
+	salt, _ := os.ReadFile(filepath.Join(dir, ".hcs_salt"))
+	if bytes.Contains(backup.Bytes(), salt) {
+		t.Error("the backup should not contain the plaintext salt")
+	}
+
+	restored := filepath.Join(t.TempDir(), "restored")
+	result, err := saltbackup.Restore(restored, bytes.NewReader(backup.Bytes()), false, operator)
+	if err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	if len(result.Written) != 4 {
+		t.Errorf("expected 4 restored files, got %v", result.Written)
+	}
+	for _, name := range files {
+		want, _ := os.ReadFile(filepath.Join(dir, name))
+		got, _ := os.ReadFile(filepath.Join(restored, name))
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s not restored identically", name)
+		}
+	}
+	if _, current, err := (hcs.DirSaltProvider{Dir: restored, Secrets: secrets}).SaltEpochs(); err != nil || current != 1 {
+		t.Errorf("restored salts should load with their seals, got epoch %d, %v", current, err)
+	}
+
+	// Restoring again is a no-op; a differing salt is only replaced with force
+	if result, err := saltbackup.Restore(restored, bytes.NewReader(backup.Bytes()), false, operator); err != nil || len(result.Unchanged) != 4 {
+		t.Errorf("restoring identical files should leave them unchanged, got %+v, %v", result, err)
+	}
+	os.WriteFile(filepath.Join(restored, ".hcs_salt.1"), bytes.Repeat([]byte{1}, 32), 0600)
+	if _, err := saltbackup.Restore(restored, bytes.NewReader(backup.Bytes()), false, operator); err == nil {
+		t.Error("a differing salt should not be replaced without force")
+	}
+	if _, err := saltbackup.Restore(restored, bytes.NewReader(backup.Bytes()), true, operator); err != nil {
+		t.Errorf("force should replace a differing salt: %v", err)
+	}
+
+	stranger, _ := age.GenerateX25519Identity()
+	if _, err := saltbackup.Open(bytes.NewReader(backup.Bytes()), stranger); err == nil {
+		t.Error("another key should not decrypt the backup")
+	}
+	if _, err := saltbackup.Backup(t.TempDir(), &backup, false, time.Now(), recipient); err == nil {
+		t.Error("backing up a directory without salt should fail")
+	}
+}
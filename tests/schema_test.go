@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/schema"
+)
+
+// TestOutputSchema verifies that a generation using every optional feature
+// matches the OutputHCS JSON Schema, and that drift is reported.
+func TestOutputSchema(t *testing.T) {
+	skipInFIPSMode(t)
+	setTestSecretKey(t)
+	signer, err := hcs.NewMLDSASigner(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	cost := hcs.CHIPHardening{Algorithm: hcs.CHIPAlgorithmArgon2id, Time: 1, MemoryKiB: 64, Threads: 1}
+	gen, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()), hcs.WithPQSigner(signer), hcs.WithHardenedCHIP(cost))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+
+	input := getTestInput()
+	input.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Hour: 14, Minute: 30, Timezone: "UTC"}
+	out, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{
+		ValidityMonths:   12,
+		PostQuantum:      true,
+		Trace:            true,
+		KeyDerivation:    hcs.KeyDerivationHKDF,
+		LegacyU7Versions: []string{hcs.CurrentU7Version},
+		Quality:          true,
+	})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	violations, err := schema.ValidateOutput(out)
+	if err != nil {
+		t.Fatalf("ValidateOutput failed: %v", err)
+	}
+	for _, v := range violations {
+		t.Errorf("full output violates the schema: %s", v)
+	}
+
+	u4, err := gen.GenerateWithOptions(getTestInput(), &hcs.GeneratorOptions{U4Only: true})
+	if err != nil {
+		t.Fatalf("failed to generate U4 only: %v", err)
+	}
+	if violations, _ := schema.ValidateOutput(u4); len(violations) != 0 {
+		t.Errorf("U4-only output violates the schema: %v", violations)
+	}
+
+	// An unknown field and a malformed CHIP are both contract breaks
+	data, _ := json.Marshal(out)
+	var doc map[string]any
+	json.Unmarshal(data, &doc)
+	doc["chip"] = "not-a-chip"
+	doc["trace"].(map[string]any)["extra"] = true
+	got := map[string]bool{}
+	for _, v := range schema.Output().Validate(doc) {
+		got[v.Path] = true
+	}
+	for _, path := range []string{"/chip", "/trace/extra"} {
+		if !got[path] {
+			t.Errorf("expected a violation at %s, got %v", path, got)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/scoring"
+)
+
+// TestQuestionnaireScoring verifies item keys, weights and the mapping onto profile values.
+func TestQuestionnaireScoring(t *testing.T) {
+	bank := scoring.DefaultBank()
+
+	// Agree with every forward item and disagree with every reverse one
+	answers := map[string]int{}
+	for _, item := range bank.Items {
+		answers[item.ID] = bank.Scale
+		if item.Reverse {
+			answers[item.ID] = 1
+		}
+	}
+	r, err := bank.Score(answers)
+	if err != nil {
+		t.Fatalf("Score failed: %v", err)
+	}
+	if r.Cognition.Fluid != 1 || r.Cognition.Creative != 1 {
+		t.Errorf("keyed answers should max out cognition, got %+v", r.Cognition)
+	}
+	if r.Interaction.Pace != "fast" || r.Interaction.Structure != "high" {
+		t.Errorf("keyed answers should give fast/high, got %+v", r.Interaction)
+	}
+	if sum := r.Modal.Cardinal + r.Modal.Fixed + r.Modal.Mutable; math.Abs(sum-1) > 1e-3 {
+		t.Errorf("modal values should sum to 1, got %v", sum)
+	}
+	if r.BankVersion != bank.Version || len(r.Unscored) != 0 {
+		t.Errorf("unexpected result metadata: %+v", r)
+	}
+
+	// Weighted mean: forward item (weight 1.5) at 5, reverse item (weight 1) at 5
+	r, err = bank.Score(map[string]int{"cog-fl-1": 5, "cog-fl-2": 5, "int-t-p": 5, "int-t-w": 2})
+	if err != nil {
+		t.Fatalf("Score failed: %v", err)
+	}
+	if r.Cognition.Fluid != 0.6 {
+		t.Errorf("fluid = %v, want (1*1.5 + 0*1)/2.5 = 0.6", r.Cognition.Fluid)
+	}
+	if r.Interaction.Tone != "precise" {
+		t.Errorf("tone = %s, want the most endorsed", r.Interaction.Tone)
+	}
+	if r.Cognition.Verbal != 0.5 || len(r.Unscored) != 9 {
+		t.Errorf("unanswered targets should default and be listed, got %v", r.Unscored)
+	}
+
+	// The result is a valid profile once the element is added
+	input := &hcs.InputProfile{DominantElement: "Water", Modal: r.Modal, Cognition: r.Cognition, Interaction: r.Interaction}
+	if err := hcs.ValidateInput(input); err != nil {
+		t.Errorf("scored values should form a valid profile: %v", err)
+	}
+
+	for name, bad := range map[string]map[string]int{
+		"unknown item": {"nope": 3},
+		"out of scale": {"cog-fl-1": 6},
+		"below scale":  {"cog-fl-1": 0},
+	} {
+		if _, err := bank.Score(bad); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := scoring.LoadBank([]byte(`{"version":"x","scale":5,"items":[{"id":"a","target":"cognition.wisdom","weight":1}]}`)); err == nil {
+		t.Error("an unknown target should be rejected")
+	}
+}
+
+// TestItemBankVersions verifies that banks load by version and built-in versions stay frozen.
+func TestItemBankVersions(t *testing.T) {
+	dir := t.TempDir()
+	bank := `{"version":"test-2","scale":7,"items":[{"id":"a","target":"cognition.fluid","weight":1}]}`
+	if err := os.WriteFile(filepath.Join(dir, "test-2.json"), []byte(bank), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := scoring.LoadBanks(dir); err != nil {
+		t.Fatalf("LoadBanks failed: %v", err)
+	}
+
+	b, err := scoring.LookupBank("test-2")
+	if err != nil || b.Scale != 7 {
+		t.Fatalf("LookupBank(test-2) = %+v, %v", b, err)
+	}
+	r, err := b.Score(map[string]int{"a": 7})
+	if err != nil || r.BankVersion != "test-2" || r.Cognition.Fluid != 1 {
+		t.Errorf("scoring with test-2 = %+v, %v", r, err)
+	}
+
+	if def, err := scoring.LookupBank(""); err != nil || def.Version != scoring.DefaultBankVersion {
+		t.Errorf("an empty version should select the default bank, got %+v, %v", def, err)
+	}
+	if _, err := scoring.LookupBank("1999.1"); err == nil {
+		t.Error("an unknown version should be rejected")
+	}
+
+	// Loading test-2 again is a no-op, but its items cannot change
+	if err := scoring.LoadBanks(dir); err != nil {
+		t.Errorf("reloading the same bank failed: %v", err)
+	}
+	edited := `{"version":"test-2","scale":7,"items":[{"id":"a","target":"cognition.fluid","weight":1,"reverse":true}]}`
+	if err := os.WriteFile(filepath.Join(dir, "test-2.json"), []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scoring.ReadBanks(dir); err == nil {
+		t.Error("ReadBanks should reject new items under a registered version")
+	}
+	edit, err := scoring.LoadBank([]byte(edited))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := scoring.RegisterBank(edit); err == nil {
+		t.Error("RegisterBank should reject new items under a registered version")
+	}
+	if b, _ := scoring.LookupBank("test-2"); b.Items[0].Reverse {
+		t.Error("a rejected bank must not replace the registered one")
+	}
+	if err := os.Remove(filepath.Join(dir, "test-2.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	frozen := `{"version":"` + scoring.DefaultBankVersion + `","scale":3,"items":[]}`
+	if err := os.WriteFile(filepath.Join(dir, "frozen.json"), []byte(frozen), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := scoring.LoadBanks(dir); err == nil {
+		t.Error("a built-in version must not be replaceable")
+	}
+	if _, err := scoring.ReadBanks(dir); err == nil {
+		t.Error("ReadBanks should reject a built-in version")
+	}
+	if scoring.DefaultBank().Scale != 5 {
+		t.Error("the built-in bank changed")
+	}
+
+	versions := scoring.BankVersions()
+	if !sort.StringsAreSorted(versions) || len(versions) < 2 {
+		t.Errorf("BankVersions = %v", versions)
+	}
+}
@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+	"github.com/corehuman/hcs-lab-api/internal/secretstore"
+)
+
+const (
+	keyV1 = "1111111111111111111111111111111111111111111111111111111111111111"
+	keyV2 = "2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+// TestVaultSecretProvider verifies fetching, TTL caching, rotation and outages.
+func TestVaultSecretProvider(t *testing.T) {
+	var current atomic.Value
+	current.Store(keyV1)
+	var fetches, down atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if r.URL.Path != "/v1/secret/data/hcs" || r.Header.Get("X-Vault-Token") != "vt" || down.Load() == 1 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"data": map[string]any{"key": current.Load()}}})
+	}))
+	defer srv.Close()
+
+	clk := clock.NewFrozen(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	p := secretstore.NewProvider(secretstore.NewVault(srv.URL+"/", "vt", "/secret/data/hcs", ""), time.Minute)
+	p.Clock = clk
+
+	key, err := p.SecretKey()
+	if err != nil || key[0] != 0x11 {
+		t.Fatalf("unexpected key %x, %v", key, err)
+	}
+	p.SecretKey()
+	if fetches.Load() != 1 {
+		t.Errorf("the key should be cached for the TTL, got %d fetches", fetches.Load())
+	}
+
+	current.Store(keyV2)
+	clk.Advance(time.Minute)
+	if key, _ := p.SecretKey(); key[0] != 0x22 {
+		t.Error("a rotated key should be picked up after the TTL")
+	}
+
+	down.Store(1)
+	clk.Advance(time.Minute)
+	if key, err := p.SecretKey(); err != nil || key[0] != 0x22 {
+		t.Errorf("an outage should keep the previous key, got %x, %v", key, err)
+	}
+	if _, err := p.Reload(); err == nil {
+		t.Error("an explicit reload should report the outage")
+	}
+
+	empty := secretstore.NewProvider(secretstore.NewVault(srv.URL, "wrong", "secret/data/hcs", ""), 0)
+	if _, err := empty.SecretKey(); err == nil {
+		t.Error("a provider that never fetched a key should fail")
+	}
+}
+
+// TestAWSSecretProvider verifies the signed GetSecretValue call and JSON secrets.
+func TestAWSSecretProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" || r.Header.Get("X-Amz-Security-Token") != "st" ||
+			!strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/20240115/eu-west-1/secretsmanager/aws4_request, ") ||
+			!strings.Contains(auth, "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target, ") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		secret, _ := json.Marshal(map[string]string{"hcs": keyV1})
+		json.NewEncoder(w).Encode(map[string]string{"Name": in["SecretId"], "SecretString": string(secret)})
+	}))
+	defer srv.Close()
+
+	fetcher := &secretstore.AWS{Region: "eu-west-1", SecretID: "hcs/prod", Field: "hcs", AccessKeyID: "AKID",
+		SecretAccessKey: "secret", SessionToken: "st", Endpoint: srv.URL,
+		Clock: clock.NewFrozen(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))}
+	value, err := fetcher.Fetch(context.Background())
+	if err != nil || value != keyV1 {
+		t.Fatalf("unexpected secret %q, %v", value, err)
+	}
+}
+
+// TestGCPSecretProvider verifies the Secret Manager access call.
+func TestGCPSecretProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/projects/p/secrets/hcs/versions/latest:access" || r.Header.Get("Authorization") != "Bearer gt" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"payload": map[string]string{"data": base64.StdEncoding.EncodeToString([]byte(keyV2 + "\n"))}})
+	}))
+	defer srv.Close()
+
+	p := secretstore.NewProvider(&secretstore.GCP{Name: "projects/p/secrets/hcs", Token: "gt", Endpoint: srv.URL}, 0)
+	key, err := p.SecretKey()
+	if err != nil || len(key) != 32 || key[0] != 0x22 {
+		t.Fatalf("unexpected key %x, %v", key, err)
+	}
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/errcode"
+	"github.com/corehuman/hcs-lab-api/internal/selfupdate"
+)
+
+// releaseServer serves a signed stable manifest for one binary; tamper
+// changes the served binary after signing
+func releaseServer(t *testing.T, key ed25519.PrivateKey, binary []byte, tamper bool) *httptest.Server {
+	t.Helper()
+	sum := sha256.Sum256(binary)
+	manifest, err := json.Marshal(selfupdate.Release{
+		Version: "1.2.0",
+		Channel: selfupdate.Stable,
+		Assets:  []selfupdate.Asset{{OS: "linux", Arch: "amd64", URL: "hcsgen-linux-amd64", SHA256: hex.EncodeToString(sum[:])}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := binary
+	if tamper {
+		served = append([]byte("evil"), binary...)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stable.json", func(w http.ResponseWriter, r *http.Request) { w.Write(manifest) })
+	mux.HandleFunc("/stable.json.sig", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, manifest))))
+	})
+	mux.HandleFunc("/hcsgen-linux-amd64", func(w http.ResponseWriter, r *http.Request) { w.Write(served) })
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestSelfUpdate verifies that releases are installed only when signed with the
+// release key and matching their checksum.
+func TestSelfUpdate(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary := []byte("#!/bin/sh\necho 1.2.0\n")
+	ctx := context.Background()
+
+	srv := releaseServer(t, priv, binary, false)
+	client := &selfupdate.Client{BaseURL: srv.URL, PublicKey: pub}
+	release, err := client.Latest(ctx, selfupdate.Stable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Version != "1.2.0" {
+		t.Fatalf("unexpected version %q", release.Version)
+	}
+	asset, ok := release.Asset("linux", "amd64")
+	if !ok {
+		t.Fatal("missing linux/amd64 asset")
+	}
+	got, err := client.Download(ctx, release, asset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exe := filepath.Join(t.TempDir(), "hcsgen")
+	if err := os.WriteFile(exe, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := selfupdate.Replace(exe, got); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != string(binary) {
+		t.Fatalf("binary not replaced: %q", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(exe)); len(entries) != 1 {
+		t.Fatalf("leftover files next to the binary: %v", entries)
+	}
+
+	// A manifest signed with another key is rejected
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	if _, err := (&selfupdate.Client{BaseURL: srv.URL, PublicKey: otherPub}).Latest(ctx, selfupdate.Stable); errcode.Of(err, "") != errcode.UpdateUnverified {
+		t.Fatalf("expected %s for a foreign signature, got %v", errcode.UpdateUnverified, err)
+	}
+
+	// A binary that does not match the signed checksum is rejected
+	tampered := releaseServer(t, priv, binary, true)
+	client = &selfupdate.Client{BaseURL: tampered.URL, PublicKey: pub}
+	release, err = client.Latest(ctx, selfupdate.Stable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	asset, _ = release.Asset("linux", "amd64")
+	if _, err := client.Download(ctx, release, asset); errcode.Of(err, "") != errcode.UpdateUnverified {
+		t.Fatalf("expected %s for a tampered binary, got %v", errcode.UpdateUnverified, err)
+	}
+
+	// Channels without a manifest are unavailable
+	if _, err := client.Latest(ctx, selfupdate.Beta); errcode.Of(err, "") != errcode.UpdateUnavailable {
+		t.Fatalf("expected %s for a missing channel, got %v", errcode.UpdateUnavailable, err)
+	}
+}
+
+// TestNewerVersion verifies release version ordering.
+func TestNewerVersion(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		newer bool
+	}{
+		{"1.2.0", "1.0.0-hcs-lab", true},
+		{"1.0.0", "1.0.0-hcs-lab", false},
+		{"v1.10.0", "1.9.3", true},
+		{"0.9.9", "1.0.0", false},
+		{"2.0", "1.0.0", false},
+		{"garbage", "1.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := selfupdate.Newer(tt.a, tt.b); got != tt.newer {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.newer)
+		}
+	}
+}
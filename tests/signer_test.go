@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/signer"
+)
+
+// noSecret stands for a generation node that never holds the secret key
+type noSecret struct{}
+
+func (noSecret) SecretKey() ([]byte, error) { return nil, errors.New("no secret key on this node") }
+
+// TestRemoteSigner verifies that delegating U7 signing to a signing service
+// produces the same codes as signing in process.
+func TestRemoteSigner(t *testing.T) {
+	secrets, err := hcs.NewStaticSecretProvider(bytes.Repeat([]byte{0x5a}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(signer.Handler(hcs.NewLocalSigner(secrets), "s3cret"))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	local, err := hcs.NewGenerator(hcs.WithSaltDir(dir), hcs.WithSecretProvider(secrets))
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := hcs.NewGenerator(hcs.WithSaltDir(dir), hcs.WithSecretProvider(noSecret{}),
+		hcs.WithSigner(signer.NewRemote(srv.URL+"/", "s3cret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &hcs.GeneratorOptions{KeyDerivation: hcs.KeyDerivationHKDF}
+	want, err := local.GenerateWithOptions(getTestInput(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := remote.GenerateWithOptions(getTestInput(), opts)
+	if err != nil {
+		t.Fatalf("remote signing failed: %v", err)
+	}
+	if got.CodeU7 == "" || got.CodeU7 != want.CodeU7 || got.QSig != want.QSig {
+		t.Errorf("remote signing should match local signing:\n%s\n%s", got.CodeU7, want.CodeU7)
+	}
+
+	unauthorized, err := hcs.NewGenerator(hcs.WithSaltDir(dir), hcs.WithSecretProvider(noSecret{}),
+		hcs.WithSigner(signer.NewRemote(srv.URL, "wrong")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unauthorized.Generate(getTestInput()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("a wrong signing token should fail generation, got %v", err)
+	}
+
+	unprotected := httptest.NewServer(signer.Handler(hcs.NewLocalSigner(secrets), ""))
+	defer unprotected.Close()
+	req := hcs.U7SignRequest{Canonical: []byte("{}"), Salt: []byte("salt")}
+	if _, err := signer.NewRemote(unprotected.URL, "").SignU7(context.Background(), req); err == nil {
+		t.Error("a signing service without a token should reject every request")
+	}
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestSolarLongitude checks the Sun's apparent longitude against Meeus'
+// worked example (1992-10-13 0h TD: 199.90895°).
+func TestSolarLongitude(t *testing.T) {
+	got := hcs.SolarLongitude(time.Date(1992, 10, 13, 0, 0, 0, 0, time.UTC))
+	if math.Abs(got-199.909) > 0.01 {
+		t.Errorf("SolarLongitude = %.4f, want 199.909", got)
+	}
+}
+
+// TestSolarTermPillars verifies that the year and month pillars change at Li
+// Chun, not on the calendar.
+func TestSolarTermPillars(t *testing.T) {
+	for _, tc := range []struct {
+		liChun                  time.Time // published instant
+		yearBefore, yearAfter   string
+		monthBefore, monthAfter string
+	}{
+		{time.Date(1990, 2, 4, 2, 14, 0, 0, time.UTC), "Ji-Si", "Geng-Wu", "Ding-Chou", "Wu-Yin"},
+		{time.Date(2024, 2, 4, 8, 27, 0, 0, time.UTC), "Gui-Mao", "Jia-Chen", "Yi-Chou", "Bing-Yin"},
+	} {
+		before, after := tc.liChun.Add(-time.Hour), tc.liChun.Add(time.Hour)
+		if got := hcs.ComputeSolarYearPillar(before).PillarToString(); got != tc.yearBefore {
+			t.Errorf("year before %s = %s, want %s", tc.liChun, got, tc.yearBefore)
+		}
+		if got := hcs.ComputeSolarYearPillar(after).PillarToString(); got != tc.yearAfter {
+			t.Errorf("year after %s = %s, want %s", tc.liChun, got, tc.yearAfter)
+		}
+		if got := hcs.ComputeSolarMonthPillar(before).PillarToString(); got != tc.monthBefore {
+			t.Errorf("month before %s = %s, want %s", tc.liChun, got, tc.monthBefore)
+		}
+		if got := hcs.ComputeSolarMonthPillar(after).PillarToString(); got != tc.monthAfter {
+			t.Errorf("month after %s = %s, want %s", tc.liChun, got, tc.monthAfter)
+		}
+	}
+
+	// Births on either side of the term in their own timezone
+	early := hcs.BirthInfo{Year: 1990, Month: 2, Day: 4, Hour: 9, Timezone: "Asia/Shanghai"}
+	late := early
+	late.Hour = 11
+	for birth, want := range map[*hcs.BirthInfo][2]string{&early: {"Ji-Si", "Ding-Chou"}, &late: {"Geng-Wu", "Wu-Yin"}} {
+		profile, err := hcs.ComputeChineseProfile(*birth)
+		if err != nil {
+			t.Fatalf("ComputeChineseProfile: %v", err)
+		}
+		if profile.YearPillar != want[0] || profile.MonthPillar != want[1] {
+			t.Errorf("%02d:00 in Shanghai: got %s %s, want %s %s", birth.Hour, profile.YearPillar, profile.MonthPillar, want[0], want[1])
+		}
+	}
+}
+
+// TestEngineVersions verifies that v1 profiles and codes can still be
+// reproduced after the switch to solar terms.
+func TestEngineVersions(t *testing.T) {
+	birth := hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Hour: 14, Minute: 30, Timezone: "UTC"}
+	for engine, want := range map[string]string{"v1": "Ren-Wei", "v2": "Ren-Wu"} {
+		profile, err := hcs.ComputeChineseProfileWithEngine(birth, engine)
+		if err != nil || profile.MonthPillar != want {
+			t.Errorf("engine %s: month pillar %v, %v; want %s", engine, profile, err, want)
+		}
+	}
+	if _, err := hcs.ComputeChineseProfileWithEngine(birth, "v9"); err == nil {
+		t.Error("an unknown engine should be rejected")
+	}
+
+	setTestSecretKey(t)
+	gen, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	input := getTestInput()
+	input.BirthInfo = &birth
+	old, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{EngineVersion: "v1"})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	current, _ := gen.Generate(input)
+	if old.CodeU5 == current.CodeU5 {
+		t.Fatal("the engines should give different U5 codes for this birth")
+	}
+	ctx := context.Background()
+	if v, err := gen.VerifyProfile(ctx, old.CodeU5, input, nil, nil); err != nil || v.Valid {
+		t.Errorf("a v1 code should not verify with the current engine, got %+v, %v", v, err)
+	}
+	if v, err := gen.VerifyProfile(ctx, old.CodeU5, input, nil, &hcs.GeneratorOptions{EngineVersion: "v1"}); err != nil || !v.Valid {
+		t.Errorf("a v1 code should verify with engine v1, got %+v, %v", v, err)
+	}
+}
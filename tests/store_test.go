@@ -0,0 +1,422 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/breaker"
+	"github.com/corehuman/hcs-lab-api/internal/clock"
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+// storeDSNs returns the DSNs of the memory, file and SQLite stores in dir,
+// the last left out in builds tagged hcs_nosqlite, followed by extra
+func storeDSNs(dir string, extra ...string) []string {
+	dsns := []string{"memory", "file:" + filepath.Join(dir, "store.json")}
+	if store.SQLiteAvailable() {
+		dsns = append(dsns, "sqlite:"+filepath.Join(dir, "hcs.db"))
+	}
+	return append(dsns, extra...)
+}
+
+// requireSQLite skips tests of the SQLite store in builds tagged hcs_nosqlite
+func requireSQLite(t *testing.T) {
+	t.Helper()
+	if !store.SQLiteAvailable() {
+		t.Skip("built without SQLite (hcs_nosqlite)")
+	}
+}
+
+func TestFileStorePersistence(t *testing.T) {
+	ctx := context.Background()
+	dsn := "file:" + filepath.Join(t.TempDir(), "store.json")
+
+	s1, err := store.Open(dsn)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	rec := store.Record{Chip: "aae673a93e1f", Input: *getTestInput(), CreatedAt: time.Now().UTC()}
+	if err := s1.Save(ctx, rec); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	s2, err := store.Open(dsn)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	got, err := s2.Get(ctx, "", rec.Chip)
+	if err != nil {
+		t.Fatalf("record not persisted: %v", err)
+	}
+	if got.Input.DominantElement != "Air" {
+		t.Errorf("unexpected persisted record: %+v", got)
+	}
+
+	if _, err := s2.Get(ctx, "", "missing"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestOpenDSN(t *testing.T) {
+	if s, err := store.Open(""); s != nil || err != nil {
+		t.Errorf("empty DSN should disable storage, got %v, %v", s, err)
+	}
+	if _, err := store.Open("memory"); err != nil {
+		t.Errorf("memory DSN failed: %v", err)
+	}
+	if _, err := store.Open("file:"); err == nil {
+		t.Errorf("file DSN without path should fail")
+	}
+	if _, err := store.Open("sqlite:"); err == nil {
+		t.Errorf("sqlite DSN without path should fail")
+	}
+	if _, err := store.Open("mysql://x"); err == nil {
+		t.Errorf("unknown DSN should fail")
+	}
+	if _, err := store.Open("sqlite:" + filepath.Join(t.TempDir(), "hcs.db")); err == nil != store.SQLiteAvailable() {
+		t.Errorf("sqlite DSN: got %v with SQLiteAvailable() = %v", err, store.SQLiteAvailable())
+	}
+}
+
+// TestSQLiteStore verifies the SQLite store round trip and its schema migrations.
+func TestSQLiteStore(t *testing.T) {
+	requireSQLite(t)
+	ctx := context.Background()
+	dsn := "sqlite:" + filepath.Join(t.TempDir(), "hcs.db")
+
+	s, err := store.Open(dsn)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	runner := s.(store.Migrator).Migrations()
+	if pending, err := runner.Pending(ctx); err != nil || len(pending) == 0 {
+		t.Fatalf("a new database should have pending migrations, got %d, %v", len(pending), err)
+	}
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	if _, err := runner.Up(ctx, now); err != nil {
+		t.Fatalf("migrations failed: %v", err)
+	}
+	statuses, err := runner.Status(ctx)
+	if err != nil || !statuses[0].Applied || !statuses[0].AppliedAt.Equal(now) {
+		t.Fatalf("unexpected migration status: %+v, %v", statuses, err)
+	}
+
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := gen.Generate(getTestInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cluster := 3
+	first := store.Record{Chip: out.Chip, SubjectID: "s-1", TenantID: "lab", MatchOptIn: true,
+		Input: *getTestInput(), Output: out, CreatedAt: now.Add(time.Nanosecond),
+		ValidUntil: now.AddDate(1, 0, 0), ClusterID: &cluster}
+	second := store.Record{Chip: "000000000000", Input: *getTestInput(), CreatedAt: now.Add(time.Hour)}
+	for _, rec := range []store.Record{second, first} {
+		if err := s.Save(ctx, rec); err != nil {
+			t.Fatalf("failed to save: %v", err)
+		}
+	}
+
+	reopened, err := store.Open(dsn)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	got, err := reopened.Get(ctx, "lab", first.Chip)
+	if err != nil {
+		t.Fatalf("record not persisted: %v", err)
+	}
+	if got.SubjectID != "s-1" || got.TenantID != "lab" || !got.MatchOptIn || *got.ClusterID != 3 ||
+		!got.CreatedAt.Equal(first.CreatedAt) || !got.ValidUntil.Equal(first.ValidUntil) ||
+		!got.ReminderSentAt.IsZero() || got.Output.Chip != first.Output.Chip {
+		t.Errorf("unexpected persisted record: %+v", got)
+	}
+	if _, err := reopened.Get(ctx, "lab", "missing"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	records, err := reopened.ListAll(ctx)
+	if err != nil || len(records) != 2 || records[0].Chip != first.Chip {
+		t.Errorf("records should be listed by creation time, got %d, %v", len(records), err)
+	}
+
+	// Keying records by tenant keeps the stored records across the migration
+	if _, err := runner.Down(ctx, 1); err != nil {
+		t.Fatalf("reverting the tenant key failed: %v", err)
+	}
+	if _, err := runner.Up(ctx, now); err != nil {
+		t.Fatalf("migrations failed: %v", err)
+	}
+	if records, err := reopened.ListAll(ctx); err != nil || len(records) != 2 {
+		t.Errorf("records should survive the tenant key migration, got %d, %v", len(records), err)
+	}
+
+	if _, err := runner.Down(ctx, len(statuses)); err != nil {
+		t.Fatalf("reverting failed: %v", err)
+	}
+	if pending, _ := runner.Pending(ctx); len(pending) != len(statuses) {
+		t.Errorf("all migrations should be pending after reverting, got %d", len(pending))
+	}
+}
+
+// TestStoreMetadata verifies that metadata survives reopening file and SQLite stores.
+func TestStoreMetadata(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	for _, dsn := range storeDSNs(dir) {
+		open := func() store.Metadata {
+			s, err := store.Open(dsn)
+			if err != nil {
+				t.Fatalf("%s: failed to open store: %v", dsn, err)
+			}
+			if m, ok := s.(store.Migrator); ok {
+				if _, err := m.Migrations().Up(ctx, time.Now()); err != nil {
+					t.Fatalf("%s: failed to migrate: %v", dsn, err)
+				}
+			}
+			return s.(store.Metadata)
+		}
+
+		m := open()
+		if _, err := m.GetMeta(ctx, "salt_fingerprints"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", dsn, err)
+		}
+		m.SetMeta(ctx, "salt_fingerprints", "old")
+		if err := m.SetMeta(ctx, "salt_fingerprints", `{"0":"aae673a93e1f0b2c"}`); err != nil {
+			t.Fatalf("%s: failed to set metadata: %v", dsn, err)
+		}
+		if dsn != "memory" {
+			m = open()
+		}
+		if v, err := m.GetMeta(ctx, "salt_fingerprints"); err != nil || v != `{"0":"aae673a93e1f0b2c"}` {
+			t.Errorf("%s: metadata not persisted, got %q, %v", dsn, v, err)
+		}
+	}
+}
+
+// TestSQLiteMetadataClock verifies that SQLite metadata updates are stamped by the store's clock.
+func TestSQLiteMetadataClock(t *testing.T) {
+	requireSQLite(t)
+	ctx := context.Background()
+	s, err := store.OpenSQLiteStore(filepath.Join(t.TempDir(), "hcs.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer s.Close()
+	if _, err := s.Migrations().Up(ctx, time.Now()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	frozen := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	s.Clock = clock.NewFrozen(frozen)
+	if err := s.SetMeta(ctx, "salt_fingerprints", "{}"); err != nil {
+		t.Fatalf("failed to set metadata: %v", err)
+	}
+	var updatedAt string
+	if err := s.Migrations().DB.QueryRowContext(ctx, "SELECT updated_at FROM metadata WHERE key = ?", "salt_fingerprints").Scan(&updatedAt); err != nil {
+		t.Fatalf("failed to read updated_at: %v", err)
+	}
+	if got, err := time.Parse(time.RFC3339Nano, updatedAt); err != nil || !got.Equal(frozen) {
+		t.Errorf("updated_at = %s, want %s", updatedAt, frozen.Format(time.RFC3339))
+	}
+}
+
+// TestSaveBatch verifies chunked batch writes on every store, through a
+// breaker and without SaveChunk, under both conflict modes, and that
+// conflicts stay within a tenant.
+func TestSaveBatch(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	record := func(chip, subject string) store.Record {
+		return store.Record{Chip: chip, SubjectID: subject, Output: &hcs.OutputHCS{Chip: chip}, CreatedAt: time.Unix(1700000000, 0).UTC()}
+	}
+	for _, dsn := range storeDSNs(dir, "breaker", "plain") {
+		var s store.Store
+		var err error
+		switch dsn {
+		case "breaker":
+			s = store.WithBreaker(store.NewMemoryStore(), breaker.New("store", 5, time.Minute))
+		case "plain":
+			// Hides SaveChunk, so records are saved one by one
+			s = struct{ store.Store }{store.NewMemoryStore()}
+		default:
+			if s, err = store.Open(dsn); err != nil {
+				t.Fatalf("%s: failed to open store: %v", dsn, err)
+			}
+		}
+		if m, ok := s.(store.Migrator); ok {
+			if _, err := m.Migrations().Up(ctx, time.Now()); err != nil {
+				t.Fatalf("%s: failed to migrate: %v", dsn, err)
+			}
+		}
+
+		s.Save(ctx, record("aaaaaaaaaaaa", "stored"))
+		// Another tenant's record of a batch CHIP neither conflicts nor gets replaced
+		foreign := record("cccccccccccc", "foreign")
+		foreign.TenantID = "beta"
+		s.Save(ctx, foreign)
+		batch := []store.Record{
+			record("aaaaaaaaaaaa", "new"), record("bbbbbbbbbbbb", "first"), record("cccccccccccc", "c"),
+			record("bbbbbbbbbbbb", "second"), record("dddddddddddd", "d"),
+		}
+		outcomes := store.SaveBatch(ctx, s, batch, store.ConflictReject, 2)
+		want := []error{store.ErrConflict, nil, nil, store.ErrConflict, nil}
+		for j := range want {
+			if !errors.Is(outcomes[j], want[j]) {
+				t.Errorf("%s: reject outcome %d = %v, want %v", dsn, j, outcomes[j], want[j])
+			}
+		}
+		for chip, subject := range map[string]string{"aaaaaaaaaaaa": "stored", "bbbbbbbbbbbb": "first", "dddddddddddd": "d"} {
+			if rec, err := s.Get(ctx, "", chip); err != nil || rec.SubjectID != subject {
+				t.Errorf("%s: %s after reject = %+v, %v; want subject %q", dsn, chip, rec, err, subject)
+			}
+		}
+
+		for j, err := range store.SaveBatch(ctx, s, batch, store.ConflictUpsert, 0) {
+			if err != nil {
+				t.Errorf("%s: upsert outcome %d = %v", dsn, j, err)
+			}
+		}
+		for chip, subject := range map[string]string{"aaaaaaaaaaaa": "new", "bbbbbbbbbbbb": "second"} {
+			if rec, err := s.Get(ctx, "", chip); err != nil || rec.SubjectID != subject {
+				t.Errorf("%s: %s after upsert = %+v, %v; want subject %q", dsn, chip, rec, err, subject)
+			}
+		}
+		if records, _ := s.List(ctx, ""); len(records) != 4 {
+			t.Errorf("%s: expected 4 records, got %d", dsn, len(records))
+		}
+		if rec, err := s.Get(ctx, "beta", "cccccccccccc"); err != nil || rec.SubjectID != "foreign" {
+			t.Errorf("%s: the batch replaced another tenant's record: %+v, %v", dsn, rec, err)
+		}
+	}
+
+	if _, err := store.ParseConflict("merge"); err == nil {
+		t.Error("expected an error for an unknown conflict mode")
+	}
+}
+
+// TestTenantKeyedRecords verifies that every store keys records by tenant
+// and CHIP: tenants storing the same CHIP keep their own record, and reads
+// never return the records of another tenant.
+func TestTenantKeyedRecords(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	for _, dsn := range storeDSNs(dir) {
+		s, err := store.Open(dsn)
+		if err != nil {
+			t.Fatalf("%s: failed to open store: %v", dsn, err)
+		}
+		if m, ok := s.(store.Migrator); ok {
+			if _, err := m.Migrations().Up(ctx, time.Now()); err != nil {
+				t.Fatalf("%s: failed to migrate: %v", dsn, err)
+			}
+		}
+		for i, rec := range []store.Record{
+			{Chip: "aaaaaaaaaaaa", TenantID: "acme", SubjectID: "s-1"},
+			{Chip: "aaaaaaaaaaaa", TenantID: "beta", SubjectID: "s-2"}, // the same profile, generated by beta
+			{Chip: "bbbbbbbbbbbb", TenantID: "beta", SubjectID: "s-1"},
+			{Chip: "cccccccccccc", TenantID: "acme", SubjectID: "s-1"},
+			{Chip: "dddddddddddd", SubjectID: "s-1"},
+		} {
+			rec.Output = &hcs.OutputHCS{Chip: rec.Chip}
+			rec.CreatedAt = time.Unix(1700000000+int64(i), 0).UTC()
+			if err := s.Save(ctx, rec); err != nil {
+				t.Fatalf("%s: failed to save: %v", dsn, err)
+			}
+		}
+		if strings.HasPrefix(dsn, "file:") || strings.HasPrefix(dsn, "sqlite:") {
+			if s, err = store.Open(dsn); err != nil {
+				t.Fatalf("%s: failed to reopen store: %v", dsn, err)
+			}
+		}
+
+		for tenant, subject := range map[string]string{"acme": "s-1", "beta": "s-2"} {
+			if rec, err := s.Get(ctx, tenant, "aaaaaaaaaaaa"); err != nil || rec.TenantID != tenant || rec.SubjectID != subject {
+				t.Errorf("%s: %s's record of a shared CHIP = %+v, %v", dsn, tenant, rec, err)
+			}
+		}
+		for tenant, chip := range map[string]string{"acme": "bbbbbbbbbbbb", "beta": "cccccccccccc", "": "aaaaaaaaaaaa"} {
+			if _, err := s.Get(ctx, tenant, chip); !errors.Is(err, store.ErrNotFound) {
+				t.Errorf("%s: %q reading %s: expected ErrNotFound, got %v", dsn, tenant, chip, err)
+			}
+		}
+
+		records, err := s.List(ctx, "acme")
+		if err != nil || len(records) != 2 || records[0].Chip != "aaaaaaaaaaaa" || records[1].Chip != "cccccccccccc" {
+			t.Errorf("%s: List(acme) = %+v, %v", dsn, records, err)
+		}
+		if all, err := s.ListAll(ctx); err != nil || len(all) != 5 {
+			t.Errorf("%s: ListAll returned %d records, %v", dsn, len(all), err)
+		}
+		for tenant, chip := range map[string]string{"acme": "cccccccccccc", "beta": "bbbbbbbbbbbb", "": "dddddddddddd"} {
+			if rec, err := store.LatestForSubject(ctx, s, tenant, "s-1"); err != nil || rec.Chip != chip {
+				t.Errorf("%s: LatestForSubject(%q) = %+v, %v; want %s", dsn, tenant, rec, err, chip)
+			}
+		}
+		if _, err := store.LatestForSubject(ctx, s, "gamma", "s-1"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound for a tenant without records, got %v", dsn, err)
+		}
+	}
+}
+
+// TestSetClusterIDs verifies that cluster labels are written in place,
+// keeping the fields updated since the records were listed.
+func TestSetClusterIDs(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	created := time.Unix(1700000000, 0).UTC()
+	for _, dsn := range storeDSNs(dir, "breaker", "plain") {
+		var s store.Store
+		var err error
+		switch dsn {
+		case "breaker":
+			s = store.WithBreaker(store.NewMemoryStore(), breaker.New("store", 5, time.Minute))
+		case "plain":
+			// Hides SetClusterIDs, so records are read and saved one by one
+			s = struct{ store.Store }{store.NewMemoryStore()}
+		default:
+			if s, err = store.Open(dsn); err != nil {
+				t.Fatalf("%s: failed to open store: %v", dsn, err)
+			}
+		}
+		if m, ok := s.(store.Migrator); ok {
+			if _, err := m.Migrations().Up(ctx, created); err != nil {
+				t.Fatalf("%s: failed to migrate: %v", dsn, err)
+			}
+		}
+
+		for _, chip := range []string{"aaaaaaaaaaaa", "bbbbbbbbbbbb"} {
+			s.Save(ctx, store.Record{Chip: chip, Output: &hcs.OutputHCS{Chip: chip}, CreatedAt: created})
+		}
+		// A reminder sent after the analytics run listed the records
+		reminded := store.Record{Chip: "aaaaaaaaaaaa", Output: &hcs.OutputHCS{Chip: "aaaaaaaaaaaa"}, CreatedAt: created,
+			ReminderSentAt: created.Add(time.Hour)}
+		s.Save(ctx, reminded)
+
+		if err := store.SetClusterIDs(ctx, s, "", map[string]int{"aaaaaaaaaaaa": 2, "zzzzzzzzzzzz": 1}); err != nil {
+			t.Fatalf("%s: SetClusterIDs failed: %v", dsn, err)
+		}
+		if strings.HasPrefix(dsn, "file:") || strings.HasPrefix(dsn, "sqlite:") {
+			if s, err = store.Open(dsn); err != nil {
+				t.Fatalf("%s: failed to reopen store: %v", dsn, err)
+			}
+		}
+		rec, err := s.Get(ctx, "", "aaaaaaaaaaaa")
+		if err != nil || rec.ClusterID == nil || *rec.ClusterID != 2 || !rec.ReminderSentAt.Equal(reminded.ReminderSentAt) {
+			t.Errorf("%s: labeled record = %+v, %v", dsn, rec, err)
+		}
+		if rec, err := s.Get(ctx, "", "bbbbbbbbbbbb"); err != nil || rec.ClusterID != nil {
+			t.Errorf("%s: unlabeled record = %+v, %v", dsn, rec, err)
+		}
+		if _, err := s.Get(ctx, "", "zzzzzzzzzzzz"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s: labeling a missing CHIP stored it: %v", dsn, err)
+		}
+	}
+}
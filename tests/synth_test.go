@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/synth"
+	"github.com/corehuman/hcs-lab-api/pkg/hcstest"
+)
+
+// TestSyntheticProfiles verifies that generated profiles are valid and reproducible.
+func TestSyntheticProfiles(t *testing.T) {
+	gen := synth.New(synth.Options{Seed: 42, BirthRate: 0.5})
+	same := synth.New(synth.Options{Seed: 42, BirthRate: 0.5})
+
+	withBirth := 0
+	for i := 0; i < 2000; i++ {
+		in := gen.Profile()
+		if !reflect.DeepEqual(in, same.Profile()) {
+			t.Fatalf("profile %d differs for the same seed", i)
+		}
+		if sum := in.Modal.Cardinal + in.Modal.Fixed + in.Modal.Mutable; math.Abs(sum-1) > hcs.DefaultModalSumTolerance {
+			t.Errorf("profile %d: modal values sum to %v", i, sum)
+		}
+		if err := hcs.ValidateInput(in); err != nil {
+			t.Fatalf("profile %d is invalid: %v", i, err)
+		}
+		if in.BirthInfo != nil {
+			withBirth++
+		}
+	}
+	if withBirth < 800 || withBirth > 1200 {
+		t.Errorf("expected about half the profiles with birth info, got %d of 2000", withBirth)
+	}
+
+	if synth.New(synth.Options{Seed: 1}).Profile().BirthInfo != nil {
+		t.Error("a zero birth rate should give no birth info")
+	}
+}
+
+// TestLoadTest verifies the load-test report against the mock server.
+func TestLoadTest(t *testing.T) {
+	srv := hcstest.NewServer(t)
+	lt := &synth.LoadTest{BaseURL: srv.URL, Client: srv.Client(), Concurrency: 4}
+	report := lt.Run(context.Background(), synth.New(synth.Options{Seed: 7}), 50)
+
+	if report.Requests != 50 || report.Errors != 0 || report.StatusCodes[200] != 50 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if report.P50 > report.P90 || report.P90 > report.P99 || report.P99 > report.Max || report.Max <= 0 {
+		t.Errorf("percentiles should be ordered and positive: %+v", report)
+	}
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/report"
+	"github.com/corehuman/hcs-lab-api/internal/terminology"
+)
+
+// TestTerminologyDictionary verifies whole-word, longest-first and
+// case-preserving replacement.
+func TestTerminologyDictionary(t *testing.T) {
+	d, err := terminology.NewDictionary(map[string]string{
+		"element":          "energy",
+		"dominant element": "primary energy",
+		"HCS code":         "profile code",
+		"élément dominant": "pôle énergétique dominant",
+	})
+	if err != nil {
+		t.Fatalf("NewDictionary: %v", err)
+	}
+	for in, want := range map[string]string{
+		"Dominant element":                         "Primary energy",
+		"The dominant element is not Fire":         "The primary energy is not Fire",
+		"ELEMENT":                                  "ENERGY",
+		"elemental elements, element.":             "elemental elements, energy.",
+		"The HCS code is malformed":                "The profile code is malformed",
+		"L'élément dominant":                       "L'pôle énergétique dominant",
+		"Élément dominant":                         "Pôle énergétique dominant",
+		"no terms here":                            "no terms here",
+		"HCS-U3|V:3.0|E:F|MOD:c45f30m25|CHIP:aae6": "HCS-U3|V:3.0|E:F|MOD:c45f30m25|CHIP:aae6",
+	} {
+		if got := d.Apply(in); got != want {
+			t.Errorf("Apply(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	var none *terminology.Dictionary
+	if got := none.Apply("Dominant element"); got != "Dominant element" {
+		t.Errorf("a nil dictionary should keep the text, got %q", got)
+	}
+	if _, err := terminology.NewDictionary(map[string]string{"element": ""}); err == nil {
+		t.Error("an empty replacement should be rejected")
+	}
+	if _, err := terminology.NewDictionary(map[string]string{"Element": "a", "element": "b"}); err == nil {
+		t.Error("a term listed twice should be rejected")
+	}
+}
+
+// TestTerminologyCatalog verifies the built-in neutral terminology and
+// loading terminology files.
+func TestTerminologyCatalog(t *testing.T) {
+	neutral, err := terminology.Builtin().Lookup("neutral")
+	if err != nil {
+		t.Fatalf("Lookup(neutral): %v", err)
+	}
+	if got := neutral.Apply("fr", "L'élément dominant n'est ni Earth, ni Air"); got != "Le pôle énergétique dominant n'est ni Earth, ni Air" {
+		t.Errorf("unexpected French rewording %q", got)
+	}
+	if got := neutral.Apply("", "The element balance"); got != "The energy balance" {
+		t.Errorf("unexpected English rewording %q", got)
+	}
+	if standard, err := terminology.Builtin().Lookup(terminology.Standard); err != nil || standard != nil || standard.Apply("en", "element") != "element" {
+		t.Errorf("the standard terminology should reword nothing, got %v, %v", standard, err)
+	}
+	if _, err := terminology.Builtin().Lookup("astro-free"); err == nil {
+		t.Error("an unknown terminology should fail")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "terms.json")
+	os.WriteFile(path, []byte(`{"clinic": {"en": {"archetype": "profile type"}}}`), 0644)
+	c, err := terminology.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	clinic, err := c.Lookup("clinic")
+	if err != nil || clinic.Apply("en", "Your archetype") != "Your profile type" {
+		t.Errorf("unexpected clinic terminology %v, %v", clinic, err)
+	}
+	if clinic.Dictionary("fr") != nil {
+		t.Error("a terminology without French should keep French texts")
+	}
+	if got := strings.Join(c.Names(), ","); got != "clinic,neutral,standard" {
+		t.Errorf("Names = %s", got)
+	}
+
+	for name, content := range map[string]string{
+		"language": `{"clinic": {"de": {"element": "Energie"}}}`,
+		"reserved": `{"standard": {"en": {"element": "energy"}}}`,
+		"empty":    `{"clinic": {"en": {"": "energy"}}}`,
+		"json":     `{"clinic": `,
+	} {
+		os.WriteFile(path, []byte(content), 0644)
+		if _, err := terminology.Load(path); err == nil {
+			t.Errorf("a terminology file with an invalid %s should fail", name)
+		}
+	}
+}
+
+// TestReportTerminology verifies that reports reword their labels but not
+// their codes or links.
+func TestReportTerminology(t *testing.T) {
+	sample := report.Samples()[1].Report
+	standard, err := sample.Text()
+	if err != nil || !strings.Contains(standard, "Dominant element: Fire") {
+		t.Fatalf("unexpected standard report %q, %v", standard, err)
+	}
+
+	neutral, _ := terminology.Builtin().Lookup("neutral")
+	sample.Terms = neutral.Dictionary("en")
+	text, err := sample.Text()
+	if err != nil {
+		t.Fatalf("Text: %v", err)
+	}
+	for _, want := range []string{"Primary energy: Fire", "Working styles: initiating 45%, steady 30%, adaptive 25%", sample.Output.CodeU3} {
+		if !strings.Contains(text, want) {
+			t.Errorf("neutral report should contain %q:\n%s", want, text)
+		}
+	}
+	html, err := sample.HTML()
+	if err != nil || !strings.Contains(html, "<th align=\"left\">Primary energy</th>") || strings.Contains(html, "Dominant element") {
+		t.Errorf("unexpected neutral HTML report, %v:\n%s", err, html)
+	}
+	if sample.Subject() != report.Subject {
+		t.Errorf("Subject = %q", sample.Subject())
+	}
+
+	// Renders stay independent: the standard terms come back without Terms
+	sample.Terms = nil
+	if again, _ := sample.Text(); again != standard {
+		t.Error("a report without terms should render the standard terms")
+	}
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/breaker"
+	"github.com/corehuman/hcs-lab-api/internal/store"
+)
+
+// TestStoreTokens verifies single-use token consumption in every store,
+// including under concurrent verification, and that consumption survives
+// reopening file and SQLite stores.
+func TestStoreTokens(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	for _, dsn := range storeDSNs(dir) {
+		open := func() store.Tokens {
+			s, err := store.Open(dsn)
+			if err != nil {
+				t.Fatalf("%s: failed to open store: %v", dsn, err)
+			}
+			if m, ok := s.(store.Migrator); ok {
+				if _, err := m.Migrations().Up(ctx, now); err != nil {
+					t.Fatalf("%s: failed to migrate: %v", dsn, err)
+				}
+			}
+			ts := store.TokensOf(store.WithBreaker(s, breaker.New("storage", 2, time.Hour)))
+			if ts == nil {
+				t.Fatalf("%s: store keeps no tokens", dsn)
+			}
+			return ts
+		}
+
+		ts := open()
+		batch := []store.Token{
+			{Hash: store.TokenHash("hct_a"), BatchID: "b1", Chip: "aae673a93e1f", Event: "conf", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+			{Hash: store.TokenHash("hct_b"), BatchID: "b1", Chip: "aae673a93e1f", Event: "conf", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+			{Hash: store.TokenHash("hct_c"), BatchID: "b1", Chip: "aae673a93e1f", Event: "conf", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		}
+		if err := ts.SaveTokens(ctx, batch); err != nil {
+			t.Fatalf("%s: SaveTokens failed: %v", dsn, err)
+		}
+
+		// Of concurrent verifications of one token, exactly one succeeds
+		var wg sync.WaitGroup
+		var accepted, used atomic.Int32
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := ts.ConsumeToken(ctx, store.TokenHash("hct_a"), now.Add(time.Minute))
+				switch {
+				case err == nil:
+					accepted.Add(1)
+				case errors.Is(err, store.ErrTokenUsed):
+					used.Add(1)
+				default:
+					t.Errorf("%s: unexpected error: %v", dsn, err)
+				}
+			}()
+		}
+		wg.Wait()
+		if accepted.Load() != 1 || used.Load() != 7 {
+			t.Errorf("%s: %d verifications accepted, %d rejected as used; want 1 and 7", dsn, accepted.Load(), used.Load())
+		}
+
+		if _, err := ts.ConsumeToken(ctx, store.TokenHash("hct_b"), now.Add(time.Hour)); !errors.Is(err, store.ErrTokenExpired) {
+			t.Errorf("%s: expired token: got %v", dsn, err)
+		}
+		if _, err := ts.ConsumeToken(ctx, store.TokenHash("hct_x"), now); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s: unknown token: got %v", dsn, err)
+		}
+
+		if dsn != "memory" {
+			ts = open()
+		}
+		tok, err := ts.ConsumeToken(ctx, store.TokenHash("hct_a"), now.Add(2*time.Minute))
+		if !errors.Is(err, store.ErrTokenUsed) || tok == nil || !tok.UsedAt.Equal(now.Add(time.Minute)) {
+			t.Errorf("%s: consumption not persisted: %+v, %v", dsn, tok, err)
+		}
+		got, err := ts.TokenBatch(ctx, "b1")
+		if err != nil || len(got) != 3 || got[0].Event != "conf" {
+			t.Errorf("%s: unexpected batch: %+v, %v", dsn, got, err)
+		}
+		if _, err := ts.TokenBatch(ctx, "missing"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", dsn, err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestReliabilityBands verifies the bands of encoded percentages and the
+// rounding rule built on them.
+func TestReliabilityBands(t *testing.T) {
+	for percent, want := range map[int]hcs.ReliabilityBand{
+		52:  {Low: 51.5, High: 52.5},
+		0:   {Low: 0, High: 0.5},
+		100: {Low: 99.5, High: 100},
+	} {
+		if got := hcs.BandOf(percent); got != want {
+			t.Errorf("BandOf(%d) = %+v, want %+v", percent, got, want)
+		}
+	}
+	for _, tc := range []struct {
+		a, b int
+		want bool
+	}{
+		{52, 52, true},
+		{52, 53, true},
+		{53, 52, true},
+		{52, 54, false},
+		{0, 1, true},
+		{99, 100, true},
+		{10, 40, false},
+	} {
+		if got := hcs.WithinRounding(tc.a, tc.b); got != tc.want {
+			t.Errorf("WithinRounding(%d, %d) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+// TestDiffCodeSegmentsTolerant verifies that percentage segments moved within
+// rounding are not reported.
+func TestDiffCodeSegmentsTolerant(t *testing.T) {
+	base := "HCS-U3|V:7.0|ALG:QS|E:E|MOD:c31f23m46|COG:F52C13V53S15Cr33|INT:PB=B,SM=M,TN=N|CHIP:abc"
+	tests := []struct {
+		name string
+		new  string
+		want []string
+	}{
+		{"identical", base, nil},
+		{"one point", "HCS-U3|V:7.0|ALG:QS|E:E|MOD:c31f23m46|COG:F53C13V53S15Cr33|INT:PB=B,SM=M,TN=N|CHIP:abd", []string{"CHIP"}},
+		{"two points", "HCS-U3|V:7.0|ALG:QS|E:E|MOD:c31f23m46|COG:F54C13V53S15Cr33|INT:PB=B,SM=M,TN=N|CHIP:abd", []string{"COG", "CHIP"}},
+		{"modal shares", "HCS-U3|V:7.0|ALG:QS|E:E|MOD:c32f22m46|COG:F52C13V53S15Cr33|INT:PB=B,SM=M,TN=N|CHIP:abc", nil},
+		{"version", "HCS-U3|V:7.1|ALG:QS|E:E|MOD:c31f23m46|COG:F52C13V53S15Cr33|INT:PB=B,SM=M,TN=N|CHIP:abc", []string{"V"}},
+		{"level", "HCS-U4|V:7.0", []string{"LEVEL"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hcs.DiffCodeSegmentsTolerant(base, tt.new); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffCodeSegmentsTolerant() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRetestWithinRounding verifies that a retest whose values cross a
+// rounding boundary is stable, while a move beyond it is reported.
+func TestRetestWithinRounding(t *testing.T) {
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	input := getTestInput()
+	input.Cognition.Fluid = 0.524
+	prev, err := gen.Generate(input)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	input.Cognition.Fluid = 0.526
+	curr, _ := gen.Generate(input)
+	r := hcs.CompareRetest(prev, curr)
+	if !r.Stable || !r.ChipChanged || len(r.Changes) != 0 || !reflect.DeepEqual(r.ChangedSegments, []string{"CHIP"}) {
+		t.Errorf("0.524 -> 0.526 should be stable with only the CHIP changed, got %+v", r)
+	}
+
+	input.Cognition.Fluid = 0.536
+	curr, _ = gen.Generate(input)
+	r = hcs.CompareRetest(prev, curr)
+	if r.Stable || len(r.Changes) != 1 || r.Changes[0].Delta != 2 || !reflect.DeepEqual(r.ChangedSegments, []string{"COG", "CHIP"}) {
+		t.Errorf("0.524 -> 0.536 should be reported, got %+v", r)
+	}
+
+	a, b := hcs.NormalizeProfile(&prev.Input), hcs.NormalizeProfile(&curr.Input)
+	if changes := hcs.CompareNormalized(a, a); changes == nil || len(changes) != 0 {
+		t.Errorf("a profile compared with itself should have no changes, got %#v", changes)
+	}
+	if changes := hcs.CompareNormalized(a, b); len(changes) != 1 || changes[0].Field != "cognition.fluid" {
+		t.Errorf("expected the fluid change, got %+v", changes)
+	}
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+func TestGenerationTrace(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+
+	input := getTestInput()
+	input.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Hour: 14, Minute: 30, Timezone: "UTC"}
+
+	plain, err := gen.Generate(input)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if plain.Trace != nil {
+		t.Fatal("trace should only be attached on request")
+	}
+
+	out, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{Trace: true})
+	if err != nil {
+		t.Fatalf("failed to generate with trace: %v", err)
+	}
+	tr := out.Trace
+	if tr == nil {
+		t.Fatal("expected trace")
+	}
+	if out.CodeU7 != plain.CodeU7 {
+		t.Error("tracing must not change the codes")
+	}
+	if !strings.HasPrefix(tr.ChipDigest, out.Chip) || len(tr.ChipDigest) != 64 {
+		t.Errorf("chip digest %q should extend CHIP %q", tr.ChipDigest, out.Chip)
+	}
+	if u5Chip := out.CodeU5[strings.LastIndex(out.CodeU5, ":")+1:]; !strings.HasPrefix(tr.U5ChipDigest, u5Chip) {
+		t.Errorf("U5 digest %q should extend U5 CHIP %q", tr.U5ChipDigest, u5Chip)
+	}
+	if len(tr.Pillars) != 4 || tr.Pillars[2].Pillar.PillarToString() != out.ChineseProfile.DayPillar {
+		t.Errorf("unexpected pillars: %+v", tr.Pillars)
+	}
+	if canonical, err := hex.DecodeString(tr.CanonicalHex); err != nil || len(canonical) == 0 {
+		t.Errorf("invalid canonical hex: %v", err)
+	}
+	if tr.EngineVersion != hcs.CurrentEngineVersion || tr.FusionConfig != hcs.DefaultFusionConfigID {
+		t.Errorf("unexpected engine/fusion config: %s/%s", tr.EngineVersion, tr.FusionConfig)
+	}
+}
@@ -1,7 +1,11 @@
 package tests
 
 import (
+	"bytes"
+	"context"
+	"encoding/hex"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/corehuman/hcs-lab-api/internal/hcs"
@@ -15,6 +19,14 @@ func setTestSecretKey(t *testing.T) {
 	}
 }
 
+// requireBLAKE3 skips tests pinned to BLAKE3 signatures in builds tagged hcs_noblake3
+func requireBLAKE3(t *testing.T) {
+	t.Helper()
+	if !hcs.BLAKE3Available() {
+		t.Skip("built without BLAKE3 (hcs_noblake3)")
+	}
+}
+
 // TestU7Determinism verifies that with a fixed secret, salt, and profile,
 // U7-related outputs are fully deterministic.
 func TestU7Determinism(t *testing.T) {
@@ -78,7 +90,8 @@ func TestU7SecretSensitivity(t *testing.T) {
 		t.Fatalf("failed to set secret2: %v", err)
 	}
 
-	gen2, err := hcs.NewGeneratorWithSaltDir(tempDir)
+	// The salt was sealed with the first secret only
+	gen2, err := hcs.NewGenerator(hcs.WithSaltDir(tempDir), hcs.WithSaltSealMigration())
 	if err != nil {
 		t.Fatalf("failed to create generator2: %v", err)
 	}
@@ -129,3 +142,332 @@ func TestU7Avalanche(t *testing.T) {
 		t.Errorf("QSIG should change when profile is slightly modified")
 	}
 }
+
+// TestU7DualWrite verifies that legacy format versions are emitted side by side
+// and that unknown versions are rejected.
+func TestU7DualWrite(t *testing.T) {
+	setTestSecretKey(t)
+
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+
+	out, err := gen.GenerateWithOptions(getTestInput(), &hcs.GeneratorOptions{
+		LegacyU7Versions: []string{hcs.CurrentU7Version},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if len(out.LegacyCodes) != 0 {
+		t.Errorf("the primary version should not be duplicated, got %+v", out.LegacyCodes)
+	}
+
+	if _, err := gen.GenerateWithOptions(getTestInput(), &hcs.GeneratorOptions{U7Version: "6.9"}); err == nil {
+		t.Errorf("unknown primary version should be rejected")
+	}
+	if _, err := gen.GenerateWithOptions(getTestInput(), &hcs.GeneratorOptions{LegacyU7Versions: []string{"6.9"}}); err == nil {
+		t.Errorf("unknown legacy version should be rejected")
+	}
+}
+
+// TestReloadSecretKey verifies that a rotated key is picked up on reload and
+// that an invalid key keeps the previous one in use.
+func TestReloadSecretKey(t *testing.T) {
+	secrets := hcs.NewEnvSecretProvider()
+
+	t.Setenv("HCS_SECRET_KEY", "cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc")
+	rotated, err := secrets.Reload()
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if rotated[0] != 0xcc {
+		t.Fatalf("reload did not pick up the new key")
+	}
+
+	t.Setenv("HCS_SECRET_KEY", "not-hex")
+	if _, err := secrets.Reload(); err == nil {
+		t.Fatal("expected error for invalid key")
+	}
+	current, err := secrets.SecretKey()
+	if err != nil || current[0] != 0xcc {
+		t.Errorf("invalid reload should keep the previous key, got %x, %v", current, err)
+	}
+}
+
+// TestPerGeneratorSecrets verifies that generators in one process can sign
+// with different keys.
+func TestPerGeneratorSecrets(t *testing.T) {
+	if _, err := hcs.NewStaticSecretProvider([]byte("short")); err == nil {
+		t.Fatal("expected error for a short static key")
+	}
+
+	tempDir := t.TempDir()
+	var codes []string
+	for _, b := range []byte{0x11, 0x22} {
+		key := make([]byte, 32)
+		for i := range key {
+			key[i] = b
+		}
+		secrets, err := hcs.NewStaticSecretProvider(key)
+		if err != nil {
+			t.Fatalf("failed to create provider: %v", err)
+		}
+		// Each key seals the shared salt for itself
+		gen, err := hcs.NewGenerator(hcs.WithSaltDir(tempDir), hcs.WithSecretProvider(secrets), hcs.WithSaltSealMigration())
+		if err != nil {
+			t.Fatalf("failed to create generator: %v", err)
+		}
+		out, err := gen.Generate(getTestInput())
+		if err != nil {
+			t.Fatalf("failed to generate: %v", err)
+		}
+		codes = append(codes, out.CodeU7)
+	}
+
+	if codes[0] == codes[1] {
+		t.Errorf("CodeU7 should differ between generators with different secrets")
+	}
+}
+
+// TestU7SignatureLengths verifies configurable inline signature lengths and
+// their declaration in the ALG segment.
+func TestU7SignatureLengths(t *testing.T) {
+	requireBLAKE3(t)
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	input := getTestInput()
+
+	def, err := gen.Generate(input)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if !strings.Contains(def.CodeU7, "|ALG:QS-HKDF|") {
+		t.Errorf("default lengths should keep the plain ALG segment: %s", def.CodeU7)
+	}
+
+	out, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{
+		U7SignatureLengths: hcs.SignatureLengths{QSig: 32, B3: 48},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if !strings.Contains(out.CodeU7, "|ALG:QS-HKDF.32.48|") || !strings.Contains(out.CodeU7, "|QSIG:"+out.QSig[:32]+"|") {
+		t.Errorf("unexpected code for custom lengths: %s", out.CodeU7)
+	}
+
+	lengths, err := hcs.ParseSignatureLengths(out.CodeU7)
+	if err != nil || lengths != (hcs.SignatureLengths{QSig: 32, B3: 48}) {
+		t.Errorf("ParseSignatureLengths = %+v, %v", lengths, err)
+	}
+	if _, err := hcs.NormalizedFromCode(out.CodeU7); err != nil {
+		t.Errorf("custom-length code should decode: %v", err)
+	}
+
+	// Inline signatures shorter than declared must be rejected
+	truncated := strings.Replace(out.CodeU7, out.QSig[:32], out.QSig[:24], 1)
+	if _, err := hcs.ParseSignatureLengths(truncated); err == nil {
+		t.Error("expected error for signatures not matching the declared lengths")
+	}
+
+	if _, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{
+		U7SignatureLengths: hcs.SignatureLengths{QSig: hcs.MinSignatureInlineLength - 1},
+	}); err == nil {
+		t.Error("expected error for a QSIG length below the minimum")
+	}
+}
+
+// TestU7PostQuantum verifies the detached ML-DSA signature mode.
+func TestU7PostQuantum(t *testing.T) {
+	skipInFIPSMode(t)
+	requireBLAKE3(t)
+	setTestSecretKey(t)
+	seed := make([]byte, 32)
+	signer, err := hcs.NewMLDSASigner(seed)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	gen, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()), hcs.WithPQSigner(signer))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	input := getTestInput()
+
+	out, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{PostQuantum: true})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	key, _ := gen.PQPublicKey()
+	if !strings.Contains(out.CodeU7, "|ALG:QS-HKDF+MLDSA65|") || !strings.HasSuffix(out.CodeU7, "|PQ:"+key.KeyID) {
+		t.Errorf("unexpected post-quantum code: %s", out.CodeU7)
+	}
+	if err := hcs.VerifyPQSignature(out.CodeU7, out.PQSignature, key); err != nil {
+		t.Errorf("signature should verify: %v", err)
+	}
+	if _, err := hcs.NormalizedFromCode(out.CodeU7); err != nil {
+		t.Errorf("post-quantum code should decode: %v", err)
+	}
+
+	tampered := strings.Replace(out.CodeU7, "|E:A|", "|E:F|", 1)
+	if err := hcs.VerifyPQSignature(tampered, out.PQSignature, key); err == nil {
+		t.Error("tampered code should not verify")
+	}
+
+	plain, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	if _, err := plain.GenerateWithOptions(input, &hcs.GeneratorOptions{PostQuantum: true}); err == nil {
+		t.Error("expected error when no post-quantum signer is configured")
+	}
+}
+
+// TestU7KeyDerivation verifies that new codes are signed with HKDF and that
+// legacy codes still verify.
+func TestU7KeyDerivation(t *testing.T) {
+	requireBLAKE3(t)
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	input := getTestInput()
+
+	derived, err := gen.GenerateWithOptions(input, nil)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	explicit, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{KeyDerivation: hcs.KeyDerivationHKDF})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if explicit.CodeU7 != derived.CodeU7 {
+		t.Error("HKDF derivation should be the default")
+	}
+	if !strings.Contains(derived.CodeU7, "|ALG:QS-HKDF|") {
+		t.Errorf("HKDF codes should declare the derivation: %s", derived.CodeU7)
+	}
+	if kd, err := hcs.ParseKeyDerivation(derived.CodeU7); err != nil || kd != hcs.KeyDerivationHKDF {
+		t.Errorf("ParseKeyDerivation = %q, %v", kd, err)
+	}
+	if _, err := hcs.ParseSignatureLengths(derived.CodeU7); err != nil {
+		t.Errorf("HKDF codes should keep the default lengths: %v", err)
+	}
+	if _, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{KeyDerivation: hcs.KeyDerivationLegacy}); err == nil {
+		t.Error("new codes should not use the legacy derivation")
+	}
+
+	// A code published before HKDF became the default, from the first test vector
+	legacyCode := "HCS-U7|V:7.0|ALG:QS|E:A|MOD:c31f23m46|COG:F72C68V81S59Cr77|INT:PB=B,SM=M,TN=W|QSIG:ef66969f2e5dc2bf3a1e040d|B3:7dd96a5560a834a0c777b3e3761b47a0"
+	if kd, err := hcs.ParseKeyDerivation(legacyCode); err != nil || kd != hcs.KeyDerivationLegacy {
+		t.Errorf("ParseKeyDerivation = %q, %v", kd, err)
+	}
+	vectors, err := hcs.TestVectors()
+	if err != nil {
+		t.Fatalf("failed to compute vectors: %v", err)
+	}
+	vectorSecret, _ := hex.DecodeString(vectors[0].SecretHex)
+	vectorSalt, _ := hex.DecodeString(vectors[0].SaltHex)
+	secrets, err := hcs.NewStaticSecretProvider(vectorSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := hcs.NewGenerator(hcs.WithSaltProvider(fixedSalt(vectorSalt)), hcs.WithSecretProvider(secrets))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	v, err := verifier.VerifyProfile(context.Background(), legacyCode, &vectors[0].Input, nil, &hcs.GeneratorOptions{EngineVersion: vectors[0].EngineVersion})
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if !v.Valid || !*v.QSigValid || !*v.B3Valid {
+		t.Errorf("legacy codes should still verify: %+v", v)
+	}
+	if derived.QSig == vectors[0].QSig {
+		t.Error("HKDF derivation should change the signatures")
+	}
+
+	secret, salt := []byte("secret"), []byte("salt")
+	qsigKey, _ := hcs.DeriveKey(secret, salt, hcs.PurposeU7QSig)
+	b3Key, _ := hcs.DeriveKey(secret, salt, hcs.PurposeU7B3)
+	if bytes.Equal(qsigKey, b3Key) {
+		t.Error("purposes should derive independent keys")
+	}
+
+	if _, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{KeyDerivation: "md5"}); err == nil {
+		t.Error("expected error for unknown key derivation")
+	}
+}
+
+// TestU7SecondaryDigest verifies the SHA3-512 secondary digest and its ALG declaration.
+func TestU7SecondaryDigest(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	input := getTestInput()
+
+	out, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{
+		SecondaryDigest:    hcs.DigestSHA3,
+		U7SignatureLengths: hcs.SignatureLengths{QSig: 32, B3: 48},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if want := "|ALG:QS-HKDF-SHA3.32.48|"; !strings.Contains(out.CodeU7, want) {
+		t.Errorf("SHA3 codes should declare the digest as %s: %s", want, out.CodeU7)
+	}
+	if len(out.B3Sig) != 128 {
+		t.Errorf("SHA3-512 digest should have 128 hex characters, got %d", len(out.B3Sig))
+	}
+	if d, err := hcs.ParseSecondaryDigest(out.CodeU7); err != nil || d != hcs.DigestSHA3 {
+		t.Errorf("ParseSecondaryDigest = %q, %v", d, err)
+	}
+	if l, err := hcs.ParseSignatureLengths(out.CodeU7); err != nil || l.QSig != 32 || l.B3 != 48 {
+		t.Errorf("ParseSignatureLengths = %+v, %v", l, err)
+	}
+
+	// Legacy SHA3-512 codes declare the digest without a derivation marker
+	legacy := strings.Replace(out.CodeU7, "|ALG:QS-HKDF-SHA3.32.48|", "|ALG:QS-SHA3.32.48|", 1)
+	if kd, err := hcs.ParseKeyDerivation(legacy); err != nil || kd != hcs.KeyDerivationLegacy {
+		t.Errorf("ParseKeyDerivation = %q, %v", kd, err)
+	}
+	if d, err := hcs.ParseSecondaryDigest(legacy); err != nil || d != hcs.DigestSHA3 {
+		t.Errorf("ParseSecondaryDigest = %q, %v", d, err)
+	}
+
+	// The digest only changes the secondary signature
+	secret, salt, canonical := []byte("secret"), []byte("salt"), []byte(`{"profile":1}`)
+	for _, derivation := range []hcs.KeyDerivation{hcs.KeyDerivationLegacy, hcs.KeyDerivationHKDF} {
+		qsig, b3, err := hcs.ComputeQuantumSignaturesWithDigest(canonical, secret, salt, derivation, hcs.DigestSHA3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hcs.BLAKE3Available() {
+			qsigB3, b3B3, err := hcs.ComputeQuantumSignaturesWithDigest(canonical, secret, salt, derivation, hcs.DigestBLAKE3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if qsig != qsigB3 || b3 == b3B3 {
+				t.Errorf("%s: the digest should change B3 only", derivation)
+			}
+		}
+	}
+
+	if hcs.BLAKE3Available() {
+		out, err := gen.GenerateWithOptions(input, nil)
+		if err != nil {
+			t.Fatalf("failed to generate: %v", err)
+		}
+		if d, _ := hcs.ParseSecondaryDigest(out.CodeU7); d != hcs.DigestBLAKE3 || strings.Contains(out.CodeU7, "SHA3") {
+			t.Errorf("BLAKE3 should stay the default and undeclared: %s", out.CodeU7)
+		}
+	}
+	if _, err := gen.GenerateWithOptions(input, &hcs.GeneratorOptions{SecondaryDigest: "md5"}); err == nil {
+		t.Error("expected error for unknown secondary digest")
+	}
+}
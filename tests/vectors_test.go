@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestVectorsMatchPublished guards the published cross-language vectors:
+// any change to canonical encoding or signing shows up here. Regenerate with
+// `hcsgen vectors > tests/testdata/vectors.json` only for deliberate format changes.
+func TestVectorsMatchPublished(t *testing.T) {
+	requireBLAKE3(t)
+	data, err := os.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatalf("failed to read published vectors: %v", err)
+	}
+	var published []hcs.TestVector
+	if err := json.Unmarshal(data, &published); err != nil {
+		t.Fatalf("failed to parse published vectors: %v", err)
+	}
+
+	computed, err := hcs.TestVectors()
+	if err != nil {
+		t.Fatalf("failed to compute vectors: %v", err)
+	}
+	if len(computed) < len(published) {
+		t.Fatalf("published vectors were removed: got %d, want at least %d", len(computed), len(published))
+	}
+
+	for i, want := range published {
+		got := computed[i]
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("vector %s differs from published:\n got %+v\nwant %+v", want.Name, got, want)
+		}
+
+		// Recompute from the published bytes alone, as a port would
+		secret, _ := hex.DecodeString(want.SecretHex)
+		salt, _ := hex.DecodeString(want.SaltHex)
+		digest := sha256.Sum256(append(salt, want.NormalizedJSON...))
+		if chip := hex.EncodeToString(digest[:])[:12]; chip != want.Chip {
+			t.Errorf("vector %s: CHIP %s does not follow from normalizedJson (%s)", want.Name, want.Chip, chip)
+		}
+		derivation, err := hcs.ParseKeyDerivation(want.CodeU7)
+		if err != nil {
+			t.Fatalf("vector %s: %v", want.Name, err)
+		}
+		qsig, b3, err := hcs.ComputeQuantumSignaturesWithDerivation([]byte(want.CanonicalJSON), secret, salt, derivation)
+		if err != nil {
+			t.Fatalf("vector %s: %v", want.Name, err)
+		}
+		if qsig != want.QSig || b3 != want.B3Sig {
+			t.Errorf("vector %s: signatures do not follow from canonicalJson", want.Name)
+		}
+	}
+}
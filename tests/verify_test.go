@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+)
+
+// TestVerifyProfile verifies that every code level checks out against the
+// profile it was generated from, and not against another profile or once
+// its signatures are tampered with.
+func TestVerifyProfile(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGeneratorWithSaltDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	input := getTestInput()
+	input.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 5, Day: 1, Hour: 12, Timezone: "UTC"}
+	out, err := gen.Generate(input)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	other := *input
+	other.DominantElement = "Water"
+	ctx := context.Background()
+
+	for level, code := range map[string]string{"U3": out.CodeU3, "U4": out.CodeU4, "U5": out.CodeU5, "U6": out.CodeU6, "U7": out.CodeU7} {
+		v, err := gen.VerifyProfile(ctx, code, input, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: failed to verify: %v", level, err)
+		}
+		if !v.Valid || !v.ProfileMatches || v.Chip != out.Chip {
+			t.Errorf("%s: the code should verify against its profile: %+v", level, v)
+		}
+		if (level == "U7") != (v.QSigValid != nil && v.B3Valid != nil && v.ChipValid == nil) {
+			t.Errorf("%s: unexpected checks: %+v", level, v)
+		}
+
+		v, err = gen.VerifyProfile(ctx, code, &other, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: failed to verify against another profile: %v", level, err)
+		}
+		if v.Valid || v.ProfileMatches {
+			t.Errorf("%s: the code should not verify against another profile: %+v", level, v)
+		}
+	}
+
+	// Flip the first QSIG character of the U7 code
+	i := strings.Index(out.CodeU7, "|QSIG:") + len("|QSIG:")
+	flipped := "0"
+	if out.CodeU7[i] == '0' {
+		flipped = "1"
+	}
+	tampered := out.CodeU7[:i] + flipped + out.CodeU7[i+1:]
+	v, err := gen.VerifyProfile(ctx, tampered, input, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to verify the tampered code: %v", err)
+	}
+	if v.Valid || *v.QSigValid || !*v.B3Valid || !v.ProfileMatches {
+		t.Errorf("a tampered QSIG should fail verification alone: %+v", v)
+	}
+
+	if _, err := gen.VerifyProfile(ctx, out.CodeU5, getTestInput(), nil, nil); err == nil {
+		t.Error("a U5 code should not verify against a profile without birth info")
+	}
+
+	// Verification normalizes a copy, never the caller's profile
+	latitude, longitude := 48.85, 2.35
+	derived := getTestInput()
+	derived.ElementBalance = map[string]float64{"Fire": 0.7, "Earth": 0.1, "Air": 0.1, "Water": 0.1}
+	derived.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 5, Day: 1, Hour: 12, Timezone: "UTC", Latitude: &latitude, Longitude: &longitude}
+	opts := &hcs.GeneratorOptions{WesternFromBirth: true}
+	derivedOut, err := gen.GenerateWithOptions(derived, opts)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	balance, birthInfo := derived.ElementBalance["Fire"], *derived.BirthInfo
+	if v, err := gen.VerifyProfile(ctx, derivedOut.CodeU7, derived, nil, opts); err != nil || !v.Valid {
+		t.Fatalf("failed to verify a derived profile: %+v, %v", v, err)
+	}
+	if len(derived.ElementBalance) != 4 || derived.ElementBalance["Fire"] != balance || *derived.BirthInfo != birthInfo ||
+		latitude != 48.85 || longitude != 2.35 {
+		t.Errorf("VerifyProfile modified the caller's profile: %+v, %+v", derived.ElementBalance, derived.BirthInfo)
+	}
+}
@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/corehuman/hcs-lab-api/internal/store"
+	"github.com/corehuman/hcs-lab-api/internal/webhook"
+)
+
+// TestWebhookSignature verifies that deliveries carry an HMAC of their body
+// that receivers can check with the shared secret.
+func TestWebhookSignature(t *testing.T) {
+	var body []byte
+	var header, event string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header, event = r.Header.Get(webhook.SignatureHeader), r.Header.Get(webhook.EventHeader)
+	}))
+	defer srv.Close()
+
+	n := webhook.NewNotifier(srv.URL)
+	n.Secret = "whsec_test"
+	if err := n.Send(context.Background(), "code.expiring", map[string]string{"chip": "aae673a93e1f"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if event != "code.expiring" {
+		t.Errorf("event header = %q", event)
+	}
+	if !webhook.Verify("whsec_test", body, header) {
+		t.Errorf("signature %q does not verify", header)
+	}
+	if webhook.Verify("whsec_other", body, header) || webhook.Verify("whsec_test", append(body, ' '), header) {
+		t.Error("signature verifies with the wrong secret or body")
+	}
+	// Known answer, so receivers in other languages can check their implementation
+	if got := webhook.Sign("secret", []byte(`{"type":"webhook.test"}`)); got != "sha256=dc5ef8914be243f86454d29f76641602871249c9ed7c8c5020ceee0152866b03" {
+		t.Errorf("unexpected signature %q", got)
+	}
+}
+
+// TestWebhookRetry verifies exponential backoff, and that client errors
+// other than timeouts and rate limits are not retried.
+func TestWebhookRetry(t *testing.T) {
+	r := webhook.Retry{Attempts: 5, Base: time.Second, Max: 5 * time.Second}
+	for n, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		if got := r.Delay(n); got != want {
+			t.Errorf("Delay(%d) = %s, want %s", n, got, want)
+		}
+	}
+
+	var calls atomic.Int32
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(status)
+		}
+	}))
+	defer srv.Close()
+	fast := webhook.Retry{Attempts: 4, Base: time.Millisecond, Max: 5 * time.Millisecond}
+
+	attempts, err := webhook.PostWithRetry(context.Background(), srv.Client(), srv.URL, "s", "code.generated", []byte(`{}`), fast)
+	if err != nil || attempts != 3 {
+		t.Errorf("expected success on attempt 3, got %d, %v", attempts, err)
+	}
+
+	calls.Store(0)
+	fast.Attempts = 2
+	attempts, err = webhook.PostWithRetry(context.Background(), srv.Client(), srv.URL, "s", "code.generated", []byte(`{}`), fast)
+	var statusErr *webhook.StatusError
+	if attempts != 2 || !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected to give up after 2 attempts with a 503, got %d, %v", attempts, err)
+	}
+
+	calls.Store(0)
+	status = http.StatusGone
+	if attempts, _ := webhook.PostWithRetry(context.Background(), srv.Client(), srv.URL, "s", "code.generated", []byte(`{}`), fast); attempts != 1 {
+		t.Errorf("a 410 should not be retried, got %d attempts", attempts)
+	}
+}
+
+// TestWebhookRegistry verifies that webhooks and their dead letters survive
+// reopening the file and SQLite stores, and that deleting a webhook drops
+// its dead letters.
+func TestWebhookRegistry(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, dsn := range storeDSNs(dir) {
+		open := func() store.Webhooks {
+			s, err := store.Open(dsn)
+			if err != nil {
+				t.Fatalf("%s: failed to open store: %v", dsn, err)
+			}
+			if m, ok := s.(store.Migrator); ok {
+				if _, err := m.Migrations().Up(ctx, now); err != nil {
+					t.Fatalf("%s: failed to migrate: %v", dsn, err)
+				}
+			}
+			return store.WebhooksOf(s)
+		}
+
+		hooks := open()
+		wh := store.Webhook{ID: "wh_1", TenantID: "acme", URL: "https://example.com/hook", Events: []string{"code.generated"},
+			Secret: "whsec_1", CreatedAt: now, SecretRotatedAt: now}
+		hooks.SaveWebhook(ctx, store.Webhook{ID: "wh_2", URL: "https://example.com/other", Secret: "whsec_2", CreatedAt: now.Add(time.Minute)})
+		hooks.SaveWebhook(ctx, wh)
+		wh.Secret, wh.SecretRotatedAt = "whsec_rotated", now.Add(time.Hour)
+		if err := hooks.SaveWebhook(ctx, wh); err != nil {
+			t.Fatalf("%s: SaveWebhook failed: %v", dsn, err)
+		}
+		for i, id := range []string{"dl_1", "dl_2"} {
+			dl := store.DeadLetter{ID: id, WebhookID: "wh_1", Type: "code.generated", Body: json.RawMessage(`{"type":"code.generated"}`),
+				Attempts: 6, LastError: "webhook endpoint returned status 503", FailedAt: now.Add(time.Duration(i) * time.Minute)}
+			if err := hooks.SaveDeadLetter(ctx, dl); err != nil {
+				t.Fatalf("%s: SaveDeadLetter failed: %v", dsn, err)
+			}
+		}
+
+		if dsn != "memory" {
+			hooks = open()
+		}
+		got, err := hooks.GetWebhook(ctx, "wh_1")
+		if err != nil || got.Secret != "whsec_rotated" || !got.SecretRotatedAt.Equal(wh.SecretRotatedAt) || len(got.Events) != 1 || got.TenantID != "acme" {
+			t.Errorf("%s: GetWebhook = %+v, %v", dsn, got, err)
+		}
+		if all, err := hooks.ListWebhooks(ctx); err != nil || len(all) != 2 || all[0].ID != "wh_1" {
+			t.Errorf("%s: ListWebhooks = %+v, %v", dsn, all, err)
+		}
+		dls, err := hooks.ListDeadLetters(ctx, "wh_1")
+		if err != nil || len(dls) != 2 || dls[0].ID != "dl_1" || string(dls[1].Body) != `{"type":"code.generated"}` {
+			t.Errorf("%s: ListDeadLetters = %+v, %v", dsn, dls, err)
+		}
+
+		if err := hooks.DeleteWebhook(ctx, "wh_1"); err != nil {
+			t.Fatalf("%s: DeleteWebhook failed: %v", dsn, err)
+		}
+		if err := hooks.DeleteWebhook(ctx, "wh_1"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound deleting twice, got %v", dsn, err)
+		}
+		if dls, _ := hooks.ListDeadLetters(ctx, "wh_1"); len(dls) != 0 {
+			t.Errorf("%s: dead letters kept after delete: %d", dsn, len(dls))
+		}
+	}
+}
+
+// TestWebhookTargetGuard verifies that tenant webhooks cannot reach internal
+// addresses, whether at registration or at delivery, nor follow redirects.
+func TestWebhookTargetGuard(t *testing.T) {
+	ctx := context.Background()
+	for _, u := range []string{"http://127.0.0.1/hook", "http://localhost:8080/hook", "http://10.1.2.3/hook", "http://192.168.0.1/hook",
+		"http://169.254.169.254/latest/meta-data", "http://[::1]/hook", "http://[fd00::1]/hook", "http://100.64.0.1/hook", "http://0.0.0.0/hook"} {
+		if err := webhook.CheckURL(ctx, u); !errors.Is(err, webhook.ErrBlockedTarget) {
+			t.Errorf("CheckURL(%s) = %v, want ErrBlockedTarget", u, err)
+		}
+	}
+	if err := webhook.CheckURL(ctx, "ftp://example.com/hook"); err == nil || errors.Is(err, webhook.ErrBlockedTarget) {
+		t.Errorf("CheckURL(ftp) = %v, want a URL error", err)
+	}
+	if err := webhook.CheckURL(ctx, "https://93.184.216.34/hook"); err != nil {
+		t.Errorf("CheckURL(public address) = %v", err)
+	}
+
+	// Delivery checks again when dialing, e.g. after the name re-resolved
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/internal", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+	client := webhook.NewPublicClient(time.Second)
+	attempts, err := webhook.PostWithRetry(ctx, client, srv.URL, "whsec_test", "webhook.test", []byte(`{}`), webhook.DefaultRetry)
+	if !errors.Is(err, webhook.ErrBlockedTarget) || attempts != 1 || hits.Load() != 0 {
+		t.Errorf("delivery to loopback: %d attempts, %d hits, %v", attempts, hits.Load(), err)
+	}
+	if got := webhook.PublicError(err); got != webhook.ErrBlockedTarget.Error() {
+		t.Errorf("PublicError = %q", got)
+	}
+
+	// Redirects are answered, not followed
+	client.Transport = http.DefaultTransport
+	err = webhook.Post(ctx, client, srv.URL+"/redirect", "", "webhook.test", []byte(`{}`))
+	var status *webhook.StatusError
+	if !errors.As(err, &status) || status.StatusCode != http.StatusFound || hits.Load() != 1 {
+		t.Errorf("redirect: %d hits, %v", hits.Load(), err)
+	}
+
+	srv.Close()
+	err = webhook.Post(ctx, client, srv.URL, "", "webhook.test", []byte(`{}`))
+	if got := webhook.PublicError(err); err == nil || got != "webhook endpoint could not be reached" {
+		t.Errorf("PublicError(%v) = %q", err, got)
+	}
+}
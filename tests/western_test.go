@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/schema"
+)
+
+func placement(t *testing.T, chart *hcs.WesternChart, body string) hcs.Placement {
+	t.Helper()
+	for _, p := range chart.Placements {
+		if p.Body == body {
+			return p
+		}
+	}
+	t.Fatalf("no %s placement in %+v", body, chart.Placements)
+	return hcs.Placement{}
+}
+
+// angleDiff returns the distance between two ecliptic longitudes
+func angleDiff(a, b float64) float64 {
+	d := math.Abs(math.Mod(a-b, 360))
+	return math.Min(d, 360-d)
+}
+
+// TestWesternChartPositions checks the computed positions against the worked
+// examples of Meeus' Astronomical Algorithms.
+func TestWesternChartPositions(t *testing.T) {
+	for _, tc := range []struct {
+		body       string
+		birth      hcs.BirthInfo
+		longitude  float64
+		tolerance  float64
+		wantedSign string
+	}{
+		{"Sun", hcs.BirthInfo{Year: 1992, Month: 10, Day: 13, Timezone: "UTC"}, 199.91, 0.05, "Libra"},
+		{"Moon", hcs.BirthInfo{Year: 1992, Month: 4, Day: 12, Timezone: "UTC"}, 133.16, 0.5, "Leo"},
+		{"Venus", hcs.BirthInfo{Year: 1992, Month: 12, Day: 20, Timezone: "UTC"}, 313.08, 0.2, "Aquarius"},
+	} {
+		chart, err := hcs.ComputeWesternChart(tc.birth)
+		if err != nil {
+			t.Fatalf("ComputeWesternChart: %v", err)
+		}
+		p := placement(t, chart, tc.body)
+		if angleDiff(p.Longitude, tc.longitude) > tc.tolerance || p.Sign != tc.wantedSign {
+			t.Errorf("%s = %.2f° %s, want %.2f° %s", tc.body, p.Longitude, p.Sign, tc.longitude, tc.wantedSign)
+		}
+	}
+
+	// The birth timezone shifts the instant the chart is cast for
+	local, _ := hcs.ComputeWesternChart(hcs.BirthInfo{Year: 1992, Month: 4, Day: 12, Hour: 2, Timezone: "Europe/Paris"})
+	if p := placement(t, local, "Moon"); angleDiff(p.Longitude, 133.16) > 0.5 {
+		t.Errorf("2:00 in Paris should be 0:00 UTC, got the Moon at %.2f°", p.Longitude)
+	}
+}
+
+// TestWesternChartAscendant verifies the ascendant and the birth place checks.
+func TestWesternChartAscendant(t *testing.T) {
+	lat, lon := 0.0, 0.0
+	// 2000-01-01 03:00 UTC: the sidereal time at Greenwich is about 145°, so
+	// the rising ecliptic point on the equator is about 237°, in Scorpio
+	birth := hcs.BirthInfo{Year: 2000, Month: 1, Day: 1, Hour: 3, Timezone: "UTC", Latitude: &lat, Longitude: &lon}
+	chart, err := hcs.ComputeWesternChart(birth)
+	if err != nil {
+		t.Fatalf("ComputeWesternChart: %v", err)
+	}
+	asc := placement(t, chart, "Ascendant")
+	if angleDiff(asc.Longitude, 237.3) > 0.2 || chart.Ascendant != "Scorpio" {
+		t.Errorf("ascendant = %.2f° %s, want about 237.3° Scorpio", asc.Longitude, chart.Ascendant)
+	}
+	if len(chart.Placements) != 11 {
+		t.Errorf("expected ten bodies and the ascendant, got %d placements", len(chart.Placements))
+	}
+
+	// Six hours later the Earth has turned a quarter, and so has the horizon
+	later := birth
+	later.Hour = 9
+	if chart, _ := hcs.ComputeWesternChart(later); chart.Ascendant != "Aquarius" {
+		t.Errorf("the ascendant six hours later should be Aquarius, got %s", chart.Ascendant)
+	}
+
+	birth.Latitude, birth.Longitude = nil, nil
+	if chart, _ := hcs.ComputeWesternChart(birth); chart.Ascendant != "" || len(chart.Placements) != 10 {
+		t.Errorf("a chart without the birth place should have no ascendant, got %+v", chart)
+	}
+
+	north, far := 90.0, 200.0
+	for name, b := range map[string]hcs.BirthInfo{
+		"latitude alone":    {Year: 2000, Month: 1, Day: 1, Latitude: &lat},
+		"polar latitude":    {Year: 2000, Month: 1, Day: 1, Latitude: &north, Longitude: &lon},
+		"longitude too far": {Year: 2000, Month: 1, Day: 1, Latitude: &lat, Longitude: &far},
+		"year out of range": {Year: 1800, Month: 1, Day: 1},
+	} {
+		if _, err := hcs.ComputeWesternChart(b); err == nil {
+			t.Errorf("a birth info with a %s should be rejected", name)
+		}
+	}
+}
+
+// TestGenerateWesternFromBirth verifies that the Western profile is derived
+// from the natal chart when requested, and verified the same way.
+func TestGenerateWesternFromBirth(t *testing.T) {
+	setTestSecretKey(t)
+	gen, err := hcs.NewGenerator(hcs.WithSaltDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("failed to create generator: %v", err)
+	}
+	lat, lon := 48.85, 2.35
+	input := getTestInput()
+	input.BirthInfo = &hcs.BirthInfo{Year: 1990, Month: 6, Day: 15, Hour: 14, Minute: 30, Timezone: "Europe/Paris", Latitude: &lat, Longitude: &lon}
+	opts := &hcs.GeneratorOptions{WesternFromBirth: true}
+
+	out, err := gen.GenerateWithOptions(input, opts)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	chart := out.WesternChart
+	if chart == nil || chart.Sun != "Gemini" || chart.Ascendant == "" {
+		t.Fatalf("expected the natal chart of a Gemini, got %+v", chart)
+	}
+	if out.Input.DominantElement != chart.DominantElement || out.Input.Modal != chart.Modal {
+		t.Errorf("the input should carry the chart's values, got %+v", out.Input)
+	}
+	sum := 0.0
+	for _, share := range chart.ElementBalance {
+		sum += share
+	}
+	if math.Abs(sum-1) > 0.001 || math.Abs(chart.Modal.Cardinal+chart.Modal.Fixed+chart.Modal.Mutable-1) > 0.001 {
+		t.Errorf("the chart's shares should sum to 1, got %+v", chart)
+	}
+	if violations, err := schema.ValidateOutput(out); err != nil || len(violations) != 0 {
+		t.Errorf("an output with a natal chart should match the schema: %v, %v", violations, err)
+	}
+
+	// Whatever Western values are sent, the codes follow the birth data
+	other := getTestInput()
+	other.BirthInfo = input.BirthInfo
+	other.DominantElement = "Water"
+	other.Modal = hcs.ModalBalance{Cardinal: 0.9, Fixed: 0.05, Mutable: 0.05}
+	again, err := gen.GenerateWithOptions(other, &hcs.GeneratorOptions{WesternFromBirth: true})
+	if err != nil || again.CodeU7 != out.CodeU7 {
+		t.Errorf("the codes should not depend on the Western values sent, got %v", err)
+	}
+
+	sent := getTestInput()
+	sent.BirthInfo = input.BirthInfo
+	for _, o := range []*hcs.GeneratorOptions{opts, nil} {
+		v, err := gen.VerifyProfile(context.Background(), out.CodeU7, sent, nil, o)
+		if err != nil {
+			t.Fatalf("VerifyProfile: %v", err)
+		}
+		if v.Valid != (o != nil) {
+			t.Errorf("verification with %+v should be %v, got %+v", o, o != nil, v)
+		}
+	}
+
+	if plain, _ := gen.Generate(getTestInput()); plain.WesternChart != nil {
+		t.Error("the chart should only be added when requested")
+	}
+	if _, err := gen.GenerateWithOptions(getTestInput(), opts); err == nil {
+		t.Error("deriving the Western profile without birth info should fail")
+	}
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/corehuman/hcs-lab-api/internal/hcs"
+	"github.com/corehuman/hcs-lab-api/internal/yamljson"
+)
+
+// TestYAMLProfile verifies that a YAML profile decodes to the same
+// InputProfile as its JSON form.
+func TestYAMLProfile(t *testing.T) {
+	yamlProfile := `
+# Subject 12, session 2
+dominantElement: Air
+modal: {cardinal: 0.31, fixed: 0.23, mutable: 0.46}
+cognition:
+  fluid: 0.52
+  crystallized: 0.13
+  verbal: 0.53
+  strategic: 0.15
+  creative: 0.33
+interaction:
+  pace: balanced
+  structure: medium
+  tone: precise
+birthInfo:
+  year: 1990
+  month: 6
+  day: 15
+  hour: 14
+  minute: 30
+  timezone: UTC
+`
+	jsonProfile := `{"dominantElement": "Air", "modal": {"cardinal": 0.31, "fixed": 0.23, "mutable": 0.46},
+	  "cognition": {"fluid": 0.52, "crystallized": 0.13, "verbal": 0.53, "strategic": 0.15, "creative": 0.33},
+	  "interaction": {"pace": "balanced", "structure": "medium", "tone": "precise"},
+	  "birthInfo": {"year": 1990, "month": 6, "day": 15, "hour": 14, "minute": 30, "timezone": "UTC"}}`
+
+	data, err := yamljson.ToJSON([]byte(yamlProfile))
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	var fromYAML, fromJSON hcs.InputProfile
+	if err := json.Unmarshal(data, &fromYAML); err != nil {
+		t.Fatalf("converted profile does not decode: %v\n%s", err, data)
+	}
+	if err := json.Unmarshal([]byte(jsonProfile), &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromYAML, fromJSON) {
+		t.Errorf("YAML profile decodes to %+v, JSON to %+v", fromYAML, fromJSON)
+	}
+
+}
+
+// TestYAMLConversion verifies how scalars are converted and which documents
+// are rejected.
+func TestYAMLConversion(t *testing.T) {
+	data, err := yamljson.ToJSON([]byte("date: 2024-03-01\nnote: 'yes'\nflag: true\nempty: null\nlist: [1, 2.5]\n"))
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if want := `{"date":"2024-03-01","empty":null,"flag":true,"list":[1,2.5],"note":"yes"}`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+	for _, bad := range []string{"", "a: 1\n---\nb: 2\n", "a: [1, 2\n", "a: .inf\n", "? [1, 2]\n: x\n"} {
+		if _, err := yamljson.ToJSON([]byte(bad)); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+	for path, want := range map[string]bool{"p.yaml": true, "p.YML": true, "p.json": false, "yaml": false} {
+		if got := yamljson.IsFile(path); got != want {
+			t.Errorf("IsFile(%q) = %v, want %v", path, got, want)
+		}
+	}
+}